@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/joho/godotenv"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/config"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/logging"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/tracing"
+)
+
+// app - What every command shares: the configuration, the Firestore client
+// and the PII keyring, set up the same way for the server and the admin commands
+type app struct {
+	cfg             *config.Config
+	logger          *slog.Logger
+	firebase        *config.FirebaseClient
+	keyring         *pii.Keyring
+	shutdownTracing func(context.Context) error
+}
+
+// loadConfig - Load the configuration and set up logging with it
+func loadConfig(src config.Sources, logOutput io.Writer) (*config.Config, *slog.Logger, error) {
+	// Load environment variables
+	envErr := godotenv.Load()
+
+	// Configure the application and its logger
+	cfg, err := config.Load(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, err := logging.New(logOutput, cfg.LogLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	slog.SetDefault(logger)
+	if envErr != nil {
+		slog.Warn(".env file not found, using system environment variables")
+	}
+	return cfg, logger, nil
+}
+
+// newApp - Load the configuration and connect to Firestore, logging to logOutput
+func newApp(src config.Sources, logOutput io.Writer) (*app, error) {
+	cfg, logger, err := loadConfig(src, logOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send spans to the configured exporter
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "drank-backend-firestore",
+		Exporter:    cfg.TracingExporter,
+		File:        cfg.TracingFile,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set up tracing: %w", err)
+	}
+
+	// Initialize Firebase client
+	firebase, err := config.NewFirebaseClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize Firebase: %w", err)
+	}
+
+	// Load the keyring that encrypts customer PII
+	keyring, err := pii.New(cfg.PIIKeyringFile)
+	if err != nil {
+		firebase.Close()
+		return nil, fmt.Errorf("load PII keyring: %w", err)
+	}
+
+	return &app{
+		cfg:             cfg,
+		logger:          logger,
+		firebase:        firebase,
+		keyring:         keyring,
+		shutdownTracing: shutdownTracing,
+	}, nil
+}
+
+// close - Flush spans and close the Firestore client, if the app has them
+func (a *app) close(ctx context.Context) {
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush spans", "error", err)
+		}
+	}
+	if a.firebase != nil {
+		a.firebase.Close()
+	}
+}
+
+// repositories - The repositories over the configured collections
+type repositories struct {
+	user         interfaces.UserRepository
+	account      interfaces.AccountRepository
+	transaction  interfaces.TransactionRepository
+	loginAttempt interfaces.LoginAttemptRepository
+}
+
+// repositories - Create the repositories with the configured timeouts
+func (a *app) repositories() repositories {
+	client, userID := a.firebase.Firestore, a.cfg.UserID
+	timeouts := repository.Timeouts{Read: a.cfg.DBReadTimeout, Write: a.cfg.DBWriteTimeout}
+	return repositories{
+		user:         repository.NewUserRepository(client, userID, a.keyring, timeouts),
+		account:      repository.NewAccountRepository(client, userID, timeouts),
+		transaction:  repository.NewTransactionRepository(client, userID, timeouts),
+		loginAttempt: repository.NewLoginAttemptRepository(client, userID, a.keyring, timeouts),
+	}
+}
+
+// adminService - The service behind the user and account commands
+func (a *app) adminService() *services.AdminService {
+	repos := a.repositories()
+	return services.NewAdminService(repos.user, repos.account)
+}
+
+// ledgerService - The service behind reconcile and accrue-interest
+func (a *app) ledgerService() *services.LedgerService {
+	repos := a.repositories()
+	return services.NewLedgerService(repos.account, repos.transaction)
+}
@@ -1,39 +1,197 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/ratelimit"
 )
 
-// Config - Application configuration
+// DefaultJWTSecret - Shared secret that is only acceptable in development mode
+const DefaultJWTSecret = "your-very-secret-jwt-key-change-in-production"
+
+// Environments the application runs in. Production refuses the development
+// defaults of secrets and requires a PII keyring.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// Config - Application configuration, loaded by Load
 type Config struct {
+	Environment       string
 	Port              int
+	FrontendURL       string   // Origin allowed by CORS besides http://localhost:3000
+	TrustedProxies    []string // Addresses or CIDR ranges of the proxies whose X-Forwarded-For is believed; none when empty
 	FirebaseProjectID string
 	FirestoreEmulator string
 	AuthEmulator      string
 	JWTSecret         string
+	JWTKeysDir        string // PEM keys for RS256/EdDSA signing; HS256 with JWTSecret when empty
+	JWTSigningKeyID   string // Kid of the key that signs new tokens; defaults to the last kid in JWTKeysDir
 	UserID            string
+	TwoFactorIssuer   string
+
+	TwoFactorChallengeTTL time.Duration // How long the second login step can be completed after the password
+
+	LoginMaxFailures     int
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	LoginIPMaxFailures   int
+	LoginDelayBase       time.Duration
+
+	PIIKeyringFile string // JSON keyring that encrypts customer PII; a fixed development keyring when empty
+
+	LogLevel string // debug, info, warn or error
+
+	DBReadTimeout  time.Duration // Limit on each repository read, 0 disables
+	DBWriteTimeout time.Duration // Limit on each repository write, 0 disables
+
+	HealthCheckTimeout time.Duration // Limit on each readiness check
+	ShutdownDrainDelay time.Duration // How long /readyz fails before the server stops accepting requests
+
+	MetricsToken string // Bearer token that scrapers present to /metrics; the endpoint is open without one, which production refuses
+
+	TracingExporter    string  // none, otlp or stdout
+	TracingFile        string  // File the stdout exporter appends spans to, standard output when empty
+	TracingSampleRatio float64 // Share of new traces recorded
+
+	SavingsInterestRate float64 // Annual interest in percent credited daily to savings accounts by accrue-interest
+
+	RateLimitAuth         ratelimit.Limit // Per IP address on the login and registration routes
+	RateLimitTransactions ratelimit.Limit // Per user on /transactions
+	RateLimitAPI          ratelimit.Limit // Per user on the other authenticated routes
+	RateLimitFailOpen     bool            // Let requests through, rather than refuse them with 503, while the store cannot be reached
+
+	settings []Setting
+}
+
+// Load - Read the configuration from the defaults, the config file, the
+// environment and the command line, each overriding the one before. It
+// returns Problems listing every setting that cannot be parsed or is out of
+// range, along with the configuration as far as it could be read.
+func Load(src Sources) (*Config, error) {
+	l := newLoader(src)
+	environment := l.oneOf("APP_ENV", Development, Development, Production)
+	l.production = environment == Production
+	jwtKeysDir := l.string("JWT_KEYS_DIR", "")
+
+	cfg := &Config{
+		Environment:       environment,
+		Port:              l.int("PORT", 8080),
+		FrontendURL:       l.string("FRONTEND_URL", ""),
+		TrustedProxies:    l.list("TRUSTED_PROXIES"),
+		FirebaseProjectID: l.string("FIREBASE_PROJECT_ID", "seventh-league-405315"),
+		FirestoreEmulator: l.string("FIRESTORE_EMULATOR_HOST", "localhost:8091"),
+		AuthEmulator:      l.string("FIREBASE_AUTH_EMULATOR_HOST", "localhost:9099"),
+		JWTSecret:         l.secret("JWT_SECRET", DefaultJWTSecret, jwtKeysDir == ""),
+		JWTKeysDir:        jwtKeysDir,
+		JWTSigningKeyID:   l.string("JWT_SIGNING_KEY_ID", ""),
+		UserID:            l.string("UNIQUE_USER_ID", "demo_user"),
+		TwoFactorIssuer:   l.string("TWO_FACTOR_ISSUER", "Drank Bank"),
+
+		TwoFactorChallengeTTL: l.duration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+
+		LoginMaxFailures:     l.int("LOGIN_MAX_FAILURES", 5),
+		LoginFailureWindow:   l.duration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration: l.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginIPMaxFailures:   l.int("LOGIN_IP_MAX_FAILURES", 20),
+		LoginDelayBase:       l.duration("LOGIN_DELAY_BASE", time.Second),
+
+		PIIKeyringFile: l.string("PII_KEYRING_FILE", ""),
+
+		LogLevel: l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error"),
+
+		DBReadTimeout:  l.duration("DB_READ_TIMEOUT", 5*time.Second),
+		DBWriteTimeout: l.duration("DB_WRITE_TIMEOUT", 10*time.Second),
+
+		HealthCheckTimeout: l.duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		ShutdownDrainDelay: l.duration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+
+		MetricsToken: l.secret("METRICS_TOKEN", "", true),
+
+		TracingExporter:    l.oneOf("TRACING_EXPORTER", "none", "none", "otlp", "stdout"),
+		TracingFile:        l.string("TRACING_FILE", ""),
+		TracingSampleRatio: l.float("TRACING_SAMPLE_RATIO", 1),
+
+		SavingsInterestRate: l.float("SAVINGS_INTEREST_RATE", 1.5),
+
+		RateLimitAuth:         l.rateLimit("RATE_LIMIT_AUTH", ratelimit.Limit{Requests: 20, Per: time.Minute}),
+		RateLimitTransactions: l.rateLimit("RATE_LIMIT_TRANSACTIONS", ratelimit.Limit{Requests: 120, Per: time.Minute}),
+		RateLimitAPI:          l.rateLimit("RATE_LIMIT_API", ratelimit.Limit{Requests: 600, Per: time.Minute}),
+		RateLimitFailOpen:     l.bool("RATE_LIMIT_FAIL_OPEN", false),
+	}
+
+	cfg.settings = l.settings
+	l.finish()
+	problems := append(l.problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return cfg, problems
+	}
+	return cfg, nil
 }
 
-// New - Create a new configuration
-func New() *Config {
-	port, _ := strconv.Atoi(getEnv("PORT", "8080"))
+// IsDevelopment - Whether the application runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == Development
+}
 
-	return &Config{
-		Port:              port,
-		FirebaseProjectID: getEnv("FIREBASE_PROJECT_ID", "seventh-league-405315"),
-		FirestoreEmulator: getEnv("FIRESTORE_EMULATOR_HOST", "localhost:8091"),
-		AuthEmulator:      getEnv("FIREBASE_AUTH_EMULATOR_HOST", "localhost:9099"),
-		JWTSecret:         getEnv("JWT_SECRET", "your-very-secret-jwt-key-change-in-production"),
-		UserID:            getEnv("UNIQUE_USER_ID", "demo_user"),
+// Validate - Check the settings against each other and their ranges
+func (c *Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return problems
 	}
+	return nil
 }
 
-// getEnv - Get environment variable or default value
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+func (c *Config) validate() Problems {
+	var problems Problems
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	if !c.IsDevelopment() {
+		check(c.PIIKeyringFile != "", "PII_KEYRING_FILE must be set when APP_ENV is production")
+	}
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535")
+	for _, proxy := range c.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+	}
+	check(c.UserID != "", "UNIQUE_USER_ID cannot be empty")
+	if u, err := url.Parse(c.FrontendURL); c.FrontendURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		problems = append(problems, "FRONTEND_URL must be an origin such as https://bank.example.com")
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"LOGIN_FAILURE_WINDOW", c.LoginFailureWindow},
+		{"LOGIN_LOCKOUT_DURATION", c.LoginLockoutDuration},
+		{"TWO_FACTOR_CHALLENGE_TTL", c.TwoFactorChallengeTTL},
+		{"HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout},
+	} {
+		check(d.value > 0, "%s must be positive", d.key)
 	}
-	return value
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"LOGIN_DELAY_BASE", c.LoginDelayBase},
+		{"DB_READ_TIMEOUT", c.DBReadTimeout},
+		{"DB_WRITE_TIMEOUT", c.DBWriteTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", c.ShutdownDrainDelay},
+	} {
+		check(d.value >= 0, "%s cannot be negative", d.key)
+	}
+
+	check(c.LoginMaxFailures > 0 && c.LoginIPMaxFailures > 0, "LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be positive")
+	check(c.SavingsInterestRate >= 0, "SAVINGS_INTEREST_RATE cannot be negative")
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	return problems
 }
@@ -2,7 +2,7 @@ package config
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
@@ -27,22 +27,19 @@ func NewFirebaseClient(cfg *Config) (*FirebaseClient, error) {
 	// Create Firebase app with emulator configuration
 	app, err := firebase.NewApp(ctx, config)
 	if err != nil {
-		log.Fatalf("Error initializing Firebase app: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("initializing Firebase app: %w", err)
 	}
 
 	// Get Firebase Auth client
 	authClient, err := app.Auth(ctx)
 	if err != nil {
-		log.Fatalf("Error initializing Firebase Auth client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("initializing Firebase Auth client: %w", err)
 	}
 
 	// Get Firestore client
 	firestoreClient, err := firestore.NewClient(ctx, cfg.FirebaseProjectID)
 	if err != nil {
-		log.Fatalf("Error initializing Firestore client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("initializing Firestore client: %w", err)
 	}
 
 	return &FirebaseClient{
@@ -31,7 +31,7 @@ func NewAccountHandler(accountService *services.AccountService) *AccountHandler
 // @Failure 500 {object} map[string]string
 // @Router /accounts [get]
 func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
-	accounts, err := h.accountService.GetAll()
+	accounts, err := h.accountService.GetAll(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -55,7 +55,7 @@ func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
 func (h *AccountHandler) GetAccountByID(c *gin.Context) {
 	id := c.Param("id")
 
-	account, err := h.accountService.GetByID(id)
+	account, err := h.accountService.GetByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -88,7 +88,7 @@ func (h *AccountHandler) GetAccountsByUserID(c *gin.Context) {
 		return
 	}
 
-	accounts, err := h.accountService.GetByUserID(userID)
+	accounts, err := h.accountService.GetByUserID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -127,7 +127,7 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 	account.UserID = userID.(string)
 
 	// Create the account
-	createdAccount, err := h.accountService.Create(account)
+	createdAccount, err := h.accountService.Create(c.Request.Context(), account)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -1,38 +1,50 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
 )
 
 // AuthHandler - Handler for authentication operations
 type AuthHandler struct {
-	userService *services.UserService
-	jwtSecret   string
+	userService         *services.UserService
+	twoFactorService    *services.TwoFactorService
+	loginAttemptService *services.LoginAttemptService
+	keySet              *signing.KeySet
 }
 
 // NewAuthHandler - Create a new auth handler
-func NewAuthHandler(userService *services.UserService, jwtSecret string) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, twoFactorService *services.TwoFactorService, loginAttemptService *services.LoginAttemptService, keySet *signing.KeySet) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		jwtSecret:   jwtSecret,
+		userService:         userService,
+		twoFactorService:    twoFactorService,
+		loginAttemptService: loginAttemptService,
+		keySet:              keySet,
 	}
 }
 
 // Login - Login endpoint
 // @Summary Login user
-// @Description Authenticate a user and return a JWT token
+// @Description Authenticate a user and return a JWT token. Users with 2FA enabled
+// @Description get a challenge token to complete at /auth/login/2fa instead.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param loginRequest body models.LoginRequest true "Login credentials"
 // @Success 200 {object} models.LoginResponse
+// @Success 202 {object} models.TwoFactorChallenge
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -43,16 +55,122 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse locked accounts and throttled clients before checking the password
+	if !h.checkLoginAllowed(c, req.Email) {
+		return
+	}
+
 	// Authenticate the user
-	user, err := h.userService.Authenticate(req.Email, req.Password)
+	user, err := h.userService.Authenticate(c.Request.Context(), req.Email, req.Password)
+	if errors.Is(err, services.ErrUserDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
 	if err != nil {
+		h.recordFailure(c, req.Email, "invalid credentials")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate a JWT token
-	authMiddleware := middleware.NewAuthMiddleware(h.jwtSecret)
-	token, err := authMiddleware.GenerateToken(user.ID, user.Email)
+	// The password alone is not enough when 2FA is enabled
+	if user.TwoFactorEnabled {
+		challenge, err := h.twoFactorService.CreateChallenge(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create two-factor challenge"})
+			return
+		}
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	h.respondWithToken(c, user)
+}
+
+// LoginTwoFactor - Second login step for users with 2FA enabled
+// @Summary Complete a two-factor login
+// @Description Exchange a login challenge token and a TOTP or recovery code for a JWT token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param twoFactorLoginRequest body models.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+
+	// Bind the request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Find the challenged user
+	ctx := c.Request.Context()
+	user, err := h.twoFactorService.ChallengedUser(ctx, req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code or challenge"})
+		return
+	}
+
+	// The account may have been locked or disabled since the challenge was issued,
+	// in which case the code is not checked so it is not used up
+	if !h.checkLoginAllowed(c, user.Email) {
+		return
+	}
+	if user.IsDisabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	// Verify the second factor
+	if err := h.twoFactorService.VerifyLoginCode(ctx, user, req.Code); err != nil {
+		// Wrong codes count towards the lockout like wrong passwords
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			h.recordFailure(c, user.Email, "invalid two-factor code")
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code or challenge"})
+		return
+	}
+
+	h.respondWithToken(c, user)
+}
+
+// checkLoginAllowed - Write a 429 response and return false while the account or the client address is throttled
+func (h *AuthHandler) checkLoginAllowed(c *gin.Context, email string) bool {
+	err := h.loginAttemptService.CheckAllowed(c.Request.Context(), email, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	var throttled *services.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+	return false
+}
+
+// recordFailure - Store a failed login attempt. The login has failed either way,
+// so an error here must not change the response.
+func (h *AuthHandler) recordFailure(c *gin.Context, email, reason string) {
+	_ = h.loginAttemptService.RecordFailure(c.Request.Context(), email, c.ClientIP(), c.Request.UserAgent(), reason)
+}
+
+// respondWithToken - Generate a JWT token for an authenticated user and return it
+func (h *AuthHandler) respondWithToken(c *gin.Context, user models.User) {
+	if err := h.loginAttemptService.RecordSuccess(c.Request.Context(), user, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login"})
+		return
+	}
+
+	authMiddleware := middleware.NewAuthMiddleware(h.keySet)
+	token, err := authMiddleware.GenerateToken(user.ID, user.Email, user.GetRole())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -85,7 +203,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Create the user
-	createdUser, err := h.userService.Create(user)
+	createdUser, err := h.userService.Create(c.Request.Context(), user)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/health"
+)
+
+// HealthHandler - Handler for the liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler - Create a new health handler
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Live - Report that the process is serving requests. No dependencies are
+// checked, so an orchestrator only restarts a server that hangs. Mounted at
+// /healthz, outside the API base path.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Ready - Run the dependency checks and answer 503 when one fails or the
+// server is shutting down, so load balancers stop routing to it. Mounted at
+// /readyz.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+)
+
+// JWKSHandler - Handler for publishing the token verification keys
+type JWKSHandler struct {
+	keySet *signing.KeySet
+}
+
+// NewJWKSHandler - Create a new JWKS handler
+func NewJWKSHandler(keySet *signing.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		keySet: keySet,
+	}
+}
+
+// GetJWKS - Serve the public keys that verify access tokens, matched by their
+// kid header. The set is empty when tokens are signed with a shared secret.
+// Mounted at /.well-known/jwks.json, outside the API base path.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Verifiers may cache the keys; a rotated-in key is published before it signs
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keySet.JWKS())
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+)
+
+// LoginAttemptHandler - Handler for login attempt review and account unlock
+type LoginAttemptHandler struct {
+	loginAttemptService *services.LoginAttemptService
+}
+
+// NewLoginAttemptHandler - Create a new login attempt handler
+func NewLoginAttemptHandler(loginAttemptService *services.LoginAttemptService) *LoginAttemptHandler {
+	return &LoginAttemptHandler{
+		loginAttemptService: loginAttemptService,
+	}
+}
+
+// Unlock - Admin unlock of a user endpoint
+// @Summary Unlock a user
+// @Description Lift a lockout caused by failed logins and clear the failure count. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/unlock [post]
+func (h *LoginAttemptHandler) Unlock(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.loginAttemptService.Unlock(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User has been unlocked"})
+}
+
+// GetAttemptsByUserID - Admin review of a user's login attempts endpoint
+// @Summary Get a user's login attempts
+// @Description Get the most recent login attempts against a user's account, newest first. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} models.LoginAttempt
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/users/{id}/login-attempts [get]
+func (h *LoginAttemptHandler) GetAttemptsByUserID(c *gin.Context) {
+	id := c.Param("id")
+
+	attempts, err := h.loginAttemptService.GetAttemptsByUserID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
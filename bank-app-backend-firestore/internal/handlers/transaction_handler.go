@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
 )
 
@@ -31,7 +33,7 @@ func NewTransactionHandler(transactionService *services.TransactionService) *Tra
 // @Failure 500 {object} map[string]string
 // @Router /transactions [get]
 func (h *TransactionHandler) GetAllTransactions(c *gin.Context) {
-	transactions, err := h.transactionService.GetAll()
+	transactions, err := h.transactionService.GetAll(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -55,7 +57,7 @@ func (h *TransactionHandler) GetAllTransactions(c *gin.Context) {
 func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 	id := c.Param("id")
 
-	transaction, err := h.transactionService.GetByID(id)
+	transaction, err := h.transactionService.GetByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -79,7 +81,7 @@ func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 func (h *TransactionHandler) GetTransactionsByAccountID(c *gin.Context) {
 	accountID := c.Param("accountId")
 
-	transactions, err := h.transactionService.GetByAccountID(accountID)
+	transactions, err := h.transactionService.GetByAccountID(c.Request.Context(), accountID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -99,6 +101,7 @@ func (h *TransactionHandler) GetTransactionsByAccountID(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /transactions/transfer [post]
 func (h *TransactionHandler) Transfer(c *gin.Context) {
 	var req models.TransferRequest
@@ -110,9 +113,9 @@ func (h *TransactionHandler) Transfer(c *gin.Context) {
 	}
 
 	// Perform the transfer
-	err := h.transactionService.Transfer(req)
+	err := h.transactionService.Transfer(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -130,6 +133,7 @@ func (h *TransactionHandler) Transfer(c *gin.Context) {
 // @Success 201 {object} models.TransactionDTO
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /transactions/deposit [post]
 func (h *TransactionHandler) CreateDeposit(c *gin.Context) {
 	var transaction models.Transaction
@@ -144,9 +148,9 @@ func (h *TransactionHandler) CreateDeposit(c *gin.Context) {
 	transaction.Type = models.Deposit
 
 	// Create the transaction
-	createdTransaction, err := h.transactionService.Create(transaction)
+	createdTransaction, err := h.transactionService.Create(c.Request.Context(), transaction)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -164,6 +168,7 @@ func (h *TransactionHandler) CreateDeposit(c *gin.Context) {
 // @Success 201 {object} models.TransactionDTO
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /transactions/withdrawal [post]
 func (h *TransactionHandler) CreateWithdrawal(c *gin.Context) {
 	var transaction models.Transaction
@@ -182,11 +187,19 @@ func (h *TransactionHandler) CreateWithdrawal(c *gin.Context) {
 	}
 
 	// Create the transaction
-	createdTransaction, err := h.transactionService.Create(transaction)
+	createdTransaction, err := h.transactionService.Create(c.Request.Context(), transaction)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, createdTransaction)
 }
+
+// transactionErrorStatus - 403 for frozen accounts, 400 for every other failure
+func transactionErrorStatus(err error) int {
+	if errors.Is(err, interfaces.ErrAccountFrozen) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
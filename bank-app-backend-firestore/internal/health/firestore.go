@@ -0,0 +1,20 @@
+package health
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Firestore - Read one document from a collection; an empty collection passes
+func Firestore(client *firestore.Client, collection string) Check {
+	return func(ctx context.Context) error {
+		iter := client.Collection(collection).Limit(1).Documents(ctx)
+		defer iter.Stop()
+		if _, err := iter.Next(); err != nil && err != iterator.Done {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses - Of a report and of each check
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusFailed      = "failed"
+)
+
+// Check - Returns why a dependency cannot serve requests, or nil when it can
+type Check func(ctx context.Context) error
+
+// Result - Outcome of one check
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report - Readiness of the server and each of its dependencies
+type Report struct {
+	Status   string            `json:"status"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]Result `json:"checks,omitempty"`
+}
+
+// Ready - Whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker - Runs the readiness checks, each bounded by the timeout
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// New - Create a checker whose checks fail after timeout, 0 waits indefinitely
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add - Register a check under a name shown in the report
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Drain - Make every later report fail, so load balancers stop sending
+// requests before the server shuts down
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run - Run the checks concurrently. A check that does not return within the
+// timeout fails, even when it ignores its context.
+func (c *Checker) Run(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: StatusUnavailable, Draining: true}
+	}
+
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusOK, Duration: time.Since(start).Round(time.Millisecond).String()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Heartbeat - Lets a background worker report that it is still making progress.
+// Its check fails when the worker has not beaten within maxAge.
+type Heartbeat struct {
+	maxAge time.Duration
+	last   atomic.Int64
+}
+
+// NewHeartbeat - Create a heartbeat that has just beaten
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	h := &Heartbeat{maxAge: maxAge}
+	h.Beat()
+	return h
+}
+
+// Beat - Record that the worker is alive
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check - Fail when the last beat is older than the heartbeat's maximum age
+func (h *Heartbeat) Check(ctx context.Context) error {
+	if age := time.Since(time.Unix(0, h.last.Load())); age > h.maxAge {
+		return errors.New("no progress for " + age.Round(time.Second).String())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted - Replaces the value of a sensitive attribute
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys - Attribute keys containing any of these, ignoring case,
+// underscores and dashes, are never logged with their value
+var sensitiveKeys = []string{"password", "secret", "token", "apikey", "authorization", "cookie", "otp", "recoverycode"}
+
+// New - Logger writing JSON lines at or above the level (debug, info, warn
+// or error), with sensitive attributes redacted
+func New(w io.Writer, level string) (*slog.Logger, error) {
+	var minimum slog.Level
+	if err := minimum.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       minimum,
+		ReplaceAttr: redact,
+	})
+	return slog.New(handler), nil
+}
+
+// redact - Hide the value of attributes whose key looks sensitive, however
+// deeply they are grouped
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+// IsSensitive - Whether values under the key must not be logged
+func IsSensitive(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(normalized, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext - Context carrying the logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext - Logger of the request the context belongs to, which
+// carries its request ID and user, or the default logger outside a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
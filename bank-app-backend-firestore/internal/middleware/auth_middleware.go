@@ -1,36 +1,37 @@
 package middleware
 
 import (
-	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
 )
 
 // AuthMiddleware - Middleware for authentication
 type AuthMiddleware struct {
-	jwtSecret string
+	keySet *signing.KeySet
 }
 
 // Claims - JWT claims
 type Claims struct {
 	UserID string `json:"userId"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
 // NewAuthMiddleware - Create a new auth middleware
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
+func NewAuthMiddleware(keySet *signing.KeySet) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret: jwtSecret,
+		keySet: keySet,
 	}
 }
 
 // GenerateToken - Generate a JWT token
-func (m *AuthMiddleware) GenerateToken(userID, email string) (string, error) {
+func (m *AuthMiddleware) GenerateToken(userID, email, role string) (string, error) {
 	// Set expiration time
 	expirationTime := time.Now().Add(24 * time.Hour)
 
@@ -38,6 +39,7 @@ func (m *AuthMiddleware) GenerateToken(userID, email string) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -47,16 +49,8 @@ func (m *AuthMiddleware) GenerateToken(userID, email string) (string, error) {
 		},
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token with secret key
-	tokenString, err := token.SignedString([]byte(m.jwtSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	// Sign the token with the active key
+	return m.keySet.Sign(claims)
 }
 
 // Authenticate - Authenticate middleware
@@ -81,15 +75,10 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 
 		// Parse and validate the token
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return []byte(m.jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		token, err := jwt.ParseWithClaims(tokenString, claims, m.keySet.Keyfunc)
+
+		// Tokens without a user ID, such as 2FA login challenges, are not access tokens
+		if err != nil || !token.Valid || claims.UserID == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
@@ -97,6 +86,21 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		// Set the user ID in the context
 		c.Set("userId", claims.UserID)
 		c.Set("userEmail", claims.Email)
+		c.Set("userRole", claims.Role)
+		withLogAttrs(c, "user_id", claims.UserID)
+
+		c.Next()
+	}
+}
+
+// RequireRole - Only let through users whose token carries the given role.
+// Must run after Authenticate.
+func (m *AuthMiddleware) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("userRole") != role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/logging"
+)
+
+// RequestLogger - Give every request a logger carrying its request ID, which
+// Authenticate adds the user to, and log the request once it is served. Must
+// run after RequestID.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		setLogger(c, logger.With("request_id", c.GetString("requestID")))
+
+		c.Next()
+
+		// Server errors are errors, client errors warnings
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		// The path is logged without its query, which may carry tokens
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Recovery - Answer a panic with 500 and log it with its stack, in place of
+// gin's text output. Must run after RequestLogger.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err interface{}) {
+		logging.FromContext(c.Request.Context()).Error("panic while serving request",
+			"error", err,
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// withLogAttrs - Add attributes to every later log line of the request
+func withLogAttrs(c *gin.Context, args ...interface{}) {
+	setLogger(c, logging.FromContext(c.Request.Context()).With(args...))
+}
+
+func setLogger(c *gin.Context, logger *slog.Logger) {
+	c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger))
+}
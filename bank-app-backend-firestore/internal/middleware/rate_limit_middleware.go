@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/logging"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/ratelimit"
+)
+
+// RateLimitHeaders - The response headers the rate limiter sets, which
+// browsers are allowed to read
+var RateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// RateLimiter - Limits how often each caller may call a group of routes, with a
+// token bucket for every group and caller in the store
+type RateLimiter struct {
+	store    ratelimit.Store
+	failOpen bool
+}
+
+// NewRateLimiter - Create a rate limiter over the store. While the store cannot
+// be reached, requests are let through when failOpen is set and refused with 503 otherwise.
+func NewRateLimiter(store ratelimit.Store, failOpen bool) *RateLimiter {
+	return &RateLimiter{store, failOpen}
+}
+
+// Limit - Let each caller through limit's number of requests per period, in
+// bursts of up to as many, and answer the rest with 429. Callers are told
+// apart by user when the middleware runs after Authenticate, and by IP
+// address otherwise.
+func (r *RateLimiter) Limit(group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Off() {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := r.store.Take(ctx, group+":"+rateLimitCaller(c), limit)
+		if err != nil {
+			if r.failOpen {
+				logging.FromContext(ctx).Error("Rate limiter unavailable, letting the request through", "group", group, "error", err)
+				c.Next()
+				return
+			}
+			logging.FromContext(ctx).Error("Rate limiter unavailable, refusing the request", "group", group, "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"message": "Service temporarily unavailable, please try again later"})
+			c.Abort()
+			return
+		}
+
+		// Headers as the IETF RateLimit header fields draft describes them
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, ceilSeconds(limit.Per)))
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(group).Inc()
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitCaller - Name the caller whose bucket a request takes from
+func rateLimitCaller(c *gin.Context) string {
+	if userID := c.GetString("userId"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds - Format d in whole seconds, rounded up so that a caller
+// waiting that long is not refused again
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -18,6 +18,7 @@ type Account struct {
 	AccountNumber string      `json:"accountNumber" firestore:"accountNumber"`
 	AccountType   AccountType `json:"accountType" firestore:"accountType"`
 	Balance       float64     `json:"balance" firestore:"balance"`
+	FrozenAt      *time.Time  `json:"frozenAt,omitempty" firestore:"frozenAt"` // Set while transfers into and out of the account are refused
 	CreatedAt     time.Time   `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt     time.Time   `json:"updatedAt" firestore:"updatedAt"`
 }
@@ -29,6 +30,7 @@ type AccountDTO struct {
 	AccountNumber string      `json:"accountNumber"`
 	AccountType   AccountType `json:"accountType"`
 	Balance       float64     `json:"balance"`
+	FrozenAt      *time.Time  `json:"frozenAt,omitempty"`
 	CreatedAt     time.Time   `json:"createdAt"`
 	UpdatedAt     time.Time   `json:"updatedAt"`
 }
@@ -41,6 +43,7 @@ func (a *Account) ToDTO() AccountDTO {
 		AccountNumber: a.AccountNumber,
 		AccountType:   a.AccountType,
 		Balance:       a.Balance,
+		FrozenAt:      a.FrozenAt,
 		CreatedAt:     a.CreatedAt,
 		UpdatedAt:     a.UpdatedAt,
 	}
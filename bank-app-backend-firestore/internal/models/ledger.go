@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Discrepancy - An account whose balance disagrees with its transactions
+type Discrepancy struct {
+	AccountID     string  `json:"accountId"`
+	AccountNumber string  `json:"accountNumber"`
+	Balance       float64 `json:"balance"`
+	LedgerTotal   float64 `json:"ledgerTotal"` // Sum of the account's transaction amounts
+	LastBalance   float64 `json:"lastBalance"` // Running balance recorded by the newest transaction
+	Entries       int     `json:"entries"`
+}
+
+// ReconcileResult - Outcome of checking every account against its transactions
+type ReconcileResult struct {
+	Checked       int           `json:"checked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// InterestResult - Outcome of an interest run
+type InterestResult struct {
+	Date     time.Time `json:"date"`
+	Credited int       `json:"credited"`
+	Skipped  int       `json:"skipped"` // Credited by an earlier run, or under a cent
+	Total    float64   `json:"total"`
+}
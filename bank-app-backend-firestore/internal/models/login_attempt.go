@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+)
+
+// LoginAttempt - A single login attempt, kept for security review. UserID is
+// empty when the email did not match any user. The email tried is PII, so only
+// its blind index is stored.
+type LoginAttempt struct {
+	ID         string    `json:"id" firestore:"id"`
+	UserID     string    `json:"userId,omitempty" firestore:"userId"`
+	Email      string    `json:"-" firestore:"-"`          // Email tried, not stored
+	EmailIndex string    `json:"-" firestore:"emailIndex"` // Blind index of Email
+	IPAddress  string    `json:"ipAddress" firestore:"ipAddress"`
+	UserAgent  string    `json:"userAgent" firestore:"userAgent"`
+	Success    bool      `json:"success" firestore:"success"`
+	Reason     string    `json:"reason,omitempty" firestore:"reason"` // Why a failed attempt was rejected
+	CreatedAt  time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// Indexed - Copy of the attempt as it is stored: the email replaced by its blind index
+func (a LoginAttempt) Indexed(keyring *pii.Keyring) LoginAttempt {
+	a.EmailIndex = keyring.EmailIndex(a.Email)
+	a.Email = ""
+	return a
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactorEnrollment - TOTP secret returned when enrollment starts
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"`
+}
+
+// TwoFactorCodeRequest - Request body carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse - Recovery codes shown once when 2FA is enabled
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TwoFactorChallenge - Response body for a login that still needs a second factor
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"twoFactorRequired"`
+	ChallengeToken    string    `json:"challengeToken"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// TwoFactorLoginRequest - Request body for completing a two-factor login with
+// either a TOTP code or a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
@@ -3,18 +3,67 @@ package models
 import (
 	"time"
 
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// User roles
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
 // User - User model for Firestore
 type User struct {
-	ID        string    `json:"id" firestore:"id"`
-	Email     string    `json:"email" firestore:"email"`
-	Password  string    `json:"-" firestore:"password"` // Password is not exposed in JSON
-	FirstName string    `json:"firstName" firestore:"firstName"`
-	LastName  string    `json:"lastName" firestore:"lastName"`
-	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+	ID                 string    `json:"id" firestore:"id"`
+	Email              string    `json:"email" firestore:"email"`         // Stored encrypted
+	EmailIndex         string    `json:"-" firestore:"emailIndex"`        // Blind index of Email, used to look users up by email
+	Password           string    `json:"-" firestore:"password"`          // Password is not exposed in JSON
+	FirstName          string    `json:"firstName" firestore:"firstName"` // Stored encrypted
+	LastName           string    `json:"lastName" firestore:"lastName"`   // Stored encrypted
+	Role               string    `json:"-" firestore:"role"`
+	TwoFactorSecret    string    `json:"-" firestore:"twoFactorSecret"`    // TOTP secret, set on enrollment
+	TwoFactorEnabled   bool      `json:"-" firestore:"twoFactorEnabled"`   // Set once enrollment is confirmed
+	TwoFactorLastStep  int64     `json:"-" firestore:"twoFactorLastStep"`  // Last accepted TOTP time step, prevents code replay
+	RecoveryCodeHashes []string  `json:"-" firestore:"recoveryCodeHashes"` // SHA-256 hashes of unused recovery codes
+	FailedLoginCount   int       `json:"-" firestore:"failedLoginCount"`   // Consecutive failed logins within the failure window
+	LastFailedLoginAt  time.Time `json:"-" firestore:"lastFailedLoginAt"`
+	LockedUntil        time.Time `json:"-" firestore:"lockedUntil"` // Set while the account is locked after too many failed logins
+	DisabledAt         time.Time `json:"-" firestore:"disabledAt"`  // Set when an administrator disables the user, who can no longer log in
+	CreatedAt          time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Encrypted - Copy of the user as it is written to Firestore, with PII
+// encrypted and the email indexed. Repositories call it on every write.
+func (u User) Encrypted(keyring *pii.Keyring) (User, error) {
+	u.EmailIndex = keyring.EmailIndex(u.Email)
+	for _, field := range u.piiFields() {
+		ciphertext, err := keyring.Encrypt(*field)
+		if err != nil {
+			return User{}, err
+		}
+		*field = ciphertext
+	}
+	return u, nil
+}
+
+// Decrypt - Decrypt the PII of a user read from Firestore. Repositories call
+// it after every read.
+func (u *User) Decrypt(keyring *pii.Keyring) error {
+	for _, field := range u.piiFields() {
+		plaintext, err := keyring.Decrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = plaintext
+	}
+	return nil
+}
+
+// piiFields - Fields that are encrypted at rest
+func (u *User) piiFields() []*string {
+	return []*string{&u.Email, &u.FirstName, &u.LastName, &u.TwoFactorSecret}
 }
 
 // GeneratePasswordHash - Generate a hash for the password
@@ -31,26 +80,43 @@ func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
 
+// GetRole - Role of the user; users created before roles existed are customers
+func (u *User) GetRole() string {
+	if u.Role == "" {
+		return RoleCustomer
+	}
+	return u.Role
+}
+
+// IsDisabled - Whether an administrator has disabled the user
+func (u *User) IsDisabled() bool {
+	return !u.DisabledAt.IsZero()
+}
+
 // ToDTO - Convert User model to DTO (Data Transfer Object)
 func (u *User) ToDTO() UserDTO {
 	return UserDTO{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+		Role:             u.GetRole(),
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
 
 // UserDTO - Data Transfer Object for User
 type UserDTO struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID               string    `json:"id"`
+	Email            string    `json:"email"`
+	FirstName        string    `json:"firstName"`
+	LastName         string    `json:"lastName"`
+	Role             string    `json:"role"`
+	TwoFactorEnabled bool      `json:"twoFactorEnabled"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
 }
 
 // LoginRequest - Request body for login
@@ -0,0 +1,282 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ciphertextPrefix marks a value written by Encrypt. Values without it are
+// plaintext stored before encryption was enabled.
+const ciphertextPrefix = "enc:v"
+
+const keySize = 32 // AES-256
+
+// developmentSecret derives the keyring used when no keyring file is configured
+const developmentSecret = "drank-firestore-development-pii-keyring"
+
+// KeyringFile - Layout of the keyring file. Keys are key-encryption keys; the
+// one with the highest version encrypts new values. The blind index key never
+// rotates because every stored index would have to be recomputed.
+type KeyringFile struct {
+	Keys []struct {
+		Version int    `json:"version"`
+		Key     string `json:"key"` // base64, 32 bytes
+	} `json:"keys"`
+	BlindIndexKey string `json:"blindIndexKey"` // base64, 32 bytes
+}
+
+// Keyring - Encrypt values with envelope encryption: each value gets its own
+// data key, which is stored next to it wrapped by the current key-encryption
+// key. Values encrypted with an older key stay readable until re-encrypted.
+type Keyring struct {
+	current  int
+	keys     map[int][]byte
+	indexKey []byte
+}
+
+// New - Load the keyring file at path, or fall back to a fixed development
+// keyring when no file is configured
+func New(path string) (*Keyring, error) {
+	if path == "" {
+		return NewDevelopmentKeyring(), nil
+	}
+	return LoadKeyring(path)
+}
+
+// LoadKeyring - Read a keyring file
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file KeyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %v", path, err)
+	}
+
+	keys := map[int][]byte{}
+	for _, entry := range file.Keys {
+		if entry.Version < 1 {
+			return nil, fmt.Errorf("key version %d must be at least 1", entry.Version)
+		}
+		if _, exists := keys[entry.Version]; exists {
+			return nil, fmt.Errorf("duplicate key version %d", entry.Version)
+		}
+		key, err := decodeKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d: %v", entry.Version, err)
+		}
+		keys[entry.Version] = key
+	}
+
+	indexKey, err := decodeKey(file.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind index key: %v", err)
+	}
+
+	return NewKeyring(keys, indexKey)
+}
+
+// NewKeyring - Build a keyring from raw keys. The highest version is current.
+func NewKeyring(keys map[int][]byte, indexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring has no keys")
+	}
+	if len(indexKey) != keySize {
+		return nil, fmt.Errorf("blind index key must be %d bytes", keySize)
+	}
+
+	k := &Keyring{keys: map[int][]byte{}, indexKey: indexKey}
+	for version, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key version %d must be %d bytes", version, keySize)
+		}
+		k.keys[version] = key
+		if version > k.current {
+			k.current = version
+		}
+	}
+	return k, nil
+}
+
+// NewDevelopmentKeyring - Return a keyring derived from a fixed secret. It is
+// only acceptable in development mode.
+func NewDevelopmentKeyring() *Keyring {
+	key := sha256.Sum256([]byte(developmentSecret + ":key"))
+	indexKey := sha256.Sum256([]byte(developmentSecret + ":index"))
+	k, _ := NewKeyring(map[int][]byte{1: key[:]}, indexKey[:])
+	return k
+}
+
+// CurrentVersion - Return the version of the key new values are encrypted with
+func (k *Keyring) CurrentVersion() int {
+	return k.current
+}
+
+// Encrypt - Seal plaintext under a fresh data key. The result has the form
+// enc:v<version>:<wrapped data key>:<ciphertext>. Empty values stay empty.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(k.keys[k.current], dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%d:%s:%s", ciphertextPrefix, k.current,
+		base64.RawStdEncoding.EncodeToString(wrappedKey),
+		base64.RawStdEncoding.EncodeToString(ciphertext)), nil
+}
+
+// Decrypt - Open a value written by Encrypt. Values that were never encrypted
+// are returned unchanged so documents written before encryption stay readable.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, ciphertextPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", errors.New("malformed key version")
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("key version %d is not in the keyring", version)
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed data key")
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed ciphertext")
+	}
+
+	dataKey, err := open(key, wrappedKey)
+	if err != nil {
+		return "", errors.New("failed to unwrap data key")
+	}
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", errors.New("failed to decrypt value")
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption - Whether a stored value is plaintext or was
+// encrypted with a key older than the current one
+func (k *Keyring) NeedsReencryption(value string) bool {
+	if value == "" {
+		return false
+	}
+	version, ok := keyVersion(value)
+	return !ok || version != k.current
+}
+
+// BlindIndex - Keyed hash of value, so encrypted fields can still be
+// looked up by equality without storing them in plaintext
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EmailIndex - Blind index of an email, which matches however the email is
+// cased or padded with spaces
+func (k *Keyring) EmailIndex(email string) string {
+	return k.BlindIndex(NormalizeEmail(email))
+}
+
+// NormalizeEmail - email as it is indexed: trimmed and in lowercase
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsEncrypted - Whether value was written by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+func keyVersion(value string) (int, bool) {
+	if !IsEncrypted(value) {
+		return 0, false
+	}
+	rest := strings.TrimPrefix(value, ciphertextPrefix)
+	end := strings.IndexByte(rest, ':')
+	if end < 0 {
+		return 0, false
+	}
+	version, err := strconv.Atoi(rest[:end])
+	return version, err == nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("key is not valid base64")
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes", keySize)
+	}
+	return key, nil
+}
+
+// seal - Encrypt with AES-GCM and prepend the nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
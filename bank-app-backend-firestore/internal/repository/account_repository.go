@@ -15,17 +15,17 @@ import (
 
 // AccountRepositoryImpl - Implementation of the AccountRepository interface
 type AccountRepositoryImpl struct {
-	client *firestore.Client
-	ctx    context.Context
-	userID string
+	client   *firestore.Client
+	userID   string
+	timeouts Timeouts
 }
 
 // NewAccountRepository - Create a new account repository
-func NewAccountRepository(client *firestore.Client, userID string) interfaces.AccountRepository {
+func NewAccountRepository(client *firestore.Client, userID string, timeouts Timeouts) interfaces.AccountRepository {
 	return &AccountRepositoryImpl{
-		client: client,
-		ctx:    context.Background(),
-		userID: userID,
+		client:   client,
+		userID:   userID,
+		timeouts: timeouts,
 	}
 }
 
@@ -35,10 +35,14 @@ func (r *AccountRepositoryImpl) getCollectionName() string {
 }
 
 // Create - Create a new account
-func (r *AccountRepositoryImpl) Create(account models.Account) (models.Account, error) {
+func (r *AccountRepositoryImpl) Create(ctx context.Context, account models.Account) (models.Account, error) {
+	defer observe("accounts", "Create")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Check if account number already exists
 	query := r.client.Collection(r.getCollectionName()).Where("accountNumber", "==", account.AccountNumber).Limit(1)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	_, err := iter.Next()
@@ -55,14 +59,14 @@ func (r *AccountRepositoryImpl) Create(account models.Account) (models.Account,
 	account.UpdatedAt = now
 
 	// Add account to Firestore
-	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(r.ctx, account)
+	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(ctx, account)
 	if err != nil {
 		return models.Account{}, err
 	}
 
 	// Update the account with the generated ID
 	account.ID = docRef.ID
-	_, err = docRef.Set(r.ctx, map[string]interface{}{
+	_, err = docRef.Set(ctx, map[string]interface{}{
 		"id": docRef.ID,
 	}, firestore.MergeAll)
 	if err != nil {
@@ -73,9 +77,13 @@ func (r *AccountRepositoryImpl) Create(account models.Account) (models.Account,
 }
 
 // FindByID - Find account by ID
-func (r *AccountRepositoryImpl) FindByID(id string) (models.Account, error) {
+func (r *AccountRepositoryImpl) FindByID(ctx context.Context, id string) (models.Account, error) {
+	defer observe("accounts", "FindByID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
-	docSnapshot, err := docRef.Get(r.ctx)
+	docSnapshot, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return models.Account{}, errors.New("account not found")
@@ -93,9 +101,13 @@ func (r *AccountRepositoryImpl) FindByID(id string) (models.Account, error) {
 }
 
 // FindByAccountNumber - Find account by account number
-func (r *AccountRepositoryImpl) FindByAccountNumber(accountNumber string) (models.Account, error) {
+func (r *AccountRepositoryImpl) FindByAccountNumber(ctx context.Context, accountNumber string) (models.Account, error) {
+	defer observe("accounts", "FindByAccountNumber")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	query := r.client.Collection(r.getCollectionName()).Where("accountNumber", "==", accountNumber).Limit(1)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	doc, err := iter.Next()
@@ -116,11 +128,15 @@ func (r *AccountRepositoryImpl) FindByAccountNumber(accountNumber string) (model
 }
 
 // FindByUserID - Find accounts by user ID
-func (r *AccountRepositoryImpl) FindByUserID(userID string) ([]models.Account, error) {
+func (r *AccountRepositoryImpl) FindByUserID(ctx context.Context, userID string) ([]models.Account, error) {
+	defer observe("accounts", "FindByUserID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var accounts []models.Account
 
 	query := r.client.Collection(r.getCollectionName()).Where("userId", "==", userID).OrderBy("createdAt", firestore.Desc)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -145,10 +161,14 @@ func (r *AccountRepositoryImpl) FindByUserID(userID string) ([]models.Account, e
 }
 
 // FindAll - Find all accounts
-func (r *AccountRepositoryImpl) FindAll() ([]models.Account, error) {
+func (r *AccountRepositoryImpl) FindAll(ctx context.Context) ([]models.Account, error) {
+	defer observe("accounts", "FindAll")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var accounts []models.Account
 
-	iter := r.client.Collection(r.getCollectionName()).Documents(r.ctx)
+	iter := r.client.Collection(r.getCollectionName()).Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -173,10 +193,14 @@ func (r *AccountRepositoryImpl) FindAll() ([]models.Account, error) {
 }
 
 // Update - Update an account
-func (r *AccountRepositoryImpl) Update(account models.Account) (models.Account, error) {
+func (r *AccountRepositoryImpl) Update(ctx context.Context, account models.Account) (models.Account, error) {
+	defer observe("accounts", "Update")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Check if account exists
 	docRef := r.client.Collection(r.getCollectionName()).Doc(account.ID)
-	_, err := docRef.Get(r.ctx)
+	_, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return models.Account{}, errors.New("account not found")
@@ -186,7 +210,7 @@ func (r *AccountRepositoryImpl) Update(account models.Account) (models.Account,
 
 	// Update account
 	account.UpdatedAt = time.Now()
-	_, err = docRef.Set(r.ctx, account)
+	_, err = docRef.Set(ctx, account)
 	if err != nil {
 		return models.Account{}, err
 	}
@@ -195,8 +219,12 @@ func (r *AccountRepositoryImpl) Update(account models.Account) (models.Account,
 }
 
 // Delete - Delete an account
-func (r *AccountRepositoryImpl) Delete(id string) error {
-	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Delete(r.ctx)
+func (r *AccountRepositoryImpl) Delete(ctx context.Context, id string) error {
+	defer observe("accounts", "Delete")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Delete(ctx)
 	if err != nil {
 		return err
 	}
@@ -205,9 +233,13 @@ func (r *AccountRepositoryImpl) Delete(id string) error {
 }
 
 // UpdateBalance - Update account balance
-func (r *AccountRepositoryImpl) UpdateBalance(id string, amount float64) (models.Account, error) {
+func (r *AccountRepositoryImpl) UpdateBalance(ctx context.Context, id string, amount float64) (models.Account, error) {
+	defer observe("accounts", "UpdateBalance")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Get account
-	account, err := r.FindByID(id)
+	account, err := r.FindByID(ctx, id)
 	if err != nil {
 		return models.Account{}, err
 	}
@@ -218,10 +250,93 @@ func (r *AccountRepositoryImpl) UpdateBalance(id string, amount float64) (models
 
 	// Update in database
 	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
-	_, err = docRef.Set(r.ctx, account)
+	_, err = docRef.Set(ctx, account)
 	if err != nil {
 		return models.Account{}, err
 	}
 
 	return account, nil
 }
+
+// SetFrozen - Freeze or unfreeze an account
+func (r *AccountRepositoryImpl) SetFrozen(ctx context.Context, id string, frozen bool) error {
+	defer observe("accounts", "SetFrozen")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	now := time.Now()
+	var frozenAt interface{}
+	if frozen {
+		frozenAt = now
+	}
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "frozenAt", Value: frozenAt},
+		{Path: "updatedAt", Value: now},
+	})
+	if status.Code(err) == codes.NotFound {
+		return errors.New("account not found")
+	}
+	return err
+}
+
+// CreditOnce - Deposit amount into an account unless the deposit stored under
+// key already exists. The key is the ID of the deposit's transaction, so that
+// repeating a credit, even concurrently, cannot pay it twice. Reports whether
+// the account was credited.
+func (r *AccountRepositoryImpl) CreditOnce(ctx context.Context, id, key string, amount float64, description string, date time.Time) (bool, error) {
+	defer observe("accounts", "CreditOnce")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	accountRef := r.client.Collection(r.getCollectionName()).Doc(id)
+	transactionRef := r.client.Collection(r.userID + "_transactions").Doc(key)
+
+	credited := false
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		credited = false
+
+		accountDoc, err := tx.Get(accountRef)
+		if err != nil {
+			return err
+		}
+		var account models.Account
+		if err := accountDoc.DataTo(&account); err != nil {
+			return err
+		}
+
+		// Reads come before writes in a Firestore transaction
+		if _, err := tx.Get(transactionRef); err == nil {
+			return nil
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		now := time.Now()
+		account.Balance += amount
+		account.UpdatedAt = now
+		transaction := models.Transaction{
+			ID:              key,
+			AccountID:       id,
+			Amount:          amount,
+			Balance:         account.Balance,
+			Type:            models.Deposit,
+			Description:     description,
+			TransactionDate: date,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+
+		if err := tx.Set(accountRef, account); err != nil {
+			return err
+		}
+		if err := tx.Create(transactionRef, transaction); err != nil {
+			return err
+		}
+		credited = true
+		return nil
+	})
+	if status.Code(err) == codes.NotFound {
+		return false, errors.New("account not found")
+	}
+	return credited, err
+}
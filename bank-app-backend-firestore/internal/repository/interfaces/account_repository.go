@@ -1,17 +1,22 @@
 package interfaces
 
 import (
+	"context"
+	"time"
+
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 )
 
 // AccountRepository defines the interface for account repository operations
 type AccountRepository interface {
-	Create(account models.Account) (models.Account, error)
-	FindByID(id string) (models.Account, error)
-	FindByUserID(userID string) ([]models.Account, error)
-	FindByAccountNumber(accountNumber string) (models.Account, error)
-	FindAll() ([]models.Account, error)
-	Update(account models.Account) (models.Account, error)
-	Delete(id string) error
-	UpdateBalance(id string, amount float64) (models.Account, error)
+	Create(ctx context.Context, account models.Account) (models.Account, error)
+	FindByID(ctx context.Context, id string) (models.Account, error)
+	FindByUserID(ctx context.Context, userID string) ([]models.Account, error)
+	FindByAccountNumber(ctx context.Context, accountNumber string) (models.Account, error)
+	FindAll(ctx context.Context) ([]models.Account, error)
+	Update(ctx context.Context, account models.Account) (models.Account, error)
+	Delete(ctx context.Context, id string) error
+	UpdateBalance(ctx context.Context, id string, amount float64) (models.Account, error)
+	SetFrozen(ctx context.Context, id string, frozen bool) error
+	CreditOnce(ctx context.Context, id, key string, amount float64, description string, date time.Time) (bool, error)
 }
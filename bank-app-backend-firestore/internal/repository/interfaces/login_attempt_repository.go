@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+)
+
+// LoginAttemptRepository defines the interface for login attempt repository operations
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt models.LoginAttempt) (models.LoginAttempt, error)
+	CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error)
+	FindByUserID(ctx context.Context, userID string, limit int) ([]models.LoginAttempt, error)
+}
@@ -1,16 +1,26 @@
 package interfaces
 
 import (
+	"context"
+	"errors"
+
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 )
 
+var (
+	// ErrInsufficientBalance - Returned by CreateTransfer when the source account cannot cover the amount
+	ErrInsufficientBalance = errors.New("insufficient balance in source account")
+	// ErrAccountFrozen - Returned by CreateTransfer when either account is frozen
+	ErrAccountFrozen = errors.New("account is frozen")
+)
+
 // TransactionRepository defines the interface for transaction repository operations
 type TransactionRepository interface {
-	Create(transaction models.Transaction) (models.Transaction, error)
-	FindByID(id string) (models.Transaction, error)
-	FindByAccountID(accountID string) ([]models.Transaction, error)
-	FindAll() ([]models.Transaction, error)
-	FindBySourceAccountID(sourceAccountID string) ([]models.Transaction, error)
-	FindByTargetAccountID(targetAccountID string) ([]models.Transaction, error)
-	CreateTransfer(sourceAccountID, targetAccountID string, amount float64, description string) error
+	Create(ctx context.Context, transaction models.Transaction) (models.Transaction, error)
+	FindByID(ctx context.Context, id string) (models.Transaction, error)
+	FindByAccountID(ctx context.Context, accountID string) ([]models.Transaction, error)
+	FindAll(ctx context.Context) ([]models.Transaction, error)
+	FindBySourceAccountID(ctx context.Context, sourceAccountID string) ([]models.Transaction, error)
+	FindByTargetAccountID(ctx context.Context, targetAccountID string) ([]models.Transaction, error)
+	CreateTransfer(ctx context.Context, sourceAccountID, targetAccountID string, amount float64, description string) error
 }
@@ -1,15 +1,28 @@
 package interfaces
 
 import (
+	"context"
+	"time"
+
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 )
 
 // UserRepository defines the interface for user repository operations
 type UserRepository interface {
-	Create(user models.User) (models.User, error)
-	FindByID(id string) (models.User, error)
-	FindByEmail(email string) (models.User, error)
-	FindAll() ([]models.User, error)
-	Update(user models.User) (models.User, error)
-	Delete(id string) error
+	Create(ctx context.Context, user models.User) (models.User, error)
+	FindByID(ctx context.Context, id string) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	FindAll(ctx context.Context) ([]models.User, error)
+	Update(ctx context.Context, user models.User) (models.User, error)
+	Delete(ctx context.Context, id string) error
+	SetTwoFactorSecret(ctx context.Context, id string, secret string) error
+	EnableTwoFactor(ctx context.Context, id string, recoveryCodeHashes []string) error
+	ResetTwoFactor(ctx context.Context, id string) error
+	ConsumeTwoFactorStep(ctx context.Context, id string, step int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error)
+	IncrementFailedLogins(ctx context.Context, id string, windowStart time.Time) (int, error)
+	Lock(ctx context.Context, id string, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id string) error
+	UpdatePassword(ctx context.Context, id string, passwordHash string) error
+	Disable(ctx context.Context, id string) error
 }
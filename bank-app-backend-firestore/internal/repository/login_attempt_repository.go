@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"google.golang.org/api/iterator"
+)
+
+// LoginAttemptRepositoryImpl - Implementation of the LoginAttemptRepository interface
+type LoginAttemptRepositoryImpl struct {
+	client   *firestore.Client
+	userID   string
+	keyring  *pii.Keyring
+	timeouts Timeouts
+}
+
+// NewLoginAttemptRepository - Create a new login attempt repository. The
+// email of each attempt is replaced by its blind index with the keyring.
+func NewLoginAttemptRepository(client *firestore.Client, userID string, keyring *pii.Keyring, timeouts Timeouts) interfaces.LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{
+		client:   client,
+		userID:   userID,
+		keyring:  keyring,
+		timeouts: timeouts,
+	}
+}
+
+// getCollectionName returns the user-prefixed collection name
+func (r *LoginAttemptRepositoryImpl) getCollectionName() string {
+	return r.userID + "_login_attempts"
+}
+
+// Create - Record a login attempt
+func (r *LoginAttemptRepositoryImpl) Create(ctx context.Context, attempt models.LoginAttempt) (models.LoginAttempt, error) {
+	defer observe("login_attempts", "Create")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	attempt = attempt.Indexed(r.keyring)
+	attempt.CreatedAt = time.Now()
+
+	// Use a generated document ID so the attempt can be stored in a single write
+	docRef := r.client.Collection(r.getCollectionName()).NewDoc()
+	attempt.ID = docRef.ID
+	if _, err := docRef.Set(ctx, attempt); err != nil {
+		return models.LoginAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// CountFailuresByIP - Count the failed attempts made from an address since the given time
+func (r *LoginAttemptRepositoryImpl) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	defer observe("login_attempts", "CountFailuresByIP")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	query := r.client.Collection(r.getCollectionName()).
+		Where("ipAddress", "==", ipAddress).
+		Where("success", "==", false).
+		Where("createdAt", ">=", since)
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	count := 0
+	for {
+		_, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// FindByUserID - Find the most recent attempts against a user's account, newest first
+func (r *LoginAttemptRepositoryImpl) FindByUserID(ctx context.Context, userID string, limit int) ([]models.LoginAttempt, error) {
+	defer observe("login_attempts", "FindByUserID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	attempts := []models.LoginAttempt{}
+
+	query := r.client.Collection(r.getCollectionName()).
+		Where("userId", "==", userID).
+		OrderBy("createdAt", firestore.Desc).
+		Limit(limit)
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var attempt models.LoginAttempt
+		if err := doc.DataTo(&attempt); err != nil {
+			return nil, err
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
+)
+
+// observe - Time a repository call to Firestore; defer the returned function
+func observe(collection, operation string) func() {
+	start := time.Now()
+	return func() {
+		metrics.FirestoreCallDuration.WithLabelValues(collection, operation).Observe(time.Since(start).Seconds())
+	}
+}
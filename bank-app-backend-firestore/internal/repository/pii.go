@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReencryptUsers - Rewrite encrypted user fields that are still plaintext or
+// were encrypted with an older key, and fill in missing email indexes. Users
+// are read batchSize at a time. Returns the number of users rewritten.
+func ReencryptUsers(ctx context.Context, client *firestore.Client, userID string, keyring *pii.Keyring, batchSize int) (int, error) {
+	query := client.Collection(userID+"_users").OrderBy(firestore.DocumentID, firestore.Asc).Limit(batchSize)
+
+	rewritten := 0
+	var last *firestore.DocumentSnapshot
+	for {
+		batch := query
+		if last != nil {
+			batch = query.StartAfter(last)
+		}
+		docs, err := batch.Documents(ctx).GetAll()
+		if err != nil {
+			return rewritten, err
+		}
+		if len(docs) == 0 {
+			return rewritten, nil
+		}
+		last = docs[len(docs)-1]
+
+		for _, doc := range docs {
+			var stored models.User
+			if err := doc.DataTo(&stored); err != nil {
+				return rewritten, err
+			}
+			updates, err := reencryptedFields(stored, keyring)
+			if err != nil {
+				return rewritten, err
+			}
+			if len(updates) == 0 {
+				continue
+			}
+
+			// Skip the document if it was changed since it was read
+			_, err = doc.Ref.Update(ctx, updates, firestore.LastUpdateTime(doc.UpdateTime))
+			if status.Code(err) == codes.FailedPrecondition {
+				continue
+			}
+			if err != nil {
+				return rewritten, err
+			}
+			rewritten++
+		}
+	}
+}
+
+// reencryptedFields - Updates that move a stored user to the current key
+func reencryptedFields(stored models.User, keyring *pii.Keyring) ([]firestore.Update, error) {
+	var updates []firestore.Update
+	fields := []struct {
+		path  string
+		value string
+	}{
+		{"email", stored.Email},
+		{"firstName", stored.FirstName},
+		{"lastName", stored.LastName},
+		{"twoFactorSecret", stored.TwoFactorSecret},
+	}
+	for _, field := range fields {
+		if !keyring.NeedsReencryption(field.value) {
+			continue
+		}
+		plaintext, err := keyring.Decrypt(field.value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, firestore.Update{Path: field.path, Value: ciphertext})
+	}
+
+	email, err := keyring.Decrypt(stored.Email)
+	if err != nil {
+		return nil, err
+	}
+	if index := keyring.EmailIndex(email); index != stored.EmailIndex {
+		updates = append(updates, firestore.Update{Path: "emailIndex", Value: index})
+	}
+	return updates, nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts - How long a single repository call may take on top of the
+// caller's context; zero leaves it unbounded. A write's limit covers every
+// attempt of its Firestore transaction.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// read - Bound a call that only reads documents
+func (t Timeouts) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Read)
+}
+
+// write - Bound a call that writes documents
+func (t Timeouts) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Write)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
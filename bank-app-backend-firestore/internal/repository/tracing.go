@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan - Start a span for a repository call to Firestore. Calls made
+// outside a trace, such as seeding, get a span that is not recorded.
+func startSpan(ctx context.Context, collection, operation string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracing.StartKind(ctx, collection+"."+operation, trace.SpanKindClient,
+		attribute.String("db.system", "firestore"),
+		semconv.DBOperation(operation),
+		attribute.String("db.collection", collection),
+	)
+}
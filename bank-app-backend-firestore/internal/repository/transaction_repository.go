@@ -8,6 +8,9 @@ import (
 	"cloud.google.com/go/firestore"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,17 +18,17 @@ import (
 
 // TransactionRepositoryImpl - Implementation of the TransactionRepository interface
 type TransactionRepositoryImpl struct {
-	client *firestore.Client
-	ctx    context.Context
-	userID string
+	client   *firestore.Client
+	userID   string
+	timeouts Timeouts
 }
 
 // NewTransactionRepository - Create a new transaction repository
-func NewTransactionRepository(client *firestore.Client, userID string) interfaces.TransactionRepository {
+func NewTransactionRepository(client *firestore.Client, userID string, timeouts Timeouts) interfaces.TransactionRepository {
 	return &TransactionRepositoryImpl{
-		client: client,
-		ctx:    context.Background(),
-		userID: userID,
+		client:   client,
+		userID:   userID,
+		timeouts: timeouts,
 	}
 }
 
@@ -35,7 +38,11 @@ func (r *TransactionRepositoryImpl) getCollectionName() string {
 }
 
 // Create - Create a new transaction
-func (r *TransactionRepositoryImpl) Create(transaction models.Transaction) (models.Transaction, error) {
+func (r *TransactionRepositoryImpl) Create(ctx context.Context, transaction models.Transaction) (models.Transaction, error) {
+	defer observe("transactions", "Create")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Set created and updated timestamps
 	now := time.Now()
 	transaction.CreatedAt = now
@@ -46,14 +53,14 @@ func (r *TransactionRepositoryImpl) Create(transaction models.Transaction) (mode
 	}
 
 	// Add transaction to Firestore
-	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(r.ctx, transaction)
+	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(ctx, transaction)
 	if err != nil {
 		return models.Transaction{}, err
 	}
 
 	// Update the transaction with the generated ID
 	transaction.ID = docRef.ID
-	_, err = docRef.Set(r.ctx, map[string]interface{}{
+	_, err = docRef.Set(ctx, map[string]interface{}{
 		"id": docRef.ID,
 	}, firestore.MergeAll)
 	if err != nil {
@@ -64,9 +71,13 @@ func (r *TransactionRepositoryImpl) Create(transaction models.Transaction) (mode
 }
 
 // FindByID - Find transaction by ID
-func (r *TransactionRepositoryImpl) FindByID(id string) (models.Transaction, error) {
+func (r *TransactionRepositoryImpl) FindByID(ctx context.Context, id string) (models.Transaction, error) {
+	defer observe("transactions", "FindByID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
-	docSnapshot, err := docRef.Get(r.ctx)
+	docSnapshot, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return models.Transaction{}, errors.New("transaction not found")
@@ -84,11 +95,15 @@ func (r *TransactionRepositoryImpl) FindByID(id string) (models.Transaction, err
 }
 
 // FindByAccountID - Find transactions by account ID
-func (r *TransactionRepositoryImpl) FindByAccountID(accountID string) ([]models.Transaction, error) {
+func (r *TransactionRepositoryImpl) FindByAccountID(ctx context.Context, accountID string) ([]models.Transaction, error) {
+	defer observe("transactions", "FindByAccountID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var transactions []models.Transaction
 
 	query := r.client.Collection(r.getCollectionName()).Where("accountId", "==", accountID).OrderBy("transactionDate", firestore.Desc)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -113,10 +128,14 @@ func (r *TransactionRepositoryImpl) FindByAccountID(accountID string) ([]models.
 }
 
 // FindAll - Find all transactions
-func (r *TransactionRepositoryImpl) FindAll() ([]models.Transaction, error) {
+func (r *TransactionRepositoryImpl) FindAll(ctx context.Context) ([]models.Transaction, error) {
+	defer observe("transactions", "FindAll")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var transactions []models.Transaction
 
-	iter := r.client.Collection(r.getCollectionName()).OrderBy("transactionDate", firestore.Desc).Documents(r.ctx)
+	iter := r.client.Collection(r.getCollectionName()).OrderBy("transactionDate", firestore.Desc).Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -141,11 +160,15 @@ func (r *TransactionRepositoryImpl) FindAll() ([]models.Transaction, error) {
 }
 
 // FindBySourceAccountID - Find transactions by source account ID
-func (r *TransactionRepositoryImpl) FindBySourceAccountID(sourceAccountID string) ([]models.Transaction, error) {
+func (r *TransactionRepositoryImpl) FindBySourceAccountID(ctx context.Context, sourceAccountID string) ([]models.Transaction, error) {
+	defer observe("transactions", "FindBySourceAccountID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var transactions []models.Transaction
 
 	query := r.client.Collection(r.getCollectionName()).Where("sourceAccountId", "==", sourceAccountID).OrderBy("transactionDate", firestore.Desc)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -170,11 +193,15 @@ func (r *TransactionRepositoryImpl) FindBySourceAccountID(sourceAccountID string
 }
 
 // FindByTargetAccountID - Find transactions by target account ID
-func (r *TransactionRepositoryImpl) FindByTargetAccountID(targetAccountID string) ([]models.Transaction, error) {
+func (r *TransactionRepositoryImpl) FindByTargetAccountID(ctx context.Context, targetAccountID string) ([]models.Transaction, error) {
+	defer observe("transactions", "FindByTargetAccountID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var transactions []models.Transaction
 
 	query := r.client.Collection(r.getCollectionName()).Where("targetAccountId", "==", targetAccountID).OrderBy("transactionDate", firestore.Desc)
-	iter := query.Documents(r.ctx)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -198,13 +225,26 @@ func (r *TransactionRepositoryImpl) FindByTargetAccountID(targetAccountID string
 	return transactions, nil
 }
 
-// CreateTransfer - Create a transfer transaction between two accounts using Firestore transaction
-func (r *TransactionRepositoryImpl) CreateTransfer(sourceAccountID, targetAccountID string, amount float64, description string) error {
+// CreateTransfer - Create a transfer transaction between two accounts using
+// Firestore transaction. Its span counts the attempts, as Firestore retries
+// the transaction when the accounts change under it.
+func (r *TransactionRepositoryImpl) CreateTransfer(ctx context.Context, sourceAccountID, targetAccountID string, amount float64, description string) (err error) {
+	defer observe("transactions", "CreateTransfer")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	ctx, span := startSpan(ctx, "transactions", "CreateTransfer")
+	defer func() { tracing.End(span, err) }()
+
 	sourceAccountRef := r.client.Collection(r.userID + "_accounts").Doc(sourceAccountID)
 	targetAccountRef := r.client.Collection(r.userID + "_accounts").Doc(targetAccountID)
 
 	// Use a Firestore transaction for atomic operation
-	return r.client.RunTransaction(r.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+	attempts := 0
+	defer func() { span.SetAttributes(attribute.Int("firestore.transaction.attempts", attempts)) }()
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		attempts++
+		span.AddEvent("transaction attempt", trace.WithAttributes(attribute.Int("attempt", attempts)))
+
 		// Get source account
 		sourceAccountDoc, err := tx.Get(sourceAccountRef)
 		if err != nil {
@@ -225,9 +265,14 @@ func (r *TransactionRepositoryImpl) CreateTransfer(sourceAccountID, targetAccoun
 			return err
 		}
 
+		// Refuse to move money into or out of a frozen account
+		if sourceAccount.FrozenAt != nil || targetAccount.FrozenAt != nil {
+			return interfaces.ErrAccountFrozen
+		}
+
 		// Check if source account has enough balance
 		if sourceAccount.Balance < amount {
-			return errors.New("insufficient balance in source account")
+			return interfaces.ErrInsufficientBalance
 		}
 
 		// Update account balances
@@ -7,6 +7,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
@@ -15,17 +16,20 @@ import (
 
 // UserRepositoryImpl - Implementation of the UserRepository interface
 type UserRepositoryImpl struct {
-	client *firestore.Client
-	ctx    context.Context
-	userID string
+	client   *firestore.Client
+	userID   string
+	keyring  *pii.Keyring
+	timeouts Timeouts
 }
 
-// NewUserRepository - Create a new user repository
-func NewUserRepository(client *firestore.Client, userID string) interfaces.UserRepository {
+// NewUserRepository - Create a new user repository. PII is encrypted with
+// keyring on write and decrypted on read.
+func NewUserRepository(client *firestore.Client, userID string, keyring *pii.Keyring, timeouts Timeouts) interfaces.UserRepository {
 	return &UserRepositoryImpl{
-		client: client,
-		ctx:    context.Background(),
-		userID: userID,
+		client:   client,
+		userID:   userID,
+		keyring:  keyring,
+		timeouts: timeouts,
 	}
 }
 
@@ -34,11 +38,27 @@ func (r *UserRepositoryImpl) getCollectionName() string {
 	return r.userID + "_users"
 }
 
+// decode - Read a user document and decrypt its PII
+func (r *UserRepositoryImpl) decode(doc *firestore.DocumentSnapshot) (models.User, error) {
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return models.User{}, err
+	}
+	if err := user.Decrypt(r.keyring); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
 // Create - Create a new user
-func (r *UserRepositoryImpl) Create(user models.User) (models.User, error) {
+func (r *UserRepositoryImpl) Create(ctx context.Context, user models.User) (models.User, error) {
+	defer observe("users", "Create")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Check if user already exists
-	query := r.client.Collection(r.getCollectionName()).Where("email", "==", user.Email).Limit(1)
-	iter := query.Documents(r.ctx)
+	query := r.client.Collection(r.getCollectionName()).Where("emailIndex", "==", r.keyring.EmailIndex(user.Email)).Limit(1)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	_, err := iter.Next()
@@ -55,14 +75,18 @@ func (r *UserRepositoryImpl) Create(user models.User) (models.User, error) {
 	user.UpdatedAt = now
 
 	// Add user to Firestore
-	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(r.ctx, user)
+	stored, err := user.Encrypted(r.keyring)
+	if err != nil {
+		return models.User{}, err
+	}
+	docRef, _, err := r.client.Collection(r.getCollectionName()).Add(ctx, stored)
 	if err != nil {
 		return models.User{}, err
 	}
 
 	// Update the user with the generated ID
 	user.ID = docRef.ID
-	_, err = docRef.Set(r.ctx, map[string]interface{}{
+	_, err = docRef.Set(ctx, map[string]interface{}{
 		"id": docRef.ID,
 	}, firestore.MergeAll)
 	if err != nil {
@@ -73,9 +97,13 @@ func (r *UserRepositoryImpl) Create(user models.User) (models.User, error) {
 }
 
 // FindByID - Find user by ID
-func (r *UserRepositoryImpl) FindByID(id string) (models.User, error) {
+func (r *UserRepositoryImpl) FindByID(ctx context.Context, id string) (models.User, error) {
+	defer observe("users", "FindByID")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
-	docSnapshot, err := docRef.Get(r.ctx)
+	docSnapshot, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return models.User{}, errors.New("user not found")
@@ -83,19 +111,18 @@ func (r *UserRepositoryImpl) FindByID(id string) (models.User, error) {
 		return models.User{}, err
 	}
 
-	var user models.User
-	err = docSnapshot.DataTo(&user)
-	if err != nil {
-		return models.User{}, err
-	}
-
-	return user, nil
+	return r.decode(docSnapshot)
 }
 
-// FindByEmail - Find user by email
-func (r *UserRepositoryImpl) FindByEmail(email string) (models.User, error) {
-	query := r.client.Collection(r.getCollectionName()).Where("email", "==", email).Limit(1)
-	iter := query.Documents(r.ctx)
+// FindByEmail - Find user by email, using the blind index as the email is encrypted.
+// Emails match regardless of case and surrounding spaces.
+func (r *UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	defer observe("users", "FindByEmail")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	query := r.client.Collection(r.getCollectionName()).Where("emailIndex", "==", r.keyring.EmailIndex(email)).Limit(1)
+	iter := query.Documents(ctx)
 	defer iter.Stop()
 
 	doc, err := iter.Next()
@@ -106,20 +133,18 @@ func (r *UserRepositoryImpl) FindByEmail(email string) (models.User, error) {
 		return models.User{}, err
 	}
 
-	var user models.User
-	err = doc.DataTo(&user)
-	if err != nil {
-		return models.User{}, err
-	}
-
-	return user, nil
+	return r.decode(doc)
 }
 
 // FindAll - Find all users
-func (r *UserRepositoryImpl) FindAll() ([]models.User, error) {
+func (r *UserRepositoryImpl) FindAll(ctx context.Context) ([]models.User, error) {
+	defer observe("users", "FindAll")()
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
 	var users []models.User
 
-	iter := r.client.Collection(r.getCollectionName()).Documents(r.ctx)
+	iter := r.client.Collection(r.getCollectionName()).Documents(ctx)
 	defer iter.Stop()
 
 	for {
@@ -131,8 +156,7 @@ func (r *UserRepositoryImpl) FindAll() ([]models.User, error) {
 			return nil, err
 		}
 
-		var user models.User
-		err = doc.DataTo(&user)
+		user, err := r.decode(doc)
 		if err != nil {
 			return nil, err
 		}
@@ -144,10 +168,14 @@ func (r *UserRepositoryImpl) FindAll() ([]models.User, error) {
 }
 
 // Update - Update a user
-func (r *UserRepositoryImpl) Update(user models.User) (models.User, error) {
+func (r *UserRepositoryImpl) Update(ctx context.Context, user models.User) (models.User, error) {
+	defer observe("users", "Update")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
 	// Check if user exists
 	docRef := r.client.Collection(r.getCollectionName()).Doc(user.ID)
-	_, err := docRef.Get(r.ctx)
+	_, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return models.User{}, errors.New("user not found")
@@ -157,7 +185,11 @@ func (r *UserRepositoryImpl) Update(user models.User) (models.User, error) {
 
 	// Update user
 	user.UpdatedAt = time.Now()
-	_, err = docRef.Set(r.ctx, user)
+	stored, err := user.Encrypted(r.keyring)
+	if err != nil {
+		return models.User{}, err
+	}
+	_, err = docRef.Set(ctx, stored)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -166,11 +198,231 @@ func (r *UserRepositoryImpl) Update(user models.User) (models.User, error) {
 }
 
 // Delete - Delete a user
-func (r *UserRepositoryImpl) Delete(id string) error {
-	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Delete(r.ctx)
+func (r *UserRepositoryImpl) Delete(ctx context.Context, id string) error {
+	defer observe("users", "Delete")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Delete(ctx)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// SetTwoFactorSecret - Store a new TOTP secret, encrypted, for an enrollment that is not yet confirmed
+func (r *UserRepositoryImpl) SetTwoFactorSecret(ctx context.Context, id string, secret string) error {
+	defer observe("users", "SetTwoFactorSecret")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	encrypted, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "twoFactorSecret", Value: encrypted},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	return err
+}
+
+// EnableTwoFactor - Turn on 2FA and replace the user's recovery codes
+func (r *UserRepositoryImpl) EnableTwoFactor(ctx context.Context, id string, recoveryCodeHashes []string) error {
+	defer observe("users", "EnableTwoFactor")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "twoFactorEnabled", Value: true},
+		{Path: "recoveryCodeHashes", Value: recoveryCodeHashes},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	return err
+}
+
+// ResetTwoFactor - Clear the TOTP secret and recovery codes so the user can
+// log in with a password alone and enroll again
+func (r *UserRepositoryImpl) ResetTwoFactor(ctx context.Context, id string) error {
+	defer observe("users", "ResetTwoFactor")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "twoFactorSecret", Value: ""},
+		{Path: "twoFactorEnabled", Value: false},
+		{Path: "twoFactorLastStep", Value: 0},
+		{Path: "recoveryCodeHashes", Value: []string{}},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	return err
+}
+
+// ConsumeTwoFactorStep - Record a TOTP time step as used. Returns false when a
+// code for the same or a later step was already accepted.
+func (r *UserRepositoryImpl) ConsumeTwoFactorStep(ctx context.Context, id string, step int64) (bool, error) {
+	defer observe("users", "ConsumeTwoFactorStep")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
+	consumed := false
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnapshot, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := docSnapshot.DataTo(&user); err != nil {
+			return err
+		}
+
+		consumed = user.TwoFactorLastStep < step
+		if !consumed {
+			return nil
+		}
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "twoFactorLastStep", Value: step},
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return consumed, nil
+}
+
+// ConsumeRecoveryCode - Remove an unused recovery code from the user. Returns
+// false when the user has no unused code with the given hash.
+func (r *UserRepositoryImpl) ConsumeRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error) {
+	defer observe("users", "ConsumeRecoveryCode")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
+	consumed := false
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnapshot, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := docSnapshot.DataTo(&user); err != nil {
+			return err
+		}
+
+		consumed = false
+		for _, hash := range user.RecoveryCodeHashes {
+			if hash == codeHash {
+				consumed = true
+				break
+			}
+		}
+		if !consumed {
+			return nil
+		}
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "recoveryCodeHashes", Value: firestore.ArrayRemove(codeHash)},
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return consumed, nil
+}
+
+// IncrementFailedLogins - Count a failed login and return the new count. The
+// count starts over when the previous failure happened before windowStart.
+func (r *UserRepositoryImpl) IncrementFailedLogins(ctx context.Context, id string, windowStart time.Time) (int, error) {
+	defer observe("users", "IncrementFailedLogins")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	docRef := r.client.Collection(r.getCollectionName()).Doc(id)
+	count := 0
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docSnapshot, err := tx.Get(docRef)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := docSnapshot.DataTo(&user); err != nil {
+			return err
+		}
+
+		count = user.FailedLoginCount + 1
+		if user.LastFailedLoginAt.Before(windowStart) {
+			count = 1
+		}
+		return tx.Update(docRef, []firestore.Update{
+			{Path: "failedLoginCount", Value: count},
+			{Path: "lastFailedLoginAt", Value: time.Now()},
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Lock - Refuse logins for the user until the given time
+func (r *UserRepositoryImpl) Lock(ctx context.Context, id string, until time.Time) error {
+	defer observe("users", "Lock")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "lockedUntil", Value: until},
+	})
+	return err
+}
+
+// ResetFailedLogins - Clear the failed login count and any lockout
+func (r *UserRepositoryImpl) ResetFailedLogins(ctx context.Context, id string) error {
+	defer observe("users", "ResetFailedLogins")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "failedLoginCount", Value: 0},
+		{Path: "lastFailedLoginAt", Value: time.Time{}},
+		{Path: "lockedUntil", Value: time.Time{}},
+	})
+	return err
+}
+
+// UpdatePassword - Replace the user's password hash
+func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	defer observe("users", "UpdatePassword")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "password", Value: passwordHash},
+		{Path: "updatedAt", Value: time.Now()},
+	})
+	return err
+}
+
+// Disable - Refuse logins for the user until further notice
+func (r *UserRepositoryImpl) Disable(ctx context.Context, id string) error {
+	defer observe("users", "Disable")()
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	now := time.Now()
+	_, err := r.client.Collection(r.getCollectionName()).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "disabledAt", Value: now},
+		{Path: "updatedAt", Value: now},
+	})
+	return err
+}
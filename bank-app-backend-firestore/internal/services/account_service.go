@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
@@ -22,14 +23,14 @@ func NewAccountService(repo interfaces.AccountRepository) *AccountService {
 }
 
 // Create - Create a new account
-func (s *AccountService) Create(account models.Account) (models.AccountDTO, error) {
+func (s *AccountService) Create(ctx context.Context, account models.Account) (models.AccountDTO, error) {
 	// Generate account number if not provided
 	if account.AccountNumber == "" {
 		account.AccountNumber = generateAccountNumber()
 	}
 
 	// Create the account
-	createdAccount, err := s.repo.Create(account)
+	createdAccount, err := s.repo.Create(ctx, account)
 	if err != nil {
 		return models.AccountDTO{}, err
 	}
@@ -38,8 +39,8 @@ func (s *AccountService) Create(account models.Account) (models.AccountDTO, erro
 }
 
 // GetByID - Get account by ID
-func (s *AccountService) GetByID(id string) (models.AccountDTO, error) {
-	account, err := s.repo.FindByID(id)
+func (s *AccountService) GetByID(ctx context.Context, id string) (models.AccountDTO, error) {
+	account, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return models.AccountDTO{}, err
 	}
@@ -48,8 +49,8 @@ func (s *AccountService) GetByID(id string) (models.AccountDTO, error) {
 }
 
 // GetByAccountNumber - Get account by account number
-func (s *AccountService) GetByAccountNumber(accountNumber string) (models.AccountDTO, error) {
-	account, err := s.repo.FindByAccountNumber(accountNumber)
+func (s *AccountService) GetByAccountNumber(ctx context.Context, accountNumber string) (models.AccountDTO, error) {
+	account, err := s.repo.FindByAccountNumber(ctx, accountNumber)
 	if err != nil {
 		return models.AccountDTO{}, err
 	}
@@ -58,8 +59,8 @@ func (s *AccountService) GetByAccountNumber(accountNumber string) (models.Accoun
 }
 
 // GetByUserID - Get accounts by user ID
-func (s *AccountService) GetByUserID(userID string) ([]models.AccountDTO, error) {
-	accounts, err := s.repo.FindByUserID(userID)
+func (s *AccountService) GetByUserID(ctx context.Context, userID string) ([]models.AccountDTO, error) {
+	accounts, err := s.repo.FindByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +74,8 @@ func (s *AccountService) GetByUserID(userID string) ([]models.AccountDTO, error)
 }
 
 // GetAll - Get all accounts
-func (s *AccountService) GetAll() ([]models.AccountDTO, error) {
-	accounts, err := s.repo.FindAll()
+func (s *AccountService) GetAll(ctx context.Context) ([]models.AccountDTO, error) {
+	accounts, err := s.repo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -88,8 +89,8 @@ func (s *AccountService) GetAll() ([]models.AccountDTO, error) {
 }
 
 // Update - Update an account
-func (s *AccountService) Update(account models.Account) (models.AccountDTO, error) {
-	updatedAccount, err := s.repo.Update(account)
+func (s *AccountService) Update(ctx context.Context, account models.Account) (models.AccountDTO, error) {
+	updatedAccount, err := s.repo.Update(ctx, account)
 	if err != nil {
 		return models.AccountDTO{}, err
 	}
@@ -98,13 +99,13 @@ func (s *AccountService) Update(account models.Account) (models.AccountDTO, erro
 }
 
 // Delete - Delete an account
-func (s *AccountService) Delete(id string) error {
-	return s.repo.Delete(id)
+func (s *AccountService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
 }
 
 // UpdateBalance - Update account balance
-func (s *AccountService) UpdateBalance(id string, amount float64) (models.AccountDTO, error) {
-	account, err := s.repo.UpdateBalance(id, amount)
+func (s *AccountService) UpdateBalance(ctx context.Context, id string, amount float64) (models.AccountDTO, error) {
+	account, err := s.repo.UpdateBalance(ctx, id, amount)
 	if err != nil {
 		return models.AccountDTO{}, err
 	}
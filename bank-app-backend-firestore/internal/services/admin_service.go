@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+)
+
+// AdminPasswordMinLength - Shortest password an administrator may set
+const AdminPasswordMinLength = 8
+
+var (
+	// ErrInvalidRole - Returned when creating a user with a role that does not exist
+	ErrInvalidRole = errors.New("role must be customer or admin")
+	// ErrWeakPassword - Returned for a password shorter than AdminPasswordMinLength
+	ErrWeakPassword = fmt.Errorf("password must be at least %d characters long", AdminPasswordMinLength)
+)
+
+// AdminService - Administrative tasks that have no API, run from the command line
+type AdminService struct {
+	userRepo    interfaces.UserRepository
+	accountRepo interfaces.AccountRepository
+}
+
+// NewAdminService - Create a new admin service
+func NewAdminService(userRepo interfaces.UserRepository, accountRepo interfaces.AccountRepository) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+	}
+}
+
+// CreateUser - Create a user with the given role and plain text password
+func (s *AdminService) CreateUser(ctx context.Context, user models.User, role string) (models.User, error) {
+	if role != models.RoleCustomer && role != models.RoleAdmin {
+		return models.User{}, ErrInvalidRole
+	}
+	user.Email = strings.TrimSpace(user.Email)
+	user.FirstName = strings.TrimSpace(user.FirstName)
+	user.LastName = strings.TrimSpace(user.LastName)
+	if user.Email == "" || user.FirstName == "" || user.LastName == "" {
+		return models.User{}, errors.New("email, first and last name are required")
+	}
+	if utf8.RuneCountInString(user.Password) < AdminPasswordMinLength {
+		return models.User{}, ErrWeakPassword
+	}
+
+	hashedPassword, err := models.GeneratePasswordHash(user.Password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Password = hashedPassword
+	user.Role = role
+
+	// The repository refuses emails that are taken
+	return s.userRepo.Create(ctx, user)
+}
+
+// DisableUser - Stop the user logging in. Tokens already issued stay valid
+// until they expire.
+func (s *AdminService) DisableUser(ctx context.Context, userID string) error {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.Disable(ctx, userID)
+}
+
+// ResetPassword - Replace the user's password
+func (s *AdminService) ResetPassword(ctx context.Context, userID, password string) error {
+	if utf8.RuneCountInString(password) < AdminPasswordMinLength {
+		return ErrWeakPassword
+	}
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	hashedPassword, err := models.GeneratePasswordHash(password)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.UpdatePassword(ctx, userID, hashedPassword)
+}
+
+// FreezeAccount - Stop money moving into or out of the account
+func (s *AdminService) FreezeAccount(ctx context.Context, accountID string) error {
+	return s.accountRepo.SetFrozen(ctx, accountID, true)
+}
+
+// UnfreezeAccount - Let money move into and out of the account again
+func (s *AdminService) UnfreezeAccount(ctx context.Context, accountID string) error {
+	return s.accountRepo.SetFrozen(ctx, accountID, false)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+)
+
+// ledgerTolerance - Absorbs float rounding when comparing balances, which are kept to the cent
+const ledgerTolerance = 0.005
+
+// LedgerService - Checks and end-of-day postings over all accounts
+type LedgerService struct {
+	accountRepo     interfaces.AccountRepository
+	transactionRepo interfaces.TransactionRepository
+}
+
+// NewLedgerService - Create a new ledger service
+func NewLedgerService(accountRepo interfaces.AccountRepository, transactionRepo interfaces.TransactionRepository) *LedgerService {
+	return &LedgerService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// Reconcile - Compare every account's balance with the sum of its transaction
+// amounts and with the running balance its newest transaction recorded
+func (s *LedgerService) Reconcile(ctx context.Context) (models.ReconcileResult, error) {
+	accounts, err := s.accountRepo.FindAll(ctx)
+	if err != nil {
+		return models.ReconcileResult{}, err
+	}
+	transactions, err := s.transactionRepo.FindAll(ctx)
+	if err != nil {
+		return models.ReconcileResult{}, err
+	}
+
+	// Amounts are signed, so an account's transactions add up to its balance
+	totals := make(map[string]*models.Discrepancy)
+	newest := make(map[string]models.Transaction)
+	for _, transaction := range transactions {
+		total, ok := totals[transaction.AccountID]
+		if !ok {
+			total = &models.Discrepancy{}
+			totals[transaction.AccountID] = total
+		}
+		total.LedgerTotal += transaction.Amount
+		total.Entries++
+		if latest, ok := newest[transaction.AccountID]; !ok || isNewer(transaction, latest) {
+			newest[transaction.AccountID] = transaction
+			total.LastBalance = transaction.Balance
+		}
+	}
+
+	result := models.ReconcileResult{Checked: len(accounts), Discrepancies: []models.Discrepancy{}}
+	for _, account := range accounts {
+		d := models.Discrepancy{AccountID: account.ID, AccountNumber: account.AccountNumber, Balance: account.Balance}
+		if total, ok := totals[account.ID]; ok {
+			d.LedgerTotal, d.LastBalance, d.Entries = total.LedgerTotal, total.LastBalance, total.Entries
+		}
+		matches := math.Abs(d.Balance-d.LedgerTotal) < ledgerTolerance
+		if d.Entries > 0 && math.Abs(d.Balance-d.LastBalance) >= ledgerTolerance {
+			matches = false
+		}
+		if !matches {
+			result.Discrepancies = append(result.Discrepancies, d)
+		}
+	}
+	return result, nil
+}
+
+// isNewer - Whether a was made after b. Transactions of one transfer share a
+// date, so creation time breaks the tie.
+func isNewer(a, b models.Transaction) bool {
+	if !a.TransactionDate.Equal(b.TransactionDate) {
+		return a.TransactionDate.After(b.TransactionDate)
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// AccrueInterest - Credit a day of interest at annualRate percent to every
+// savings account with a positive balance. Each account is credited once per
+// date, so a failed run can be repeated.
+func (s *LedgerService) AccrueInterest(ctx context.Context, date time.Time, annualRate float64) (models.InterestResult, error) {
+	if annualRate <= 0 {
+		return models.InterestResult{}, fmt.Errorf("interest rate must be positive, got %g", annualRate)
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	day := date.Format("2006-01-02")
+
+	accounts, err := s.accountRepo.FindAll(ctx)
+	if err != nil {
+		return models.InterestResult{}, err
+	}
+
+	result := models.InterestResult{Date: date}
+	for _, account := range accounts {
+		if account.AccountType != models.Savings || account.Balance <= 0 {
+			continue
+		}
+		amount := math.Round(account.Balance*annualRate/100/365*100) / 100
+		if amount < 0.01 {
+			result.Skipped++
+			continue
+		}
+
+		key := fmt.Sprintf("interest_%s_%s", account.ID, day)
+		credited, err := s.accountRepo.CreditOnce(ctx, account.ID, key, amount, "Interest for "+day, date)
+		if err != nil {
+			return result, fmt.Errorf("account %s: %w", account.AccountNumber, err)
+		}
+		if !credited {
+			result.Skipped++
+			continue
+		}
+		result.Credited++
+		result.Total += amount
+	}
+	result.Total = math.Round(result.Total*100) / 100
+	return result, nil
+}
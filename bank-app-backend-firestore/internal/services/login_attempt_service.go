@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+)
+
+const (
+	maxLoginDelay      = 30 * time.Second
+	loginAttemptsLimit = 100
+)
+
+// LoginAttemptOptions - Settings used by the login attempt service
+type LoginAttemptOptions struct {
+	MaxFailures     int           // Failed logins that lock an account
+	FailureWindow   time.Duration // Failures older than this are forgotten
+	LockoutDuration time.Duration
+	IPMaxFailures   int           // Failed logins from one address that block it for the failure window
+	DelayBase       time.Duration // Wait after the first failure, doubled after every further failure
+}
+
+// LoginThrottledError - Returned while an account is locked or a client has to wait before trying again
+type LoginThrottledError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// LoginAttemptService - Service for login attempt tracking and account lockout
+type LoginAttemptService struct {
+	attemptRepo interfaces.LoginAttemptRepository
+	userRepo    interfaces.UserRepository
+	options     LoginAttemptOptions
+}
+
+// NewLoginAttemptService - Create a new login attempt service
+func NewLoginAttemptService(attemptRepo interfaces.LoginAttemptRepository, userRepo interfaces.UserRepository, options LoginAttemptOptions) *LoginAttemptService {
+	return &LoginAttemptService{
+		attemptRepo: attemptRepo,
+		userRepo:    userRepo,
+		options:     options,
+	}
+}
+
+// CheckAllowed - Return a *LoginThrottledError when the account is locked, the
+// user has to wait after recent failures, or the address made too many failed
+// attempts. Unknown emails are only limited by address.
+func (s *LoginAttemptService) CheckAllowed(ctx context.Context, email, ipAddress string) error {
+	err := s.checkAllowed(ctx, email, ipAddress)
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		metrics.Logins.WithLabelValues(metrics.LoginThrottled).Inc()
+	}
+	return err
+}
+
+func (s *LoginAttemptService) checkAllowed(ctx context.Context, email, ipAddress string) error {
+	now := time.Now()
+
+	if user, err := s.userRepo.FindByEmail(ctx, email); err == nil {
+		if now.Before(user.LockedUntil) {
+			return &LoginThrottledError{
+				Reason:     "account is temporarily locked",
+				RetryAfter: user.LockedUntil.Sub(now),
+			}
+		}
+
+		if user.FailedLoginCount > 0 && now.Sub(user.LastFailedLoginAt) < s.options.FailureWindow {
+			next := user.LastFailedLoginAt.Add(s.delay(user.FailedLoginCount))
+			if now.Before(next) {
+				return &LoginThrottledError{
+					Reason:     "too many failed login attempts",
+					RetryAfter: next.Sub(now),
+				}
+			}
+		}
+	}
+
+	if s.options.IPMaxFailures > 0 {
+		failures, err := s.attemptRepo.CountFailuresByIP(ctx, ipAddress, now.Add(-s.options.FailureWindow))
+		if err != nil {
+			return err
+		}
+		if failures >= s.options.IPMaxFailures {
+			return &LoginThrottledError{
+				Reason:     "too many failed login attempts from this address",
+				RetryAfter: s.options.FailureWindow,
+			}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure - Store a failed attempt and lock the account once it reaches
+// the configured number of failures within the window
+func (s *LoginAttemptService) RecordFailure(ctx context.Context, email, ipAddress, userAgent, reason string) error {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Reason:    reason,
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+
+	if user, err := s.userRepo.FindByEmail(ctx, email); err == nil {
+		attempt.UserID = user.ID
+
+		now := time.Now()
+		count, err := s.userRepo.IncrementFailedLogins(ctx, user.ID, now.Add(-s.options.FailureWindow))
+		if err != nil {
+			return err
+		}
+		if s.options.MaxFailures > 0 && count >= s.options.MaxFailures {
+			lockedUntil := now.Add(s.options.LockoutDuration)
+			if err := s.userRepo.Lock(ctx, user.ID, lockedUntil); err != nil {
+				return err
+			}
+			slog.Warn("account locked after failed logins",
+				"target_user_id", user.ID, "failures", count, "locked_until", lockedUntil)
+		}
+	}
+
+	_, err := s.attemptRepo.Create(ctx, attempt)
+	return err
+}
+
+// RecordSuccess - Store a successful attempt and clear the user's failures
+func (s *LoginAttemptService) RecordSuccess(ctx context.Context, user models.User, ipAddress, userAgent string) error {
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
+	if user.FailedLoginCount > 0 || !user.LockedUntil.IsZero() {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.attemptRepo.Create(ctx, models.LoginAttempt{
+		UserID:    user.ID,
+		Email:     user.Email,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Success:   true,
+	})
+	return err
+}
+
+// Unlock - Lift a lockout before it expires
+func (s *LoginAttemptService) Unlock(ctx context.Context, userID string) error {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.ResetFailedLogins(ctx, userID)
+}
+
+// GetAttemptsByUserID - Get the most recent login attempts against a user's account
+func (s *LoginAttemptService) GetAttemptsByUserID(ctx context.Context, userID string) ([]models.LoginAttempt, error) {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.attemptRepo.FindByUserID(ctx, userID, loginAttemptsLimit)
+}
+
+// delay - How long a user has to wait after the given number of consecutive failures
+func (s *LoginAttemptService) delay(failures int) time.Duration {
+	delay := s.options.DelayBase
+	for i := 1; i < failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	return delay
+}
@@ -1,11 +1,26 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrSameAccount - Returned for a transfer to the account it is made from
+	ErrSameAccount = errors.New("cannot transfer to the same account")
+	// ErrInvalidAmount - Returned for a transfer of zero or less
+	ErrInvalidAmount = errors.New("transfer amount must be positive")
+	// ErrSourceAccountNotFound - Returned when the account to transfer from does not exist
+	ErrSourceAccountNotFound = errors.New("source account not found")
+	// ErrTargetAccountNotFound - Returned when the account to transfer to does not exist
+	ErrTargetAccountNotFound = errors.New("target account not found")
 )
 
 // TransactionService - Service for transaction operations
@@ -23,12 +38,15 @@ func NewTransactionService(transactionRepo interfaces.TransactionRepository, acc
 }
 
 // Create - Create a new transaction
-func (s *TransactionService) Create(transaction models.Transaction) (models.TransactionDTO, error) {
+func (s *TransactionService) Create(ctx context.Context, transaction models.Transaction) (models.TransactionDTO, error) {
 	// Get the account
-	account, err := s.accountRepo.FindByID(transaction.AccountID)
+	account, err := s.accountRepo.FindByID(ctx, transaction.AccountID)
 	if err != nil {
 		return models.TransactionDTO{}, err
 	}
+	if account.FrozenAt != nil {
+		return models.TransactionDTO{}, interfaces.ErrAccountFrozen
+	}
 
 	// Update account balance
 	newBalance := account.Balance + transaction.Amount
@@ -39,13 +57,13 @@ func (s *TransactionService) Create(transaction models.Transaction) (models.Tran
 	transaction.Balance = newBalance
 
 	// Save transaction
-	createdTransaction, err := s.transactionRepo.Create(transaction)
+	createdTransaction, err := s.transactionRepo.Create(ctx, transaction)
 	if err != nil {
 		return models.TransactionDTO{}, err
 	}
 
 	// Update account in database
-	_, err = s.accountRepo.Update(account)
+	_, err = s.accountRepo.Update(ctx, account)
 	if err != nil {
 		// Here we should ideally roll back the transaction, but for simplicity we'll just return an error
 		return models.TransactionDTO{}, errors.New("failed to update account balance: " + err.Error())
@@ -55,8 +73,8 @@ func (s *TransactionService) Create(transaction models.Transaction) (models.Tran
 }
 
 // GetByID - Get transaction by ID
-func (s *TransactionService) GetByID(id string) (models.TransactionDTO, error) {
-	transaction, err := s.transactionRepo.FindByID(id)
+func (s *TransactionService) GetByID(ctx context.Context, id string) (models.TransactionDTO, error) {
+	transaction, err := s.transactionRepo.FindByID(ctx, id)
 	if err != nil {
 		return models.TransactionDTO{}, err
 	}
@@ -65,8 +83,8 @@ func (s *TransactionService) GetByID(id string) (models.TransactionDTO, error) {
 }
 
 // GetByAccountID - Get transactions by account ID
-func (s *TransactionService) GetByAccountID(accountID string) ([]models.TransactionDTO, error) {
-	transactions, err := s.transactionRepo.FindByAccountID(accountID)
+func (s *TransactionService) GetByAccountID(ctx context.Context, accountID string) ([]models.TransactionDTO, error) {
+	transactions, err := s.transactionRepo.FindByAccountID(ctx, accountID)
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +98,8 @@ func (s *TransactionService) GetByAccountID(accountID string) ([]models.Transact
 }
 
 // GetAll - Get all transactions
-func (s *TransactionService) GetAll() ([]models.TransactionDTO, error) {
-	transactions, err := s.transactionRepo.FindAll()
+func (s *TransactionService) GetAll(ctx context.Context) ([]models.TransactionDTO, error) {
+	transactions, err := s.transactionRepo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -95,28 +113,63 @@ func (s *TransactionService) GetAll() ([]models.TransactionDTO, error) {
 }
 
 // Transfer - Transfer funds between accounts
-func (s *TransactionService) Transfer(req models.TransferRequest) error {
+func (s *TransactionService) Transfer(ctx context.Context, req models.TransferRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.Transfer",
+		attribute.String("transfer.from_account_id", req.FromAccountID),
+		attribute.String("transfer.to_account_id", req.ToAccountID),
+		attribute.Float64("transfer.amount", req.Amount),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.transfer(ctx, req); err != nil {
+		metrics.ObserveTransferFailure(transferFailureReason(err))
+		return err
+	}
+	metrics.ObserveTransfer(metrics.TransferCompleted, req.Amount)
+	return nil
+}
+
+func (s *TransactionService) transfer(ctx context.Context, req models.TransferRequest) error {
 	// Validate accounts
 	if req.FromAccountID == req.ToAccountID {
-		return errors.New("cannot transfer to the same account")
+		return ErrSameAccount
 	}
 
 	// Validate amount
 	if req.Amount <= 0 {
-		return errors.New("transfer amount must be positive")
+		return ErrInvalidAmount
 	}
 
 	// Check if accounts exist
-	_, err := s.accountRepo.FindByID(req.FromAccountID)
+	_, err := s.accountRepo.FindByID(ctx, req.FromAccountID)
 	if err != nil {
-		return errors.New("source account not found")
+		return ErrSourceAccountNotFound
 	}
 
-	_, err = s.accountRepo.FindByID(req.ToAccountID)
+	_, err = s.accountRepo.FindByID(ctx, req.ToAccountID)
 	if err != nil {
-		return errors.New("target account not found")
+		return ErrTargetAccountNotFound
 	}
 
 	// Perform the transfer using transaction repository's atomic transaction function
-	return s.transactionRepo.CreateTransfer(req.FromAccountID, req.ToAccountID, req.Amount, req.Description)
+	return s.transactionRepo.CreateTransfer(ctx, req.FromAccountID, req.ToAccountID, req.Amount, req.Description)
+}
+
+// transferFailureReason - Why a transfer failed, from a fixed set of values so
+// that error messages do not become metric labels
+func transferFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrSameAccount):
+		return "same_account"
+	case errors.Is(err, ErrInvalidAmount):
+		return "invalid_amount"
+	case errors.Is(err, ErrSourceAccountNotFound), errors.Is(err, ErrTargetAccountNotFound):
+		return "account_not_found"
+	case errors.Is(err, interfaces.ErrInsufficientBalance):
+		return "insufficient_funds"
+	case errors.Is(err, interfaces.ErrAccountFrozen):
+		return "account_frozen"
+	default:
+		return "error"
+	}
 }
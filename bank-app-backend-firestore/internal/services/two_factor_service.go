@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+	challengeAudience = "login-2fa"
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled - Returned when enrolling a user who already has 2FA
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrInvalidTwoFactorCode - Returned for wrong, expired or replayed codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// TwoFactorOptions - Settings for the two-factor service
+type TwoFactorOptions struct {
+	KeySet       *signing.KeySet // Signs and verifies login challenges
+	Issuer       string          // Shown by authenticator apps
+	ChallengeTTL time.Duration   // How long a login challenge can be completed for
+}
+
+// TwoFactorService - Service for TOTP two-factor authentication
+type TwoFactorService struct {
+	repo    interfaces.UserRepository
+	options TwoFactorOptions
+}
+
+// NewTwoFactorService - Create a new two-factor service
+func NewTwoFactorService(repo interfaces.UserRepository, options TwoFactorOptions) *TwoFactorService {
+	return &TwoFactorService{
+		repo:    repo,
+		options: options,
+	}
+}
+
+// Enroll - Generate a new TOTP secret. It only takes effect once confirmed.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID string) (models.TwoFactorEnrollment, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	if user.TwoFactorEnabled {
+		return models.TwoFactorEnrollment{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.options.Issuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+
+	if err := s.repo.SetTwoFactorSecret(ctx, user.ID, key.Secret()); err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+
+	return models.TwoFactorEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+	}, nil
+}
+
+// Confirm - Enable 2FA with a code from the authenticator and return new recovery codes
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+
+	if err := s.useTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	// Only the 2FA fields are written, so the step recorded by useTOTP is kept
+	if err := s.repo.EnableTwoFactor(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CreateChallenge - Issue the short-lived token that links the two login steps
+func (s *TwoFactorService) CreateChallenge(ctx context.Context, user models.User) (models.TwoFactorChallenge, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.options.ChallengeTTL)
+	claims := &jwt.RegisteredClaims{
+		Subject:   user.ID,
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	tokenString, err := s.options.KeySet.Sign(claims)
+	if err != nil {
+		return models.TwoFactorChallenge{}, err
+	}
+
+	return models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    tokenString,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// ChallengedUser - Find the user a login challenge was issued to, who must still have 2FA enabled.
+// Only tokens for the login-2fa audience are accepted, so access tokens cannot stand in for a challenge.
+// The code is verified separately so the caller can check the user may log in before a code is used up.
+func (s *TwoFactorService) ChallengedUser(ctx context.Context, challengeToken string) (models.User, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(challengeToken, claims, s.options.KeySet.Keyfunc)
+	if err != nil || !token.Valid || !claims.VerifyAudience(challengeAudience, true) {
+		return models.User{}, errors.New("invalid or expired challenge")
+	}
+
+	user, err := s.repo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !user.TwoFactorEnabled {
+		return models.User{}, errors.New("two-factor authentication is not enabled")
+	}
+	return user, nil
+}
+
+// VerifyLoginCode - Complete a two-factor login with a TOTP code or an unused recovery code, using it up.
+// A wrong code returns ErrInvalidTwoFactorCode.
+func (s *TwoFactorService) VerifyLoginCode(ctx context.Context, user models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return s.useTOTP(ctx, user, code)
+	}
+
+	consumed, err := s.repo.ConsumeRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Reset - Remove the user's second factor, e.g. after they lost their device
+func (s *TwoFactorService) Reset(ctx context.Context, userID string) error {
+	if _, err := s.repo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.repo.ResetTwoFactor(ctx, userID)
+}
+
+// useTOTP - Validate a code and consume its time step so it cannot be replayed
+func (s *TwoFactorService) useTOTP(ctx context.Context, user models.User, code string) error {
+	step, ok := matchTOTPStep(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	consumed, err := s.repo.ConsumeTwoFactorStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// matchTOTPStep - Return the time step a code belongs to, allowing one step of clock drift
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCode - Return a code formatted as two groups of five characters
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode - Hash a recovery code ignoring case and separators
+func hashRecoveryCode(code string) string {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"context"
+	"errors"
+
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 )
 
+// ErrUserDisabled - Returned by Authenticate for a user an administrator has disabled
+var ErrUserDisabled = errors.New("user is disabled")
+
 // UserService - Service for user operations
 type UserService struct {
 	repo interfaces.UserRepository
@@ -18,7 +24,7 @@ func NewUserService(repo interfaces.UserRepository) *UserService {
 }
 
 // Create - Create a new user
-func (s *UserService) Create(user models.User) (models.UserDTO, error) {
+func (s *UserService) Create(ctx context.Context, user models.User) (models.UserDTO, error) {
 	// Hash the password
 	hashedPassword, err := models.GeneratePasswordHash(user.Password)
 	if err != nil {
@@ -27,7 +33,7 @@ func (s *UserService) Create(user models.User) (models.UserDTO, error) {
 	user.Password = hashedPassword
 
 	// Create the user
-	createdUser, err := s.repo.Create(user)
+	createdUser, err := s.repo.Create(ctx, user)
 	if err != nil {
 		return models.UserDTO{}, err
 	}
@@ -36,8 +42,8 @@ func (s *UserService) Create(user models.User) (models.UserDTO, error) {
 }
 
 // GetByID - Get user by ID
-func (s *UserService) GetByID(id string) (models.UserDTO, error) {
-	user, err := s.repo.FindByID(id)
+func (s *UserService) GetByID(ctx context.Context, id string) (models.UserDTO, error) {
+	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return models.UserDTO{}, err
 	}
@@ -46,13 +52,13 @@ func (s *UserService) GetByID(id string) (models.UserDTO, error) {
 }
 
 // GetByEmail - Get user by email
-func (s *UserService) GetByEmail(email string) (models.User, error) {
-	return s.repo.FindByEmail(email)
+func (s *UserService) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return s.repo.FindByEmail(ctx, email)
 }
 
 // GetAll - Get all users
-func (s *UserService) GetAll() ([]models.UserDTO, error) {
-	users, err := s.repo.FindAll()
+func (s *UserService) GetAll(ctx context.Context) ([]models.UserDTO, error) {
+	users, err := s.repo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +72,7 @@ func (s *UserService) GetAll() ([]models.UserDTO, error) {
 }
 
 // Update - Update a user
-func (s *UserService) Update(user models.User) (models.UserDTO, error) {
+func (s *UserService) Update(ctx context.Context, user models.User) (models.UserDTO, error) {
 	// Check if password needs to be updated
 	if user.Password != "" {
 		hashedPassword, err := models.GeneratePasswordHash(user.Password)
@@ -77,7 +83,7 @@ func (s *UserService) Update(user models.User) (models.UserDTO, error) {
 	}
 
 	// Update the user
-	updatedUser, err := s.repo.Update(user)
+	updatedUser, err := s.repo.Update(ctx, user)
 	if err != nil {
 		return models.UserDTO{}, err
 	}
@@ -86,14 +92,14 @@ func (s *UserService) Update(user models.User) (models.UserDTO, error) {
 }
 
 // Delete - Delete a user
-func (s *UserService) Delete(id string) error {
-	return s.repo.Delete(id)
+func (s *UserService) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
 }
 
 // Authenticate - Authenticate a user
-func (s *UserService) Authenticate(email, password string) (models.User, error) {
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (models.User, error) {
 	// Find user by email
-	user, err := s.repo.FindByEmail(email)
+	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -103,5 +109,10 @@ func (s *UserService) Authenticate(email, password string) (models.User, error)
 		return models.User{}, err
 	}
 
+	// Only tell a disabled user so once they have proven who they are
+	if user.IsDisabled() {
+		return models.User{}, ErrUserDisabled
+	}
+
 	return user, nil
 }
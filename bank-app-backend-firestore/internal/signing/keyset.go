@@ -0,0 +1,246 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported asymmetric algorithms, chosen by key type
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const minRSAKeyBits = 2048
+
+// Key - A signing or verification key identified by the kid header of the tokens it signs
+type Key struct {
+	ID        string
+	Algorithm string
+	private   interface{} // nil for keys that only verify
+	public    interface{}
+}
+
+// JSONWebKey - Public part of a key as published in the JWKS document
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA public exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JSONWebKeySet - Response body of /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet - Sign tokens with one active key and verify tokens signed by any
+// key it holds, so keys can be rotated without invalidating issued tokens.
+// A KeySet created from a shared secret signs with HS256 and publishes no keys.
+type KeySet struct {
+	signingKey *Key
+	keys       map[string]*Key
+	secret     []byte
+}
+
+// New - Load the keys in keysDir, or fall back to HMAC with secret when no
+// directory is configured
+func New(keysDir, signingKeyID, secret string) (*KeySet, error) {
+	if keysDir == "" {
+		return NewHMACKeySet(secret), nil
+	}
+	return LoadKeySet(keysDir, signingKeyID)
+}
+
+// NewHMACKeySet - Return a key set that signs and verifies with a shared secret
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{secret: []byte(secret)}
+}
+
+// LoadKeySet - Read every <kid>.pem file in dir. Files may hold an RSA or
+// Ed25519 private key, or a public key that is only used for verification.
+// Tokens are signed with signingKeyID, or with the private key whose kid sorts
+// last when it is empty, so date-based kids rotate by adding a new file.
+func LoadKeySet(dir, signingKeyID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: map[string]*Key{}}
+	var signingCandidates []string
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %v", path, err)
+		}
+		ks.keys[key.ID] = key
+		if key.private != nil {
+			signingCandidates = append(signingCandidates, key.ID)
+		}
+	}
+
+	if signingKeyID == "" {
+		if len(signingCandidates) == 0 {
+			return nil, fmt.Errorf("no private keys found in %s", dir)
+		}
+		sort.Strings(signingCandidates)
+		signingKeyID = signingCandidates[len(signingCandidates)-1]
+	}
+
+	key, ok := ks.keys[signingKeyID]
+	if !ok || key.private == nil {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKeyID, dir)
+	}
+	ks.signingKey = key
+
+	return ks, nil
+}
+
+// SigningKeyID - Return the kid of the key new tokens are signed with, or "" for HMAC
+func (ks *KeySet) SigningKeyID() string {
+	if ks.signingKey == nil {
+		return ""
+	}
+	return ks.signingKey.ID
+}
+
+// Sign - Sign the claims with the active key and set the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signingKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+
+	token := jwt.NewWithClaims(signingMethod(ks.signingKey.Algorithm), claims)
+	token.Header["kid"] = ks.signingKey.ID
+	return token.SignedString(ks.signingKey.private)
+}
+
+// Keyfunc - Resolve the verification key of a token for jwt.Parse. The token's
+// algorithm must match the key so that a public key is never used as an HMAC
+// secret.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks.signingKey == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.public, nil
+}
+
+// JWKS - Return the public keys that verify tokens, sorted by kid
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+func (k *Key) jwk() JSONWebKey {
+	jwk := JSONWebKey{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.public = parsed
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	switch private := key.private.(type) {
+	case *rsa.PrivateKey:
+		key.public = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.public = private.Public()
+	case nil:
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		key.Algorithm = AlgorithmRS256
+	case ed25519.PublicKey:
+		key.Algorithm = AlgorithmEdDSA
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+
+	return key, nil
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
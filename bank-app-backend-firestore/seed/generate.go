@@ -0,0 +1,60 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+)
+
+// Generate - Simulate customers and their history with Simulate and add them
+// to the collections of userID, keeping the existing documents. Each batch of
+// customers is written with its own BulkWriter, so an interrupted run keeps
+// the batches it finished; running it again with another email prefix adds
+// more customers.
+func Generate(ctx context.Context, client *firestore.Client, userID string, keyring *pii.Keyring, opts SimulateOptions) (*Summary, error) {
+	taken, err := takenNumbers(ctx, client.Collection(userID+"_accounts"))
+	if err != nil {
+		return nil, err
+	}
+	opts.TakenNumbers = taken
+
+	total := &Summary{}
+	err = Simulate(opts, func(plan *Plan) error {
+		emails := make([]string, len(plan.Users))
+		for i, user := range plan.Users {
+			emails[i] = user.Email
+		}
+		existing, err := existingUsers(ctx, client.Collection(userID+"_users"), keyring, emails)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("%d of the customers already exist, starting with %s; generate them with another email prefix", len(existing), firstExisting(emails, existing))
+		}
+
+		summary, err := write(ctx, client, userID, keyring, plan)
+		if err != nil {
+			return err
+		}
+		total.Users += summary.Users
+		total.Accounts += summary.Accounts
+		total.Transactions += summary.Transactions
+		slog.Info("Generated customers", "customers", total.Users, "of", opts.Customers, "transactions", total.Transactions)
+		return nil
+	})
+	return total, err
+}
+
+// firstExisting - The first of the emails that exists
+func firstExisting(emails []string, existing map[string]bool) string {
+	for _, email := range emails {
+		if existing[strings.ToLower(email)] {
+			return email
+		}
+	}
+	return ""
+}
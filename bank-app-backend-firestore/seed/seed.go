@@ -2,204 +2,257 @@ package seed
 
 import (
 	"context"
-	"log"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
 )
 
-// SeedDatabase - Seed the database with initial data
-func SeedDatabase(client *firestore.Client, userID string) error {
-	ctx := context.Background()
+// inQueryLimit - Most values Firestore compares in one "in" query
+const inQueryLimit = 30
 
+// Options - How Seed loads a fixture
+type Options struct {
+	SeedValue int64 // Seeds the random activity and account numbers
+	Additive  bool  // Keep the existing data and skip users that already exist
+}
+
+// Summary - What Seed loaded
+type Summary struct {
+	Users        int
+	Accounts     int
+	Transactions int
+	Skipped      []string // Emails of the users that already existed
+}
+
+// SeedDatabase - Seed the database with the demo profile, encrypting user PII with keyring
+func SeedDatabase(client *firestore.Client, userID string, keyring *pii.Keyring) error {
+	fixture, err := LoadFixture(ProfileDemo)
+	if err != nil {
+		return err
+	}
+	_, err = Seed(context.Background(), client, userID, keyring, fixture, Options{SeedValue: 1})
+	return err
+}
+
+// Seed - Load a fixture into the collections of userID. Unless opts.Additive
+// is set it first deletes all documents, so the result is the same on every
+// run with the same seed value on the same day. Firestore ignores the kyc_level
+// and unverified fields of a fixture, as this backend has neither.
+func Seed(ctx context.Context, client *firestore.Client, userID string, keyring *pii.Keyring, fixture *Fixture, opts Options) (*Summary, error) {
 	usersCol := userID + "_users"
 	accountsCol := userID + "_accounts"
 	transactionsCol := userID + "_transactions"
+	loginAttemptsCol := userID + "_login_attempts"
 
-	// Clear existing data in collections before seeding using BulkWriter
-	collections := []string{usersCol, accountsCol, transactionsCol}
-	for _, col := range collections {
-		colRef := client.Collection(col)
-		for {
-			docs, err := colRef.Limit(100).Documents(ctx).GetAll()
-			if err != nil {
-				return err
-			}
-			if len(docs) == 0 {
-				break
-			}
-
-			bulkWriter := client.BulkWriter(ctx)
-			for _, doc := range docs {
-				bulkWriter.Delete(doc.Ref)
+	planOpts := PlanOptions{SeedValue: opts.SeedValue}
+	if opts.Additive {
+		emails := make([]string, len(fixture.Users))
+		for i, user := range fixture.Users {
+			emails[i] = user.Email
+		}
+		existing, err := existingUsers(ctx, client.Collection(usersCol), keyring, emails)
+		if err != nil {
+			return nil, err
+		}
+		taken, err := takenNumbers(ctx, client.Collection(accountsCol))
+		if err != nil {
+			return nil, err
+		}
+		planOpts.ExistingUsers = existing
+		planOpts.TakenNumbers = taken
+	} else {
+		for _, col := range []string{usersCol, accountsCol, transactionsCol, loginAttemptsCol} {
+			if err := clearCollection(ctx, client, client.Collection(col)); err != nil {
+				return nil, err
 			}
-			bulkWriter.End()
 		}
 	}
 
-	// Check if users collection already has data
-	usersRef := client.Collection(usersCol)
-	usersDocs, err := usersRef.Limit(1).Documents(ctx).GetAll()
-	if err != nil && status.Code(err) != codes.NotFound {
-		return err
+	plan, err := BuildPlan(fixture, planOpts)
+	if err != nil {
+		return nil, err
 	}
+	return write(ctx, client, userID, keyring, plan)
+}
 
-	// If users collection is not empty, don't seed
-	if len(usersDocs) > 0 {
-		log.Println("Database already seeded, skipping...")
+// write - Write the plan's users, accounts and transactions with one
+// BulkWriter. Users sharing a password share its hash, as bcrypt is slow
+// enough to dominate a large plan otherwise.
+func write(ctx context.Context, client *firestore.Client, userID string, keyring *pii.Keyring, plan *Plan) (*Summary, error) {
+	now := time.Now()
+	bulkWriter := client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	set := func(ref *firestore.DocumentRef, datum interface{}) error {
+		job, err := bulkWriter.Set(ref, datum)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
 		return nil
 	}
 
-	log.Println("Seeding database...")
-
-	// Create demo users
-	users := []models.User{
-		{
-			Email:     "john.doe@example.com",
-			Password:  "password123",
-			FirstName: "John",
-			LastName:  "Doe",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		{
-			Email:     "jane.smith@example.com",
-			Password:  "password123",
-			FirstName: "Jane",
-			LastName:  "Smith",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-	}
+	hashes := map[string]string{}
+	userIDs := make([]string, len(plan.Users))
+	for i, u := range plan.Users {
+		if _, ok := hashes[u.Password]; !ok {
+			hashed, err := models.GeneratePasswordHash(u.Password)
+			if err != nil {
+				bulkWriter.End()
+				return nil, err
+			}
+			hashes[u.Password] = hashed
+		}
+		role := models.RoleCustomer
+		if u.Role != "" {
+			role = u.Role
+		}
 
-	// Encrypt passwords before inserting users
-	for i, user := range users {
-		hashed, err := models.GeneratePasswordHash(user.Password)
+		userRef := client.Collection(userID + "_users").NewDoc()
+		userIDs[i] = userRef.ID
+		stored, err := models.User{
+			ID:        userRef.ID,
+			Email:     u.Email,
+			Password:  hashes[u.Password],
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Role:      role,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}.Encrypted(keyring)
+		if err == nil {
+			err = set(userRef, stored)
+		}
 		if err != nil {
-			return err
+			bulkWriter.End()
+			return nil, err
 		}
-		users[i].Password = hashed
 	}
 
-	// Create users in batch using BulkWriter
-	bulkWriter := client.BulkWriter(ctx)
-	userIDs := make([]string, len(users))
+	// Accounts are written with their final balance, since every entry is known
+	accountIDs := make([]string, len(plan.Accounts))
+	for i, a := range plan.Accounts {
+		accountRef := client.Collection(userID + "_accounts").NewDoc()
+		accountIDs[i] = accountRef.ID
+		if err := set(accountRef, models.Account{
+			ID:            accountRef.ID,
+			UserID:        userIDs[a.User],
+			AccountNumber: a.Number,
+			AccountType:   models.AccountType(a.Type),
+			Balance:       a.Balance,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}); err != nil {
+			bulkWriter.End()
+			return nil, err
+		}
+	}
 
-	for i, user := range users {
-		userRef := client.Collection(usersCol).NewDoc()
-		userIDs[i] = userRef.ID
-		user.ID = userRef.ID
-		bulkWriter.Set(userRef, user)
+	for _, e := range plan.Entries {
+		transactionRef := client.Collection(userID + "_transactions").NewDoc()
+		transaction := models.Transaction{
+			ID:              transactionRef.ID,
+			AccountID:       accountIDs[e.Account],
+			Amount:          e.Amount,
+			Balance:         e.Balance,
+			Type:            models.TransactionType(e.Kind),
+			Description:     e.Description,
+			TransactionDate: e.Date,
+			CreatedAt:       e.Date,
+			UpdatedAt:       e.Date,
+		}
+		// Money leaving an account is stored as a negative amount
+		if e.Outgoing {
+			transaction.Amount = -e.Amount
+		}
+		if e.Kind == EntryTransfer {
+			transaction.SourceAccountID = &accountIDs[e.Source]
+			transaction.TargetAccountID = &accountIDs[e.Target]
+		}
+		if err := set(transactionRef, transaction); err != nil {
+			bulkWriter.End()
+			return nil, err
+		}
 	}
-	bulkWriter.End()
 
-	// Create accounts for the users using BulkWriter
-	accounts := []models.Account{
-		{
-			UserID:        userIDs[0],
-			AccountNumber: "1000000001",
-			AccountType:   models.Checking,
-			Balance:       1000.00,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
-		},
-		{
-			UserID:        userIDs[0],
-			AccountNumber: "1000000002",
-			AccountType:   models.Savings,
-			Balance:       5000.00,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
-		},
-		{
-			UserID:        userIDs[1],
-			AccountNumber: "1000000003",
-			AccountType:   models.Checking,
-			Balance:       2000.00,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
-		},
+	bulkWriter.End()
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return nil, err
+		}
 	}
-	bulkWriter = client.BulkWriter(ctx)
-	accountIDs := make([]string, len(accounts))
 
-	for i, account := range accounts {
-		accountRef := client.Collection(accountsCol).NewDoc()
-		accountIDs[i] = accountRef.ID
-		account.ID = accountRef.ID
-		bulkWriter.Set(accountRef, account)
+	return &Summary{
+		Users:        len(plan.Users),
+		Accounts:     len(plan.Accounts),
+		Transactions: len(plan.Entries),
+		Skipped:      plan.Skipped,
+	}, nil
+}
+
+// clearCollection - Delete every document of a collection using BulkWriter
+func clearCollection(ctx context.Context, client *firestore.Client, colRef *firestore.CollectionRef) error {
+	for {
+		docs, err := colRef.Limit(500).Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		bulkWriter := client.BulkWriter(ctx)
+		for _, doc := range docs {
+			if _, err := bulkWriter.Delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+		bulkWriter.End()
 	}
-	bulkWriter.End()
+}
 
-	// Create some sample transactions using BulkWriter
-	now := time.Now()
-	yesterday := now.Add(-24 * time.Hour)
-	lastWeek := now.Add(-7 * 24 * time.Hour)
-
-	transactions := []models.Transaction{
-		{
-			AccountID:       accountIDs[0],
-			Amount:          500.00,
-			Balance:         500.00,
-			Type:            models.Deposit,
-			Description:     "Initial deposit",
-			TransactionDate: lastWeek,
-			CreatedAt:       lastWeek,
-			UpdatedAt:       lastWeek,
-		},
-		{
-			AccountID:       accountIDs[0],
-			Amount:          -100.00,
-			Balance:         400.00,
-			Type:            models.Withdrawal,
-			Description:     "ATM withdrawal",
-			TransactionDate: yesterday,
-			CreatedAt:       yesterday,
-			UpdatedAt:       yesterday,
-		},
-		{
-			AccountID:       accountIDs[0],
-			Amount:          600.00,
-			Balance:         1000.00,
-			Type:            models.Deposit,
-			Description:     "Salary deposit",
-			TransactionDate: now,
-			CreatedAt:       now,
-			UpdatedAt:       now,
-		},
-		{
-			AccountID:       accountIDs[1],
-			Amount:          5000.00,
-			Balance:         5000.00,
-			Type:            models.Deposit,
-			Description:     "Initial deposit",
-			TransactionDate: lastWeek,
-			CreatedAt:       lastWeek,
-			UpdatedAt:       lastWeek,
-		},
-		{
-			AccountID:       accountIDs[2],
-			Amount:          2000.00,
-			Balance:         2000.00,
-			Type:            models.Deposit,
-			Description:     "Initial deposit",
-			TransactionDate: yesterday,
-			CreatedAt:       yesterday,
-			UpdatedAt:       yesterday,
-		},
+// existingUsers - Which of the emails belong to users in the collection, found
+// by blind index and returned in lowercase
+func existingUsers(ctx context.Context, usersRef *firestore.CollectionRef, keyring *pii.Keyring, emails []string) (map[string]bool, error) {
+	byIndex := map[string]string{}
+	indexes := make([]string, 0, len(emails))
+	for _, email := range emails {
+		index := keyring.EmailIndex(email)
+		byIndex[index] = pii.NormalizeEmail(email)
+		indexes = append(indexes, index)
 	}
-	bulkWriter = client.BulkWriter(ctx)
 
-	for _, transaction := range transactions {
-		transactionRef := client.Collection(transactionsCol).NewDoc()
-		transaction.ID = transactionRef.ID
-		bulkWriter.Set(transactionRef, transaction)
+	existing := map[string]bool{}
+	for start := 0; start < len(indexes); start += inQueryLimit {
+		end := start + inQueryLimit
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		docs, err := usersRef.Where("emailIndex", "in", indexes[start:end]).Select("emailIndex").Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if index, ok := doc.Data()["emailIndex"].(string); ok {
+				existing[byIndex[index]] = true
+			}
+		}
 	}
-	bulkWriter.End()
+	return existing, nil
+}
 
-	log.Println("Database seeded successfully!")
-	return nil
+// takenNumbers - The account numbers already in the collection
+func takenNumbers(ctx context.Context, accountsRef *firestore.CollectionRef) (map[string]bool, error) {
+	docs, err := accountsRef.Select("accountNumber").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, doc := range docs {
+		if number, ok := doc.Data()["accountNumber"].(string); ok {
+			taken[number] = true
+		}
+	}
+	return taken, nil
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/handlers"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/health"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/ratelimit"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+)
+
+// serve - Run the API server until SIGINT or SIGTERM
+func serve(a *app) error {
+	// Load the token signing keys
+	keySet, err := signing.New(a.cfg.JWTKeysDir, a.cfg.JWTSigningKeyID, a.cfg.JWTSecret)
+	if err != nil {
+		return fmt.Errorf("load signing keys: %w", err)
+	}
+
+	// Initialize repositories
+	repos := a.repositories()
+
+	// Initialize services
+	userService := services.NewUserService(repos.user)
+	twoFactorService := services.NewTwoFactorService(repos.user, services.TwoFactorOptions{
+		KeySet:       keySet,
+		Issuer:       a.cfg.TwoFactorIssuer,
+		ChallengeTTL: a.cfg.TwoFactorChallengeTTL,
+	})
+	loginAttemptService := services.NewLoginAttemptService(repos.loginAttempt, repos.user, services.LoginAttemptOptions{
+		MaxFailures:     a.cfg.LoginMaxFailures,
+		FailureWindow:   a.cfg.LoginFailureWindow,
+		LockoutDuration: a.cfg.LoginLockoutDuration,
+		IPMaxFailures:   a.cfg.LoginIPMaxFailures,
+		DelayBase:       a.cfg.LoginDelayBase,
+	})
+	accountService := services.NewAccountService(repos.account)
+	transactionService := services.NewTransactionService(repos.transaction, repos.account)
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(userService, twoFactorService, loginAttemptService, keySet)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	loginAttemptHandler := handlers.NewLoginAttemptHandler(loginAttemptService)
+	userHandler := handlers.NewUserHandler(userService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	jwksHandler := handlers.NewJWKSHandler(keySet)
+
+	// Readiness depends on reading from Firestore
+	checker := health.New(a.cfg.HealthCheckTimeout)
+	checker.Add("firestore", health.Firestore(a.firebase.Firestore, a.cfg.UserID+"_users"))
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	// Initialize auth middleware
+	authMiddleware := middleware.NewAuthMiddleware(keySet)
+
+	// Rate limits are kept by each instance on its own
+	rateLimiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), a.cfg.RateLimitFailOpen)
+	authLimit := rateLimiter.Limit("auth", a.cfg.RateLimitAuth)
+	apiLimit := rateLimiter.Limit("api", a.cfg.RateLimitAPI)
+
+	// Initialize Gin router; requests and panics are logged as JSON
+	if !a.cfg.IsDevelopment() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+
+	// Client IPs, which login lockouts and rate limits are keyed on, are only
+	// taken from X-Forwarded-For when a trusted proxy sent it
+	if err := router.SetTrustedProxies(a.cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	router.Use(middleware.RequestID(), middleware.RequestLogger(a.logger), middleware.Tracing(), middleware.Metrics(), middleware.Recovery())
+
+	// Configure CORS - allow requests from both localhost and the actual server hostname
+	allowedOrigins := []string{"http://localhost:3000"}
+	if a.cfg.FrontendURL != "" {
+		allowedOrigins = append(allowedOrigins, a.cfg.FrontendURL)
+	}
+
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     allowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.RequestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    append([]string{"Content-Length", middleware.RequestIDHeader}, middleware.RateLimitHeaders...),
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Liveness and readiness probes
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+
+	// Prometheus metrics. They show traffic, error rates and login failures, so
+	// scrapers present METRICS_TOKEN as a bearer token. A token rather than an
+	// address allow-list, as scrapers in another network or behind NAT have no
+	// stable address; production refuses to start without one.
+	router.GET("/metrics", middleware.MetricsAuth(a.cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+
+	// API routes
+	v1 := router.Group("/api/v1")
+	{
+		// Auth routes - no auth required, limited per IP address
+		v1.POST("/auth/login", authLimit, authHandler.Login)
+		v1.POST("/auth/login/2fa", authLimit, authHandler.LoginTwoFactor)
+		v1.POST("/auth/register", authLimit, authHandler.Register)
+
+		// Two-factor enrollment routes - auth required
+		v1.POST("/auth/2fa/enroll", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Enroll)
+		v1.POST("/auth/2fa/confirm", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Confirm)
+
+		// User routes - auth required
+		users := v1.Group("/users")
+		users.Use(authMiddleware.Authenticate(), apiLimit)
+		{
+			users.GET("", userHandler.GetAllUsers)
+			users.GET("/:id", userHandler.GetUserByID)
+			users.GET("/me", userHandler.GetCurrentUser)
+		}
+
+		// Account routes - auth required
+		accounts := v1.Group("/accounts")
+		accounts.Use(authMiddleware.Authenticate(), apiLimit)
+		{
+			accounts.GET("", accountHandler.GetAllAccounts)
+			accounts.GET("/:id", accountHandler.GetAccountByID)
+			accounts.GET("/user/:userId", accountHandler.GetAccountsByUserID)
+			accounts.POST("", accountHandler.CreateAccount)
+		}
+
+		// Transaction routes - auth required
+		transactions := v1.Group("/transactions")
+		transactions.Use(authMiddleware.Authenticate(), rateLimiter.Limit("transactions", a.cfg.RateLimitTransactions))
+		{
+			transactions.GET("", transactionHandler.GetAllTransactions)
+			transactions.GET("/:id", transactionHandler.GetTransactionByID)
+			transactions.GET("/account/:accountId", transactionHandler.GetTransactionsByAccountID)
+			transactions.POST("/transfer", transactionHandler.Transfer)
+			transactions.POST("/deposit", transactionHandler.CreateDeposit)
+			transactions.POST("/withdrawal", transactionHandler.CreateWithdrawal)
+		}
+
+		// Admin routes - admin role required
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			admin.POST("/users/:id/2fa/reset", twoFactorHandler.Reset)
+			admin.POST("/users/:id/unlock", loginAttemptHandler.Unlock)
+			admin.GET("/users/:id/login-attempts", loginAttemptHandler.GetAttemptsByUserID)
+		}
+	}
+
+	// Start server
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", a.cfg.Port),
+		Handler: router,
+	}
+
+	// Graceful shutdown
+	go func() {
+		slog.Info("Server starting", "port", a.cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Server failed to listen", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shut down the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Fail readiness first so load balancers stop routing new requests here
+	slog.Info("Draining server", "delay", a.cfg.ShutdownDrainDelay)
+	checker.Drain()
+	time.Sleep(a.cfg.ShutdownDrainDelay)
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	slog.Info("Server exited")
+	return nil
+}
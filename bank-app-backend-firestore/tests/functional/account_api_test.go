@@ -2,7 +2,6 @@ package functional
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"testing"
 
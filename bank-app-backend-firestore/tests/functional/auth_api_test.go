@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -182,5 +183,17 @@ func TestAuthAPI(t *testing.T) {
 		assert.Equal(t, "auth@example.com", response.Email)
 		assert.Equal(t, "Auth", response.FirstName)
 		assert.Equal(t, "User", response.LastName)
+	})	
+	t.Run("JWKS endpoint is public", func(t *testing.T) {
+		// Act - fetch the verification keys without a token
+		w := MakeRequest("GET", "/.well-known/jwks.json", nil, "")
+		
+		// Assert - tests sign with a shared secret, so no keys are published
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response signing.JSONWebKeySet
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Empty(t, response.Keys)
 	})
 }
@@ -10,7 +10,6 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
-	"time"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go/v4"
@@ -20,8 +19,11 @@ import (
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/handlers"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
 	"google.golang.org/api/option"
 )
 
@@ -31,8 +33,22 @@ var (
 	testRouter          *gin.Engine
 	testConfig          *config.Config
 	testContext         context.Context
+	testKeyring         = pii.NewDevelopmentKeyring()
+	testRepos           repositories
 )
 
+// repositories - The repositories the router is built on, which the helpers
+// write through so test data is stored as the backend stores it
+type repositories struct {
+	user         interfaces.UserRepository
+	account      interfaces.AccountRepository
+	transaction  interfaces.TransactionRepository
+	loginAttempt interfaces.LoginAttemptRepository
+}
+
+// testCollections - The collections the tests write to, without the user prefix
+var testCollections = []string{"users", "accounts", "transactions", "login_attempts"}
+
 // SetupTestFirebase initializes Firebase clients for testing
 func SetupTestFirebase(t *testing.T) (*firestore.Client, *auth.Client, error) {
 	ctx := context.Background()
@@ -92,9 +108,8 @@ func CleanupCollection(client *firestore.Client, collection string) error {
 // CleanupTestFirebase cleans up all collections and closes clients
 func CleanupTestFirebase(firestoreClient *firestore.Client) error {
 	// Clean up collections
-	collections := []string{"users", "accounts", "transactions"}
-	for _, collection := range collections {
-		if err := CleanupCollection(firestoreClient, collection); err != nil {
+	for _, collection := range testCollections {
+		if err := CleanupCollection(firestoreClient, testConfig.UserID+"_"+collection); err != nil {
 			return err
 		}
 	}
@@ -103,43 +118,82 @@ func CleanupTestFirebase(firestoreClient *firestore.Client) error {
 	return firestoreClient.Close()
 }
 
+// mustLoadConfig loads the configuration from the environment, which the
+// tests cannot run without
+func mustLoadConfig() *config.Config {
+	cfg, err := config.Load(config.Sources{})
+	if err != nil {
+		panic(fmt.Sprintf("invalid test configuration: %v", err))
+	}
+	return cfg
+}
+
 // SetupTestRouter creates a router with all the routes for testing
 func SetupTestRouter(firestoreClient *firestore.Client, authClient *auth.Client) *gin.Engine {
 	// Set gin to test mode
 	gin.SetMode(gin.TestMode)
 	
 	// Use default test configuration
-	cfg := config.New()
+	cfg := mustLoadConfig()
+	
+	keySet := signing.NewHMACKeySet(cfg.JWTSecret)
 	
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(firestoreClient)
-	accountRepo := repository.NewAccountRepository(firestoreClient)
-	transactionRepo := repository.NewTransactionRepository(firestoreClient)
+	timeouts := repository.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}
+	testRepos = repositories{
+		user:         repository.NewUserRepository(firestoreClient, cfg.UserID, testKeyring, timeouts),
+		account:      repository.NewAccountRepository(firestoreClient, cfg.UserID, timeouts),
+		transaction:  repository.NewTransactionRepository(firestoreClient, cfg.UserID, timeouts),
+		loginAttempt: repository.NewLoginAttemptRepository(firestoreClient, cfg.UserID, testKeyring, timeouts),
+	}
 	
 	// Initialize services
-	userService := services.NewUserService(userRepo)
-	accountService := services.NewAccountService(accountRepo)
-	transactionService := services.NewTransactionService(transactionRepo, accountRepo)
+	userService := services.NewUserService(testRepos.user)
+	twoFactorService := services.NewTwoFactorService(testRepos.user, services.TwoFactorOptions{
+		KeySet:       keySet,
+		Issuer:       cfg.TwoFactorIssuer,
+		ChallengeTTL: cfg.TwoFactorChallengeTTL,
+	})
+	loginAttemptService := services.NewLoginAttemptService(testRepos.loginAttempt, testRepos.user, services.LoginAttemptOptions{
+		MaxFailures:     cfg.LoginMaxFailures,
+		FailureWindow:   cfg.LoginFailureWindow,
+		LockoutDuration: cfg.LoginLockoutDuration,
+		IPMaxFailures:   cfg.LoginIPMaxFailures,
+		DelayBase:       0, // Tests log in right after failed attempts
+	})
+	accountService := services.NewAccountService(testRepos.account)
+	transactionService := services.NewTransactionService(testRepos.transaction, testRepos.account)
 	
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(userService, twoFactorService, loginAttemptService, keySet)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	loginAttemptHandler := handlers.NewLoginAttemptHandler(loginAttemptService)
 	userHandler := handlers.NewUserHandler(userService)
 	accountHandler := handlers.NewAccountHandler(accountService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	jwksHandler := handlers.NewJWKSHandler(keySet)
 	
 	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret)
+	authMiddleware := middleware.NewAuthMiddleware(keySet)
 	
 	// Initialize router
 	router := gin.Default()
 	
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+	
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
 		// Auth routes - no auth required
 		v1.POST("/auth/login", authHandler.Login)
+		v1.POST("/auth/login/2fa", authHandler.LoginTwoFactor)
 		v1.POST("/auth/register", authHandler.Register)
 		
+		// Two-factor enrollment routes - auth required
+		v1.POST("/auth/2fa/enroll", authMiddleware.Authenticate(), twoFactorHandler.Enroll)
+		v1.POST("/auth/2fa/confirm", authMiddleware.Authenticate(), twoFactorHandler.Confirm)
+		
 		// User routes - auth required
 		users := v1.Group("/users")
 		users.Use(authMiddleware.Authenticate())
@@ -170,6 +224,15 @@ func SetupTestRouter(firestoreClient *firestore.Client, authClient *auth.Client)
 			transactions.POST("/deposit", transactionHandler.CreateDeposit)
 			transactions.POST("/withdrawal", transactionHandler.CreateWithdrawal)
 		}
+		
+		// Admin routes - admin role required
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware.Authenticate(), authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			admin.POST("/users/:id/2fa/reset", twoFactorHandler.Reset)
+			admin.POST("/users/:id/unlock", loginAttemptHandler.Unlock)
+			admin.GET("/users/:id/login-attempts", loginAttemptHandler.GetAttemptsByUserID)
+		}
 	}
 	
 	return router
@@ -188,10 +251,14 @@ func SetupTest(t *testing.T) {
 		testContext = context.Background()
 	}
 	
+	// Initialize config
+	if testConfig == nil {
+		testConfig = mustLoadConfig()
+	}
+	
 	// Clean up any existing data
-	collections := []string{"users", "accounts", "transactions"}
-	for _, collection := range collections {
-		if err := CleanupCollection(testFirestoreClient, collection); err != nil {
+	for _, collection := range testCollections {
+		if err := CleanupCollection(testFirestoreClient, testConfig.UserID+"_"+collection); err != nil {
 			t.Logf("Warning: Failed to clean up collection %s: %v", collection, err)
 		}
 	}
@@ -200,11 +267,6 @@ func SetupTest(t *testing.T) {
 	if testRouter == nil {
 		testRouter = SetupTestRouter(testFirestoreClient, testAuthClient)
 	}
-	
-	// Initialize config
-	if testConfig == nil {
-		testConfig = config.New()
-	}
 }
 
 // MakeRequest is a helper function to make HTTP requests for tests
@@ -239,62 +301,23 @@ func CreateTestUser(email, password, firstName, lastName string) (models.User, e
 		return models.User{}, err
 	}
 	
-	// Create user
-	user := models.User{
+	// The repository encrypts the user's PII and indexes the email
+	return testRepos.user.Create(testContext, models.User{
 		Email:     email,
 		Password:  hashedPassword,
 		FirstName: firstName,
 		LastName:  lastName,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	
-	// Add to Firestore
-	docRef, _, err := testFirestoreClient.Collection("users").Add(testContext, user)
-	if err != nil {
-		return models.User{}, err
-	}
-	
-	// Update the user with the generated ID
-	user.ID = docRef.ID
-	_, err = docRef.Set(testContext, map[string]interface{}{
-		"id": docRef.ID,
-	}, firestore.MergeAll)
-	if err != nil {
-		return models.User{}, err
-	}
-	
-	return user, nil
+	})
 }
 
 // CreateTestAccount creates a test account for tests
 func CreateTestAccount(userID string, accountNumber string, accountType models.AccountType, balance float64) (models.Account, error) {
-	// Create account
-	account := models.Account{
+	return testRepos.account.Create(testContext, models.Account{
 		UserID:        userID,
 		AccountNumber: accountNumber,
 		AccountType:   accountType,
 		Balance:       balance,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-	
-	// Add to Firestore
-	docRef, _, err := testFirestoreClient.Collection("accounts").Add(testContext, account)
-	if err != nil {
-		return models.Account{}, err
-	}
-	
-	// Update the account with the generated ID
-	account.ID = docRef.ID
-	_, err = docRef.Set(testContext, map[string]interface{}{
-		"id": docRef.ID,
-	}, firestore.MergeAll)
-	if err != nil {
-		return models.Account{}, err
-	}
-	
-	return account, nil
+	})
 }
 
 // LoginTestUser logs in a test user and returns the auth token
@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,25 +29,15 @@ func TestTransactionAPI(t *testing.T) {
 	token, err := LoginTestUser("transaction_test@example.com", "password123")
 	assert.NoError(t, err)
 	
-	// Create initial transaction directly in Firestore
-	transaction := models.Transaction{
+	// Create initial transaction through the repository
+	transaction, err := testRepos.transaction.Create(testContext, models.Transaction{
 		AccountID:       checkingAccount.ID,
 		Amount:          500.00,
 		Balance:         1500.00, // New balance after this transaction
 		Type:            models.Deposit,
 		Description:     "Initial deposit",
 		TransactionDate: time.Now(),
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-	}
-	
-	docRef, _, err := testFirestoreClient.Collection("transactions").Add(testContext, transaction)
-	assert.NoError(t, err)
-	
-	transaction.ID = docRef.ID
-	_, err = docRef.Set(testContext, map[string]interface{}{
-		"id": docRef.ID,
-	}, firestore.MergeAll)
+	})
 	assert.NoError(t, err)
 	
 	t.Run("Get all transactions should return a list of transactions", func(t *testing.T) {
@@ -12,6 +12,9 @@ import (
 	"google.golang.org/api/option"
 )
 
+// testUserID prefixes the collections the tests use, as the backend's USER_ID does
+const testUserID = "integration"
+
 // TestFirebaseClient holds Firestore and Auth clients for testing
 type TestFirebaseClient struct {
 	Auth      *auth.Client
@@ -83,10 +86,10 @@ func (tfc *TestFirebaseClient) CleanupCollection(collection string) error {
 
 // CleanupAllCollections cleans up all collections used in tests
 func (tfc *TestFirebaseClient) CleanupAllCollections() error {
-	collections := []string{"users", "accounts", "transactions"}
+	collections := []string{"users", "accounts", "transactions", "login_attempts"}
 	
 	for _, collection := range collections {
-		if err := tfc.CleanupCollection(collection); err != nil {
+		if err := tfc.CleanupCollection(testUserID + "_" + collection); err != nil {
 			return err
 		}
 	}
@@ -1,9 +1,11 @@
 package integration
 
 import (
+	"context"
 	"testing"
 
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,10 +23,11 @@ func TestUserRepository_Integration(t *testing.T) {
 	defer firebaseClient.Close()
 
 	// Create user repository
-	userRepo := repository.NewUserRepository(firebaseClient.Firestore)
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(firebaseClient.Firestore, testUserID, pii.NewDevelopmentKeyring(), repository.Timeouts{})
 
 	// Clean up test data before starting
-	err = firebaseClient.CleanupCollection("users")
+	err = firebaseClient.CleanupCollection(testUserID + "_users")
 	require.NoError(t, err)
 
 	t.Run("Create and FindByID", func(t *testing.T) {
@@ -40,7 +43,7 @@ func TestUserRepository_Integration(t *testing.T) {
 		}
 
 		// Create the user in Firestore
-		createdUser, err := userRepo.Create(user)
+		createdUser, err := userRepo.Create(ctx, user)
 		require.NoError(t, err)
 		assert.NotEmpty(t, createdUser.ID)
 		assert.Equal(t, "test@example.com", createdUser.Email)
@@ -48,7 +51,7 @@ func TestUserRepository_Integration(t *testing.T) {
 		assert.NotZero(t, createdUser.UpdatedAt)
 
 		// Find the user by ID
-		foundUser, err := userRepo.FindByID(createdUser.ID)
+		foundUser, err := userRepo.FindByID(ctx, createdUser.ID)
 		require.NoError(t, err)
 		assert.Equal(t, createdUser.ID, foundUser.ID)
 		assert.Equal(t, "test@example.com", foundUser.Email)
@@ -56,20 +59,20 @@ func TestUserRepository_Integration(t *testing.T) {
 
 	t.Run("FindByEmail", func(t *testing.T) {
 		// Find the user by email
-		foundUser, err := userRepo.FindByEmail("test@example.com")
+		foundUser, err := userRepo.FindByEmail(ctx, "test@example.com")
 		require.NoError(t, err)
 		assert.Equal(t, "test@example.com", foundUser.Email)
 	})
 
 	t.Run("Update", func(t *testing.T) {
 		// Find the user to update
-		user, err := userRepo.FindByEmail("test@example.com")
+		user, err := userRepo.FindByEmail(ctx, "test@example.com")
 		require.NoError(t, err)
 
 		// Update user data
 		user.FirstName = "Updated"
 		user.LastName = "Name"
-		updatedUser, err := userRepo.Update(user)
+		updatedUser, err := userRepo.Update(ctx, user)
 		require.NoError(t, err)
 
 		// Verify the update
@@ -78,7 +81,7 @@ func TestUserRepository_Integration(t *testing.T) {
 		assert.True(t, updatedUser.UpdatedAt.After(user.UpdatedAt) || updatedUser.UpdatedAt.Equal(user.UpdatedAt))
 
 		// Retrieve again to confirm persistence
-		retrievedUser, err := userRepo.FindByID(user.ID)
+		retrievedUser, err := userRepo.FindByID(ctx, user.ID)
 		require.NoError(t, err)
 		assert.Equal(t, "Updated", retrievedUser.FirstName)
 		assert.Equal(t, "Name", retrievedUser.LastName)
@@ -97,11 +100,11 @@ func TestUserRepository_Integration(t *testing.T) {
 		}
 
 		// Create the second user
-		_, err = userRepo.Create(user2)
+		_, err = userRepo.Create(ctx, user2)
 		require.NoError(t, err)
 
 		// Retrieve all users
-		users, err := userRepo.FindAll()
+		users, err := userRepo.FindAll(ctx)
 		require.NoError(t, err)
 		assert.GreaterOrEqual(t, len(users), 2)
 
@@ -122,20 +125,20 @@ func TestUserRepository_Integration(t *testing.T) {
 
 	t.Run("Delete", func(t *testing.T) {
 		// Find a user to delete
-		user, err := userRepo.FindByEmail("another@example.com")
+		user, err := userRepo.FindByEmail(ctx, "another@example.com")
 		require.NoError(t, err)
 
 		// Delete the user
-		err = userRepo.Delete(user.ID)
+		err = userRepo.Delete(ctx, user.ID)
 		require.NoError(t, err)
 
 		// Try to find the deleted user
-		_, err = userRepo.FindByID(user.ID)
+		_, err = userRepo.FindByID(ctx, user.ID)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "not found")
 	})
 
 	// Clean up test data after tests
-	err = firebaseClient.CleanupCollection("users")
+	err = firebaseClient.CleanupCollection(testUserID + "_users")
 	require.NoError(t, err)
 }
@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminService(t *testing.T) {
+	t.Run("CreateUser should hash the password and set the role", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewAdminService(mockUserRepo, new(MockAccountRepository))
+
+		mockUserRepo.On("Create", mock.MatchedBy(func(user models.User) bool {
+			return user.Email == "ops@example.com" && user.Role == models.RoleAdmin &&
+				bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("longpassword1")) == nil
+		})).Return(models.User{ID: "user1", Email: "ops@example.com", Role: models.RoleAdmin}, nil)
+
+		// Act
+		user, err := service.CreateUser(context.Background(), models.User{
+			Email: " ops@example.com ", Password: "longpassword1", FirstName: "Ops", LastName: "Person",
+		}, models.RoleAdmin)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "user1", user.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateUser should refuse an unknown role or a short password", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewAdminService(mockUserRepo, new(MockAccountRepository))
+		user := models.User{Email: "ops@example.com", Password: "longpassword1", FirstName: "Ops", LastName: "Person"}
+		short := user
+		short.Password = "short"
+
+		// Act
+		_, roleErr := service.CreateUser(context.Background(), user, "root")
+		_, passwordErr := service.CreateUser(context.Background(), short, models.RoleCustomer)
+
+		// Assert
+		assert.ErrorIs(t, roleErr, services.ErrInvalidRole)
+		assert.ErrorIs(t, passwordErr, services.ErrWeakPassword)
+		mockUserRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("ResetPassword should store the new hash", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewAdminService(mockUserRepo, new(MockAccountRepository))
+
+		mockUserRepo.On("FindByID", "user1").Return(models.User{ID: "user1"}, nil)
+		mockUserRepo.On("UpdatePassword", "user1", mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("newpassword1")) == nil
+		})).Return(nil)
+
+		// Act
+		err := service.ResetPassword(context.Background(), "user1", "newpassword1")
+
+		// Assert
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("DisableUser should disable an existing user", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewAdminService(mockUserRepo, new(MockAccountRepository))
+
+		mockUserRepo.On("FindByID", "user1").Return(models.User{ID: "user1"}, nil)
+		mockUserRepo.On("Disable", "user1").Return(nil)
+
+		// Act
+		err := service.DisableUser(context.Background(), "user1")
+
+		// Assert
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("FreezeAccount and UnfreezeAccount should set the frozen flag", func(t *testing.T) {
+		// Arrange
+		mockAccountRepo := new(MockAccountRepository)
+		service := services.NewAdminService(new(MockUserRepository), mockAccountRepo)
+
+		mockAccountRepo.On("SetFrozen", "acc1", true).Return(nil)
+		mockAccountRepo.On("SetFrozen", "acc1", false).Return(nil)
+
+		// Act
+		freezeErr := service.FreezeAccount(context.Background(), "acc1")
+		unfreezeErr := service.UnfreezeAccount(context.Background(), "acc1")
+
+		// Assert
+		assert.NoError(t, freezeErr)
+		assert.NoError(t, unfreezeErr)
+		mockAccountRepo.AssertExpectations(t)
+	})
+}
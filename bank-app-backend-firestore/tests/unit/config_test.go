@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnv returns a LookupEnv reading from vars instead of the process environment
+func fakeEnv(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+// sourceOf returns where the loaded setting key came from
+func sourceOf(cfg *config.Config, key string) string {
+	for _, setting := range cfg.Settings() {
+		if setting.Key == key {
+			return setting.Source
+		}
+	}
+	return ""
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("Load should use the defaults without other sources", func(t *testing.T) {
+		// Act
+		cfg, err := config.Load(config.Sources{LookupEnv: fakeEnv(nil)})
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, cfg.IsDevelopment())
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, config.DefaultJWTSecret, cfg.JWTSecret)
+		assert.Equal(t, "demo_user", cfg.UserID)
+	})
+
+	t.Run("Load should prefer flags to the environment and the environment to the file", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(file, []byte("port: 7000\nunique_user_id: file_user\nlog_level: warn\n"), 0o600))
+		src := config.Sources{
+			File:      file,
+			Overrides: map[string]string{"PORT": "9000"},
+			LookupEnv: fakeEnv(map[string]string{"PORT": "8000", "UNIQUE_USER_ID": "env_user"}),
+		}
+
+		// Act
+		cfg, err := config.Load(src)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 9000, cfg.Port)
+		assert.Equal(t, config.SourceFlag, sourceOf(cfg, "PORT"))
+		assert.Equal(t, "env_user", cfg.UserID)
+		assert.Equal(t, config.SourceEnv, sourceOf(cfg, "UNIQUE_USER_ID"))
+		assert.Equal(t, "warn", cfg.LogLevel)
+		assert.Equal(t, config.SourceFile, sourceOf(cfg, "LOG_LEVEL"))
+	})
+
+	t.Run("Load should report every invalid setting", func(t *testing.T) {
+		// Arrange
+		src := config.Sources{
+			Overrides: map[string]string{"PROT": "8080"},
+			LookupEnv: fakeEnv(map[string]string{
+				"PORT":                 "eighty",
+				"LOGIN_FAILURE_WINDOW": "15",
+				"TRACING_SAMPLE_RATIO": "2",
+			}),
+		}
+
+		// Act
+		_, err := config.Load(src)
+
+		// Assert
+		var problems config.Problems
+		require.ErrorAs(t, err, &problems)
+		assert.Equal(t, config.Problems{
+			`PORT: "eighty" is not a whole number`,
+			`LOGIN_FAILURE_WINDOW: "15" is not a duration such as 90s or 15m`,
+			"-set: unknown setting PROT",
+			"TRACING_SAMPLE_RATIO must be between 0 and 1",
+		}, problems)
+	})
+
+	t.Run("Load should refuse the development JWT secret in production", func(t *testing.T) {
+		// Act
+		_, err := config.Load(config.Sources{LookupEnv: fakeEnv(map[string]string{"APP_ENV": config.Production})})
+
+		// Assert
+		var problems config.Problems
+		require.ErrorAs(t, err, &problems)
+		assert.Equal(t, config.Problems{
+			"JWT_SECRET must be set when APP_ENV is production; its development default is refused",
+			"METRICS_TOKEN must be set when APP_ENV is production; its development default is refused",
+			"PII_KEYRING_FILE must be set when APP_ENV is production",
+		}, problems)
+	})
+
+	t.Run("Load should read a secret from the file its _FILE variable names", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "jwt_secret")
+		require.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0o600))
+		src := config.Sources{LookupEnv: fakeEnv(map[string]string{
+			"APP_ENV":          config.Production,
+			"JWT_SECRET_FILE":  file,
+			"METRICS_TOKEN":    "metrics-token",
+			"PII_KEYRING_FILE": "/etc/drank/keyring.json",
+		})}
+
+		// Act
+		cfg, err := config.Load(src)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", cfg.JWTSecret)
+		assert.Equal(t, "env JWT_SECRET_FILE", sourceOf(cfg, "JWT_SECRET"))
+	})
+
+	t.Run("Print should redact secrets when asked", func(t *testing.T) {
+		// Arrange
+		cfg, err := config.Load(config.Sources{Overrides: map[string]string{"JWT_SECRET": "top-secret"}, LookupEnv: fakeEnv(nil)})
+		require.NoError(t, err)
+		var redacted, plain bytes.Buffer
+
+		// Act
+		require.NoError(t, cfg.Print(&redacted, true))
+		require.NoError(t, cfg.Print(&plain, false))
+
+		// Assert
+		assert.Contains(t, redacted.String(), `JWT_SECRET="[REDACTED]" # flag`+"\n")
+		assert.NotContains(t, redacted.String(), "top-secret")
+		assert.Contains(t, plain.String(), `JWT_SECRET="top-secret" # flag`+"\n")
+	})
+}
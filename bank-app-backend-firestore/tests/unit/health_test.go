@@ -0,0 +1,97 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/handlers"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthChecker(t *testing.T) {
+	t.Run("A failing check should make the server unavailable", func(t *testing.T) {
+		// Arrange
+		checker := health.New(time.Second)
+		checker.Add("firestore", func(ctx context.Context) error { return errors.New("connection refused") })
+		checker.Add("worker", func(ctx context.Context) error { return nil })
+
+		// Act
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.False(t, report.Ready())
+		assert.Equal(t, health.StatusFailed, report.Checks["firestore"].Status)
+		assert.Equal(t, "connection refused", report.Checks["firestore"].Error)
+		assert.Equal(t, health.StatusOK, report.Checks["worker"].Status)
+	})
+
+	t.Run("A check that hangs should time out", func(t *testing.T) {
+		// Arrange
+		checker := health.New(20 * time.Millisecond)
+		block := make(chan struct{})
+		defer close(block)
+		checker.Add("firestore", func(ctx context.Context) error {
+			<-block // ignores its context
+			return nil
+		})
+
+		// Act
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.False(t, report.Ready())
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["firestore"].Error)
+	})
+
+	t.Run("A stale heartbeat should fail", func(t *testing.T) {
+		// Arrange
+		heartbeat := health.NewHeartbeat(10 * time.Millisecond)
+
+		// Act
+		fresh := heartbeat.Check(context.Background())
+		time.Sleep(20 * time.Millisecond)
+		stale := heartbeat.Check(context.Background())
+
+		// Assert
+		assert.NoError(t, fresh)
+		assert.Error(t, stale)
+	})
+}
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Readiness should fail while draining but liveness should not", func(t *testing.T) {
+		// Arrange
+		checker := health.New(time.Second)
+		checker.Add("firestore", func(ctx context.Context) error { return nil })
+		router := gin.New()
+		healthHandler := handlers.NewHealthHandler(checker)
+		router.GET("/healthz", healthHandler.Live)
+		router.GET("/readyz", healthHandler.Ready)
+
+		// Act
+		before := httptest.NewRecorder()
+		router.ServeHTTP(before, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		checker.Drain()
+		after := httptest.NewRecorder()
+		router.ServeHTTP(after, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		live := httptest.NewRecorder()
+		router.ServeHTTP(live, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, before.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, after.Code)
+		assert.Equal(t, http.StatusOK, live.Code)
+		var report health.Report
+		assert.NoError(t, json.Unmarshal(after.Body.Bytes(), &report))
+		assert.True(t, report.Draining)
+	})
+}
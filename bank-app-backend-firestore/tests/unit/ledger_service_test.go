@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerService(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Reconcile should report accounts that disagree with their transactions", func(t *testing.T) {
+		// Arrange
+		mockAccountRepo := new(MockAccountRepository)
+		mockTransactionRepo := new(MockTransactionRepository)
+		service := services.NewLedgerService(mockAccountRepo, mockTransactionRepo)
+
+		mockAccountRepo.On("FindAll").Return([]models.Account{
+			{ID: "acc1", AccountNumber: "1000000001", Balance: 400},  // matches
+			{ID: "acc2", AccountNumber: "1000000002", Balance: 900},  // total disagrees
+			{ID: "acc3", AccountNumber: "1000000003", Balance: 0},    // no transactions
+			{ID: "acc4", AccountNumber: "1000000004", Balance: 50.5}, // running balance disagrees
+		}, nil)
+		mockTransactionRepo.On("FindAll").Return([]models.Transaction{
+			{AccountID: "acc1", Amount: 500, Balance: 500, TransactionDate: now.Add(-time.Hour)},
+			{AccountID: "acc1", Amount: -100, Balance: 400, TransactionDate: now},
+			{AccountID: "acc2", Amount: 1000, Balance: 900, TransactionDate: now},
+			{AccountID: "acc4", Amount: 50.5, Balance: 60, TransactionDate: now},
+		}, nil)
+
+		// Act
+		result, err := service.Reconcile(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 4, result.Checked)
+		require.Len(t, result.Discrepancies, 2)
+		assert.Equal(t, "acc2", result.Discrepancies[0].AccountID)
+		assert.Equal(t, 1000.0, result.Discrepancies[0].LedgerTotal)
+		assert.Equal(t, "acc4", result.Discrepancies[1].AccountID)
+		assert.Equal(t, 60.0, result.Discrepancies[1].LastBalance)
+	})
+
+	t.Run("AccrueInterest should credit savings accounts once per day", func(t *testing.T) {
+		// Arrange
+		mockAccountRepo := new(MockAccountRepository)
+		service := services.NewLedgerService(mockAccountRepo, new(MockTransactionRepository))
+
+		mockAccountRepo.On("FindAll").Return([]models.Account{
+			{ID: "acc1", AccountType: models.Savings, Balance: 36500},
+			{ID: "acc2", AccountType: models.Checking, Balance: 36500},
+			{ID: "acc3", AccountType: models.Savings, Balance: 1000},
+			{ID: "acc4", AccountType: models.Savings, Balance: 1}, // under a cent
+		}, nil)
+		date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		mockAccountRepo.On("CreditOnce", "acc1", "interest_acc1_2024-03-01", 2.0, "Interest for 2024-03-01", date).Return(true, nil)
+		// Already credited by an earlier run
+		mockAccountRepo.On("CreditOnce", "acc3", "interest_acc3_2024-03-01", 0.05, "Interest for 2024-03-01", date).Return(false, nil)
+
+		// Act, with a time of day that is ignored
+		result, err := service.AccrueInterest(context.Background(), date.Add(15*time.Hour), 2)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Credited)
+		assert.Equal(t, 2, result.Skipped)
+		assert.Equal(t, 2.0, result.Total)
+		mockAccountRepo.AssertExpectations(t)
+	})
+
+	t.Run("AccrueInterest should refuse a rate that is not positive", func(t *testing.T) {
+		// Arrange
+		service := services.NewLedgerService(new(MockAccountRepository), new(MockTransactionRepository))
+
+		// Act
+		_, err := service.AccrueInterest(context.Background(), now, 0)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
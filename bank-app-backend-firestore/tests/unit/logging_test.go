@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/logging"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+	"github.com/stretchr/testify/assert"
+)
+
+// logLines decodes the JSON lines a logger wrote
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var decoded []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+		decoded = append(decoded, fields)
+	}
+	return decoded
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("New should drop lines below the level", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		logger, err := logging.New(&buf, "warn")
+		assert.NoError(t, err)
+
+		// Act
+		logger.Info("hidden")
+		logger.Warn("shown")
+		_, invalidErr := logging.New(&buf, "loud")
+
+		// Assert
+		logged := logLines(t, &buf)
+		assert.Len(t, logged, 1)
+		assert.Equal(t, "WARN", logged[0]["level"])
+		assert.Error(t, invalidErr)
+	})
+
+	t.Run("Sensitive attributes should be redacted", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		logger, _ := logging.New(&buf, "info")
+
+		// Act
+		logger.Info("login", "password", "hunter2", "refresh_token", "abc", "Authorization", "Bearer xyz", "user_id", "u1")
+		logger.WithGroup("request").Info("grouped", "client_secret", "s3cret")
+
+		// Assert
+		logged := logLines(t, &buf)
+		assert.Equal(t, logging.Redacted, logged[0]["password"])
+		assert.Equal(t, logging.Redacted, logged[0]["refresh_token"])
+		assert.Equal(t, logging.Redacted, logged[0]["Authorization"])
+		assert.Equal(t, "u1", logged[0]["user_id"])
+		assert.Equal(t, logging.Redacted, logged[1]["request"].(map[string]interface{})["client_secret"])
+		assert.NotContains(t, buf.String(), "hunter2")
+	})
+}
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// newRouter serves a route that logs from the handler, as services do
+	newRouter := func(buf *bytes.Buffer, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
+		logger, _ := logging.New(buf, "info")
+		router := gin.New()
+		router.Use(middleware.RequestID(), middleware.RequestLogger(logger), middleware.Recovery())
+		router.GET("/accounts/:id", authMiddleware.Authenticate(), func(c *gin.Context) {
+			logging.FromContext(c.Request.Context()).Info("loading account")
+			c.Status(http.StatusOK)
+		})
+		router.GET("/panic", func(c *gin.Context) {
+			panic("boom")
+		})
+		return router
+	}
+
+	t.Run("Every line should carry the request ID and user", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		authMiddleware := middleware.NewAuthMiddleware(signing.NewHMACKeySet("secret"))
+		token, _ := authMiddleware.GenerateToken("user-1", "user@example.com", "user")
+		router := newRouter(&buf, authMiddleware)
+		req := httptest.NewRequest(http.MethodGet, "/accounts/acc-1?token=abc", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(middleware.RequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		logged := logLines(t, &buf)
+		assert.Len(t, logged, 2)
+		for _, line := range logged {
+			assert.Equal(t, "req-1", line["request_id"])
+			assert.Equal(t, "user-1", line["user_id"])
+		}
+		assert.Equal(t, "request", logged[1]["msg"])
+		assert.Equal(t, "/accounts/:id", logged[1]["route"])
+		assert.Equal(t, "/accounts/acc-1", logged[1]["path"], "the query is not logged")
+		assert.Equal(t, float64(http.StatusOK), logged[1]["status"])
+		assert.Equal(t, "req-1", w.Header().Get(middleware.RequestIDHeader))
+	})
+
+	t.Run("A panic should be logged as an error and answered with 500", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		router := newRouter(&buf, middleware.NewAuthMiddleware(signing.NewHMACKeySet("secret")))
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		// Assert
+		logged := logLines(t, &buf)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Len(t, logged, 2)
+		assert.Equal(t, "panic while serving request", logged[0]["msg"])
+		assert.Equal(t, "ERROR", logged[1]["level"])
+		assert.NotEmpty(t, logged[1]["request_id"], "an ID is generated when none is sent")
+	})
+}
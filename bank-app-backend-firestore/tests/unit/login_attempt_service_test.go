@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoginAttemptService(t *testing.T) {
+	// Set up common test data
+	options := services.LoginAttemptOptions{
+		MaxFailures:     5,
+		FailureWindow:   15 * time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		IPMaxFailures:   20,
+		DelayBase:       time.Second,
+	}
+	
+	t.Run("CheckAllowed should allow a user without failures", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByEmail", "test@example.com").Return(models.User{ID: "user1"}, nil)
+		mockAttemptRepo.On("CountFailuresByIP", "10.0.0.1", mock.AnythingOfType("time.Time")).Return(0, nil)
+		
+		// Act
+		err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockAttemptRepo.AssertExpectations(t)
+	})
+	
+	t.Run("CheckAllowed should refuse a locked account", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		lockedUntil := time.Now().Add(10 * time.Minute)
+		mockUserRepo.On("FindByEmail", "test@example.com").Return(models.User{ID: "user1", LockedUntil: lockedUntil}, nil)
+		
+		// Act
+		err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+		
+		// Assert
+		var throttled *services.LoginThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.Equal(t, "account is temporarily locked", throttled.Reason)
+		mockAttemptRepo.AssertNotCalled(t, "CountFailuresByIP", mock.Anything, mock.Anything)
+	})
+	
+	t.Run("CheckAllowed should enforce the delay after recent failures", func(t *testing.T) {
+		// Arrange - three failures require a four second wait, two have passed
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByEmail", "test@example.com").
+			Return(models.User{ID: "user1", FailedLoginCount: 3, LastFailedLoginAt: time.Now().Add(-2 * time.Second)}, nil)
+		
+		// Act
+		err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+		
+		// Assert
+		var throttled *services.LoginThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.InDelta(t, 2, throttled.RetryAfter.Seconds(), 0.5)
+	})
+	
+	t.Run("CheckAllowed should block an address with too many failures", func(t *testing.T) {
+		// Arrange - unknown emails are still limited by address
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByEmail", "unknown@example.com").Return(models.User{}, errors.New("user not found"))
+		mockAttemptRepo.On("CountFailuresByIP", "10.0.0.1", mock.AnythingOfType("time.Time")).Return(20, nil)
+		
+		// Act
+		err := service.CheckAllowed(context.Background(), "unknown@example.com", "10.0.0.1")
+		
+		// Assert
+		var throttled *services.LoginThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.Equal(t, 15*time.Minute, throttled.RetryAfter)
+	})
+	
+	t.Run("RecordFailure should lock the account after the maximum failures", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByEmail", "test@example.com").Return(models.User{ID: "user1", Email: "test@example.com"}, nil)
+		mockUserRepo.On("IncrementFailedLogins", "user1", mock.AnythingOfType("time.Time")).Return(5, nil)
+		mockUserRepo.On("Lock", "user1", mock.AnythingOfType("time.Time")).Return(nil)
+		mockAttemptRepo.On("Create", mock.MatchedBy(func(attempt models.LoginAttempt) bool {
+			return attempt.UserID == "user1" && !attempt.Success &&
+				attempt.IPAddress == "10.0.0.1" && attempt.UserAgent == "test-agent"
+		})).Return(models.LoginAttempt{}, nil)
+		
+		// Act
+		err := service.RecordFailure(context.Background(), "test@example.com", "10.0.0.1", "test-agent", "invalid credentials")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockAttemptRepo.AssertExpectations(t)
+	})
+	
+	t.Run("RecordFailure should not lock below the maximum failures", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByEmail", "test@example.com").Return(models.User{ID: "user1"}, nil)
+		mockUserRepo.On("IncrementFailedLogins", "user1", mock.AnythingOfType("time.Time")).Return(2, nil)
+		mockAttemptRepo.On("Create", mock.Anything).Return(models.LoginAttempt{}, nil)
+		
+		// Act
+		err := service.RecordFailure(context.Background(), "test@example.com", "10.0.0.1", "test-agent", "invalid credentials")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockUserRepo.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything)
+	})
+	
+	t.Run("RecordSuccess should clear failures", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		user := models.User{ID: "user1", Email: "test@example.com", FailedLoginCount: 2}
+		mockUserRepo.On("ResetFailedLogins", "user1").Return(nil)
+		mockAttemptRepo.On("Create", mock.MatchedBy(func(attempt models.LoginAttempt) bool {
+			return attempt.Success && attempt.UserID == "user1"
+		})).Return(models.LoginAttempt{}, nil)
+		
+		// Act
+		err := service.RecordSuccess(context.Background(), user, "10.0.0.1", "test-agent")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockAttemptRepo.AssertExpectations(t)
+	})
+	
+	t.Run("Unlock should fail for an unknown user", func(t *testing.T) {
+		// Arrange
+		mockAttemptRepo := new(MockLoginAttemptRepository)
+		mockUserRepo := new(MockUserRepository)
+		service := services.NewLoginAttemptService(mockAttemptRepo, mockUserRepo, options)
+		
+		mockUserRepo.On("FindByID", "missing").Return(models.User{}, errors.New("user not found"))
+		
+		// Act
+		err := service.Unlock(context.Background(), "missing")
+		
+		// Assert
+		assert.Error(t, err)
+		mockUserRepo.AssertNotCalled(t, "ResetFailedLogins", mock.Anything)
+	})
+}
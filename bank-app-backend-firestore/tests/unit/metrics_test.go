@@ -0,0 +1,115 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferMetrics(t *testing.T) {
+	t.Run("A completed transfer should be counted", func(t *testing.T) {
+		// Arrange
+		mockTransactionRepo := new(MockTransactionRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		service := services.NewTransactionService(mockTransactionRepo, mockAccountRepo)
+		mockAccountRepo.On("FindByID", "acc123").Return(models.Account{ID: "acc123"}, nil)
+		mockAccountRepo.On("FindByID", "acc456").Return(models.Account{ID: "acc456"}, nil)
+		mockTransactionRepo.On("CreateTransfer", "acc123", "acc456", 200.00, "").Return(nil)
+		completed := testutil.ToFloat64(metrics.Transfers.WithLabelValues(metrics.TransferCompleted))
+
+		// Act
+		err := service.Transfer(context.Background(), models.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc456", Amount: 200.00})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, completed+1, testutil.ToFloat64(metrics.Transfers.WithLabelValues(metrics.TransferCompleted)))
+	})
+
+	t.Run("A failed transfer should be counted by reason", func(t *testing.T) {
+		// Arrange
+		mockTransactionRepo := new(MockTransactionRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		service := services.NewTransactionService(mockTransactionRepo, mockAccountRepo)
+		mockAccountRepo.On("FindByID", "acc123").Return(models.Account{ID: "acc123"}, nil)
+		mockAccountRepo.On("FindByID", "acc456").Return(models.Account{ID: "acc456"}, nil)
+		mockTransactionRepo.On("CreateTransfer", "acc123", "acc456", 5000.00, "").Return(interfaces.ErrInsufficientBalance)
+		insufficient := testutil.ToFloat64(metrics.TransferFailures.WithLabelValues("insufficient_funds"))
+		sameAccount := testutil.ToFloat64(metrics.TransferFailures.WithLabelValues("same_account"))
+
+		// Act
+		insufficientErr := service.Transfer(context.Background(), models.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc456", Amount: 5000.00})
+		sameAccountErr := service.Transfer(context.Background(), models.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc123", Amount: 10.00})
+
+		// Assert
+		assert.ErrorIs(t, insufficientErr, interfaces.ErrInsufficientBalance)
+		assert.ErrorIs(t, sameAccountErr, services.ErrSameAccount)
+		assert.Equal(t, insufficient+1, testutil.ToFloat64(metrics.TransferFailures.WithLabelValues("insufficient_funds")))
+		assert.Equal(t, sameAccount+1, testutil.ToFloat64(metrics.TransferFailures.WithLabelValues("same_account")))
+	})
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Requests should be labelled by route template", func(t *testing.T) {
+		// Arrange
+		router := gin.New()
+		router.Use(middleware.Metrics())
+		router.GET("/accounts/:id", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+		// Act
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/accounts/acc-1", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/accounts/acc-2", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/accounts/:id", "200")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")))
+		assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/accounts/:id",status="200"}`)
+		assert.NotContains(t, w.Body.String(), "acc-1")
+	})
+
+	t.Run("The metrics endpoint should require the token when one is set", func(t *testing.T) {
+		// Arrange
+		router := gin.New()
+		router.GET("/metrics", middleware.MetricsAuth("metrics-token"), gin.WrapH(metrics.Handler()))
+		scrape := func(authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		// Act
+		missing := scrape("")
+		wrong := scrape("Bearer wrong-token")
+		valid := scrape("Bearer metrics-token")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, missing.Code)
+		assert.Equal(t, http.StatusUnauthorized, wrong.Code)
+		assert.Equal(t, `Bearer realm="metrics"`, wrong.Header().Get("WWW-Authenticate"))
+		assert.NotContains(t, wrong.Body.String(), "http_requests_total")
+		assert.Equal(t, http.StatusOK, valid.Code)
+		assert.Contains(t, valid.Body.String(), "http_requests_total")
+	})
+}
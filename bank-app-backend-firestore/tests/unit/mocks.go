@@ -1,6 +1,9 @@
 package unit
 
 import (
+	"context"
+	"time"
+
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 	"github.com/stretchr/testify/mock"
@@ -14,32 +17,82 @@ type MockUserRepository struct {
 // Ensure MockUserRepository implements UserRepository interface
 var _ interfaces.UserRepository = (*MockUserRepository)(nil)
 
-func (m *MockUserRepository) Create(user models.User) (models.User, error) {
+func (m *MockUserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByID(id string) (models.User, error) {
+func (m *MockUserRepository) FindByID(ctx context.Context, id string) (models.User, error) {
 	args := m.Called(id)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (models.User, error) {
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
 	args := m.Called(email)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindAll() ([]models.User, error) {
+func (m *MockUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user models.User) (models.User, error) {
+func (m *MockUserRepository) Update(ctx context.Context, user models.User) (models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Delete(id string) error {
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetTwoFactorSecret(ctx context.Context, id string, secret string) error {
+	args := m.Called(id, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTwoFactor(ctx context.Context, id string, recoveryCodeHashes []string) error {
+	args := m.Called(id, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetTwoFactor(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ConsumeTwoFactorStep(ctx context.Context, id string, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ConsumeRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error) {
+	args := m.Called(id, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementFailedLogins(ctx context.Context, id string, windowStart time.Time) (int, error) {
+	args := m.Called(id, windowStart)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Lock(ctx context.Context, id string, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	args := m.Called(id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Disable(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -52,46 +105,56 @@ type MockAccountRepository struct {
 // Ensure MockAccountRepository implements AccountRepository interface
 var _ interfaces.AccountRepository = (*MockAccountRepository)(nil)
 
-func (m *MockAccountRepository) Create(account models.Account) (models.Account, error) {
+func (m *MockAccountRepository) Create(ctx context.Context, account models.Account) (models.Account, error) {
 	args := m.Called(account)
 	return args.Get(0).(models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByID(id string) (models.Account, error) {
+func (m *MockAccountRepository) FindByID(ctx context.Context, id string) (models.Account, error) {
 	args := m.Called(id)
 	return args.Get(0).(models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByUserID(userID string) ([]models.Account, error) {
+func (m *MockAccountRepository) FindByUserID(ctx context.Context, userID string) ([]models.Account, error) {
 	args := m.Called(userID)
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByAccountNumber(accountNumber string) (models.Account, error) {
+func (m *MockAccountRepository) FindByAccountNumber(ctx context.Context, accountNumber string) (models.Account, error) {
 	args := m.Called(accountNumber)
 	return args.Get(0).(models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindAll() ([]models.Account, error) {
+func (m *MockAccountRepository) FindAll(ctx context.Context) ([]models.Account, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) Update(account models.Account) (models.Account, error) {
+func (m *MockAccountRepository) Update(ctx context.Context, account models.Account) (models.Account, error) {
 	args := m.Called(account)
 	return args.Get(0).(models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) Delete(id string) error {
+func (m *MockAccountRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) UpdateBalance(id string, amount float64) (models.Account, error) {
+func (m *MockAccountRepository) UpdateBalance(ctx context.Context, id string, amount float64) (models.Account, error) {
 	args := m.Called(id, amount)
 	return args.Get(0).(models.Account), args.Error(1)
 }
 
+func (m *MockAccountRepository) SetFrozen(ctx context.Context, id string, frozen bool) error {
+	args := m.Called(id, frozen)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) CreditOnce(ctx context.Context, id, key string, amount float64, description string, date time.Time) (bool, error) {
+	args := m.Called(id, key, amount, description, date)
+	return args.Bool(0), args.Error(1)
+}
+
 // MockTransactionRepository implements the TransactionRepository interface for testing
 type MockTransactionRepository struct {
 	mock.Mock
@@ -100,37 +163,60 @@ type MockTransactionRepository struct {
 // Ensure MockTransactionRepository implements TransactionRepository interface
 var _ interfaces.TransactionRepository = (*MockTransactionRepository)(nil)
 
-func (m *MockTransactionRepository) Create(transaction models.Transaction) (models.Transaction, error) {
+func (m *MockTransactionRepository) Create(ctx context.Context, transaction models.Transaction) (models.Transaction, error) {
 	args := m.Called(transaction)
 	return args.Get(0).(models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByID(id string) (models.Transaction, error) {
+func (m *MockTransactionRepository) FindByID(ctx context.Context, id string) (models.Transaction, error) {
 	args := m.Called(id)
 	return args.Get(0).(models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByAccountID(accountID string) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindByAccountID(ctx context.Context, accountID string) ([]models.Transaction, error) {
 	args := m.Called(accountID)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindAll() ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindAll(ctx context.Context) ([]models.Transaction, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindBySourceAccountID(sourceAccountID string) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindBySourceAccountID(ctx context.Context, sourceAccountID string) ([]models.Transaction, error) {
 	args := m.Called(sourceAccountID)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByTargetAccountID(targetAccountID string) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindByTargetAccountID(ctx context.Context, targetAccountID string) ([]models.Transaction, error) {
 	args := m.Called(targetAccountID)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CreateTransfer(sourceAccountID, targetAccountID string, amount float64, description string) error {
+func (m *MockTransactionRepository) CreateTransfer(ctx context.Context, sourceAccountID, targetAccountID string, amount float64, description string) error {
 	args := m.Called(sourceAccountID, targetAccountID, amount, description)
 	return args.Error(0)
 }
+
+// MockLoginAttemptRepository implements the LoginAttemptRepository interface for testing
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+// Ensure MockLoginAttemptRepository implements LoginAttemptRepository
+var _ interfaces.LoginAttemptRepository = (*MockLoginAttemptRepository)(nil)
+
+func (m *MockLoginAttemptRepository) Create(ctx context.Context, attempt models.LoginAttempt) (models.LoginAttempt, error) {
+	args := m.Called(attempt)
+	return args.Get(0).(models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int, error) {
+	args := m.Called(ipAddress, since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]models.LoginAttempt, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
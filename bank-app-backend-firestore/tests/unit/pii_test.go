@@ -0,0 +1,193 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/pii"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeyring builds a keyring holding the given key versions
+func testKeyring(t *testing.T, versions ...int) *pii.Keyring {
+	keys := map[int][]byte{}
+	for _, version := range versions {
+		keys[version] = bytes.Repeat([]byte{byte(version)}, 32)
+	}
+	keyring, err := pii.NewKeyring(keys, bytes.Repeat([]byte{0xff}, 32))
+	assert.NoError(t, err)
+	return keyring
+}
+
+func TestKeyring(t *testing.T) {
+	t.Run("Encrypt should round trip with a fresh data key", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+
+		// Act
+		first, err := keyring.Encrypt("Jane")
+		second, _ := keyring.Encrypt("Jane")
+		plaintext, decryptErr := keyring.Decrypt(first)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, decryptErr)
+		assert.True(t, strings.HasPrefix(first, "enc:v1:"))
+		assert.NotEqual(t, first, second)
+		assert.Equal(t, "Jane", plaintext)
+	})
+
+	t.Run("Decrypt should pass plaintext through", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+
+		// Act
+		plaintext, err := keyring.Decrypt("written before encryption")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "written before encryption", plaintext)
+		assert.True(t, keyring.NeedsReencryption("written before encryption"))
+	})
+
+	t.Run("Decrypt should reject a tampered value", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+		ciphertext, _ := keyring.Encrypt("Jane")
+
+		// Act
+		_, err := keyring.Decrypt(ciphertext[:len(ciphertext)-2] + "AA")
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("Rotation should keep old values readable", func(t *testing.T) {
+		// Arrange
+		ciphertext, _ := testKeyring(t, 1).Encrypt("Jane")
+		rotated := testKeyring(t, 1, 2)
+
+		// Act
+		plaintext, err := rotated.Decrypt(ciphertext)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", plaintext)
+		assert.Equal(t, 2, rotated.CurrentVersion())
+		assert.True(t, rotated.NeedsReencryption(ciphertext))
+	})
+
+	t.Run("BlindIndex should not change with rotation", func(t *testing.T) {
+		// Act & Assert
+		assert.Equal(t, testKeyring(t, 1).BlindIndex("jane@example.com"), testKeyring(t, 1, 2).BlindIndex("jane@example.com"))
+		assert.NotEqual(t, testKeyring(t, 1).BlindIndex("jane@example.com"), testKeyring(t, 1).BlindIndex("john@example.com"))
+	})
+
+	t.Run("EmailIndex should ignore case and surrounding spaces", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+
+		// Act & Assert
+		assert.Equal(t, keyring.BlindIndex("jane@example.com"), keyring.EmailIndex("jane@example.com"))
+		assert.Equal(t, keyring.EmailIndex("jane@example.com"), keyring.EmailIndex(" Jane@Example.COM\n"))
+		assert.NotEqual(t, keyring.EmailIndex("jane@example.com"), keyring.EmailIndex("john@example.com"))
+	})
+
+	t.Run("LoadKeyring should read a keyring file", func(t *testing.T) {
+		// Arrange
+		encode := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+		data, _ := json.Marshal(map[string]interface{}{
+			"keys": []map[string]interface{}{
+				{"version": 1, "key": encode(1)},
+				{"version": 2, "key": encode(2)},
+			},
+			"blindIndexKey": encode(0xff),
+		})
+		path := filepath.Join(t.TempDir(), "keyring.json")
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+		// Act
+		keyring, err := pii.LoadKeyring(path)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, keyring.CurrentVersion())
+		assert.Equal(t, testKeyring(t, 1).BlindIndex("x"), keyring.BlindIndex("x"))
+	})
+
+	t.Run("LoadKeyring should reject a short key", func(t *testing.T) {
+		// Arrange
+		data, _ := json.Marshal(map[string]interface{}{
+			"keys":          []map[string]interface{}{{"version": 1, "key": base64.StdEncoding.EncodeToString([]byte("short"))}},
+			"blindIndexKey": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 32)),
+		})
+		path := filepath.Join(t.TempDir(), "keyring.json")
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+		// Act
+		_, err := pii.LoadKeyring(path)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestUserEncryption(t *testing.T) {
+	t.Run("Encrypted should encrypt PII and index the email", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+		user := models.User{ID: "user1", Email: "jane@example.com", FirstName: "Jane", LastName: "Smith", Role: models.RoleCustomer, TwoFactorSecret: "JBSWY3DPEHPK3PXP"}
+
+		// Act
+		stored, err := user.Encrypted(keyring)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, pii.IsEncrypted(stored.Email))
+		assert.True(t, pii.IsEncrypted(stored.FirstName))
+		assert.True(t, pii.IsEncrypted(stored.LastName))
+		assert.True(t, pii.IsEncrypted(stored.TwoFactorSecret))
+		assert.Equal(t, keyring.BlindIndex("jane@example.com"), stored.EmailIndex)
+		assert.Equal(t, models.RoleCustomer, stored.Role)
+		assert.Equal(t, "Jane", user.FirstName, "the original user is left unchanged")
+	})
+
+	t.Run("Decrypt should restore the stored user", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+		stored, _ := models.User{Email: "jane@example.com", FirstName: "Jane", LastName: "Smith", TwoFactorSecret: "JBSWY3DPEHPK3PXP"}.Encrypted(keyring)
+
+		// Act
+		err := stored.Decrypt(keyring)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", stored.Email)
+		assert.Equal(t, "Jane", stored.FirstName)
+		assert.Equal(t, "Smith", stored.LastName)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", stored.TwoFactorSecret)
+	})
+}
+
+func TestLoginAttemptIndexing(t *testing.T) {
+	t.Run("Indexed should replace the email with its blind index", func(t *testing.T) {
+		// Arrange
+		keyring := testKeyring(t, 1)
+		attempt := models.LoginAttempt{Email: " Jane@Example.com", IPAddress: "10.0.0.1"}
+
+		// Act
+		stored := attempt.Indexed(keyring)
+
+		// Assert
+		assert.Empty(t, stored.Email)
+		assert.Equal(t, keyring.EmailIndex("jane@example.com"), stored.EmailIndex)
+		assert.Equal(t, "10.0.0.1", stored.IPAddress)
+		assert.Equal(t, " Jane@Example.com", attempt.Email, "the original attempt is left unchanged")
+	})
+}
@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRateLimitStore - A rate limit store that cannot be reached
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("unavailable")
+}
+
+// rateLimitedRouter - Serve GET /ping behind the limiter, as the user in the
+// X-User header when there is one. Like the server by default, it trusts no
+// proxy's X-Forwarded-For.
+func rateLimitedRouter(store ratelimit.Store, limit ratelimit.Limit, failOpen bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetTrustedProxies(nil)
+	authenticate := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("userId", user)
+		}
+	}
+	router.GET("/ping", authenticate, middleware.NewRateLimiter(store, failOpen).Limit("api", limit), func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+func ping(router *gin.Engine, user, ip string) *httptest.ResponseRecorder {
+	return pingForwarded(router, user, ip, "")
+}
+
+// pingForwarded - Ping as ip, a proxy when forwardedFor is set
+func pingForwarded(router *gin.Engine, user, ip, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = ip + ":12345"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("ParseLimit should read requests per duration, or off", func(t *testing.T) {
+		// Act
+		limit, err := ratelimit.ParseLimit("100/1m")
+		off, offErr := ratelimit.ParseLimit("off")
+		_, badErr := ratelimit.ParseLimit("100")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, ratelimit.Limit{Requests: 100, Per: time.Minute}, limit)
+		require.NoError(t, offErr)
+		assert.True(t, off.Off())
+		assert.EqualError(t, badErr, `"100" is not a limit such as 100/1m, or off`)
+	})
+
+	t.Run("MemoryStore should refill a token every period divided by the requests", func(t *testing.T) {
+		// Arrange
+		store := ratelimit.NewMemoryStore()
+		limit := ratelimit.Limit{Requests: 2, Per: time.Hour}
+		ctx := context.Background()
+
+		// Act
+		first, _ := store.Take(ctx, "api:user:1", limit)
+		second, _ := store.Take(ctx, "api:user:1", limit)
+		third, _ := store.Take(ctx, "api:user:1", limit)
+
+		// Assert
+		assert.True(t, first.Allowed)
+		assert.Equal(t, 1, first.Remaining)
+		assert.True(t, second.Allowed)
+		assert.False(t, third.Allowed)
+		assert.InDelta(t, float64(30*time.Minute), float64(third.RetryAfter), float64(time.Second))
+		assert.InDelta(t, float64(time.Hour), float64(third.Reset), float64(time.Second))
+	})
+
+	t.Run("Limit should set the RateLimit headers and refuse callers over the limit", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Per: time.Minute}, false)
+
+		// Act
+		first := ping(router, "", "10.0.0.1")
+		ping(router, "", "10.0.0.1")
+		refused := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", first.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+		assert.Equal(t, http.StatusTooManyRequests, refused.Code)
+		assert.Equal(t, "30", refused.Header().Get("Retry-After"))
+	})
+
+	t.Run("Limit should keep a bucket per user, else per IP address", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act
+		ip := ping(router, "", "10.0.0.1")
+		ipAgain := ping(router, "", "10.0.0.1")
+		user := ping(router, "user-1", "10.0.0.1")
+		userAgain := ping(router, "user-1", "10.0.0.2")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, ip.Code)
+		assert.Equal(t, http.StatusTooManyRequests, ipAgain.Code)
+		assert.Equal(t, http.StatusOK, user.Code)
+		assert.Equal(t, http.StatusTooManyRequests, userAgain.Code)
+	})
+
+	t.Run("Limit should refuse requests when the store fails", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(failingRateLimitStore{}, ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act
+		rec := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("Limit should ignore X-Forwarded-For from an untrusted proxy", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act - a caller claims a new address for every request
+		first := pingForwarded(router, "", "10.0.0.1", "203.0.113.1")
+		spoofed := pingForwarded(router, "", "10.0.0.1", "203.0.113.2")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusTooManyRequests, spoofed.Code)
+	})
+
+	t.Run("Limit should let requests through when the store fails and it fails open", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(failingRateLimitStore{}, ratelimit.Limit{Requests: 1, Per: time.Minute}, true)
+
+		// Act
+		rec := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+}
@@ -0,0 +1,152 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend-firestore/seed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	now := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+
+	t.Run("Built-in profiles should load", func(t *testing.T) {
+		for _, profile := range seed.Profiles {
+			fixture, err := seed.LoadFixture(profile)
+			require.NoError(t, err, profile)
+			assert.Equal(t, profile, fixture.Name)
+		}
+	})
+
+	t.Run("BuildPlan should give the same history for the same seed value", func(t *testing.T) {
+		// Arrange
+		fixture, err := seed.LoadFixture(seed.ProfileDemo)
+		require.NoError(t, err)
+
+		// Act
+		first, err := seed.BuildPlan(fixture, seed.PlanOptions{SeedValue: 7, Now: now})
+		require.NoError(t, err)
+		second, err := seed.BuildPlan(fixture, seed.PlanOptions{SeedValue: 7, Now: now})
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("BuildPlan should keep running balances that match the accounts", func(t *testing.T) {
+		// Arrange
+		fixture, err := seed.LoadFixture(seed.ProfileDemo)
+		require.NoError(t, err)
+
+		// Act
+		plan, err := seed.BuildPlan(fixture, seed.PlanOptions{SeedValue: 1, Now: now})
+
+		// Assert
+		require.NoError(t, err)
+		balances := make([]float64, len(plan.Accounts))
+		for _, e := range plan.Entries {
+			if e.Outgoing {
+				balances[e.Account] -= e.Amount
+			} else {
+				balances[e.Account] += e.Amount
+			}
+			assert.InDelta(t, balances[e.Account], e.Balance, 0.001)
+		}
+		for i, account := range plan.Accounts {
+			assert.InDelta(t, balances[i], account.Balance, 0.001)
+		}
+	})
+
+	t.Run("BuildPlan should skip users that already exist", func(t *testing.T) {
+		// Arrange
+		fixture, err := seed.LoadFixture(seed.ProfileDemo)
+		require.NoError(t, err)
+
+		// Act
+		plan, err := seed.BuildPlan(fixture, seed.PlanOptions{
+			SeedValue:     1,
+			Now:           now,
+			ExistingUsers: map[string]bool{"jane.smith@example.com": true},
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"jane.smith@example.com"}, plan.Skipped)
+		assert.Len(t, plan.Accounts, 2)
+	})
+
+	t.Run("Simulate should give every customer payroll, card spending and consistent balances", func(t *testing.T) {
+		// Arrange
+		opts := seed.SimulateOptions{
+			Customers:   12,
+			From:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:          time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			SeedValue:   1,
+			Password:    "password123",
+			EmailPrefix: "customer",
+			BatchSize:   5,
+		}
+		var plans []*seed.Plan
+
+		// Act
+		err := seed.Simulate(opts, func(plan *seed.Plan) error {
+			plans = append(plans, plan)
+			return nil
+		})
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, plans, 3)
+		payroll, card := 0, 0
+		for _, plan := range plans {
+			balances := make([]float64, len(plan.Accounts))
+			for _, e := range plan.Entries {
+				if e.Outgoing {
+					balances[e.Account] -= e.Amount
+				} else {
+					balances[e.Account] += e.Amount
+				}
+				assert.InDelta(t, balances[e.Account], e.Balance, 0.001)
+				assert.GreaterOrEqual(t, e.Balance, 0.0)
+				if strings.HasPrefix(e.Description, "Payroll - ") {
+					payroll++
+				}
+				if strings.HasPrefix(e.Description, "Card purchase - ") {
+					card++
+				}
+			}
+			for i, account := range plan.Accounts {
+				assert.InDelta(t, balances[i], account.Balance, 0.001)
+			}
+		}
+		assert.GreaterOrEqual(t, payroll, 12*2)
+		assert.Greater(t, card, payroll)
+	})
+
+	// The fixtures and the code that plans them are shared with the Postgres
+	// backend, so that the same file loads the same data into both
+	t.Run("Shared seed files should match the Postgres backend", func(t *testing.T) {
+		postgres := filepath.Join("..", "..", "..", "bank-app-backend", "seed")
+		if _, err := os.Stat(postgres); err != nil {
+			t.Skip("Postgres backend not checked out next to this one")
+		}
+
+		shared, err := filepath.Glob(filepath.Join(postgres, "fixtures", "*.yaml"))
+		require.NoError(t, err)
+		shared = append(shared, filepath.Join(postgres, "fixture.go"), filepath.Join(postgres, "plan.go"), filepath.Join(postgres, "simulate.go"))
+		for _, theirs := range shared {
+			rel, err := filepath.Rel(postgres, theirs)
+			require.NoError(t, err)
+			want, err := os.ReadFile(theirs)
+			require.NoError(t, err)
+			got, err := os.ReadFile(filepath.Join("..", "..", "seed", rel))
+			require.NoError(t, err, rel)
+			assert.Equal(t, string(want), string(got), "seed/%s differs from the Postgres backend", rel)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePEM stores a key as <kid>.pem in dir
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func TestKeySet(t *testing.T) {
+	// Set up common test data
+	claims := jwt.RegisteredClaims{Subject: "user1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+	parse := func(ks *signing.KeySet, tokenString string) (*jwt.Token, error) {
+		return jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, ks.Keyfunc)
+	}
+	
+	t.Run("LoadKeySet should sign with the latest key", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		oldDER, _ := x509.MarshalPKCS8PrivateKey(oldKey)
+		rsaDER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+		writePEM(t, dir, "2026-01-01", "PRIVATE KEY", oldDER)
+		writePEM(t, dir, "2026-07-01", "PRIVATE KEY", rsaDER)
+		
+		// Act
+		ks, err := signing.LoadKeySet(dir, "")
+		assert.NoError(t, err)
+		tokenString, signErr := ks.Sign(claims)
+		token, parseErr := parse(ks, tokenString)
+		
+		// Assert
+		assert.NoError(t, signErr)
+		assert.NoError(t, parseErr)
+		assert.Equal(t, "2026-07-01", ks.SigningKeyID())
+		assert.Equal(t, "2026-07-01", token.Header["kid"])
+		assert.Equal(t, signing.AlgorithmRS256, token.Method.Alg())
+	})
+	
+	t.Run("Rotated-out keys should still verify", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+		oldDER, _ := x509.MarshalPKCS8PrivateKey(oldKey)
+		writePEM(t, dir, "2026-01-01", "PRIVATE KEY", oldDER)
+		before, _ := signing.LoadKeySet(dir, "")
+		tokenString, _ := before.Sign(claims)
+		
+		// Rotate: add a new key and keep only the public part of the old one
+		_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+		newDER, _ := x509.MarshalPKCS8PrivateKey(newKey)
+		oldPublicDER, _ := x509.MarshalPKIXPublicKey(oldKey.Public())
+		writePEM(t, dir, "2026-07-01", "PRIVATE KEY", newDER)
+		writePEM(t, dir, "2026-01-01", "PUBLIC KEY", oldPublicDER)
+		
+		// Act
+		after, err := signing.LoadKeySet(dir, "")
+		assert.NoError(t, err)
+		_, parseErr := parse(after, tokenString)
+		
+		// Assert
+		assert.Equal(t, "2026-07-01", after.SigningKeyID())
+		assert.NoError(t, parseErr)
+		assert.Len(t, after.JWKS().Keys, 2)
+	})
+	
+	t.Run("LoadKeySet should reject weak RSA keys and empty directories", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		weak, _ := rsa.GenerateKey(rand.Reader, 1024)
+		weakDER, _ := x509.MarshalPKCS8PrivateKey(weak)
+		writePEM(t, dir, "weak", "PRIVATE KEY", weakDER)
+		
+		// Act
+		_, weakErr := signing.LoadKeySet(dir, "")
+		_, emptyErr := signing.LoadKeySet(t.TempDir(), "")
+		
+		// Assert
+		assert.Error(t, weakErr)
+		assert.Error(t, emptyErr)
+	})
+	
+	t.Run("Keyfunc should reject HMAC tokens signed with a public key", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		rsaDER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+		writePEM(t, dir, "rsa", "PRIVATE KEY", rsaDER)
+		ks, _ := signing.LoadKeySet(dir, "")
+		
+		publicDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = "rsa"
+		tokenString, _ := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+		
+		// Act
+		_, err := parse(ks, tokenString)
+		
+		// Assert
+		assert.Error(t, err)
+	})
+	
+	t.Run("AuthMiddleware should issue tokens verifiable through the JWKS key", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		public, key, _ := ed25519.GenerateKey(rand.Reader)
+		der, _ := x509.MarshalPKCS8PrivateKey(key)
+		writePEM(t, dir, "k1", "PRIVATE KEY", der)
+		ks, _ := signing.LoadKeySet(dir, "")
+		
+		// Act
+		tokenString, err := middleware.NewAuthMiddleware(ks).GenerateToken("user1", "test@example.com", models.RoleCustomer)
+		assert.NoError(t, err)
+		token, parseErr := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return public, nil
+		})
+		
+		// Assert
+		assert.NoError(t, parseErr)
+		assert.Equal(t, "k1", token.Header["kid"])
+		assert.Equal(t, "k1", ks.JWKS().Keys[0].KeyID)
+		assert.Equal(t, "Ed25519", ks.JWKS().Keys[0].Curve)
+	})
+	
+	t.Run("HMAC key set should publish no keys", func(t *testing.T) {
+		// Arrange
+		ks := signing.NewHMACKeySet("secret")
+		tokenString, _ := ks.Sign(claims)
+		
+		// Act
+		_, err := parse(ks, tokenString)
+		_, otherErr := parse(signing.NewHMACKeySet("other-secret"), tokenString)
+		
+		// Assert
+		assert.NoError(t, err)
+		assert.Error(t, otherErr)
+		assert.Empty(t, ks.JWKS().Keys)
+	})
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTracingSetup(t *testing.T) {
+	t.Run("The stdout exporter should write spans to the file", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "spans.json")
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		// Act
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{ServiceName: "test", Exporter: tracing.ExporterStdout, File: file, SampleRatio: 1})
+		require.NoError(t, err)
+		_, span := tracing.Start(context.Background(), "work")
+		span.End()
+		require.NoError(t, shutdown(context.Background()))
+
+		// Assert
+		written, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(written), `"Name":"work"`)
+	})
+
+	t.Run("An unknown exporter should be rejected", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "zipkin"})
+		assert.Error(t, err)
+	})
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("A request should continue the caller's trace", func(t *testing.T) {
+		// Arrange
+		recorder := recordSpans(t)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		router := gin.New()
+		router.Use(middleware.Tracing())
+		router.GET("/accounts/:id", func(c *gin.Context) {
+			c.Status(http.StatusInternalServerError)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/accounts/acc-1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		// Act
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		// Assert
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /accounts/:id", spans[0].Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
+
+func TestTransferSpan(t *testing.T) {
+	// Arrange
+	recorder := recordSpans(t)
+	service := services.NewTransactionService(new(MockTransactionRepository), new(MockAccountRepository))
+
+	// Act
+	err := service.Transfer(context.Background(), models.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc123", Amount: 25.0})
+
+	// Assert
+	assert.ErrorIs(t, err, services.ErrSameAccount)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "TransactionService.Transfer", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
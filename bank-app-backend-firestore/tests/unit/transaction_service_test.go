@@ -1,11 +1,13 @@
 package unit
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/repository/interfaces"
 	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -54,7 +56,7 @@ func TestTransactionService(t *testing.T) {
 		mockAccountRepo.On("Update", mock.AnythingOfType("models.Account")).Return(updatedAccount, nil)
 
 		// Act
-		result, err := service.Create(transaction)
+		result, err := service.Create(context.Background(), transaction)
 
 		// Assert
 		assert.NoError(t, err)
@@ -65,6 +67,23 @@ func TestTransactionService(t *testing.T) {
 		mockAccountRepo.AssertExpectations(t)
 	})
 
+	t.Run("Create should refuse a frozen account", func(t *testing.T) {
+		// Arrange
+		mockTransactionRepo := new(MockTransactionRepository)
+		mockAccountRepo := new(MockAccountRepository)
+		service := services.NewTransactionService(mockTransactionRepo, mockAccountRepo)
+
+		mockAccountRepo.On("FindByID", "acc123").Return(models.Account{ID: "acc123", Balance: 1000.00, FrozenAt: &now}, nil)
+
+		// Act
+		_, err := service.Create(context.Background(), models.Transaction{AccountID: "acc123", Amount: 500.00, Type: models.Deposit})
+
+		// Assert
+		assert.ErrorIs(t, err, interfaces.ErrAccountFrozen)
+		mockTransactionRepo.AssertNotCalled(t, "Create", mock.Anything)
+		mockAccountRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
 	t.Run("Create should create a new withdrawal transaction", func(t *testing.T) {
 		// Arrange
 		mockTransactionRepo := new(MockTransactionRepository)
@@ -104,7 +123,7 @@ func TestTransactionService(t *testing.T) {
 		mockAccountRepo.On("Update", mock.AnythingOfType("models.Account")).Return(updatedAccount, nil)
 
 		// Act
-		result, err := service.Create(transaction)
+		result, err := service.Create(context.Background(), transaction)
 
 		// Assert
 		assert.NoError(t, err)
@@ -153,7 +172,7 @@ func TestTransactionService(t *testing.T) {
 		mockTransactionRepo.On("CreateTransfer", "acc123", "acc456", 200.00, "Test transfer").Return(nil)
 
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req)
 
 		// Assert
 		assert.NoError(t, err)
@@ -177,7 +196,7 @@ func TestTransactionService(t *testing.T) {
 		mockAccountRepo.On("FindByID", "nonexistent").Return(models.Account{}, errors.New("account not found"))
 
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req)
 
 		// Assert
 		assert.Error(t, err)
@@ -212,7 +231,7 @@ func TestTransactionService(t *testing.T) {
 		mockAccountRepo.On("FindByID", "nonexistent").Return(models.Account{}, errors.New("account not found"))
 
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req)
 
 		// Assert
 		assert.Error(t, err)
@@ -234,7 +253,7 @@ func TestTransactionService(t *testing.T) {
 		}
 
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req)
 
 		// Assert
 		assert.Error(t, err)
@@ -257,7 +276,7 @@ func TestTransactionService(t *testing.T) {
 		}
 
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req)
 
 		// Assert
 		assert.Error(t, err)
@@ -287,7 +306,7 @@ func TestTransactionService(t *testing.T) {
 		mockTransactionRepo.On("FindByID", "t123").Return(transaction, nil)
 
 		// Act
-		result, err := service.GetByID("t123")
+		result, err := service.GetByID(context.Background(), "t123")
 
 		// Assert
 		assert.NoError(t, err)
@@ -306,7 +325,7 @@ func TestTransactionService(t *testing.T) {
 		mockTransactionRepo.On("FindByID", "nonexistent").Return(models.Transaction{}, errors.New("transaction not found"))
 
 		// Act
-		result, err := service.GetByID("nonexistent")
+		result, err := service.GetByID(context.Background(), "nonexistent")
 
 		// Assert
 		assert.Error(t, err)
@@ -349,7 +368,7 @@ func TestTransactionService(t *testing.T) {
 		mockTransactionRepo.On("FindByAccountID", "acc123").Return(transactions, nil)
 
 		// Act
-		result, err := service.GetByAccountID("acc123")
+		result, err := service.GetByAccountID(context.Background(), "acc123")
 
 		// Assert
 		assert.NoError(t, err)
@@ -393,7 +412,7 @@ func TestTransactionService(t *testing.T) {
 		mockTransactionRepo.On("FindAll").Return(transactions, nil)
 
 		// Act
-		result, err := service.GetAll()
+		result, err := service.GetAll(context.Background())
 
 		// Assert
 		assert.NoError(t, err)
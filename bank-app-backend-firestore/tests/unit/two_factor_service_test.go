@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend-firestore/internal/signing"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTwoFactorService(t *testing.T) {
+	// Set up common test data
+	const secret = "JBSWY3DPEHPK3PXP"
+	keySet := signing.NewHMACKeySet("test-secret")
+	options := services.TwoFactorOptions{KeySet: keySet, Issuer: "Drank Bank", ChallengeTTL: 5 * time.Minute}
+	
+	t.Run("Enroll should store a secret and return an otpauth URL", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{ID: "user1", Email: "test@example.com"}
+		mockRepo.On("FindByID", "user1").Return(user, nil)
+		mockRepo.On("SetTwoFactorSecret", "user1", mock.AnythingOfType("string")).Return(nil)
+		
+		// Act
+		enrollment, err := service.Enroll(context.Background(), "user1")
+		
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, enrollment.Secret)
+		assert.Contains(t, enrollment.OTPAuthURL, "otpauth://totp/")
+		mockRepo.AssertExpectations(t)
+	})
+	
+	t.Run("Enroll should fail when 2FA is already enabled", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		mockRepo.On("FindByID", "user1").Return(models.User{ID: "user1", TwoFactorEnabled: true}, nil)
+		
+		// Act
+		_, err := service.Enroll(context.Background(), "user1")
+		
+		// Assert
+		assert.ErrorIs(t, err, services.ErrTwoFactorAlreadyEnabled)
+		mockRepo.AssertNotCalled(t, "SetTwoFactorSecret", mock.Anything, mock.Anything)
+	})
+	
+	t.Run("Confirm should enable 2FA and store hashed recovery codes", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{ID: "user1", TwoFactorSecret: secret}
+		code, _ := totp.GenerateCode(secret, time.Now())
+		
+		var hashes []string
+		mockRepo.On("FindByID", "user1").Return(user, nil)
+		mockRepo.On("ConsumeTwoFactorStep", "user1", mock.AnythingOfType("int64")).Return(true, nil)
+		mockRepo.On("EnableTwoFactor", "user1", mock.AnythingOfType("[]string")).
+			Run(func(args mock.Arguments) { hashes = args.Get(1).([]string) }).
+			Return(nil)
+		
+		// Act
+		codes, err := service.Confirm(context.Background(), "user1", code)
+		
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, codes, 10)
+		assert.Len(t, hashes, 10)
+		assert.NotContains(t, hashes, codes[0])
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+	
+	t.Run("Confirm should reject an invalid code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		mockRepo.On("FindByID", "user1").Return(models.User{ID: "user1", TwoFactorSecret: secret}, nil)
+		
+		// Act
+		_, err := service.Confirm(context.Background(), "user1", "12345x")
+		
+		// Assert
+		assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+		mockRepo.AssertNotCalled(t, "EnableTwoFactor", mock.Anything, mock.Anything)
+	})
+	
+	t.Run("ChallengedUser should find the user the challenge was issued to", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{ID: "user1", TwoFactorSecret: secret, TwoFactorEnabled: true}
+		mockRepo.On("FindByID", "user1").Return(user, nil)
+		
+		challenge, err := service.CreateChallenge(context.Background(), user)
+		assert.NoError(t, err)
+		
+		// Act
+		result, err := service.ChallengedUser(context.Background(), challenge.ChallengeToken)
+		
+		// Assert - no code is used up yet
+		assert.NoError(t, err)
+		assert.Equal(t, "user1", result.ID)
+		mockRepo.AssertNotCalled(t, "ConsumeTwoFactorStep", mock.Anything, mock.Anything)
+	})
+	
+	t.Run("VerifyLoginCode should accept a TOTP code once", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{ID: "user1", TwoFactorSecret: secret, TwoFactorEnabled: true}
+		mockRepo.On("ConsumeTwoFactorStep", "user1", mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockRepo.On("ConsumeTwoFactorStep", "user1", mock.AnythingOfType("int64")).Return(false, nil)
+		
+		code, _ := totp.GenerateCode(secret, time.Now())
+		
+		// Act
+		err := service.VerifyLoginCode(context.Background(), user, code)
+		replayErr := service.VerifyLoginCode(context.Background(), user, code)
+		
+		// Assert
+		assert.NoError(t, err)
+		assert.ErrorIs(t, replayErr, services.ErrInvalidTwoFactorCode)
+	})
+	
+	t.Run("VerifyLoginCode should accept a recovery code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{ID: "user1", TwoFactorSecret: secret, TwoFactorEnabled: true}
+		mockRepo.On("ConsumeRecoveryCode", "user1", mock.AnythingOfType("string")).Return(true, nil)
+		
+		// Act
+		err := service.VerifyLoginCode(context.Background(), user, "abcde-12345")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+	
+	t.Run("ChallengedUser should reject an access token", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		accessToken, err := middleware.NewAuthMiddleware(keySet).GenerateToken("user1", "test@example.com", models.RoleCustomer)
+		assert.NoError(t, err)
+		
+		// Act
+		_, err = service.ChallengedUser(context.Background(), accessToken)
+		
+		// Assert
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+	
+	t.Run("ChallengedUser should reject a token without the login-2fa audience", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		token, err := keySet.Sign(&jwt.RegisteredClaims{
+			Subject:   "user1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+		assert.NoError(t, err)
+		
+		// Act
+		_, err = service.ChallengedUser(context.Background(), token)
+		
+		// Assert
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+	
+	t.Run("CreateChallenge should expire after the configured TTL", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, services.TwoFactorOptions{KeySet: keySet, ChallengeTTL: -time.Second})
+		
+		user := models.User{ID: "user1", TwoFactorEnabled: true}
+		
+		// Act
+		challenge, err := service.CreateChallenge(context.Background(), user)
+		assert.NoError(t, err)
+		_, err = service.ChallengedUser(context.Background(), challenge.ChallengeToken)
+		
+		// Assert
+		assert.WithinDuration(t, time.Now().Add(-time.Second), challenge.ExpiresAt, time.Second)
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+	
+	t.Run("Reset should clear the secret and recovery codes", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewTwoFactorService(mockRepo, options)
+		
+		user := models.User{
+			ID:                 "user1",
+			TwoFactorSecret:    secret,
+			TwoFactorEnabled:   true,
+			RecoveryCodeHashes: []string{"hash"},
+		}
+		mockRepo.On("FindByID", "user1").Return(user, nil)
+		mockRepo.On("ResetTwoFactor", "user1").Return(nil)
+		
+		// Act
+		err := service.Reset(context.Background(), "user1")
+		
+		// Assert
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
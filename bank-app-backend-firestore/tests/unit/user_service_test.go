@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -41,7 +42,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("Create", mock.AnythingOfType("models.User")).Return(createdUser, nil)
 		
 		// Act
-		userDTO, err := service.Create(inputUser)
+		userDTO, err := service.Create(context.Background(), inputUser)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -67,7 +68,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByID", "user1").Return(user, nil)
 		
 		// Act
-		result, err := service.GetByID("user1")
+		result, err := service.GetByID(context.Background(), "user1")
 		
 		// Assert
 		assert.NoError(t, err)
@@ -84,7 +85,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByID", "nonexistent").Return(models.User{}, errors.New("user not found"))
 		
 		// Act
-		result, err := service.GetByID("nonexistent")
+		result, err := service.GetByID(context.Background(), "nonexistent")
 		
 		// Assert
 		assert.Error(t, err)
@@ -114,7 +115,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "test@example.com").Return(user, nil)
 		
 		// Act
-		result, err := service.Authenticate("test@example.com", password)
+		result, err := service.Authenticate(context.Background(), "test@example.com", password)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -130,7 +131,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "nonexistent@example.com").Return(models.User{}, errors.New("user not found"))
 		
 		// Act
-		result, err := service.Authenticate("nonexistent@example.com", "password123")
+		result, err := service.Authenticate(context.Background(), "nonexistent@example.com", "password123")
 		
 		// Assert
 		assert.Error(t, err)
@@ -159,13 +160,39 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "test@example.com").Return(user, nil)
 		
 		// Act
-		result, err := service.Authenticate("test@example.com", "wrongpassword")
+		result, err := service.Authenticate(context.Background(), "test@example.com", "wrongpassword")
 		
 		// Assert
 		assert.Error(t, err)
 		assert.Equal(t, models.User{}, result)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Authenticate should refuse a disabled user", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(MockUserRepository)
+		service := services.NewUserService(mockRepo)
+
+		password := "password123"
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+		user := models.User{
+			ID:         "user1",
+			Email:      "test@example.com",
+			Password:   string(hashedPassword),
+			DisabledAt: now,
+		}
+
+		mockRepo.On("FindByEmail", "test@example.com").Return(user, nil)
+
+		// Act
+		result, err := service.Authenticate(context.Background(), "test@example.com", password)
+
+		// Assert
+		assert.ErrorIs(t, err, services.ErrUserDisabled)
+		assert.Equal(t, models.User{}, result)
+		mockRepo.AssertExpectations(t)
+	})
 	
 	t.Run("GetAll should return all users", func(t *testing.T) {
 		// Arrange
@@ -194,7 +221,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindAll").Return(users, nil)
 		
 		// Act
-		result, err := service.GetAll()
+		result, err := service.GetAll(context.Background())
 		
 		// Assert
 		assert.NoError(t, err)
@@ -230,7 +257,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("Update", user).Return(updatedUser, nil)
 		
 		// Act
-		result, err := service.Update(user)
+		result, err := service.Update(context.Background(), user)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/config"
+	"github.com/jbadhree/drank/bank-app-backend/internal/logging"
+	"github.com/jbadhree/drank/bank-app-backend/internal/mailer"
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend/internal/migrations"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/sanctions"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"github.com/jbadhree/drank/bank-app-backend/internal/tracing"
+)
+
+// app is what every command shares: the configuration, the database and its
+// migrations, set up the same way for the server and the admin commands
+type app struct {
+	cfg             *config.Config
+	logger          *slog.Logger
+	db              *gorm.DB
+	keyring         *pii.Keyring
+	migrator        *migrations.Migrator
+	shutdownTracing func(context.Context) error
+}
+
+// loadConfig loads the configuration and sets up logging with it
+func loadConfig(src config.Sources, logOutput io.Writer) (*config.Config, *slog.Logger, error) {
+	// Load environment variables
+	envErr := godotenv.Load()
+
+	// Configure the application
+	cfg, err := config.Load(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Log JSON lines through slog. Making it the default also sends the
+	// standard library logger, and so any library using it, through it.
+	logger, err := logging.New(logOutput, cfg.LogLevel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	if envErr != nil {
+		slog.Warn(".env file not found, using system environment variables")
+	}
+	return cfg, logger, nil
+}
+
+// newApp loads the configuration and connects to the database. Unless
+// checkSchema is false, it fails while migrations are pending.
+func newApp(src config.Sources, logOutput io.Writer, checkSchema bool) (*app, error) {
+	cfg, logger, err := loadConfig(src, logOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send spans to the configured exporter
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "drank-backend",
+		Exporter:    cfg.TracingExporter,
+		File:        cfg.TracingFile,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set up tracing: %w", err)
+	}
+
+	// Initialize database
+	db, err := initDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	// Load the keyring that encrypts customer PII. It must be registered
+	// before the first query touches the users table.
+	keyring, err := pii.New(cfg.PIIKeyringFile)
+	if err != nil {
+		return nil, fmt.Errorf("load PII keyring: %w", err)
+	}
+	pii.Register(keyring)
+
+	// Load the schema migrations compiled into the binary
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+
+	// Refuse to run against a schema older than this release expects
+	if checkSchema {
+		if err := migrator.Check(context.Background()); err != nil {
+			return nil, fmt.Errorf("%w, run `drank-backend migrate up`", err)
+		}
+	}
+
+	return &app{
+		cfg:             cfg,
+		logger:          logger,
+		db:              db,
+		keyring:         keyring,
+		migrator:        migrator,
+		shutdownTracing: shutdownTracing,
+	}, nil
+}
+
+// close flushes spans and closes the database connections, if the app has them
+func (a *app) close(ctx context.Context) {
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush spans", "error", err)
+		}
+	}
+	if a.db == nil {
+		return
+	}
+	if sqlDB, err := a.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func initDB(cfg *config.Config) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logging.NewGormLogger(cfg.SlowQueryThreshold),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	if err := db.Use(repository.TimeoutPlugin{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// newMigrator returns a migrator for the migrations compiled into the binary
+func newMigrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB, embedded), nil
+}
+
+// serviceSet holds the services built on the database
+type serviceSet struct {
+	keySet        *signing.KeySet
+	documents     *storage.LocalStore
+	user          services.UserService
+	account       services.AccountService
+	transaction   services.TransactionService
+	password      services.PasswordService
+	token         services.TokenService
+	identity      services.IdentityService
+	twoFactor     services.TwoFactorService
+	loginAttempt  services.LoginAttemptService
+	serviceClient services.ServiceClientService
+	audit         services.AuditService
+	sanctions     services.SanctionsService
+	kyc           services.KYCService
+	admin         services.AdminService
+	ledger        services.LedgerService
+}
+
+// services wires the repositories and services from the configuration
+func (a *app) services() (*serviceSet, error) {
+	cfg, db := a.cfg, a.db
+
+	// Initialize mailer
+	mail, err := mailer.New(cfg.Mailer, cfg.MailDir, cfg.SMTPAddr, cfg.MailFrom)
+	if err != nil {
+		return nil, fmt.Errorf("initialize mailer: %w", err)
+	}
+
+	// Load the token signing keys
+	keySet, err := signing.New(cfg.JWTKeysDir, cfg.JWTSigningKeyID, cfg.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("load signing keys: %w", err)
+	}
+
+	// Load the breached password list
+	var breachedPasswords services.BreachedPasswords
+	if cfg.PasswordBreachedList != "" {
+		breachedPasswords, err = services.LoadBreachedPasswords(cfg.PasswordBreachedList)
+		if err != nil {
+			return nil, fmt.Errorf("load breached password list: %w", err)
+		}
+	}
+
+	// Load the sanctions watchlist
+	var watchlist *sanctions.List
+	if cfg.SanctionsListFile != "" {
+		watchlist, err = sanctions.LoadList(strings.Split(cfg.SanctionsListFile, ",")...)
+		if err != nil {
+			return nil, fmt.Errorf("load sanctions watchlist: %w", err)
+		}
+		slog.Info("Loaded sanctions watchlist", "entries", watchlist.Len())
+	}
+
+	// Initialize repositories
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	emailTokenRepo := repository.NewEmailTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	serviceClientRepo := repository.NewServiceClientRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	transferReviewRepo := repository.NewTransferReviewRepository(db)
+	screeningHitRepo := repository.NewScreeningHitRepository(db)
+	kycRepo := repository.NewKYCRepository(db)
+
+	// Initialize services
+	var fraudService services.FraudService
+	if cfg.FraudScreening {
+		fraudService = services.NewFraudService(transactionRepo, auditRepo, services.FraudOptions{
+			ReviewScore:          cfg.FraudReviewScore,
+			BlockScore:           cfg.FraudBlockScore,
+			VelocityCount:        cfg.FraudVelocityCount,
+			VelocityWindow:       cfg.FraudVelocityWindow,
+			VelocityScore:        cfg.FraudVelocityScore,
+			AverageMultiplier:    cfg.FraudAverageMultiplier,
+			AverageMinHistory:    cfg.FraudAverageMinHistory,
+			AverageScore:         cfg.FraudAverageScore,
+			NewPayeeAmount:       cfg.FraudNewPayeeAmount,
+			NewPayeeScore:        cfg.FraudNewPayeeScore,
+			PasswordChangeWindow: cfg.FraudPasswordChangeWindow,
+			PasswordChangeScore:  cfg.FraudPasswordChangeScore,
+		})
+	}
+	sanctionsService := services.NewSanctionsService(screeningHitRepo, userRepo, services.SanctionsOptions{
+		List:       watchlist,
+		FlagScore:  cfg.SanctionsFlagScore,
+		BlockScore: cfg.SanctionsBlockScore,
+	})
+	var screening services.SanctionsService
+	if watchlist != nil {
+		screening = sanctionsService
+	}
+	documents := storage.NewLocalStore(cfg.KYCDocumentDir)
+	kycService := services.NewKYCService(kycRepo, transactionRepo, services.KYCOptions{
+		Documents:       documents,
+		MaxDocumentSize: cfg.KYCMaxDocumentSize,
+		Limits: map[int]services.KYCLimits{
+			models.KYCLevelBasic: {PerTransfer: cfg.KYCLevel1TransferLimit, Daily: cfg.KYCLevel1DailyLimit},
+			models.KYCLevelFull:  {PerTransfer: cfg.KYCLevel2TransferLimit, Daily: cfg.KYCLevel2DailyLimit},
+		},
+	})
+	var kycCheck services.KYCService
+	if cfg.KYCRequired {
+		kycCheck = kycService
+	}
+	passwordService := services.NewPasswordService(userRepo, services.PasswordOptions{
+		MinLength:  cfg.PasswordMinLength,
+		BcryptCost: cfg.BcryptCost,
+		Breached:   breachedPasswords,
+	})
+	tokenService := services.NewTokenService(tokenRepo, userRepo, services.TokenOptions{
+		KeySet:          keySet,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
+		StepUpTTL:       cfg.StepUpTTL,
+		ClientTokenTTL:  cfg.ClientTokenTTL,
+
+		SessionTouchInterval: cfg.SessionTouchInterval,
+		NewDeviceNotifier:    services.NewMailSessionNotifier(mail),
+	})
+
+	return &serviceSet{
+		keySet:    keySet,
+		documents: documents,
+		user:      services.NewUserService(userRepo),
+		account:   services.NewAccountService(accountRepo),
+		transaction: services.NewTransactionService(transactionRepo, accountRepo, auditRepo, transferReviewRepo, services.TransactionOptions{
+			StepUpThreshold: cfg.StepUpTransferThreshold,
+			StepUpMaxAge:    cfg.StepUpTTL,
+			Fraud:           fraudService,
+			Sanctions:       screening,
+			KYC:             kycCheck,
+		}),
+		password: passwordService,
+		token:    tokenService,
+		identity: services.NewIdentityService(userRepo, emailTokenRepo, tokenService, passwordService, mail, services.IdentityOptions{
+			AppBaseURL:               cfg.AppBaseURL,
+			RequireEmailVerification: cfg.RequireEmailVerification,
+			VerificationTokenTTL:     cfg.VerificationTokenTTL,
+			PasswordResetTokenTTL:    cfg.PasswordResetTokenTTL,
+			Sanctions:                screening,
+		}),
+		twoFactor: services.NewTwoFactorService(userRepo, recoveryCodeRepo, services.TwoFactorOptions{
+			KeySet:       keySet,
+			Issuer:       cfg.TwoFactorIssuer,
+			ChallengeTTL: cfg.TwoFactorChallengeTTL,
+		}),
+		loginAttempt: services.NewLoginAttemptService(loginAttemptRepo, userRepo, services.LoginAttemptOptions{
+			MaxFailures:     cfg.LoginMaxFailures,
+			FailureWindow:   cfg.LoginFailureWindow,
+			LockoutDuration: cfg.LoginLockoutDuration,
+			IPMaxFailures:   cfg.LoginIPMaxFailures,
+			DelayBase:       cfg.LoginDelayBase,
+		}),
+		serviceClient: services.NewServiceClientService(serviceClientRepo, tokenService),
+		audit:         services.NewAuditService(auditRepo),
+		sanctions:     sanctionsService,
+		kyc:           kycService,
+		admin:         services.NewAdminService(userRepo, accountRepo, passwordService, tokenService),
+		ledger:        services.NewLedgerService(accountRepo, transactionRepo),
+	}, nil
+}
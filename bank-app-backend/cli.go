@@ -0,0 +1,834 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osuser "os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/config"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"github.com/jbadhree/drank/bank-app-backend/seed"
+)
+
+// Exit statuses of the command line
+const (
+	exitOK      = 0
+	exitFailure = 1 // The command ran and failed, or found a problem
+	exitUsage   = 2 // The command line was wrong
+)
+
+// command is a node of the command tree. Groups have subcommands, the rest
+// have setup, which defines the command's flags and returns what runs it.
+type command struct {
+	name     string
+	args     string // Shown after the name and flags in the usage line
+	summary  string
+	commands []*command
+	setup    func(fs *flag.FlagSet) runFunc
+
+	// anySchema lets the command run while migrations are pending
+	anySchema bool
+	// configOnly runs the command with the configuration alone, without
+	// connecting to the database
+	configOnly bool
+	// logToStdout sends the logs to stdout rather than stderr, which
+	// commands keep for their logs so that their output can be piped
+	logToStdout bool
+}
+
+// runFunc runs a command with its remaining arguments, writing its
+// output to out
+type runFunc func(ctx context.Context, a *app, out io.Writer, args []string) error
+
+// usageError is returned by commands for a wrong command line
+type usageError struct{ error }
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// legacyFlags maps the flags that used to select a command to the command
+var legacyFlags = map[string][]string{
+	"--seed":          {"seed"},
+	"--verify-audit":  {"audit", "verify"},
+	"--reencrypt-pii": {"pii", "reencrypt"},
+}
+
+// settingsFlag collects repeated -set KEY=VALUE flags
+type settingsFlag map[string]string
+
+func (s settingsFlag) String() string { return "" }
+
+func (s settingsFlag) Set(value string) error {
+	key, setting, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("must be KEY=VALUE")
+	}
+	s[strings.ToUpper(key)] = setting
+	return nil
+}
+
+// globalFlags defines the flags that come before the command and configure
+// every command, writing them into src
+func globalFlags(fs *flag.FlagSet, src *config.Sources) {
+	src.Overrides = settingsFlag{}
+	fs.StringVar(&src.File, "config", "", "YAML file of settings (default $CONFIG_FILE)")
+	fs.Var(settingsFlag(src.Overrides), "set", "override a setting, as KEY=VALUE; repeatable")
+}
+
+// execute runs the command line and returns the exit status. Without
+// arguments it serves the API.
+func execute(args []string, stdout, stderr io.Writer) int {
+	var src config.Sources
+	global := flag.NewFlagSet("drank-backend", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { printHelp(stderr, rootCommand(), []string{"drank-backend"}) }
+	globalFlags(global, &src)
+	if len(args) == 0 || !legacyFlagGiven(args[0]) {
+		if err := global.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		args = global.Args()
+	}
+
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	if replacement, ok := legacyFlags[args[0]]; ok {
+		args = append(append([]string{}, replacement...), args[1:]...)
+	}
+
+	root := rootCommand()
+	cmd, path, args, err := resolve(root, args)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n\n", err)
+		printHelp(stderr, cmd, path)
+		return exitUsage
+	}
+	if cmd == nil {
+		// help was asked for
+		target, targetPath, _, err := resolve(root, args)
+		if err != nil || len(targetPath) != len(args)+1 {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", strings.Join(args, " "))
+			printHelp(stderr, root, []string{root.name})
+			return exitUsage
+		}
+		printHelp(stdout, target, targetPath)
+		return exitOK
+	}
+	if cmd.commands != nil {
+		printHelp(stderr, cmd, path)
+		return exitUsage
+	}
+
+	fs := newFlagSet(cmd, path, stderr)
+	run := cmd.setup(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	logOutput := stderr
+	if cmd.logToStdout {
+		logOutput = stdout
+	}
+	var a *app
+	if cmd.configOnly {
+		var cfg *config.Config
+		cfg, _, err = loadConfig(src, logOutput)
+		a = &app{cfg: cfg}
+	} else {
+		a, err = newApp(src, logOutput, !cmd.anySchema)
+	}
+	if err != nil {
+		var problems config.Problems
+		if errors.As(err, &problems) {
+			// Logging may be what is misconfigured, so the report goes to stderr as it is
+			fmt.Fprintln(stderr, "Invalid configuration:")
+			for _, problem := range problems {
+				fmt.Fprintf(stderr, "  - %s\n", problem)
+			}
+			return exitFailure
+		}
+		slog.Error("Failed to start", "command", strings.Join(path[1:], " "), "error", err)
+		return exitFailure
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		a.close(ctx)
+	}()
+
+	if err := run(context.Background(), a, stdout, fs.Args()); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			fmt.Fprintf(stderr, "%v\n\n", err)
+			fs.Usage()
+			return exitUsage
+		}
+		slog.Error("Command failed", "command", strings.Join(path[1:], " "), "error", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// legacyFlagGiven reports whether arg is one of the legacyFlags, which are
+// not global flags even though they come first
+func legacyFlagGiven(arg string) bool {
+	_, ok := legacyFlags[arg]
+	return ok
+}
+
+// resolve walks args down the command tree to a leaf or to a group that
+// ran out of arguments, returning it with its path and the arguments left.
+// It returns a nil command when help was asked for, with the arguments
+// naming the command to help with.
+func resolve(root *command, args []string) (*command, []string, []string, error) {
+	cmd, path := root, []string{root.name}
+	for cmd.commands != nil && len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			return nil, nil, append(path[1:len(path):len(path)], args[1:]...), nil
+		}
+		next := cmd.find(args[0])
+		if next == nil {
+			return cmd, path, args, fmt.Errorf("unknown command %q", strings.Join(append(path[1:len(path):len(path)], args[0]), " "))
+		}
+		cmd, path, args = next, append(path, next.name), args[1:]
+	}
+	return cmd, path, args, nil
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns the flag set of a leaf command, with its usage
+func newFlagSet(cmd *command, path []string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		printLeafUsage(fs.Output(), cmd, path, fs)
+	}
+	return fs
+}
+
+// printHelp prints the usage of a command, listing a group's subcommands
+func printHelp(w io.Writer, cmd *command, path []string) {
+	if cmd.commands == nil {
+		fs := newFlagSet(cmd, path, w)
+		cmd.setup(fs)
+		printLeafUsage(w, cmd, path, fs)
+		return
+	}
+
+	fmt.Fprintf(w, "Usage: %s <command> [arguments]\n\n", strings.Join(path, " "))
+	if cmd.summary != "" {
+		fmt.Fprintf(w, "%s\n\n", cmd.summary)
+	}
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, sub := range cmd.commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", sub.name, sub.summary)
+	}
+	tw.Flush()
+	if len(path) == 1 {
+		fmt.Fprintln(w, "\nGlobal flags, given before the command:")
+		fs := flag.NewFlagSet(path[0], flag.ContinueOnError)
+		fs.SetOutput(w)
+		globalFlags(fs, &config.Sources{})
+		fs.PrintDefaults()
+	}
+	fmt.Fprintf(w, "\nRun `%s help <command>` for a command's usage.\n", strings.Join(path, " "))
+}
+
+func printLeafUsage(w io.Writer, cmd *command, path []string, fs *flag.FlagSet) {
+	usage := strings.Join(path, " ")
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [flags]"
+	}
+	if cmd.args != "" {
+		usage += " " + cmd.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, cmd.summary)
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+// rootCommand returns the command tree
+func rootCommand() *command {
+	return &command{
+		name:    "drank-backend",
+		summary: "The banking API server and the commands that administer its database.",
+		commands: []*command{
+			{
+				name:        "serve",
+				summary:     "Run the API server (the default without a command)",
+				logToStdout: true,
+				setup: func(fs *flag.FlagSet) runFunc {
+					return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+						return serve(a)
+					}
+				},
+			},
+			{
+				name:    "migrate",
+				summary: "Apply, revert or list schema migrations",
+				commands: []*command{
+					{
+						name:      "up",
+						summary:   "Apply the pending migrations",
+						anySchema: true,
+						setup:     func(fs *flag.FlagSet) runFunc { return migrateUp },
+					},
+					{
+						name:      "down",
+						args:      "[steps]",
+						summary:   "Revert the newest migration, or the given number of them",
+						anySchema: true,
+						setup:     func(fs *flag.FlagSet) runFunc { return migrateDown },
+					},
+					{
+						name:      "status",
+						summary:   "List the migrations and when they were applied",
+						anySchema: true,
+						setup:     func(fs *flag.FlagSet) runFunc { return migrateStatus },
+					},
+				},
+			},
+			{
+				name:    "seed",
+				summary: "Load a seed profile or fixture file: " + strings.Join(seed.Profiles, ", "),
+				setup:   seedCommand,
+			},
+			{
+				name:    "generate",
+				summary: "Add simulated customers with realistic history, for load tests and demos",
+				setup:   generateCommand,
+			},
+			{
+				name:    "user",
+				summary: "Create, disable and reset the password of users",
+				commands: []*command{
+					{name: "create", summary: "Create a user with a verified email address", setup: userCreate},
+					{name: "disable", summary: "Stop a user logging in and end their sessions", setup: userDisable},
+					{name: "reset-password", summary: "Set a user's password and end their sessions", setup: userResetPassword},
+				},
+			},
+			{
+				name:    "account",
+				summary: "Freeze and unfreeze accounts",
+				commands: []*command{
+					{name: "freeze", summary: "Refuse transfers into and out of an account", setup: accountFreeze(true)},
+					{name: "unfreeze", summary: "Allow transfers into and out of an account again", setup: accountFreeze(false)},
+				},
+			},
+			{
+				name:    "reconcile",
+				summary: "Check every account's balance against its transactions",
+				setup:   reconcile,
+			},
+			{
+				name:    "accrue-interest",
+				summary: "Credit a day of interest to savings accounts, once per day",
+				setup:   accrueInterest,
+			},
+			{
+				name:    "prune-tokens",
+				summary: "Delete revoked access tokens and refresh tokens that have expired",
+				setup:   func(fs *flag.FlagSet) runFunc { return pruneTokens },
+			},
+			{
+				name:    "audit",
+				summary: "Check the audit log",
+				commands: []*command{
+					{name: "verify", summary: "Verify the audit log's hash chain", setup: func(fs *flag.FlagSet) runFunc { return verifyAuditLog }},
+				},
+			},
+			{
+				name:    "pii",
+				summary: "Manage encrypted customer data",
+				commands: []*command{
+					{name: "reencrypt", summary: "Move every encrypted column and KYC document to the current key", setup: func(fs *flag.FlagSet) runFunc { return reencryptPII }},
+				},
+			},
+			{
+				name:    "config",
+				summary: "Inspect the configuration",
+				commands: []*command{
+					{name: "print", summary: "Print every setting, where it came from, and exit", configOnly: true, setup: configPrint},
+				},
+			},
+		},
+	}
+}
+
+func migrateUp(ctx context.Context, a *app, out io.Writer, args []string) error {
+	applied, err := a.migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	slog.Info("Database schema is up to date", "applied", len(applied))
+	return nil
+}
+
+func migrateDown(ctx context.Context, a *app, out io.Writer, args []string) error {
+	steps := 1
+	if len(args) > 1 {
+		return usageErrorf("expected at most one argument")
+	}
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return usageErrorf("%q is not a positive number", args[0])
+		}
+		steps = n
+	}
+	reverted, err := a.migrator.Down(ctx, steps)
+	if err != nil {
+		return fmt.Errorf("revert migrations: %w", err)
+	}
+	for _, m := range reverted {
+		slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+	}
+	return nil
+}
+
+func migrateStatus(ctx context.Context, a *app, out io.Writer, args []string) error {
+	statuses, err := a.migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("read migration status: %w", err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			fmt.Fprintf(out, "%04d_%s\tpending\n", st.Version, st.Name)
+		} else {
+			fmt.Fprintf(out, "%04d_%s\tapplied %s\n", st.Version, st.Name, st.AppliedAt.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func seedCommand(fs *flag.FlagSet) runFunc {
+	profile := fs.String("profile", seed.ProfileDemo, "built-in profile to load: "+strings.Join(seed.Profiles, " or "))
+	file := fs.String("file", "", "fixture file to load instead of a profile")
+	seedValue := fs.Int64("seed-value", 1, "seed for the random activity and account numbers")
+	additive := fs.Bool("additive", false, "keep the existing data and skip users that already exist")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		source := *file
+		if source == "" {
+			known := false
+			for _, p := range seed.Profiles {
+				known = known || p == *profile
+			}
+			if !known {
+				return usageErrorf("unknown profile %q", *profile)
+			}
+			source = *profile
+		}
+		fixture, err := seed.LoadFixture(source)
+		if err != nil {
+			return fmt.Errorf("load fixture: %w", err)
+		}
+
+		slog.Info("Seeding database", "name", a.cfg.DBName, "port", a.cfg.DBPort, "fixture", source, "seed_value", *seedValue, "additive", *additive)
+		summary, err := seed.Seed(a.db.WithContext(ctx), fixture, seed.Options{SeedValue: *seedValue, Additive: *additive})
+		if err != nil {
+			return fmt.Errorf("seed database: %w", err)
+		}
+		for _, email := range summary.Skipped {
+			slog.Info("Skipped existing user", "email", email)
+		}
+		slog.Info("Database seeded successfully", "users", summary.Users, "accounts", summary.Accounts, "transactions", summary.Transactions)
+		return nil
+	}
+}
+
+func generateCommand(fs *flag.FlagSet) runFunc {
+	customers := fs.Int("customers", 100, "how many customers to add")
+	from := fs.String("from", "", "first day of history, as YYYY-MM-DD (default 90 days before -to)")
+	to := fs.String("to", "", "last day of history, as YYYY-MM-DD (default yesterday, UTC)")
+	seedValue := fs.Int64("seed-value", 1, "seed for everything about the customers")
+	prefix := fs.String("prefix", "customer", "customers get the emails <prefix><n>@example.com")
+	password := fs.String("password", "password123", "password of every customer")
+	batch := fs.Int("batch", 100, "customers written in each database transaction")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		last := time.Now().UTC().AddDate(0, 0, -1)
+		if *to != "" {
+			parsed, err := time.Parse("2006-01-02", *to)
+			if err != nil {
+				return usageErrorf("-to must be YYYY-MM-DD: %v", err)
+			}
+			last = parsed
+		}
+		first := last.AddDate(0, 0, -90)
+		if *from != "" {
+			parsed, err := time.Parse("2006-01-02", *from)
+			if err != nil {
+				return usageErrorf("-from must be YYYY-MM-DD: %v", err)
+			}
+			first = parsed
+		}
+		switch {
+		case *customers < 1 || *batch < 1:
+			return usageErrorf("-customers and -batch must be positive")
+		case last.Before(first):
+			return usageErrorf("-from must not be after -to")
+		case *prefix == "" || *password == "":
+			return usageErrorf("-prefix and -password must not be empty")
+		}
+
+		summary, err := seed.Generate(a.db.WithContext(ctx), seed.SimulateOptions{
+			Customers:   *customers,
+			From:        first,
+			To:          last,
+			SeedValue:   *seedValue,
+			Password:    *password,
+			EmailPrefix: *prefix,
+			BatchSize:   *batch,
+		})
+		if err != nil {
+			return fmt.Errorf("generate customers: %w", err)
+		}
+		fmt.Fprintf(out, "Added %d customers with %d accounts and %d transactions from %s to %s\n",
+			summary.Users, summary.Accounts, summary.Transactions, first.Format("2006-01-02"), last.Format("2006-01-02"))
+		return nil
+	}
+}
+
+func userCreate(fs *flag.FlagSet) runFunc {
+	email := fs.String("email", "", "email address (required)")
+	firstName := fs.String("first-name", "", "first name (required)")
+	lastName := fs.String("last-name", "", "last name (required)")
+	role := fs.String("role", models.RoleCustomer, "role: customer or admin")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		if *email == "" || *firstName == "" || *lastName == "" {
+			return usageErrorf("-email, -first-name and -last-name are required")
+		}
+		password, generated, err := readPassword(*passwordStdin)
+		if err != nil {
+			return err
+		}
+		s, err := a.services()
+		if err != nil {
+			return err
+		}
+
+		user, err := s.admin.CreateUser(ctx, &models.RegisterRequest{
+			Email:     *email,
+			Password:  password,
+			FirstName: *firstName,
+			LastName:  *lastName,
+		}, *role, operator())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created %s %d <%s>\n", user.Role, user.ID, user.Email)
+		if generated {
+			fmt.Fprintf(out, "Password: %s\n", password)
+		}
+		return nil
+	}
+}
+
+func userDisable(fs *flag.FlagSet) runFunc {
+	id, email := userFlags(fs)
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		userID, err := findUser(ctx, a, *id, *email)
+		if err != nil {
+			return err
+		}
+		s, err := a.services()
+		if err != nil {
+			return err
+		}
+		if err := s.admin.DisableUser(ctx, userID, operator()); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Disabled user %d\n", userID)
+		return nil
+	}
+}
+
+func userResetPassword(fs *flag.FlagSet) runFunc {
+	id, email := userFlags(fs)
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		userID, err := findUser(ctx, a, *id, *email)
+		if err != nil {
+			return err
+		}
+		password, generated, err := readPassword(*passwordStdin)
+		if err != nil {
+			return err
+		}
+		s, err := a.services()
+		if err != nil {
+			return err
+		}
+		if err := s.admin.ResetPassword(ctx, userID, password, operator()); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Reset the password of user %d\n", userID)
+		if generated {
+			fmt.Fprintf(out, "Password: %s\n", password)
+		}
+		return nil
+	}
+}
+
+// userFlags defines the flags that pick a user
+func userFlags(fs *flag.FlagSet) (*uint, *string) {
+	id := fs.Uint("id", 0, "user ID")
+	email := fs.String("email", "", "email address, instead of -id")
+	return id, email
+}
+
+// findUser returns the ID of the user picked by exactly one of id and email
+func findUser(ctx context.Context, a *app, id uint, email string) (uint, error) {
+	if (id == 0) == (email == "") {
+		return 0, usageErrorf("exactly one of -id and -email is required")
+	}
+	if id != 0 {
+		return id, nil
+	}
+	user, err := repository.NewUserRepository(a.db).FindByEmail(ctx, email)
+	if err != nil {
+		return 0, fmt.Errorf("find user %s: %w", email, err)
+	}
+	return user.ID, nil
+}
+
+func accountFreeze(frozen bool) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		id := fs.Uint("id", 0, "account ID")
+		number := fs.String("number", "", "account number, instead of -id")
+		return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+			if (*id == 0) == (*number == "") {
+				return usageErrorf("exactly one of -id and -number is required")
+			}
+			accountID := *id
+			if *number != "" {
+				account, err := repository.NewAccountRepository(a.db).FindByAccountNumber(ctx, *number)
+				if err != nil {
+					return fmt.Errorf("find account %s: %w", *number, err)
+				}
+				accountID = account.ID
+			}
+			s, err := a.services()
+			if err != nil {
+				return err
+			}
+
+			if frozen {
+				err = s.admin.FreezeAccount(ctx, accountID, operator())
+			} else {
+				err = s.admin.UnfreezeAccount(ctx, accountID, operator())
+			}
+			if err != nil {
+				return err
+			}
+			if frozen {
+				fmt.Fprintf(out, "Froze account %d\n", accountID)
+			} else {
+				fmt.Fprintf(out, "Unfroze account %d\n", accountID)
+			}
+			return nil
+		}
+	}
+}
+
+func reconcile(fs *flag.FlagSet) runFunc {
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		s, err := a.services()
+		if err != nil {
+			return err
+		}
+		result, err := s.ledger.Reconcile(ctx)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+		} else if len(result.Discrepancies) > 0 {
+			tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ACCOUNT\tBALANCE\tLEDGER TOTAL\tLAST BALANCE\tENTRIES")
+			for _, d := range result.Discrepancies {
+				fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.2f\t%d\n", d.AccountNumber, d.Balance, d.LedgerTotal, d.LastBalance, d.Entries)
+			}
+			tw.Flush()
+		}
+
+		if len(result.Discrepancies) > 0 {
+			return fmt.Errorf("%d of %d accounts do not reconcile", len(result.Discrepancies), result.Checked)
+		}
+		slog.Info("Accounts reconcile", "accounts", result.Checked)
+		return nil
+	}
+}
+
+func accrueInterest(fs *flag.FlagSet) runFunc {
+	date := fs.String("date", "", "day to credit, as YYYY-MM-DD (default yesterday, UTC)")
+	rate := fs.Float64("rate", 0, "annual rate in percent (default SAVINGS_INTEREST_RATE)")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		day := time.Now().UTC().AddDate(0, 0, -1)
+		if *date != "" {
+			parsed, err := time.Parse("2006-01-02", *date)
+			if err != nil {
+				return usageErrorf("-date must be YYYY-MM-DD: %v", err)
+			}
+			day = parsed
+		}
+		annualRate := *rate
+		if annualRate == 0 {
+			annualRate = a.cfg.SavingsInterestRate
+		}
+		s, err := a.services()
+		if err != nil {
+			return err
+		}
+
+		result, err := s.ledger.AccrueInterest(ctx, day, annualRate, operator())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Credited %.2f to %d accounts for %s, skipped %d\n",
+			result.Total, result.Credited, result.Date.Format("2006-01-02"), result.Skipped)
+		return nil
+	}
+}
+
+// pruneTokens deletes the revocations and refresh tokens that can no longer be
+// presented, which logging out leaves behind
+func pruneTokens(ctx context.Context, a *app, out io.Writer, args []string) error {
+	s, err := a.services()
+	if err != nil {
+		return err
+	}
+	if err := s.token.DeleteExpired(ctx); err != nil {
+		return fmt.Errorf("prune tokens: %w", err)
+	}
+	fmt.Fprintln(out, "Deleted expired tokens")
+	return nil
+}
+
+// verifyAuditLog checks the audit log's hash chain and fails when an entry
+// was modified or deleted
+func verifyAuditLog(ctx context.Context, a *app, out io.Writer, args []string) error {
+	s, err := a.services()
+	if err != nil {
+		return err
+	}
+	result, err := s.audit.Verify(ctx)
+	if err != nil {
+		return fmt.Errorf("verify audit log: %w", err)
+	}
+	if !result.Valid {
+		slog.Error("Audit log verification FAILED", "valid_entries", result.Checked, "problem", result.Problem)
+		return errors.New("audit log verification failed")
+	}
+	slog.Info("Audit log verified", "entries", result.Checked, "head_hash", result.HeadHash)
+	return nil
+}
+
+// reencryptPII moves every encrypted column, and the KYC documents, to the
+// keyring's current key, one table at a time
+func reencryptPII(ctx context.Context, a *app, out io.Writer, args []string) error {
+	const batchSize = 500
+	tables := []struct {
+		name      string
+		reencrypt func() (int, error)
+	}{
+		{"users", func() (int, error) { return repository.ReencryptUsers(ctx, a.db, a.keyring, batchSize) }},
+		{"kyc_profiles", func() (int, error) { return repository.ReencryptKYCProfiles(ctx, a.db, a.keyring, batchSize) }},
+		{"kyc_documents", func() (int, error) {
+			return repository.ReencryptKYCDocuments(ctx, a.db, a.keyring, storage.NewLocalStore(a.cfg.KYCDocumentDir), batchSize)
+		}},
+		{"screening_hits", func() (int, error) { return repository.ReencryptScreeningHits(ctx, a.db, a.keyring, batchSize) }},
+	}
+	for _, table := range tables {
+		count, err := table.reencrypt()
+		if err != nil {
+			return fmt.Errorf("re-encrypt %s: %w", table.name, err)
+		}
+		slog.Info("Re-encrypted rows", "table", table.name, "rows", count, "key_version", a.keyring.CurrentVersion())
+	}
+	return nil
+}
+
+func configPrint(fs *flag.FlagSet) runFunc {
+	redacted := fs.Bool("redacted", false, "replace secrets such as DB_PASSWORD and JWT_SECRET")
+	return func(ctx context.Context, a *app, out io.Writer, args []string) error {
+		return a.cfg.Print(out, *redacted)
+	}
+}
+
+// readPassword reads a password from the first line of stdin, or
+// generates one when fromStdin is false
+func readPassword(fromStdin bool) (string, bool, error) {
+	if !fromStdin {
+		buf := make([]byte, 18)
+		if _, err := rand.Read(buf); err != nil {
+			return "", false, err
+		}
+		return base64.RawURLEncoding.EncodeToString(buf), true, nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, fmt.Errorf("read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", false, usageErrorf("no password on stdin")
+	}
+	return password, false, nil
+}
+
+// operator is the audit actor of the commands: the operating system user
+// who ran them
+func operator() models.AuditActor {
+	name := "unknown"
+	if current, err := osuser.Current(); err == nil {
+		name = current.Username
+	}
+	return models.AuditActor{Type: models.AuditActorOperator, ID: name}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute_Help(t *testing.T) {
+	// Arrange
+	var stdout, stderr bytes.Buffer
+
+	// Act
+	code := execute([]string{"help", "user", "create"}, &stdout, &stderr)
+
+	// Assert
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), "Usage: drank-backend user create [flags]")
+	assert.Contains(t, stdout.String(), "-password-stdin")
+	assert.Empty(t, stderr.String())
+}
+
+func TestExecute_UsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown command", []string{"bogus"}, `unknown command "bogus"`},
+		{"unknown subcommand", []string{"account", "close"}, `unknown command "account close"`},
+		{"group without subcommand", []string{"migrate"}, "Usage: drank-backend migrate <command>"},
+		{"unknown flag", []string{"reconcile", "-csv"}, "flag provided but not defined: -csv"},
+		{"help for unknown command", []string{"help", "user", "delete"}, `unknown command "user delete"`},
+		{"setting without value", []string{"-set", "PORT", "config", "print"}, "must be KEY=VALUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var stdout, stderr bytes.Buffer
+
+			// Act
+			code := execute(tt.args, &stdout, &stderr)
+
+			// Assert
+			assert.Equal(t, exitUsage, code)
+			assert.Contains(t, stderr.String(), tt.want)
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
+
+func TestExecute_ConfigPrint(t *testing.T) {
+	// Arrange
+	var stdout, stderr bytes.Buffer
+
+	// Act
+	code := execute([]string{"-set", "JWT_SECRET=top-secret", "-set", "port=9090", "config", "print", "--redacted"}, &stdout, &stderr)
+
+	// Assert
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), `JWT_SECRET="[REDACTED]" # flag`)
+	assert.Contains(t, stdout.String(), `PORT="9090" # flag`)
+	assert.NotContains(t, stdout.String(), "top-secret")
+}
+
+func TestExecute_InvalidConfiguration(t *testing.T) {
+	// Arrange
+	var stdout, stderr bytes.Buffer
+
+	// Act
+	code := execute([]string{"-set", "PORT=http", "-set", "LOG_LEVEL=loud", "config", "print"}, &stdout, &stderr)
+
+	// Assert
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stderr.String(), "Invalid configuration:\n")
+	assert.Contains(t, stderr.String(), `  - PORT: "http" is not a whole number`)
+	assert.Contains(t, stderr.String(), `  - LOG_LEVEL: "loud" must be one of debug, info, warn, error`)
+	assert.Empty(t, stdout.String())
+}
@@ -1,39 +1,320 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultJWTSecret is only acceptable in development mode
+const DefaultJWTSecret = "your-secret-key"
+
+// Environments the application runs in. Production refuses the development
+// defaults of secrets and requires a PII keyring.
+const (
+	Development = "development"
+	Production  = "production"
 )
 
+// Config is the application configuration, loaded by Load
 type Config struct {
-	DBHost     string
-	DBPort     int
-	DBUser     string
-	DBPassword string
-	DBName     string
-	Port       int
-	JWTSecret  string
+	Environment     string
+	DBHost          string
+	DBPort          int
+	DBUser          string
+	DBPassword      string
+	DBName          string
+	Port            int
+	TrustedProxies  []string // Addresses or CIDR ranges of the proxies whose X-Forwarded-For is believed; none when empty
+	JWTSecret       string
+	JWTKeysDir      string // Directory of PEM keys for RS256/EdDSA signing; HS256 with JWTSecret when empty
+	JWTSigningKeyID string // Kid of the key that signs new tokens; defaults to the last kid in JWTKeysDir
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	ClientTokenTTL  time.Duration // Lifetime of service client tokens from the client-credentials grant
+
+	SessionTouchInterval time.Duration // How often a session's last-seen time is written while it is in use
+
+	AppBaseURL               string
+	FrontendURL              string // Origin allowed by CORS besides http://localhost:3000
+	RequireEmailVerification bool
+	VerificationTokenTTL     time.Duration
+	PasswordResetTokenTTL    time.Duration
+	Mailer                   string
+	MailDir                  string
+	SMTPAddr                 string
+	MailFrom                 string
+
+	TwoFactorIssuer       string
+	TwoFactorChallengeTTL time.Duration
+
+	StepUpTransferThreshold float64
+	StepUpTTL               time.Duration
+
+	SavingsInterestRate float64 // Annual interest in percent credited daily to savings accounts by accrue-interest
+
+	FraudScreening            bool
+	FraudReviewScore          int
+	FraudBlockScore           int
+	FraudVelocityCount        int
+	FraudVelocityWindow       time.Duration
+	FraudVelocityScore        int
+	FraudAverageMultiplier    float64
+	FraudAverageMinHistory    int
+	FraudAverageScore         int
+	FraudNewPayeeAmount       float64
+	FraudNewPayeeScore        int
+	FraudPasswordChangeWindow time.Duration
+	FraudPasswordChangeScore  int
+
+	SanctionsListFile   string  // Watchlist files in the OFAC SDN CSV or XML format, comma separated; screening is disabled when empty
+	SanctionsFlagScore  float64 // Name similarity, from 0 to 1, at which a match is recorded for review
+	SanctionsBlockScore float64 // Name similarity at which registration or a transfer is refused
+
+	KYCRequired            bool   // Only verified users may transfer, within the limits of their level
+	KYCDocumentDir         string // Directory where uploaded KYC documents are kept, encrypted
+	KYCMaxDocumentSize     int64  // In bytes
+	KYCLevel1TransferLimit float64
+	KYCLevel1DailyLimit    float64
+	KYCLevel2TransferLimit float64
+	KYCLevel2DailyLimit    float64
+
+	LoginMaxFailures     int
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	LoginIPMaxFailures   int
+	LoginDelayBase       time.Duration
+
+	PasswordMinLength    int
+	PasswordBreachedList string // File of breached passwords that cannot be chosen, one per line; disabled when empty
+	BcryptCost           int    // Raising it rehashes each user's password at their next login
+
+	PIIKeyringFile string // JSON keyring that encrypts customer PII; a fixed development keyring when empty
+
+	LogLevel           string        // debug, info, warn or error
+	SlowQueryThreshold time.Duration // Queries slower than this are logged as warnings, 0 disables
+
+	DBReadTimeout  time.Duration // Limit on each query, 0 disables
+	DBWriteTimeout time.Duration // Limit on each insert, update or delete, 0 disables
+
+	HealthCheckTimeout time.Duration // Limit on each readiness check
+	ShutdownDrainDelay time.Duration // How long /readyz fails before the server stops accepting requests
+
+	MetricsToken string // Bearer token that scrapers present to /metrics; the endpoint is open without one, which production refuses
+
+	TracingExporter    string  // none, otlp or stdout
+	TracingFile        string  // File the stdout exporter appends spans to, standard output when empty
+	TracingSampleRatio float64 // Share of new traces recorded
+
+	RateLimitStore        string          // memory, for a single instance, or postgres, shared by every instance
+	RateLimitAuth         ratelimit.Limit // Per IP address on the login, registration and token routes
+	RateLimitTransactions ratelimit.Limit // Per user or service client on /transactions
+	RateLimitAPI          ratelimit.Limit // Per user or service client on the other authenticated routes
+	RateLimitFailOpen     bool            // Let requests through, rather than refuse them with 503, while the store cannot be reached
+
+	settings []Setting
 }
 
-func New() *Config {
-	dbPort, _ := strconv.Atoi(getEnv("DB_PORT", "5434"))
-	port, _ := strconv.Atoi(getEnv("PORT", "8080"))
+// Load reads the configuration from the defaults, the config file, the
+// environment and the command line, each overriding the one before. It
+// returns Problems listing every setting that cannot be parsed or is out of
+// range, along with the configuration as far as it could be read.
+func Load(src Sources) (*Config, error) {
+	l := newLoader(src)
+	environment := l.oneOf("APP_ENV", Development, Development, Production)
+	l.production = environment == Production
+	jwtKeysDir := l.string("JWT_KEYS_DIR", "")
+
+	cfg := &Config{
+		Environment:     environment,
+		DBHost:          l.string("DB_HOST", "localhost"),
+		DBPort:          l.int("DB_PORT", 5434),
+		DBUser:          l.string("DB_USER", "postgres"),
+		DBPassword:      l.secret("DB_PASSWORD", "Demo123!", true),
+		DBName:          l.string("DB_NAME", "drank"),
+		Port:            l.int("PORT", 8080),
+		TrustedProxies:  l.list("TRUSTED_PROXIES"),
+		JWTSecret:       l.secret("JWT_SECRET", DefaultJWTSecret, jwtKeysDir == ""),
+		JWTKeysDir:      jwtKeysDir,
+		JWTSigningKeyID: l.string("JWT_SIGNING_KEY_ID", ""),
+		AccessTokenTTL:  l.duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: l.duration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		ClientTokenTTL:  l.duration("CLIENT_TOKEN_TTL", time.Hour),
+
+		SessionTouchInterval: l.duration("SESSION_TOUCH_INTERVAL", 5*time.Minute),
+
+		AppBaseURL:               l.string("APP_BASE_URL", "http://localhost:3000"),
+		FrontendURL:              l.string("FRONTEND_URL", ""),
+		RequireEmailVerification: l.bool("REQUIRE_EMAIL_VERIFICATION", true),
+		VerificationTokenTTL:     l.duration("VERIFICATION_TOKEN_TTL", 24*time.Hour),
+		PasswordResetTokenTTL:    l.duration("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+		Mailer:                   l.oneOf("MAILER", "file", "file", "smtp"),
+		MailDir:                  l.string("MAIL_DIR", "./mail"),
+		SMTPAddr:                 l.string("SMTP_ADDR", "localhost:1025"),
+		MailFrom:                 l.string("MAIL_FROM", "no-reply@drank.local"),
+
+		TwoFactorIssuer:       l.string("TWO_FACTOR_ISSUER", "Drank Bank"),
+		TwoFactorChallengeTTL: l.duration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+
+		StepUpTransferThreshold: l.float("STEP_UP_TRANSFER_THRESHOLD", 1000),
+		StepUpTTL:               l.duration("STEP_UP_TTL", 5*time.Minute),
+
+		SavingsInterestRate: l.float("SAVINGS_INTEREST_RATE", 1.5),
 
-	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     dbPort,
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "Demo123!"),
-		DBName:     getEnv("DB_NAME", "drank"),
-		Port:       port,
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+		FraudScreening:            l.bool("FRAUD_SCREENING", true),
+		FraudReviewScore:          l.int("FRAUD_REVIEW_SCORE", 50),
+		FraudBlockScore:           l.int("FRAUD_BLOCK_SCORE", 100),
+		FraudVelocityCount:        l.int("FRAUD_VELOCITY_COUNT", 10),
+		FraudVelocityWindow:       l.duration("FRAUD_VELOCITY_WINDOW", 10*time.Minute),
+		FraudVelocityScore:        l.int("FRAUD_VELOCITY_SCORE", 50),
+		FraudAverageMultiplier:    l.float("FRAUD_AVERAGE_MULTIPLIER", 5),
+		FraudAverageMinHistory:    l.int("FRAUD_AVERAGE_MIN_HISTORY", 5),
+		FraudAverageScore:         l.int("FRAUD_AVERAGE_SCORE", 30),
+		FraudNewPayeeAmount:       l.float("FRAUD_NEW_PAYEE_AMOUNT", 5000),
+		FraudNewPayeeScore:        l.int("FRAUD_NEW_PAYEE_SCORE", 30),
+		FraudPasswordChangeWindow: l.duration("FRAUD_PASSWORD_CHANGE_WINDOW", 24*time.Hour),
+		FraudPasswordChangeScore:  l.int("FRAUD_PASSWORD_CHANGE_SCORE", 40),
+
+		SanctionsListFile:   l.string("SANCTIONS_LIST_FILE", ""),
+		SanctionsFlagScore:  l.float("SANCTIONS_FLAG_SCORE", 0.85),
+		SanctionsBlockScore: l.float("SANCTIONS_BLOCK_SCORE", 0.95),
+
+		KYCRequired:            l.bool("KYC_REQUIRED", true),
+		KYCDocumentDir:         l.string("KYC_DOCUMENT_DIR", "./data/kyc-documents"),
+		KYCMaxDocumentSize:     l.int64("KYC_MAX_DOCUMENT_SIZE", 10<<20),
+		KYCLevel1TransferLimit: l.float("KYC_LEVEL1_TRANSFER_LIMIT", 1000),
+		KYCLevel1DailyLimit:    l.float("KYC_LEVEL1_DAILY_LIMIT", 2500),
+		KYCLevel2TransferLimit: l.float("KYC_LEVEL2_TRANSFER_LIMIT", 10000),
+		KYCLevel2DailyLimit:    l.float("KYC_LEVEL2_DAILY_LIMIT", 25000),
+
+		LoginMaxFailures:     l.int("LOGIN_MAX_FAILURES", 5),
+		LoginFailureWindow:   l.duration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration: l.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginIPMaxFailures:   l.int("LOGIN_IP_MAX_FAILURES", 20),
+		LoginDelayBase:       l.duration("LOGIN_DELAY_BASE", time.Second),
+
+		PasswordMinLength:    l.int("PASSWORD_MIN_LENGTH", 8),
+		PasswordBreachedList: l.string("PASSWORD_BREACHED_LIST", ""),
+		BcryptCost:           l.int("BCRYPT_COST", bcrypt.DefaultCost),
+
+		PIIKeyringFile: l.string("PII_KEYRING_FILE", ""),
+
+		LogLevel:           l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error"),
+		SlowQueryThreshold: l.duration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+
+		DBReadTimeout:  l.duration("DB_READ_TIMEOUT", 5*time.Second),
+		DBWriteTimeout: l.duration("DB_WRITE_TIMEOUT", 10*time.Second),
+
+		HealthCheckTimeout: l.duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		ShutdownDrainDelay: l.duration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+
+		MetricsToken: l.secret("METRICS_TOKEN", "", true),
+
+		TracingExporter:    l.oneOf("TRACING_EXPORTER", "none", "none", "otlp", "stdout"),
+		TracingFile:        l.string("TRACING_FILE", ""),
+		TracingSampleRatio: l.float("TRACING_SAMPLE_RATIO", 1),
+
+		RateLimitStore:        l.oneOf("RATE_LIMIT_STORE", "memory", "memory", "postgres"),
+		RateLimitAuth:         l.rateLimit("RATE_LIMIT_AUTH", ratelimit.Limit{Requests: 20, Per: time.Minute}),
+		RateLimitTransactions: l.rateLimit("RATE_LIMIT_TRANSACTIONS", ratelimit.Limit{Requests: 120, Per: time.Minute}),
+		RateLimitAPI:          l.rateLimit("RATE_LIMIT_API", ratelimit.Limit{Requests: 600, Per: time.Minute}),
+		RateLimitFailOpen:     l.bool("RATE_LIMIT_FAIL_OPEN", false),
 	}
+
+	cfg.settings = l.settings
+	l.finish()
+	problems := append(l.problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return cfg, problems
+	}
+	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// IsDevelopment reports whether the application runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == Development
+}
+
+// Validate checks the settings against each other and their ranges
+func (c *Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (c *Config) validate() Problems {
+	var problems Problems
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	if !c.IsDevelopment() {
+		check(c.PIIKeyringFile != "", "PII_KEYRING_FILE must be set when APP_ENV is production")
+	}
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535")
+	for _, proxy := range c.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
 	}
-	return value
+	check(c.DBPort > 0 && c.DBPort < 65536, "DB_PORT must be between 1 and 65535")
+	if u, err := url.Parse(c.AppBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		problems = append(problems, "APP_BASE_URL must be an absolute URL such as https://bank.example.com")
+	}
+	if u, err := url.Parse(c.FrontendURL); c.FrontendURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		problems = append(problems, "FRONTEND_URL must be an origin such as https://bank.example.com")
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"CLIENT_TOKEN_TTL", c.ClientTokenTTL},
+		{"SESSION_TOUCH_INTERVAL", c.SessionTouchInterval},
+		{"VERIFICATION_TOKEN_TTL", c.VerificationTokenTTL},
+		{"PASSWORD_RESET_TOKEN_TTL", c.PasswordResetTokenTTL},
+		{"TWO_FACTOR_CHALLENGE_TTL", c.TwoFactorChallengeTTL},
+		{"STEP_UP_TTL", c.StepUpTTL},
+		{"FRAUD_VELOCITY_WINDOW", c.FraudVelocityWindow},
+		{"LOGIN_FAILURE_WINDOW", c.LoginFailureWindow},
+		{"LOGIN_LOCKOUT_DURATION", c.LoginLockoutDuration},
+		{"HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout},
+	} {
+		check(d.value > 0, "%s must be positive", d.key)
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"FRAUD_PASSWORD_CHANGE_WINDOW", c.FraudPasswordChangeWindow},
+		{"LOGIN_DELAY_BASE", c.LoginDelayBase},
+		{"SLOW_QUERY_THRESHOLD", c.SlowQueryThreshold},
+		{"DB_READ_TIMEOUT", c.DBReadTimeout},
+		{"DB_WRITE_TIMEOUT", c.DBWriteTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", c.ShutdownDrainDelay},
+	} {
+		check(d.value >= 0, "%s cannot be negative", d.key)
+	}
+
+	check(c.FraudReviewScore <= c.FraudBlockScore, "FRAUD_REVIEW_SCORE cannot be above FRAUD_BLOCK_SCORE")
+	check(c.SanctionsFlagScore >= 0 && c.SanctionsFlagScore <= 1, "SANCTIONS_FLAG_SCORE must be between 0 and 1")
+	check(c.SanctionsBlockScore >= 0 && c.SanctionsBlockScore <= 1, "SANCTIONS_BLOCK_SCORE must be between 0 and 1")
+	check(c.SanctionsFlagScore <= c.SanctionsBlockScore, "SANCTIONS_FLAG_SCORE cannot be above SANCTIONS_BLOCK_SCORE")
+	check(c.KYCMaxDocumentSize > 0, "KYC_MAX_DOCUMENT_SIZE must be positive")
+	check(c.KYCLevel1TransferLimit <= c.KYCLevel2TransferLimit && c.KYCLevel1DailyLimit <= c.KYCLevel2DailyLimit,
+		"KYC level 1 limits cannot be above the level 2 ones")
+	check(c.LoginMaxFailures > 0 && c.LoginIPMaxFailures > 0, "LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be positive")
+	check(c.BcryptCost >= bcrypt.MinCost && c.BcryptCost <= bcrypt.MaxCost, "BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	check(c.PasswordMinLength >= 1, "PASSWORD_MIN_LENGTH must be at least 1")
+	check(c.SavingsInterestRate >= 0, "SAVINGS_INTEREST_RATE cannot be negative")
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	return problems
 }
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a LookupEnv reading from vars instead of the process environment
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// setting returns the loaded setting of key
+func setting(t *testing.T, cfg *Config, key string) Setting {
+	for _, s := range cfg.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+	t.Fatalf("setting %s not loaded", key)
+	return Setting{}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	// Act
+	cfg, err := Load(Sources{LookupEnv: env(nil)})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, Development, cfg.Environment)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "Demo123!", cfg.DBPassword)
+	assert.Equal(t, DefaultJWTSecret, cfg.JWTSecret)
+	assert.Equal(t, 15*time.Minute, cfg.AccessTokenTTL)
+	assert.Equal(t, SourceDefault, setting(t, cfg, "PORT").Source)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "config.yaml", "port: 7000\ndb_host: file-host\ndb_name: file-db\n")
+	src := Sources{
+		File:      file,
+		Overrides: map[string]string{"PORT": "9000"},
+		LookupEnv: env(map[string]string{"PORT": "8000", "DB_HOST": "env-host", "DB_NAME": ""}),
+	}
+
+	// Act
+	cfg, err := Load(src)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 9000, cfg.Port)
+	assert.Equal(t, SourceFlag, setting(t, cfg, "PORT").Source)
+	assert.Equal(t, "env-host", cfg.DBHost)
+	assert.Equal(t, SourceEnv, setting(t, cfg, "DB_HOST").Source)
+	assert.Equal(t, "file-db", cfg.DBName, "an empty variable does not override the file")
+	assert.Equal(t, SourceFile, setting(t, cfg, "DB_NAME").Source)
+}
+
+func TestLoad_ConfigFileFromEnvironment(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "config.yaml", "LOG_LEVEL: debug\n")
+
+	// Act
+	cfg, err := Load(Sources{LookupEnv: env(map[string]string{"CONFIG_FILE": file})})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.LogLevel)
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "config.yaml", "PROT: 8080\n")
+	src := Sources{
+		File:      file,
+		Overrides: map[string]string{"LOG_LEVL": "debug"},
+		LookupEnv: env(map[string]string{
+			"PORT":                 "eighty",
+			"ACCESS_TOKEN_TTL":     "15",
+			"FRAUD_SCREENING":      "maybe",
+			"MAILER":               "pigeon",
+			"SANCTIONS_FLAG_SCORE": "1.5",
+		}),
+	}
+
+	// Act
+	_, err := Load(src)
+
+	// Assert
+	var problems Problems
+	require.ErrorAs(t, err, &problems)
+	assert.Equal(t, Problems{
+		`PORT: "eighty" is not a whole number`,
+		`ACCESS_TOKEN_TTL: "15" is not a duration such as 90s or 15m`,
+		`MAILER: "pigeon" must be one of file, smtp`,
+		`FRAUD_SCREENING: "maybe" is not true or false`,
+		"-set: unknown setting LOG_LEVL",
+		"config file " + file + ": unknown setting PROT",
+		"SANCTIONS_FLAG_SCORE must be between 0 and 1",
+		"SANCTIONS_FLAG_SCORE cannot be above SANCTIONS_BLOCK_SCORE",
+	}, problems)
+}
+
+func TestLoad_FrontendURL(t *testing.T) {
+	// Act
+	cfg, err := Load(Sources{Overrides: map[string]string{"FRONTEND_URL": "https://bank.example.com"}, LookupEnv: env(nil)})
+	_, badErr := Load(Sources{Overrides: map[string]string{"FRONTEND_URL": "bank.example.com"}, LookupEnv: env(nil)})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "https://bank.example.com", cfg.FrontendURL)
+	assert.EqualError(t, badErr, "FRONTEND_URL must be an origin such as https://bank.example.com")
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	// Act
+	none, err := Load(Sources{LookupEnv: env(nil)})
+	require.NoError(t, err)
+	cfg, err := Load(Sources{LookupEnv: env(map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8, 192.168.1.1,"})})
+	require.NoError(t, err)
+	_, badErr := Load(Sources{LookupEnv: env(map[string]string{"TRUSTED_PROXIES": "10.0.0.0/33"})})
+
+	// Assert
+	assert.Empty(t, none.TrustedProxies, "no proxy is trusted by default")
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.TrustedProxies)
+	assert.EqualError(t, badErr, `TRUSTED_PROXIES: "10.0.0.0/33" is not an IP address or CIDR range`)
+}
+
+func TestLoad_RateLimits(t *testing.T) {
+	// Arrange
+	src := Sources{LookupEnv: env(map[string]string{
+		"RATE_LIMIT_AUTH":         "5/30s",
+		"RATE_LIMIT_TRANSACTIONS": "off",
+		"RATE_LIMIT_API":          "lots",
+	})}
+
+	// Act
+	cfg, err := Load(src)
+
+	// Assert
+	assert.EqualError(t, err, `RATE_LIMIT_API: "lots" is not a limit such as 100/1m, or off`)
+	assert.Equal(t, ratelimit.Limit{Requests: 5, Per: 30 * time.Second}, cfg.RateLimitAuth)
+	assert.True(t, cfg.RateLimitTransactions.Off())
+	assert.False(t, cfg.RateLimitFailOpen, "requests are refused while the store is down unless told otherwise")
+}
+
+func TestLoad_ProductionRefusesDevelopmentDefaults(t *testing.T) {
+	// Act
+	_, err := Load(Sources{LookupEnv: env(map[string]string{"APP_ENV": Production, "JWT_SECRET": DefaultJWTSecret})})
+
+	// Assert
+	var problems Problems
+	require.ErrorAs(t, err, &problems)
+	assert.Equal(t, Problems{
+		"DB_PASSWORD must be set when APP_ENV is production; its development default is refused",
+		"JWT_SECRET must be set when APP_ENV is production; its development default is refused",
+		"METRICS_TOKEN must be set when APP_ENV is production; its development default is refused",
+		"PII_KEYRING_FILE must be set when APP_ENV is production",
+	}, problems)
+}
+
+func TestLoad_Production(t *testing.T) {
+	// Arrange
+	src := Sources{LookupEnv: env(map[string]string{
+		"APP_ENV":          Production,
+		"DB_PASSWORD":      "db-password",
+		"JWT_KEYS_DIR":     "/etc/drank/jwt",
+		"METRICS_TOKEN":    "metrics-token",
+		"PII_KEYRING_FILE": "/etc/drank/keyring.json",
+	})}
+
+	// Act
+	cfg, err := Load(src)
+
+	// Assert
+	require.NoError(t, err, "JWT_SECRET is not needed with JWT_KEYS_DIR")
+	assert.False(t, cfg.IsDevelopment())
+	assert.Empty(t, cfg.JWTSecret)
+}
+
+func TestLoad_SecretFromFile(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "db_password", "from-file\n")
+
+	// Act
+	cfg, err := Load(Sources{LookupEnv: env(map[string]string{"DB_PASSWORD_FILE": file})})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.DBPassword)
+	assert.Equal(t, "env DB_PASSWORD_FILE", setting(t, cfg, "DB_PASSWORD").Source)
+}
+
+func TestLoad_SecretFileOverriddenByHigherSource(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "db_password", "from-file")
+	src := Sources{
+		Overrides: map[string]string{"DB_PASSWORD": "from-flag"},
+		LookupEnv: env(map[string]string{"DB_PASSWORD_FILE": file}),
+	}
+
+	// Act
+	cfg, err := Load(src)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "from-flag", cfg.DBPassword)
+}
+
+func TestLoad_SecretSetTwice(t *testing.T) {
+	// Arrange
+	file := writeFile(t, "db_password", "from-file")
+
+	// Act
+	_, err := Load(Sources{LookupEnv: env(map[string]string{"DB_PASSWORD": "from-env", "DB_PASSWORD_FILE": file})})
+
+	// Assert
+	assert.EqualError(t, err, "DB_PASSWORD and DB_PASSWORD_FILE are both set")
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	// Act
+	_, err := Load(Sources{File: filepath.Join(t.TempDir(), "missing.yaml"), LookupEnv: env(nil)})
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config file:")
+}
+
+func TestConfig_Print(t *testing.T) {
+	// Arrange
+	cfg, err := Load(Sources{Overrides: map[string]string{"JWT_SECRET": "top-secret"}, LookupEnv: env(nil)})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		redact bool
+		want   string
+	}{
+		{"redacted", true, `JWT_SECRET="[REDACTED]" # flag`},
+		{"plain", false, `JWT_SECRET="top-secret" # flag`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			var buf bytes.Buffer
+			require.NoError(t, cfg.Print(&buf, tc.redact))
+
+			// Assert
+			assert.Contains(t, buf.String(), tc.want+"\n")
+			assert.Contains(t, buf.String(), `PORT="8080" # default`+"\n")
+			assert.Equal(t, strings.Contains(buf.String(), "top-secret"), !tc.redact)
+		})
+	}
+}
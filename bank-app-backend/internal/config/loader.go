@@ -0,0 +1,328 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a setting's value came from, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// redactedValue replaces secrets in printed configuration
+const redactedValue = "[REDACTED]"
+
+// Sources are where Load reads settings, besides the defaults. Every
+// setting is named by its environment variable, in the file and on the
+// command line too; later sources override earlier ones.
+type Sources struct {
+	File      string                      // YAML file of settings; CONFIG_FILE when empty, none when both are
+	Overrides map[string]string           // Settings from the command line
+	LookupEnv func(string) (string, bool) // os.LookupEnv when nil
+}
+
+// Setting is one loaded setting, as config print shows it
+type Setting struct {
+	Key    string
+	Value  string
+	Source string // One of the Source constants, followed by the variable naming the file for a secret read from one
+	Secret bool
+}
+
+// Problems lists everything wrong with the configuration, so that it can
+// all be fixed at once
+type Problems []string
+
+func (p Problems) Error() string {
+	if len(p) == 1 {
+		return p[0]
+	}
+	return fmt.Sprintf("%d problems:\n  %s", len(p), strings.Join(p, "\n  "))
+}
+
+// loader reads typed settings from the sources, recording problems
+// rather than stopping at the first
+type loader struct {
+	file       map[string]string
+	fileName   string
+	env        func(string) (string, bool)
+	overrides  map[string]string
+	production bool
+
+	settings []Setting
+	known    map[string]bool
+	problems Problems
+}
+
+func newLoader(src Sources) *loader {
+	l := &loader{
+		env:       src.LookupEnv,
+		overrides: map[string]string{},
+		known:     map[string]bool{},
+	}
+	if l.env == nil {
+		l.env = os.LookupEnv
+	}
+	for key, value := range src.Overrides {
+		l.overrides[strings.ToUpper(key)] = value
+	}
+
+	l.fileName = src.File
+	if l.fileName == "" {
+		l.fileName, _ = l.env("CONFIG_FILE")
+	}
+	if l.fileName != "" {
+		file, err := readFile(l.fileName)
+		if err != nil {
+			l.problems = append(l.problems, err.Error())
+		}
+		l.file = file
+	}
+	return l
+}
+
+// readFile reads a flat YAML mapping of settings to scalar values
+func readFile(name string) (map[string]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", name, err)
+	}
+	file := make(map[string]string, len(nodes))
+	for key, node := range nodes {
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("config file %s: %s must be a single value", name, key)
+		}
+		file[strings.ToUpper(key)] = node.Value
+	}
+	return file, nil
+}
+
+// lookup finds the value of key in the highest source that sets it
+func (l *loader) lookup(key string) (string, string, bool) {
+	l.known[key] = true
+	if value, ok := l.overrides[key]; ok {
+		return value, SourceFlag, true
+	}
+	if value, ok := l.env(key); ok && value != "" {
+		return value, SourceEnv, true
+	}
+	if value, ok := l.file[key]; ok {
+		return value, SourceFile, true
+	}
+	return "", SourceDefault, false
+}
+
+// raw looks key up and records it, returning the default when no source sets it
+func (l *loader) raw(key, defaultValue string) (string, bool) {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+	l.settings = append(l.settings, Setting{Key: key, Value: value, Source: source})
+	return value, ok
+}
+
+func (l *loader) problem(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) string(key, defaultValue string) string {
+	value, _ := l.raw(key, defaultValue)
+	return value
+}
+
+// secret reads a setting that is never printed. It can also be read from
+// the file named by <key>_FILE, as container secrets are mounted. In
+// production the development default is not used, so a secret that has one
+// must be set.
+func (l *loader) secret(key, developmentDefault string, required bool) string {
+	value, source, ok := l.lookup(key)
+	fileName, fileSource, fromFile := l.lookup(key + "_FILE")
+	switch {
+	case ok && fromFile && fileSource == source:
+		l.problem("%s and %s_FILE are both set", key, key)
+	case fromFile && (!ok || sourceRank(fileSource) > sourceRank(source)):
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			l.problem("%s_FILE: %v", key, err)
+		}
+		value, source, ok = strings.TrimRight(string(data), "\r\n"), fileSource+" "+key+"_FILE", true
+	}
+
+	if !ok && !l.production {
+		value = developmentDefault
+	}
+	if l.production && required && (value == "" || (developmentDefault != "" && value == developmentDefault)) {
+		l.problem("%s must be set when APP_ENV is production; its development default is refused", key)
+	}
+	l.settings = append(l.settings, Setting{Key: key, Value: value, Source: source, Secret: true})
+	return value
+}
+
+func sourceRank(source string) int {
+	switch source {
+	case SourceFlag:
+		return 3
+	case SourceEnv:
+		return 2
+	case SourceFile:
+		return 1
+	}
+	return 0
+}
+
+// list reads a comma-separated setting, dropping empty items
+func (l *loader) list(key string) []string {
+	value, _ := l.raw(key, "")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (l *loader) int(key string, defaultValue int) int {
+	value, ok := l.raw(key, strconv.Itoa(defaultValue))
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		l.problem("%s: %q is not a whole number", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) int64(key string, defaultValue int64) int64 {
+	value, ok := l.raw(key, strconv.FormatInt(defaultValue, 10))
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		l.problem("%s: %q is not a whole number", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) float(key string, defaultValue float64) float64 {
+	value, ok := l.raw(key, strconv.FormatFloat(defaultValue, 'g', -1, 64))
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		l.problem("%s: %q is not a number", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) bool(key string, defaultValue bool) bool {
+	value, ok := l.raw(key, strconv.FormatBool(defaultValue))
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		l.problem("%s: %q is not true or false", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) duration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := l.raw(key, defaultValue.String())
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		l.problem("%s: %q is not a duration such as 90s or 15m", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) rateLimit(key string, defaultValue ratelimit.Limit) ratelimit.Limit {
+	value, ok := l.raw(key, defaultValue.String())
+	if !ok {
+		return defaultValue
+	}
+	parsed, err := ratelimit.ParseLimit(strings.TrimSpace(value))
+	if err != nil {
+		l.problem("%s: %v", key, err)
+		return defaultValue
+	}
+	return parsed
+}
+
+// oneOf reads a string setting that must be one of the choices
+func (l *loader) oneOf(key, defaultValue string, choices ...string) string {
+	value := l.string(key, defaultValue)
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	l.problem("%s: %q must be one of %s", key, value, strings.Join(choices, ", "))
+	return value
+}
+
+// finish reports settings in the file or on the command line that do not
+// exist, which are most likely misspelt
+func (l *loader) finish() {
+	var unknown []string
+	for key := range l.file {
+		if !l.known[key] {
+			unknown = append(unknown, fmt.Sprintf("config file %s: unknown setting %s", l.fileName, key))
+		}
+	}
+	for key := range l.overrides {
+		if !l.known[key] {
+			unknown = append(unknown, fmt.Sprintf("-set: unknown setting %s", key))
+		}
+	}
+	sort.Strings(unknown)
+	l.problems = append(l.problems, unknown...)
+}
+
+// Print writes the settings as KEY=value lines, each followed by where its
+// value came from. With redact set, secrets are replaced.
+func (c *Config) Print(w io.Writer, redact bool) error {
+	for _, setting := range c.settings {
+		value := setting.Value
+		if setting.Secret && redact && value != "" {
+			value = redactedValue
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s # %s\n", setting.Key, strconv.Quote(value), setting.Source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Settings returns the loaded settings in the order they were read
+func (c *Config) Settings() []Setting {
+	return append([]Setting(nil), c.settings...)
+}
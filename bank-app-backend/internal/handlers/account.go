@@ -27,7 +27,7 @@ func NewAccountHandler(accountService services.AccountService) *AccountHandler {
 // @Failure 500 {object} ErrorResponse
 // @Router /accounts [get]
 func (h *AccountHandler) GetAllAccounts(c *gin.Context) {
-	accounts, err := h.accountService.GetAllAccounts()
+	accounts, err := h.accountService.GetAllAccounts(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get accounts: " + err.Error()})
 		return
@@ -62,7 +62,7 @@ func (h *AccountHandler) GetAccountByID(c *gin.Context) {
 		return
 	}
 
-	account, err := h.accountService.GetAccountByID(uint(id))
+	account, err := h.accountService.GetAccountByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Account not found: " + err.Error()})
 		return
@@ -90,7 +90,7 @@ func (h *AccountHandler) GetAccountsByUserID(c *gin.Context) {
 		return
 	}
 
-	accounts, err := h.accountService.GetAccountsByUserID(uint(userID))
+	accounts, err := h.accountService.GetAccountsByUserID(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get accounts: " + err.Error()})
 		return
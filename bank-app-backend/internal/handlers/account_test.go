@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -18,12 +19,12 @@ type MockAccountService struct {
 	mock.Mock
 }
 
-func (m *MockAccountService) CreateAccount(account *models.Account) error {
+func (m *MockAccountService) CreateAccount(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountService) GetAccountByID(id uint) (*models.Account, error) {
+func (m *MockAccountService) GetAccountByID(ctx context.Context, id uint) (*models.Account, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -31,22 +32,22 @@ func (m *MockAccountService) GetAccountByID(id uint) (*models.Account, error) {
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockAccountService) GetAccountsByUserID(userID uint) ([]models.Account, error) {
+func (m *MockAccountService) GetAccountsByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
 	args := m.Called(userID)
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountService) GetAllAccounts() ([]models.Account, error) {
+func (m *MockAccountService) GetAllAccounts(ctx context.Context) ([]models.Account, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountService) UpdateAccount(account *models.Account) error {
+func (m *MockAccountService) UpdateAccount(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountService) DeleteAccount(id uint) error {
+func (m *MockAccountService) DeleteAccount(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
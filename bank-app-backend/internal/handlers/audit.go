@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(auditService services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService}
+}
+
+// auditActor describes who made the request for the audit log: the
+// authenticated user or service client, or an anonymous caller
+func auditActor(c *gin.Context) models.AuditActor {
+	actor := models.AuditActor{
+		Type:      models.AuditActorAnonymous,
+		IPAddress: c.ClientIP(),
+		RequestID: c.GetString("requestID"),
+	}
+
+	if value, exists := c.Get("serviceClient"); exists {
+		if client, ok := value.(*models.ServiceClient); ok {
+			actor.Type = models.AuditActorServiceClient
+			actor.ID = client.ClientID
+		}
+		return actor
+	}
+	if userID, exists := c.Get("userID"); exists {
+		actor.Type = models.AuditActorUser
+		actor.ID = fmt.Sprint(userID)
+	}
+	return actor
+}
+
+// @Summary Search the audit log
+// @Description Get audit entries for security and money-moving actions, newest first. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param actorType query string false "Actor type (user, service_client, anonymous, system)"
+// @Param actorId query string false "Actor ID"
+// @Param action query string false "Action, e.g. transaction.transfer"
+// @Param targetType query string false "Target type (user, session, service_client, account)"
+// @Param targetId query string false "Target ID"
+// @Param from query string false "Earliest time (RFC 3339)"
+// @Param to query string false "Time before which entries are returned (RFC 3339)"
+// @Param limit query int false "Limit (default 100, at most 1000)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit [get]
+func (h *AuditHandler) GetEntries(c *gin.Context) {
+	var filter models.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid filter: " + err.Error()})
+		return
+	}
+
+	entries, err := h.auditService.GetEntries(c.Request.Context(), &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get audit entries: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary Verify the audit log
+// @Description Check the audit log's hash chain for modified or deleted entries. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.AuditVerification
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit/verify [get]
+func (h *AuditHandler) Verify(c *gin.Context) {
+	result, err := h.auditService.Verify(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to verify audit log: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
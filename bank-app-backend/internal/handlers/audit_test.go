@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the audit service
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) GetEntries(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditService) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditVerification), args.Error(1)
+}
+
+func TestGetAuditEntries_Filters(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockAuditService := new(MockAuditService)
+	mockAuditService.On("GetEntries", mock.MatchedBy(func(filter *models.AuditFilter) bool {
+		return filter.Action == models.AuditTransferCompleted && filter.ActorID == "1" &&
+			filter.From != nil && filter.From.Year() == 2026 && filter.Limit == 100
+	})).Return([]models.AuditEntry{{ID: 1, Action: models.AuditTransferCompleted}}, nil)
+
+	handler := NewAuditHandler(mockAuditService)
+
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/admin/audit?action=transaction.transfer&actorId=1&from=2026-01-01T00:00:00Z", nil)
+
+	// Call the handler
+	handler.GetEntries(c)
+
+	// Parse the response
+	var response []models.AuditEntry
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, response, 1)
+	mockAuditService.AssertExpectations(t)
+}
+
+func TestGetAuditEntries_InvalidLimit(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockAuditService := new(MockAuditService)
+	handler := NewAuditHandler(mockAuditService)
+
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/admin/audit?limit=5000", nil)
+
+	// Call the handler
+	handler.GetEntries(c)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockAuditService.AssertNotCalled(t, "GetEntries", mock.Anything)
+}
+
+func TestVerifyAudit_ReportsBrokenChain(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockAuditService := new(MockAuditService)
+	mockAuditService.On("Verify").Return(&models.AuditVerification{Valid: false, Checked: 4, BrokenAt: 5}, nil)
+
+	handler := NewAuditHandler(mockAuditService)
+
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/admin/audit/verify", nil)
+
+	// Call the handler
+	handler.Verify(c)
+
+	// Parse the response
+	var response models.AuditVerification
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, response.Valid)
+	assert.Equal(t, uint(5), response.BrokenAt)
+}
+
+func TestAuditActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("POST", "/", nil)
+		c.Request.RemoteAddr = "10.0.0.1:1234"
+		c.Set("requestID", "req-1")
+		return c
+	}
+
+	anonymous := auditActor(newContext())
+	assert.Equal(t, models.AuditActor{Type: models.AuditActorAnonymous, IPAddress: "10.0.0.1", RequestID: "req-1"}, anonymous)
+
+	userContext := newContext()
+	userContext.Set("userID", uint(3))
+	assert.Equal(t, models.AuditActor{Type: models.AuditActorUser, ID: "3", IPAddress: "10.0.0.1", RequestID: "req-1"}, auditActor(userContext))
+
+	clientContext := newContext()
+	clientContext.Set("serviceClient", &models.ServiceClient{ID: 2, ClientID: "svc_batch"})
+	assert.Equal(t, models.AuditActor{Type: models.AuditActorServiceClient, ID: "svc_batch", IPAddress: "10.0.0.1", RequestID: "req-1"}, auditActor(clientContext))
+}
@@ -1,33 +1,42 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend/internal/services"
 )
 
 type AuthHandler struct {
-	userService services.UserService
-	jwtSecret   string
+	userService         services.UserService
+	tokenService        services.TokenService
+	identityService     services.IdentityService
+	twoFactorService    services.TwoFactorService
+	loginAttemptService services.LoginAttemptService
+	passwordService     services.PasswordService
 }
 
-func NewAuthHandler(userService services.UserService, jwtSecret string) *AuthHandler {
-	return &AuthHandler{userService, jwtSecret}
+func NewAuthHandler(userService services.UserService, tokenService services.TokenService, identityService services.IdentityService, twoFactorService services.TwoFactorService, loginAttemptService services.LoginAttemptService, passwordService services.PasswordService) *AuthHandler {
+	return &AuthHandler{userService, tokenService, identityService, twoFactorService, loginAttemptService, passwordService}
 }
 
 // @Summary Login user
-// @Description Authenticate a user and return a JWT token
+// @Description Authenticate a user and return an access token and a refresh token.
+// @Description Users with 2FA enabled get a challenge token to complete at /auth/login/2fa instead.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param loginRequest body models.LoginRequest true "Login Request"
 // @Success 200 {object} models.LoginResponse
+// @Success 202 {object} models.TwoFactorChallenge
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginRequest models.LoginRequest
@@ -36,41 +45,426 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.AuthenticateUser(loginRequest.Email, loginRequest.Password)
+	if !h.checkLoginAllowed(c, loginRequest.Email) {
+		return
+	}
+
+	user, err := h.userService.AuthenticateUser(c.Request.Context(), loginRequest.Email, loginRequest.Password)
 	if err != nil {
+		h.recordFailure(c, loginRequest.Email, "invalid email or password")
 		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication failed: " + err.Error()})
 		return
 	}
 
-	// Generate JWT token
-	token, err := h.generateToken(user)
+	// Upgrade hashes made with a lower bcrypt cost while the password is at
+	// hand. A failure only means the next login tries again.
+	_ = h.passwordService.RehashIfNeeded(c.Request.Context(), user, loginRequest.Password)
+
+	if err := h.identityService.CheckLoginAllowed(c.Request.Context(), user); err != nil {
+		c.JSON(http.StatusForbidden, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return
+	}
+
+	// The password alone is not enough when 2FA is enabled
+	if user.TwoFactorEnabled() {
+		challenge, err := h.twoFactorService.CreateChallenge(c.Request.Context(), user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to create two-factor challenge"})
+			return
+		}
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	h.issueTokens(c, user)
+}
+
+// @Summary Complete a two-factor login
+// @Description Exchange a login challenge token and a TOTP or recovery code for an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param twoFactorLoginRequest body models.TwoFactorLoginRequest true "Two-Factor Login Request"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var loginRequest models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&loginRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.twoFactorService.ChallengedUser(ctx, loginRequest.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return
+	}
+
+	// The account may have been locked since the challenge was issued, in which
+	// case the code is not checked, so neither a TOTP step nor a recovery code
+	// is used up
+	if !h.checkLoginAllowed(c, user.Email) {
+		return
+	}
+
+	// The user may also have been disabled since, which the challenge token
+	// alone does not show
+	if err := h.identityService.CheckLoginAllowed(ctx, user); err != nil {
+		c.JSON(http.StatusForbidden, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return
+	}
+
+	if err := h.twoFactorService.VerifyLoginCode(ctx, user, loginRequest.Code); err != nil {
+		// Wrong codes count towards the lockout like wrong passwords
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			h.recordFailure(c, user.Email, "invalid two-factor code")
+		}
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return
+	}
+
+	h.issueTokens(c, user)
+}
+
+// checkLoginAllowed writes a 429 response and returns false while the account
+// or the client address is throttled
+func (h *AuthHandler) checkLoginAllowed(c *gin.Context, email string) bool {
+	return checkLoginAllowed(c, h.loginAttemptService, email)
+}
+
+// recordFailure stores a failed login attempt
+func (h *AuthHandler) recordFailure(c *gin.Context, email, reason string) {
+	recordLoginFailure(c, h.loginAttemptService, email, reason)
+}
+
+// checkLoginAllowed writes a 429 response and returns false while the account
+// or the client address is throttled. Anything else that checks the user's
+// password or second factor goes through it too, so that it cannot be used to
+// get around the lockout.
+func checkLoginAllowed(c *gin.Context, loginAttemptService services.LoginAttemptService, email string) bool {
+	err := loginAttemptService.CheckAllowed(c.Request.Context(), email, c.ClientIP())
+	if err == nil {
+		return true
+	}
+
+	var throttled *services.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to check login attempts"})
+	return false
+}
+
+// recordLoginFailure stores a failed attempt, which counts towards the
+// lockout. The request has failed either way, so an error here must not
+// change the response.
+func recordLoginFailure(c *gin.Context, loginAttemptService services.LoginAttemptService, email, reason string) {
+	_ = loginAttemptService.RecordFailure(c.Request.Context(), email, auditActor(c), c.Request.UserAgent(), reason)
+}
+
+// issueTokens starts a session for an authenticated user and writes the login response
+func (h *AuthHandler) issueTokens(c *gin.Context, user *models.User) {
+	if err := h.loginAttemptService.RecordSuccess(c.Request.Context(), user, auditActor(c), c.Request.UserAgent()); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to record login"})
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(c.Request.Context(), user, models.DeviceInfo{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Token: token,
-		User:  user.ToDTO(),
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		User:             user.ToDTO(),
 	})
 }
 
-func (h *AuthHandler) generateToken(user *models.User) (string, error) {
-	// Create the Claims
-	claims := jwt.MapClaims{
-		"id":    user.ID,
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(), // 24 hours
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a rotated refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refreshRequest body models.RefreshRequest true "Refresh Request"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var refreshRequest models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(c.Request.Context(), refreshRequest.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Refresh failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary Logout
+// @Description Revoke the current access token and its session
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	if err := h.tokenService.Logout(c.Request.Context(), claims.(*services.AccessClaims), auditActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to logout: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// @Summary Logout from all devices
+// @Description Revoke every session of the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	if err := h.tokenService.LogoutAll(c.Request.Context(), userID.(uint), auditActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to logout: " + err.Error()})
+		return
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
 
-	// Generate encoded token
-	tokenString, err := token.SignedString([]byte(h.jwtSecret))
+// @Summary Register user
+// @Description Create a new user and send an email verification link. An address that is already registered gets the same response, and its owner is emailed instead.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param registerRequest body models.RegisterRequest true "Register Request"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var registerRequest models.RegisterRequest
+	if err := c.ShouldBindJSON(&registerRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	// A taken address is answered like a new one so that the endpoint cannot be
+	// used to probe for registered emails; the service has emailed its owner
+	_, err := h.identityService.Register(c.Request.Context(), &registerRequest)
+	if err != nil && !errors.Is(err, services.ErrEmailTaken) {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRegistrationDeclined) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, ErrorResponse{Message: "Registration failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your email to finish registering"})
+}
+
+// @Summary Verify email
+// @Description Confirm an email address with the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verifyEmailRequest body models.VerifyEmailRequest true "Verify Email Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var verifyRequest models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.identityService.VerifyEmail(c.Request.Context(), verifyRequest.Token); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Verification failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// @Summary Resend verification email
+// @Description Send a new verification link if the address belongs to an unverified user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param emailRequest body models.EmailRequest true "Email Request"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var emailRequest models.EmailRequest
+	if err := c.ShouldBindJSON(&emailRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.identityService.ResendVerification(c.Request.Context(), emailRequest.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address needs verification, an email has been sent"})
+}
+
+// @Summary Forgot password
+// @Description Email a password reset link if the address belongs to a user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param emailRequest body models.EmailRequest true "Email Request"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var emailRequest models.EmailRequest
+	if err := c.ShouldBindJSON(&emailRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.identityService.RequestPasswordReset(c.Request.Context(), emailRequest.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to send password reset email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token from the password reset email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param resetPasswordRequest body models.ResetPasswordRequest true "Reset Password Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var resetRequest models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&resetRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.identityService.ResetPassword(c.Request.Context(), resetRequest.Token, resetRequest.Password, auditActor(c)); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Password reset failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// @Summary Step-up authentication
+// @Description Re-prove identity for sensitive operations and receive a short-lived elevated access token.
+// @Description Users with 2FA enabled must send a TOTP code, other users their password.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reauthenticateRequest body models.ReauthenticateRequest true "Reauthenticate Request"
+// @Success 200 {object} models.ElevatedToken
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /auth/reauthenticate [post]
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+	accessClaims := claims.(*services.AccessClaims)
+
+	var reauthRequest models.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&reauthRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), accessClaims.UserID)
 	if err != nil {
-		return "", err
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication failed: " + err.Error()})
+		return
+	}
+
+	// A password is not a fresh second factor for users who have one
+	if user.TwoFactorEnabled() && reauthRequest.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: code is required"})
+		return
+	}
+	if !user.TwoFactorEnabled() && reauthRequest.Password == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: password is required"})
+		return
+	}
+
+	// Guesses count towards the same lockout as logins, so an access token
+	// cannot be used to brute force the password or TOTP code
+	if !h.checkLoginAllowed(c, user.Email) {
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		err = h.twoFactorService.VerifyCode(c.Request.Context(), user, reauthRequest.Code)
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			h.recordFailure(c, user.Email, "invalid two-factor code on reauthentication")
+		}
+	} else {
+		err = user.ComparePassword(reauthRequest.Password)
+		if err != nil {
+			h.recordFailure(c, user.Email, "invalid password on reauthentication")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication failed: invalid credentials"})
+		return
+	}
+
+	token, err := h.tokenService.Elevate(c.Request.Context(), accessClaims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to generate token"})
+		return
 	}
 
-	return tokenString, nil
+	c.JSON(http.StatusOK, token)
 }
@@ -1,17 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"bytes"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Mock user service
@@ -19,12 +23,12 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) CreateUser(user *models.User) error {
+func (m *MockUserService) CreateUser(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserService) GetUserByID(id uint) (*models.User, error) {
+func (m *MockUserService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -32,12 +36,12 @@ func (m *MockUserService) GetUserByID(id uint) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) GetAllUsers() ([]models.User, error) {
+func (m *MockUserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserService) GetUserByEmail(email string) (*models.User, error) {
+func (m *MockUserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -45,17 +49,17 @@ func (m *MockUserService) GetUserByEmail(email string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) UpdateUser(user *models.User) error {
+func (m *MockUserService) UpdateUser(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserService) DeleteUser(id uint) error {
+func (m *MockUserService) DeleteUser(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockUserService) AuthenticateUser(email, password string) (*models.User, error) {
+func (m *MockUserService) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
 	args := m.Called(email, password)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -63,6 +67,221 @@ func (m *MockUserService) AuthenticateUser(email, password string) (*models.User
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+// Mock token service
+type MockTokenService struct {
+	mock.Mock
+}
+
+func (m *MockTokenService) IssueTokens(ctx context.Context, user *models.User, device models.DeviceInfo) (*models.TokenPair, error) {
+	args := m.Called(user, device)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenPair), args.Error(1)
+}
+
+func (m *MockTokenService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	args := m.Called(refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenPair), args.Error(1)
+}
+
+func (m *MockTokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*services.AccessClaims, error) {
+	args := m.Called(tokenString)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.AccessClaims), args.Error(1)
+}
+
+func (m *MockTokenService) Logout(ctx context.Context, claims *services.AccessClaims, actor models.AuditActor) error {
+	args := m.Called(claims, actor)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) LogoutAll(ctx context.Context, userID uint, actor models.AuditActor) error {
+	args := m.Called(userID, actor)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) LogoutOthers(ctx context.Context, userID uint, currentSessionID string, actor models.AuditActor) error {
+	args := m.Called(userID, currentSessionID, actor)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) DeleteExpired(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockTokenService) Elevate(ctx context.Context, claims *services.AccessClaims) (*models.ElevatedToken, error) {
+	args := m.Called(claims)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ElevatedToken), args.Error(1)
+}
+
+func (m *MockTokenService) IssueClientToken(ctx context.Context, clientID string, scopes []string) (*models.ClientToken, error) {
+	args := m.Called(clientID, scopes)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientToken), args.Error(1)
+}
+
+func (m *MockTokenService) GetSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionDTO, error) {
+	args := m.Called(userID, currentSessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SessionDTO), args.Error(1)
+}
+
+func (m *MockTokenService) RevokeSession(ctx context.Context, userID, sessionID uint, actor models.AuditActor) error {
+	args := m.Called(userID, sessionID, actor)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) TouchSession(ctx context.Context, sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// Mock identity service
+type MockIdentityService struct {
+	mock.Mock
+}
+
+func (m *MockIdentityService) Register(ctx context.Context, request *models.RegisterRequest) (*models.User, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockIdentityService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) ResendVerification(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) ResetPassword(ctx context.Context, token, newPassword string, actor models.AuditActor) error {
+	args := m.Called(token, newPassword, actor)
+	return args.Error(0)
+}
+
+func (m *MockIdentityService) CheckLoginAllowed(ctx context.Context, user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+// Mock two-factor service
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Enroll(ctx context.Context, userID uint) (*models.TwoFactorEnrollment, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrollment), args.Error(1)
+}
+
+func (m *MockTwoFactorService) Confirm(ctx context.Context, userID uint, code string, actor models.AuditActor) ([]string, error) {
+	args := m.Called(userID, code, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTwoFactorService) CreateChallenge(ctx context.Context, user *models.User) (*models.TwoFactorChallenge, error) {
+	args := m.Called(user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorChallenge), args.Error(1)
+}
+
+func (m *MockTwoFactorService) ChallengedUser(ctx context.Context, challengeToken string) (*models.User, error) {
+	args := m.Called(challengeToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockTwoFactorService) VerifyLoginCode(ctx context.Context, user *models.User, code string) error {
+	args := m.Called(user, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) VerifyCode(ctx context.Context, user *models.User, code string) error {
+	args := m.Called(user, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) Reset(ctx context.Context, userID uint, actor models.AuditActor) error {
+	args := m.Called(userID, actor)
+	return args.Error(0)
+}
+
+// Mock login attempt service
+type MockLoginAttemptService struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptService) CheckAllowed(ctx context.Context, email, ipAddress string) error {
+	args := m.Called(email, ipAddress)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptService) RecordFailure(ctx context.Context, email string, actor models.AuditActor, userAgent, reason string) error {
+	args := m.Called(email, actor, userAgent, reason)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptService) RecordSuccess(ctx context.Context, user *models.User, actor models.AuditActor, userAgent string) error {
+	args := m.Called(user, actor, userAgent)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptService) Unlock(ctx context.Context, userID uint, actor models.AuditActor) error {
+	args := m.Called(userID, actor)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptService) GetAttemptsByUserID(ctx context.Context, userID uint) ([]models.LoginAttempt, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
+
+// newAllowingLoginAttemptService returns a login attempt service that never throttles
+func newAllowingLoginAttemptService() *MockLoginAttemptService {
+	m := new(MockLoginAttemptService)
+	m.On("CheckAllowed", mock.Anything, mock.Anything).Return(nil).Maybe()
+	m.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	m.On("RecordSuccess", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return m
+}
+
 func TestLogin_Success(t *testing.T) {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
@@ -78,12 +297,23 @@ func TestLogin_Success(t *testing.T) {
 		LastName:  "User",
 	}
 	
+	// Create mock token service
+	mockTokenService := new(MockTokenService)
+	tokens := &models.TokenPair{
+		AccessToken:      "access-token",
+		AccessExpiresAt:  time.Now().Add(15 * time.Minute),
+		RefreshToken:     "refresh-token",
+		RefreshExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	
 	// Set up expectations
 	mockUserService.On("AuthenticateUser", "test@example.com", "password123").Return(testUser, nil)
+	mockTokenService.On("IssueTokens", testUser, models.DeviceInfo{UserAgent: "test-agent"}).Return(tokens, nil)
+	mockIdentityService := new(MockIdentityService)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(nil)
 	
-	// Create auth handler with mock service
-	jwtSecret := "test-secret-key"
-	authHandler := NewAuthHandler(mockUserService, jwtSecret)
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
 	
 	// Create a request body
 	loginRequest := models.LoginRequest{
@@ -95,6 +325,7 @@ func TestLogin_Success(t *testing.T) {
 	// Create a request to pass to our handler
 	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonValue))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
 	
 	// Create a response recorder
 	w := httptest.NewRecorder()
@@ -112,10 +343,12 @@ func TestLogin_Success(t *testing.T) {
 	
 	// Assert expectations
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, "access-token", response.Token)
+	assert.Equal(t, "refresh-token", response.RefreshToken)
 	assert.Equal(t, testUser.ID, response.User.ID)
 	assert.Equal(t, testUser.Email, response.User.Email)
 	mockUserService.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
 }
 
 func TestLogin_InvalidCredentials(t *testing.T) {
@@ -129,9 +362,10 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	mockUserService.On("AuthenticateUser", "test@example.com", "wrongpassword").
 		Return(nil, errors.New("invalid credentials"))
 	
-	// Create auth handler with mock service
-	jwtSecret := "test-secret-key"
-	authHandler := NewAuthHandler(mockUserService, jwtSecret)
+	// Create auth handler with mock services
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
 	
 	// Create a request body
 	loginRequest := models.LoginRequest{
@@ -162,6 +396,7 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 	assert.Equal(t, "Authentication failed: invalid credentials", response.Message)
 	mockUserService.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "IssueTokens")
 }
 
 func TestLogin_InvalidRequest(t *testing.T) {
@@ -171,9 +406,10 @@ func TestLogin_InvalidRequest(t *testing.T) {
 	// Create mock service
 	mockUserService := new(MockUserService)
 	
-	// Create auth handler with mock service
-	jwtSecret := "test-secret-key"
-	authHandler := NewAuthHandler(mockUserService, jwtSecret)
+	// Create auth handler with mock services
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
 	
 	// Create an invalid request body (missing required fields)
 	loginRequest := struct {
@@ -201,3 +437,832 @@ func TestLogin_InvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	mockUserService.AssertNotCalled(t, "AuthenticateUser")
 }
+
+func TestLogin_Throttled(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	
+	// Set up expectations - the account is locked
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", mock.Anything).
+		Return(&services.LoginThrottledError{Reason: "account is temporarily locked", RetryAfter: 90 * time.Second})
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, new(MockTokenService), new(MockIdentityService), new(MockTwoFactorService), mockLoginAttemptService, newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.LoginRequest{Email: "test@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Login(c)
+	
+	// Assert expectations - the password is not even checked
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+	mockUserService.AssertNotCalled(t, "AuthenticateUser", mock.Anything, mock.Anything)
+}
+
+func TestLogin_RecordsFailure(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	
+	// Set up expectations
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", "10.0.0.1").Return(nil)
+	mockUserService.On("AuthenticateUser", "test@example.com", "wrongpassword").
+		Return(nil, errors.New("invalid email or password"))
+	mockLoginAttemptService.On("RecordFailure", "test@example.com", mock.MatchedBy(func(actor models.AuditActor) bool {
+		return actor.Type == models.AuditActorAnonymous && actor.IPAddress == "10.0.0.1"
+	}), "test-agent", "invalid email or password").Return(nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, new(MockTokenService), new(MockIdentityService), new(MockTwoFactorService), mockLoginAttemptService, newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.LoginRequest{Email: "test@example.com", Password: "wrongpassword"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "10.0.0.1:12345"
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Login(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockLoginAttemptService.AssertExpectations(t)
+}
+
+func TestRefresh_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	tokens := &models.TokenPair{
+		AccessToken:  "new-access-token",
+		RefreshToken: "new-refresh-token",
+	}
+	
+	// Set up expectations
+	mockTokenService.On("Refresh", "old-refresh-token").Return(tokens, nil)
+	
+	// Create auth handler with mock services
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request body
+	jsonValue, _ := json.Marshal(models.RefreshRequest{RefreshToken: "old-refresh-token"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Refresh(c)
+	
+	// Parse the response
+	var response models.TokenPair
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "new-access-token", response.AccessToken)
+	assert.Equal(t, "new-refresh-token", response.RefreshToken)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestRefresh_ReusedToken(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	
+	// Set up expectations for a token that was already rotated
+	mockTokenService.On("Refresh", "used-refresh-token").
+		Return(nil, errors.New("refresh token reuse detected"))
+	
+	// Create auth handler with mock services
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request body
+	jsonValue, _ := json.Marshal(models.RefreshRequest{RefreshToken: "used-refresh-token"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Refresh(c)
+	
+	// Parse the response
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Refresh failed: refresh token reuse detected", response.Message)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestLogout_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	claims := &services.AccessClaims{UserID: 1, SessionID: "family-1"}
+	
+	// Set up expectations
+	mockTokenService.On("Logout", claims, mock.AnythingOfType("models.AuditActor")).Return(nil)
+	
+	// Create auth handler with mock services
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request and gin context with the authenticated claims
+	req, _ := http.NewRequest("POST", "/api/v1/auth/logout", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userID", uint(1))
+	c.Set("claims", claims)
+	
+	// Call the handler
+	authHandler.Logout(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestLogoutAll_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	
+	// Set up expectations
+	mockTokenService.On("LogoutAll", uint(1), mock.AnythingOfType("models.AuditActor")).Return(nil)
+	
+	// Create auth handler with mock services
+	mockIdentityService := new(MockIdentityService)
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request and gin context for an authenticated user
+	req, _ := http.NewRequest("POST", "/api/v1/auth/logout-all", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userID", uint(1))
+	
+	// Call the handler
+	authHandler.LogoutAll(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestLogin_EmailNotVerified(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	
+	// Set up expectations
+	mockUserService.On("AuthenticateUser", "test@example.com", "password123").Return(testUser, nil)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(services.ErrEmailNotVerified)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.LoginRequest{Email: "test@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Login(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockTokenService.AssertNotCalled(t, "IssueTokens", mock.Anything, mock.Anything)
+}
+
+func TestRegister_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	
+	registerRequest := models.RegisterRequest{
+		Email:     "new@example.com",
+		Password:  "password123",
+		FirstName: "New",
+		LastName:  "User",
+	}
+	
+	// Set up expectations
+	mockIdentityService.On("Register", &registerRequest).
+		Return(&models.User{ID: 5, Email: "new@example.com", FirstName: "New", LastName: "User"}, nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(registerRequest)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Register(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message": "Check your email to finish registering"}`, w.Body.String())
+	mockIdentityService.AssertExpectations(t)
+}
+
+func TestRegister_EmailTaken(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	
+	// Set up expectations
+	mockIdentityService.On("Register", mock.AnythingOfType("*models.RegisterRequest")).Return(nil, services.ErrEmailTaken)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.RegisterRequest{
+		Email:     "taken@example.com",
+		Password:  "password123",
+		FirstName: "Taken",
+		LastName:  "User",
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Register(c)
+	
+	// Assert expectations - the same response as a new registration, so the
+	// caller cannot tell that the address is registered
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"message": "Check your email to finish registering"}`, w.Body.String())
+}
+
+func TestRegister_Declined(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockIdentityService := new(MockIdentityService)
+	
+	// Set up expectations - the name is on the sanctions watchlist
+	mockIdentityService.On("Register", mock.AnythingOfType("*models.RegisterRequest")).Return(nil, services.ErrRegistrationDeclined)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(new(MockUserService), new(MockTokenService), mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.RegisterRequest{
+		Email:     "ivan@example.com",
+		Password:  "password123",
+		FirstName: "Ivan",
+		LastName:  "Volkov",
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Register(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRegister_InvalidRequest(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request with an invalid email and a short password
+	jsonValue, _ := json.Marshal(models.RegisterRequest{
+		Email:     "not-an-email",
+		Password:  "short",
+		FirstName: "Bad",
+		LastName:  "Input",
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Register(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockIdentityService.AssertNotCalled(t, "Register", mock.Anything)
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	
+	// Set up expectations
+	mockIdentityService.On("ResetPassword", "bad-token", "newpassword123", mock.AnythingOfType("models.AuditActor")).Return(errors.New("invalid or expired token"))
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.ResetPasswordRequest{Token: "bad-token", Password: "newpassword123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/reset-password", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.ResetPassword(c)
+	
+	// Parse the response
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Password reset failed: invalid or expired token", response.Message)
+	mockIdentityService.AssertExpectations(t)
+}
+
+func TestLogin_TwoFactorRequired(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create test user with 2FA enabled
+	enabledAt := time.Now()
+	testUser := &models.User{
+		ID:                 1,
+		Email:              "test@example.com",
+		TwoFactorEnabledAt: &enabledAt,
+	}
+	challenge := &models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    "challenge-token",
+		ExpiresAt:         time.Now().Add(5 * time.Minute),
+	}
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockIdentityService := new(MockIdentityService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	
+	// Set up expectations
+	mockUserService.On("AuthenticateUser", "test@example.com", "password123").Return(testUser, nil)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(nil)
+	mockTwoFactorService.On("CreateChallenge", testUser).Return(challenge, nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, mockIdentityService, mockTwoFactorService, newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.LoginRequest{Email: "test@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.Login(c)
+	
+	// Parse the response
+	var response models.TwoFactorChallenge
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations - no tokens are issued before the second factor
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.True(t, response.TwoFactorRequired)
+	assert.Equal(t, "challenge-token", response.ChallengeToken)
+	mockTokenService.AssertNotCalled(t, "IssueTokens", mock.Anything, mock.Anything)
+	mockTwoFactorService.AssertExpectations(t)
+}
+
+func TestLoginTwoFactor_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	tokens := &models.TokenPair{
+		AccessToken:      "access-token",
+		AccessExpiresAt:  time.Now().Add(15 * time.Minute),
+		RefreshToken:     "refresh-token",
+		RefreshExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	
+	// Create mock services
+	mockTokenService := new(MockTokenService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	
+	// Set up expectations
+	mockTwoFactorService.On("ChallengedUser", "challenge-token").Return(testUser, nil)
+	mockTwoFactorService.On("VerifyLoginCode", testUser, "123456").Return(nil)
+	mockTokenService.On("IssueTokens", testUser, mock.Anything).Return(tokens, nil)
+	mockIdentityService := new(MockIdentityService)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(new(MockUserService), mockTokenService, mockIdentityService, mockTwoFactorService, newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "123456"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login/2fa", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.LoginTwoFactor(c)
+	
+	// Parse the response
+	var response models.LoginResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "access-token", response.Token)
+	assert.Equal(t, testUser.ID, response.User.ID)
+	mockTwoFactorService.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestLoginTwoFactor_InvalidCode(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockTokenService := new(MockTokenService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	
+	// Set up expectations - the failure is counted against the challenged user
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	mockTwoFactorService.On("ChallengedUser", "challenge-token").Return(testUser, nil)
+	mockTwoFactorService.On("VerifyLoginCode", testUser, "000000").Return(services.ErrInvalidTwoFactorCode)
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", mock.Anything).Return(nil)
+	mockLoginAttemptService.On("RecordFailure", "test@example.com", mock.Anything, mock.Anything, "invalid two-factor code").Return(nil)
+	mockIdentityService := new(MockIdentityService)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(new(MockUserService), mockTokenService, mockIdentityService, mockTwoFactorService, mockLoginAttemptService, newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "000000"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login/2fa", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.LoginTwoFactor(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockTokenService.AssertNotCalled(t, "IssueTokens", mock.Anything, mock.Anything)
+	mockLoginAttemptService.AssertExpectations(t)
+}
+
+func TestLoginTwoFactor_Throttled(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	
+	// Set up expectations - the account was locked after the challenge was issued
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	mockTwoFactorService.On("ChallengedUser", "challenge-token").Return(testUser, nil)
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", mock.Anything).
+		Return(&services.LoginThrottledError{Reason: "account is temporarily locked", RetryAfter: 90 * time.Second})
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(new(MockUserService), new(MockTokenService), new(MockIdentityService), mockTwoFactorService, mockLoginAttemptService, newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "ABCDE-12345"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login/2fa", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.LoginTwoFactor(c)
+	
+	// Assert expectations - the code is not checked, so it is not used up
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+	mockTwoFactorService.AssertNotCalled(t, "VerifyLoginCode", mock.Anything, mock.Anything)
+}
+
+func TestLoginTwoFactor_UserDisabled(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock services
+	mockTokenService := new(MockTokenService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	mockIdentityService := new(MockIdentityService)
+	
+	// Set up expectations - the user was disabled after the challenge was issued
+	disabledAt := time.Now()
+	testUser := &models.User{ID: 1, Email: "test@example.com", DisabledAt: &disabledAt}
+	mockTwoFactorService.On("ChallengedUser", "challenge-token").Return(testUser, nil)
+	mockIdentityService.On("CheckLoginAllowed", testUser).Return(services.ErrUserDisabled)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(new(MockUserService), mockTokenService, mockIdentityService, mockTwoFactorService, newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge-token", Code: "123456"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login/2fa", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	
+	// Call the handler
+	authHandler.LoginTwoFactor(c)
+	
+	// Assert expectations - no tokens, and the code is not used up
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrUserDisabled.Error())
+	mockTokenService.AssertNotCalled(t, "IssueTokens", mock.Anything, mock.Anything)
+	mockTwoFactorService.AssertNotCalled(t, "VerifyLoginCode", mock.Anything, mock.Anything)
+}
+
+func TestReauthenticate_WithPassword(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword)}
+	claims := &services.AccessClaims{UserID: 1, SessionID: "family-1"}
+	elevated := &models.ElevatedToken{Token: "elevated-token", ExpiresAt: time.Now().Add(5 * time.Minute)}
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	
+	// Set up expectations
+	mockUserService.On("GetUserByID", uint(1)).Return(testUser, nil)
+	mockTokenService.On("Elevate", claims).Return(elevated, nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, new(MockIdentityService), new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.ReauthenticateRequest{Password: "password123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/reauthenticate", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("claims", claims)
+	
+	// Call the handler
+	authHandler.Reauthenticate(c)
+	
+	// Parse the response
+	var response models.ElevatedToken
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "elevated-token", response.Token)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestReauthenticate_WrongPassword(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword)}
+	claims := &services.AccessClaims{UserID: 1, SessionID: "family-1"}
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	
+	// Set up expectations
+	mockUserService.On("GetUserByID", uint(1)).Return(testUser, nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, new(MockIdentityService), new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(models.ReauthenticateRequest{Password: "wrongpassword"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/reauthenticate", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("claims", claims)
+	
+	// Call the handler
+	authHandler.Reauthenticate(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockTokenService.AssertNotCalled(t, "Elevate", mock.Anything)
+}
+
+func TestReauthenticate_TwoFactorUserNeedsCode(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	enabledAt := time.Now()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	testUser := &models.User{ID: 1, Password: string(hashedPassword), TwoFactorEnabledAt: &enabledAt}
+	claims := &services.AccessClaims{UserID: 1, SessionID: "family-1"}
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	
+	// Set up expectations
+	mockUserService.On("GetUserByID", uint(1)).Return(testUser, nil)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, new(MockIdentityService), new(MockTwoFactorService), newAllowingLoginAttemptService(), newRehashingPasswordService())
+	
+	// Create a request with only the password
+	jsonValue, _ := json.Marshal(models.ReauthenticateRequest{Password: "password123"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/reauthenticate", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("claims", claims)
+	
+	// Call the handler
+	authHandler.Reauthenticate(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockTokenService.AssertNotCalled(t, "Elevate", mock.Anything)
+}
+
+// lockingLoginAttemptService counts failures and locks the account once there
+// are maxFailures of them, as the login attempt service does
+type lockingLoginAttemptService struct {
+	MockLoginAttemptService
+	maxFailures int
+	failures    int
+}
+
+func (s *lockingLoginAttemptService) CheckAllowed(ctx context.Context, email, ipAddress string) error {
+	if s.failures >= s.maxFailures {
+		return &services.LoginThrottledError{Reason: "account is temporarily locked", RetryAfter: 15 * time.Minute}
+	}
+	return nil
+}
+
+func (s *lockingLoginAttemptService) RecordFailure(ctx context.Context, email string, actor models.AuditActor, userAgent, reason string) error {
+	s.failures++
+	return nil
+}
+
+func TestReauthenticate_RepeatedWrongCodesLockAccount(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	enabledAt := time.Now()
+	testUser := &models.User{ID: 1, Email: "test@example.com", TwoFactorEnabledAt: &enabledAt}
+	claims := &services.AccessClaims{UserID: 1, SessionID: "family-1"}
+	
+	// Create mock services
+	mockUserService := new(MockUserService)
+	mockTokenService := new(MockTokenService)
+	mockTwoFactorService := new(MockTwoFactorService)
+	loginAttemptService := &lockingLoginAttemptService{maxFailures: 3}
+	
+	// Set up expectations - every code is wrong
+	mockUserService.On("GetUserByID", uint(1)).Return(testUser, nil)
+	mockTwoFactorService.On("VerifyCode", testUser, "000000").Return(services.ErrInvalidTwoFactorCode)
+	
+	// Create auth handler with mock services
+	authHandler := NewAuthHandler(mockUserService, mockTokenService, new(MockIdentityService), mockTwoFactorService, loginAttemptService, newRehashingPasswordService())
+	
+	reauthenticate := func() *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(models.ReauthenticateRequest{Code: "000000"})
+		req, _ := http.NewRequest("POST", "/api/v1/auth/reauthenticate", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Set("claims", claims)
+		authHandler.Reauthenticate(c)
+		return w
+	}
+	
+	// Call the handler until the account locks, then once more
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, reauthenticate().Code)
+	}
+	locked := reauthenticate()
+	
+	// Assert expectations - each wrong code counted, and once locked the code
+	// is no longer checked
+	assert.Equal(t, 3, loginAttemptService.failures)
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code)
+	assert.Equal(t, "900", locked.Header().Get("Retry-After"))
+	mockTwoFactorService.AssertNumberOfCalls(t, "VerifyCode", 3)
+	mockTokenService.AssertNotCalled(t, "Elevate", mock.Anything)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/health"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker}
+}
+
+// Live reports that the process is serving requests. It checks no
+// dependencies, so an orchestrator only restarts a server that hangs. It is
+// mounted at /healthz, outside the API base path, so it is not part of the
+// Swagger documentation.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Ready runs the dependency checks and answers 503 when one fails or the
+// server is shutting down, so load balancers stop routing to it. It is
+// mounted at /readyz.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthProbes(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// The database is down
+	checker := health.New(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	healthHandler := NewHealthHandler(checker)
+
+	// Call both probes
+	live := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(live)
+	c.Request, _ = http.NewRequest("GET", "/healthz", nil)
+	healthHandler.Live(c)
+
+	ready := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(ready)
+	c.Request, _ = http.NewRequest("GET", "/readyz", nil)
+	healthHandler.Ready(c)
+
+	// The process is alive but not ready
+	assert.Equal(t, http.StatusOK, live.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, ready.Code)
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(ready.Body.Bytes(), &report))
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+}
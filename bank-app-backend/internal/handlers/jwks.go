@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+)
+
+type JWKSHandler struct {
+	keySet *signing.KeySet
+}
+
+func NewJWKSHandler(keySet *signing.KeySet) *JWKSHandler {
+	return &JWKSHandler{keySet}
+}
+
+// GetJWKS serves the public keys that verify access tokens, matched by their
+// kid header. The set is empty when tokens are signed with a shared secret.
+// It is mounted at /.well-known/jwks.json, outside the API base path, so it
+// is not part of the Swagger documentation.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Verifiers may cache the keys; a rotated-in key is published before it signs
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keySet.JWKS())
+}
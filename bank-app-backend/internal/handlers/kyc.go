@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type KYCHandler struct {
+	kycService      services.KYCService
+	maxDocumentSize int64
+}
+
+func NewKYCHandler(kycService services.KYCService, maxDocumentSize int64) *KYCHandler {
+	return &KYCHandler{kycService, maxDocumentSize}
+}
+
+// @Summary Get my KYC profile
+// @Description Get the current user's identity verification profile. A user who has not started has status NOT_STARTED.
+// @Tags kyc
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.KYCProfile
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/kyc [get]
+func (h *KYCHandler) GetMyProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	profile, err := h.kycService.GetProfile(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Save my KYC profile
+// @Description Save the current user's personal details as a draft. A profile can't be changed while it is pending review or verified.
+// @Tags kyc
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param kycProfileRequest body models.KYCProfileRequest true "KYC Profile Request"
+// @Success 200 {object} models.KYCProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/kyc [put]
+func (h *KYCHandler) SaveMyProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	var request models.KYCProfileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	profile, err := h.kycService.SaveProfile(c.Request.Context(), userID.(uint), &request)
+	if err != nil {
+		if errors.Is(err, services.ErrKYCLocked) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to save KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Upload a KYC document
+// @Description Upload a JPEG, PNG or PDF document to the current user's KYC profile. Save the profile first.
+// @Tags kyc
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param kind formData string true "Document kind" Enums(id_front, id_back, proof_of_address)
+// @Param file formData file true "Document"
+// @Success 201 {object} models.KYCDocument
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Router /users/me/kyc/documents [post]
+func (h *KYCHandler) UploadDocument(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "A file is required"})
+		return
+	}
+	if h.maxDocumentSize > 0 && file.Size > h.maxDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: services.ErrDocumentTooLarge.Error()})
+		return
+	}
+	reader, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to read file: " + err.Error()})
+		return
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to read file: " + err.Error()})
+		return
+	}
+
+	document, err := h.kycService.UploadDocument(c.Request.Context(), userID.(uint), c.PostForm("kind"), file.Filename, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrKYCLocked):
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, services.ErrDocumentTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, services.ErrKYCProfileNotFound):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Save your KYC profile before uploading documents"})
+		default:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to upload document: " + err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, document)
+}
+
+// @Summary Submit my KYC profile
+// @Description Send the current user's KYC profile for review. The front of an identity document must be uploaded.
+// @Tags kyc
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.KYCProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /users/me/kyc/submit [post]
+func (h *KYCHandler) Submit(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	profile, err := h.kycService.Submit(c.Request.Context(), userID.(uint), auditActor(c))
+	if err != nil {
+		if errors.Is(err, services.ErrKYCLocked) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to submit KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Get KYC profiles
+// @Description Get KYC profiles in a status, oldest submission first. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Profile status" Enums(DRAFT, PENDING, VERIFIED, REJECTED) default(PENDING)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.KYCProfile
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/kyc [get]
+func (h *KYCHandler) GetProfiles(c *gin.Context) {
+	status := c.DefaultQuery("status", models.KYCPending)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	profiles, err := h.kycService.GetProfiles(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get KYC profiles: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// @Summary Get a user's KYC profile
+// @Description Get a user's KYC profile with its documents. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID"
+// @Success 200 {object} models.KYCProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/kyc/{userId} [get]
+func (h *KYCHandler) GetProfile(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	profile, err := h.kycService.GetProfile(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary Download a KYC document
+// @Description Download a decrypted KYC document. Admin only.
+// @Tags admin
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param id path int true "Document ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/kyc/documents/{id} [get]
+func (h *KYCHandler) GetDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	document, data, err := h.kycService.GetDocument(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Document not found: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(document.FileName))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, document.ContentType, data)
+}
+
+// @Summary Approve a KYC profile
+// @Description Verify a pending KYC profile at level 1 (identity document) or 2 (identity and proof of address). Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID"
+// @Param kycApproveRequest body models.KYCApproveRequest true "KYC Approve Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/kyc/{userId}/approve [post]
+func (h *KYCHandler) Approve(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	var request models.KYCApproveRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.kycService.Approve(c.Request.Context(), uint(userID), adminID.(uint), request.Level, auditActor(c)); err != nil {
+		if errors.Is(err, services.ErrKYCNotPending) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to approve KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "KYC profile approved"})
+}
+
+// @Summary Reject a KYC profile
+// @Description Reject a pending KYC profile with a reason the user can see. The user may correct it and submit again. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId path int true "User ID"
+// @Param kycRejectRequest body models.KYCRejectRequest true "KYC Reject Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/kyc/{userId}/reject [post]
+func (h *KYCHandler) Reject(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	var request models.KYCRejectRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.kycService.Reject(c.Request.Context(), uint(userID), adminID.(uint), request.Reason, auditActor(c)); err != nil {
+		if errors.Is(err, services.ErrKYCNotPending) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to reject KYC profile: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "KYC profile rejected"})
+}
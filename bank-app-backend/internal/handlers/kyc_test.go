@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the KYC service
+type MockKYCService struct {
+	mock.Mock
+}
+
+func (m *MockKYCService) GetProfile(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) SaveProfile(ctx context.Context, userID uint, request *models.KYCProfileRequest) (*models.KYCProfile, error) {
+	args := m.Called(userID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) UploadDocument(ctx context.Context, userID uint, kind, fileName string, data []byte) (*models.KYCDocument, error) {
+	args := m.Called(userID, kind, fileName, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCDocument), args.Error(1)
+}
+
+func (m *MockKYCService) Submit(ctx context.Context, userID uint, actor models.AuditActor) (*models.KYCProfile, error) {
+	args := m.Called(userID, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) GetProfiles(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) GetDocument(ctx context.Context, id uint) (*models.KYCDocument, []byte, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.KYCDocument), args.Get(1).([]byte), args.Error(2)
+}
+
+func (m *MockKYCService) Approve(ctx context.Context, userID, adminID uint, level int, actor models.AuditActor) error {
+	args := m.Called(userID, adminID, level, actor)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) Reject(ctx context.Context, userID, adminID uint, reason string, actor models.AuditActor) error {
+	args := m.Called(userID, adminID, reason, actor)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) CheckTransfer(ctx context.Context, userID uint, amount float64) error {
+	args := m.Called(userID, amount)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) CheckTransferWithTx(ctx context.Context, userID uint, amount float64, tx repository.GormTx) error {
+	args := m.Called(userID, amount, tx)
+	return args.Error(0)
+}
+
+func TestSaveMyKYCProfile(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	body := `{"dateOfBirth":"1990-04-12","addressLine1":"1 Main St","city":"Springfield","postalCode":"12345","country":"US","idType":"passport","idNumber":"X1234567"}`
+
+	tests := []struct {
+		name         string
+		body         string
+		serviceError error
+		expectedCode int
+	}{
+		{"saved", body, nil, http.StatusOK},
+		{"locked", body, services.ErrKYCLocked, http.StatusConflict},
+		{"unknown ID type", `{"dateOfBirth":"1990-04-12","addressLine1":"1 Main St","city":"Springfield","postalCode":"12345","country":"US","idType":"library_card","idNumber":"1"}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCService := new(MockKYCService)
+			mockKYCService.On("SaveProfile", uint(1), mock.AnythingOfType("*models.KYCProfileRequest")).Return(&models.KYCProfile{UserID: 1, Status: models.KYCDraft}, tt.serviceError)
+			kycHandler := NewKYCHandler(mockKYCService, 1024)
+
+			req, _ := http.NewRequest("PUT", "/api/v1/users/me/kyc", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Set("userID", uint(1))
+
+			// Act
+			kycHandler.SaveMyProfile(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestUploadKYCDocument(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		size         int
+		serviceError error
+		expectedCode int
+	}{
+		{"uploaded", 16, nil, http.StatusCreated},
+		{"too large", 2048, nil, http.StatusRequestEntityTooLarge},
+		{"wrong type", 16, services.ErrDocumentType, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCService := new(MockKYCService)
+			mockKYCService.On("UploadDocument", uint(1), models.KYCDocumentIDFront, "passport.png", mock.Anything).Return(&models.KYCDocument{ID: 5}, tt.serviceError)
+			kycHandler := NewKYCHandler(mockKYCService, 1024)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			writer.WriteField("kind", models.KYCDocumentIDFront)
+			part, _ := writer.CreateFormFile("file", "passport.png")
+			part.Write(make([]byte, tt.size))
+			writer.Close()
+
+			req, _ := http.NewRequest("POST", "/api/v1/users/me/kyc/documents", &body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Set("userID", uint(1))
+
+			// Act
+			kycHandler.UploadDocument(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusRequestEntityTooLarge {
+				mockKYCService.AssertNotCalled(t, "UploadDocument", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetKYCDocument(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockKYCService := new(MockKYCService)
+	mockKYCService.On("GetDocument", uint(5)).Return(&models.KYCDocument{ID: 5, FileName: "passport.pdf", ContentType: "application/pdf"}, []byte("%PDF-1.4"), nil)
+	kycHandler := NewKYCHandler(mockKYCService, 1024)
+
+	req, _ := http.NewRequest("GET", "/api/v1/admin/kyc/documents/5", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = []gin.Param{{Key: "id", Value: "5"}}
+
+	// Call the handler
+	kycHandler.GetDocument(c)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `"passport.pdf"`)
+	assert.Equal(t, "%PDF-1.4", w.Body.String())
+}
+
+func TestApproveKYCProfile(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		body         string
+		serviceError error
+		expectedCode int
+	}{
+		{"approved", `{"level":2}`, nil, http.StatusOK},
+		{"not pending", `{"level":2}`, services.ErrKYCNotPending, http.StatusConflict},
+		{"invalid level", `{"level":3}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCService := new(MockKYCService)
+			mockKYCService.On("Approve", uint(3), uint(5), 2, mock.AnythingOfType("models.AuditActor")).Return(tt.serviceError)
+			kycHandler := NewKYCHandler(mockKYCService, 1024)
+
+			req, _ := http.NewRequest("POST", "/api/v1/admin/kyc/3/approve", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = []gin.Param{{Key: "userId", Value: "3"}}
+			c.Set("userID", uint(5))
+
+			// Act
+			kycHandler.Approve(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestRejectKYCProfile(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockKYCService := new(MockKYCService)
+	mockKYCService.On("Reject", uint(3), uint(5), "Document is blurry", mock.AnythingOfType("models.AuditActor")).Return(nil)
+	kycHandler := NewKYCHandler(mockKYCService, 1024)
+
+	req, _ := http.NewRequest("POST", "/api/v1/admin/kyc/3/reject", bytes.NewBufferString(`{"reason":"Document is blurry"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = []gin.Param{{Key: "userId", Value: "3"}}
+	c.Set("userID", uint(5))
+
+	// Call the handler
+	kycHandler.Reject(c)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "KYC profile rejected", response["message"])
+	mockKYCService.AssertExpectations(t)
+}
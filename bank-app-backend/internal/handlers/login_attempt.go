@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type LoginAttemptHandler struct {
+	loginAttemptService services.LoginAttemptService
+}
+
+func NewLoginAttemptHandler(loginAttemptService services.LoginAttemptService) *LoginAttemptHandler {
+	return &LoginAttemptHandler{loginAttemptService}
+}
+
+// @Summary Unlock a user
+// @Description Lift a lockout caused by failed logins and clear the failure count. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{id}/unlock [post]
+func (h *LoginAttemptHandler) Unlock(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	if err := h.loginAttemptService.Unlock(c.Request.Context(), uint(id), auditActor(c)); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Failed to unlock user: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User has been unlocked"})
+}
+
+// @Summary Get a user's login attempts
+// @Description Get the most recent login attempts against a user's account, newest first. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} models.LoginAttempt
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/users/{id}/login-attempts [get]
+func (h *LoginAttemptHandler) GetAttemptsByUserID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	attempts, err := h.loginAttemptService.GetAttemptsByUserID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Failed to get login attempts: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type PasswordHandler struct {
+	passwordService     services.PasswordService
+	tokenService        services.TokenService
+	loginAttemptService services.LoginAttemptService
+}
+
+func NewPasswordHandler(passwordService services.PasswordService, tokenService services.TokenService, loginAttemptService services.LoginAttemptService) *PasswordHandler {
+	return &PasswordHandler{passwordService, tokenService, loginAttemptService}
+}
+
+// @Summary Change my password
+// @Description Set a new password for the current user. The current password is required and the new one must meet the password policy.
+// @Description Wrong current passwords count towards the login lockout. Every other session of the user is ended.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param changePasswordRequest body models.ChangePasswordRequest true "Change Password Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/password [post]
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+	accessClaims := claims.(*services.AccessClaims)
+
+	var changeRequest models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&changeRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	// Guesses of the current password count towards the same lockout as
+	// logins, so a stolen access token cannot be used to brute force it
+	if !checkLoginAllowed(c, h.loginAttemptService, accessClaims.Email) {
+		return
+	}
+
+	err := h.passwordService.ChangePassword(c.Request.Context(), accessClaims.UserID, changeRequest.CurrentPassword, changeRequest.NewPassword, auditActor(c))
+	switch {
+	case errors.Is(err, services.ErrWrongPassword):
+		recordLoginFailure(c, h.loginAttemptService, accessClaims.Email, "invalid current password on password change")
+		c.JSON(http.StatusForbidden, ErrorResponse{Message: "Password change failed: " + err.Error()})
+		return
+	case errors.Is(err, services.ErrPasswordPolicy):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Password change failed: " + err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to change password"})
+		return
+	}
+
+	// Whoever else knew the old password is signed out; this session stays
+	if err := h.tokenService.LogoutOthers(c.Request.Context(), accessClaims.UserID, accessClaims.SessionID, auditActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Password has been changed, but other sessions could not be ended"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been changed"})
+}
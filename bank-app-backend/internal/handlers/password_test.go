@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock password service
+type MockPasswordService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordService) Validate(password string) error {
+	args := m.Called(password)
+	return args.Error(0)
+}
+
+func (m *MockPasswordService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	args := m.Called(user, password)
+	return args.Error(0)
+}
+
+func (m *MockPasswordService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string, actor models.AuditActor) error {
+	args := m.Called(userID, currentPassword, newPassword, actor)
+	return args.Error(0)
+}
+
+func (m *MockPasswordService) RehashIfNeeded(ctx context.Context, user *models.User, password string) error {
+	args := m.Called(user, password)
+	return args.Error(0)
+}
+
+// newRehashingPasswordService returns a password service mock for the login flow
+func newRehashingPasswordService() *MockPasswordService {
+	m := new(MockPasswordService)
+	m.On("RehashIfNeeded", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return m
+}
+
+// newChangePasswordContext builds a gin context for the current user's change-password request
+func newChangePasswordContext(w *httptest.ResponseRecorder, request models.ChangePasswordRequest) *gin.Context {
+	jsonValue, _ := json.Marshal(request)
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/api/v1/users/me/password", bytes.NewBuffer(jsonValue))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("userID", uint(1))
+	c.Set("claims", &services.AccessClaims{UserID: 1, Email: "test@example.com", SessionID: "family-1"})
+	return c
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockPasswordService := new(MockPasswordService)
+	mockPasswordService.On("ChangePassword", uint(1), "password123", "new-password-456", mock.AnythingOfType("models.AuditActor")).Return(nil)
+
+	// Every other session is ended, the current one is kept
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("LogoutOthers", uint(1), "family-1", mock.AnythingOfType("models.AuditActor")).Return(nil)
+
+	handler := NewPasswordHandler(mockPasswordService, mockTokenService, newAllowingLoginAttemptService())
+
+	// Call the handler
+	w := httptest.NewRecorder()
+	handler.ChangePassword(newChangePasswordContext(w, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "new-password-456"}))
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockPasswordService.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockPasswordService := new(MockPasswordService)
+	mockPasswordService.On("ChangePassword", uint(1), "wrongpassword", "new-password-456", mock.AnythingOfType("models.AuditActor")).Return(services.ErrWrongPassword)
+
+	// The wrong guess counts towards the login lockout
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", mock.Anything).Return(nil)
+	mockLoginAttemptService.On("RecordFailure", "test@example.com", mock.Anything, mock.Anything, "invalid current password on password change").Return(nil)
+	mockTokenService := new(MockTokenService)
+
+	handler := NewPasswordHandler(mockPasswordService, mockTokenService, mockLoginAttemptService)
+
+	// Call the handler
+	w := httptest.NewRecorder()
+	handler.ChangePassword(newChangePasswordContext(w, models.ChangePasswordRequest{CurrentPassword: "wrongpassword", NewPassword: "new-password-456"}))
+
+	// Assert expectations
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockPasswordService.AssertExpectations(t)
+	mockLoginAttemptService.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "LogoutOthers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_PolicyViolation(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockPasswordService := new(MockPasswordService)
+	mockPasswordService.On("ChangePassword", uint(1), "password123", "short", mock.AnythingOfType("models.AuditActor")).
+		Return(fmt.Errorf("%w: it must be at least 8 characters long", services.ErrPasswordPolicy))
+
+	handler := NewPasswordHandler(mockPasswordService, new(MockTokenService), newAllowingLoginAttemptService())
+
+	// Call the handler
+	w := httptest.NewRecorder()
+	handler.ChangePassword(newChangePasswordContext(w, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "short"}))
+
+	// Parse the response
+	var response ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, response.Message, "at least 8 characters")
+}
+
+func TestChangePassword_Throttled(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock services - the account is locked after earlier wrong guesses
+	mockPasswordService := new(MockPasswordService)
+	mockLoginAttemptService := new(MockLoginAttemptService)
+	mockLoginAttemptService.On("CheckAllowed", "test@example.com", mock.Anything).
+		Return(&services.LoginThrottledError{Reason: "account is temporarily locked", RetryAfter: time.Minute})
+
+	handler := NewPasswordHandler(mockPasswordService, new(MockTokenService), mockLoginAttemptService)
+
+	// Call the handler
+	w := httptest.NewRecorder()
+	handler.ChangePassword(newChangePasswordContext(w, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "new-password-456"}))
+
+	// Assert expectations - the current password is not even checked
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	mockPasswordService.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
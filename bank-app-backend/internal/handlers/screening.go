@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type ScreeningHandler struct {
+	sanctionsService services.SanctionsService
+}
+
+func NewScreeningHandler(sanctionsService services.SanctionsService) *ScreeningHandler {
+	return &ScreeningHandler{sanctionsService}
+}
+
+// @Summary Get screening hits
+// @Description Get sanctions watchlist matches recorded at registration and transfer time, oldest first. Without a status, returns the hits not yet cleared. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Hit status" Enums(FLAGGED, BLOCKED, CLEARED)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.ScreeningHit
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/screening/hits [get]
+func (h *ScreeningHandler) GetHits(c *gin.Context) {
+	status := c.Query("status")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	hits, err := h.sanctionsService.GetHits(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get screening hits: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hits)
+}
+
+// @Summary Clear a screening hit
+// @Description Mark a watchlist match as a false positive, with a justification. The user is no longer matched against the entry. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Hit ID"
+// @Param clearScreeningHitRequest body models.ClearScreeningHitRequest true "Clear Screening Hit Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/screening/hits/{id}/clear [post]
+func (h *ScreeningHandler) ClearHit(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	var request models.ClearScreeningHitRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	if err := h.sanctionsService.ClearHit(c.Request.Context(), uint(id), userID.(uint), request.Justification, auditActor(c)); err != nil {
+		if errors.Is(err, services.ErrHitAlreadyCleared) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to clear screening hit: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Screening hit cleared"})
+}
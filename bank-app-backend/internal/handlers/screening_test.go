@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the sanctions service
+type MockSanctionsService struct {
+	mock.Mock
+}
+
+func (m *MockSanctionsService) Screen(ctx context.Context, name, email string, subjectID *uint, context string) (*models.ScreeningResult, error) {
+	args := m.Called(name, email, subjectID, context)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningResult), args.Error(1)
+}
+
+func (m *MockSanctionsService) ScreenUser(ctx context.Context, userID uint, context string) (*models.ScreeningResult, error) {
+	args := m.Called(userID, context)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningResult), args.Error(1)
+}
+
+func (m *MockSanctionsService) Record(ctx context.Context, result *models.ScreeningResult, subjectID *uint) error {
+	args := m.Called(result, subjectID)
+	return args.Error(0)
+}
+
+func (m *MockSanctionsService) GetHits(ctx context.Context, status string, limit, offset int) ([]models.ScreeningHit, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockSanctionsService) ClearHit(ctx context.Context, id, adminID uint, justification string, actor models.AuditActor) error {
+	args := m.Called(id, adminID, justification, actor)
+	return args.Error(0)
+}
+
+func TestGetScreeningHits(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create mock service
+	mockSanctionsService := new(MockSanctionsService)
+	mockSanctionsService.On("GetHits", "", 20, 0).Return([]models.ScreeningHit{{ID: 3, EntryID: "9001", Status: models.HitFlagged}}, nil)
+	screeningHandler := NewScreeningHandler(mockSanctionsService)
+
+	// Create a request without a status, which lists the hits not yet cleared
+	req, _ := http.NewRequest("GET", "/api/v1/admin/screening/hits", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	// Call the handler
+	screeningHandler.GetHits(c)
+
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	var hits []models.ScreeningHit
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hits))
+	assert.Len(t, hits, 1)
+	mockSanctionsService.AssertExpectations(t)
+}
+
+func TestClearScreeningHit(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		body         string
+		serviceError error
+		expectedCode int
+	}{
+		{"cleared", `{"justification":"Different date of birth"}`, nil, http.StatusOK},
+		{"already cleared", `{"justification":"Different date of birth"}`, services.ErrHitAlreadyCleared, http.StatusConflict},
+		{"justification too short", `{"justification":"ok"}`, nil, http.StatusBadRequest},
+		{"justification missing", `{}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockSanctionsService := new(MockSanctionsService)
+			mockSanctionsService.On("ClearHit", uint(3), uint(5), "Different date of birth", mock.AnythingOfType("models.AuditActor")).Return(tt.serviceError)
+			screeningHandler := NewScreeningHandler(mockSanctionsService)
+
+			req, _ := http.NewRequest("POST", "/api/v1/admin/screening/hits/3/clear", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = []gin.Param{{Key: "id", Value: "3"}}
+			c.Set("userID", uint(5))
+
+			// Act
+			screeningHandler.ClearHit(c)
+
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusBadRequest {
+				mockSanctionsService.AssertNotCalled(t, "ClearHit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
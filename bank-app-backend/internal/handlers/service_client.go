@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+const grantTypeClientCredentials = "client_credentials"
+
+type ServiceClientHandler struct {
+	serviceClientService services.ServiceClientService
+}
+
+func NewServiceClientHandler(serviceClientService services.ServiceClientService) *ServiceClientHandler {
+	return &ServiceClientHandler{serviceClientService}
+}
+
+// @Summary Client-credentials token
+// @Description OAuth2 client-credentials grant for service clients. Credentials are sent in the body or with HTTP Basic
+// @Description authentication. The token carries the requested scopes, or all granted scopes when none are requested.
+// @Tags auth
+// @Accept x-www-form-urlencoded,json
+// @Produce json
+// @Param tokenRequest body models.ClientCredentialsRequest true "Token Request"
+// @Success 200 {object} models.ClientToken
+// @Failure 400 {object} models.OAuthErrorResponse
+// @Failure 401 {object} models.OAuthErrorResponse
+// @Failure 500 {object} models.OAuthErrorResponse
+// @Router /auth/token [post]
+func (h *ServiceClientHandler) Token(c *gin.Context) {
+	var tokenRequest models.ClientCredentialsRequest
+	if err := c.ShouldBind(&tokenRequest); err != nil {
+		c.JSON(http.StatusBadRequest, models.OAuthErrorResponse{Error: "invalid_request", ErrorDescription: err.Error()})
+		return
+	}
+	if tokenRequest.GrantType != grantTypeClientCredentials {
+		c.JSON(http.StatusBadRequest, models.OAuthErrorResponse{Error: "unsupported_grant_type"})
+		return
+	}
+
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		tokenRequest.ClientID = clientID
+		tokenRequest.ClientSecret = clientSecret
+	}
+
+	token, err := h.serviceClientService.IssueToken(c.Request.Context(), tokenRequest.ClientID, tokenRequest.ClientSecret, tokenRequest.Scope)
+	switch {
+	case errors.Is(err, services.ErrInvalidClient):
+		c.JSON(http.StatusUnauthorized, models.OAuthErrorResponse{Error: "invalid_client"})
+		return
+	case errors.Is(err, services.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, models.OAuthErrorResponse{Error: "invalid_scope", ErrorDescription: err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	// Tokens must not be cached by intermediaries (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, token)
+}
+
+// @Summary Create a service client
+// @Description Register a machine client with the given scopes. The client secret and API key are only returned once. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createServiceClientRequest body models.CreateServiceClientRequest true "Create Service Client Request"
+// @Success 201 {object} models.ServiceClientCredentials
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /admin/clients [post]
+func (h *ServiceClientHandler) CreateClient(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	var createRequest models.CreateServiceClientRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+		return
+	}
+
+	credentials, err := h.serviceClientService.CreateClient(c.Request.Context(), &createRequest, userID.(uint), auditActor(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to create service client: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// @Summary Get all service clients
+// @Description Get all service clients, including revoked ones. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ServiceClientDTO
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/clients [get]
+func (h *ServiceClientHandler) GetAllClients(c *gin.Context) {
+	clients, err := h.serviceClientService.GetAllClients(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get service clients: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// @Summary Get a service client
+// @Description Get a service client by ID. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service client ID"
+// @Success 200 {object} models.ServiceClientDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/clients/{id} [get]
+func (h *ServiceClientHandler) GetClientByID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	client, err := h.serviceClientService.GetClientByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Service client not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, client)
+}
+
+// @Summary Revoke a service client
+// @Description Disable a service client. Its API key and tokens stop working immediately. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service client ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/clients/{id} [delete]
+func (h *ServiceClientHandler) RevokeClient(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	if err := h.serviceClientService.RevokeClient(c.Request.Context(), uint(id), auditActor(c)); err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Failed to revoke service client: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service client has been revoked"})
+}
+
+// @Summary Get a service client's usage
+// @Description Get the most recent API requests made by a service client, newest first. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Service client ID"
+// @Success 200 {array} models.ServiceClientUsage
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/clients/{id}/usage [get]
+func (h *ServiceClientHandler) GetUsage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	usage, err := h.serviceClientService.GetUsageByClientID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Failed to get service client usage: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
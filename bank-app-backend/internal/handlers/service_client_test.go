@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock service client service
+type MockServiceClientService struct {
+	mock.Mock
+}
+
+func (m *MockServiceClientService) CreateClient(ctx context.Context, request *models.CreateServiceClientRequest, createdByID uint, actor models.AuditActor) (*models.ServiceClientCredentials, error) {
+	args := m.Called(request, createdByID, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClientCredentials), args.Error(1)
+}
+
+func (m *MockServiceClientService) GetAllClients(ctx context.Context) ([]models.ServiceClientDTO, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServiceClientDTO), args.Error(1)
+}
+
+func (m *MockServiceClientService) GetClientByID(ctx context.Context, id uint) (*models.ServiceClientDTO, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClientDTO), args.Error(1)
+}
+
+func (m *MockServiceClientService) RevokeClient(ctx context.Context, id uint, actor models.AuditActor) error {
+	args := m.Called(id, actor)
+	return args.Error(0)
+}
+
+func (m *MockServiceClientService) IssueToken(ctx context.Context, clientID, clientSecret, scope string) (*models.ClientToken, error) {
+	args := m.Called(clientID, clientSecret, scope)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ClientToken), args.Error(1)
+}
+
+func (m *MockServiceClientService) AuthenticateAPIKey(ctx context.Context, apiKey string) (*models.ServiceClient, error) {
+	args := m.Called(apiKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientService) GetActiveClient(ctx context.Context, clientID string) (*models.ServiceClient, error) {
+	args := m.Called(clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientService) RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error {
+	args := m.Called(usage)
+	return args.Error(0)
+}
+
+func (m *MockServiceClientService) GetUsageByClientID(ctx context.Context, id uint) ([]models.ServiceClientUsage, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServiceClientUsage), args.Error(1)
+}
+
+// newTokenRequest builds a form-encoded client-credentials request
+func newTokenRequest(form url.Values) *http.Request {
+	req, _ := http.NewRequest("POST", "/api/v1/auth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestToken_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockServiceClientService := new(MockServiceClientService)
+	mockServiceClientService.On("IssueToken", "svc_batch", "client-secret", "accounts:read").
+		Return(&models.ClientToken{AccessToken: "client-token", TokenType: "Bearer", ExpiresIn: 3600, Scope: "accounts:read"}, nil)
+	
+	handler := NewServiceClientHandler(mockServiceClientService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newTokenRequest(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc_batch"},
+		"client_secret": {"client-secret"},
+		"scope":         {"accounts:read"},
+	})
+	
+	// Call the handler
+	handler.Token(c)
+	
+	// Parse the response
+	var response models.ClientToken
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "client-token", response.AccessToken)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	mockServiceClientService.AssertExpectations(t)
+}
+
+func TestToken_BasicAuth(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockServiceClientService := new(MockServiceClientService)
+	mockServiceClientService.On("IssueToken", "svc_batch", "client-secret", "").
+		Return(&models.ClientToken{AccessToken: "client-token", TokenType: "Bearer"}, nil)
+	
+	handler := NewServiceClientHandler(mockServiceClientService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newTokenRequest(url.Values{"grant_type": {"client_credentials"}})
+	c.Request.SetBasicAuth("svc_batch", "client-secret")
+	
+	// Call the handler
+	handler.Token(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockServiceClientService.AssertExpectations(t)
+}
+
+func TestToken_InvalidClient(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockServiceClientService := new(MockServiceClientService)
+	mockServiceClientService.On("IssueToken", "svc_batch", "wrong-secret", "").Return(nil, services.ErrInvalidClient)
+	
+	handler := NewServiceClientHandler(mockServiceClientService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newTokenRequest(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc_batch"},
+		"client_secret": {"wrong-secret"},
+	})
+	
+	// Call the handler
+	handler.Token(c)
+	
+	// Parse the response
+	var response models.OAuthErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "invalid_client", response.Error)
+}
+
+func TestToken_UnsupportedGrantType(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockServiceClientService := new(MockServiceClientService)
+	handler := NewServiceClientHandler(mockServiceClientService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newTokenRequest(url.Values{"grant_type": {"password"}})
+	
+	// Call the handler
+	handler.Token(c)
+	
+	// Parse the response
+	var response models.OAuthErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "unsupported_grant_type", response.Error)
+	mockServiceClientService.AssertNotCalled(t, "IssueToken", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+type SessionHandler struct {
+	tokenService services.TokenService
+}
+
+func NewSessionHandler(tokenService services.TokenService) *SessionHandler {
+	return &SessionHandler{tokenService}
+}
+
+// @Summary Get my sessions
+// @Description Get the devices the current user is logged in on, most recently seen first. The session of this request is flagged as current.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SessionDTO
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	var currentSessionID string
+	if claims, ok := c.Get("claims"); ok {
+		currentSessionID = claims.(*services.AccessClaims).SessionID
+	}
+
+	sessions, err := h.tokenService.GetSessions(c.Request.Context(), userID.(uint), currentSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get sessions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// @Summary Revoke one of my sessions
+// @Description Log the current user out on one device. The session's refresh token and access tokens stop working immediately.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	err = h.tokenService.RevokeSession(c.Request.Context(), userID.(uint), uint(id), auditActor(c))
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Session not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to revoke session: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session has been revoked"})
+}
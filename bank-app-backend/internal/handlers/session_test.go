@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSessions_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("GetSessions", uint(1), "family-1").Return([]models.SessionDTO{
+		{ID: 1, UserAgent: "laptop", Current: true},
+		{ID: 2, UserAgent: "phone"},
+	}, nil)
+	
+	handler := NewSessionHandler(mockTokenService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/api/v1/users/me/sessions", nil)
+	c.Set("userID", uint(1))
+	c.Set("claims", &services.AccessClaims{UserID: 1, SessionID: "family-1"})
+	
+	// Call the handler
+	handler.GetSessions(c)
+	
+	// Parse the response
+	var response []models.SessionDTO
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, response, 2)
+	assert.True(t, response[0].Current)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestRevokeSession_Success(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("RevokeSession", uint(1), uint(2), mock.AnythingOfType("models.AuditActor")).Return(nil)
+	
+	handler := NewSessionHandler(mockTokenService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/api/v1/users/me/sessions/2", nil)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+	c.Set("userID", uint(1))
+	
+	// Call the handler
+	handler.RevokeSession(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestRevokeSession_NotFound(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("RevokeSession", uint(1), uint(9), mock.AnythingOfType("models.AuditActor")).Return(services.ErrSessionNotFound)
+	
+	handler := NewSessionHandler(mockTokenService)
+	
+	// Create a response recorder and gin context
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/api/v1/users/me/sessions/9", nil)
+	c.Params = gin.Params{{Key: "id", Value: "9"}}
+	c.Set("userID", uint(1))
+	
+	// Call the handler
+	handler.RevokeSession(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockTokenService.AssertExpectations(t)
+}
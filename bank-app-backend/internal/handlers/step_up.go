@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+// authContext returns the authentication details the auth middleware stored
+// for the request, or nil when there are none
+func authContext(c *gin.Context) *services.AuthContext {
+	value, exists := c.Get("authContext")
+	if !exists {
+		return nil
+	}
+	auth, _ := value.(*services.AuthContext)
+	return auth
+}
+
+// stepUpChallenge is the 403 body telling clients to call /auth/reauthenticate
+// and retry with the elevated token
+func stepUpChallenge() models.StepUpChallenge {
+	return models.StepUpChallenge{
+		Message:        "This operation requires recent authentication",
+		StepUpRequired: true,
+		Methods:        []string{"password", "totp"},
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -32,7 +34,7 @@ func (h *TransactionHandler) GetAllTransactions(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	transactions, err := h.transactionService.GetAllTransactions(limit, offset)
+	transactions, err := h.transactionService.GetAllTransactions(c.Request.Context(), limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get transactions: " + err.Error()})
 		return
@@ -67,7 +69,7 @@ func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.transactionService.GetTransactionByID(uint(id))
+	transaction, err := h.transactionService.GetTransactionByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Transaction not found: " + err.Error()})
 		return
@@ -100,7 +102,7 @@ func (h *TransactionHandler) GetTransactionsByAccountID(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	transactions, err := h.transactionService.GetTransactionsByAccountID(uint(accountID), limit, offset)
+	transactions, err := h.transactionService.GetTransactionsByAccountID(c.Request.Context(), uint(accountID), limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get transactions: " + err.Error()})
 		return
@@ -116,14 +118,20 @@ func (h *TransactionHandler) GetTransactionsByAccountID(c *gin.Context) {
 }
 
 // @Summary Transfer money
-// @Description Transfer money between accounts
+// @Description Transfer money between accounts. Transfers above the step-up threshold need an
+// @Description elevated token from /auth/reauthenticate. Fraud screening may hold a transfer
+// @Description for admin review (202) or decline it (403 without stepUpRequired). Users must
+// @Description pass identity verification (KYC) to transfer, within the limits of their level (403).
+// @Description Money cannot move into or out of a frozen account (403).
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param transferRequest body models.TransferRequest true "Transfer Request"
 // @Success 200 {object} map[string]string
+// @Success 202 {object} models.HeldTransferResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} models.StepUpChallenge
 // @Failure 500 {object} ErrorResponse
 // @Router /transactions/transfer [post]
 func (h *TransactionHandler) Transfer(c *gin.Context) {
@@ -133,10 +141,124 @@ func (h *TransactionHandler) Transfer(c *gin.Context) {
 		return
 	}
 
-	if err := h.transactionService.Transfer(&transferRequest); err != nil {
+	if err := h.transactionService.Transfer(c.Request.Context(), &transferRequest, authContext(c), auditActor(c)); err != nil {
+		if errors.Is(err, services.ErrStepUpRequired) {
+			c.JSON(http.StatusForbidden, stepUpChallenge())
+			return
+		}
+		var held *services.TransferHeldError
+		if errors.As(err, &held) {
+			c.JSON(http.StatusAccepted, models.HeldTransferResponse{
+				Message:  "Transfer is held for review",
+				ReviewID: held.Review.ID,
+				Status:   held.Review.Status,
+			})
+			return
+		}
+		if errors.Is(err, services.ErrTransferBlocked) || errors.Is(err, services.ErrAccountFrozen) || errors.Is(err, services.ErrKYCRequired) || errors.Is(err, services.ErrKYCLimitExceeded) {
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "Transfer failed: " + err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Transfer failed: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Transfer successful"})
 }
+
+// @Summary Get transfer reviews
+// @Description Get transfers held or blocked by fraud screening, oldest first, with their score and reasons. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Review status" Enums(PENDING_REVIEW, APPROVED, REJECTED, BLOCKED) default(PENDING_REVIEW)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.TransferReview
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/transfers/reviews [get]
+func (h *TransactionHandler) GetTransferReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewPending)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	reviews, err := h.transactionService.GetTransferReviews(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get transfer reviews: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Approve a held transfer
+// @Description Complete a transfer held by fraud screening. The from account must still have the funds, and neither account may be frozen (409). Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Review ID"
+// @Param reviewDecisionRequest body models.ReviewDecisionRequest false "Review Decision Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/transfers/reviews/{id}/approve [post]
+func (h *TransactionHandler) ApproveTransfer(c *gin.Context) {
+	h.decideTransfer(c, h.transactionService.ApproveTransfer, "Transfer approved")
+}
+
+// @Summary Reject a held transfer
+// @Description Close a transfer held by fraud screening without moving any money. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Review ID"
+// @Param reviewDecisionRequest body models.ReviewDecisionRequest false "Review Decision Request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/transfers/reviews/{id}/reject [post]
+func (h *TransactionHandler) RejectTransfer(c *gin.Context) {
+	h.decideTransfer(c, h.transactionService.RejectTransfer, "Transfer rejected")
+}
+
+// decideTransfer reads the review ID and optional note, and applies the decision
+func (h *TransactionHandler) decideTransfer(c *gin.Context, decide func(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) error, message string) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid ID format"})
+		return
+	}
+
+	var decision models.ReviewDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&decision); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	if err := decide(c.Request.Context(), uint(id), userID.(uint), decision.Note, auditActor(c)); err != nil {
+		if errors.Is(err, services.ErrReviewNotPending) || errors.Is(err, services.ErrAccountFrozen) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Failed to decide transfer: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,12 +22,12 @@ type MockTransactionService struct {
 	mock.Mock
 }
 
-func (m *MockTransactionService) CreateTransaction(transaction *models.Transaction) error {
+func (m *MockTransactionService) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
 	args := m.Called(transaction)
 	return args.Error(0)
 }
 
-func (m *MockTransactionService) GetTransactionByID(id uint) (*models.Transaction, error) {
+func (m *MockTransactionService) GetTransactionByID(ctx context.Context, id uint) (*models.Transaction, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -33,18 +35,33 @@ func (m *MockTransactionService) GetTransactionByID(id uint) (*models.Transactio
 	return args.Get(0).(*models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionService) GetTransactionsByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionService) GetTransactionsByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(accountID, limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionService) GetAllTransactions(limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionService) GetAllTransactions(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionService) Transfer(request *models.TransferRequest) error {
-	args := m.Called(request)
+func (m *MockTransactionService) Transfer(ctx context.Context, request *models.TransferRequest, auth *services.AuthContext, actor models.AuditActor) error {
+	args := m.Called(request, auth, actor)
+	return args.Error(0)
+}
+
+func (m *MockTransactionService) GetTransferReviews(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.TransferReview), args.Error(1)
+}
+
+func (m *MockTransactionService) ApproveTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) error {
+	args := m.Called(reviewID, adminID, note, actor)
+	return args.Error(0)
+}
+
+func (m *MockTransactionService) RejectTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) error {
+	args := m.Called(reviewID, adminID, note, actor)
 	return args.Error(0)
 }
 
@@ -287,7 +304,7 @@ func TestTransfer_Success(t *testing.T) {
 	// Set up expectations
 	mockTransactionService.On("Transfer", mock.MatchedBy(func(req *models.TransferRequest) bool {
 		return req.FromAccountID == 1 && req.ToAccountID == 2 && req.Amount == 50.0
-	})).Return(nil)
+	}), mock.Anything, mock.Anything).Return(nil)
 	
 	// Create transaction handler with mock service
 	transactionHandler := NewTransactionHandler(mockTransactionService)
@@ -350,7 +367,7 @@ func TestTransfer_InvalidRequest(t *testing.T) {
 	
 	// Assert expectations
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	mockTransactionService.AssertNotCalled(t, "Transfer")
+	mockTransactionService.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestTransfer_ServiceError(t *testing.T) {
@@ -371,7 +388,7 @@ func TestTransfer_ServiceError(t *testing.T) {
 	// Set up expectations for error
 	mockTransactionService.On("Transfer", mock.MatchedBy(func(req *models.TransferRequest) bool {
 		return req.FromAccountID == 1 && req.ToAccountID == 2 && req.Amount == 50.0
-	})).Return(errors.New("insufficient funds"))
+	}), mock.Anything, mock.Anything).Return(errors.New("insufficient funds"))
 	
 	// Create transaction handler with mock service
 	transactionHandler := NewTransactionHandler(mockTransactionService)
@@ -402,3 +419,155 @@ func TestTransfer_ServiceError(t *testing.T) {
 	assert.Equal(t, "Transfer failed: insufficient funds", response.Message)
 	mockTransactionService.AssertExpectations(t)
 }
+
+func TestTransfer_StepUpRequired(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockTransactionService := new(MockTransactionService)
+	
+	// Create transfer request above the step-up threshold
+	transferRequest := models.TransferRequest{
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        5000.0,
+	}
+	auth := &services.AuthContext{UserID: 1, AuthTime: time.Now(), AuthLevel: services.AuthLevelBasic}
+	
+	// Set up expectations - the handler passes the caller's auth context through
+	mockTransactionService.On("Transfer", mock.AnythingOfType("*models.TransferRequest"), auth, mock.AnythingOfType("models.AuditActor")).Return(services.ErrStepUpRequired)
+	
+	// Create transaction handler with mock service
+	transactionHandler := NewTransactionHandler(mockTransactionService)
+	
+	// Create a request
+	jsonValue, _ := json.Marshal(transferRequest)
+	req, _ := http.NewRequest("POST", "/api/v1/transactions/transfer", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("authContext", auth)
+	
+	// Call the handler
+	transactionHandler.Transfer(c)
+	
+	// Parse the response
+	var response models.StepUpChallenge
+	json.Unmarshal(w.Body.Bytes(), &response)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, response.StepUpRequired)
+	mockTransactionService.AssertExpectations(t)
+}
+
+func TestTransfer_HeldAndBlocked(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	tests := []struct {
+		name         string
+		serviceError error
+		expectedCode int
+	}{
+		{"held for review", &services.TransferHeldError{Review: &models.TransferReview{ID: 9, Status: models.ReviewPending}}, http.StatusAccepted},
+		{"blocked", services.ErrTransferBlocked, http.StatusForbidden},
+		{"identity not verified", services.ErrKYCRequired, http.StatusForbidden},
+		{"over the KYC limit", services.ErrKYCLimitExceeded, http.StatusForbidden},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockTransactionService := new(MockTransactionService)
+			mockTransactionService.On("Transfer", mock.AnythingOfType("*models.TransferRequest"), mock.Anything, mock.Anything).Return(tt.serviceError)
+			transactionHandler := NewTransactionHandler(mockTransactionService)
+			
+			jsonValue, _ := json.Marshal(models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 6000.0})
+			req, _ := http.NewRequest("POST", "/api/v1/transactions/transfer", bytes.NewBuffer(jsonValue))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			
+			// Act
+			transactionHandler.Transfer(c)
+			
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusAccepted {
+				var response models.HeldTransferResponse
+				json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, uint(9), response.ReviewID)
+				assert.Equal(t, models.ReviewPending, response.Status)
+			}
+		})
+	}
+}
+
+func TestApproveTransfer(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	tests := []struct {
+		name         string
+		body         string
+		serviceError error
+		expectedNote string
+		expectedCode int
+	}{
+		{"with a note", `{"note":"customer confirmed"}`, nil, "customer confirmed", http.StatusOK},
+		{"without a body", "", nil, "", http.StatusOK},
+		{"already resolved", "", services.ErrReviewNotPending, "", http.StatusConflict},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockTransactionService := new(MockTransactionService)
+			mockTransactionService.On("ApproveTransfer", uint(9), uint(5), tt.expectedNote, mock.AnythingOfType("models.AuditActor")).Return(tt.serviceError)
+			transactionHandler := NewTransactionHandler(mockTransactionService)
+			
+			req, _ := http.NewRequest("POST", "/api/v1/admin/transfers/reviews/9/approve", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = req
+			c.Params = []gin.Param{{Key: "id", Value: "9"}}
+			c.Set("userID", uint(5))
+			
+			// Act
+			transactionHandler.ApproveTransfer(c)
+			
+			// Assert
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockTransactionService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRejectTransfer_InvalidID(t *testing.T) {
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+	
+	// Create mock service
+	mockTransactionService := new(MockTransactionService)
+	transactionHandler := NewTransactionHandler(mockTransactionService)
+	
+	// Create a request with an invalid ID
+	req, _ := http.NewRequest("POST", "/api/v1/admin/transfers/reviews/abc/reject", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = []gin.Param{{Key: "id", Value: "abc"}}
+	c.Set("userID", uint(5))
+	
+	// Call the handler
+	transactionHandler.RejectTransfer(c)
+	
+	// Assert expectations
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockTransactionService.AssertNotCalled(t, "RejectTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,18 @@
+package health
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the database
+func Database(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
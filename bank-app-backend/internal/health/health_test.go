@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("Passing checks are ready", func(t *testing.T) {
+		// Arrange
+		checker := New(time.Second)
+		checker.Add("database", func(ctx context.Context) error { return nil })
+
+		// Act
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.True(t, report.Ready())
+		assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	})
+
+	t.Run("A failing check makes the server unavailable", func(t *testing.T) {
+		// Arrange
+		checker := New(time.Second)
+		checker.Add("database", func(ctx context.Context) error { return nil })
+		checker.Add("migrations", func(ctx context.Context) error { return errors.New("table for *models.User is missing") })
+
+		// Act
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.False(t, report.Ready())
+		assert.Equal(t, StatusUnavailable, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["database"].Status)
+		assert.Equal(t, StatusFailed, report.Checks["migrations"].Status)
+		assert.Equal(t, "table for *models.User is missing", report.Checks["migrations"].Error)
+	})
+
+	t.Run("A check that hangs times out", func(t *testing.T) {
+		// Arrange
+		checker := New(20 * time.Millisecond)
+		block := make(chan struct{})
+		defer close(block)
+		checker.Add("database", func(ctx context.Context) error {
+			<-block // ignores its context
+			return nil
+		})
+
+		// Act
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.False(t, report.Ready())
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+	})
+
+	t.Run("A draining server is unavailable without running checks", func(t *testing.T) {
+		// Arrange
+		checker := New(time.Second)
+		ran := false
+		checker.Add("database", func(ctx context.Context) error { ran = true; return nil })
+
+		// Act
+		checker.Drain()
+		report := checker.Run(context.Background())
+
+		// Assert
+		assert.False(t, report.Ready())
+		assert.True(t, report.Draining)
+		assert.False(t, ran)
+	})
+}
+
+func TestHeartbeat(t *testing.T) {
+	// Arrange
+	heartbeat := NewHeartbeat(time.Minute)
+
+	// Act
+	fresh := heartbeat.Check(context.Background())
+	heartbeat.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	stale := heartbeat.Check(context.Background())
+
+	// Assert
+	assert.NoError(t, fresh)
+	assert.Error(t, stale)
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// stringLiteral matches the quoted values GORM inlines into logged SQL
+var stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// GormLogger writes GORM's logs through the logger of each query's context,
+// so a query run for a request carries the request's ID and user. Failed
+// queries are logged as errors, slow ones as warnings and the rest at debug.
+type GormLogger struct {
+	SlowThreshold time.Duration // 0 never reports a query as slow
+	silent        bool
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold}
+}
+
+// LogMode only honours the silent level, which GORM uses to hide its own
+// queries; the level of the slog handler decides everything else
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	mode := *l
+	mode.silent = level == gormlogger.Silent
+	return &mode
+}
+
+func (l *GormLogger) Info(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelInfo, message, data...)
+}
+
+func (l *GormLogger) Warn(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelWarn, message, data...)
+}
+
+func (l *GormLogger) Error(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelError, message, data...)
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, message string, data ...interface{}) {
+	if l.silent {
+		return
+	}
+	FromContext(ctx).Log(ctx, level, fmt.Sprintf(message, data...))
+}
+
+// Trace logs a query once it has run
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+
+	level, message := slog.LevelDebug, "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, message = slog.LevelError, "query failed"
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level, message = slog.LevelWarn, "slow query"
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", RedactSQL(sql)),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, message, attrs...)
+}
+
+// RedactSQL leaves string values out of SQL, as they may hold password
+// hashes, tokens or personal data
+func RedactSQL(sql string) string {
+	return stringLiteral.ReplaceAllString(sql, "'"+Redacted+"'")
+}
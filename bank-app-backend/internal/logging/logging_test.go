@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// lines decodes the JSON lines a logger wrote
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var decoded []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		decoded = append(decoded, fields)
+	}
+	return decoded
+}
+
+func TestNew_LevelAndFormat(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn")
+	require.NoError(t, err)
+
+	// Act
+	logger.Info("hidden")
+	logger.Warn("shown", "request_id", "req-1")
+
+	// Assert
+	logged := lines(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "shown", logged[0]["msg"])
+	assert.Equal(t, "WARN", logged[0]["level"])
+	assert.Equal(t, "req-1", logged[0]["request_id"])
+}
+
+func TestNew_InvalidLevel(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "loud")
+	assert.Error(t, err)
+}
+
+func TestRedaction(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug")
+	require.NoError(t, err)
+
+	// Act
+	logger.Info("login",
+		"password", "hunter2",
+		"newPassword", "hunter3",
+		"refresh_token", "abc",
+		"Authorization", "Bearer xyz",
+		"X-API-Key", "key",
+		"user_id", 7,
+	)
+	logger.WithGroup("request").Info("grouped", "client_secret", "s3cret")
+
+	// Assert
+	logged := lines(t, &buf)
+	require.Len(t, logged, 2)
+	for _, key := range []string{"password", "newPassword", "refresh_token", "Authorization", "X-API-Key"} {
+		assert.Equal(t, Redacted, logged[0][key], key)
+	}
+	assert.Equal(t, float64(7), logged[0]["user_id"])
+	assert.Equal(t, Redacted, logged[1]["request"].(map[string]interface{})["client_secret"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestFromContext(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+	ctx := NewContext(context.Background(), logger.With("request_id", "req-1", "user_id", 7))
+
+	// Act
+	FromContext(ctx).Info("from a handler")
+
+	// Assert
+	logged := lines(t, &buf)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "req-1", logged[0]["request_id"])
+	assert.Equal(t, float64(7), logged[0]["user_id"])
+	assert.NotNil(t, FromContext(context.Background()), "outside a request the default logger is used")
+}
+
+func TestRedactSQL(t *testing.T) {
+	sql := `UPDATE "users" SET "password_hash"='$2a$10$abc',"name"='O''Brien' WHERE "id" = 7`
+
+	assert.Equal(t, `UPDATE "users" SET "password_hash"='[REDACTED]',"name"='[REDACTED]' WHERE "id" = 7`, RedactSQL(sql))
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		err     error
+		level   string
+		message string
+	}{
+		{"failed query", 0, errors.New("connection refused"), "ERROR", "query failed"},
+		{"slow query", time.Second, nil, "WARN", "slow query"},
+		{"fast query", 0, nil, "DEBUG", "query"},
+		{"record not found is not an error", 0, gorm.ErrRecordNotFound, "DEBUG", "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var buf bytes.Buffer
+			logger, err := New(&buf, "debug")
+			require.NoError(t, err)
+			ctx := NewContext(context.Background(), logger.With("request_id", "req-1"))
+			gormLogger := NewGormLogger(100 * time.Millisecond)
+
+			// Act
+			gormLogger.Trace(ctx, time.Now().Add(-tt.elapsed), func() (string, int64) {
+				return `SELECT * FROM "users" WHERE email_index = 'abc'`, 1
+			}, tt.err)
+
+			// Assert
+			logged := lines(t, &buf)
+			require.Len(t, logged, 1)
+			assert.Equal(t, tt.level, logged[0]["level"])
+			assert.Equal(t, tt.message, logged[0]["msg"])
+			assert.Equal(t, "req-1", logged[0]["request_id"])
+			assert.NotContains(t, logged[0]["sql"], "abc")
+		})
+	}
+}
+
+func TestGormLogger_SkipsBelowLevel(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+	ctx := NewContext(context.Background(), logger)
+	called := false
+
+	// Act
+	NewGormLogger(0).Trace(ctx, time.Now(), func() (string, int64) {
+		called = true
+		return "SELECT 1", 1
+	}, nil)
+
+	// Assert
+	assert.False(t, called, "the SQL is not built for a line that is not logged")
+	assert.Empty(t, buf.String())
+}
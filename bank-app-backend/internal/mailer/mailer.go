@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message - An outgoing plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional email such as verification and reset links
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FileMailer writes every message as an .eml file into a directory, for local
+// development without a mail server
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir, from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o600)
+}
+
+// SMTPMailer delivers messages over SMTP without authentication, which is what
+// development sinks such as MailHog expect
+type SMTPMailer struct {
+	addr string
+	from string
+}
+
+func NewSMTPMailer(addr, from string) *SMTPMailer {
+	return &SMTPMailer{addr, from}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.addr, nil, m.from, []string{msg.To}, formatMessage(m.from, msg))
+}
+
+// New returns the mailer selected by kind ("file" or "smtp")
+func New(kind, dir, smtpAddr, from string) (Mailer, error) {
+	switch kind {
+	case "file":
+		return NewFileMailer(dir, from), nil
+	case "smtp":
+		return NewSMTPMailer(smtpAddr, from), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", kind)
+	}
+}
+
+func formatMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer_WritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFileMailer(dir, "no-reply@drank.local")
+
+	err := m.Send(Message{To: "test@example.com", Subject: "Hello", Body: "line one\nline two"})
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Len(t, files, 1)
+
+	content, _ := os.ReadFile(files[0])
+	assert.True(t, strings.HasPrefix(string(content), "From: no-reply@drank.local\r\n"))
+	assert.Contains(t, string(content), "To: test@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.Contains(t, string(content), "line one\r\nline two")
+}
+
+func TestNew_UnknownKind(t *testing.T) {
+	_, err := New("carrier-pigeon", "", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPlugin(t *testing.T) {
+	// Arrange - a dry run builds the SQL without a database
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+
+	// Act
+	db.First(&widget{}, 1)
+	db.Create(&widget{Name: "gear"})
+	db.Exec("SELECT 1")
+
+	// Assert
+	assert.Equal(t, uint64(1), sampleCount(t, DBQueryDuration.WithLabelValues("query", "widgets")))
+	assert.Equal(t, uint64(1), sampleCount(t, DBQueryDuration.WithLabelValues("create", "widgets")))
+	assert.Equal(t, uint64(1), sampleCount(t, DBQueryDuration.WithLabelValues("raw", "unknown")))
+}
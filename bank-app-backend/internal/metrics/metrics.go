@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Transfer outcomes
+const (
+	TransferCompleted = "completed"
+	TransferHeld      = "held"
+	TransferFailed    = "failed"
+)
+
+// Login results
+const (
+	LoginSuccess   = "success"
+	LoginFailure   = "failure"
+	LoginThrottled = "throttled"
+)
+
+// Registry holds every metric served at /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts requests by route template, so paths holding IDs
+	// do not each get their own series
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	Transfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bank_transfers_total",
+		Help: "Transfers by outcome: completed, held for review or failed.",
+	}, []string{"outcome"})
+
+	TransferAmount = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "bank_transfer_amount",
+		Help:    "Amounts of completed transfers.",
+		Buckets: []float64{10, 50, 100, 500, 1000, 5000, 10000, 50000},
+	})
+
+	TransferFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bank_transfer_failures_total",
+		Help: "Failed transfers by reason.",
+	}, []string{"reason"})
+
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bank_logins_total",
+		Help: "Login attempts by result: success, failure or throttled.",
+	}, []string{"result"})
+
+	// RateLimited counts requests refused with 429 by the rate limit group
+	// they exceeded
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Requests refused by the rate limiter, by route group.",
+	}, []string{"group"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		Transfers,
+		TransferAmount,
+		TransferFailures,
+		Logins,
+		RateLimited,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveTransfer records a transfer that completed or was held. A held
+// transfer that is later approved is recorded again as completed.
+func ObserveTransfer(outcome string, amount float64) {
+	Transfers.WithLabelValues(outcome).Inc()
+	if outcome == TransferCompleted {
+		TransferAmount.Observe(amount)
+	}
+}
+
+// ObserveTransferFailure records a transfer refused for the reason
+func ObserveTransferFailure(reason string) {
+	Transfers.WithLabelValues(TransferFailed).Inc()
+	TransferFailures.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleCount returns how many values a histogram has observed
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestObserveTransfer(t *testing.T) {
+	// Arrange
+	completed := testutil.ToFloat64(Transfers.WithLabelValues(TransferCompleted))
+	held := testutil.ToFloat64(Transfers.WithLabelValues(TransferHeld))
+	amounts := sampleCount(t, TransferAmount)
+
+	// Act
+	ObserveTransfer(TransferCompleted, 250)
+	ObserveTransfer(TransferHeld, 9000)
+
+	// Assert
+	assert.Equal(t, completed+1, testutil.ToFloat64(Transfers.WithLabelValues(TransferCompleted)))
+	assert.Equal(t, held+1, testutil.ToFloat64(Transfers.WithLabelValues(TransferHeld)))
+	assert.Equal(t, amounts+1, sampleCount(t, TransferAmount), "only completed transfers are amounts moved")
+}
+
+func TestObserveTransferFailure(t *testing.T) {
+	// Arrange
+	failed := testutil.ToFloat64(Transfers.WithLabelValues(TransferFailed))
+	reason := testutil.ToFloat64(TransferFailures.WithLabelValues("insufficient_funds"))
+
+	// Act
+	ObserveTransferFailure("insufficient_funds")
+
+	// Assert
+	assert.Equal(t, failed+1, testutil.ToFloat64(Transfers.WithLabelValues(TransferFailed)))
+	assert.Equal(t, reason+1, testutil.ToFloat64(TransferFailures.WithLabelValues("insufficient_funds")))
+}
+
+func TestHandler(t *testing.T) {
+	// Arrange
+	Logins.WithLabelValues(LoginSuccess).Inc()
+	w := httptest.NewRecorder()
+
+	// Act
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `bank_logins_total{result="success"}`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
@@ -1,24 +1,44 @@
 package middleware
 
 import (
-	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+)
+
+// Ways a service client can authenticate, as recorded in its usage
+const (
+	clientAuthAPIKey = "api_key"
+	clientAuthToken  = "token"
 )
 
 type AuthMiddleware struct {
-	jwtSecret string
+	tokenService         services.TokenService
+	serviceClientService services.ServiceClientService
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{jwtSecret}
+func NewAuthMiddleware(tokenService services.TokenService, serviceClientService services.ServiceClientService) *AuthMiddleware {
+	return &AuthMiddleware{tokenService, serviceClientService}
 }
 
+// Authenticate accepts a user or service client access token in the
+// Authorization header, or a service client API key in the X-API-Key header
 func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			client, err := m.serviceClientService.AuthenticateAPIKey(c.Request.Context(), apiKey)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid API key"})
+				c.Abort()
+				return
+			}
+			m.serveClient(c, client, clientAuthAPIKey, client.ScopeList())
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authorization header is required"})
@@ -33,37 +53,88 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := m.validateToken(tokenString)
+		claims, err := m.tokenService.ValidateAccessToken(c.Request.Context(), tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		if claims.ClientID != "" {
+			client, err := m.serviceClientService.GetActiveClient(c.Request.Context(), claims.ClientID)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+				c.Abort()
+				return
+			}
+			c.Set("claims", claims)
+			m.serveClient(c, client, clientAuthToken, strings.Fields(claims.Scope))
+			return
+		}
+
+		if claims.SessionID != "" {
+			// Last-seen times are informational, so a failed update does not fail the request
+			_ = m.tokenService.TouchSession(c.Request.Context(), claims.SessionID)
+		}
+
 		// Set user ID in request context
-		c.Set("userID", uint(claims["id"].(float64)))
-		c.Set("userEmail", claims["email"].(string))
+		c.Set("userID", claims.UserID)
+		c.Set("userEmail", claims.Email)
+		c.Set("claims", claims)
+		c.Set("authContext", claims.AuthContext()) // Auth time and level for step-up checks
+		withLogAttrs(c, "user_id", claims.UserID)
 
 		c.Next()
 	}
 }
 
-func (m *AuthMiddleware) validateToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(m.jwtSecret), nil
+// serveClient runs the rest of the chain for a service client and records the
+// request in the client's usage. Clients have no user ID or auth context, so
+// user-only handlers reject them.
+func (m *AuthMiddleware) serveClient(c *gin.Context, client *models.ServiceClient, authMethod string, scopes []string) {
+	c.Set("serviceClient", client)
+	c.Set("scopes", scopes)
+	withLogAttrs(c, "client_id", client.ClientID)
+
+	c.Next()
+
+	// The request has been served either way, so a failure here is ignored
+	_ = m.serviceClientService.RecordUsage(c.Request.Context(), &models.ServiceClientUsage{
+		ServiceClientID: client.ID,
+		AuthMethod:      authMethod,
+		Method:          c.Request.Method,
+		Path:            c.FullPath(),
+		StatusCode:      c.Writer.Status(),
+		IPAddress:       c.ClientIP(),
 	})
+}
 
-	if err != nil {
-		return nil, err
-	}
+// RequireScope rejects service clients that were not granted the scope. User
+// tokens carry no scopes and pass. It must run after Authenticate.
+func (m *AuthMiddleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isClient := c.Get("scopes")
+		if isClient && !models.HasScope(scopes.([]string), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient scope: " + scope + " is required"})
+			c.Abort()
+			return
+		}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+		c.Next()
 	}
+}
 
-	return nil, errors.New("invalid token")
+// RequireRole only lets through users whose access token carries the given role.
+// It must run after Authenticate.
+func (m *AuthMiddleware) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists || claims.(*services.AccessClaims).Role != role {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
 }
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// scrape requests GET /metrics behind MetricsAuth, with the Authorization
+// header when it is not empty
+func scrape(token, authorization string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", MetricsAuth(token), func(c *gin.Context) {
+		c.String(http.StatusOK, "http_requests_total 1")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMetricsAuth(t *testing.T) {
+	t.Run("Serves scrapers that present the token", func(t *testing.T) {
+		// Act
+		w := scrape("metrics-token", "Bearer metrics-token")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "http_requests_total 1", w.Body.String())
+	})
+
+	t.Run("Refuses requests without the token", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer wrong-token", "metrics-token", "Bearer metrics-token-and-more"} {
+			// Act
+			w := scrape("metrics-token", authorization)
+
+			// Assert
+			assert.Equal(t, http.StatusUnauthorized, w.Code, authorization)
+			assert.Equal(t, `Bearer realm="metrics"`, w.Header().Get("WWW-Authenticate"))
+			assert.NotContains(t, w.Body.String(), "http_requests_total")
+		}
+	})
+
+	t.Run("Is open when no token is configured", func(t *testing.T) {
+		// Act
+		w := scrape("", "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
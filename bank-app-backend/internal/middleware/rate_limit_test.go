@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a rate limit store that cannot be reached
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+// rateLimitedRouter serves GET /ping behind the limiter, as the user in the
+// X-User header when there is one. Like the server by default, it trusts no
+// proxy's X-Forwarded-For.
+func rateLimitedRouter(store ratelimit.Store, limit ratelimit.Limit, failOpen bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.SetTrustedProxies(nil)
+	authenticate := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("userID", user)
+		}
+	}
+	router.GET("/ping", authenticate, NewRateLimiter(store, failOpen).Limit("api", limit), func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return router
+}
+
+func ping(router *gin.Engine, user, ip string) *httptest.ResponseRecorder {
+	return pingForwarded(router, user, ip, "")
+}
+
+// pingForwarded pings as ip, a proxy when forwardedFor is set
+func pingForwarded(router *gin.Engine, user, ip, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = ip + ":12345"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	t.Run("Sets the RateLimit headers and refuses callers over the limit", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Per: time.Minute}, false)
+
+		// Act
+		first := ping(router, "", "10.0.0.1")
+		ping(router, "", "10.0.0.1")
+		refused := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", first.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, first.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusTooManyRequests, refused.Code)
+		assert.Equal(t, "0", refused.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", refused.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"message": "Too many requests, please try again later"}`, refused.Body.String())
+	})
+
+	t.Run("Limits each user and each IP address on its own", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act
+		ipFirst := ping(router, "", "10.0.0.1")
+		ipAgain := ping(router, "", "10.0.0.1")
+		otherIP := ping(router, "", "10.0.0.2")
+		user := ping(router, "7", "10.0.0.1")
+		otherUser := ping(router, "8", "10.0.0.1")
+		userAgain := ping(router, "7", "10.0.0.3")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, ipFirst.Code)
+		assert.Equal(t, http.StatusTooManyRequests, ipAgain.Code)
+		assert.Equal(t, http.StatusOK, otherIP.Code)
+		assert.Equal(t, http.StatusOK, user.Code, "an authenticated user is not limited by their IP address")
+		assert.Equal(t, http.StatusOK, otherUser.Code)
+		assert.Equal(t, http.StatusTooManyRequests, userAgain.Code, "nor freed by changing it")
+	})
+
+	t.Run("Lets everything through when off", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(failingStore{}, ratelimit.Limit{}, false)
+
+		// Act
+		rec := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Refuses requests when the store fails", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(failingStore{}, ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act
+		rec := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Lets requests through when the store fails and it fails open", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(failingStore{}, ratelimit.Limit{Requests: 1, Per: time.Minute}, true)
+
+		// Act
+		rec := ping(router, "", "10.0.0.1")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Ignores X-Forwarded-For from an untrusted proxy", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+
+		// Act - a caller claims a new address for every request
+		first := pingForwarded(router, "", "10.0.0.1", "203.0.113.1")
+		spoofed := pingForwarded(router, "", "10.0.0.1", "203.0.113.2")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusTooManyRequests, spoofed.Code)
+	})
+
+	t.Run("Limits the forwarded address behind a trusted proxy", func(t *testing.T) {
+		// Arrange
+		router := rateLimitedRouter(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 1, Per: time.Minute}, false)
+		assert.NoError(t, router.SetTrustedProxies([]string{"10.0.0.1"}))
+
+		// Act
+		first := pingForwarded(router, "", "10.0.0.1", "203.0.113.1")
+		again := pingForwarded(router, "", "10.0.0.1", "203.0.113.1")
+		other := pingForwarded(router, "", "10.0.0.1", "203.0.113.2")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusTooManyRequests, again.Code)
+		assert.Equal(t, http.StatusOK, other.Code)
+	})
+}
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS "kyc_documents";
+DROP TABLE IF EXISTS "kyc_profiles";
+DROP TABLE IF EXISTS "screening_hits";
+DROP TABLE IF EXISTS "transfer_reviews";
+DROP TABLE IF EXISTS "audit_entries";
+DROP TABLE IF EXISTS "sessions";
+DROP TABLE IF EXISTS "service_client_usages";
+DROP TABLE IF EXISTS "service_clients";
+DROP TABLE IF EXISTS "login_attempts";
+DROP TABLE IF EXISTS "recovery_codes";
+DROP TABLE IF EXISTS "email_tokens";
+DROP TABLE IF EXISTS "revoked_tokens";
+DROP TABLE IF EXISTS "refresh_tokens";
+DROP TABLE IF EXISTS "transactions";
+DROP TABLE IF EXISTS "accounts";
+DROP TABLE IF EXISTS "users";
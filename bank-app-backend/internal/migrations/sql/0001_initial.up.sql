@@ -0,0 +1,291 @@
+-- Schema as created by GORM AutoMigrate before versioned migrations, so
+-- databases it created adopt this migration without changes. Databases of
+-- the first release only have users, accounts and transactions, with fewer
+-- columns on users; the columns added since are added to them below.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "email" text NOT NULL,
+    "email_index" text,
+    "password" text NOT NULL,
+    "first_name" text NOT NULL,
+    "last_name" text NOT NULL,
+    "role" text NOT NULL DEFAULT 'customer',
+    "email_verified_at" timestamptz,
+    "two_factor_secret" text,
+    "two_factor_enabled_at" timestamptz,
+    "two_factor_last_step" bigint NOT NULL DEFAULT 0,
+    "failed_login_count" bigint NOT NULL DEFAULT 0,
+    "last_failed_login_at" timestamptz,
+    "locked_until" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+ALTER TABLE "users"
+    ADD COLUMN IF NOT EXISTS "email_index" text,
+    ADD COLUMN IF NOT EXISTS "role" text NOT NULL DEFAULT 'customer',
+    ADD COLUMN IF NOT EXISTS "email_verified_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "two_factor_secret" text,
+    ADD COLUMN IF NOT EXISTS "two_factor_enabled_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "two_factor_last_step" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "failed_login_count" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "last_failed_login_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "locked_until" timestamptz;
+-- The first release kept emails in plaintext, unique by themselves; they are
+-- unique by their blind index now, which pii reencrypt fills in
+DROP INDEX IF EXISTS "idx_users_email";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email_index" ON "users" ("email_index");
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "accounts" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "account_number" text NOT NULL,
+    "account_type" text NOT NULL,
+    "balance" decimal NOT NULL DEFAULT 0.000000,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_accounts" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_accounts_deleted_at" ON "accounts" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_accounts_account_number" ON "accounts" ("account_number");
+
+CREATE TABLE IF NOT EXISTS "transactions" (
+    "id" bigserial,
+    "account_id" bigint NOT NULL,
+    "source_account_id" bigint,
+    "target_account_id" bigint,
+    "amount" decimal NOT NULL,
+    "balance" decimal NOT NULL,
+    "type" text NOT NULL,
+    "description" text,
+    "transaction_date" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_accounts_transactions" FOREIGN KEY ("account_id") REFERENCES "accounts"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transactions_deleted_at" ON "transactions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "family_id" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "auth_time" timestamptz,
+    "used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+
+CREATE TABLE IF NOT EXISTS "revoked_tokens" (
+    "jti" text,
+    "user_id" bigint NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("jti")
+);
+CREATE INDEX IF NOT EXISTS "idx_revoked_tokens_expires_at" ON "revoked_tokens" ("expires_at");
+
+CREATE TABLE IF NOT EXISTS "email_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "purpose" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_email_tokens_token_hash" ON "email_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_email_tokens_user_id" ON "email_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "recovery_codes" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "code_hash" text NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_recovery_codes_code_hash" ON "recovery_codes" ("code_hash");
+CREATE INDEX IF NOT EXISTS "idx_recovery_codes_user_id" ON "recovery_codes" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "login_attempts" (
+    "id" bigserial,
+    "user_id" bigint,
+    "email" text NOT NULL,
+    "ip_address" text NOT NULL,
+    "user_agent" text,
+    "success" boolean NOT NULL,
+    "reason" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_created_at" ON "login_attempts" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_ip_address" ON "login_attempts" ("ip_address");
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_email" ON "login_attempts" ("email");
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_user_id" ON "login_attempts" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "service_clients" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "client_id" text NOT NULL,
+    "secret_hash" text NOT NULL,
+    "scopes" text NOT NULL,
+    "created_by_id" bigint,
+    "last_used_at" timestamptz,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_service_clients_client_id" ON "service_clients" ("client_id");
+
+CREATE TABLE IF NOT EXISTS "service_client_usages" (
+    "id" bigserial,
+    "service_client_id" bigint NOT NULL,
+    "auth_method" text NOT NULL,
+    "method" text NOT NULL,
+    "path" text NOT NULL,
+    "status_code" bigint,
+    "ip_address" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_service_client_usages_created_at" ON "service_client_usages" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_service_client_usages_service_client_id" ON "service_client_usages" ("service_client_id");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "family_id" text NOT NULL,
+    "device_hash" text NOT NULL,
+    "user_agent" text,
+    "ip_address" text,
+    "last_seen_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sessions_family_id" ON "sessions" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_sessions_device_hash" ON "sessions" ("device_hash");
+
+CREATE TABLE IF NOT EXISTS "audit_entries" (
+    "id" bigserial,
+    "actor_type" text NOT NULL,
+    "actor_id" text,
+    "action" text NOT NULL,
+    "target_type" text,
+    "target_id" text,
+    "before" text,
+    "after" text,
+    "ip_address" text,
+    "request_id" text,
+    "prev_hash" text,
+    "hash" text NOT NULL,
+    "created_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_created_at" ON "audit_entries" ("created_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_entries_hash" ON "audit_entries" ("hash");
+CREATE INDEX IF NOT EXISTS "idx_audit_target" ON "audit_entries" ("target_type","target_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_entries_action" ON "audit_entries" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_actor" ON "audit_entries" ("actor_type","actor_id");
+
+CREATE TABLE IF NOT EXISTS "transfer_reviews" (
+    "id" bigserial,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" decimal NOT NULL,
+    "description" text,
+    "status" text NOT NULL,
+    "score" bigint NOT NULL,
+    "reasons" text,
+    "requested_by" text,
+    "reviewed_by_id" bigint,
+    "review_note" text,
+    "reviewed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transfer_reviews_status" ON "transfer_reviews" ("status");
+CREATE INDEX IF NOT EXISTS "idx_transfer_reviews_from_account_id" ON "transfer_reviews" ("from_account_id");
+
+CREATE TABLE IF NOT EXISTS "screening_hits" (
+    "id" bigserial,
+    "subject_id" bigint,
+    "name" text NOT NULL,
+    "name_index" text NOT NULL,
+    "context" text NOT NULL,
+    "entry_id" text NOT NULL,
+    "entry_name" text,
+    "matched_name" text,
+    "program" text,
+    "score" decimal,
+    "status" text NOT NULL,
+    "cleared_by_id" bigint,
+    "justification" text,
+    "cleared_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_screening_hits_status" ON "screening_hits" ("status");
+CREATE INDEX IF NOT EXISTS "idx_screening_hits_entry_id" ON "screening_hits" ("entry_id");
+CREATE INDEX IF NOT EXISTS "idx_screening_hits_name_index" ON "screening_hits" ("name_index");
+CREATE INDEX IF NOT EXISTS "idx_screening_hits_subject_id" ON "screening_hits" ("subject_id");
+
+CREATE TABLE IF NOT EXISTS "kyc_profiles" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "date_of_birth" text,
+    "address_line1" text,
+    "address_line2" text,
+    "city" text,
+    "postal_code" text,
+    "country" text,
+    "id_type" text,
+    "id_number" text,
+    "status" text NOT NULL DEFAULT 'DRAFT',
+    "level" bigint NOT NULL DEFAULT 0,
+    "review_note" text,
+    "reviewed_by_id" bigint,
+    "submitted_at" timestamptz,
+    "reviewed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_kyc_profiles_user_id" ON "kyc_profiles" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_kyc_profiles_status" ON "kyc_profiles" ("status");
+
+CREATE TABLE IF NOT EXISTS "kyc_documents" (
+    "id" bigserial,
+    "profile_id" bigint NOT NULL,
+    "kind" text NOT NULL,
+    "file_name" text,
+    "content_type" text,
+    "size" bigint,
+    "sha256" text,
+    "storage_key" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_kyc_profiles_documents" FOREIGN KEY ("profile_id") REFERENCES "kyc_profiles"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_kyc_documents_profile_id" ON "kyc_documents" ("profile_id");
@@ -0,0 +1,2 @@
+ALTER TABLE "accounts" DROP COLUMN "frozen_at";
+ALTER TABLE "users" DROP COLUMN "disabled_at";
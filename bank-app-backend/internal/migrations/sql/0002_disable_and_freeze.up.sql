@@ -0,0 +1,2 @@
+ALTER TABLE "users" ADD COLUMN "disabled_at" timestamptz;
+ALTER TABLE "accounts" ADD COLUMN "frozen_at" timestamptz;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_login_attempts_email_index";
+ALTER TABLE "login_attempts" DROP COLUMN "email_index";
+ALTER TABLE "login_attempts" ADD COLUMN "email" text NOT NULL DEFAULT '';
+ALTER TABLE "login_attempts" ALTER COLUMN "email" DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_email" ON "login_attempts" ("email");
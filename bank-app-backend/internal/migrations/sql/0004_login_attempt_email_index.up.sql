@@ -0,0 +1,6 @@
+ALTER TABLE "login_attempts" ADD COLUMN "email_index" text NOT NULL DEFAULT '';
+ALTER TABLE "login_attempts" ALTER COLUMN "email_index" DROP DEFAULT;
+UPDATE "login_attempts" SET "email_index" = "users"."email_index" FROM "users" WHERE "users"."id" = "login_attempts"."user_id" AND "users"."email_index" IS NOT NULL;
+DROP INDEX IF EXISTS "idx_login_attempts_email";
+ALTER TABLE "login_attempts" DROP COLUMN "email";
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_email_index" ON "login_attempts" ("email_index");
@@ -0,0 +1,9 @@
+ALTER TABLE "screening_hits" DROP CONSTRAINT IF EXISTS "chk_screening_hits_status";
+ALTER TABLE "kyc_profiles" DROP CONSTRAINT IF EXISTS "chk_kyc_profiles_status";
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "chk_users_role";
+ALTER TABLE "transfer_reviews" DROP CONSTRAINT IF EXISTS "chk_transfer_reviews_status";
+ALTER TABLE "transfer_reviews" DROP CONSTRAINT IF EXISTS "chk_transfer_reviews_amount";
+ALTER TABLE "transactions" DROP CONSTRAINT IF EXISTS "chk_transactions_type";
+ALTER TABLE "transactions" DROP CONSTRAINT IF EXISTS "chk_transactions_amount";
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "chk_accounts_account_type";
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "chk_accounts_balance";
@@ -0,0 +1,17 @@
+-- Enforce in the database what the services already check. No account type
+-- allows an overdraft, so every balance must stay at or above zero.
+
+ALTER TABLE "accounts" ADD CONSTRAINT "chk_accounts_balance" CHECK ("balance" >= 0);
+ALTER TABLE "accounts" ADD CONSTRAINT "chk_accounts_account_type" CHECK ("account_type" IN ('CHECKING', 'SAVINGS'));
+
+ALTER TABLE "transactions" ADD CONSTRAINT "chk_transactions_amount" CHECK ("amount" > 0);
+ALTER TABLE "transactions" ADD CONSTRAINT "chk_transactions_type" CHECK ("type" IN ('DEPOSIT', 'WITHDRAWAL', 'TRANSFER'));
+
+ALTER TABLE "transfer_reviews" ADD CONSTRAINT "chk_transfer_reviews_amount" CHECK ("amount" > 0);
+ALTER TABLE "transfer_reviews" ADD CONSTRAINT "chk_transfer_reviews_status" CHECK ("status" IN ('PENDING_REVIEW', 'APPROVED', 'REJECTED', 'BLOCKED'));
+
+ALTER TABLE "users" ADD CONSTRAINT "chk_users_role" CHECK ("role" IN ('customer', 'admin'));
+
+ALTER TABLE "kyc_profiles" ADD CONSTRAINT "chk_kyc_profiles_status" CHECK ("status" IN ('DRAFT', 'PENDING', 'VERIFIED', 'REJECTED'));
+
+ALTER TABLE "screening_hits" ADD CONSTRAINT "chk_screening_hits_status" CHECK ("status" IN ('FLAGGED', 'BLOCKED', 'CLEARED'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_screening_hits_email_index";
+ALTER TABLE "screening_hits" DROP COLUMN "email_index";
@@ -0,0 +1,9 @@
+-- Tie screening hits to the person screened by the blind index of their
+-- email, so that clearing a refused registration only clears that applicant.
+-- Registration hits recorded before this have no applicant to tie them to
+-- and are no longer reused.
+
+ALTER TABLE "screening_hits" ADD COLUMN "email_index" text NOT NULL DEFAULT '';
+ALTER TABLE "screening_hits" ALTER COLUMN "email_index" DROP DEFAULT;
+UPDATE "screening_hits" SET "email_index" = "users"."email_index" FROM "users" WHERE "users"."id" = "screening_hits"."subject_id" AND "users"."email_index" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS "idx_screening_hits_email_index" ON "screening_hits" ("email_index");
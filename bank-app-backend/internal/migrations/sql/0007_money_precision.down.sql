@@ -0,0 +1,4 @@
+ALTER TABLE "transfer_reviews" ALTER COLUMN "amount" TYPE decimal;
+ALTER TABLE "transactions" ALTER COLUMN "balance" TYPE decimal;
+ALTER TABLE "transactions" ALTER COLUMN "amount" TYPE decimal;
+ALTER TABLE "accounts" ALTER COLUMN "balance" TYPE decimal;
@@ -0,0 +1,10 @@
+-- Money is kept in whole cents. The columns were unbounded decimals, which
+-- stored whatever floating point arithmetic produced; existing values are
+-- rounded to the cent.
+
+ALTER TABLE "accounts" ALTER COLUMN "balance" TYPE numeric(19,2) USING round("balance", 2);
+
+ALTER TABLE "transactions" ALTER COLUMN "amount" TYPE numeric(19,2) USING round("amount", 2);
+ALTER TABLE "transactions" ALTER COLUMN "balance" TYPE numeric(19,2) USING round("balance", 2);
+
+ALTER TABLE "transfer_reviews" ALTER COLUMN "amount" TYPE numeric(19,2) USING round("amount", 2);
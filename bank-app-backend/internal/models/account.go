@@ -18,7 +18,8 @@ type Account struct {
 	UserID        uint           `json:"userId" gorm:"not null"`
 	AccountNumber string         `json:"accountNumber" gorm:"uniqueIndex;not null"`
 	AccountType   AccountType    `json:"accountType" gorm:"not null"`
-	Balance       float64        `json:"balance" gorm:"not null;default:0"`
+	Balance       float64        `json:"balance" gorm:"type:numeric(19,2);not null;default:0"`
+	FrozenAt      *time.Time     `json:"frozenAt,omitempty"` // Set while money cannot move into or out of the account
 	Transactions  []Transaction  `json:"transactions,omitempty" gorm:"foreignKey:AccountID"`
 	CreatedAt     time.Time      `json:"createdAt"`
 	UpdatedAt     time.Time      `json:"updatedAt"`
@@ -32,6 +33,7 @@ type AccountDTO struct {
 	AccountNumber string      `json:"accountNumber"`
 	AccountType   AccountType `json:"accountType"`
 	Balance       float64     `json:"balance"`
+	FrozenAt      *time.Time  `json:"frozenAt,omitempty"`
 	CreatedAt     time.Time   `json:"createdAt"`
 	UpdatedAt     time.Time   `json:"updatedAt"`
 }
@@ -44,6 +46,7 @@ func (a *Account) ToDTO() AccountDTO {
 		AccountNumber: a.AccountNumber,
 		AccountType:   a.AccountType,
 		Balance:       a.Balance,
+		FrozenAt:      a.FrozenAt,
 		CreatedAt:     a.CreatedAt,
 		UpdatedAt:     a.UpdatedAt,
 	}
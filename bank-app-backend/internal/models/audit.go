@@ -0,0 +1,167 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Kinds of actors recorded in the audit log
+const (
+	AuditActorUser          = "user"
+	AuditActorServiceClient = "service_client"
+	AuditActorAnonymous     = "anonymous" // Not authenticated, e.g. a failed login or a password reset link
+	AuditActorSystem        = "system"    // The backend itself, e.g. revoking a session after refresh token reuse
+	AuditActorOperator      = "operator"  // Someone running an admin command on the server, identified by their OS user
+)
+
+// Audited actions
+const (
+	AuditLoginSucceeded       = "auth.login_succeeded"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditLogout               = "auth.logout"
+	AuditLogoutAll            = "auth.logout_all"
+	AuditLogoutOthers         = "auth.logout_others" // Every session but the current one ended, after a password change
+	AuditRefreshTokenReused   = "auth.refresh_token_reused"
+	AuditSessionRevoked       = "auth.session_revoked"
+	AuditPasswordChanged      = "user.password_changed"
+	AuditPasswordReset        = "user.password_reset"
+	AuditTwoFactorEnabled     = "user.two_factor_enabled"
+	AuditTwoFactorReset       = "admin.two_factor_reset"
+	AuditUserUnlocked         = "admin.user_unlocked"
+	AuditServiceClientCreated = "admin.service_client_created"
+	AuditServiceClientRevoked = "admin.service_client_revoked"
+	AuditTransferCompleted    = "transaction.transfer"
+	AuditTransferHeld         = "transaction.transfer_held"    // Held for review by fraud screening
+	AuditTransferBlocked      = "transaction.transfer_blocked" // Refused by fraud screening
+	AuditTransferApproved     = "admin.transfer_approved"      // A held transfer was approved and completed
+	AuditTransferRejected     = "admin.transfer_rejected"
+	AuditScreeningHitCleared  = "admin.screening_hit_cleared" // A watchlist match was cleared as a false positive
+	AuditKYCSubmitted         = "user.kyc_submitted"
+	AuditKYCApproved          = "admin.kyc_approved"
+	AuditKYCRejected          = "admin.kyc_rejected"
+	AuditUserCreated          = "admin.user_created"
+	AuditUserDisabled         = "admin.user_disabled"
+	AuditAccountFrozen        = "admin.account_frozen"
+	AuditAccountUnfrozen      = "admin.account_unfrozen"
+	AuditInterestAccrued      = "account.interest_accrued"
+)
+
+// Kinds of audit targets
+const (
+	AuditTargetUser           = "user"
+	AuditTargetSession        = "session"
+	AuditTargetServiceClient  = "service_client"
+	AuditTargetAccount        = "account"
+	AuditTargetTransferReview = "transfer_review"
+	AuditTargetScreeningHit   = "screening_hit"
+)
+
+// AuditActor - Who made a change and the request it was made in
+type AuditActor struct {
+	Type      string
+	ID        string
+	IPAddress string
+	RequestID string
+}
+
+// AsUser - The same request, attributed to a user who has just proven who they are
+func (a AuditActor) AsUser(userID uint) AuditActor {
+	a.Type = AuditActorUser
+	a.ID = fmt.Sprint(userID)
+	return a
+}
+
+// AuditEntry - One record of the append-only audit log. Each entry is chained
+// to the one before it: Hash covers the entry's content and PrevHash, so
+// changing or deleting any entry breaks the chain from there on.
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorType  string    `json:"actorType" gorm:"not null;index:idx_audit_actor"`
+	ActorID    string    `json:"actorId,omitempty" gorm:"index:idx_audit_actor"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"targetType,omitempty" gorm:"index:idx_audit_target"`
+	TargetID   string    `json:"targetId,omitempty" gorm:"index:idx_audit_target"`
+	Before     string    `json:"before,omitempty" gorm:"type:text"` // JSON snapshot of the target before the change
+	After      string    `json:"after,omitempty" gorm:"type:text"`  // JSON snapshot of the target after the change
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	PrevHash   string    `json:"prevHash"`
+	Hash       string    `json:"hash" gorm:"not null;uniqueIndex"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"not null;index"`
+}
+
+// NewAuditEntry - Start an audit entry for an action on a target
+func NewAuditEntry(actor AuditActor, action, targetType string, targetID interface{}) *AuditEntry {
+	return &AuditEntry{
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		IPAddress:  actor.IPAddress,
+		RequestID:  actor.RequestID,
+	}
+}
+
+// WithChange - Record snapshots of the target before and after the change.
+// Either may be nil. Snapshots must not contain secrets.
+func (e *AuditEntry) WithChange(before, after interface{}) *AuditEntry {
+	e.Before = auditSnapshot(before)
+	e.After = auditSnapshot(after)
+	return e
+}
+
+// ComputeHash - SHA-256 over the previous hash and the entry's content
+func (e *AuditEntry) ComputeHash() string {
+	content, _ := json.Marshal([]string{
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.ActorType,
+		e.ActorID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.Before,
+		e.After,
+		e.IPAddress,
+		e.RequestID,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func auditSnapshot(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	snapshot, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(snapshot)
+}
+
+// AuditFilter - Query parameters for searching the audit log
+type AuditFilter struct {
+	ActorType  string     `form:"actorType"`
+	ActorID    string     `form:"actorId"`
+	Action     string     `form:"action"`
+	TargetType string     `form:"targetType"`
+	TargetID   string     `form:"targetId"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int        `form:"limit,default=100" binding:"min=1,max=1000"`
+	Offset     int        `form:"offset" binding:"min=0"`
+}
+
+// AuditVerification - Result of checking the audit log's hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`            // Entries checked
+	HeadHash string `json:"headHash"`           // Hash of the newest entry, to compare with a copy kept elsewhere
+	BrokenAt uint   `json:"brokenAt,omitempty"` // First entry that does not verify
+	Problem  string `json:"problem,omitempty"`
+}
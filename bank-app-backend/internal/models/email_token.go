@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+type EmailTokenPurpose string
+
+const (
+	EmailVerification EmailTokenPurpose = "EMAIL_VERIFICATION"
+	PasswordReset     EmailTokenPurpose = "PASSWORD_RESET"
+)
+
+// EmailToken - A single-use token sent to a user by email. Only the SHA-256
+// hash of the token is stored.
+type EmailToken struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	UserID    uint              `json:"userId" gorm:"not null;index"`
+	Purpose   EmailTokenPurpose `json:"purpose" gorm:"not null"`
+	TokenHash string            `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time         `json:"expiresAt" gorm:"not null"`
+	UsedAt    *time.Time        `json:"usedAt,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// VerifyEmailRequest - Request body for email verification
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest - Request body for flows that only need an email address
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest - Request body for resetting a forgotten password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"` // Checked against the password policy
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// Fraud screening decisions
+const (
+	FraudAllow  = "ALLOW"
+	FraudReview = "REVIEW" // Hold the transfer until an admin approves or rejects it
+	FraudBlock  = "BLOCK"
+)
+
+// FraudAssessment - Outcome of screening a transfer against the fraud rules
+type FraudAssessment struct {
+	Score    int      `json:"score"`
+	Reasons  []string `json:"reasons"` // One per rule that fired
+	Decision string   `json:"decision"`
+}
+
+// Transfer review statuses
+const (
+	ReviewPending  = "PENDING_REVIEW"
+	ReviewApproved = "APPROVED" // The transfer was completed when it was approved
+	ReviewRejected = "REJECTED"
+	ReviewBlocked  = "BLOCKED" // Refused outright by fraud screening, kept for the record
+)
+
+// TransferReview - A transfer that fraud screening held or blocked. No money
+// moves until a held transfer is approved.
+type TransferReview struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	FromAccountID uint       `json:"fromAccountId" gorm:"not null;index"`
+	ToAccountID   uint       `json:"toAccountId" gorm:"not null"`
+	Amount        float64    `json:"amount" gorm:"type:numeric(19,2);not null"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status" gorm:"not null;index"`
+	Score         int        `json:"score" gorm:"not null"`
+	Reasons       []string   `json:"reasons" gorm:"type:text;serializer:json"`
+	RequestedBy   string     `json:"requestedBy"` // Audit actor that asked for the transfer, e.g. user:3
+	ReviewedByID  *uint      `json:"reviewedById,omitempty"`
+	ReviewNote    string     `json:"reviewNote,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// TransferRequest - The transfer as it was requested
+func (r *TransferReview) TransferRequest() *TransferRequest {
+	return &TransferRequest{
+		FromAccountID: r.FromAccountID,
+		ToAccountID:   r.ToAccountID,
+		Amount:        r.Amount,
+		Description:   r.Description,
+	}
+}
+
+// HeldTransferResponse - Response body when a transfer is held for review
+type HeldTransferResponse struct {
+	Message  string `json:"message"`
+	ReviewID uint   `json:"reviewId"`
+	Status   string `json:"status"`
+}
+
+// ReviewDecisionRequest - Request body for approving or rejecting a held transfer
+type ReviewDecisionRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+)
+
+// KYC verification statuses
+const (
+	KYCNotStarted = "NOT_STARTED" // No profile yet, only ever returned, never stored
+	KYCDraft      = "DRAFT"       // Being filled in by the user
+	KYCPending    = "PENDING"     // Submitted and waiting for an admin
+	KYCVerified   = "VERIFIED"
+	KYCRejected   = "REJECTED" // The user may correct the profile and submit it again
+)
+
+// KYC verification levels. Transfer limits scale with the level.
+const (
+	KYCLevelNone  = 0 // Unverified users can view their accounts but not transfer
+	KYCLevelBasic = 1 // Identity document checked
+	KYCLevelFull  = 2 // Identity document and proof of address checked
+)
+
+// Government ID types
+const (
+	IDTypePassport       = "passport"
+	IDTypeDriversLicense = "drivers_license"
+	IDTypeNationalID     = "national_id"
+)
+
+// KYC document kinds
+const (
+	KYCDocumentIDFront        = "id_front"
+	KYCDocumentIDBack         = "id_back"
+	KYCDocumentProofOfAddress = "proof_of_address"
+)
+
+// KYCProfile - Identity details a user submits for verification. Personal
+// details are stored encrypted.
+type KYCProfile struct {
+	ID           uint          `json:"id" gorm:"primaryKey"`
+	UserID       uint          `json:"userId" gorm:"not null;uniqueIndex"`
+	DateOfBirth  string        `json:"dateOfBirth" gorm:"serializer:encrypted"` // YYYY-MM-DD
+	AddressLine1 string        `json:"addressLine1" gorm:"serializer:encrypted"`
+	AddressLine2 string        `json:"addressLine2,omitempty" gorm:"serializer:encrypted"`
+	City         string        `json:"city" gorm:"serializer:encrypted"`
+	PostalCode   string        `json:"postalCode" gorm:"serializer:encrypted"`
+	Country      string        `json:"country"` // ISO 3166-1 alpha-2
+	IDType       string        `json:"idType"`
+	IDNumber     string        `json:"idNumber" gorm:"serializer:encrypted"`
+	Status       string        `json:"status" gorm:"not null;default:DRAFT;index"`
+	Level        int           `json:"level" gorm:"not null;default:0"`
+	ReviewNote   string        `json:"reviewNote,omitempty"` // Reason given when rejected
+	ReviewedByID *uint         `json:"reviewedById,omitempty"`
+	SubmittedAt  *time.Time    `json:"submittedAt,omitempty"`
+	ReviewedAt   *time.Time    `json:"reviewedAt,omitempty"`
+	Documents    []KYCDocument `json:"documents" gorm:"foreignKey:ProfileID"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
+
+// Editable - Whether the user may still change the profile
+func (p *KYCProfile) Editable() bool {
+	return p.Status == KYCDraft || p.Status == KYCRejected || p.Status == KYCNotStarted
+}
+
+// HasDocument - Whether a document of the kind was uploaded
+func (p *KYCProfile) HasDocument(kind string) bool {
+	for _, document := range p.Documents {
+		if document.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// KYCDocument - A document uploaded for verification. The file is kept
+// encrypted in document storage.
+type KYCDocument struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ProfileID   uint      `json:"profileId" gorm:"not null;index"`
+	Kind        string    `json:"kind" gorm:"not null"`
+	FileName    string    `json:"fileName" gorm:"serializer:encrypted"`
+	ContentType string    `json:"contentType"` // Detected from the content, not taken from the upload
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-" gorm:"not null"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// KYCProfileRequest - Request body for saving the current user's KYC profile
+type KYCProfileRequest struct {
+	DateOfBirth  string `json:"dateOfBirth" binding:"required"` // YYYY-MM-DD
+	AddressLine1 string `json:"addressLine1" binding:"required,max=200"`
+	AddressLine2 string `json:"addressLine2" binding:"max=200"`
+	City         string `json:"city" binding:"required,max=100"`
+	PostalCode   string `json:"postalCode" binding:"required,max=20"`
+	Country      string `json:"country" binding:"required,len=2"`
+	IDType       string `json:"idType" binding:"required,oneof=passport drivers_license national_id"`
+	IDNumber     string `json:"idNumber" binding:"required,max=50"`
+}
+
+// KYCApproveRequest - Request body for approving a KYC profile
+type KYCApproveRequest struct {
+	Level int `json:"level" binding:"required,oneof=1 2"`
+}
+
+// KYCRejectRequest - Request body for rejecting a KYC profile
+type KYCRejectRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// LedgerTotals - What an account's transactions add up to
+type LedgerTotals struct {
+	AccountID   uint
+	Total       float64 // Deposits and incoming transfers less withdrawals and outgoing transfers
+	LastBalance float64 // Running balance recorded by the newest transaction
+	Entries     int64
+}
+
+// Discrepancy - An account whose balance does not match its transactions
+type Discrepancy struct {
+	AccountID     uint    `json:"accountId"`
+	AccountNumber string  `json:"accountNumber"`
+	Balance       float64 `json:"balance"`
+	LedgerTotal   float64 `json:"ledgerTotal"`
+	LastBalance   float64 `json:"lastBalance"` // Running balance of the newest transaction, 0 without transactions
+	Entries       int64   `json:"entries"`
+}
+
+// ReconcileResult - Outcome of comparing every account with its transactions
+type ReconcileResult struct {
+	Checked       int           `json:"checked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// InterestResult - Outcome of accruing a day's interest on savings accounts
+type InterestResult struct {
+	Date     time.Time `json:"date"`
+	Credited int       `json:"credited"`
+	Skipped  int       `json:"skipped"` // Already credited for the date, or earning less than a cent
+	Total    float64   `json:"total"`
+}
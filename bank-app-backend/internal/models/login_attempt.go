@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt - A single login attempt, kept for security review. UserID is
+// nil when the email did not match any user. The email tried is kept as its
+// blind index only, as it is PII.
+type LoginAttempt struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     *uint     `json:"userId,omitempty" gorm:"index"`
+	EmailIndex string    `json:"-" gorm:"not null;index"`
+	IPAddress  string    `json:"ipAddress" gorm:"not null;index"`
+	UserAgent  string    `json:"userAgent"`
+	Success    bool      `json:"success" gorm:"not null"`
+	Reason     string    `json:"reason,omitempty"` // Why a failed attempt was rejected
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
+}
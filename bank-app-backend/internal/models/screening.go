@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Screening decisions
+const (
+	ScreeningClear = "CLEAR"
+	ScreeningFlag  = "FLAG"  // Let the action through but record the match for review
+	ScreeningBlock = "BLOCK" // Refuse the action
+)
+
+// Where a name was screened
+const (
+	ScreeningRegistration = "registration"
+	ScreeningTransfer     = "transfer" // The payee of a transfer
+)
+
+// Screening hit statuses
+const (
+	HitFlagged = "FLAGGED"
+	HitBlocked = "BLOCKED"
+	HitCleared = "CLEARED" // A false positive; the subject, or the applicant of a refused registration, is no longer matched against the entry
+)
+
+// ScreeningResult - Outcome of screening a name against the watchlist
+type ScreeningResult struct {
+	Name     string
+	Decision string
+	Hits     []ScreeningHit // One per matching entry that has not been cleared
+}
+
+// ScreeningHit - A watchlist match recorded for review
+type ScreeningHit struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	SubjectID     *uint      `json:"subjectId,omitempty" gorm:"index"` // User screened, not set for a refused registration
+	Name          string     `json:"name" gorm:"not null;serializer:encrypted"`
+	NameIndex     string     `json:"-" gorm:"not null;index"` // Blind index of the normalized name
+	EmailIndex    string     `json:"-" gorm:"not null;index"` // Blind index of the screened person's email, which ties a refused registration's hits to its applicant
+	Context       string     `json:"context" gorm:"not null"`
+	EntryID       string     `json:"entryId" gorm:"not null;index"`
+	EntryName     string     `json:"entryName"`
+	MatchedName   string     `json:"matchedName"` // The entry's name or alias that matched
+	Program       string     `json:"program"`
+	Score         float64    `json:"score"`
+	Status        string     `json:"status" gorm:"not null;index"`
+	ClearedByID   *uint      `json:"clearedById,omitempty"`
+	Justification string     `json:"justification,omitempty"`
+	ClearedAt     *time.Time `json:"clearedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// ClearScreeningHitRequest - Request body for clearing a false positive
+type ClearScreeningHitRequest struct {
+	Justification string `json:"justification" binding:"required,min=10,max=1000"`
+}
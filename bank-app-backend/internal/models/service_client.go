@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes that can be granted to service clients
+const (
+	ScopeUsersRead        = "users:read"
+	ScopeAccountsRead     = "accounts:read"
+	ScopeTransactionsRead = "transactions:read"
+	ScopeTransfersWrite   = "transfers:write"
+)
+
+// Scopes - Every scope a service client can be granted
+var Scopes = []string{ScopeUsersRead, ScopeAccountsRead, ScopeTransactionsRead, ScopeTransfersWrite}
+
+// HasScope reports whether scope is one of scopes
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceClient - A machine client that calls the API with an API key or
+// client-credentials tokens instead of a user login. Only the SHA-256 hash of
+// its secret is stored.
+type ServiceClient struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"not null"`
+	ClientID    string     `json:"clientId" gorm:"uniqueIndex;not null"`
+	SecretHash  string     `json:"-" gorm:"not null"`
+	Scopes      string     `json:"scopes" gorm:"not null"` // Space-separated, as in OAuth2
+	CreatedByID uint       `json:"createdById"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// ScopeList returns the scopes granted to the client
+func (c *ServiceClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// ToDTO - Convert ServiceClient model to DTO (Data Transfer Object)
+func (c *ServiceClient) ToDTO() ServiceClientDTO {
+	return ServiceClientDTO{
+		ID:         c.ID,
+		Name:       c.Name,
+		ClientID:   c.ClientID,
+		Scopes:     c.ScopeList(),
+		LastUsedAt: c.LastUsedAt,
+		RevokedAt:  c.RevokedAt,
+		CreatedAt:  c.CreatedAt,
+	}
+}
+
+// ServiceClientDTO - Data Transfer Object for ServiceClient
+type ServiceClientDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	ClientID   string     `json:"clientId"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// CreateServiceClientRequest - Request body for registering a service client
+type CreateServiceClientRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
+
+// ServiceClientCredentials - Returned once when a client is created. The
+// secret cannot be retrieved later.
+type ServiceClientCredentials struct {
+	Client       ServiceClientDTO `json:"client"`
+	ClientSecret string           `json:"clientSecret"`
+	APIKey       string           `json:"apiKey"` // Client ID and secret for the X-API-Key header
+}
+
+// ClientCredentialsRequest - OAuth2 token request. Credentials may also be sent
+// with HTTP Basic authentication.
+type ClientCredentialsRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type" binding:"required"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"` // Space-separated subset of the client's scopes, all of them when empty
+}
+
+// ClientToken - OAuth2 token response for the client-credentials grant
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"` // Seconds
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorResponse - OAuth2 error body returned by the token endpoint
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ServiceClientUsage - One API request made by a service client
+type ServiceClientUsage struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	ServiceClientID uint      `json:"serviceClientId" gorm:"not null;index"`
+	AuthMethod      string    `json:"authMethod" gorm:"not null"` // "api_key" or "token"
+	Method          string    `json:"method" gorm:"not null"`
+	Path            string    `json:"path" gorm:"not null"` // Route pattern, e.g. /api/v1/accounts/:id
+	StatusCode      int       `json:"statusCode"`
+	IPAddress       string    `json:"ipAddress"`
+	CreatedAt       time.Time `json:"createdAt" gorm:"index"`
+}
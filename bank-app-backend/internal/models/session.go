@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Session - A login on one device. It lives as long as its refresh token
+// family, whose ID is carried in the sid claim of access tokens.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"userId" gorm:"not null;index"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex;not null"`
+	DeviceHash string     `json:"-" gorm:"not null;index"` // SHA-256 of the user agent, used to spot new devices
+	UserAgent  string     `json:"userAgent"`
+	IPAddress  string     `json:"ipAddress"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"not null"` // Expiry of the latest refresh token
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// ToDTO - Convert Session model to DTO (Data Transfer Object)
+func (s *Session) ToDTO(currentFamilyID string) SessionDTO {
+	return SessionDTO{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		Current:    s.FamilyID == currentFamilyID,
+	}
+}
+
+// SessionDTO - Data Transfer Object for Session
+type SessionDTO struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"` // Whether the request was made with this session
+}
+
+// DeviceInfo - The client a login came from
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// StepUpChallenge - Response body when an operation needs fresh authentication
+type StepUpChallenge struct {
+	Message        string   `json:"message"`
+	StepUpRequired bool     `json:"stepUpRequired"`
+	Methods        []string `json:"methods"` // Accepted by /auth/reauthenticate
+}
+
+// ReauthenticateRequest - Request body for step-up authentication. Users with
+// 2FA enabled must send a TOTP code, other users their password.
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// ElevatedToken - Short-lived access token issued by step-up authentication
+type ElevatedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
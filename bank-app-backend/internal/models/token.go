@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken - A server-side refresh token. Only the SHA-256 hash of the
+// token is stored; every token issued from the same login shares a FamilyID.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"userId" gorm:"not null;index"`
+	FamilyID  string     `json:"familyId" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"not null"`
+	AuthTime  time.Time  `json:"authTime"`            // When the user logged in, kept across rotations
+	UsedAt    *time.Time `json:"usedAt,omitempty"`    // Set when the token is rotated
+	RevokedAt *time.Time `json:"revokedAt,omitempty"` // Set when the whole family is revoked
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// RevokedToken - The jti of an access token revoked before its expiry
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    uint      `json:"userId" gorm:"not null"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TokenPair - Access and refresh tokens issued on login or refresh
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// RefreshRequest - Request body for refreshing a token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
@@ -19,8 +19,8 @@ type Transaction struct {
 	AccountID        uint             `json:"accountId" gorm:"not null"`
 	SourceAccountID  *uint            `json:"sourceAccountId,omitempty"`
 	TargetAccountID  *uint            `json:"targetAccountId,omitempty"`
-	Amount           float64          `json:"amount" gorm:"not null"`
-	Balance          float64          `json:"balance" gorm:"not null"` // Balance after the transaction
+	Amount           float64          `json:"amount" gorm:"type:numeric(19,2);not null"`
+	Balance          float64          `json:"balance" gorm:"type:numeric(19,2);not null"` // Balance after the transaction
 	Type             TransactionType  `json:"type" gorm:"not null"`
 	Description      string           `json:"description"`
 	TransactionDate  time.Time        `json:"transactionDate" gorm:"not null"`
@@ -1,71 +1,153 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// MaxPasswordBytes - bcrypt ignores everything after the first 72 bytes
+const MaxPasswordBytes = 72
+
+// User roles
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"` // Password is not exposed in JSON
-	FirstName string         `json:"firstName" gorm:"not null"`
-	LastName  string         `json:"lastName" gorm:"not null"`
-	Accounts  []Account      `json:"accounts,omitempty" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Email              string         `json:"email" gorm:"not null;serializer:encrypted"`
+	EmailIndex         string         `json:"-" gorm:"uniqueIndex"` // Blind index of Email, used to look users up by email
+	Password           string         `json:"-" gorm:"not null"`    // Password is not exposed in JSON
+	FirstName          string         `json:"firstName" gorm:"not null;serializer:encrypted"`
+	LastName           string         `json:"lastName" gorm:"not null;serializer:encrypted"`
+	Role               string         `json:"role" gorm:"not null;default:customer"`
+	EmailVerifiedAt    *time.Time     `json:"emailVerifiedAt,omitempty"`
+	TwoFactorSecret    string         `json:"-" gorm:"serializer:encrypted"` // TOTP secret, set on enrollment
+	TwoFactorEnabledAt *time.Time     `json:"twoFactorEnabledAt,omitempty"`  // Set once enrollment is confirmed
+	TwoFactorLastStep  int64          `json:"-" gorm:"not null;default:0"`   // Last accepted TOTP time step, prevents code replay
+	FailedLoginCount   int            `json:"-" gorm:"not null;default:0"`   // Consecutive failed logins within the failure window
+	LastFailedLoginAt  *time.Time     `json:"-"`
+	LockedUntil        *time.Time     `json:"lockedUntil,omitempty"` // Set while the account is locked after too many failed logins
+	DisabledAt         *time.Time     `json:"disabledAt,omitempty"`  // Set once an administrator disabled the user, who can no longer log in
+	Accounts           []Account      `json:"accounts,omitempty" gorm:"foreignKey:UserID"`
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// BeforeCreate - Index the email, which is stored encrypted
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	return u.indexEmail()
+}
+
+// BeforeUpdate - Keep the email index in step with a changed email
+func (u *User) BeforeUpdate(tx *gorm.DB) error {
+	return u.indexEmail()
+}
+
+func (u *User) indexEmail() error {
+	if u.Email == "" {
+		return nil
+	}
+	index, err := pii.EmailIndex(u.Email)
+	if err != nil {
+		return err
+	}
+	u.EmailIndex = index
+	return nil
 }
 
-// BeforeSave - Hash the user's password before saving to the database
-func (u *User) BeforeSave(tx *gorm.DB) error {
-	if u.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		u.Password = string(hashedPassword)
+// SetPassword - Hash a plain-text password into the user. Password always
+// holds a bcrypt hash and saving the user never hashes it again.
+func (u *User) SetPassword(password string, cost int) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return err
 	}
+	u.Password = string(hashedPassword)
 	return nil
 }
 
+// PasswordNeedsRehash - Whether the password hash was made with a lower bcrypt cost
+func (u *User) PasswordNeedsRehash(cost int) bool {
+	hashCost, err := bcrypt.Cost([]byte(u.Password))
+	return err == nil && hashCost < cost
+}
+
+// TwoFactorEnabled - Whether login requires a second factor
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactorEnabledAt != nil
+}
+
 // ComparePassword - Compare the password with the hashed password
 func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
 
+// FullName - First and last name, as the user is screened by
+func (u *User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
 // ToDTO - Convert User model to DTO (Data Transfer Object)
 func (u *User) ToDTO() UserDTO {
 	return UserDTO{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:               u.ID,
+		Email:            u.Email,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+		Role:             u.Role,
+		EmailVerified:    u.EmailVerifiedAt != nil,
+		TwoFactorEnabled: u.TwoFactorEnabled(),
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
 
 // UserDTO - Data Transfer Object for User
 type UserDTO struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID               uint      `json:"id"`
+	Email            string    `json:"email"`
+	FirstName        string    `json:"firstName"`
+	LastName         string    `json:"lastName"`
+	Role             string    `json:"role"`
+	EmailVerified    bool      `json:"emailVerified"`
+	TwoFactorEnabled bool      `json:"twoFactorEnabled"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
 }
 
-// LoginRequest - Request body for login
+// LoginRequest - Request body for login. The password policy applies when a
+// password is set, so older passwords that do not meet it still work.
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 // LoginResponse - Response body for login
 type LoginResponse struct {
-	Token string  `json:"token"`
-	User  UserDTO `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+	User             UserDTO   `json:"user"`
+}
+
+// RegisterRequest - Request body for self-service registration
+type RegisterRequest struct {
+	Email     string `json:"email" binding:"required,email,max=254"`
+	Password  string `json:"password" binding:"required"` // Checked against the password policy
+	FirstName string `json:"firstName" binding:"required,max=100"`
+	LastName  string `json:"lastName" binding:"required,max=100"`
+}
+
+// ChangePasswordRequest - Request body for changing the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"` // Checked against the password policy
 }
@@ -0,0 +1,199 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func testKeyring(t *testing.T, versions ...int) *Keyring {
+	keys := map[int][]byte{}
+	for _, version := range versions {
+		keys[version] = testKey(byte(version))
+	}
+	k, err := NewKeyring(keys, testKey(0xff))
+	assert.NoError(t, err)
+	return k
+}
+
+func writeKeyring(t *testing.T, file interface{}) string {
+	data, err := json.Marshal(file)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	ciphertext, err := k.Encrypt("Jane")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "enc:v1:"))
+	assert.NotContains(t, ciphertext, "Jane")
+
+	plaintext, err := k.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", plaintext)
+}
+
+func TestEncrypt_UsesFreshDataKeys(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	first, _ := k.Encrypt("Jane")
+	second, _ := k.Encrypt("Jane")
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestEncrypt_LeavesEmptyValuesEmpty(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	ciphertext, err := k.Encrypt("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", ciphertext)
+	assert.False(t, k.NeedsReencryption(ciphertext))
+}
+
+func TestDecrypt_PassesPlaintextThrough(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	plaintext, err := k.Decrypt("written before encryption")
+	assert.NoError(t, err)
+	assert.Equal(t, "written before encryption", plaintext)
+	assert.True(t, k.NeedsReencryption("written before encryption"))
+}
+
+func TestDecrypt_RejectsTamperedValue(t *testing.T) {
+	k := testKeyring(t, 1)
+	ciphertext, _ := k.Encrypt("Jane")
+
+	tampered := ciphertext[:len(ciphertext)-2] + "AA"
+	_, err := k.Decrypt(tampered)
+
+	assert.Error(t, err)
+}
+
+func TestRotation_OldValuesStayReadable(t *testing.T) {
+	old := testKeyring(t, 1)
+	ciphertext, _ := old.Encrypt("Jane")
+
+	rotated := testKeyring(t, 1, 2)
+	assert.Equal(t, 2, rotated.CurrentVersion())
+	assert.True(t, rotated.NeedsReencryption(ciphertext))
+
+	plaintext, err := rotated.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", plaintext)
+
+	reencrypted, _ := rotated.Encrypt(plaintext)
+	assert.True(t, strings.HasPrefix(reencrypted, "enc:v2:"))
+	assert.False(t, rotated.NeedsReencryption(reencrypted))
+}
+
+func TestDecrypt_UnknownKeyVersion(t *testing.T) {
+	ciphertext, _ := testKeyring(t, 2).Encrypt("Jane")
+
+	_, err := testKeyring(t, 1).Decrypt(ciphertext)
+
+	assert.EqualError(t, err, "key version 2 is not in the keyring")
+}
+
+func TestBlindIndex(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	assert.Equal(t, k.BlindIndex("jane@example.com"), testKeyring(t, 1, 2).BlindIndex("jane@example.com"))
+	assert.NotEqual(t, k.BlindIndex("jane@example.com"), k.BlindIndex("john@example.com"))
+	assert.Len(t, k.BlindIndex("jane@example.com"), 64)
+}
+
+func TestEmailIndex(t *testing.T) {
+	k := testKeyring(t, 1)
+
+	assert.Equal(t, k.BlindIndex("jane@example.com"), k.EmailIndex("jane@example.com"))
+	assert.Equal(t, k.EmailIndex("jane@example.com"), k.EmailIndex(" Jane@Example.COM\n"))
+	assert.NotEqual(t, k.EmailIndex("jane@example.com"), k.EmailIndex("john@example.com"))
+}
+
+func TestLoadKeyring(t *testing.T) {
+	path := writeKeyring(t, map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{"version": 1, "key": base64.StdEncoding.EncodeToString(testKey(1))},
+			{"version": 3, "key": base64.StdEncoding.EncodeToString(testKey(3))},
+		},
+		"blindIndexKey": base64.StdEncoding.EncodeToString(testKey(0xff)),
+	})
+
+	k, err := LoadKeyring(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, k.CurrentVersion())
+	assert.Equal(t, testKeyring(t, 1).BlindIndex("x"), k.BlindIndex("x"))
+}
+
+func TestLoadKeyring_RejectsBadKeys(t *testing.T) {
+	indexKey := base64.StdEncoding.EncodeToString(testKey(0xff))
+	cases := map[string]interface{}{
+		"no keys": map[string]interface{}{"blindIndexKey": indexKey},
+		"short key": map[string]interface{}{
+			"keys":          []map[string]interface{}{{"version": 1, "key": base64.StdEncoding.EncodeToString([]byte("short"))}},
+			"blindIndexKey": indexKey,
+		},
+		"duplicate version": map[string]interface{}{
+			"keys": []map[string]interface{}{
+				{"version": 1, "key": base64.StdEncoding.EncodeToString(testKey(1))},
+				{"version": 1, "key": base64.StdEncoding.EncodeToString(testKey(2))},
+			},
+			"blindIndexKey": indexKey,
+		},
+		"missing index key": map[string]interface{}{
+			"keys": []map[string]interface{}{{"version": 1, "key": base64.StdEncoding.EncodeToString(testKey(1))}},
+		},
+	}
+
+	for name, file := range cases {
+		_, err := LoadKeyring(writeKeyring(t, file))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSerializer_RoundTrip(t *testing.T) {
+	type customer struct {
+		Name string `gorm:"serializer:encrypted"`
+	}
+	Register(testKeyring(t, 1))
+	defer Register(nil)
+
+	s, err := schema.Parse(&customer{}, &sync.Map{}, schema.NamingStrategy{})
+	assert.NoError(t, err)
+	field := s.LookUpField("Name")
+
+	stored, err := Serializer{}.Value(context.Background(), field, reflect.Value{}, "Jane")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(stored.(string)))
+
+	var c customer
+	assert.NoError(t, Serializer{}.Scan(context.Background(), field, reflect.ValueOf(&c).Elem(), stored))
+	assert.Equal(t, "Jane", c.Name)
+}
+
+func TestBlindIndex_RequiresRegisteredKeyring(t *testing.T) {
+	Register(nil)
+
+	_, err := BlindIndex("jane@example.com")
+
+	assert.Equal(t, ErrNoKeyring, err)
+}
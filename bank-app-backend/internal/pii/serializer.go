@@ -0,0 +1,113 @@
+package pii
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is used in model tags: gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+// ErrNoKeyring is returned when PII is read or written before Register
+var ErrNoKeyring = errors.New("no PII keyring registered")
+
+// GORM keeps serializers in a process-wide registry, so the keyring they
+// use is process-wide as well
+var (
+	registeredMu sync.RWMutex
+	registered   *Keyring
+)
+
+// Register installs the keyring used by the encrypted serializer and by
+// BlindIndex. It must be called before the first query touches an encrypted
+// model.
+func Register(keyring *Keyring) {
+	registeredMu.Lock()
+	registered = keyring
+	registeredMu.Unlock()
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Registered returns the keyring installed by Register
+func Registered() (*Keyring, error) {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	if registered == nil {
+		return nil, ErrNoKeyring
+	}
+	return registered, nil
+}
+
+// BlindIndex hashes value with the registered keyring's blind index key
+func BlindIndex(value string) (string, error) {
+	keyring, err := Registered()
+	if err != nil {
+		return "", err
+	}
+	return keyring.BlindIndex(value), nil
+}
+
+// Encrypt encrypts value with the registered keyring, for columns written
+// without going through the serializer
+func Encrypt(value string) (string, error) {
+	keyring, err := Registered()
+	if err != nil {
+		return "", err
+	}
+	return keyring.Encrypt(value)
+}
+
+// EmailIndex indexes email with the registered keyring, as Keyring.EmailIndex does
+func EmailIndex(email string) (string, error) {
+	keyring, err := Registered()
+	if err != nil {
+		return "", err
+	}
+	return keyring.EmailIndex(email), nil
+}
+
+// Serializer encrypts string fields on write and decrypts them on read
+type Serializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported value %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	keyring, err := Registered()
+	if err != nil {
+		return err
+	}
+	plaintext, err := keyring.Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("field %s: %v", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+// Value implements schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string", field.Name)
+	}
+
+	keyring, err := Registered()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Encrypt(plaintext)
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often stores forget buckets that have refilled
+const pruneInterval = time.Minute
+
+// memoryBucket is a bucket and when it will have refilled
+type memoryBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+// MemoryStore keeps the buckets in memory, so each instance of the server
+// limits on its own
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]*memoryBucket
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+// Take takes a token from the bucket of key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.prune(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Requests), refilled: now}}
+		s.buckets[key] = b
+	}
+	result := b.take(limit, now)
+	b.fullAt = b.bucket.fullAt(limit)
+	return result, nil
+}
+
+// prune forgets the buckets that have refilled, as a new bucket starts full
+// anyway, at most once every pruneInterval
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPruned) < pruneInterval {
+		return
+	}
+	s.lastPruned = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bucketRow is a bucket as the rate_limit_buckets table keeps it
+type bucketRow struct {
+	Key        string `gorm:"primaryKey"`
+	Tokens     float64
+	RefilledAt time.Time
+	FullAt     time.Time // Rows are deleted once full, as a new bucket starts full anyway
+}
+
+func (bucketRow) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresStore keeps the buckets in the database, so every instance of the
+// server takes from the same buckets. Each take locks the bucket's row; the
+// instances' clocks are assumed to agree.
+type PostgresStore struct {
+	db         *gorm.DB
+	mu         sync.Mutex
+	lastPruned time.Time
+	now        func() time.Time
+}
+
+// NewPostgresStore returns a store over the rate_limit_buckets table
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db, now: time.Now}
+}
+
+// Take takes a token from the bucket of key
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+	s.prune(ctx, now)
+
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		row := bucketRow{Key: key, Tokens: float64(limit.Requests), RefilledAt: now, FullAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		b := bucket{tokens: row.Tokens, refilled: row.RefilledAt}
+		result = b.take(limit, now)
+		return tx.Model(&bucketRow{}).Where("key = ?", key).Updates(map[string]interface{}{
+			"tokens":      b.tokens,
+			"refilled_at": b.refilled,
+			"full_at":     b.fullAt(limit),
+		}).Error
+	})
+	return result, err
+}
+
+// prune deletes the buckets that have refilled, at most once every
+// pruneInterval. A failure only leaves the rows for the next time.
+func (s *PostgresStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.lastPruned) >= pruneInterval
+	if due {
+		s.lastPruned = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if err := s.db.WithContext(ctx).Where("full_at <= ?", now).Delete(&bucketRow{}).Error; err != nil {
+		logging.FromContext(ctx).Warn("Failed to prune rate limit buckets", "error", err)
+	}
+}
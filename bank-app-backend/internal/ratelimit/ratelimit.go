@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit lets Requests through every Per, in bursts of up to Requests. The
+// zero Limit lets everything through.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Off reports whether the limit lets everything through
+func (l Limit) Off() bool {
+	return l.Requests == 0
+}
+
+// String formats the limit as ParseLimit reads it
+func (l Limit) String() string {
+	if l.Off() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// ParseLimit reads a limit written as <requests>/<duration>, such as 100/1m,
+// or off
+func ParseLimit(s string) (Limit, error) {
+	if s == "off" {
+		return Limit{}, nil
+	}
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not a limit such as 100/1m, or off", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%q is not a limit: the number of requests must be a positive whole number", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%q is not a limit: the period must be a positive duration such as 1m", s)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// perSecond is how fast the bucket refills
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int           // Whole tokens left in the bucket
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, when the request was refused
+}
+
+// Store keeps the token buckets, one for each key
+type Store interface {
+	// Take refills the bucket of key for the time since it was last used and
+	// takes a token from it, if one is left. A new bucket starts full.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is a token bucket as the stores keep it
+type bucket struct {
+	tokens   float64
+	refilled time.Time
+}
+
+// take refills the bucket up to now and takes a token from it, if one is left
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	rate := limit.perSecond()
+	if elapsed := now.Sub(b.refilled).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed*rate)
+		b.refilled = now
+	}
+
+	result := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Requests) - b.tokens) / rate)
+	return result
+}
+
+// fullAt is when the bucket will have refilled, after which it can be forgotten
+func (b *bucket) fullAt(limit Limit) time.Time {
+	return b.refilled.Add(seconds((float64(limit.Requests) - b.tokens) / limit.perSecond()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+		err  string
+	}{
+		{"100/1m", Limit{Requests: 100, Per: time.Minute}, ""},
+		{"5/30s", Limit{Requests: 5, Per: 30 * time.Second}, ""},
+		{"off", Limit{}, ""},
+		{"100", Limit{}, `"100" is not a limit such as 100/1m, or off`},
+		{"0/1m", Limit{}, `"0/1m" is not a limit: the number of requests must be a positive whole number`},
+		{"10/minute", Limit{}, `"10/minute" is not a limit: the period must be a positive duration such as 1m`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			// Act
+			limit, err := ParseLimit(tt.in)
+
+			// Assert
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, limit)
+			assert.Equal(t, limit, mustParse(t, limit.String()), "String is read back as the same limit")
+		})
+	}
+}
+
+func mustParse(t *testing.T, s string) Limit {
+	limit, err := ParseLimit(s)
+	require.NoError(t, err)
+	return limit
+}
+
+func TestBucket_Take(t *testing.T) {
+	// Arrange - 3 requests a minute refill a token every 20 seconds
+	limit := Limit{Requests: 3, Per: time.Minute}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := bucket{tokens: 3, refilled: start}
+
+	// Act - a burst empties the bucket
+	var results []Result
+	for i := 0; i < 4; i++ {
+		results = append(results, b.take(limit, start))
+	}
+
+	// Assert
+	assert.True(t, results[0].Allowed)
+	assert.Equal(t, 2, results[0].Remaining)
+	assert.Equal(t, 20*time.Second, results[0].Reset)
+	assert.True(t, results[2].Allowed)
+	assert.Equal(t, 0, results[2].Remaining)
+	assert.Equal(t, time.Minute, results[2].Reset)
+	assert.False(t, results[3].Allowed)
+	assert.Equal(t, 20*time.Second, results[3].RetryAfter)
+	assert.Equal(t, start.Add(time.Minute), b.fullAt(limit))
+
+	// Act - a token is back 20 seconds later, but not half of one after 10
+	refused := b.take(limit, start.Add(10*time.Second))
+	allowed := b.take(limit, start.Add(20*time.Second))
+
+	// Assert
+	assert.False(t, refused.Allowed)
+	assert.Equal(t, 10*time.Second, refused.RetryAfter)
+	assert.True(t, allowed.Allowed)
+	assert.Equal(t, 0, allowed.Remaining)
+}
+
+func TestBucket_RefillsUpToTheLimit(t *testing.T) {
+	// Arrange
+	limit := Limit{Requests: 3, Per: time.Minute}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := bucket{tokens: 0, refilled: start}
+
+	// Act
+	result := b.take(limit, start.Add(time.Hour))
+
+	// Assert
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining, "an idle hour refills the bucket, not beyond it")
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("Keeps a bucket per key", func(t *testing.T) {
+		// Arrange
+		store := NewMemoryStore()
+		limit := Limit{Requests: 1, Per: time.Minute}
+		ctx := context.Background()
+
+		// Act
+		first, _ := store.Take(ctx, "api:user:1", limit)
+		second, _ := store.Take(ctx, "api:user:1", limit)
+		other, _ := store.Take(ctx, "api:user:2", limit)
+
+		// Assert
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+		assert.True(t, other.Allowed)
+	})
+
+	t.Run("Forgets buckets that have refilled", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		store := NewMemoryStore()
+		store.now = func() time.Time { return now }
+		ctx := context.Background()
+		_, err := store.Take(ctx, "auth:ip:10.0.0.1", Limit{Requests: 10, Per: time.Minute})
+		require.NoError(t, err)
+		_, err = store.Take(ctx, "auth:ip:10.0.0.2", Limit{Requests: 10, Per: time.Hour})
+		require.NoError(t, err)
+
+		// Act
+		now = now.Add(2 * time.Minute)
+		_, err = store.Take(ctx, "auth:ip:10.0.0.3", Limit{Requests: 10, Per: time.Minute})
+		require.NoError(t, err)
+
+		// Assert
+		assert.NotContains(t, store.buckets, "auth:ip:10.0.0.1")
+		assert.Contains(t, store.buckets, "auth:ip:10.0.0.2", "a slowly refilling bucket is kept")
+		assert.Contains(t, store.buckets, "auth:ip:10.0.0.3")
+	})
+}
@@ -1,22 +1,27 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AccountRepository interface {
-	Create(account *models.Account) error
-	FindByID(id uint) (*models.Account, error)
-	FindByUserID(userID uint) ([]models.Account, error)
-	FindByAccountNumber(accountNumber string) (*models.Account, error)
-	FindAll() ([]models.Account, error)
-	Update(account *models.Account) error
-	Delete(id uint) error
-	UpdateBalance(id uint, amount float64) error
-	FindByIDWithLock(id uint) (*models.Account, GormTx, error)
+	Create(ctx context.Context, account *models.Account) error
+	FindByID(ctx context.Context, id uint) (*models.Account, error)
+	FindByUserID(ctx context.Context, userID uint) ([]models.Account, error)
+	FindByAccountNumber(ctx context.Context, accountNumber string) (*models.Account, error)
+	FindAll(ctx context.Context) ([]models.Account, error)
+	Update(ctx context.Context, account *models.Account) error
+	Delete(ctx context.Context, id uint) error
+	UpdateBalance(ctx context.Context, id uint, amount float64) error
+	LockForTransfer(ctx context.Context, fromID, toID uint, fn func(from, to *models.Account, tx GormTx) error) error
+	SetFrozen(ctx context.Context, id uint, frozen bool, entry *models.AuditEntry) error
+	CreditOnce(ctx context.Context, id uint, amount float64, description string, date time.Time, entry *models.AuditEntry) (bool, error)
 }
 
 type accountRepository struct {
@@ -27,13 +32,13 @@ func NewAccountRepository(db *gorm.DB) AccountRepository {
 	return &accountRepository{db}
 }
 
-func (r *accountRepository) Create(account *models.Account) error {
-	return r.db.Create(account).Error
+func (r *accountRepository) Create(ctx context.Context, account *models.Account) error {
+	return r.db.WithContext(ctx).Create(account).Error
 }
 
-func (r *accountRepository) FindByID(id uint) (*models.Account, error) {
+func (r *accountRepository) FindByID(ctx context.Context, id uint) (*models.Account, error) {
 	var account models.Account
-	result := r.db.First(&account, id)
+	result := r.db.WithContext(ctx).First(&account, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("account not found")
@@ -43,17 +48,17 @@ func (r *accountRepository) FindByID(id uint) (*models.Account, error) {
 	return &account, nil
 }
 
-func (r *accountRepository) FindByUserID(userID uint) ([]models.Account, error) {
+func (r *accountRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
 	var accounts []models.Account
-	if err := r.db.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
 		return nil, err
 	}
 	return accounts, nil
 }
 
-func (r *accountRepository) FindByAccountNumber(accountNumber string) (*models.Account, error) {
+func (r *accountRepository) FindByAccountNumber(ctx context.Context, accountNumber string) (*models.Account, error) {
 	var account models.Account
-	result := r.db.Where("account_number = ?", accountNumber).First(&account)
+	result := r.db.WithContext(ctx).Where("account_number = ?", accountNumber).First(&account)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("account not found")
@@ -63,36 +68,107 @@ func (r *accountRepository) FindByAccountNumber(accountNumber string) (*models.A
 	return &account, nil
 }
 
-func (r *accountRepository) FindAll() ([]models.Account, error) {
+func (r *accountRepository) FindAll(ctx context.Context) ([]models.Account, error) {
 	var accounts []models.Account
-	if err := r.db.Find(&accounts).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&accounts).Error; err != nil {
 		return nil, err
 	}
 	return accounts, nil
 }
 
-func (r *accountRepository) Update(account *models.Account) error {
-	return r.db.Save(account).Error
+func (r *accountRepository) Update(ctx context.Context, account *models.Account) error {
+	return r.db.WithContext(ctx).Save(account).Error
 }
 
-func (r *accountRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Account{}, id).Error
+func (r *accountRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Account{}, id).Error
 }
 
-func (r *accountRepository) UpdateBalance(id uint, amount float64) error {
-	return r.db.Model(&models.Account{}).Where("id = ?", id).UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
+func (r *accountRepository) UpdateBalance(ctx context.Context, id uint, amount float64) error {
+	return r.db.WithContext(ctx).Model(&models.Account{}).Where("id = ?", id).UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
 }
 
-func (r *accountRepository) FindByIDWithLock(id uint) (*models.Account, GormTx, error) {
-	var account models.Account
-	tx := r.db.Begin()
-	result := tx.Set("gorm:query_option", "FOR UPDATE").First(&account, id)
-	if result.Error != nil {
-		tx.Rollback()
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, nil, errors.New("account not found")
+// LockForTransfer locks the rows of both accounts and runs fn in the same
+// transaction, which commits if fn returns nil and rolls back otherwise. The
+// rows are locked in ascending ID order, so two transfers between the same
+// accounts in opposite directions cannot deadlock. Queries made through the
+// transaction share the context, so a trace shows how long each one waited.
+func (r *accountRepository) LockForTransfer(ctx context.Context, fromID, toID uint, fn func(from, to *models.Account, tx GormTx) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lock := func(account *models.Account, id uint) error {
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(account, id).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("account not found")
+			}
+			return err
+		}
+
+		var from, to models.Account
+		first, firstID, second, secondID := &from, fromID, &to, toID
+		if toID < fromID {
+			first, firstID, second, secondID = &to, toID, &from, fromID
 		}
-		return nil, nil, result.Error
+		if err := lock(first, firstID); err != nil {
+			return err
+		}
+		if err := lock(second, secondID); err != nil {
+			return err
+		}
+		return fn(&from, &to, GormDBWrapper{tx})
+	})
+}
+
+// SetFrozen freezes or unfreezes the account. The mutators that take an audit
+// entry append it in the same transaction as the change; nil skips auditing.
+func (r *accountRepository) SetFrozen(ctx context.Context, id uint, frozen bool, entry *models.AuditEntry) error {
+	var frozenAt *time.Time
+	if frozen {
+		now := time.Now()
+		frozenAt = &now
 	}
-	return &account, GormDBWrapper{tx}, nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Account{}).Where("id = ?", id).UpdateColumn("frozen_at", frozenAt).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// CreditOnce deposits amount into the account, unless it already has a
+// transaction with the same description, and reports whether it did. The
+// account's row is locked, so concurrent runs credit it once.
+func (r *accountRepository) CreditOnce(ctx context.Context, id uint, amount float64, description string, date time.Time, entry *models.AuditEntry) (bool, error) {
+	credited := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, id).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Transaction{}).Where("account_id = ? AND description = ?", id, description).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		account.Balance += amount
+		if err := tx.Create(&models.Transaction{
+			AccountID:       id,
+			Amount:          amount,
+			Balance:         account.Balance,
+			Type:            models.Deposit,
+			Description:     description,
+			TransactionDate: date,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&account).UpdateColumn("balance", account.Balance).Error; err != nil {
+			return err
+		}
+		credited = true
+		return appendAuditEntry(tx, entry)
+	})
+	return credited, err
 }
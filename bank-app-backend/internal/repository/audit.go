@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the Postgres advisory lock that serialises appends to the
+// audit log, so that every entry links to the one committed before it
+const auditChainLock = 0x61756469
+
+type AuditRepository interface {
+	AppendWithTx(ctx context.Context, entry *models.AuditEntry, tx GormTx) error
+	Find(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error)
+	FindAfter(ctx context.Context, id uint, limit int) ([]models.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+// AppendWithTx adds an entry to the audit log in the caller's transaction, so
+// it is only kept if the audited change is committed
+func (r *auditRepository) AppendWithTx(ctx context.Context, entry *models.AuditEntry, tx GormTx) error {
+	wrapper, ok := tx.(GormDBWrapper)
+	if !ok {
+		return errors.New("audit entries can only be appended in a database transaction")
+	}
+	return appendAuditEntry(wrapper.DB, entry)
+}
+
+// Find returns the entries matching the filter, newest first
+func (r *auditRepository) Find(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditEntry{})
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Order("id desc").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindAfter returns up to limit entries with an ID above id, in chain order
+func (r *auditRepository) FindAfter(ctx context.Context, id uint, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	if err := r.db.WithContext(ctx).Where("id > ?", id).Order("id").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// appendAuditEntry links an entry to the newest one and stores it. It must run
+// inside the transaction that makes the audited change. A nil entry is ignored.
+func appendAuditEntry(tx *gorm.DB, entry *models.AuditEntry) error {
+	if entry == nil {
+		return nil
+	}
+
+	// Held until the transaction ends, so IDs are assigned in chain order
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+		return err
+	}
+
+	var last models.AuditEntry
+	if err := tx.Select("hash").Order("id desc").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+
+	// Postgres keeps microseconds, so the stored time hashes the same when read back
+	entry.PrevHash = last.Hash
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	return tx.Create(entry).Error
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type EmailTokenRepository interface {
+	Create(ctx context.Context, token *models.EmailToken) error
+	FindByHash(ctx context.Context, tokenHash string, purpose models.EmailTokenPurpose) (*models.EmailToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint, purpose models.EmailTokenPurpose) error
+}
+
+type emailTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailTokenRepository(db *gorm.DB) EmailTokenRepository {
+	return &emailTokenRepository{db}
+}
+
+func (r *emailTokenRepository) Create(ctx context.Context, token *models.EmailToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *emailTokenRepository) FindByHash(ctx context.Context, tokenHash string, purpose models.EmailTokenPurpose) (*models.EmailToken, error) {
+	var token models.EmailToken
+	result := r.db.WithContext(ctx).Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token not found")
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes a token. It returns false when the token had already been
+// used, so concurrent requests cannot both redeem it.
+func (r *emailTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.EmailToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser consumes every outstanding token of the given purpose
+func (r *emailTokenRepository) InvalidateForUser(ctx context.Context, userID uint, purpose models.EmailTokenPurpose) error {
+	return r.db.WithContext(ctx).Model(&models.EmailToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		UpdateColumn("used_at", time.Now()).Error
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrKYCProfileNotFound is returned when a user has not started a KYC profile
+	ErrKYCProfileNotFound = errors.New("KYC profile not found")
+	// ErrKYCNotPending is returned when reviewing a profile that is not waiting for review
+	ErrKYCNotPending = errors.New("KYC profile is not pending review")
+)
+
+type KYCRepository interface {
+	FindByUserID(ctx context.Context, userID uint) (*models.KYCProfile, error)
+	LockWithTx(ctx context.Context, userID uint, tx GormTx) (*models.KYCProfile, error)
+	FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error)
+	Save(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error
+	Review(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error
+	AddDocument(ctx context.Context, document *models.KYCDocument) error
+	FindDocument(ctx context.Context, id uint) (*models.KYCDocument, error)
+}
+
+type kycRepository struct {
+	db *gorm.DB
+}
+
+func NewKYCRepository(db *gorm.DB) KYCRepository {
+	return &kycRepository{db}
+}
+
+// FindByUserID returns the user's profile with its documents
+func (r *kycRepository) FindByUserID(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	var profile models.KYCProfile
+	result := r.db.WithContext(ctx).Preload("Documents").Where("user_id = ?", userID).First(&profile)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCProfileNotFound
+		}
+		return nil, result.Error
+	}
+	return &profile, nil
+}
+
+// LockWithTx returns the user's profile, without its documents, locked until
+// the caller's transaction ends. Transfers lock it so that each user's are
+// checked against their limits one at a time.
+func (r *kycRepository) LockWithTx(ctx context.Context, userID uint, tx GormTx) (*models.KYCProfile, error) {
+	wrapper, ok := tx.(GormDBWrapper)
+	if !ok {
+		return nil, errors.New("profiles can only be locked with a database transaction")
+	}
+
+	var profile models.KYCProfile
+	result := wrapper.DB.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&profile)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrKYCProfileNotFound
+		}
+		return nil, result.Error
+	}
+	return &profile, nil
+}
+
+// FindByStatus returns profiles oldest submission first, so the queue is
+// worked in order. An empty status returns profiles in every status.
+func (r *kycRepository) FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error) {
+	query := r.db.WithContext(ctx).Preload("Documents").Order("submitted_at, id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var profiles []models.KYCProfile
+	if err := query.Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// Save creates or updates a profile, without its documents, together with
+// its audit entry
+func (r *kycRepository) Save(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Documents").Save(profile).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// Review stores an admin's decision on a pending profile. Only a profile
+// that is still pending is updated, so two admins cannot both decide it.
+func (r *kycRepository) Review(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.KYCProfile{}).
+			Where("id = ? AND status = ?", profile.ID, models.KYCPending).
+			Updates(map[string]interface{}{
+				"status":         profile.Status,
+				"level":          profile.Level,
+				"review_note":    profile.ReviewNote,
+				"reviewed_by_id": profile.ReviewedByID,
+				"reviewed_at":    profile.ReviewedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrKYCNotPending
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *kycRepository) AddDocument(ctx context.Context, document *models.KYCDocument) error {
+	return r.db.WithContext(ctx).Create(document).Error
+}
+
+func (r *kycRepository) FindDocument(ctx context.Context, id uint) (*models.KYCDocument, error) {
+	var document models.KYCDocument
+	result := r.db.WithContext(ctx).First(&document, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("KYC document not found")
+		}
+		return nil, result.Error
+	}
+	return &document, nil
+}
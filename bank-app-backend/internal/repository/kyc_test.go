@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestKYCRepository_LockWithTx(t *testing.T) {
+	// Arrange - a dry run builds the SQL without a database; a callback after
+	// the query records what would have been run
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	pii.Register(pii.NewDevelopmentKeyring())
+	var queries []string
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:query", func(db *gorm.DB) {
+		queries = append(queries, db.Statement.SQL.String())
+	}))
+	tx := GormDBWrapper{db}
+
+	// Act - the dry run finds no profile, which does not matter here
+	NewKYCRepository(db).LockWithTx(context.Background(), 1, tx)
+
+	// Assert - the profile's row is locked, so each user's transfers are
+	// checked one at a time
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0], `FROM "kyc_profiles" WHERE user_id = $1`)
+	assert.Contains(t, queries[0], "FOR UPDATE")
+}
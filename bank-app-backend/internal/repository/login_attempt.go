@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *models.LoginAttempt, entry *models.AuditEntry) error
+	CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error)
+	FindByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginAttempt, error)
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db}
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// CountFailuresByIP counts the failed attempts made from an address since the given time
+func (r *loginAttemptRepository) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("ip_address = ? AND success = ? AND created_at >= ?", ipAddress, false, since).
+		Count(&count).Error
+	return count, err
+}
+
+// FindByUserID returns the most recent attempts against a user's account, newest first
+func (r *loginAttemptRepository) FindByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").Limit(limit).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
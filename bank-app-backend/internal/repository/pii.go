@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// The stored* types read a table without the encrypted serializer, so the
+// stored values and the key versions they were encrypted with can be seen
+
+type storedUser struct {
+	ID              uint
+	Email           string
+	EmailIndex      string
+	FirstName       string
+	LastName        string
+	TwoFactorSecret string
+}
+
+func (storedUser) TableName() string {
+	return "users"
+}
+
+type storedKYCProfile struct {
+	ID           uint
+	DateOfBirth  string
+	AddressLine1 string
+	AddressLine2 string
+	City         string
+	PostalCode   string
+	IDNumber     string
+}
+
+func (storedKYCProfile) TableName() string {
+	return "kyc_profiles"
+}
+
+type storedKYCDocument struct {
+	ID         uint
+	FileName   string
+	StorageKey string
+}
+
+func (storedKYCDocument) TableName() string {
+	return "kyc_documents"
+}
+
+type storedScreeningHit struct {
+	ID   uint
+	Name string
+}
+
+func (storedScreeningHit) TableName() string {
+	return "screening_hits"
+}
+
+// ReencryptUsers rewrites encrypted user fields that are still plaintext or
+// were encrypted with an older key, and fills in missing email indexes. Soft
+// deleted users are included. It returns the number of users rewritten.
+func ReencryptUsers(ctx context.Context, db *gorm.DB, keyring *pii.Keyring, batchSize int) (int, error) {
+	rewritten := 0
+	var users []storedUser
+	result := db.WithContext(ctx).FindInBatches(&users, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			stored := map[string]string{
+				"email":             user.Email,
+				"first_name":        user.FirstName,
+				"last_name":         user.LastName,
+				"two_factor_secret": user.TwoFactorSecret,
+			}
+			columns, err := reencryptedColumns(stored, keyring)
+			if err != nil {
+				return err
+			}
+
+			email, err := keyring.Decrypt(user.Email)
+			if err != nil {
+				return err
+			}
+			if index := keyring.EmailIndex(email); index != user.EmailIndex {
+				columns["email_index"] = index
+			}
+
+			n, err := rewriteRow(ctx, db, "users", user.ID, stored, columns)
+			if err != nil {
+				return err
+			}
+			rewritten += n
+		}
+		return nil
+	})
+	return rewritten, result.Error
+}
+
+// ReencryptKYCProfiles moves the personal details of KYC profiles to the
+// current key. It returns the number of profiles rewritten.
+func ReencryptKYCProfiles(ctx context.Context, db *gorm.DB, keyring *pii.Keyring, batchSize int) (int, error) {
+	rewritten := 0
+	var profiles []storedKYCProfile
+	result := db.WithContext(ctx).FindInBatches(&profiles, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, profile := range profiles {
+			stored := map[string]string{
+				"date_of_birth": profile.DateOfBirth,
+				"address_line1": profile.AddressLine1,
+				"address_line2": profile.AddressLine2,
+				"city":          profile.City,
+				"postal_code":   profile.PostalCode,
+				"id_number":     profile.IDNumber,
+			}
+			columns, err := reencryptedColumns(stored, keyring)
+			if err != nil {
+				return err
+			}
+
+			n, err := rewriteRow(ctx, db, "kyc_profiles", profile.ID, stored, columns)
+			if err != nil {
+				return err
+			}
+			rewritten += n
+		}
+		return nil
+	})
+	return rewritten, result.Error
+}
+
+// ReencryptKYCDocuments moves KYC document file names, and the documents
+// themselves, to the current key. A document is written to documents under a
+// new key before its row points to it, and the old file is deleted after. It
+// returns the number of documents rewritten.
+func ReencryptKYCDocuments(ctx context.Context, db *gorm.DB, keyring *pii.Keyring, documents storage.Store, batchSize int) (int, error) {
+	rewritten := 0
+	var rows []storedKYCDocument
+	result := db.WithContext(ctx).FindInBatches(&rows, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, row := range rows {
+			stored := map[string]string{
+				"file_name":   row.FileName,
+				"storage_key": row.StorageKey,
+			}
+			columns, err := reencryptedColumns(map[string]string{"file_name": row.FileName}, keyring)
+			if err != nil {
+				return err
+			}
+			key, err := reencryptDocument(documents, row.StorageKey, keyring)
+			if err != nil {
+				return fmt.Errorf("KYC document %d: %w", row.ID, err)
+			}
+			if key != "" {
+				columns["storage_key"] = key
+			}
+
+			n, err := rewriteRow(ctx, db, "kyc_documents", row.ID, stored, columns)
+			if err != nil || n == 0 {
+				// The row was not moved to the new file
+				if key != "" {
+					documents.Delete(key)
+				}
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if key != "" {
+				if err := documents.Delete(row.StorageKey); err != nil {
+					return fmt.Errorf("KYC document %d: %w", row.ID, err)
+				}
+			}
+			rewritten += n
+		}
+		return nil
+	})
+	return rewritten, result.Error
+}
+
+// ReencryptScreeningHits moves the names of screening hits to the current
+// key. Their blind indexes do not depend on the key. It returns the number of
+// hits rewritten.
+func ReencryptScreeningHits(ctx context.Context, db *gorm.DB, keyring *pii.Keyring, batchSize int) (int, error) {
+	rewritten := 0
+	var hits []storedScreeningHit
+	result := db.WithContext(ctx).FindInBatches(&hits, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, hit := range hits {
+			stored := map[string]string{"name": hit.Name}
+			columns, err := reencryptedColumns(stored, keyring)
+			if err != nil {
+				return err
+			}
+
+			n, err := rewriteRow(ctx, db, "screening_hits", hit.ID, stored, columns)
+			if err != nil {
+				return err
+			}
+			rewritten += n
+		}
+		return nil
+	})
+	return rewritten, result.Error
+}
+
+// reencryptedColumns returns the stored values that need moving to the
+// current key, encrypted with it
+func reencryptedColumns(stored map[string]string, keyring *pii.Keyring) (map[string]interface{}, error) {
+	columns := map[string]interface{}{}
+	for column, value := range stored {
+		if !keyring.NeedsReencryption(value) {
+			continue
+		}
+		plaintext, err := keyring.Decrypt(value)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		columns[column] = ciphertext
+	}
+	return columns, nil
+}
+
+// reencryptDocument saves the document stored under key again, encrypted with
+// the current key, and returns its new key. It returns an empty key when the
+// document is already encrypted with the current key.
+func reencryptDocument(documents storage.Store, key string, keyring *pii.Keyring) (string, error) {
+	ciphertext, err := documents.Load(key)
+	if err != nil {
+		return "", err
+	}
+	if !keyring.NeedsReencryption(string(ciphertext)) {
+		return "", nil
+	}
+	data, err := keyring.Decrypt(string(ciphertext))
+	if err != nil {
+		return "", err
+	}
+	reencrypted, err := keyring.Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return documents.Save([]byte(reencrypted))
+}
+
+// rewriteRow updates a row's columns, unless any of the stored values it was
+// read with has changed since. It returns the number of rows updated.
+func rewriteRow(ctx context.Context, db *gorm.DB, table string, id uint, stored map[string]string, columns map[string]interface{}) (int, error) {
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	query := db.WithContext(ctx).Table(table).Where("id = ?", id)
+	for column, value := range stored {
+		query = query.Where(fmt.Sprintf("COALESCE(%s, '') = ?", column), value)
+	}
+	result := query.UpdateColumns(columns)
+	return int(result.RowsAffected), result.Error
+}
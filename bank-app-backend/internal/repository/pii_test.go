@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestReencryptDocument(t *testing.T) {
+	// Arrange - a document encrypted under key 1, then a second key added
+	key1, key2, indexKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{9}, 32)
+	keyring, err := pii.NewKeyring(map[int][]byte{1: key1}, indexKey)
+	require.NoError(t, err)
+	rotated, err := pii.NewKeyring(map[int][]byte{1: key1, 2: key2}, indexKey)
+	require.NoError(t, err)
+
+	documents := storage.NewLocalStore(filepath.Join(t.TempDir(), "documents"))
+	ciphertext, err := keyring.Encrypt("%PDF-1.4")
+	require.NoError(t, err)
+	oldKey, err := documents.Save([]byte(ciphertext))
+	require.NoError(t, err)
+
+	// Act
+	newKey, err := reencryptDocument(documents, oldKey, rotated)
+
+	// Assert - the document is saved again under the new key, and the old
+	// file is left for the caller to delete once the row points elsewhere
+	require.NoError(t, err)
+	require.NotEmpty(t, newKey)
+	assert.NotEqual(t, oldKey, newKey)
+	data, err := documents.Load(newKey)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "enc:v2:"))
+	plaintext, err := rotated.Decrypt(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", plaintext)
+	_, err = documents.Load(oldKey)
+	assert.NoError(t, err)
+
+	// A document already under the current key is left alone
+	again, err := reencryptDocument(documents, newKey, rotated)
+	assert.NoError(t, err)
+	assert.Empty(t, again)
+
+	// A missing document is an error rather than skipped
+	require.NoError(t, documents.Delete(oldKey))
+	_, err = reencryptDocument(documents, oldKey, rotated)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRewriteRow_SkipsChangedRows(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	var statement *gorm.Statement
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:update", func(db *gorm.DB) {
+		statement = db.Statement
+	}))
+
+	// Act
+	_, err = rewriteRow(context.Background(), db, "screening_hits", 7, map[string]string{"name": "plain"}, map[string]interface{}{"name": "enc:v2:x"})
+
+	// Assert - the update only applies while the row still holds the value read
+	require.NoError(t, err)
+	require.NotNil(t, statement)
+	sql := statement.SQL.String()
+	assert.Contains(t, sql, `UPDATE "screening_hits" SET "name"=`)
+	assert.Contains(t, sql, "COALESCE(name, '') =")
+	assert.Equal(t, []interface{}{"enc:v2:x", uint(7), "plain"}, statement.Vars)
+}
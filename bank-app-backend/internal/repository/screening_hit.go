@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrHitAlreadyCleared is returned when clearing a hit that was already cleared
+var ErrHitAlreadyCleared = errors.New("screening hit is already cleared")
+
+type ScreeningHitRepository interface {
+	Create(ctx context.Context, hits []models.ScreeningHit) error
+	FindByID(ctx context.Context, id uint) (*models.ScreeningHit, error)
+	FindBySubject(ctx context.Context, subjectID *uint, emailIndex string) ([]models.ScreeningHit, error)
+	FindByStatus(ctx context.Context, statuses []string, limit, offset int) ([]models.ScreeningHit, error)
+	Clear(ctx context.Context, hit *models.ScreeningHit, entry *models.AuditEntry) error
+}
+
+type screeningHitRepository struct {
+	db *gorm.DB
+}
+
+func NewScreeningHitRepository(db *gorm.DB) ScreeningHitRepository {
+	return &screeningHitRepository{db}
+}
+
+func (r *screeningHitRepository) Create(ctx context.Context, hits []models.ScreeningHit) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&hits).Error
+}
+
+func (r *screeningHitRepository) FindByID(ctx context.Context, id uint) (*models.ScreeningHit, error) {
+	var hit models.ScreeningHit
+	result := r.db.WithContext(ctx).First(&hit, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("screening hit not found")
+		}
+		return nil, result.Error
+	}
+	return &hit, nil
+}
+
+// FindBySubject returns the hits for a user, together with the hits recorded
+// at a refused registration with the same email, before the user existed.
+// Registrations by other people with the same name are not included.
+func (r *screeningHitRepository) FindBySubject(ctx context.Context, subjectID *uint, emailIndex string) ([]models.ScreeningHit, error) {
+	query := r.db.WithContext(ctx).Where("subject_id IS NULL AND email_index = ?", emailIndex)
+	if subjectID != nil {
+		query = query.Or("subject_id = ?", *subjectID)
+	}
+
+	var hits []models.ScreeningHit
+	if err := query.Order("id").Find(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// FindByStatus returns hits oldest first. No statuses returns hits in every status.
+func (r *screeningHitRepository) FindByStatus(ctx context.Context, statuses []string, limit, offset int) ([]models.ScreeningHit, error) {
+	query := r.db.WithContext(ctx).Order("id")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var hits []models.ScreeningHit
+	if err := query.Find(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// Clear stores the clearance of a hit together with its audit entry. Only a
+// hit that is not cleared yet is updated, so two admins cannot both clear it.
+func (r *screeningHitRepository) Clear(ctx context.Context, hit *models.ScreeningHit, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ScreeningHit{}).
+			Where("id = ? AND status <> ?", hit.ID, models.HitCleared).
+			Updates(map[string]interface{}{
+				"status":        hit.Status,
+				"cleared_by_id": hit.ClearedByID,
+				"justification": hit.Justification,
+				"cleared_at":    hit.ClearedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrHitAlreadyCleared
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ServiceClientRepository interface {
+	Create(ctx context.Context, client *models.ServiceClient, entry *models.AuditEntry) error
+	FindByID(ctx context.Context, id uint) (*models.ServiceClient, error)
+	FindByClientID(ctx context.Context, clientID string) (*models.ServiceClient, error)
+	FindAll(ctx context.Context) ([]models.ServiceClient, error)
+	Revoke(ctx context.Context, id uint, entry *models.AuditEntry) error
+	RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error
+	FindUsageByClientID(ctx context.Context, id uint, limit int) ([]models.ServiceClientUsage, error)
+}
+
+type serviceClientRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceClientRepository(db *gorm.DB) ServiceClientRepository {
+	return &serviceClientRepository{db}
+}
+
+// Create stores a client. The audit entry's target ID is set to the new
+// client's ID before it is appended.
+func (r *serviceClientRepository) Create(ctx context.Context, client *models.ServiceClient, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(client).Error; err != nil {
+			return err
+		}
+		if entry != nil {
+			entry.TargetID = strconv.FormatUint(uint64(client.ID), 10)
+			entry.WithChange(nil, client.ToDTO())
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *serviceClientRepository) FindByID(ctx context.Context, id uint) (*models.ServiceClient, error) {
+	var client models.ServiceClient
+	if err := r.db.WithContext(ctx).First(&client, id).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *serviceClientRepository) FindByClientID(ctx context.Context, clientID string) (*models.ServiceClient, error) {
+	var client models.ServiceClient
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *serviceClientRepository) FindAll(ctx context.Context) ([]models.ServiceClient, error) {
+	var clients []models.ServiceClient
+	if err := r.db.WithContext(ctx).Order("id").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Revoke disables a client. Revoking an already revoked client keeps the
+// original revocation time.
+func (r *serviceClientRepository) Revoke(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ServiceClient{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			UpdateColumn("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Distinguish an unknown client from one that was already revoked
+			var client models.ServiceClient
+			return tx.First(&client, id).Error
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// RecordUsage stores a request made by a client and updates its last use
+func (r *serviceClientRepository) RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ServiceClient{}).
+			Where("id = ?", usage.ServiceClientID).
+			UpdateColumn("last_used_at", usage.CreatedAt).Error
+	})
+}
+
+// FindUsageByClientID returns the most recent requests made by a client, newest first
+func (r *serviceClientRepository) FindUsageByClientID(ctx context.Context, id uint, limit int) ([]models.ServiceClientUsage, error) {
+	var usage []models.ServiceClientUsage
+	if err := r.db.WithContext(ctx).Where("service_client_id = ?", id).Order("created_at desc").Limit(limit).Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
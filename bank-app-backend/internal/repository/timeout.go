@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const deadlineKey = "timeout:deadline"
+
+// TimeoutPlugin gives every statement its own deadline on top of the caller's
+// context, so a slow query fails instead of holding a connection. Reads and
+// writes have separate limits; zero leaves them unbounded. Statements read
+// through Rows are not bounded, as their rows are read after GORM returns.
+type TimeoutPlugin struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// deadline is the context a statement had before its deadline was added
+type deadline struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+func (TimeoutPlugin) Name() string {
+	return "timeout"
+}
+
+// Initialize registers a callback first and last in each of GORM's operations,
+// so the deadline also covers the transaction GORM wraps writes in
+func (p TimeoutPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("timeout:before_create", withDeadline(p.Write)),
+		callbacks.Create().After("*").Register("timeout:after_create", clearDeadline),
+		callbacks.Query().Before("*").Register("timeout:before_query", withDeadline(p.Read)),
+		callbacks.Query().After("*").Register("timeout:after_query", clearDeadline),
+		callbacks.Update().Before("*").Register("timeout:before_update", withDeadline(p.Write)),
+		callbacks.Update().After("*").Register("timeout:after_update", clearDeadline),
+		callbacks.Delete().Before("*").Register("timeout:before_delete", withDeadline(p.Write)),
+		callbacks.Delete().After("*").Register("timeout:after_delete", clearDeadline),
+		callbacks.Raw().Before("*").Register("timeout:before_raw", withDeadline(p.Write)),
+		callbacks.Raw().After("*").Register("timeout:after_raw", clearDeadline),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func withDeadline(timeout time.Duration) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if timeout <= 0 {
+			return
+		}
+		parent := db.Statement.Context
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		db.Statement.Context = ctx
+		db.InstanceSet(deadlineKey, deadline{parent, cancel})
+	}
+}
+
+// clearDeadline restores the statement's context, as a chained query such as a
+// Count followed by a Find reuses the statement
+func clearDeadline(db *gorm.DB) {
+	value, _ := db.InstanceGet(deadlineKey)
+	d, ok := value.(deadline)
+	if !ok {
+		return
+	}
+	d.cancel()
+	db.Statement.Context = d.parent
+	db.InstanceSet(deadlineKey, nil)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestTimeoutPlugin(t *testing.T) {
+	// Arrange - a dry run builds the SQL without a database; a callback next to
+	// the statement records how long it was given
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(TimeoutPlugin{Read: time.Second, Write: time.Minute}))
+	remaining := map[string]time.Duration{}
+	record := func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			if deadline, ok := db.Statement.Context.Deadline(); ok {
+				remaining[operation] = time.Until(deadline)
+			}
+		}
+	}
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:query", record("query")))
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:create", record("create")))
+
+	// Act
+	query := db.WithContext(context.Background()).Model(&widget{})
+	query.Find(&[]widget{})
+	db.Create(&widget{Name: "gear"})
+
+	// Assert
+	assert.InDelta(t, time.Second, remaining["query"], float64(100*time.Millisecond))
+	assert.InDelta(t, time.Minute, remaining["create"], float64(100*time.Millisecond))
+	_, bounded := query.Statement.Context.Deadline()
+	assert.False(t, bounded, "the statement's own context is restored afterwards")
+}
+
+func TestTimeoutPlugin_CallerDeadline(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(TimeoutPlugin{Read: time.Minute}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var cancelled bool
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:query", func(db *gorm.DB) {
+		cancelled = db.Statement.Context.Err() != nil
+	}))
+
+	// Act - a client that went away cancels the request's context
+	db.WithContext(ctx).Find(&[]widget{})
+
+	// Assert
+	assert.True(t, cancelled)
+}
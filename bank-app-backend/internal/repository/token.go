@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, entry *models.AuditEntry) error
+	RevokeAllForUser(ctx context.Context, userID uint, entry *models.AuditEntry) error
+	RevokeOtherSessions(ctx context.Context, userID uint, keepFamilyID string, entry *models.AuditEntry) error
+	RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error)
+	DeleteExpired(ctx context.Context) error
+	CreateSession(ctx context.Context, session *models.Session) error
+	FindSessionByID(ctx context.Context, id uint) (*models.Session, error)
+	FindActiveSessionsByUserID(ctx context.Context, userID uint) ([]models.Session, error)
+	HasSessions(ctx context.Context, userID uint) (bool, error)
+	HasSessionWithDevice(ctx context.Context, userID uint, deviceHash string) (bool, error)
+	TouchSession(ctx context.Context, familyID string, seenAt time.Time) error
+	ExtendSession(ctx context.Context, familyID string, seenAt, expiresAt time.Time) error
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed flags a refresh token as rotated. It returns false when
+// the token had already been used, which callers must treat as token reuse.
+func (r *tokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes the refresh tokens of a family and ends its session
+func (r *tokenRepository) RevokeFamily(ctx context.Context, familyID string, entry *models.AuditEntry) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			UpdateColumn("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			UpdateColumn("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *tokenRepository) RevokeAllForUser(ctx context.Context, userID uint, entry *models.AuditEntry) error {
+	return r.revokeForUser(ctx, userID, "", entry)
+}
+
+// RevokeOtherSessions revokes every session family of the user except the one
+// given, which is left signed in
+func (r *tokenRepository) RevokeOtherSessions(ctx context.Context, userID uint, keepFamilyID string, entry *models.AuditEntry) error {
+	return r.revokeForUser(ctx, userID, keepFamilyID, entry)
+}
+
+// revokeForUser revokes the user's session families other than keepFamilyID,
+// or all of them when it is empty
+func (r *tokenRepository) revokeForUser(ctx context.Context, userID uint, keepFamilyID string, entry *models.AuditEntry) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tokens := tx.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+		sessions := tx.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+		if keepFamilyID != "" {
+			tokens = tokens.Where("family_id <> ?", keepFamilyID)
+			sessions = sessions.Where("family_id <> ?", keepFamilyID)
+		}
+		if err := tokens.UpdateColumn("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := sessions.UpdateColumn("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsAccessTokenRevoked reports whether the access token itself was revoked or
+// the session family it was issued for has been revoked.
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if familyID == "" {
+		return false, nil
+	}
+	if err := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NOT NULL", familyID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes revoked access tokens and refresh tokens that can no
+// longer be presented.
+func (r *tokenRepository) DeleteExpired(ctx context.Context) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error
+}
+
+func (r *tokenRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *tokenRepository) FindSessionByID(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveSessionsByUserID returns the sessions that are neither revoked nor
+// expired, most recently used first
+func (r *tokenRepository) FindActiveSessionsByUserID(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// HasSessions reports whether the user has ever logged in. Ended sessions are
+// kept so that devices are remembered.
+func (r *tokenRepository) HasSessions(ctx context.Context, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).Where("user_id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// HasSessionWithDevice reports whether the user has ever logged in from the device
+func (r *tokenRepository) HasSessionWithDevice(ctx context.Context, userID uint, deviceHash string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND device_hash = ?", userID, deviceHash).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *tokenRepository) TouchSession(ctx context.Context, familyID string, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ? AND last_seen_at < ?", familyID, seenAt).
+		UpdateColumn("last_seen_at", seenAt).Error
+}
+
+// ExtendSession records a refresh, which moves the session's expiry to that of
+// the new refresh token
+func (r *tokenRepository) ExtendSession(ctx context.Context, familyID string, seenAt, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("family_id = ?", familyID).
+		UpdateColumns(map[string]interface{}{"last_seen_at": seenAt, "expires_at": expiresAt}).Error
+}
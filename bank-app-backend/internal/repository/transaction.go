@@ -1,20 +1,28 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type TransactionRepository interface {
-	Create(transaction *models.Transaction) error
-	FindByID(id uint) (*models.Transaction, error)
-	FindByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error)
-	FindAll(limit, offset int) ([]models.Transaction, error)
-	CountByAccountID(accountID uint) (int64, error)
-	CountAll() (int64, error)
-	CreateWithTx(transaction *models.Transaction, tx GormTx) error
+	Create(ctx context.Context, transaction *models.Transaction) error
+	FindByID(ctx context.Context, id uint) (*models.Transaction, error)
+	FindByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error)
+	FindAll(ctx context.Context, limit, offset int) ([]models.Transaction, error)
+	CountByAccountID(ctx context.Context, accountID uint) (int64, error)
+	CountAll(ctx context.Context) (int64, error)
+	CreateWithTx(ctx context.Context, transaction *models.Transaction, tx GormTx) error
+	LedgerTotals(ctx context.Context) ([]models.LedgerTotals, error)
+	CountTransfersFrom(ctx context.Context, accountID uint, since time.Time) (int64, error)
+	AverageTransferFrom(ctx context.Context, accountID uint) (float64, int64, error)
+	HasTransferred(ctx context.Context, fromAccountID, toAccountID uint) (bool, error)
+	SumTransfersByUser(ctx context.Context, userID uint, since time.Time) (float64, error)
+	SumTransfersByUserWithTx(ctx context.Context, userID uint, since time.Time, tx GormTx) (float64, error)
 }
 
 type transactionRepository struct {
@@ -25,13 +33,13 @@ func NewTransactionRepository(db *gorm.DB) TransactionRepository {
 	return &transactionRepository{db}
 }
 
-func (r *transactionRepository) Create(transaction *models.Transaction) error {
-	return r.db.Create(transaction).Error
+func (r *transactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	return r.db.WithContext(ctx).Create(transaction).Error
 }
 
-func (r *transactionRepository) FindByID(id uint) (*models.Transaction, error) {
+func (r *transactionRepository) FindByID(ctx context.Context, id uint) (*models.Transaction, error) {
 	var transaction models.Transaction
-	result := r.db.First(&transaction, id)
+	result := r.db.WithContext(ctx).First(&transaction, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("transaction not found")
@@ -41,9 +49,9 @@ func (r *transactionRepository) FindByID(id uint) (*models.Transaction, error) {
 	return &transaction, nil
 }
 
-func (r *transactionRepository) FindByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error) {
+func (r *transactionRepository) FindByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	query := r.db.Where("account_id = ?", accountID).Order("transaction_date DESC")
+	query := r.db.WithContext(ctx).Where("account_id = ?", accountID).Order("transaction_date DESC")
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -60,9 +68,9 @@ func (r *transactionRepository) FindByAccountID(accountID uint, limit, offset in
 	return transactions, nil
 }
 
-func (r *transactionRepository) FindAll(limit, offset int) ([]models.Transaction, error) {
+func (r *transactionRepository) FindAll(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
 	var transactions []models.Transaction
-	query := r.db.Order("transaction_date DESC")
+	query := r.db.WithContext(ctx).Order("transaction_date DESC")
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -79,23 +87,111 @@ func (r *transactionRepository) FindAll(limit, offset int) ([]models.Transaction
 	return transactions, nil
 }
 
-func (r *transactionRepository) CountByAccountID(accountID uint) (int64, error) {
+func (r *transactionRepository) CountByAccountID(ctx context.Context, accountID uint) (int64, error) {
 	var count int64
-	if err := r.db.Model(&models.Transaction{}).Where("account_id = ?", accountID).Count(&count).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Transaction{}).Where("account_id = ?", accountID).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *transactionRepository) CountAll() (int64, error) {
+func (r *transactionRepository) CountAll(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.Model(&models.Transaction{}).Count(&count).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Transaction{}).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *transactionRepository) CreateWithTx(transaction *models.Transaction, tx GormTx) error {
+func (r *transactionRepository) CreateWithTx(ctx context.Context, transaction *models.Transaction, tx GormTx) error {
 	result := tx.Create(transaction)
 	return result.Error()
 }
+
+// LedgerTotals adds up the transactions of every account that has any. The
+// withdrawal side of a transfer is the one recorded on its source account.
+func (r *transactionRepository) LedgerTotals(ctx context.Context) ([]models.LedgerTotals, error) {
+	var totals []models.LedgerTotals
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select(`account_id,
+			SUM(CASE WHEN type = ? OR (type = ? AND source_account_id = account_id) THEN -amount ELSE amount END) AS total,
+			(SELECT l.balance FROM transactions l WHERE l.account_id = transactions.account_id AND l.deleted_at IS NULL ORDER BY l.id DESC LIMIT 1) AS last_balance,
+			COUNT(*) AS entries`, models.Withdrawal, models.Transfer).
+		Group("account_id").
+		Order("account_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// outgoingTransfers selects the withdrawal side of transfers out of an account
+func (r *transactionRepository) outgoingTransfers(ctx context.Context, accountID uint) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("account_id = ? AND source_account_id = ? AND type = ?", accountID, accountID, models.Transfer)
+}
+
+// CountTransfersFrom counts transfers out of an account since the given time
+func (r *transactionRepository) CountTransfersFrom(ctx context.Context, accountID uint, since time.Time) (int64, error) {
+	var count int64
+	if err := r.outgoingTransfers(ctx, accountID).Where("transaction_date >= ?", since).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// AverageTransferFrom returns the average amount of the transfers out of an
+// account and how many there were
+func (r *transactionRepository) AverageTransferFrom(ctx context.Context, accountID uint) (float64, int64, error) {
+	var stats struct {
+		Average float64
+		Count   int64
+	}
+	err := r.outgoingTransfers(ctx, accountID).
+		Select("COALESCE(AVG(amount), 0) AS average, COUNT(*) AS count").
+		Scan(&stats).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return stats.Average, stats.Count, nil
+}
+
+// HasTransferred reports whether an account has sent money to another before
+func (r *transactionRepository) HasTransferred(ctx context.Context, fromAccountID, toAccountID uint) (bool, error) {
+	var count int64
+	if err := r.outgoingTransfers(ctx, fromAccountID).Where("target_account_id = ?", toAccountID).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SumTransfersByUser totals the transfers out of all of a user's accounts
+// since the given time
+func (r *transactionRepository) SumTransfersByUser(ctx context.Context, userID uint, since time.Time) (float64, error) {
+	return sumTransfersByUser(r.db.WithContext(ctx), userID, since)
+}
+
+// SumTransfersByUserWithTx adds up the transfers in the caller's transaction,
+// which sees the transfers committed while it waited for its locks
+func (r *transactionRepository) SumTransfersByUserWithTx(ctx context.Context, userID uint, since time.Time, tx GormTx) (float64, error) {
+	wrapper, ok := tx.(GormDBWrapper)
+	if !ok {
+		return 0, errors.New("transfers can only be summed with a database transaction")
+	}
+	return sumTransfersByUser(wrapper.DB.WithContext(ctx), userID, since)
+}
+
+func sumTransfersByUser(db *gorm.DB, userID uint, since time.Time) (float64, error) {
+	var total float64
+	err := db.Model(&models.Transaction{}).
+		Joins("JOIN accounts ON accounts.id = transactions.account_id").
+		Where("accounts.user_id = ? AND transactions.source_account_id = transactions.account_id AND transactions.type = ?", userID, models.Transfer).
+		Where("transactions.transaction_date >= ?", since).
+		Select("COALESCE(SUM(transactions.amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrReviewNotPending is returned when resolving a review that was already resolved
+var ErrReviewNotPending = errors.New("transfer review is not pending")
+
+type TransferReviewRepository interface {
+	Create(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error
+	FindByID(ctx context.Context, id uint) (*models.TransferReview, error)
+	FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error)
+	Resolve(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error
+	ResolveWithTx(ctx context.Context, review *models.TransferReview, tx GormTx) error
+}
+
+type transferReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferReviewRepository(db *gorm.DB) TransferReviewRepository {
+	return &transferReviewRepository{db}
+}
+
+// Create stores a review. The audit entry's target ID is set to the new
+// review's ID before it is appended.
+func (r *transferReviewRepository) Create(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		if entry != nil {
+			entry.TargetID = strconv.FormatUint(uint64(review.ID), 10)
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *transferReviewRepository) FindByID(ctx context.Context, id uint) (*models.TransferReview, error) {
+	var review models.TransferReview
+	result := r.db.WithContext(ctx).First(&review, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transfer review not found")
+		}
+		return nil, result.Error
+	}
+	return &review, nil
+}
+
+// FindByStatus returns reviews oldest first, so the queue is worked in order.
+// An empty status returns reviews in every status.
+func (r *transferReviewRepository) FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error) {
+	query := r.db.WithContext(ctx).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var reviews []models.TransferReview
+	if err := query.Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// Resolve stores the decision on a pending review together with its audit entry
+func (r *transferReviewRepository) Resolve(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := resolveReview(tx, review); err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// ResolveWithTx stores the decision on a pending review in the caller's
+// transaction, so an approval is only kept if its transfer is committed
+func (r *transferReviewRepository) ResolveWithTx(ctx context.Context, review *models.TransferReview, tx GormTx) error {
+	wrapper, ok := tx.(GormDBWrapper)
+	if !ok {
+		return errors.New("reviews can only be resolved with a database transaction")
+	}
+	return resolveReview(wrapper.DB, review)
+}
+
+// resolveReview only updates a review that is still pending, so two admins
+// cannot both act on it
+func resolveReview(tx *gorm.DB, review *models.TransferReview) error {
+	result := tx.Model(&models.TransferReview{}).
+		Where("id = ? AND status = ?", review.ID, models.ReviewPending).
+		Updates(map[string]interface{}{
+			"status":         review.Status,
+			"reviewed_by_id": review.ReviewedByID,
+			"review_note":    review.ReviewNote,
+			"reviewed_at":    review.ReviewedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReviewNotPending
+	}
+	return nil
+}
@@ -1,19 +1,34 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByID(id uint) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	FindAll() ([]models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
+	Create(ctx context.Context, user *models.User) error
+	CreateAudited(ctx context.Context, user *models.User, entry *models.AuditEntry) error
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindAll(ctx context.Context) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdatePassword(ctx context.Context, id uint, passwordHash string, entry *models.AuditEntry) error
+	Delete(ctx context.Context, id uint) error
+	MarkEmailVerified(ctx context.Context, id uint) error
+	SetTwoFactorSecret(ctx context.Context, id uint, secret string) error
+	EnableTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error
+	ResetTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error
+	UseTwoFactorStep(ctx context.Context, id uint, step int64) (bool, error)
+	IncrementFailedLogins(ctx context.Context, id uint, windowStart time.Time) (int, error)
+	Lock(ctx context.Context, id uint, until time.Time) error
+	Disable(ctx context.Context, id uint, entry *models.AuditEntry) error
+	ResetFailedLogins(ctx context.Context, id uint, entry *models.AuditEntry) error
 }
 
 type userRepository struct {
@@ -24,13 +39,27 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
+// CreateAudited stores a new user and appends the entry, targeted at the new
+// user, in the same transaction
+func (r *userRepository) CreateAudited(ctx context.Context, user *models.User, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if entry != nil {
+			entry.TargetID = fmt.Sprint(user.ID)
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	result := r.db.First(&user, id)
+	result := r.db.WithContext(ctx).First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -40,9 +69,16 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+// FindByEmail looks the user up by the blind index, as the email column is
+// encrypted. Emails match regardless of case and surrounding spaces.
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	index, err := pii.EmailIndex(email)
+	if err != nil {
+		return nil, err
+	}
+
 	var user models.User
-	result := r.db.Where("email = ?", email).First(&user)
+	result := r.db.WithContext(ctx).Where("email_index = ?", index).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -52,18 +88,130 @@ func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) FindAll() ([]models.User, error) {
+func (r *userRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := r.db.Find(&users).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+// Update saves the user's profile. The password is only changed by UpdatePassword.
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Omit("Password").Save(user).Error
+}
+
+// UpdatePassword stores a new password hash. The mutators that take an audit
+// entry append it in the same transaction as the change; nil skips auditing.
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).UpdateColumn("password", passwordHash).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("email_verified_at", time.Now()).Error
+}
+
+// SetTwoFactorSecret stores the TOTP secret encrypted. Single column updates
+// bypass the encrypted serializer, so the secret is encrypted here.
+func (r *userRepository) SetTwoFactorSecret(ctx context.Context, id uint, secret string) error {
+	encrypted, err := pii.Encrypt(secret)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("two_factor_secret", encrypted).Error
+}
+
+func (r *userRepository) EnableTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).UpdateColumn("two_factor_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// ResetTwoFactor clears the TOTP secret and deletes the recovery codes, so the
+// user can log in with a password alone and enroll again
+func (r *userRepository) ResetTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"two_factor_secret":     "",
+			"two_factor_enabled_at": nil,
+			"two_factor_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// UseTwoFactorStep records a TOTP time step as used. It returns false when a
+// code for the same or a later step was already accepted.
+func (r *userRepository) UseTwoFactorStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", id, step).
+		UpdateColumn("two_factor_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// IncrementFailedLogins counts a failed login and returns the new count. The
+// count starts over when the previous failure happened before windowStart.
+func (r *userRepository) IncrementFailedLogins(ctx context.Context, id uint, windowStart time.Time) (int, error) {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"failed_login_count":   gorm.Expr("CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_login_count + 1 END", windowStart),
+		"last_failed_login_at": time.Now(),
+	}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var user models.User
+	if err := r.db.WithContext(ctx).Select("failed_login_count").First(&user, id).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLoginCount, nil
+}
+
+func (r *userRepository) Lock(ctx context.Context, id uint, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("locked_until", until).Error
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+// ResetFailedLogins clears the failed login count and any lockout
+func (r *userRepository) ResetFailedLogins(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"failed_login_count":   0,
+			"last_failed_login_at": nil,
+			"locked_until":         nil,
+		}).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
+}
+
+// Disable stops the user from logging in. Disabling a disabled user keeps the
+// original time.
+func (r *userRepository) Disable(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ? AND disabled_at IS NULL", id).UpdateColumn("disabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return appendAuditEntry(tx, entry)
+	})
 }
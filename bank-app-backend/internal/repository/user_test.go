@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestUserRepository_UpdateKeepsPasswordHash(t *testing.T) {
+	// Arrange - a dry run builds the SQL without a database, which opening a
+	// transaction would need; a callback after the update records the
+	// statement that would have been run
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	pii.Register(pii.NewDevelopmentKeyring())
+	var statement *gorm.Statement
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:update", func(db *gorm.DB) {
+		statement = db.Statement
+	}))
+
+	user := &models.User{ID: 1, Email: "test@example.com", FirstName: "Test", LastName: "User"}
+	require.NoError(t, user.SetPassword("password123", bcrypt.MinCost))
+	hash := user.Password
+
+	// Act
+	err = NewUserRepository(db).Update(context.Background(), user)
+
+	// Assert - the password column is left out, so the stored hash is not
+	// overwritten, let alone hashed again
+	require.NoError(t, err)
+	require.NotNil(t, statement)
+	sql := statement.SQL.String()
+	assert.Contains(t, sql, `UPDATE "users" SET`)
+	assert.Contains(t, sql, `"first_name"`)
+	assert.NotContains(t, sql, `"password"`)
+	assert.NotContains(t, statement.Vars, hash)
+	assert.Equal(t, hash, user.Password)
+}
+
+func TestUserRepository_SetTwoFactorSecretEncrypts(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	keyring := pii.NewDevelopmentKeyring()
+	pii.Register(keyring)
+	var statement *gorm.Statement
+	require.NoError(t, db.Callback().Update().After("gorm:update").Register("test:update", func(db *gorm.DB) {
+		statement = db.Statement
+	}))
+	secret := "JBSWY3DPEHPK3PXP"
+
+	// Act
+	err = NewUserRepository(db).SetTwoFactorSecret(context.Background(), 1, secret)
+
+	// Assert - the secret is stored as ciphertext the keyring can open
+	require.NoError(t, err)
+	require.NotNil(t, statement)
+	assert.Contains(t, statement.SQL.String(), `"two_factor_secret"`)
+	require.NotEmpty(t, statement.Vars)
+	stored, ok := statement.Vars[0].(string)
+	require.True(t, ok)
+	assert.True(t, pii.IsEncrypted(stored))
+	plaintext, err := keyring.Decrypt(stored)
+	require.NoError(t, err)
+	assert.Equal(t, secret, plaintext)
+}
@@ -0,0 +1,182 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nullField is how OFAC list files mark an empty field
+const nullField = "-0-"
+
+// Entry - A sanctioned person or organisation on the watchlist
+type Entry struct {
+	ID      string   // Entry number on the list, e.g. the OFAC ent_num
+	Name    string   // Name as published
+	Type    string   // individual, vessel, aircraft or empty for an organisation
+	Program string   // Sanctions programs, e.g. SDGT
+	Aliases []string // Other names the entry is known by
+}
+
+// Names returns the entry's name followed by its aliases
+func (e *Entry) Names() []string {
+	return append([]string{e.Name}, e.Aliases...)
+}
+
+// List - Watchlist loaded from one or more local files
+type List struct {
+	entries []*Entry
+}
+
+// NewList builds a list from entries already in memory
+func NewList(entries []Entry) *List {
+	list := &List{}
+	for i := range entries {
+		entry := entries[i]
+		list.entries = append(list.entries, &entry)
+	}
+	return list
+}
+
+// LoadList reads watchlist files in the OFAC SDN formats. A .xml file is read
+// as sdn.xml. A .csv file is read as sdn.csv, or as alt.csv when its rows are
+// aliases, which are added to entries loaded from the files before it.
+func LoadList(paths ...string) (*List, error) {
+	list := &List{}
+	byID := map[string]*Entry{}
+	for _, path := range paths {
+		var err error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			err = list.loadCSV(path, byID)
+		case ".xml":
+			err = list.loadXML(path, byID)
+		default:
+			err = errors.New("unsupported file type, expected .csv or .xml")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("watchlist %s: %w", path, err)
+		}
+	}
+	return list, nil
+}
+
+// Len returns the number of entries on the list
+func (l *List) Len() int {
+	return len(l.entries)
+}
+
+// loadCSV reads sdn.csv rows (ent_num, SDN_Name, SDN_Type, Program, ...) and
+// alt.csv rows (ent_num, alt_num, alt_type, alt_name, ...)
+func (l *List) loadCSV(path string, byID map[string]*Entry) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i := range record {
+			record[i] = field(record[i])
+		}
+		// Skip short rows, a header row and the end-of-file marker OFAC appends
+		if len(record) < 4 || strings.EqualFold(record[0], "ent_num") || record[1] == "" {
+			continue
+		}
+
+		if isAliasType(record[2]) {
+			if entry, ok := byID[record[0]]; ok && record[3] != "" {
+				entry.Aliases = append(entry.Aliases, record[3])
+			}
+			continue
+		}
+		l.add(&Entry{ID: record[0], Name: record[1], Type: record[2], Program: record[3]}, byID)
+	}
+}
+
+// sdnXML - The parts of sdn.xml that are screened against
+type sdnXML struct {
+	Entries []struct {
+		UID       string   `xml:"uid"`
+		FirstName string   `xml:"firstName"`
+		LastName  string   `xml:"lastName"`
+		Type      string   `xml:"sdnType"`
+		Programs  []string `xml:"programList>program"`
+		Aliases   []struct {
+			FirstName string `xml:"firstName"`
+			LastName  string `xml:"lastName"`
+		} `xml:"akaList>aka"`
+	} `xml:"sdnEntry"`
+}
+
+func (l *List) loadXML(path string, byID map[string]*Entry) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc sdnXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for _, e := range doc.Entries {
+		entry := &Entry{
+			ID:      strings.TrimSpace(e.UID),
+			Name:    joinName(e.FirstName, e.LastName),
+			Type:    strings.ToLower(strings.TrimSpace(e.Type)),
+			Program: strings.Join(e.Programs, "; "),
+		}
+		if entry.Type == "entity" {
+			entry.Type = ""
+		}
+		for _, aka := range e.Aliases {
+			if name := joinName(aka.FirstName, aka.LastName); name != "" {
+				entry.Aliases = append(entry.Aliases, name)
+			}
+		}
+		if entry.ID != "" && entry.Name != "" {
+			l.add(entry, byID)
+		}
+	}
+	return nil
+}
+
+func (l *List) add(entry *Entry, byID map[string]*Entry) {
+	l.entries = append(l.entries, entry)
+	byID[entry.ID] = entry
+}
+
+func field(value string) string {
+	value = strings.TrimSpace(value)
+	if value == nullField {
+		return ""
+	}
+	return value
+}
+
+func isAliasType(value string) bool {
+	switch strings.ToLower(value) {
+	case "aka", "fka", "nka":
+		return true
+	}
+	return false
+}
+
+func joinName(first, last string) string {
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
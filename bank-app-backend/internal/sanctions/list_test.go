@@ -0,0 +1,85 @@
+package sanctions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadList_CSV(t *testing.T) {
+	// Arrange - rows as in the OFAC sdn.csv and alt.csv downloads
+	sdn := writeFile(t, "sdn.csv", `36,"AEROCARIBBEAN AIRLINES",-0- ,"CUBA",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- 
+9001,"VOLKOV, Ivan","individual","SDGT",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,"DOB 01 Jan 1970."
+`+"\x1a\n")
+	alt := writeFile(t, "alt.csv", `9001,501,"aka","VOLKOFF, Ivan",-0- 
+`)
+
+	// Act
+	list, err := LoadList(sdn, alt)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, list.Len())
+	matches := list.Screen("Ivan Volkoff", 0.99)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, "9001", matches[0].Entry.ID)
+		assert.Equal(t, "individual", matches[0].Entry.Type)
+		assert.Equal(t, "SDGT", matches[0].Entry.Program)
+		assert.Equal(t, "VOLKOFF, Ivan", matches[0].MatchedName)
+	}
+	assert.Empty(t, list.entries[0].Type, "-0- is an empty field")
+}
+
+func TestLoadList_XML(t *testing.T) {
+	// Arrange - an sdn.xml excerpt, which is namespaced
+	path := writeFile(t, "sdn.xml", `<?xml version="1.0" standalone="yes"?>
+<sdnList xmlns="http://tempuri.org/sdnList.xsd">
+  <sdnEntry>
+    <uid>9001</uid>
+    <firstName>Ivan</firstName>
+    <lastName>VOLKOV</lastName>
+    <sdnType>Individual</sdnType>
+    <programList><program>SDGT</program><program>RUSSIA-EO14024</program></programList>
+    <akaList>
+      <aka><uid>501</uid><type>a.k.a.</type><category>strong</category><lastName>VOLKOFF</lastName><firstName>Ivan</firstName></aka>
+    </akaList>
+  </sdnEntry>
+  <sdnEntry>
+    <uid>36</uid>
+    <lastName>AEROCARIBBEAN AIRLINES</lastName>
+    <sdnType>Entity</sdnType>
+    <programList><program>CUBA</program></programList>
+  </sdnEntry>
+</sdnList>`)
+
+	// Act
+	list, err := LoadList(path)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, list.Len())
+	assert.Equal(t, "Ivan VOLKOV", list.entries[0].Name)
+	assert.Equal(t, "SDGT; RUSSIA-EO14024", list.entries[0].Program)
+	assert.Equal(t, []string{"Ivan VOLKOFF"}, list.entries[0].Aliases)
+	assert.Empty(t, list.entries[1].Type)
+}
+
+func TestLoadList_Errors(t *testing.T) {
+	// Act
+	_, missingErr := LoadList(filepath.Join(t.TempDir(), "sdn.csv"))
+	_, typeErr := LoadList(writeFile(t, "sdn.txt", "9001,VOLKOV"))
+	_, xmlErr := LoadList(writeFile(t, "sdn.xml", "<sdnList>"))
+
+	// Assert
+	assert.Error(t, missingErr)
+	assert.Error(t, typeErr)
+	assert.Error(t, xmlErr)
+}
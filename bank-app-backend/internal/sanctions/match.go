@@ -0,0 +1,149 @@
+package sanctions
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match - A watchlist entry whose name or alias resembles a screened name
+type Match struct {
+	Entry       *Entry
+	MatchedName string  // The entry's name or alias that matched best
+	Score       float64 // Similarity from 0 to 1
+}
+
+// Screen returns the entries matching name with a score of at least
+// threshold, best match first. Each entry is matched by its closest name.
+func (l *List) Screen(name string, threshold float64) []Match {
+	normalized := Normalize(name)
+	if normalized == "" {
+		return nil
+	}
+
+	var matches []Match
+	for _, entry := range l.entries {
+		best := Match{Entry: entry}
+		for _, candidate := range entry.Names() {
+			if score := similarity(normalized, Normalize(candidate)); score > best.Score {
+				best.Score = score
+				best.MatchedName = candidate
+			}
+		}
+		if best.Score >= threshold {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// Similarity scores how alike two names are from 0 to 1. Word order, case,
+// punctuation and common accents are ignored, so "SMITH, John" matches
+// "John Smith" exactly.
+func Similarity(a, b string) float64 {
+	return similarity(Normalize(a), Normalize(b))
+}
+
+// Normalize lowercases a name, folds accented Latin letters, drops
+// punctuation and sorts its words
+func Normalize(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if folded, ok := accents[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// O'Brien and OBrien are the same name
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+var accents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a",
+	'æ': "ae", 'ç': "c", 'č': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i",
+	'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o",
+	'œ': "oe", 'š': "s", 'ß': "ss",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u",
+	'ý': "y", 'ÿ': "y", 'ž': "z",
+}
+
+// similarity is the Jaro-Winkler similarity of two normalized names
+func similarity(a, b string) float64 {
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	jaro := jaro(ra, rb)
+
+	// Boost names sharing a prefix of up to four characters
+	prefix := 0
+	for prefix < 4 && prefix < len(ra) && prefix < len(rb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func jaro(a, b []rune) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+
+	matches := 0
+	for i := range a {
+		from, to := max(0, i-window), min(len(b), i+window+1)
+		for j := from; j < to; j++ {
+			if !matchedB[j] && a[i] == b[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	// Count matched characters that appear in a different order
+	transpositions, j := 0, 0
+	for i := range a {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	return (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+}
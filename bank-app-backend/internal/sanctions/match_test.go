@@ -0,0 +1,53 @@
+package sanctions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "ivan volkov", Normalize("VOLKOV, Ivan"))
+	assert.Equal(t, "jose munoz", Normalize("  José   Muñoz "))
+	assert.Equal(t, "obrien sean", Normalize("Seán O'Brien"))
+	assert.Equal(t, "", Normalize(" ,. "))
+}
+
+func TestSimilarity(t *testing.T) {
+	// Word order, case and accents do not matter
+	assert.Equal(t, 1.0, Similarity("Ivan Volkov", "VOLKOV, Ivan"))
+	assert.Equal(t, 1.0, Similarity("Jose Munoz", "MUÑOZ, José"))
+
+	// Spelling variants score high, different names low
+	assert.Greater(t, Similarity("Ivan Volkoff", "VOLKOV, Ivan"), 0.9)
+	assert.Less(t, Similarity("John Smith", "VOLKOV, Ivan"), 0.6)
+	assert.Equal(t, 0.0, Similarity("", "VOLKOV, Ivan"))
+}
+
+func TestScreen(t *testing.T) {
+	// Arrange
+	list := NewList([]Entry{
+		{ID: "1", Name: "VOLKOV, Ivan", Aliases: []string{"Ivan Volkoff"}},
+		{ID: "2", Name: "VOLKOVA, Irina"},
+		{ID: "3", Name: "AEROCARIBBEAN AIRLINES"},
+	})
+
+	t.Run("Screen should match each entry by its closest name, best first", func(t *testing.T) {
+		// Act
+		matches := list.Screen("Ivan Volkoff", 0.7)
+
+		// Assert
+		if assert.Len(t, matches, 2) {
+			assert.Equal(t, "1", matches[0].Entry.ID)
+			assert.Equal(t, "Ivan Volkoff", matches[0].MatchedName)
+			assert.Equal(t, 1.0, matches[0].Score)
+			assert.Equal(t, "2", matches[1].Entry.ID)
+		}
+	})
+
+	t.Run("Screen should apply the threshold", func(t *testing.T) {
+		assert.Len(t, list.Screen("Ivan Volkoff", 0.99), 1)
+		assert.Empty(t, list.Screen("Jane Doe", 0.85))
+		assert.Empty(t, list.Screen("", 0))
+	})
+}
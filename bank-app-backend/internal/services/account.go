@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"strconv"
@@ -11,12 +12,12 @@ import (
 )
 
 type AccountService interface {
-	CreateAccount(account *models.Account) error
-	GetAccountByID(id uint) (*models.Account, error)
-	GetAccountsByUserID(userID uint) ([]models.Account, error)
-	GetAllAccounts() ([]models.Account, error)
-	UpdateAccount(account *models.Account) error
-	DeleteAccount(id uint) error
+	CreateAccount(ctx context.Context, account *models.Account) error
+	GetAccountByID(ctx context.Context, id uint) (*models.Account, error)
+	GetAccountsByUserID(ctx context.Context, userID uint) ([]models.Account, error)
+	GetAllAccounts(ctx context.Context) ([]models.Account, error)
+	UpdateAccount(ctx context.Context, account *models.Account) error
+	DeleteAccount(ctx context.Context, id uint) error
 	GenerateAccountNumber() string
 }
 
@@ -28,7 +29,7 @@ func NewAccountService(accountRepo repository.AccountRepository) AccountService
 	return &accountService{accountRepo}
 }
 
-func (s *accountService) CreateAccount(account *models.Account) error {
+func (s *accountService) CreateAccount(ctx context.Context, account *models.Account) error {
 	// Generate account number if not provided
 	if account.AccountNumber == "" {
 		account.AccountNumber = s.GenerateAccountNumber()
@@ -39,39 +40,39 @@ func (s *accountService) CreateAccount(account *models.Account) error {
 		return errors.New("invalid account type")
 	}
 
-	return s.accountRepo.Create(account)
+	return s.accountRepo.Create(ctx, account)
 }
 
-func (s *accountService) GetAccountByID(id uint) (*models.Account, error) {
-	return s.accountRepo.FindByID(id)
+func (s *accountService) GetAccountByID(ctx context.Context, id uint) (*models.Account, error) {
+	return s.accountRepo.FindByID(ctx, id)
 }
 
-func (s *accountService) GetAccountsByUserID(userID uint) ([]models.Account, error) {
-	return s.accountRepo.FindByUserID(userID)
+func (s *accountService) GetAccountsByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
+	return s.accountRepo.FindByUserID(ctx, userID)
 }
 
-func (s *accountService) GetAllAccounts() ([]models.Account, error) {
-	return s.accountRepo.FindAll()
+func (s *accountService) GetAllAccounts(ctx context.Context) ([]models.Account, error) {
+	return s.accountRepo.FindAll(ctx)
 }
 
-func (s *accountService) UpdateAccount(account *models.Account) error {
+func (s *accountService) UpdateAccount(ctx context.Context, account *models.Account) error {
 	// Check if the account exists
-	_, err := s.accountRepo.FindByID(account.ID)
+	_, err := s.accountRepo.FindByID(ctx, account.ID)
 	if err != nil {
 		return err
 	}
 
-	return s.accountRepo.Update(account)
+	return s.accountRepo.Update(ctx, account)
 }
 
-func (s *accountService) DeleteAccount(id uint) error {
+func (s *accountService) DeleteAccount(ctx context.Context, id uint) error {
 	// Check if the account exists
-	_, err := s.accountRepo.FindByID(id)
+	_, err := s.accountRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.accountRepo.Delete(id)
+	return s.accountRepo.Delete(ctx, id)
 }
 
 func (s *accountService) GenerateAccountNumber() string {
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
@@ -15,12 +17,12 @@ type MockAccountRepository struct {
 	mock.Mock
 }
 
-func (m *MockAccountRepository) Create(account *models.Account) error {
+func (m *MockAccountRepository) Create(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) FindByID(id uint) (*models.Account, error) {
+func (m *MockAccountRepository) FindByID(ctx context.Context, id uint) (*models.Account, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -28,12 +30,12 @@ func (m *MockAccountRepository) FindByID(id uint) (*models.Account, error) {
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByUserID(userID uint) ([]models.Account, error) {
+func (m *MockAccountRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
 	args := m.Called(userID)
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByAccountNumber(accountNumber string) (*models.Account, error) {
+func (m *MockAccountRepository) FindByAccountNumber(ctx context.Context, accountNumber string) (*models.Account, error) {
 	args := m.Called(accountNumber)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -41,32 +43,44 @@ func (m *MockAccountRepository) FindByAccountNumber(accountNumber string) (*mode
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindAll() ([]models.Account, error) {
+func (m *MockAccountRepository) FindAll(ctx context.Context) ([]models.Account, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) Update(account *models.Account) error {
+func (m *MockAccountRepository) Update(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) Delete(id uint) error {
+func (m *MockAccountRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) UpdateBalance(id uint, amount float64) error {
+func (m *MockAccountRepository) UpdateBalance(ctx context.Context, id uint, amount float64) error {
 	args := m.Called(id, amount)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) FindByIDWithLock(id uint) (*models.Account, repository.GormTx, error) {
-	args := m.Called(id)
-	if args.Get(0) == nil {
-		return nil, nil, args.Error(2)
+// LockForTransfer runs fn with the accounts and transaction set up for the
+// two IDs, and returns what fn returns
+func (m *MockAccountRepository) LockForTransfer(ctx context.Context, fromID, toID uint, fn func(from, to *models.Account, tx repository.GormTx) error) error {
+	args := m.Called(fromID, toID)
+	if err := args.Error(3); err != nil {
+		return err
 	}
-	return args.Get(0).(*models.Account), args.Get(1).(repository.GormTx), args.Error(2)
+	return fn(args.Get(0).(*models.Account), args.Get(1).(*models.Account), args.Get(2).(repository.GormTx))
+}
+
+func (m *MockAccountRepository) SetFrozen(ctx context.Context, id uint, frozen bool, entry *models.AuditEntry) error {
+	args := m.Called(id, frozen, entry)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) CreditOnce(ctx context.Context, id uint, amount float64, description string, date time.Time, entry *models.AuditEntry) (bool, error) {
+	args := m.Called(id, amount, description, date, entry)
+	return args.Bool(0), args.Error(1)
 }
 
 func TestCreateAccount_Success(t *testing.T) {
@@ -87,7 +101,7 @@ func TestCreateAccount_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.CreateAccount(testAccount)
+	err := service.CreateAccount(context.Background(), testAccount)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -110,7 +124,7 @@ func TestCreateAccount_InvalidType(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.CreateAccount(testAccount)
+	err := service.CreateAccount(context.Background(), testAccount)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -139,7 +153,7 @@ func TestGetAccountByID_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	account, err := service.GetAccountByID(1)
+	account, err := service.GetAccountByID(context.Background(), 1)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -160,7 +174,7 @@ func TestGetAccountByID_NotFound(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	account, err := service.GetAccountByID(999)
+	account, err := service.GetAccountByID(context.Background(), 999)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -186,7 +200,7 @@ func TestGetAccountsByUserID_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	accounts, err := service.GetAccountsByUserID(1)
+	accounts, err := service.GetAccountsByUserID(context.Background(), 1)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -213,7 +227,7 @@ func TestGetAllAccounts_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	accounts, err := service.GetAllAccounts()
+	accounts, err := service.GetAllAccounts(context.Background())
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -244,7 +258,7 @@ func TestUpdateAccount_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.UpdateAccount(testAccount)
+	err := service.UpdateAccount(context.Background(), testAccount)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -270,7 +284,7 @@ func TestUpdateAccount_NotFound(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.UpdateAccount(testAccount)
+	err := service.UpdateAccount(context.Background(), testAccount)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -299,7 +313,7 @@ func TestDeleteAccount_Success(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.DeleteAccount(1)
+	err := service.DeleteAccount(context.Background(), 1)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -317,7 +331,7 @@ func TestDeleteAccount_NotFound(t *testing.T) {
 	service := NewAccountService(mockRepo)
 	
 	// Call the method being tested
-	err := service.DeleteAccount(999)
+	err := service.DeleteAccount(context.Background(), 999)
 	
 	// Assert expectations
 	assert.Error(t, err)
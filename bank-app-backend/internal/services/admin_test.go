@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var operatorActor = models.AuditActor{Type: models.AuditActorOperator, ID: "ops"}
+
+func newTestAdminService(userRepo *MockUserRepository, accountRepo *MockAccountRepository, tokenRepo *MockTokenRepository) AdminService {
+	return NewAdminService(userRepo, accountRepo, newTestPasswordService(userRepo, nil), newTestTokenService(tokenRepo, userRepo))
+}
+
+func TestAdminCreateUser_Success(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByEmail", "ops@example.com").Return(nil, errors.New("user not found"))
+	mockUserRepo.On("CreateAudited", mock.MatchedBy(func(user *models.User) bool {
+		return user.Role == models.RoleAdmin && user.EmailVerifiedAt != nil &&
+			bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("longpassword1")) == nil
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditUserCreated && entry.ActorType == models.AuditActorOperator
+	})).Return(nil)
+
+	service := newTestAdminService(mockUserRepo, new(MockAccountRepository), new(MockTokenRepository))
+
+	// Call the method being tested
+	user, err := service.CreateUser(context.Background(), &models.RegisterRequest{
+		Email: " ops@example.com ", Password: "longpassword1", FirstName: "Ops", LastName: "Person",
+	}, models.RoleAdmin, operatorActor)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, "ops@example.com", user.Email)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestAdminCreateUser_RejectsUnknownRole(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	service := newTestAdminService(mockUserRepo, new(MockAccountRepository), new(MockTokenRepository))
+
+	// Call the method being tested
+	_, err := service.CreateUser(context.Background(), &models.RegisterRequest{
+		Email: "ops@example.com", Password: "longpassword1", FirstName: "Ops", LastName: "Person",
+	}, "root", operatorActor)
+
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidRole)
+	mockUserRepo.AssertNotCalled(t, "CreateAudited", mock.Anything, mock.Anything)
+}
+
+func TestAdminDisableUser_RevokesSessions(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1}, nil)
+	mockUserRepo.On("Disable", uint(1), auditEntryFor(models.AuditUserDisabled, models.AuditTargetUser, "1")).Return(nil)
+	mockTokenRepo.On("RevokeAllForUser", uint(1), auditEntryFor(models.AuditLogoutAll, models.AuditTargetUser, "1")).Return(nil)
+
+	service := newTestAdminService(mockUserRepo, new(MockAccountRepository), mockTokenRepo)
+
+	// Call the method being tested
+	err := service.DisableUser(context.Background(), 1, operatorActor)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestAdminResetPassword_RejectsWeakPassword(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1}, nil)
+
+	service := newTestAdminService(mockUserRepo, new(MockAccountRepository), new(MockTokenRepository))
+
+	// Call the method being tested
+	err := service.ResetPassword(context.Background(), 1, "short", operatorActor)
+
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrPasswordPolicy)
+	mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminFreezeAccount(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockAccountRepo.On("FindByID", uint(7)).Return(&models.Account{ID: 7}, nil)
+	mockAccountRepo.On("SetFrozen", uint(7), true, auditEntryFor(models.AuditAccountFrozen, models.AuditTargetAccount, "7")).Return(nil)
+	mockAccountRepo.On("SetFrozen", uint(7), false, auditEntryFor(models.AuditAccountUnfrozen, models.AuditTargetAccount, "7")).Return(nil)
+
+	service := newTestAdminService(new(MockUserRepository), mockAccountRepo, new(MockTokenRepository))
+
+	// Call the methods being tested
+	assert.NoError(t, service.FreezeAccount(context.Background(), 7, operatorActor))
+	assert.NoError(t, service.UnfreezeAccount(context.Background(), 7, operatorActor))
+
+	// Assert expectations
+	mockAccountRepo.AssertExpectations(t)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+const auditVerifyBatchSize = 500
+
+// actorLogger returns a logger carrying the request ID and caller of the
+// actor, so a service's log lines match its request's log and audit entries
+func actorLogger(actor models.AuditActor) *slog.Logger {
+	logger := slog.Default()
+	if actor.RequestID != "" {
+		logger = logger.With("request_id", actor.RequestID)
+	}
+	switch actor.Type {
+	case models.AuditActorUser:
+		logger = logger.With("user_id", actor.ID)
+	case models.AuditActorServiceClient:
+		logger = logger.With("client_id", actor.ID)
+	}
+	return logger
+}
+
+type AuditService interface {
+	GetEntries(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error)
+	Verify(ctx context.Context) (*models.AuditVerification, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo}
+}
+
+// GetEntries returns the audit entries matching the filter, newest first
+func (s *auditService) GetEntries(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	return s.auditRepo.Find(ctx, filter)
+}
+
+// Verify walks the whole audit log in order and checks that every entry still
+// matches its hash and links to the entry before it. A modified entry fails
+// its own hash; a deleted one breaks the link of the entry that followed it.
+// Deleting the newest entries is only detected by comparing HeadHash with a
+// copy kept outside the database.
+func (s *auditService) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true}
+
+	var lastID uint
+	for {
+		entries, err := s.auditRepo.FindAfter(ctx, lastID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			if entry.PrevHash != result.HeadHash {
+				return auditChainBroken(result, entry, "entry does not link to the previous entry, which was deleted or modified"), nil
+			}
+			if entry.ComputeHash() != entry.Hash {
+				return auditChainBroken(result, entry, "entry was modified after it was written"), nil
+			}
+			result.Checked++
+			result.HeadHash = entry.Hash
+			lastID = entry.ID
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+func auditChainBroken(result *models.AuditVerification, entry *models.AuditEntry, problem string) *models.AuditVerification {
+	result.Valid = false
+	result.BrokenAt = entry.ID
+	result.Problem = fmt.Sprintf("audit entry %d: %s", entry.ID, problem)
+	return result
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditRepository is a mock implementation of AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) AppendWithTx(ctx context.Context, entry *models.AuditEntry, tx repository.GormTx) error {
+	args := m.Called(entry, tx)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) Find(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditRepository) FindAfter(ctx context.Context, id uint, limit int) ([]models.AuditEntry, error) {
+	args := m.Called(id, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+// testActor is the caller passed to audited service methods
+var testActor = models.AuditActor{Type: models.AuditActorUser, ID: "1", IPAddress: "10.0.0.1", RequestID: "req-1"}
+
+// auditEntryFor matches an audit entry for the action on the target
+func auditEntryFor(action, targetType, targetID string) interface{} {
+	return mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry != nil && entry.Action == action && entry.TargetType == targetType && entry.TargetID == targetID
+	})
+}
+
+// chainedEntries builds a valid hash chain of n entries
+func chainedEntries(n int) []models.AuditEntry {
+	entries := make([]models.AuditEntry, n)
+	prevHash := ""
+	for i := range entries {
+		entry := models.NewAuditEntry(testActor, models.AuditPasswordChanged, models.AuditTargetUser, i+1)
+		entry.ID = uint(i + 1)
+		entry.CreatedAt = time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC)
+		entry.PrevHash = prevHash
+		entry.Hash = entry.ComputeHash()
+		prevHash = entry.Hash
+		entries[i] = *entry
+	}
+	return entries
+}
+
+func TestVerify_ValidChain(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	entries := chainedEntries(3)
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return(entries, nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.Checked)
+	assert.Equal(t, entries[2].Hash, result.HeadHash)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestVerify_EmptyLog(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return([]models.AuditEntry{}, nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 0, result.Checked)
+}
+
+func TestVerify_DetectsModifiedEntry(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	entries := chainedEntries(3)
+	entries[1].After = `{"amount":1000000}`
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return(entries, nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 1, result.Checked)
+	assert.Equal(t, uint(2), result.BrokenAt)
+	assert.Contains(t, result.Problem, "modified")
+}
+
+func TestVerify_DetectsDeletedEntry(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	entries := chainedEntries(3)
+	withoutSecond := []models.AuditEntry{entries[0], entries[2]}
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return(withoutSecond, nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(3), result.BrokenAt)
+	assert.Contains(t, result.Problem, "does not link")
+}
+
+func TestVerify_DetectsDeletedFirstEntry(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	entries := chainedEntries(2)
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return(entries[1:], nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, uint(2), result.BrokenAt)
+}
+
+func TestVerify_ReadsInBatches(t *testing.T) {
+	// Create mocks
+	mockAuditRepo := new(MockAuditRepository)
+	entries := chainedEntries(auditVerifyBatchSize + 1)
+	mockAuditRepo.On("FindAfter", uint(0), auditVerifyBatchSize).Return(entries[:auditVerifyBatchSize], nil)
+	mockAuditRepo.On("FindAfter", uint(auditVerifyBatchSize), auditVerifyBatchSize).Return(entries[auditVerifyBatchSize:], nil)
+
+	service := NewAuditService(mockAuditRepo)
+
+	// Call the method being tested
+	result, err := service.Verify(context.Background())
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, auditVerifyBatchSize+1, result.Checked)
+	mockAuditRepo.AssertExpectations(t)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+// FraudService screens transfers before any money moves
+type FraudService interface {
+	Assess(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (*models.FraudAssessment, error)
+}
+
+// FraudOptions - Fraud rules and the scores that decide a transfer's fate.
+// Every rule that fires adds its score; a rule with a zero limit is disabled.
+type FraudOptions struct {
+	ReviewScore int // Transfers scoring at least this are held for review, 0 never holds
+	BlockScore  int // Transfers scoring at least this are refused, 0 never blocks
+
+	VelocityCount  int // Fires when the account already made this many transfers within VelocityWindow
+	VelocityWindow time.Duration
+	VelocityScore  int
+
+	AverageMultiplier float64 // Fires when the amount exceeds this multiple of the account's average transfer
+	AverageMinHistory int     // Transfers the account needs before its average is trusted
+	AverageScore      int
+
+	NewPayeeAmount float64 // Fires when at least this much goes to an account the sender never paid before
+	NewPayeeScore  int
+
+	PasswordChangeWindow time.Duration // Fires when the owner changed or reset their password this recently
+	PasswordChangeScore  int
+}
+
+type fraudService struct {
+	transactionRepo repository.TransactionRepository
+	auditRepo       repository.AuditRepository
+	options         FraudOptions
+}
+
+func NewFraudService(transactionRepo repository.TransactionRepository, auditRepo repository.AuditRepository, options FraudOptions) FraudService {
+	return &fraudService{transactionRepo, auditRepo, options}
+}
+
+// fraudRule returns the reason it fired, or "" when it did not
+type fraudRule struct {
+	score int
+	check func(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (string, error)
+}
+
+// Assess runs every enabled rule and decides from the total score
+func (s *fraudService) Assess(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (*models.FraudAssessment, error) {
+	assessment := &models.FraudAssessment{Reasons: []string{}, Decision: models.FraudAllow}
+	for _, rule := range s.rules() {
+		reason, err := rule.check(ctx, request, fromAccount)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			assessment.Score += rule.score
+			assessment.Reasons = append(assessment.Reasons, reason)
+		}
+	}
+
+	switch {
+	case s.options.BlockScore > 0 && assessment.Score >= s.options.BlockScore:
+		assessment.Decision = models.FraudBlock
+	case s.options.ReviewScore > 0 && assessment.Score >= s.options.ReviewScore:
+		assessment.Decision = models.FraudReview
+	}
+	return assessment, nil
+}
+
+func (s *fraudService) rules() []fraudRule {
+	var rules []fraudRule
+	if s.options.VelocityCount > 0 && s.options.VelocityWindow > 0 {
+		rules = append(rules, fraudRule{s.options.VelocityScore, s.checkVelocity})
+	}
+	if s.options.AverageMultiplier > 0 {
+		rules = append(rules, fraudRule{s.options.AverageScore, s.checkAverage})
+	}
+	if s.options.NewPayeeAmount > 0 {
+		rules = append(rules, fraudRule{s.options.NewPayeeScore, s.checkNewPayee})
+	}
+	if s.options.PasswordChangeWindow > 0 {
+		rules = append(rules, fraudRule{s.options.PasswordChangeScore, s.checkPasswordChange})
+	}
+	return rules
+}
+
+func (s *fraudService) checkVelocity(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (string, error) {
+	count, err := s.transactionRepo.CountTransfersFrom(ctx, fromAccount.ID, time.Now().Add(-s.options.VelocityWindow))
+	if err != nil {
+		return "", err
+	}
+	if count < int64(s.options.VelocityCount) {
+		return "", nil
+	}
+	return fmt.Sprintf("velocity: %d transfers in the last %s", count, s.options.VelocityWindow), nil
+}
+
+func (s *fraudService) checkAverage(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (string, error) {
+	average, count, err := s.transactionRepo.AverageTransferFrom(ctx, fromAccount.ID)
+	if err != nil {
+		return "", err
+	}
+	if count < int64(s.options.AverageMinHistory) || average <= 0 || request.Amount <= average*s.options.AverageMultiplier {
+		return "", nil
+	}
+	return fmt.Sprintf("amount: %.2f is %.1fx the account's average transfer of %.2f", request.Amount, request.Amount/average, average), nil
+}
+
+func (s *fraudService) checkNewPayee(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (string, error) {
+	if request.Amount < s.options.NewPayeeAmount {
+		return "", nil
+	}
+	known, err := s.transactionRepo.HasTransferred(ctx, fromAccount.ID, request.ToAccountID)
+	if err != nil || known {
+		return "", err
+	}
+	return fmt.Sprintf("new payee: first transfer to account %d is %.2f", request.ToAccountID, request.Amount), nil
+}
+
+func (s *fraudService) checkPasswordChange(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (string, error) {
+	since := time.Now().Add(-s.options.PasswordChangeWindow)
+	for _, action := range []string{models.AuditPasswordChanged, models.AuditPasswordReset} {
+		entries, err := s.auditRepo.Find(ctx, &models.AuditFilter{
+			Action:     action,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.FormatUint(uint64(fromAccount.UserID), 10),
+			From:       &since,
+			Limit:      1,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(entries) > 0 {
+			return fmt.Sprintf("password change: owner's password was changed at %s", entries[0].CreatedAt.UTC().Format(time.RFC3339)), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testFraudOptions mirrors the default configuration
+var testFraudOptions = FraudOptions{
+	ReviewScore:          50,
+	BlockScore:           100,
+	VelocityCount:        10,
+	VelocityWindow:       10 * time.Minute,
+	VelocityScore:        50,
+	AverageMultiplier:    5,
+	AverageMinHistory:    5,
+	AverageScore:         30,
+	NewPayeeAmount:       5000,
+	NewPayeeScore:        30,
+	PasswordChangeWindow: 24 * time.Hour,
+	PasswordChangeScore:  40,
+}
+
+// fraudMocks sets up an account with a quiet history: few recent transfers,
+// an average of 100 over 20 transfers, a known payee and no password change
+func fraudMocks() (*MockTransactionRepository, *MockAuditRepository) {
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockTransactionRepo.On("CountTransfersFrom", uint(1), mock.AnythingOfType("time.Time")).Return(int64(2), nil).Maybe()
+	mockTransactionRepo.On("AverageTransferFrom", uint(1)).Return(100.0, int64(20), nil).Maybe()
+	mockTransactionRepo.On("HasTransferred", uint(1), uint(2)).Return(true, nil).Maybe()
+	mockTransactionRepo.On("HasTransferred", uint(1), uint(3)).Return(false, nil).Maybe()
+
+	mockAuditRepo := new(MockAuditRepository)
+	mockAuditRepo.On("Find", mock.Anything).Return([]models.AuditEntry{}, nil).Maybe()
+	return mockTransactionRepo, mockAuditRepo
+}
+
+// hasReason reports whether a rule with the given reason prefix fired
+func hasReason(assessment *models.FraudAssessment, prefix string) bool {
+	for _, reason := range assessment.Reasons {
+		if strings.HasPrefix(reason, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFraudAssess_Allow(t *testing.T) {
+	// Create mocks
+	mockTransactionRepo, mockAuditRepo := fraudMocks()
+	service := NewFraudService(mockTransactionRepo, mockAuditRepo, testFraudOptions)
+	
+	// Call the method being tested
+	assessment, err := service.Assess(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 200}, &models.Account{ID: 1, UserID: 3})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, models.FraudAllow, assessment.Decision)
+	assert.Equal(t, 0, assessment.Score)
+	assert.Empty(t, assessment.Reasons)
+}
+
+func TestFraudAssess_Rules(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *models.TransferRequest
+		setup    func(*MockTransactionRepository, *MockAuditRepository)
+		score    int
+		decision string
+		reason   string
+	}{
+		{
+			name:    "velocity",
+			request: &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 200},
+			setup: func(transactionRepo *MockTransactionRepository, auditRepo *MockAuditRepository) {
+				transactionRepo.ExpectedCalls[0].ReturnArguments = mock.Arguments{int64(10), nil}
+			},
+			score:    50,
+			decision: models.FraudReview,
+			reason:   "velocity:",
+		},
+		{
+			name:     "amount far above the average",
+			request:  &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 600},
+			score:    30,
+			decision: models.FraudAllow,
+			reason:   "amount:",
+		},
+		{
+			name:     "large first transfer to a new payee",
+			request:  &models.TransferRequest{FromAccountID: 1, ToAccountID: 3, Amount: 5000},
+			score:    60,
+			decision: models.FraudReview,
+			reason:   "new payee:",
+		},
+		{
+			name:    "recent password change",
+			request: &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 200},
+			setup: func(transactionRepo *MockTransactionRepository, auditRepo *MockAuditRepository) {
+				auditRepo.ExpectedCalls = nil
+				auditRepo.On("Find", mock.MatchedBy(func(filter *models.AuditFilter) bool {
+					return filter.Action == models.AuditPasswordChanged && filter.TargetID == "3" && filter.From != nil
+				})).Return([]models.AuditEntry{{CreatedAt: time.Now()}}, nil)
+			},
+			score:    40,
+			decision: models.FraudAllow,
+			reason:   "password change:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockTransactionRepo, mockAuditRepo := fraudMocks()
+			if tt.setup != nil {
+				tt.setup(mockTransactionRepo, mockAuditRepo)
+			}
+			service := NewFraudService(mockTransactionRepo, mockAuditRepo, testFraudOptions)
+
+			// Act
+			assessment, err := service.Assess(context.Background(), tt.request, &models.Account{ID: 1, UserID: 3})
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.score, assessment.Score)
+			assert.Equal(t, tt.decision, assessment.Decision)
+			assert.True(t, hasReason(assessment, tt.reason), "%v", assessment.Reasons)
+		})
+	}
+}
+
+func TestFraudAssess_Block(t *testing.T) {
+	// Create mocks - a new payee right after a password change, well above the average
+	mockTransactionRepo, mockAuditRepo := fraudMocks()
+	mockAuditRepo.ExpectedCalls = nil
+	mockAuditRepo.On("Find", mock.Anything).Return([]models.AuditEntry{{CreatedAt: time.Now()}}, nil)
+	service := NewFraudService(mockTransactionRepo, mockAuditRepo, testFraudOptions)
+	
+	// Call the method being tested
+	assessment, err := service.Assess(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 3, Amount: 8000}, &models.Account{ID: 1, UserID: 3})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, 100, assessment.Score)
+	assert.Equal(t, models.FraudBlock, assessment.Decision)
+	assert.Len(t, assessment.Reasons, 3)
+}
+
+func TestFraudAssess_DisabledRules(t *testing.T) {
+	// Create mocks - no repository call is expected
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	service := NewFraudService(mockTransactionRepo, mockAuditRepo, FraudOptions{ReviewScore: 50})
+	
+	// Call the method being tested
+	assessment, err := service.Assess(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 3, Amount: 1000000}, &models.Account{ID: 1, UserID: 3})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, models.FraudAllow, assessment.Decision)
+	mockTransactionRepo.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestFraudAssess_RepositoryError(t *testing.T) {
+	// Create mocks
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockTransactionRepo.On("CountTransfersFrom", uint(1), mock.AnythingOfType("time.Time")).Return(int64(0), errors.New("database unavailable"))
+	service := NewFraudService(mockTransactionRepo, new(MockAuditRepository), testFraudOptions)
+	
+	// Call the method being tested
+	assessment, err := service.Assess(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 200}, &models.Account{ID: 1, UserID: 3})
+	
+	// Assert expectations - screening fails closed
+	assert.Error(t, err)
+	assert.Nil(t, assessment)
+}
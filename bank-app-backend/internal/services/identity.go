@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/mailer"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+var (
+	// ErrEmailTaken is returned when registering an address that is already in
+	// use. Register emails the address's owner before returning it, so that the
+	// caller can answer as it does for a new registration.
+	ErrEmailTaken = errors.New("user with this email already exists")
+	// ErrEmailNotVerified is returned when login requires a verified email address
+	ErrEmailNotVerified = errors.New("email address has not been verified")
+	// ErrUserDisabled is returned when an administrator has disabled the user
+	ErrUserDisabled = errors.New("user has been disabled")
+	// ErrRegistrationDeclined is returned when the name matches the sanctions watchlist
+	ErrRegistrationDeclined = errors.New("registration could not be completed")
+)
+
+// IdentityOptions - Settings for registration, verification and password reset
+type IdentityOptions struct {
+	AppBaseURL               string
+	RequireEmailVerification bool
+	VerificationTokenTTL     time.Duration
+	PasswordResetTokenTTL    time.Duration
+	Sanctions                SanctionsService // Screens the name of every new user, nil disables screening
+}
+
+type IdentityService interface {
+	Register(ctx context.Context, request *models.RegisterRequest) (*models.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string, actor models.AuditActor) error
+	CheckLoginAllowed(ctx context.Context, user *models.User) error
+}
+
+type identityService struct {
+	userRepo        repository.UserRepository
+	emailTokenRepo  repository.EmailTokenRepository
+	tokenService    TokenService
+	passwordService PasswordService
+	mailer          mailer.Mailer
+	options         IdentityOptions
+}
+
+func NewIdentityService(userRepo repository.UserRepository, emailTokenRepo repository.EmailTokenRepository, tokenService TokenService, passwordService PasswordService, mail mailer.Mailer, options IdentityOptions) IdentityService {
+	return &identityService{userRepo, emailTokenRepo, tokenService, passwordService, mail, options}
+}
+
+func (s *identityService) Register(ctx context.Context, request *models.RegisterRequest) (*models.User, error) {
+	user := &models.User{
+		Email:     strings.TrimSpace(request.Email),
+		FirstName: strings.TrimSpace(request.FirstName),
+		LastName:  strings.TrimSpace(request.LastName),
+	}
+	if user.FirstName == "" || user.LastName == "" {
+		return nil, errors.New("first and last name are required")
+	}
+	if err := s.passwordService.SetPassword(ctx, user, request.Password); err != nil {
+		return nil, err
+	}
+
+	// Refuse names on the watchlist, and record near matches once the user
+	// exists. The name is screened before the email is looked up, so a refusal
+	// does not depend on whether the address is registered.
+	var screening *models.ScreeningResult
+	if s.options.Sanctions != nil {
+		var err error
+		screening, err = s.options.Sanctions.Screen(ctx, user.FullName(), user.Email, nil, models.ScreeningRegistration)
+		if err != nil {
+			return nil, err
+		}
+		if screening.Decision == models.ScreeningBlock {
+			if err := s.options.Sanctions.Record(ctx, screening, nil); err != nil {
+				return nil, err
+			}
+			return nil, ErrRegistrationDeclined
+		}
+	}
+
+	// Tell the owner of an address that is already registered, rather than the
+	// caller, who may be probing for registered emails
+	if existingUser, err := s.userRepo.FindByEmail(ctx, user.Email); err == nil && existingUser != nil {
+		if err := s.sendRegistrationAttempt(existingUser); err != nil {
+			return nil, err
+		}
+		return nil, ErrEmailTaken
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if screening != nil && screening.Decision == models.ScreeningFlag {
+		if err := s.options.Sanctions.Record(ctx, screening, &user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *identityService) VerifyEmail(ctx context.Context, token string) error {
+	emailToken, err := s.redeem(ctx, token, models.EmailVerification)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.MarkEmailVerified(ctx, emailToken.UserID)
+}
+
+// ResendVerification sends a new verification link. Unknown or already
+// verified addresses are ignored so the endpoint cannot be used to probe for
+// registered emails.
+func (s *identityService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.emailTokenRepo.InvalidateForUser(ctx, user.ID, models.EmailVerification); err != nil {
+		return err
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// RequestPasswordReset emails a reset link. Unknown addresses are ignored for
+// the same reason as in ResendVerification.
+func (s *identityService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil
+	}
+
+	if err := s.emailTokenRepo.InvalidateForUser(ctx, user.ID, models.PasswordReset); err != nil {
+		return err
+	}
+
+	token, err := s.createToken(ctx, user.ID, models.PasswordReset, s.options.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not request a password reset you can ignore this email.\n",
+			user.FirstName, s.options.PasswordResetTokenTTL, s.options.AppBaseURL, token),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere
+func (s *identityService) ResetPassword(ctx context.Context, token, newPassword string, actor models.AuditActor) error {
+	// Check the policy first so that a rejected password does not use up the token
+	if err := s.passwordService.Validate(newPassword); err != nil {
+		return err
+	}
+
+	emailToken, err := s.redeem(ctx, token, models.PasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, emailToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.passwordService.SetPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actor, models.AuditPasswordReset, models.AuditTargetUser, user.ID)
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, user.Password, entry); err != nil {
+		return err
+	}
+
+	// Following the emailed link proves ownership of the address
+	if user.EmailVerifiedAt == nil {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	return s.tokenService.LogoutAll(ctx, user.ID, actor)
+}
+
+func (s *identityService) CheckLoginAllowed(ctx context.Context, user *models.User) error {
+	if user.DisabledAt != nil {
+		return ErrUserDisabled
+	}
+	if s.options.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (s *identityService) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := s.createToken(ctx, user.ID, models.EmailVerification, s.options.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.FirstName, s.options.VerificationTokenTTL, s.options.AppBaseURL, token),
+	})
+}
+
+func (s *identityService) sendRegistrationAttempt(user *models.User) error {
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Someone tried to register with your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone tried to create an account with this email address, which already has one. If it was you, log in instead; you can reset your password from the login page if you have forgotten it.\n\n%s/login\n\nIf it was not you, you can ignore this email.\n",
+			user.FirstName, s.options.AppBaseURL),
+	})
+}
+
+func (s *identityService) createToken(ctx context.Context, userID uint, purpose models.EmailTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.emailTokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// redeem looks up and consumes a single-use token
+func (s *identityService) redeem(ctx context.Context, token string, purpose models.EmailTokenPurpose) (*models.EmailToken, error) {
+	emailToken, err := s.emailTokenRepo.FindByHash(ctx, hashToken(token), purpose)
+	if err != nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if emailToken.UsedAt != nil || time.Now().After(emailToken.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	used, err := s.emailTokenRepo.MarkUsed(ctx, emailToken.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	return emailToken, nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/mailer"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Create a mock for the email token repository
+type MockEmailTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailTokenRepository) Create(ctx context.Context, token *models.EmailToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockEmailTokenRepository) FindByHash(ctx context.Context, tokenHash string, purpose models.EmailTokenPurpose) (*models.EmailToken, error) {
+	args := m.Called(tokenHash, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailToken), args.Error(1)
+}
+
+func (m *MockEmailTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEmailTokenRepository) InvalidateForUser(ctx context.Context, userID uint, purpose models.EmailTokenPurpose) error {
+	args := m.Called(userID, purpose)
+	return args.Error(0)
+}
+
+// recordingMailer keeps sent messages in memory
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var tokenInLink = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func newTestIdentityService(userRepo *MockUserRepository, emailTokenRepo *MockEmailTokenRepository, tokenRepo *MockTokenRepository, mail *recordingMailer) IdentityService {
+	return NewIdentityService(userRepo, emailTokenRepo, newTestTokenService(tokenRepo, userRepo), newTestPasswordService(userRepo, nil), mail, IdentityOptions{
+		AppBaseURL:               "http://localhost:3000",
+		RequireEmailVerification: true,
+		VerificationTokenTTL:     24 * time.Hour,
+		PasswordResetTokenTTL:    time.Hour,
+	})
+}
+
+func TestRegister_SendsVerificationEmail(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	mail := &recordingMailer{}
+	
+	// Capture the stored verification token
+	var stored *models.EmailToken
+	mockUserRepo.On("FindByEmail", "new@example.com").Return(nil, errors.New("user not found"))
+	mockUserRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
+	mockEmailTokenRepo.On("Create", mock.AnythingOfType("*models.EmailToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.EmailToken) }).
+		Return(nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), mail)
+	
+	// Call the method being tested
+	user, err := service.Register(context.Background(), &models.RegisterRequest{
+		Email:     " new@example.com ",
+		Password:  "password123",
+		FirstName: "New",
+		LastName:  "User",
+	})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.NoError(t, user.ComparePassword("password123"))
+	assert.Nil(t, user.EmailVerifiedAt)
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, "new@example.com", mail.sent[0].To)
+	
+	// The emailed token is stored only as a hash
+	match := tokenInLink.FindStringSubmatch(mail.sent[0].Body)
+	assert.Len(t, match, 2)
+	assert.Equal(t, hashToken(match[1]), stored.TokenHash)
+	assert.Equal(t, models.EmailVerification, stored.Purpose)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestRegister_EmailTaken(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	mail := &recordingMailer{}
+	
+	mockUserRepo.On("FindByEmail", "taken@example.com").Return(&models.User{ID: 1, Email: "taken@example.com", FirstName: "Owner"}, nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), mail)
+	
+	// Call the method being tested
+	_, err := service.Register(context.Background(), &models.RegisterRequest{
+		Email:     "taken@example.com",
+		Password:  "password123",
+		FirstName: "Taken",
+		LastName:  "User",
+	})
+	
+	// Assert expectations - the owner of the address is told, without a token
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, "taken@example.com", mail.sent[0].To)
+	assert.Equal(t, "Someone tried to register with your email address", mail.sent[0].Subject)
+	assert.Contains(t, mail.sent[0].Body, "Hi Owner,")
+	assert.NotRegexp(t, tokenInLink, mail.sent[0].Body)
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockEmailTokenRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestVerifyEmail_Success(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	
+	stored := &models.EmailToken{ID: 3, UserID: 1, Purpose: models.EmailVerification, ExpiresAt: time.Now().Add(time.Hour)}
+	mockEmailTokenRepo.On("FindByHash", hashToken("verify-token"), models.EmailVerification).Return(stored, nil)
+	mockEmailTokenRepo.On("MarkUsed", uint(3)).Return(true, nil)
+	mockUserRepo.On("MarkEmailVerified", uint(1)).Return(nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.VerifyEmail(context.Background(), "verify-token")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockEmailTokenRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestVerifyEmail_ExpiredToken(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	
+	stored := &models.EmailToken{ID: 3, UserID: 1, Purpose: models.EmailVerification, ExpiresAt: time.Now().Add(-time.Minute)}
+	mockEmailTokenRepo.On("FindByHash", hashToken("old-token"), models.EmailVerification).Return(stored, nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.VerifyEmail(context.Background(), "old-token")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockEmailTokenRepo.AssertNotCalled(t, "MarkUsed", mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+}
+
+func TestVerifyEmail_TokenAlreadyUsed(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	
+	stored := &models.EmailToken{ID: 3, UserID: 1, Purpose: models.EmailVerification, ExpiresAt: time.Now().Add(time.Hour)}
+	mockEmailTokenRepo.On("FindByHash", hashToken("verify-token"), models.EmailVerification).Return(stored, nil)
+	mockEmailTokenRepo.On("MarkUsed", uint(3)).Return(false, nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.VerifyEmail(context.Background(), "verify-token")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockUserRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+}
+
+func TestRequestPasswordReset_UnknownEmail(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	mail := &recordingMailer{}
+	
+	mockUserRepo.On("FindByEmail", "nobody@example.com").Return(nil, errors.New("user not found"))
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, new(MockTokenRepository), mail)
+	
+	// Call the method being tested
+	err := service.RequestPasswordReset(context.Background(), "nobody@example.com")
+	
+	// Assert expectations - no error so the caller cannot tell the email is unknown
+	assert.NoError(t, err)
+	assert.Empty(t, mail.sent)
+}
+
+func TestResetPassword_SetsPasswordAndRevokesSessions(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	mockTokenRepo := new(MockTokenRepository)
+	
+	verifiedAt := time.Now()
+	user := &models.User{ID: 1, Email: "test@example.com", Password: "old-hash", EmailVerifiedAt: &verifiedAt}
+	stored := &models.EmailToken{ID: 4, UserID: 1, Purpose: models.PasswordReset, ExpiresAt: time.Now().Add(time.Hour)}
+	
+	mockEmailTokenRepo.On("FindByHash", hashToken("reset-token"), models.PasswordReset).Return(stored, nil)
+	mockEmailTokenRepo.On("MarkUsed", uint(4)).Return(true, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("UpdatePassword", uint(1), mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("newpassword123")) == nil
+	}), auditEntryFor(models.AuditPasswordReset, models.AuditTargetUser, "1")).Return(nil)
+	mockTokenRepo.On("RevokeAllForUser", uint(1), auditEntryFor(models.AuditLogoutAll, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestIdentityService(mockUserRepo, mockEmailTokenRepo, mockTokenRepo, &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.ResetPassword(context.Background(), "reset-token", "newpassword123", loginActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestResetPassword_RejectsWeakPassword(t *testing.T) {
+	// Create mocks
+	mockEmailTokenRepo := new(MockEmailTokenRepository)
+	
+	service := newTestIdentityService(new(MockUserRepository), mockEmailTokenRepo, new(MockTokenRepository), &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.ResetPassword(context.Background(), "reset-token", "short", loginActor)
+	
+	// Assert expectations - the token is left for another try
+	assert.ErrorIs(t, err, ErrPasswordPolicy)
+	mockEmailTokenRepo.AssertNotCalled(t, "FindByHash", mock.Anything, mock.Anything)
+}
+
+func TestCheckLoginAllowed(t *testing.T) {
+	service := newTestIdentityService(new(MockUserRepository), new(MockEmailTokenRepository), new(MockTokenRepository), &recordingMailer{})
+	
+	verifiedAt := time.Now()
+	assert.NoError(t, service.CheckLoginAllowed(context.Background(), &models.User{EmailVerifiedAt: &verifiedAt}))
+	assert.ErrorIs(t, service.CheckLoginAllowed(context.Background(), &models.User{}), ErrEmailNotVerified)
+	assert.ErrorIs(t, service.CheckLoginAllowed(context.Background(), &models.User{EmailVerifiedAt: &verifiedAt, DisabledAt: &verifiedAt}), ErrUserDisabled)
+	
+	optional := NewIdentityService(nil, nil, nil, nil, nil, IdentityOptions{RequireEmailVerification: false})
+	assert.NoError(t, optional.CheckLoginAllowed(context.Background(), &models.User{}))
+}
+
+func TestRegister_SanctionsScreening(t *testing.T) {
+	newService := func(userRepo *MockUserRepository, sanctions *MockSanctionsService) IdentityService {
+		emailTokenRepo := new(MockEmailTokenRepository)
+		emailTokenRepo.On("Create", mock.AnythingOfType("*models.EmailToken")).Return(nil)
+		return NewIdentityService(userRepo, emailTokenRepo, newTestTokenService(new(MockTokenRepository), userRepo), newTestPasswordService(userRepo, nil), &recordingMailer{}, IdentityOptions{
+			VerificationTokenTTL: 24 * time.Hour,
+			Sanctions:            sanctions,
+		})
+	}
+	request := &models.RegisterRequest{Email: "ivan@example.com", Password: "password123", FirstName: "Ivan", LastName: "Volkov"}
+
+	t.Run("Register should refuse a blocked name and record the hit without a user", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		mockSanctions := new(MockSanctionsService)
+		blocked := &models.ScreeningResult{Name: "Ivan Volkov", Decision: models.ScreeningBlock, Hits: []models.ScreeningHit{{EntryID: "9001"}}}
+		mockSanctions.On("Screen", "Ivan Volkov", "ivan@example.com", (*uint)(nil), models.ScreeningRegistration).Return(blocked, nil)
+		mockSanctions.On("Record", blocked, (*uint)(nil)).Return(nil)
+
+		// Act
+		_, err := newService(mockUserRepo, mockSanctions).Register(context.Background(), request)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrRegistrationDeclined)
+		mockUserRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+		mockUserRepo.AssertNotCalled(t, "Create", mock.Anything)
+		mockSanctions.AssertExpectations(t)
+	})
+
+	t.Run("Register should create a flagged user and record the hit against them", func(t *testing.T) {
+		// Arrange
+		mockUserRepo := new(MockUserRepository)
+		mockSanctions := new(MockSanctionsService)
+		flagged := &models.ScreeningResult{Name: "Ivan Volkov", Decision: models.ScreeningFlag, Hits: []models.ScreeningHit{{EntryID: "9001"}}}
+		mockUserRepo.On("FindByEmail", "ivan@example.com").Return(nil, errors.New("user not found"))
+		mockUserRepo.On("Create", mock.AnythingOfType("*models.User")).
+			Run(func(args mock.Arguments) { args.Get(0).(*models.User).ID = 8 }).
+			Return(nil)
+		mockSanctions.On("Screen", "Ivan Volkov", "ivan@example.com", (*uint)(nil), models.ScreeningRegistration).Return(flagged, nil)
+		mockSanctions.On("Record", flagged, mock.MatchedBy(func(subjectID *uint) bool {
+			return subjectID != nil && *subjectID == 8
+		})).Return(nil)
+
+		// Act
+		user, err := newService(mockUserRepo, mockSanctions).Register(context.Background(), request)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint(8), user.ID)
+		mockSanctions.AssertExpectations(t)
+	})
+}
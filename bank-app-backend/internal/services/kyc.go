@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+)
+
+var (
+	// ErrKYCRequired is returned when an unverified user tries to move money
+	ErrKYCRequired = errors.New("identity verification is required to transfer money")
+	// ErrKYCLimitExceeded is returned when a transfer is over the limits of the user's KYC level
+	ErrKYCLimitExceeded = errors.New("transfer exceeds the limit for your verification level")
+	// ErrKYCLocked is returned when changing a profile that is pending or verified
+	ErrKYCLocked = errors.New("KYC profile cannot be changed while it is pending review or verified")
+	// ErrKYCIncomplete is returned when submitting a profile without an identity document
+	ErrKYCIncomplete = errors.New("upload the front of your identity document before submitting")
+	// ErrKYCProfileNotFound is returned when a user has not started a KYC profile
+	ErrKYCProfileNotFound = repository.ErrKYCProfileNotFound
+	// ErrKYCNotPending is returned when reviewing a profile that was not submitted
+	ErrKYCNotPending = repository.ErrKYCNotPending
+	// ErrDocumentTooLarge is returned for an upload over the size limit
+	ErrDocumentTooLarge = errors.New("document is too large")
+	// ErrDocumentType is returned for an upload that is not an image or PDF
+	ErrDocumentType = errors.New("document must be a JPEG, PNG or PDF file")
+)
+
+// minimumAge - Customers must be adults
+const minimumAge = 18
+
+// documentTypes - Content types accepted for KYC documents
+var documentTypes = map[string]bool{"image/jpeg": true, "image/png": true, "application/pdf": true}
+
+var documentKinds = map[string]bool{
+	models.KYCDocumentIDFront:        true,
+	models.KYCDocumentIDBack:         true,
+	models.KYCDocumentProofOfAddress: true,
+}
+
+// KYCLimits - Transfer limits of a KYC level, 0 is unlimited
+type KYCLimits struct {
+	PerTransfer float64 // Largest single transfer
+	Daily       float64 // Most a user may transfer out in 24 hours, across their accounts
+}
+
+// KYCOptions - Document storage and the transfer limits of each level
+type KYCOptions struct {
+	Documents       storage.Store
+	MaxDocumentSize int64             // In bytes
+	Limits          map[int]KYCLimits // By level; a verified level without limits here is unlimited
+}
+
+type KYCService interface {
+	GetProfile(ctx context.Context, userID uint) (*models.KYCProfile, error)
+	SaveProfile(ctx context.Context, userID uint, request *models.KYCProfileRequest) (*models.KYCProfile, error)
+	UploadDocument(ctx context.Context, userID uint, kind, fileName string, data []byte) (*models.KYCDocument, error)
+	Submit(ctx context.Context, userID uint, actor models.AuditActor) (*models.KYCProfile, error)
+	GetProfiles(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error)
+	GetDocument(ctx context.Context, id uint) (*models.KYCDocument, []byte, error)
+	Approve(ctx context.Context, userID, adminID uint, level int, actor models.AuditActor) error
+	Reject(ctx context.Context, userID, adminID uint, reason string, actor models.AuditActor) error
+	CheckTransfer(ctx context.Context, userID uint, amount float64) error
+	CheckTransferWithTx(ctx context.Context, userID uint, amount float64, tx repository.GormTx) error
+}
+
+type kycService struct {
+	kycRepo         repository.KYCRepository
+	transactionRepo repository.TransactionRepository
+	options         KYCOptions
+}
+
+func NewKYCService(kycRepo repository.KYCRepository, transactionRepo repository.TransactionRepository, options KYCOptions) KYCService {
+	return &kycService{kycRepo, transactionRepo, options}
+}
+
+// GetProfile returns the user's profile, or an empty NOT_STARTED one
+func (s *kycService) GetProfile(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	profile, err := s.kycRepo.FindByUserID(ctx, userID)
+	if errors.Is(err, ErrKYCProfileNotFound) {
+		return &models.KYCProfile{UserID: userID, Status: models.KYCNotStarted, Documents: []models.KYCDocument{}}, nil
+	}
+	return profile, err
+}
+
+// SaveProfile stores the user's details as a draft. A rejected profile
+// becomes a draft again so it can be corrected and resubmitted.
+func (s *kycService) SaveProfile(ctx context.Context, userID uint, request *models.KYCProfileRequest) (*models.KYCProfile, error) {
+	if err := validateDateOfBirth(request.DateOfBirth); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Editable() {
+		return nil, ErrKYCLocked
+	}
+
+	profile.DateOfBirth = request.DateOfBirth
+	profile.AddressLine1 = strings.TrimSpace(request.AddressLine1)
+	profile.AddressLine2 = strings.TrimSpace(request.AddressLine2)
+	profile.City = strings.TrimSpace(request.City)
+	profile.PostalCode = strings.TrimSpace(request.PostalCode)
+	profile.Country = strings.ToUpper(request.Country)
+	profile.IDType = request.IDType
+	profile.IDNumber = strings.TrimSpace(request.IDNumber)
+	profile.Status = models.KYCDraft
+
+	if err := s.kycRepo.Save(ctx, profile, nil); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// UploadDocument encrypts a document and adds it to the user's profile. The
+// content type is detected from the data rather than trusted from the upload.
+func (s *kycService) UploadDocument(ctx context.Context, userID uint, kind, fileName string, data []byte) (*models.KYCDocument, error) {
+	if !documentKinds[kind] {
+		return nil, errors.New("unknown document kind")
+	}
+	if s.options.MaxDocumentSize > 0 && int64(len(data)) > s.options.MaxDocumentSize {
+		return nil, ErrDocumentTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !documentTypes[contentType] {
+		return nil, ErrDocumentType
+	}
+
+	profile, err := s.kycRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Editable() {
+		return nil, ErrKYCLocked
+	}
+
+	keyring, err := pii.Registered()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := keyring.Encrypt(string(data))
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.options.Documents.Save([]byte(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	document := &models.KYCDocument{
+		ProfileID:   profile.ID,
+		Kind:        kind,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		StorageKey:  key,
+	}
+	if err := s.kycRepo.AddDocument(ctx, document); err != nil {
+		s.options.Documents.Delete(key)
+		return nil, err
+	}
+	return document, nil
+}
+
+// Submit sends the profile for review
+func (s *kycService) Submit(ctx context.Context, userID uint, actor models.AuditActor) (*models.KYCProfile, error) {
+	profile, err := s.kycRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Editable() {
+		return nil, ErrKYCLocked
+	}
+	if !profile.HasDocument(models.KYCDocumentIDFront) {
+		return nil, ErrKYCIncomplete
+	}
+
+	before := kycAudit{Status: profile.Status, Level: profile.Level}
+	now := time.Now()
+	profile.Status = models.KYCPending
+	profile.SubmittedAt = &now
+	profile.ReviewNote = ""
+
+	entry := models.NewAuditEntry(actor, models.AuditKYCSubmitted, models.AuditTargetUser, userID).
+		WithChange(before, kycAudit{Status: profile.Status, Level: profile.Level})
+	if err := s.kycRepo.Save(ctx, profile, entry); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetProfiles returns profiles in the given status, oldest submission first
+func (s *kycService) GetProfiles(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error) {
+	return s.kycRepo.FindByStatus(ctx, status, limit, offset)
+}
+
+// GetDocument returns a document and its decrypted content
+func (s *kycService) GetDocument(ctx context.Context, id uint) (*models.KYCDocument, []byte, error) {
+	document, err := s.kycRepo.FindDocument(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := s.options.Documents.Load(document.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyring, err := pii.Registered()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := keyring.Decrypt(string(ciphertext))
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, []byte(data), nil
+}
+
+// Approve verifies a pending profile at the given level. The full level
+// needs a proof of address document.
+func (s *kycService) Approve(ctx context.Context, userID, adminID uint, level int, actor models.AuditActor) error {
+	if level != models.KYCLevelBasic && level != models.KYCLevelFull {
+		return errors.New("level must be 1 or 2")
+	}
+	profile, err := s.pendingProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if level == models.KYCLevelFull && !profile.HasDocument(models.KYCDocumentProofOfAddress) {
+		return errors.New("level 2 needs a proof of address document")
+	}
+
+	return s.review(ctx, profile, models.KYCVerified, level, "", adminID, models.AuditKYCApproved, actor)
+}
+
+// Reject returns a pending profile to the user with the reason
+func (s *kycService) Reject(ctx context.Context, userID, adminID uint, reason string, actor models.AuditActor) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("a reason is required")
+	}
+	profile, err := s.pendingProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.review(ctx, profile, models.KYCRejected, models.KYCLevelNone, reason, adminID, models.AuditKYCRejected, actor)
+}
+
+// CheckTransfer returns ErrKYCRequired unless the user is verified, and
+// ErrKYCLimitExceeded when the amount is over their level's limits
+func (s *kycService) CheckTransfer(ctx context.Context, userID uint, amount float64) error {
+	profile, err := s.kycRepo.FindByUserID(ctx, userID)
+	return s.checkTransfer(profile, err, amount, func(since time.Time) (float64, error) {
+		return s.transactionRepo.SumTransfersByUser(ctx, userID, since)
+	})
+}
+
+// CheckTransferWithTx checks the transfer again in the transaction that makes
+// it, with the user's profile locked, so that transfers made at the same time
+// cannot together go over the daily limit
+func (s *kycService) CheckTransferWithTx(ctx context.Context, userID uint, amount float64, tx repository.GormTx) error {
+	profile, err := s.kycRepo.LockWithTx(ctx, userID, tx)
+	return s.checkTransfer(profile, err, amount, func(since time.Time) (float64, error) {
+		return s.transactionRepo.SumTransfersByUserWithTx(ctx, userID, since, tx)
+	})
+}
+
+// checkTransfer checks the amount against the limits of the profile found,
+// and what the user has transferred over the last day
+func (s *kycService) checkTransfer(profile *models.KYCProfile, err error, amount float64, transferredSince func(time.Time) (float64, error)) error {
+	if errors.Is(err, ErrKYCProfileNotFound) {
+		return ErrKYCRequired
+	}
+	if err != nil {
+		return err
+	}
+	if profile.Status != models.KYCVerified {
+		return ErrKYCRequired
+	}
+
+	limits := s.options.Limits[profile.Level]
+	if limits.PerTransfer > 0 && amount > limits.PerTransfer {
+		return ErrKYCLimitExceeded
+	}
+	if limits.Daily > 0 {
+		total, err := transferredSince(time.Now().Add(-24 * time.Hour))
+		if err != nil {
+			return err
+		}
+		if total+amount > limits.Daily {
+			return ErrKYCLimitExceeded
+		}
+	}
+	return nil
+}
+
+func (s *kycService) pendingProfile(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	profile, err := s.kycRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Status != models.KYCPending {
+		return nil, ErrKYCNotPending
+	}
+	return profile, nil
+}
+
+func (s *kycService) review(ctx context.Context, profile *models.KYCProfile, status string, level int, note string, adminID uint, action string, actor models.AuditActor) error {
+	before := kycAudit{Status: profile.Status, Level: profile.Level}
+	now := time.Now()
+	profile.Status = status
+	profile.Level = level
+	profile.ReviewNote = note
+	profile.ReviewedByID = &adminID
+	profile.ReviewedAt = &now
+
+	entry := models.NewAuditEntry(actor, action, models.AuditTargetUser, profile.UserID).
+		WithChange(before, kycAudit{Status: status, Level: level, Note: note})
+	return s.kycRepo.Review(ctx, profile, entry)
+}
+
+// validateDateOfBirth accepts a YYYY-MM-DD date of an adult
+func validateDateOfBirth(value string) error {
+	born, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return errors.New("date of birth must be formatted as YYYY-MM-DD")
+	}
+	if born.AddDate(minimumAge, 0, 0).After(time.Now()) {
+		return errors.New("customers must be at least 18 years old")
+	}
+	return nil
+}
+
+// kycAudit - Verification state as recorded in the audit log. Personal
+// details are left out so the audit log holds no PII.
+type kycAudit struct {
+	Status string `json:"status"`
+	Level  int    `json:"level"`
+	Note   string `json:"note,omitempty"`
+}
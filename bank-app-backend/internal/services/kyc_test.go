@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the KYC repository
+type MockKYCRepository struct {
+	mock.Mock
+}
+
+func (m *MockKYCRepository) FindByUserID(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCRepository) LockWithTx(ctx context.Context, userID uint, tx repository.GormTx) (*models.KYCProfile, error) {
+	args := m.Called(userID, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCRepository) FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCRepository) Save(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error {
+	args := m.Called(profile, entry)
+	return args.Error(0)
+}
+
+func (m *MockKYCRepository) Review(ctx context.Context, profile *models.KYCProfile, entry *models.AuditEntry) error {
+	args := m.Called(profile, entry)
+	return args.Error(0)
+}
+
+func (m *MockKYCRepository) AddDocument(ctx context.Context, document *models.KYCDocument) error {
+	args := m.Called(document)
+	return args.Error(0)
+}
+
+func (m *MockKYCRepository) FindDocument(ctx context.Context, id uint) (*models.KYCDocument, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCDocument), args.Error(1)
+}
+
+// Create a mock for the KYC service
+type MockKYCService struct {
+	mock.Mock
+}
+
+func (m *MockKYCService) GetProfile(ctx context.Context, userID uint) (*models.KYCProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) SaveProfile(ctx context.Context, userID uint, request *models.KYCProfileRequest) (*models.KYCProfile, error) {
+	args := m.Called(userID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) UploadDocument(ctx context.Context, userID uint, kind, fileName string, data []byte) (*models.KYCDocument, error) {
+	args := m.Called(userID, kind, fileName, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCDocument), args.Error(1)
+}
+
+func (m *MockKYCService) Submit(ctx context.Context, userID uint, actor models.AuditActor) (*models.KYCProfile, error) {
+	args := m.Called(userID, actor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) GetProfiles(ctx context.Context, status string, limit, offset int) ([]models.KYCProfile, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.KYCProfile), args.Error(1)
+}
+
+func (m *MockKYCService) GetDocument(ctx context.Context, id uint) (*models.KYCDocument, []byte, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.KYCDocument), args.Get(1).([]byte), args.Error(2)
+}
+
+func (m *MockKYCService) Approve(ctx context.Context, userID, adminID uint, level int, actor models.AuditActor) error {
+	args := m.Called(userID, adminID, level, actor)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) Reject(ctx context.Context, userID, adminID uint, reason string, actor models.AuditActor) error {
+	args := m.Called(userID, adminID, reason, actor)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) CheckTransfer(ctx context.Context, userID uint, amount float64) error {
+	args := m.Called(userID, amount)
+	return args.Error(0)
+}
+
+func (m *MockKYCService) CheckTransferWithTx(ctx context.Context, userID uint, amount float64, tx repository.GormTx) error {
+	args := m.Called(userID, amount, tx)
+	return args.Error(0)
+}
+
+// pngHeader is enough of a PNG file for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newTestKYCService stores documents in a temporary directory. Documents are
+// encrypted, so the development keyring is registered.
+func newTestKYCService(t *testing.T, kycRepo *MockKYCRepository, transactionRepo *MockTransactionRepository) KYCService {
+	pii.Register(pii.NewDevelopmentKeyring())
+	return NewKYCService(kycRepo, transactionRepo, KYCOptions{
+		Documents:       storage.NewLocalStore(t.TempDir()),
+		MaxDocumentSize: 1024,
+		Limits: map[int]KYCLimits{
+			models.KYCLevelBasic: {PerTransfer: 1000, Daily: 2500},
+		},
+	})
+}
+
+func validKYCProfileRequest() *models.KYCProfileRequest {
+	return &models.KYCProfileRequest{
+		DateOfBirth:  "1990-04-12",
+		AddressLine1: "1 Main St",
+		City:         "Springfield",
+		PostalCode:   "12345",
+		Country:      "us",
+		IDType:       models.IDTypePassport,
+		IDNumber:     "X1234567",
+	}
+}
+
+func TestKYCGetProfile_NotStarted(t *testing.T) {
+	// Create mocks
+	mockKYCRepo := new(MockKYCRepository)
+	service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+	// Set up expectations
+	mockKYCRepo.On("FindByUserID", uint(1)).Return(nil, ErrKYCProfileNotFound)
+
+	// Call the method being tested
+	profile, err := service.GetProfile(context.Background(), 1)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, models.KYCNotStarted, profile.Status)
+	assert.Equal(t, uint(1), profile.UserID)
+}
+
+func TestKYCSaveProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *models.KYCProfile
+		dob      string
+		expected error
+	}{
+		{"new profile is saved as a draft", nil, "1990-04-12", nil},
+		{"rejected profile becomes a draft again", &models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCRejected}, "1990-04-12", nil},
+		{"pending profile is locked", &models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCPending}, "1990-04-12", ErrKYCLocked},
+		{"verified profile is locked", &models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCVerified}, "1990-04-12", ErrKYCLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCRepo := new(MockKYCRepository)
+			if tt.existing == nil {
+				mockKYCRepo.On("FindByUserID", uint(1)).Return(nil, ErrKYCProfileNotFound)
+			} else {
+				mockKYCRepo.On("FindByUserID", uint(1)).Return(tt.existing, nil)
+			}
+			mockKYCRepo.On("Save", mock.MatchedBy(func(profile *models.KYCProfile) bool {
+				return profile.Status == models.KYCDraft && profile.Country == "US"
+			}), (*models.AuditEntry)(nil)).Return(nil)
+			service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+			request := validKYCProfileRequest()
+			request.DateOfBirth = tt.dob
+
+			// Act
+			profile, err := service.SaveProfile(context.Background(), 1, request)
+
+			// Assert
+			assert.Equal(t, tt.expected, err)
+			if tt.expected == nil {
+				assert.Equal(t, models.KYCDraft, profile.Status)
+				mockKYCRepo.AssertExpectations(t)
+			} else {
+				mockKYCRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestKYCSaveProfile_InvalidDateOfBirth(t *testing.T) {
+	minor := time.Now().AddDate(-17, 0, 0).Format("2006-01-02")
+
+	for _, dob := range []string{"12/04/1990", "1990-13-01", minor} {
+		t.Run(dob, func(t *testing.T) {
+			// Arrange
+			mockKYCRepo := new(MockKYCRepository)
+			service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+			request := validKYCProfileRequest()
+			request.DateOfBirth = dob
+
+			// Act
+			_, err := service.SaveProfile(context.Background(), 1, request)
+
+			// Assert
+			assert.Error(t, err)
+			mockKYCRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestKYCUploadDocument_EncryptsAndRoundTrips(t *testing.T) {
+	// Create mocks
+	mockKYCRepo := new(MockKYCRepository)
+	service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+	// Set up expectations
+	var stored *models.KYCDocument
+	mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCDraft}, nil)
+	mockKYCRepo.On("AddDocument", mock.AnythingOfType("*models.KYCDocument")).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.KYCDocument)
+		stored.ID = 5
+	}).Return(nil)
+
+	// Call the method being tested
+	document, err := service.UploadDocument(context.Background(), 1, models.KYCDocumentIDFront, "../../passport.png", pngHeader)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", document.ContentType)
+	assert.Equal(t, "passport.png", document.FileName)
+	assert.Equal(t, uint(3), document.ProfileID)
+	assert.Len(t, document.SHA256, 64)
+	assert.NotEmpty(t, document.StorageKey)
+
+	mockKYCRepo.On("FindDocument", uint(5)).Return(stored, nil)
+	downloaded, data, err := service.GetDocument(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, downloaded)
+	assert.Equal(t, pngHeader, data)
+}
+
+func TestKYCUploadDocument_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		data     []byte
+		status   string
+		expected error
+	}{
+		{"unsupported type", models.KYCDocumentIDFront, []byte("#!/bin/sh\necho hi\n"), models.KYCDraft, ErrDocumentType},
+		{"too large", models.KYCDocumentIDFront, append(append([]byte{}, pngHeader...), make([]byte, 2048)...), models.KYCDraft, ErrDocumentTooLarge},
+		{"pending profile", models.KYCDocumentIDFront, pngHeader, models.KYCPending, ErrKYCLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCRepo := new(MockKYCRepository)
+			mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{ID: 3, UserID: 1, Status: tt.status}, nil)
+			service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+			// Act
+			_, err := service.UploadDocument(context.Background(), 1, tt.kind, "file", tt.data)
+
+			// Assert
+			assert.Equal(t, tt.expected, err)
+			mockKYCRepo.AssertNotCalled(t, "AddDocument", mock.Anything)
+		})
+	}
+}
+
+func TestKYCSubmit(t *testing.T) {
+	t.Run("needs an identity document", func(t *testing.T) {
+		// Arrange
+		mockKYCRepo := new(MockKYCRepository)
+		mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCDraft}, nil)
+		service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+		// Act
+		_, err := service.Submit(context.Background(), 1, testActor)
+
+		// Assert
+		assert.Equal(t, ErrKYCIncomplete, err)
+	})
+
+	t.Run("sends the profile for review", func(t *testing.T) {
+		// Arrange
+		mockKYCRepo := new(MockKYCRepository)
+		mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{
+			ID: 3, UserID: 1, Status: models.KYCDraft,
+			Documents: []models.KYCDocument{{Kind: models.KYCDocumentIDFront}},
+		}, nil)
+		mockKYCRepo.On("Save", mock.MatchedBy(func(profile *models.KYCProfile) bool {
+			return profile.Status == models.KYCPending && profile.SubmittedAt != nil
+		}), auditEntryFor(models.AuditKYCSubmitted, models.AuditTargetUser, "1")).Return(nil)
+		service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+		// Act
+		profile, err := service.Submit(context.Background(), 1, testActor)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, models.KYCPending, profile.Status)
+		mockKYCRepo.AssertExpectations(t)
+	})
+}
+
+func TestKYCApprove(t *testing.T) {
+	idOnly := []models.KYCDocument{{Kind: models.KYCDocumentIDFront}}
+
+	tests := []struct {
+		name     string
+		status   string
+		level    int
+		expected string
+	}{
+		{"basic level with an identity document", models.KYCPending, models.KYCLevelBasic, ""},
+		{"full level needs proof of address", models.KYCPending, models.KYCLevelFull, "level 2 needs a proof of address document"},
+		{"only pending profiles", models.KYCDraft, models.KYCLevelBasic, ErrKYCNotPending.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCRepo := new(MockKYCRepository)
+			mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{ID: 3, UserID: 1, Status: tt.status, Documents: idOnly}, nil)
+			mockKYCRepo.On("Review", mock.MatchedBy(func(profile *models.KYCProfile) bool {
+				return profile.Status == models.KYCVerified && profile.Level == tt.level && *profile.ReviewedByID == 9
+			}), auditEntryFor(models.AuditKYCApproved, models.AuditTargetUser, "1")).Return(nil)
+			service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+			// Act
+			err := service.Approve(context.Background(), 1, 9, tt.level, testActor)
+
+			// Assert
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				mockKYCRepo.AssertExpectations(t)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+				mockKYCRepo.AssertNotCalled(t, "Review", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestKYCReject(t *testing.T) {
+	// Create mocks
+	mockKYCRepo := new(MockKYCRepository)
+	service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+	// Set up expectations
+	mockKYCRepo.On("FindByUserID", uint(1)).Return(&models.KYCProfile{ID: 3, UserID: 1, Status: models.KYCPending}, nil)
+	mockKYCRepo.On("Review", mock.MatchedBy(func(profile *models.KYCProfile) bool {
+		return profile.Status == models.KYCRejected && profile.Level == models.KYCLevelNone && profile.ReviewNote == "Document is blurry"
+	}), auditEntryFor(models.AuditKYCRejected, models.AuditTargetUser, "1")).Return(nil)
+
+	// Call the method being tested
+	err := service.Reject(context.Background(), 1, 9, " Document is blurry ", testActor)
+
+	// Assert expectations
+	assert.NoError(t, err)
+	mockKYCRepo.AssertExpectations(t)
+}
+
+func TestKYCCheckTransfer(t *testing.T) {
+	verified := &models.KYCProfile{UserID: 1, Status: models.KYCVerified, Level: models.KYCLevelBasic}
+
+	tests := []struct {
+		name     string
+		profile  *models.KYCProfile
+		amount   float64
+		sent     float64
+		expected error
+	}{
+		{"no profile", nil, 10, 0, ErrKYCRequired},
+		{"pending profile", &models.KYCProfile{UserID: 1, Status: models.KYCPending}, 10, 0, ErrKYCRequired},
+		{"within limits", verified, 500, 1000, nil},
+		{"over the per-transfer limit", verified, 1500, 0, ErrKYCLimitExceeded},
+		{"over the daily limit", verified, 600, 2000, ErrKYCLimitExceeded},
+		{"level without limits", &models.KYCProfile{UserID: 1, Status: models.KYCVerified, Level: models.KYCLevelFull}, 50000, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockKYCRepo := new(MockKYCRepository)
+			mockTransactionRepo := new(MockTransactionRepository)
+			if tt.profile == nil {
+				mockKYCRepo.On("FindByUserID", uint(1)).Return(nil, ErrKYCProfileNotFound)
+			} else {
+				mockKYCRepo.On("FindByUserID", uint(1)).Return(tt.profile, nil)
+			}
+			mockTransactionRepo.On("SumTransfersByUser", uint(1), mock.AnythingOfType("time.Time")).Return(tt.sent, nil)
+			service := newTestKYCService(t, mockKYCRepo, mockTransactionRepo)
+
+			// Act
+			err := service.CheckTransfer(context.Background(), 1, tt.amount)
+
+			// Assert
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
+
+func TestKYCCheckTransfer_RepositoryError(t *testing.T) {
+	// Create mocks
+	mockKYCRepo := new(MockKYCRepository)
+	service := newTestKYCService(t, mockKYCRepo, new(MockTransactionRepository))
+
+	// Set up expectations
+	mockKYCRepo.On("FindByUserID", uint(1)).Return(nil, errors.New("database error"))
+
+	// Call the method being tested
+	err := service.CheckTransfer(context.Background(), 1, 10)
+
+	// Assert expectations
+	assert.EqualError(t, err, "database error")
+}
+
+func TestKYCCheckTransferWithTx(t *testing.T) {
+	// Create mocks
+	mockKYCRepo := new(MockKYCRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	service := newTestKYCService(t, mockKYCRepo, mockTransactionRepo)
+	tx := new(MockDB)
+
+	// Set up expectations - the profile is locked and the day's transfers
+	// summed in the transfer's own transaction
+	verified := &models.KYCProfile{UserID: 1, Status: models.KYCVerified, Level: models.KYCLevelBasic}
+	mockKYCRepo.On("LockWithTx", uint(1), tx).Return(verified, nil)
+	mockTransactionRepo.On("SumTransfersByUserWithTx", uint(1), mock.AnythingOfType("time.Time"), tx).Return(2000.0, nil)
+
+	// Call the method being tested
+	err := service.CheckTransferWithTx(context.Background(), 1, 600, tx)
+
+	// Assert expectations
+	assert.Equal(t, ErrKYCLimitExceeded, err)
+	mockKYCRepo.AssertExpectations(t)
+	mockKYCRepo.AssertNotCalled(t, "FindByUserID", mock.Anything)
+	mockTransactionRepo.AssertNotCalled(t, "SumTransfersByUser", mock.Anything, mock.Anything)
+}
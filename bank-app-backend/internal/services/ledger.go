@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+// ledgerTolerance absorbs float rounding when comparing balances, which are
+// kept to the cent
+const ledgerTolerance = 0.005
+
+// LedgerService - Checks and end-of-day postings over all accounts
+type LedgerService interface {
+	Reconcile(ctx context.Context) (*models.ReconcileResult, error)
+	AccrueInterest(ctx context.Context, date time.Time, annualRate float64, actor models.AuditActor) (*models.InterestResult, error)
+}
+
+type ledgerService struct {
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+}
+
+func NewLedgerService(accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository) LedgerService {
+	return &ledgerService{accountRepo, transactionRepo}
+}
+
+// Reconcile compares every account's balance with the sum of its transactions
+// and with the running balance its newest transaction recorded
+func (s *ledgerService) Reconcile(ctx context.Context) (*models.ReconcileResult, error) {
+	accounts, err := s.accountRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.transactionRepo.LedgerTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byAccount := make(map[uint]models.LedgerTotals, len(totals))
+	for _, total := range totals {
+		byAccount[total.AccountID] = total
+	}
+
+	result := &models.ReconcileResult{Checked: len(accounts), Discrepancies: []models.Discrepancy{}}
+	for _, account := range accounts {
+		total := byAccount[account.ID]
+		matches := math.Abs(account.Balance-total.Total) < ledgerTolerance
+		if total.Entries > 0 && math.Abs(account.Balance-total.LastBalance) >= ledgerTolerance {
+			matches = false
+		}
+		if matches {
+			continue
+		}
+		result.Discrepancies = append(result.Discrepancies, models.Discrepancy{
+			AccountID:     account.ID,
+			AccountNumber: account.AccountNumber,
+			Balance:       account.Balance,
+			LedgerTotal:   total.Total,
+			LastBalance:   total.LastBalance,
+			Entries:       total.Entries,
+		})
+	}
+	return result, nil
+}
+
+// AccrueInterest credits a day of interest at annualRate percent to every
+// savings account with a positive balance, on its balance when the run
+// starts. Each account is credited once per date, so a failed run can be
+// repeated.
+func (s *ledgerService) AccrueInterest(ctx context.Context, date time.Time, annualRate float64, actor models.AuditActor) (*models.InterestResult, error) {
+	if annualRate <= 0 {
+		return nil, fmt.Errorf("interest rate must be positive, got %g", annualRate)
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	accounts, err := s.accountRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.InterestResult{Date: date}
+	description := fmt.Sprintf("Interest for %s", date.Format("2006-01-02"))
+	for _, account := range accounts {
+		if account.AccountType != models.Savings || account.Balance <= 0 {
+			continue
+		}
+		amount := math.Round(account.Balance*annualRate/100/365*100) / 100
+		if amount < 0.01 {
+			result.Skipped++
+			continue
+		}
+
+		entry := models.NewAuditEntry(actor, models.AuditInterestAccrued, models.AuditTargetAccount, account.ID).
+			WithChange(nil, interestAudit{Date: date.Format("2006-01-02"), AnnualRate: annualRate, Amount: amount})
+		credited, err := s.accountRepo.CreditOnce(ctx, account.ID, amount, description, date, entry)
+		if err != nil {
+			return result, fmt.Errorf("account %s: %w", account.AccountNumber, err)
+		}
+		if !credited {
+			result.Skipped++
+			continue
+		}
+		result.Credited++
+		result.Total += amount
+	}
+	result.Total = math.Round(result.Total*100) / 100
+	return result, nil
+}
+
+// interestAudit - What the audit log keeps about an interest credit
+type interestAudit struct {
+	Date       string  `json:"date"`
+	AnnualRate float64 `json:"annualRate"`
+	Amount     float64 `json:"amount"`
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo.On("FindAll").Return([]models.Account{
+		{ID: 1, AccountNumber: "1000000001", Balance: 150},   // matches
+		{ID: 2, AccountNumber: "1000000002", Balance: 90},    // total disagrees
+		{ID: 3, AccountNumber: "1000000003", Balance: 0},     // no transactions
+		{ID: 4, AccountNumber: "1000000004", Balance: 25.10}, // running balance disagrees
+	}, nil)
+	mockTransactionRepo.On("LedgerTotals").Return([]models.LedgerTotals{
+		{AccountID: 1, Total: 150.0000001, LastBalance: 150, Entries: 3},
+		{AccountID: 2, Total: 100, LastBalance: 90, Entries: 2},
+		{AccountID: 4, Total: 25.10, LastBalance: 30, Entries: 1},
+	}, nil)
+
+	service := NewLedgerService(mockAccountRepo, mockTransactionRepo)
+
+	// Call the method being tested
+	result, err := service.Reconcile(context.Background())
+
+	// Assert expectations
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Checked)
+	require.Len(t, result.Discrepancies, 2)
+	assert.Equal(t, uint(2), result.Discrepancies[0].AccountID)
+	assert.Equal(t, 100.0, result.Discrepancies[0].LedgerTotal)
+	assert.Equal(t, uint(4), result.Discrepancies[1].AccountID)
+}
+
+func TestAccrueInterest(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockAccountRepo.On("FindAll").Return([]models.Account{
+		{ID: 1, AccountNumber: "1000000001", AccountType: models.Savings, Balance: 36500},
+		{ID: 2, AccountNumber: "1000000002", AccountType: models.Checking, Balance: 36500},
+		{ID: 3, AccountNumber: "1000000003", AccountType: models.Savings, Balance: 0},
+		{ID: 4, AccountNumber: "1000000004", AccountType: models.Savings, Balance: 1000},
+		{ID: 5, AccountNumber: "1000000005", AccountType: models.Savings, Balance: 1},
+	}, nil)
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockAccountRepo.On("CreditOnce", uint(1), 2.0, "Interest for 2024-03-01", date,
+		auditEntryFor(models.AuditInterestAccrued, models.AuditTargetAccount, "1")).Return(true, nil)
+	// Already credited by an earlier run
+	mockAccountRepo.On("CreditOnce", uint(4), 0.05, "Interest for 2024-03-01", date, mock.Anything).Return(false, nil)
+
+	service := NewLedgerService(mockAccountRepo, new(MockTransactionRepository))
+
+	// Call the method being tested, with a time of day that is ignored
+	result, err := service.AccrueInterest(context.Background(), date.Add(15*time.Hour), 2, operatorActor)
+
+	// Assert expectations
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Credited)
+	assert.Equal(t, 2, result.Skipped) // account 4 and the sub-cent account 5
+	assert.Equal(t, 2.0, result.Total)
+	mockAccountRepo.AssertExpectations(t)
+}
+
+func TestAccrueInterest_RejectsNonPositiveRate(t *testing.T) {
+	service := NewLedgerService(new(MockAccountRepository), new(MockTransactionRepository))
+
+	_, err := service.AccrueInterest(context.Background(), time.Now(), 0, operatorActor)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+const (
+	maxLoginDelay      = 30 * time.Second
+	loginAttemptsLimit = 100
+)
+
+// LoginAttemptOptions - Settings used by the login attempt service
+type LoginAttemptOptions struct {
+	MaxFailures     int           // Failed logins that lock an account
+	FailureWindow   time.Duration // Failures older than this are forgotten
+	LockoutDuration time.Duration
+	IPMaxFailures   int           // Failed logins from one address that block it for the failure window
+	DelayBase       time.Duration // Wait after the first failure, doubled after every further failure
+}
+
+// LoginThrottledError - Returned while an account is locked or a client has to
+// wait before trying again
+type LoginThrottledError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+type LoginAttemptService interface {
+	CheckAllowed(ctx context.Context, email, ipAddress string) error
+	RecordFailure(ctx context.Context, email string, actor models.AuditActor, userAgent, reason string) error
+	RecordSuccess(ctx context.Context, user *models.User, actor models.AuditActor, userAgent string) error
+	Unlock(ctx context.Context, userID uint, actor models.AuditActor) error
+	GetAttemptsByUserID(ctx context.Context, userID uint) ([]models.LoginAttempt, error)
+}
+
+type loginAttemptService struct {
+	loginAttemptRepo repository.LoginAttemptRepository
+	userRepo         repository.UserRepository
+	options          LoginAttemptOptions
+}
+
+func NewLoginAttemptService(loginAttemptRepo repository.LoginAttemptRepository, userRepo repository.UserRepository, options LoginAttemptOptions) LoginAttemptService {
+	return &loginAttemptService{loginAttemptRepo, userRepo, options}
+}
+
+// CheckAllowed returns a *LoginThrottledError when the account is locked, the
+// user has to wait after recent failures, or the address made too many
+// failed attempts. Unknown emails are only limited by address.
+func (s *loginAttemptService) CheckAllowed(ctx context.Context, email, ipAddress string) error {
+	err := s.checkAllowed(ctx, email, ipAddress)
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		metrics.Logins.WithLabelValues(metrics.LoginThrottled).Inc()
+	}
+	return err
+}
+
+func (s *loginAttemptService) checkAllowed(ctx context.Context, email, ipAddress string) error {
+	now := time.Now()
+
+	if user, err := s.userRepo.FindByEmail(ctx, email); err == nil {
+		if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+			return &LoginThrottledError{
+				Reason:     "account is temporarily locked",
+				RetryAfter: user.LockedUntil.Sub(now),
+			}
+		}
+
+		if user.FailedLoginCount > 0 && user.LastFailedLoginAt != nil && now.Sub(*user.LastFailedLoginAt) < s.options.FailureWindow {
+			next := user.LastFailedLoginAt.Add(s.delay(user.FailedLoginCount))
+			if now.Before(next) {
+				return &LoginThrottledError{
+					Reason:     "too many failed login attempts",
+					RetryAfter: next.Sub(now),
+				}
+			}
+		}
+	}
+
+	if s.options.IPMaxFailures > 0 {
+		failures, err := s.loginAttemptRepo.CountFailuresByIP(ctx, ipAddress, now.Add(-s.options.FailureWindow))
+		if err != nil {
+			return err
+		}
+		if failures >= int64(s.options.IPMaxFailures) {
+			return &LoginThrottledError{
+				Reason:     "too many failed login attempts from this address",
+				RetryAfter: s.options.FailureWindow,
+			}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure stores a failed attempt and locks the account once it reaches
+// the configured number of failures within the window
+func (s *loginAttemptService) RecordFailure(ctx context.Context, email string, actor models.AuditActor, userAgent, reason string) error {
+	index, err := pii.EmailIndex(email)
+	if err != nil {
+		return err
+	}
+	attempt := &models.LoginAttempt{
+		EmailIndex: index,
+		IPAddress:  actor.IPAddress,
+		UserAgent:  userAgent,
+		Reason:     reason,
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
+
+	// Failures for unknown emails have no target
+	entry := models.NewAuditEntry(actor, models.AuditLoginFailed, "", "")
+	audit := loginAudit{Reason: reason}
+
+	if user, err := s.userRepo.FindByEmail(ctx, email); err == nil {
+		attempt.UserID = &user.ID
+		entry.TargetType = models.AuditTargetUser
+		entry.TargetID = fmt.Sprint(user.ID)
+
+		now := time.Now()
+		count, err := s.userRepo.IncrementFailedLogins(ctx, user.ID, now.Add(-s.options.FailureWindow))
+		if err != nil {
+			return err
+		}
+		if s.options.MaxFailures > 0 && count >= s.options.MaxFailures {
+			lockedUntil := now.Add(s.options.LockoutDuration)
+			if err := s.userRepo.Lock(ctx, user.ID, lockedUntil); err != nil {
+				return err
+			}
+			audit.LockedUntil = &lockedUntil
+			actorLogger(actor).Warn("account locked after failed logins",
+				"target_user_id", user.ID, "failures", count, "locked_until", lockedUntil)
+		}
+	}
+
+	return s.loginAttemptRepo.Create(ctx, attempt, entry.WithChange(nil, audit))
+}
+
+// RecordSuccess stores a successful attempt and clears the user's failures
+func (s *loginAttemptService) RecordSuccess(ctx context.Context, user *models.User, actor models.AuditActor, userAgent string) error {
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(ctx, user.ID, nil); err != nil {
+			return err
+		}
+	}
+
+	index, err := pii.EmailIndex(user.Email)
+	if err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actor.AsUser(user.ID), models.AuditLoginSucceeded, models.AuditTargetUser, user.ID)
+	return s.loginAttemptRepo.Create(ctx, &models.LoginAttempt{
+		UserID:     &user.ID,
+		EmailIndex: index,
+		IPAddress:  actor.IPAddress,
+		UserAgent:  userAgent,
+		Success:    true,
+	}, entry)
+}
+
+// Unlock lifts a lockout before it expires
+func (s *loginAttemptService) Unlock(ctx context.Context, userID uint, actor models.AuditActor) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actor, models.AuditUserUnlocked, models.AuditTargetUser, userID).
+		WithChange(lockoutAudit{FailedLoginCount: user.FailedLoginCount, LockedUntil: user.LockedUntil}, lockoutAudit{})
+	return s.userRepo.ResetFailedLogins(ctx, userID, entry)
+}
+
+// GetAttemptsByUserID returns the most recent login attempts against a user's account
+func (s *loginAttemptService) GetAttemptsByUserID(ctx context.Context, userID uint) ([]models.LoginAttempt, error) {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.loginAttemptRepo.FindByUserID(ctx, userID, loginAttemptsLimit)
+}
+
+// loginAudit - What the audit log keeps about a failed login. The email is
+// left out, as it is PII; the entry's target is the user it matched.
+type loginAudit struct {
+	Reason      string     `json:"reason,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"` // Set when the failure locked the account
+}
+
+// lockoutAudit - A user's lockout state as recorded in the audit log
+type lockoutAudit struct {
+	FailedLoginCount int        `json:"failedLoginCount"`
+	LockedUntil      *time.Time `json:"lockedUntil"`
+}
+
+// delay is how long a user has to wait after the given number of consecutive failures
+func (s *loginAttemptService) delay(failures int) time.Duration {
+	delay := s.options.DelayBase
+	for i := 1; i < failures && delay < maxLoginDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	return delay
+}
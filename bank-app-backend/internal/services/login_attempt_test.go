@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the login attempt repository
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt, entry *models.AuditEntry) error {
+	args := m.Called(attempt, entry)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error) {
+	args := m.Called(ipAddress, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) FindByUserID(ctx context.Context, userID uint, limit int) ([]models.LoginAttempt, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
+
+// loginActor is an anonymous caller of the login endpoint
+var loginActor = models.AuditActor{Type: models.AuditActorAnonymous, IPAddress: "10.0.0.1", RequestID: "req-1"}
+
+func newTestLoginAttemptService(loginAttemptRepo *MockLoginAttemptRepository, userRepo *MockUserRepository) LoginAttemptService {
+	pii.Register(pii.NewDevelopmentKeyring())
+	return NewLoginAttemptService(loginAttemptRepo, userRepo, LoginAttemptOptions{
+		MaxFailures:     5,
+		FailureWindow:   15 * time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		IPMaxFailures:   20,
+		DelayBase:       time.Second,
+	})
+}
+
+func TestCheckAllowed_NoFailures(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockUserRepo.On("FindByEmail", "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockLoginAttemptRepo.On("CountFailuresByIP", "10.0.0.1", mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockLoginAttemptRepo.AssertExpectations(t)
+}
+
+func TestCheckAllowed_Locked(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	mockUserRepo.On("FindByEmail", "test@example.com").Return(&models.User{ID: 1, LockedUntil: &lockedUntil}, nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+	
+	// Assert expectations
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.Equal(t, "account is temporarily locked", throttled.Reason)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), throttled.RetryAfter.Seconds(), 1)
+}
+
+func TestCheckAllowed_ExpiredLock(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	lockedUntil := time.Now().Add(-time.Minute)
+	lastFailure := time.Now().Add(-16 * time.Minute)
+	mockUserRepo.On("FindByEmail", "test@example.com").
+		Return(&models.User{ID: 1, FailedLoginCount: 5, LastFailedLoginAt: &lastFailure, LockedUntil: &lockedUntil}, nil)
+	mockLoginAttemptRepo.On("CountFailuresByIP", "10.0.0.1", mock.AnythingOfType("time.Time")).Return(int64(5), nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+}
+
+func TestCheckAllowed_ProgressiveDelay(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	// Three failures require a four second wait, two have passed
+	lastFailure := time.Now().Add(-2 * time.Second)
+	mockUserRepo.On("FindByEmail", "test@example.com").
+		Return(&models.User{ID: 1, FailedLoginCount: 3, LastFailedLoginAt: &lastFailure}, nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.CheckAllowed(context.Background(), "test@example.com", "10.0.0.1")
+	
+	// Assert expectations
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.InDelta(t, 2, throttled.RetryAfter.Seconds(), 0.5)
+}
+
+func TestCheckAllowed_TooManyFailuresFromIP(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	// Unknown emails are still limited by address
+	mockUserRepo.On("FindByEmail", "unknown@example.com").Return(nil, errors.New("user not found"))
+	mockLoginAttemptRepo.On("CountFailuresByIP", "10.0.0.1", mock.AnythingOfType("time.Time")).Return(int64(20), nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.CheckAllowed(context.Background(), "unknown@example.com", "10.0.0.1")
+	
+	// Assert expectations
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.Equal(t, 15*time.Minute, throttled.RetryAfter)
+}
+
+func TestRecordFailure_LocksAfterMaxFailures(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockUserRepo.On("FindByEmail", "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockUserRepo.On("IncrementFailedLogins", uint(1), mock.AnythingOfType("time.Time")).Return(5, nil)
+	mockUserRepo.On("Lock", uint(1), mock.AnythingOfType("time.Time")).Return(nil)
+	mockLoginAttemptRepo.On("Create", mock.MatchedBy(func(attempt *models.LoginAttempt) bool {
+		return attempt.UserID != nil && *attempt.UserID == 1 && !attempt.Success &&
+			attempt.IPAddress == "10.0.0.1" && attempt.UserAgent == "test-agent"
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditLoginFailed && entry.TargetID == "1" &&
+			entry.ActorType == models.AuditActorAnonymous && strings.Contains(entry.After, "lockedUntil")
+	})).Return(nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.RecordFailure(context.Background(), "test@example.com", loginActor, "test-agent", "invalid email or password")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockLoginAttemptRepo.AssertExpectations(t)
+}
+
+func TestRecordFailure_BelowMaxFailures(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockUserRepo.On("FindByEmail", "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockUserRepo.On("IncrementFailedLogins", uint(1), mock.AnythingOfType("time.Time")).Return(2, nil)
+	mockLoginAttemptRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.RecordFailure(context.Background(), "test@example.com", loginActor, "test-agent", "invalid email or password")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything)
+}
+
+func TestRecordFailure_UnknownEmail(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockUserRepo.On("FindByEmail", "unknown@example.com").Return(nil, errors.New("user not found"))
+	mockLoginAttemptRepo.On("Create", mock.MatchedBy(func(attempt *models.LoginAttempt) bool {
+		// Only the blind index of the email is kept, as the email is PII
+		return attempt.UserID == nil && attempt.EmailIndex == pii.NewDevelopmentKeyring().EmailIndex("unknown@example.com")
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditLoginFailed && entry.TargetID == "" && !strings.Contains(entry.After, "unknown@example.com")
+	})).Return(nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.RecordFailure(context.Background(), "unknown@example.com", loginActor, "test-agent", "invalid email or password")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockLoginAttemptRepo.AssertExpectations(t)
+}
+
+func TestRecordSuccess_ResetsFailures(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	user := &models.User{ID: 1, Email: "test@example.com", FailedLoginCount: 2}
+	mockUserRepo.On("ResetFailedLogins", uint(1), (*models.AuditEntry)(nil)).Return(nil)
+	mockLoginAttemptRepo.On("Create", mock.MatchedBy(func(attempt *models.LoginAttempt) bool {
+		return attempt.Success && *attempt.UserID == 1
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		// The anonymous login request is attributed to the user it authenticated
+		return entry.Action == models.AuditLoginSucceeded && entry.ActorType == models.AuditActorUser && entry.ActorID == "1"
+	})).Return(nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.RecordSuccess(context.Background(), user, loginActor, "test-agent")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockLoginAttemptRepo.AssertExpectations(t)
+}
+
+func TestUnlock_UserNotFound(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockUserRepo.On("FindByID", uint(99)).Return(nil, errors.New("user not found"))
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.Unlock(context.Background(), 99, testActor)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockUserRepo.AssertNotCalled(t, "ResetFailedLogins", mock.Anything, mock.Anything)
+}
+
+func TestUnlock_Audited(t *testing.T) {
+	// Create mocks
+	mockLoginAttemptRepo := new(MockLoginAttemptRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	lockedUntil := time.Now().Add(time.Hour)
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, FailedLoginCount: 5, LockedUntil: &lockedUntil}, nil)
+	mockUserRepo.On("ResetFailedLogins", uint(1), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditUserUnlocked && entry.TargetID == "1" && entry.ActorID == testActor.ID &&
+			strings.Contains(entry.Before, `"failedLoginCount":5`)
+	})).Return(nil)
+	
+	service := newTestLoginAttemptService(mockLoginAttemptRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.Unlock(context.Background(), 1, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
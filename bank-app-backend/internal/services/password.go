@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+var (
+	// ErrPasswordPolicy is wrapped by every password policy violation
+	ErrPasswordPolicy = errors.New("password does not meet the password policy")
+	// ErrWrongPassword is returned when the current password given to change it is wrong
+	ErrWrongPassword = errors.New("current password is incorrect")
+)
+
+// PasswordOptions - Password policy and hashing settings
+type PasswordOptions struct {
+	MinLength  int               // In characters
+	BcryptCost int               // Cost of new hashes, older hashes are upgraded at login
+	Breached   BreachedPasswords // Passwords that cannot be chosen, may be nil
+}
+
+// BreachedPasswords - Set of SHA-1 hashes of passwords known from breaches
+type BreachedPasswords map[string]struct{}
+
+// LoadBreachedPasswords reads a breached password list with one entry per
+// line. An entry is either a password or, as in the Pwned Passwords
+// downloads, its SHA-1 hash in hex optionally followed by ":<count>".
+func LoadBreachedPasswords(path string) (BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := BreachedPasswords{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if hash := strings.SplitN(line, ":", 2)[0]; isSHA1Hex(hash) {
+			breached[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		breached[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
+
+// Contains reports whether the password is on the list
+func (b BreachedPasswords) Contains(password string) bool {
+	_, found := b[sha1Hex(password)]
+	return found
+}
+
+type PasswordService interface {
+	Validate(password string) error
+	SetPassword(ctx context.Context, user *models.User, password string) error
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string, actor models.AuditActor) error
+	RehashIfNeeded(ctx context.Context, user *models.User, password string) error
+}
+
+type passwordService struct {
+	userRepo repository.UserRepository
+	options  PasswordOptions
+}
+
+func NewPasswordService(userRepo repository.UserRepository, options PasswordOptions) PasswordService {
+	return &passwordService{userRepo, options}
+}
+
+// Validate checks a new password against the password policy
+func (s *passwordService) Validate(password string) error {
+	if utf8.RuneCountInString(password) < s.options.MinLength {
+		return fmt.Errorf("%w: it must be at least %d characters long", ErrPasswordPolicy, s.options.MinLength)
+	}
+	if len(password) > models.MaxPasswordBytes {
+		return fmt.Errorf("%w: it must be at most %d bytes long", ErrPasswordPolicy, models.MaxPasswordBytes)
+	}
+	if s.options.Breached.Contains(password) {
+		return fmt.Errorf("%w: it has appeared in a data breach, choose another one", ErrPasswordPolicy)
+	}
+	return nil
+}
+
+// SetPassword validates a new password and hashes it into the user. The
+// caller stores the user.
+func (s *passwordService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	if err := s.Validate(password); err != nil {
+		return err
+	}
+	return user.SetPassword(password, s.options.BcryptCost)
+}
+
+// ChangePassword replaces the user's password after checking the current one
+func (s *passwordService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string, actor models.AuditActor) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := user.ComparePassword(currentPassword); err != nil {
+		return ErrWrongPassword
+	}
+	if newPassword == currentPassword {
+		return fmt.Errorf("%w: it must differ from the current password", ErrPasswordPolicy)
+	}
+
+	if err := s.SetPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actor, models.AuditPasswordChanged, models.AuditTargetUser, user.ID)
+	return s.userRepo.UpdatePassword(ctx, user.ID, user.Password, entry)
+}
+
+// RehashIfNeeded upgrades the user's password hash to the configured bcrypt
+// cost. It must only be called with a password that has just been verified.
+func (s *passwordService) RehashIfNeeded(ctx context.Context, user *models.User, password string) error {
+	if !user.PasswordNeedsRehash(s.options.BcryptCost) {
+		return nil
+	}
+	if err := user.SetPassword(password, s.options.BcryptCost); err != nil {
+		return err
+	}
+	// The password itself is unchanged, so there is nothing to audit
+	return s.userRepo.UpdatePassword(ctx, user.ID, user.Password, nil)
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestPasswordService(userRepo *MockUserRepository, breached BreachedPasswords) PasswordService {
+	return NewPasswordService(userRepo, PasswordOptions{
+		MinLength:  8,
+		BcryptCost: bcrypt.MinCost + 1,
+		Breached:   breached,
+	})
+}
+
+// testUserWithPassword returns a user whose password was hashed with the given cost
+func testUserWithPassword(password string, cost int) *models.User {
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	user.SetPassword(password, cost)
+	return user
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	// Plain passwords and Pwned Passwords hashes may be mixed
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "password123\n\n  qwertyuiop  \n" + sha1Hex("letmein2024") + ":1234\n" + "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	
+	// Call the method being tested
+	breached, err := LoadBreachedPasswords(path)
+	
+	// Assert expectations - the last hash is of "password"
+	assert.NoError(t, err)
+	assert.Len(t, breached, 4)
+	assert.True(t, breached.Contains("password123"))
+	assert.True(t, breached.Contains("qwertyuiop"))
+	assert.True(t, breached.Contains("letmein2024"))
+	assert.True(t, breached.Contains("password"))
+	assert.False(t, breached.Contains("correct horse battery staple"))
+}
+
+func TestValidatePassword(t *testing.T) {
+	breached := BreachedPasswords{sha1Hex("password123"): {}}
+	service := newTestPasswordService(new(MockUserRepository), breached)
+	
+	assert.NoError(t, service.Validate("correct horse battery staple"))
+	assert.NoError(t, service.Validate("pässwörd")) // Eight characters, ten bytes
+	assert.ErrorIs(t, service.Validate("short"), ErrPasswordPolicy)
+	assert.ErrorIs(t, service.Validate(string(make([]byte, 73))), ErrPasswordPolicy)
+	assert.ErrorIs(t, service.Validate("password123"), ErrPasswordPolicy)
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", uint(1)).Return(testUserWithPassword("password123", bcrypt.MinCost), nil)
+	mockUserRepo.On("UpdatePassword", uint(1), mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password-456")) == nil
+	}), auditEntryFor(models.AuditPasswordChanged, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestPasswordService(mockUserRepo, nil)
+	
+	// Call the method being tested
+	err := service.ChangePassword(context.Background(), 1, "password123", "new-password-456", testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", uint(1)).Return(testUserWithPassword("password123", bcrypt.MinCost), nil)
+	
+	service := newTestPasswordService(mockUserRepo, nil)
+	
+	// Call the method being tested
+	err := service.ChangePassword(context.Background(), 1, "wrongpassword", "new-password-456", testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_RejectsPolicyViolations(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("FindByID", uint(1)).Return(testUserWithPassword("password123", bcrypt.MinCost), nil)
+	
+	service := newTestPasswordService(mockUserRepo, nil)
+	
+	// Call the method being tested
+	tooShort := service.ChangePassword(context.Background(), 1, "password123", "short", testActor)
+	unchanged := service.ChangePassword(context.Background(), 1, "password123", "password123", testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, tooShort, ErrPasswordPolicy)
+	assert.ErrorIs(t, unchanged, ErrPasswordPolicy)
+	mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRehashIfNeeded_UpgradesCost(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("UpdatePassword", uint(1), mock.MatchedBy(func(hash string) bool {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err == nil && cost == bcrypt.MinCost+1
+	}), (*models.AuditEntry)(nil)).Return(nil)
+	
+	service := newTestPasswordService(mockUserRepo, nil)
+	user := testUserWithPassword("password123", bcrypt.MinCost)
+	
+	// Call the method being tested
+	err := service.RehashIfNeeded(context.Background(), user, "password123")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.NoError(t, user.ComparePassword("password123"))
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestRehashIfNeeded_CurrentCost(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	service := newTestPasswordService(mockUserRepo, nil)
+	
+	// Call the method being tested
+	err := service.RehashIfNeeded(context.Background(), testUserWithPassword("password123", bcrypt.MinCost+1), "password123")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
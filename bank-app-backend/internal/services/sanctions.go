@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/sanctions"
+)
+
+// ErrHitAlreadyCleared is returned when clearing a screening hit twice
+var ErrHitAlreadyCleared = repository.ErrHitAlreadyCleared
+
+// SanctionsOptions - Watchlist and the match scores that decide an action's fate
+type SanctionsOptions struct {
+	List       *sanctions.List
+	FlagScore  float64 // Matches scoring at least this (0 to 1) are recorded for review
+	BlockScore float64 // Matches scoring at least this refuse the action, 0 never blocks
+}
+
+// SanctionsService screens names against the watchlist
+type SanctionsService interface {
+	Screen(ctx context.Context, name, email string, subjectID *uint, screeningContext string) (*models.ScreeningResult, error)
+	ScreenUser(ctx context.Context, userID uint, screeningContext string) (*models.ScreeningResult, error)
+	Record(ctx context.Context, result *models.ScreeningResult, subjectID *uint) error
+	GetHits(ctx context.Context, status string, limit, offset int) ([]models.ScreeningHit, error)
+	ClearHit(ctx context.Context, id, adminID uint, justification string, actor models.AuditActor) error
+}
+
+type sanctionsService struct {
+	hitRepo  repository.ScreeningHitRepository
+	userRepo repository.UserRepository
+	options  SanctionsOptions
+}
+
+func NewSanctionsService(hitRepo repository.ScreeningHitRepository, userRepo repository.UserRepository, options SanctionsOptions) SanctionsService {
+	return &sanctionsService{hitRepo, userRepo, options}
+}
+
+// Screen matches a name against the watchlist without recording anything.
+// Entries cleared for the subject, or for a registration refused with the
+// same email, are skipped. The result only holds hits that are not recorded
+// already.
+func (s *sanctionsService) Screen(ctx context.Context, name, email string, subjectID *uint, screeningContext string) (*models.ScreeningResult, error) {
+	result := &models.ScreeningResult{Name: name, Decision: models.ScreeningClear}
+	if s.options.List == nil {
+		return result, nil
+	}
+	matches := s.options.List.Screen(name, s.options.FlagScore)
+	if len(matches) == 0 {
+		return result, nil
+	}
+
+	nameIndex, err := pii.BlindIndex(sanctions.Normalize(name))
+	if err != nil {
+		return nil, err
+	}
+	emailIndex, err := pii.EmailIndex(email)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.hitRepo.FindBySubject(ctx, subjectID, emailIndex)
+	if err != nil {
+		return nil, err
+	}
+	cleared, recorded := map[string]bool{}, map[string]bool{}
+	for _, hit := range existing {
+		if hit.Status == models.HitCleared {
+			cleared[hit.EntryID] = true
+		} else if hit.SubjectID != nil || subjectID == nil {
+			recorded[hit.EntryID] = true
+		}
+	}
+
+	for _, match := range matches {
+		if cleared[match.Entry.ID] {
+			continue
+		}
+		status := models.HitFlagged
+		if s.options.BlockScore > 0 && match.Score >= s.options.BlockScore {
+			status = models.HitBlocked
+			result.Decision = models.ScreeningBlock
+		} else if result.Decision == models.ScreeningClear {
+			result.Decision = models.ScreeningFlag
+		}
+		if recorded[match.Entry.ID] {
+			continue
+		}
+		result.Hits = append(result.Hits, models.ScreeningHit{
+			Name:        name,
+			NameIndex:   nameIndex,
+			EmailIndex:  emailIndex,
+			Context:     screeningContext,
+			EntryID:     match.Entry.ID,
+			EntryName:   match.Entry.Name,
+			MatchedName: match.MatchedName,
+			Program:     match.Entry.Program,
+			Score:       match.Score,
+			Status:      status,
+		})
+	}
+	return result, nil
+}
+
+// ScreenUser screens a user's full name and records any new hits against them
+func (s *sanctionsService) ScreenUser(ctx context.Context, userID uint, screeningContext string) (*models.ScreeningResult, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.Screen(ctx, user.FullName(), user.Email, &userID, screeningContext)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Record(ctx, result, &userID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Record stores the result's hits against the subject, which may be nil
+func (s *sanctionsService) Record(ctx context.Context, result *models.ScreeningResult, subjectID *uint) error {
+	for i := range result.Hits {
+		result.Hits[i].SubjectID = subjectID
+	}
+	return s.hitRepo.Create(ctx, result.Hits)
+}
+
+// GetHits returns screening hits oldest first. An empty status returns the
+// hits still waiting to be cleared.
+func (s *sanctionsService) GetHits(ctx context.Context, status string, limit, offset int) ([]models.ScreeningHit, error) {
+	statuses := []string{models.HitFlagged, models.HitBlocked}
+	if status != "" {
+		statuses = []string{status}
+	}
+	return s.hitRepo.FindByStatus(ctx, statuses, limit, offset)
+}
+
+// ClearHit marks a hit as a false positive. The subject is not matched
+// against the hit's entry again.
+func (s *sanctionsService) ClearHit(ctx context.Context, id, adminID uint, justification string, actor models.AuditActor) error {
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return errors.New("a justification is required")
+	}
+
+	hit, err := s.hitRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hit.Status == models.HitCleared {
+		return ErrHitAlreadyCleared
+	}
+
+	before := hitAudit{Status: hit.Status, EntryID: hit.EntryID, Score: hit.Score}
+	now := time.Now()
+	hit.Status = models.HitCleared
+	hit.ClearedByID = &adminID
+	hit.Justification = justification
+	hit.ClearedAt = &now
+
+	entry := models.NewAuditEntry(actor, models.AuditScreeningHitCleared, models.AuditTargetScreeningHit, hit.ID).
+		WithChange(before, hitAudit{Status: hit.Status, EntryID: hit.EntryID, Score: hit.Score, Justification: justification})
+	return s.hitRepo.Clear(ctx, hit, entry)
+}
+
+// hitAudit - Screening hit as recorded in the audit log
+type hitAudit struct {
+	Status        string  `json:"status"`
+	EntryID       string  `json:"entryId"`
+	Score         float64 `json:"score"`
+	Justification string  `json:"justification,omitempty"`
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/sanctions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the screening hit repository
+type MockScreeningHitRepository struct {
+	mock.Mock
+}
+
+func (m *MockScreeningHitRepository) Create(ctx context.Context, hits []models.ScreeningHit) error {
+	args := m.Called(hits)
+	return args.Error(0)
+}
+
+func (m *MockScreeningHitRepository) FindByID(ctx context.Context, id uint) (*models.ScreeningHit, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningHitRepository) FindBySubject(ctx context.Context, subjectID *uint, emailIndex string) ([]models.ScreeningHit, error) {
+	args := m.Called(subjectID, emailIndex)
+	return args.Get(0).([]models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningHitRepository) FindByStatus(ctx context.Context, statuses []string, limit, offset int) ([]models.ScreeningHit, error) {
+	args := m.Called(statuses, limit, offset)
+	return args.Get(0).([]models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningHitRepository) Clear(ctx context.Context, hit *models.ScreeningHit, entry *models.AuditEntry) error {
+	args := m.Called(hit, entry)
+	return args.Error(0)
+}
+
+// Create a mock for the sanctions service
+type MockSanctionsService struct {
+	mock.Mock
+}
+
+func (m *MockSanctionsService) Screen(ctx context.Context, name, email string, subjectID *uint, context string) (*models.ScreeningResult, error) {
+	args := m.Called(name, email, subjectID, context)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningResult), args.Error(1)
+}
+
+func (m *MockSanctionsService) ScreenUser(ctx context.Context, userID uint, context string) (*models.ScreeningResult, error) {
+	args := m.Called(userID, context)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningResult), args.Error(1)
+}
+
+func (m *MockSanctionsService) Record(ctx context.Context, result *models.ScreeningResult, subjectID *uint) error {
+	args := m.Called(result, subjectID)
+	return args.Error(0)
+}
+
+func (m *MockSanctionsService) GetHits(ctx context.Context, status string, limit, offset int) ([]models.ScreeningHit, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockSanctionsService) ClearHit(ctx context.Context, id, adminID uint, justification string, actor models.AuditActor) error {
+	args := m.Called(id, adminID, justification, actor)
+	return args.Error(0)
+}
+
+// newTestSanctionsService screens against a one-entry watchlist. Hits store
+// a blind index of the name, so the development keyring is registered.
+func newTestSanctionsService(hitRepo *MockScreeningHitRepository, userRepo *MockUserRepository) SanctionsService {
+	pii.Register(pii.NewDevelopmentKeyring())
+	list := sanctions.NewList([]sanctions.Entry{{ID: "9001", Name: "VOLKOV, Ivan", Program: "SDGT"}})
+	return NewSanctionsService(hitRepo, userRepo, SanctionsOptions{List: list, FlagScore: 0.85, BlockScore: 0.95})
+}
+
+func TestSanctionsScreen(t *testing.T) {
+	subjectID := uint(7)
+
+	tests := []struct {
+		name     string
+		screened string
+		existing []models.ScreeningHit
+		decision string
+		hits     int
+	}{
+		{"no match", "Jane Doe", nil, models.ScreeningClear, 0},
+		{"exact match blocks", "Ivan Volkov", []models.ScreeningHit{}, models.ScreeningBlock, 1},
+		{"close match flags", "Ivana Volk", []models.ScreeningHit{}, models.ScreeningFlag, 1},
+		{"cleared entry is skipped", "Ivan Volkov", []models.ScreeningHit{{EntryID: "9001", Status: models.HitCleared}}, models.ScreeningClear, 0},
+		{"recorded hit still decides but is not recorded again", "Ivan Volkov", []models.ScreeningHit{{SubjectID: &subjectID, EntryID: "9001", Status: models.HitBlocked}}, models.ScreeningBlock, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockHitRepo := new(MockScreeningHitRepository)
+			if tt.existing != nil {
+				mockHitRepo.On("FindBySubject", &subjectID, mock.AnythingOfType("string")).Return(tt.existing, nil)
+			}
+			service := newTestSanctionsService(mockHitRepo, new(MockUserRepository))
+
+			// Act
+			result, err := service.Screen(context.Background(), tt.screened, "ivan@example.com", &subjectID, models.ScreeningTransfer)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.decision, result.Decision)
+			assert.Len(t, result.Hits, tt.hits)
+			mockHitRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSanctionsScreenUser_RecordsHits(t *testing.T) {
+	// Create mocks
+	mockHitRepo := new(MockScreeningHitRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := newTestSanctionsService(mockHitRepo, mockUserRepo)
+	
+	// Set up expectations
+	mockUserRepo.On("FindByID", uint(7)).Return(&models.User{ID: 7, Email: "ivan@example.com", FirstName: "Ivan", LastName: "Volkov"}, nil)
+	mockHitRepo.On("FindBySubject", mock.Anything, mock.AnythingOfType("string")).Return([]models.ScreeningHit{}, nil)
+	mockHitRepo.On("Create", mock.MatchedBy(func(hits []models.ScreeningHit) bool {
+		return len(hits) == 1 && *hits[0].SubjectID == 7 && hits[0].EntryID == "9001" &&
+			hits[0].Status == models.HitBlocked && hits[0].Context == models.ScreeningTransfer && hits[0].NameIndex != "" && hits[0].EmailIndex != ""
+	})).Return(nil)
+	
+	// Call the method being tested
+	result, err := service.ScreenUser(context.Background(), 7, models.ScreeningTransfer)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, models.ScreeningBlock, result.Decision)
+	mockHitRepo.AssertExpectations(t)
+}
+
+func TestSanctionsScreen_ClearanceIsPerApplicant(t *testing.T) {
+	// Arrange - a registration by ivan@example.com was refused and cleared
+	pii.Register(pii.NewDevelopmentKeyring())
+	clearedIndex, _ := pii.EmailIndex("ivan@example.com")
+	otherIndex, _ := pii.EmailIndex("other.ivan@example.com")
+	mockHitRepo := new(MockScreeningHitRepository)
+	mockHitRepo.On("FindBySubject", (*uint)(nil), clearedIndex).
+		Return([]models.ScreeningHit{{EntryID: "9001", EmailIndex: clearedIndex, Status: models.HitCleared}}, nil)
+	mockHitRepo.On("FindBySubject", (*uint)(nil), otherIndex).Return([]models.ScreeningHit{}, nil)
+	service := newTestSanctionsService(mockHitRepo, new(MockUserRepository))
+
+	// Act
+	cleared, err := service.Screen(context.Background(), "Ivan Volkov", " IVAN@example.com", nil, models.ScreeningRegistration)
+	assert.NoError(t, err)
+	other, err := service.Screen(context.Background(), "Ivan Volkov", "other.ivan@example.com", nil, models.ScreeningRegistration)
+	assert.NoError(t, err)
+
+	// Assert - the clearance applies to the applicant it was given for, not
+	// to everyone with the same name
+	assert.Equal(t, models.ScreeningClear, cleared.Decision)
+	assert.Equal(t, models.ScreeningBlock, other.Decision)
+	if assert.Len(t, other.Hits, 1) {
+		assert.Equal(t, otherIndex, other.Hits[0].EmailIndex)
+	}
+	mockHitRepo.AssertExpectations(t)
+}
+
+func TestSanctionsClearHit(t *testing.T) {
+	t.Run("ClearHit should record the justification and audit it", func(t *testing.T) {
+		// Arrange
+		mockHitRepo := new(MockScreeningHitRepository)
+		mockHitRepo.On("FindByID", uint(3)).Return(&models.ScreeningHit{ID: 3, EntryID: "9001", Status: models.HitFlagged}, nil)
+		mockHitRepo.On("Clear", mock.MatchedBy(func(hit *models.ScreeningHit) bool {
+			return hit.Status == models.HitCleared && *hit.ClearedByID == 5 && hit.Justification == "Different date of birth" && hit.ClearedAt != nil
+		}), auditEntryFor(models.AuditScreeningHitCleared, models.AuditTargetScreeningHit, "3")).Return(nil)
+		service := newTestSanctionsService(mockHitRepo, new(MockUserRepository))
+
+		// Act
+		err := service.ClearHit(context.Background(), 3, 5, " Different date of birth ", testActor)
+
+		// Assert
+		assert.NoError(t, err)
+		mockHitRepo.AssertExpectations(t)
+	})
+
+	t.Run("ClearHit should refuse a cleared hit", func(t *testing.T) {
+		// Arrange
+		mockHitRepo := new(MockScreeningHitRepository)
+		mockHitRepo.On("FindByID", uint(3)).Return(&models.ScreeningHit{ID: 3, Status: models.HitCleared}, nil)
+		service := newTestSanctionsService(mockHitRepo, new(MockUserRepository))
+
+		// Act
+		err := service.ClearHit(context.Background(), 3, 5, "Different date of birth", testActor)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrHitAlreadyCleared)
+		mockHitRepo.AssertNotCalled(t, "Clear", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ClearHit should require a justification", func(t *testing.T) {
+		// Arrange
+		mockHitRepo := new(MockScreeningHitRepository)
+		service := newTestSanctionsService(mockHitRepo, new(MockUserRepository))
+
+		// Act
+		err := service.ClearHit(context.Background(), 3, 5, "   ", testActor)
+
+		// Assert
+		assert.Error(t, err)
+		mockHitRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+)
+
+const (
+	clientIDPrefix     = "svc_"
+	serviceUsageLimit  = 100
+	apiKeySeparator    = "."
+	clientSecretLength = 32
+)
+
+var (
+	// ErrInvalidClient is returned for unknown or revoked clients and wrong secrets
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidScope is returned when a client asks for a scope it was not granted
+	ErrInvalidScope = errors.New("requested scope is not granted to the client")
+)
+
+type ServiceClientService interface {
+	CreateClient(ctx context.Context, request *models.CreateServiceClientRequest, createdByID uint, actor models.AuditActor) (*models.ServiceClientCredentials, error)
+	GetAllClients(ctx context.Context) ([]models.ServiceClientDTO, error)
+	GetClientByID(ctx context.Context, id uint) (*models.ServiceClientDTO, error)
+	RevokeClient(ctx context.Context, id uint, actor models.AuditActor) error
+	IssueToken(ctx context.Context, clientID, clientSecret, scope string) (*models.ClientToken, error)
+	AuthenticateAPIKey(ctx context.Context, apiKey string) (*models.ServiceClient, error)
+	GetActiveClient(ctx context.Context, clientID string) (*models.ServiceClient, error)
+	RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error
+	GetUsageByClientID(ctx context.Context, id uint) ([]models.ServiceClientUsage, error)
+}
+
+type serviceClientService struct {
+	serviceClientRepo repository.ServiceClientRepository
+	tokenService      TokenService
+}
+
+func NewServiceClientService(serviceClientRepo repository.ServiceClientRepository, tokenService TokenService) ServiceClientService {
+	return &serviceClientService{serviceClientRepo, tokenService}
+}
+
+// CreateClient registers a client and returns its secret, which is only shown once
+func (s *serviceClientService) CreateClient(ctx context.Context, request *models.CreateServiceClientRequest, createdByID uint, actor models.AuditActor) (*models.ServiceClientCredentials, error) {
+	for _, scope := range request.Scopes {
+		if !models.HasScope(models.Scopes, scope) {
+			return nil, errors.New("unknown scope: " + scope)
+		}
+	}
+
+	randomID, err := generateRandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateRandomToken(clientSecretLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// The base64url alphabet has no "." so API keys split unambiguously
+	client := &models.ServiceClient{
+		Name:        request.Name,
+		ClientID:    clientIDPrefix + randomID,
+		SecretHash:  hashToken(secret),
+		Scopes:      strings.Join(request.Scopes, " "),
+		CreatedByID: createdByID,
+	}
+	entry := models.NewAuditEntry(actor, models.AuditServiceClientCreated, models.AuditTargetServiceClient, "")
+	if err := s.serviceClientRepo.Create(ctx, client, entry); err != nil {
+		return nil, err
+	}
+
+	return &models.ServiceClientCredentials{
+		Client:       client.ToDTO(),
+		ClientSecret: secret,
+		APIKey:       client.ClientID + apiKeySeparator + secret,
+	}, nil
+}
+
+func (s *serviceClientService) GetAllClients(ctx context.Context) ([]models.ServiceClientDTO, error) {
+	clients, err := s.serviceClientRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]models.ServiceClientDTO, len(clients))
+	for i, client := range clients {
+		dtos[i] = client.ToDTO()
+	}
+	return dtos, nil
+}
+
+func (s *serviceClientService) GetClientByID(ctx context.Context, id uint) (*models.ServiceClientDTO, error) {
+	client, err := s.serviceClientRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dto := client.ToDTO()
+	return &dto, nil
+}
+
+// RevokeClient disables the client's API key at once. Its tokens are rejected
+// by the auth middleware from then on.
+func (s *serviceClientService) RevokeClient(ctx context.Context, id uint, actor models.AuditActor) error {
+	entry := models.NewAuditEntry(actor, models.AuditServiceClientRevoked, models.AuditTargetServiceClient, id)
+	return s.serviceClientRepo.Revoke(ctx, id, entry)
+}
+
+// IssueToken handles the OAuth2 client-credentials grant. An empty scope
+// requests every scope the client was granted.
+func (s *serviceClientService) IssueToken(ctx context.Context, clientID, clientSecret, scope string) (*models.ClientToken, error) {
+	client, err := s.authenticate(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := client.ScopeList()
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = granted
+	}
+	for _, scope := range requested {
+		if !models.HasScope(granted, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	return s.tokenService.IssueClientToken(ctx, client.ClientID, requested)
+}
+
+// AuthenticateAPIKey resolves an API key of the form <client ID>.<secret>
+func (s *serviceClientService) AuthenticateAPIKey(ctx context.Context, apiKey string) (*models.ServiceClient, error) {
+	parts := strings.SplitN(apiKey, apiKeySeparator, 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidClient
+	}
+	return s.authenticate(ctx, parts[0], parts[1])
+}
+
+// GetActiveClient returns the client a token was issued to, unless it has been revoked since
+func (s *serviceClientService) GetActiveClient(ctx context.Context, clientID string) (*models.ServiceClient, error) {
+	client, err := s.serviceClientRepo.FindByClientID(ctx, clientID)
+	if err != nil || client.RevokedAt != nil {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (s *serviceClientService) RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error {
+	return s.serviceClientRepo.RecordUsage(ctx, usage)
+}
+
+// GetUsageByClientID returns the most recent requests made by a client, newest first
+func (s *serviceClientService) GetUsageByClientID(ctx context.Context, id uint) ([]models.ServiceClientUsage, error) {
+	if _, err := s.serviceClientRepo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.serviceClientRepo.FindUsageByClientID(ctx, id, serviceUsageLimit)
+}
+
+func (s *serviceClientService) authenticate(ctx context.Context, clientID, clientSecret string) (*models.ServiceClient, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := s.GetActiveClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashToken(clientSecret))) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
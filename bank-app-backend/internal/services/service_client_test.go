@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the service client repository
+type MockServiceClientRepository struct {
+	mock.Mock
+}
+
+func (m *MockServiceClientRepository) Create(ctx context.Context, client *models.ServiceClient, entry *models.AuditEntry) error {
+	args := m.Called(client, entry)
+	return args.Error(0)
+}
+
+func (m *MockServiceClientRepository) FindByID(ctx context.Context, id uint) (*models.ServiceClient, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientRepository) FindByClientID(ctx context.Context, clientID string) (*models.ServiceClient, error) {
+	args := m.Called(clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientRepository) FindAll(ctx context.Context) ([]models.ServiceClient, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServiceClient), args.Error(1)
+}
+
+func (m *MockServiceClientRepository) Revoke(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockServiceClientRepository) RecordUsage(ctx context.Context, usage *models.ServiceClientUsage) error {
+	args := m.Called(usage)
+	return args.Error(0)
+}
+
+func (m *MockServiceClientRepository) FindUsageByClientID(ctx context.Context, id uint, limit int) ([]models.ServiceClientUsage, error) {
+	args := m.Called(id, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServiceClientUsage), args.Error(1)
+}
+
+func newTestServiceClientService(serviceClientRepo *MockServiceClientRepository) ServiceClientService {
+	return NewServiceClientService(serviceClientRepo, newTestTokenService(new(MockTokenRepository), new(MockUserRepository)))
+}
+
+// testServiceClient returns a client granted the given scopes whose secret is "client-secret"
+func testServiceClient(scopes ...string) *models.ServiceClient {
+	return &models.ServiceClient{
+		ID:         1,
+		Name:       "Batch job",
+		ClientID:   "svc_batch",
+		SecretHash: hashToken("client-secret"),
+		Scopes:     strings.Join(scopes, " "),
+	}
+}
+
+func TestCreateClient_StoresHashedSecret(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	
+	var stored *models.ServiceClient
+	mockServiceClientRepo.On("Create", mock.AnythingOfType("*models.ServiceClient"), auditEntryFor(models.AuditServiceClientCreated, models.AuditTargetServiceClient, "")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.ServiceClient) }).
+		Return(nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	credentials, err := service.CreateClient(context.Background(), &models.CreateServiceClientRequest{
+		Name:   "Batch job",
+		Scopes: []string{models.ScopeAccountsRead, models.ScopeTransfersWrite},
+	}, 7, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.ClientID, "svc_"))
+	assert.Equal(t, hashToken(credentials.ClientSecret), stored.SecretHash)
+	assert.Equal(t, "accounts:read transfers:write", stored.Scopes)
+	assert.Equal(t, uint(7), stored.CreatedByID)
+	assert.Equal(t, stored.ClientID+"."+credentials.ClientSecret, credentials.APIKey)
+	assert.Equal(t, []string{models.ScopeAccountsRead, models.ScopeTransfersWrite}, credentials.Client.Scopes)
+}
+
+func TestCreateClient_UnknownScope(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.CreateClient(context.Background(), &models.CreateServiceClientRequest{
+		Name:   "Batch job",
+		Scopes: []string{"admin:everything"},
+	}, 7, testActor)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockServiceClientRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRevokeClient_Audited(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("Revoke", uint(3), auditEntryFor(models.AuditServiceClientRevoked, models.AuditTargetServiceClient, "3")).Return(nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	err := service.RevokeClient(context.Background(), 3, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockServiceClientRepo.AssertExpectations(t)
+}
+
+func TestIssueToken_DefaultsToGrantedScopes(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(testServiceClient(models.ScopeAccountsRead, models.ScopeTransactionsRead), nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	token, err := service.IssueToken(context.Background(), "svc_batch", "client-secret", "")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, "accounts:read transactions:read", token.Scope)
+}
+
+func TestIssueToken_NarrowsScopes(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(testServiceClient(models.ScopeAccountsRead, models.ScopeTransactionsRead), nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	token, err := service.IssueToken(context.Background(), "svc_batch", "client-secret", "transactions:read")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, "transactions:read", token.Scope)
+}
+
+func TestIssueToken_UngrantedScope(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(testServiceClient(models.ScopeAccountsRead), nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.IssueToken(context.Background(), "svc_batch", "client-secret", "accounts:read transfers:write")
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestIssueToken_WrongSecret(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(testServiceClient(models.ScopeAccountsRead), nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.IssueToken(context.Background(), "svc_batch", "wrong-secret", "")
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+func TestAuthenticateAPIKey_Success(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(testServiceClient(models.ScopeAccountsRead), nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	client, err := service.AuthenticateAPIKey(context.Background(), "svc_batch.client-secret")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), client.ID)
+}
+
+func TestAuthenticateAPIKey_RevokedClient(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	revokedAt := time.Now()
+	client := testServiceClient(models.ScopeAccountsRead)
+	client.RevokedAt = &revokedAt
+	mockServiceClientRepo.On("FindByClientID", "svc_batch").Return(client, nil)
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.AuthenticateAPIKey(context.Background(), "svc_batch.client-secret")
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+func TestAuthenticateAPIKey_Malformed(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.AuthenticateAPIKey(context.Background(), "not-an-api-key")
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidClient)
+	mockServiceClientRepo.AssertNotCalled(t, "FindByClientID", mock.Anything)
+}
+
+func TestGetUsageByClientID_UnknownClient(t *testing.T) {
+	// Create mocks
+	mockServiceClientRepo := new(MockServiceClientRepository)
+	mockServiceClientRepo.On("FindByID", uint(9)).Return(nil, errors.New("record not found"))
+	
+	service := newTestServiceClientService(mockServiceClientRepo)
+	
+	// Call the method being tested
+	_, err := service.GetUsageByClientID(context.Background(), 9)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockServiceClientRepo.AssertNotCalled(t, "FindUsageByClientID", mock.Anything, mock.Anything)
+}
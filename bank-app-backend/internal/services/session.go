@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/mailer"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+)
+
+// maxTrackedSessions bounds the in-memory throttle state of TouchSession
+const maxTrackedSessions = 10000
+
+// ErrSessionNotFound is returned for unknown sessions and sessions of other users
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionNotifier is told when a user logs in from a device they have not
+// used before, so that they can spot logins that were not their own
+type SessionNotifier interface {
+	NotifyNewDevice(ctx context.Context, user *models.User, session *models.Session) error
+}
+
+// mailSessionNotifier emails the user about logins from new devices
+type mailSessionNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewMailSessionNotifier(mail mailer.Mailer) SessionNotifier {
+	return &mailSessionNotifier{mail}
+}
+
+func (n *mailSessionNotifier) NotifyNewDevice(ctx context.Context, user *models.User, session *models.Session) error {
+	return n.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "New login to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was just accessed from a new device.\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was not you, end the session under your active sessions and change your password.\n",
+			user.FirstName, session.UserAgent, session.IPAddress, session.CreatedAt.Format(time.RFC1123)),
+	})
+}
+
+// GetSessions returns the user's active sessions, flagging the one the
+// request was made with
+func (s *tokenService) GetSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionDTO, error) {
+	sessions, err := s.tokenRepo.FindActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]models.SessionDTO, len(sessions))
+	for i, session := range sessions {
+		dtos[i] = session.ToDTO(currentSessionID)
+	}
+	return dtos, nil
+}
+
+// RevokeSession ends one of the user's sessions. Its refresh token stops
+// working and the auth middleware rejects its access tokens.
+func (s *tokenService) RevokeSession(ctx context.Context, userID, sessionID uint, actor models.AuditActor) error {
+	session, err := s.tokenRepo.FindSessionByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	entry := models.NewAuditEntry(actor, models.AuditSessionRevoked, models.AuditTargetSession, session.ID).
+		WithChange(session.ToDTO(""), nil)
+	return s.tokenRepo.RevokeFamily(ctx, session.FamilyID, entry)
+}
+
+// TouchSession records that a session is in use. The last-seen time is
+// written at most once per SessionTouchInterval to spare the database.
+func (s *tokenService) TouchSession(ctx context.Context, sessionID string) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	if now.Sub(s.lastTouched[sessionID]) < s.options.SessionTouchInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	if len(s.lastTouched) >= maxTrackedSessions {
+		// Entries older than the interval no longer throttle anything
+		for id, touched := range s.lastTouched {
+			if now.Sub(touched) >= s.options.SessionTouchInterval {
+				delete(s.lastTouched, id)
+			}
+		}
+	}
+	s.lastTouched[sessionID] = now
+	s.mu.Unlock()
+
+	return s.tokenRepo.TouchSession(ctx, sessionID, now)
+}
+
+// startSession records a new login and notifies the user when it comes from a
+// device they never logged in from. A user's first login is not reported.
+func (s *tokenService) startSession(ctx context.Context, user *models.User, familyID string, device models.DeviceInfo, expiresAt time.Time) error {
+	deviceHash := hashToken(device.UserAgent)
+
+	hasSessions, err := s.tokenRepo.HasSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	knownDevice, err := s.tokenRepo.HasSessionWithDevice(ctx, user.ID, deviceHash)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		DeviceHash: deviceHash,
+		UserAgent:  device.UserAgent,
+		IPAddress:  device.IPAddress,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.tokenRepo.CreateSession(ctx, session); err != nil {
+		return err
+	}
+
+	if hasSessions && !knownDevice && s.options.NewDeviceNotifier != nil {
+		// The login has succeeded, so a failed notification must not undo it
+		_ = s.options.NewDeviceNotifier.NotifyNewDevice(ctx, user, session)
+	}
+	return nil
+}
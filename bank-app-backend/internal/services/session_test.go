@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestSessionService(tokenRepo *MockTokenRepository, mail *recordingMailer) TokenService {
+	return NewTokenService(tokenRepo, new(MockUserRepository), TokenOptions{
+		KeySet:               signing.NewHMACKeySet("test-secret-key"),
+		AccessTokenTTL:       15 * time.Minute,
+		RefreshTokenTTL:      24 * time.Hour,
+		SessionTouchInterval: 5 * time.Minute,
+		NewDeviceNotifier:    NewMailSessionNotifier(mail),
+	})
+}
+
+func TestIssueTokens_RecordsSession(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mail := &recordingMailer{}
+	
+	var session *models.Session
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	mockTokenRepo.On("HasSessions", uint(1)).Return(false, nil)
+	mockTokenRepo.On("HasSessionWithDevice", uint(1), hashToken("test-agent")).Return(false, nil)
+	mockTokenRepo.On("CreateSession", mock.AnythingOfType("*models.Session")).
+		Run(func(args mock.Arguments) { session = args.Get(0).(*models.Session) }).
+		Return(nil)
+	
+	service := newTestSessionService(mockTokenRepo, mail)
+	
+	// Call the method being tested
+	tokens, err := service.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{UserAgent: "test-agent", IPAddress: "127.0.0.1"})
+	assert.NoError(t, err)
+	
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), session.FamilyID).Return(false, nil)
+	claims, err := service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err)
+	
+	// Assert expectations - the first login is not reported as a new device
+	assert.Equal(t, uint(1), session.UserID)
+	assert.Equal(t, claims.SessionID, session.FamilyID)
+	assert.Equal(t, "test-agent", session.UserAgent)
+	assert.Equal(t, "127.0.0.1", session.IPAddress)
+	assert.Equal(t, tokens.RefreshExpiresAt, session.ExpiresAt)
+	assert.Empty(t, mail.sent)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestIssueTokens_NotifiesNewDevice(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mail := &recordingMailer{}
+	
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	mockTokenRepo.On("HasSessions", uint(1)).Return(true, nil)
+	mockTokenRepo.On("HasSessionWithDevice", uint(1), hashToken("new-agent")).Return(false, nil)
+	mockTokenRepo.On("CreateSession", mock.AnythingOfType("*models.Session")).Return(nil)
+	
+	service := newTestSessionService(mockTokenRepo, mail)
+	
+	// Call the method being tested
+	_, err := service.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{UserAgent: "new-agent", IPAddress: "127.0.0.1"})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, "test@example.com", mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Body, "new-agent")
+}
+
+func TestIssueTokens_KnownDeviceIsNotNotified(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mail := &recordingMailer{}
+	
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	mockTokenRepo.On("HasSessions", uint(1)).Return(true, nil)
+	mockTokenRepo.On("HasSessionWithDevice", uint(1), hashToken("test-agent")).Return(true, nil)
+	mockTokenRepo.On("CreateSession", mock.AnythingOfType("*models.Session")).Return(nil)
+	
+	service := newTestSessionService(mockTokenRepo, mail)
+	
+	// Call the method being tested
+	_, err := service.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{UserAgent: "test-agent"})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Empty(t, mail.sent)
+}
+
+func TestGetSessions_FlagsCurrentSession(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("FindActiveSessionsByUserID", uint(1)).Return([]models.Session{
+		{ID: 1, UserID: 1, FamilyID: "family-1", UserAgent: "phone"},
+		{ID: 2, UserID: 1, FamilyID: "family-2", UserAgent: "laptop"},
+	}, nil)
+	
+	service := newTestSessionService(mockTokenRepo, &recordingMailer{})
+	
+	// Call the method being tested
+	sessions, err := service.GetSessions(context.Background(), 1, "family-2")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+	assert.Equal(t, "laptop", sessions[1].UserAgent)
+}
+
+func TestRevokeSession_Success(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("FindSessionByID", uint(5)).Return(&models.Session{ID: 5, UserID: 1, FamilyID: "family-1"}, nil)
+	mockTokenRepo.On("RevokeFamily", "family-1", auditEntryFor(models.AuditSessionRevoked, models.AuditTargetSession, "5")).Return(nil)
+	
+	service := newTestSessionService(mockTokenRepo, &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.RevokeSession(context.Background(), 1, 5, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRevokeSession_OtherUsersSession(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("FindSessionByID", uint(5)).Return(&models.Session{ID: 5, UserID: 2, FamilyID: "family-1"}, nil)
+	
+	service := newTestSessionService(mockTokenRepo, &recordingMailer{})
+	
+	// Call the method being tested
+	err := service.RevokeSession(context.Background(), 1, 5, testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	mockTokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+}
+
+func TestTouchSession_Throttled(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("TouchSession", "family-1", mock.AnythingOfType("time.Time")).Return(nil)
+	mockTokenRepo.On("TouchSession", "family-2", mock.AnythingOfType("time.Time")).Return(nil)
+	
+	service := newTestSessionService(mockTokenRepo, &recordingMailer{})
+	
+	// Call the method being tested
+	assert.NoError(t, service.TouchSession(context.Background(), "family-1"))
+	assert.NoError(t, service.TouchSession(context.Background(), "family-1"))
+	assert.NoError(t, service.TouchSession(context.Background(), "family-2"))
+	
+	// Assert expectations - only the first request of each session is written
+	mockTokenRepo.AssertNumberOfCalls(t, "TouchSession", 2)
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// Authentication levels carried in the acr claim of access tokens
+const (
+	AuthLevelBasic    = "basic"    // Password login, possibly refreshed since
+	AuthLevelElevated = "elevated" // Identity re-proven through step-up authentication
+)
+
+var ErrStepUpRequired = errors.New("step-up authentication required")
+
+// AuthContext - How and when the caller last proved their identity
+type AuthContext struct {
+	UserID    uint
+	AuthTime  time.Time
+	AuthLevel string
+}
+
+// RequireRecentAuth returns ErrStepUpRequired unless the caller completed
+// step-up authentication within maxAge
+func RequireRecentAuth(auth *AuthContext, maxAge time.Duration) error {
+	if auth == nil || auth.AuthLevel != AuthLevelElevated {
+		return ErrStepUpRequired
+	}
+	if time.Since(auth.AuthTime) > maxAge {
+		return ErrStepUpRequired
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+)
+
+// AccessClaims - Claims carried by an access token
+type AccessClaims struct {
+	UserID    uint             `json:"id"`
+	Email     string           `json:"email"`
+	Role      string           `json:"role"`
+	SessionID string           `json:"sid"`                 // Refresh token family the token was issued for
+	AuthTime  *jwt.NumericDate `json:"auth_time,omitempty"` // When the user last proved their identity
+	AuthLevel string           `json:"acr,omitempty"`
+	ClientID  string           `json:"client_id,omitempty"` // Set instead of the user fields for service clients
+	Scope     string           `json:"scope,omitempty"`     // Space-separated scopes granted to a service client
+	jwt.RegisteredClaims
+}
+
+// AuthContext returns when and how the token holder last authenticated
+func (c *AccessClaims) AuthContext() *AuthContext {
+	auth := &AuthContext{
+		UserID:    c.UserID,
+		AuthLevel: c.AuthLevel,
+	}
+	if c.AuthTime != nil {
+		auth.AuthTime = c.AuthTime.Time
+	} else if c.IssuedAt != nil {
+		auth.AuthTime = c.IssuedAt.Time
+	}
+	return auth
+}
+
+// TokenOptions - Signing keys and lifetimes used by the token service
+type TokenOptions struct {
+	KeySet          *signing.KeySet
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	StepUpTTL       time.Duration // Lifetime of elevated access tokens
+	ClientTokenTTL  time.Duration // Lifetime of client-credentials tokens
+
+	SessionTouchInterval time.Duration   // Minimum time between last-seen updates of a session
+	NewDeviceNotifier    SessionNotifier // Told about logins from devices the user never used before, may be nil
+}
+
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *models.User, device models.DeviceInfo) (*models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	ValidateAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error)
+	Logout(ctx context.Context, claims *AccessClaims, actor models.AuditActor) error
+	LogoutAll(ctx context.Context, userID uint, actor models.AuditActor) error
+	LogoutOthers(ctx context.Context, userID uint, currentSessionID string, actor models.AuditActor) error
+	Elevate(ctx context.Context, claims *AccessClaims) (*models.ElevatedToken, error)
+	IssueClientToken(ctx context.Context, clientID string, scopes []string) (*models.ClientToken, error)
+	GetSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionDTO, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint, actor models.AuditActor) error
+	TouchSession(ctx context.Context, sessionID string) error
+	DeleteExpired(ctx context.Context) error
+}
+
+type tokenService struct {
+	tokenRepo repository.TokenRepository
+	userRepo  repository.UserRepository
+	options   TokenOptions
+
+	mu          sync.Mutex
+	lastTouched map[string]time.Time // When each session's last-seen time was last written
+}
+
+func NewTokenService(tokenRepo repository.TokenRepository, userRepo repository.UserRepository, options TokenOptions) TokenService {
+	return &tokenService{
+		tokenRepo:   tokenRepo,
+		userRepo:    userRepo,
+		options:     options,
+		lastTouched: map[string]time.Time{},
+	}
+}
+
+// IssueTokens starts a new session family for the user on the given device
+func (s *tokenService) IssueTokens(ctx context.Context, user *models.User, device models.DeviceInfo) (*models.TokenPair, error) {
+	familyID, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issue(ctx, user, familyID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.startSession(ctx, user, familyID, device, tokens.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// revokes every token in its family, since either the client or an attacker is
+// replaying a stolen token.
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	stored, err := s.tokenRepo.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	if stored.UsedAt != nil {
+		if err := s.tokenRepo.RevokeFamily(ctx, stored.FamilyID, refreshReuseAudit(stored)); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token has expired")
+	}
+
+	marked, err := s.tokenRepo.MarkRefreshTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// Another request rotated the same token concurrently
+		if err := s.tokenRepo.RevokeFamily(ctx, stored.FamilyID, refreshReuseAudit(stored)); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	authTime := stored.AuthTime
+	if authTime.IsZero() {
+		authTime = stored.CreatedAt
+	}
+	tokens, err := s.issue(ctx, user, stored.FamilyID, authTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenRepo.ExtendSession(ctx, stored.FamilyID, time.Now(), tokens.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *tokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.options.KeySet.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	// Access tokens have no audience; other tokens signed with the same key,
+	// such as login challenges, name the one they are for
+	if !token.Valid || claims.ID == "" || len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// Elevate issues a short-lived access token for the same session, marking that
+// the user has just re-proven their identity. Callers must verify the user's
+// password or second factor first.
+func (s *tokenService) Elevate(ctx context.Context, claims *AccessClaims) (*models.ElevatedToken, error) {
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.options.StepUpTTL)
+	token, err := s.generateAccessToken(user, claims.SessionID, now, AuthLevelElevated, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ElevatedToken{
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// IssueClientToken issues an access token for a service client. There is no
+// refresh token; clients request a new token with their credentials.
+func (s *tokenService) IssueClientToken(ctx context.Context, clientID string, scopes []string) (*models.ClientToken, error) {
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scope := strings.Join(scopes, " ")
+	claims := &AccessClaims{
+		ClientID: clientID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   clientID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.options.ClientTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := s.options.KeySet.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ClientToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.options.ClientTokenTTL / time.Second),
+		Scope:       scope,
+	}, nil
+}
+
+// Logout revokes the presented access token and its session family
+func (s *tokenService) Logout(ctx context.Context, claims *AccessClaims, actor models.AuditActor) error {
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if claims.SessionID != "" {
+		entry := models.NewAuditEntry(actor, models.AuditLogout, models.AuditTargetUser, claims.UserID)
+		if err := s.tokenRepo.RevokeFamily(ctx, claims.SessionID, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogoutAll revokes every session family of the user, which also invalidates
+// all access tokens issued for them
+func (s *tokenService) LogoutAll(ctx context.Context, userID uint, actor models.AuditActor) error {
+	entry := models.NewAuditEntry(actor, models.AuditLogoutAll, models.AuditTargetUser, userID)
+	return s.tokenRepo.RevokeAllForUser(ctx, userID, entry)
+}
+
+// LogoutOthers revokes every session family of the user except the current
+// one, so that the request's session stays signed in
+func (s *tokenService) LogoutOthers(ctx context.Context, userID uint, currentSessionID string, actor models.AuditActor) error {
+	if currentSessionID == "" {
+		return s.LogoutAll(ctx, userID, actor)
+	}
+	entry := models.NewAuditEntry(actor, models.AuditLogoutOthers, models.AuditTargetUser, userID)
+	return s.tokenRepo.RevokeOtherSessions(ctx, userID, currentSessionID, entry)
+}
+
+// refreshReuseAudit records that a replayed refresh token ended its session
+func refreshReuseAudit(stored *models.RefreshToken) *models.AuditEntry {
+	actor := models.AuditActor{Type: models.AuditActorSystem}
+	return models.NewAuditEntry(actor, models.AuditRefreshTokenReused, models.AuditTargetUser, stored.UserID)
+}
+
+func (s *tokenService) revokeAccessToken(ctx context.Context, claims *AccessClaims) error {
+	expiresAt := time.Now().Add(s.options.AccessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.tokenRepo.RevokeAccessToken(ctx, &models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *tokenService) issue(ctx context.Context, user *models.User, familyID string, authTime time.Time) (*models.TokenPair, error) {
+	now := time.Now()
+
+	accessExpiresAt := now.Add(s.options.AccessTokenTTL)
+	accessToken, err := s.generateAccessToken(user, familyID, authTime, AuthLevelBasic, accessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshExpiresAt := now.Add(s.options.RefreshTokenTTL)
+	if err := s.tokenRepo.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: refreshExpiresAt,
+		AuthTime:  authTime,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (s *tokenService) generateAccessToken(user *models.User, familyID string, authTime time.Time, authLevel string, expiresAt time.Time) (string, error) {
+	jti, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &AccessClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: familyID,
+		AuthTime:  jwt.NewNumericDate(authTime),
+		AuthLevel: authLevel,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return s.options.KeySet.Sign(claims)
+}
+
+// generateRandomToken returns n random bytes encoded for use in URLs and headers
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 digest under which a token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteExpired removes revoked access tokens and refresh tokens that have
+// expired. Logging out does not do it; run the prune-tokens command on a schedule.
+func (s *tokenService) DeleteExpired(ctx context.Context) error {
+	return s.tokenRepo.DeleteExpired(ctx)
+}
@@ -0,0 +1,532 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the token repository
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockTokenRepository) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) RevokeFamily(ctx context.Context, familyID string, entry *models.AuditEntry) error {
+	args := m.Called(familyID, entry)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeAllForUser(ctx context.Context, userID uint, entry *models.AuditEntry) error {
+	args := m.Called(userID, entry)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeOtherSessions(ctx context.Context, userID uint, keepFamilyID string, entry *models.AuditEntry) error {
+	args := m.Called(userID, keepFamilyID, entry)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti, familyID string) (bool, error) {
+	args := m.Called(jti, familyID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) DeleteExpired(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) FindSessionByID(ctx context.Context, id uint) (*models.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockTokenRepository) FindActiveSessionsByUserID(ctx context.Context, userID uint) ([]models.Session, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockTokenRepository) HasSessions(ctx context.Context, userID uint) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) HasSessionWithDevice(ctx context.Context, userID uint, deviceHash string) (bool, error) {
+	args := m.Called(userID, deviceHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) TouchSession(ctx context.Context, familyID string, seenAt time.Time) error {
+	args := m.Called(familyID, seenAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) ExtendSession(ctx context.Context, familyID string, seenAt, expiresAt time.Time) error {
+	args := m.Called(familyID, seenAt, expiresAt)
+	return args.Error(0)
+}
+
+// expectFirstSession sets up a login of a user without earlier sessions
+func expectFirstSession(tokenRepo *MockTokenRepository) {
+	tokenRepo.On("HasSessions", mock.Anything).Return(false, nil)
+	tokenRepo.On("HasSessionWithDevice", mock.Anything, mock.Anything).Return(false, nil)
+	tokenRepo.On("CreateSession", mock.AnythingOfType("*models.Session")).Return(nil)
+}
+
+func newTestTokenService(tokenRepo *MockTokenRepository, userRepo *MockUserRepository) TokenService {
+	return NewTokenService(tokenRepo, userRepo, TokenOptions{
+		KeySet:          signing.NewHMACKeySet("test-secret-key"),
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		ClientTokenTTL:  time.Hour,
+	})
+}
+
+func TestIssueTokens_Success(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	
+	// Capture the stored refresh token
+	var stored *models.RefreshToken
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.RefreshToken) }).
+		Return(nil)
+	expectFirstSession(mockTokenRepo)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	tokens, err := service.IssueTokens(context.Background(), testUser, models.DeviceInfo{UserAgent: "test-agent", IPAddress: "127.0.0.1"})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, uint(1), stored.UserID)
+	assert.NotEmpty(t, stored.FamilyID)
+	assert.Equal(t, hashToken(tokens.RefreshToken), stored.TokenHash)
+	assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestValidateAccessToken_Success(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	expectFirstSession(mockTokenRepo)
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(false, nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	tokens, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{})
+	
+	// Call the method being tested
+	claims, err := service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+	assert.Equal(t, "test@example.com", claims.Email)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotEmpty(t, claims.SessionID)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestValidateAccessToken_Revoked(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	expectFirstSession(mockTokenRepo)
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(true, nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	tokens, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{})
+	
+	// Call the method being tested
+	claims, err := service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Nil(t, claims)
+	assert.Equal(t, "token has been revoked", err.Error())
+}
+
+func TestValidateAccessToken_WrongSecret(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	expectFirstSession(mockTokenRepo)
+	
+	issuer := NewTokenService(mockTokenRepo, mockUserRepo, TokenOptions{KeySet: signing.NewHMACKeySet("other-secret"), AccessTokenTTL: time.Minute})
+	tokens, _ := issuer.IssueTokens(context.Background(), &models.User{ID: 1, Email: "test@example.com"}, models.DeviceInfo{})
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	_, err := service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockTokenRepo.AssertNotCalled(t, "IsAccessTokenRevoked", mock.Anything, mock.Anything)
+}
+
+func TestValidateAccessToken_RejectsChallengeToken(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	// A login challenge signed with the same key, even one with a token ID
+	challenge, err := signing.NewHMACKeySet("test-secret-key").Sign(&ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "challenge-id",
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	assert.NoError(t, err)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	claims, err := service.ValidateAccessToken(context.Background(), challenge)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Nil(t, claims)
+	mockTokenRepo.AssertNotCalled(t, "IsAccessTokenRevoked", mock.Anything, mock.Anything)
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	testUser := &models.User{ID: 1, Email: "test@example.com"}
+	stored := &models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		TokenHash: hashToken("old-refresh-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	
+	// Set up expectations
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("old-refresh-token")).Return(stored, nil)
+	mockTokenRepo.On("MarkRefreshTokenUsed", uint(7)).Return(true, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(testUser, nil)
+	mockTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.RefreshToken) bool {
+		return token.FamilyID == "family-1" && token.UserID == 1
+	})).Return(nil)
+	mockTokenRepo.On("ExtendSession", "family-1", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	tokens, err := service.Refresh(context.Background(), "old-refresh-token")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.NotEqual(t, "old-refresh-token", tokens.RefreshToken)
+	mockTokenRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	usedAt := time.Now().Add(-time.Minute)
+	stored := &models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+	
+	// Set up expectations
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("used-refresh-token")).Return(stored, nil)
+	mockTokenRepo.On("RevokeFamily", "family-1", auditEntryFor(models.AuditRefreshTokenReused, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	tokens, err := service.Refresh(context.Background(), "used-refresh-token")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Nil(t, tokens)
+	assert.Equal(t, "refresh token reuse detected", err.Error())
+	mockTokenRepo.AssertExpectations(t)
+	mockTokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+}
+
+func TestRefresh_ConcurrentRotationRevokesFamily(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	stored := &models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	
+	// Set up expectations: another request marked the token first
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("refresh-token")).Return(stored, nil)
+	mockTokenRepo.On("MarkRefreshTokenUsed", uint(7)).Return(false, nil)
+	mockTokenRepo.On("RevokeFamily", "family-1", auditEntryFor(models.AuditRefreshTokenReused, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	_, err := service.Refresh(context.Background(), "refresh-token")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRefresh_Expired(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	stored := &models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("expired-token")).Return(stored, nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	_, err := service.Refresh(context.Background(), "expired-token")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Equal(t, "refresh token has expired", err.Error())
+	mockTokenRepo.AssertNotCalled(t, "MarkRefreshTokenUsed", mock.Anything)
+}
+
+func TestRefresh_UnknownToken(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("unknown")).Return(nil, errors.New("refresh token not found"))
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	_, err := service.Refresh(context.Background(), "unknown")
+	
+	// Assert expectations
+	assert.Error(t, err)
+	assert.Equal(t, "invalid refresh token", err.Error())
+}
+
+func TestLogout_RevokesTokenAndFamily(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	claims := &AccessClaims{UserID: 1, SessionID: "family-1"}
+	claims.ID = "jti-1"
+	
+	// Set up expectations
+	mockTokenRepo.On("RevokeAccessToken", mock.MatchedBy(func(token *models.RevokedToken) bool {
+		return token.JTI == "jti-1" && token.UserID == 1
+	})).Return(nil)
+	mockTokenRepo.On("RevokeFamily", "family-1", auditEntryFor(models.AuditLogout, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.Logout(context.Background(), claims, testActor)
+	
+	// Assert expectations - expired tokens are left to the prune-tokens command
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+	mockTokenRepo.AssertNotCalled(t, "DeleteExpired")
+}
+
+func TestLogoutAll_RevokesAllFamilies(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	mockTokenRepo.On("RevokeAllForUser", uint(1), auditEntryFor(models.AuditLogoutAll, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	// Call the method being tested
+	err := service.LogoutAll(context.Background(), 1, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestLogoutOthers_KeepsCurrentSession(t *testing.T) {
+	// Create mock repositories
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("RevokeOtherSessions", uint(1), "family-1", auditEntryFor(models.AuditLogoutOthers, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTokenService(mockTokenRepo, new(MockUserRepository))
+	
+	// Call the method being tested
+	err := service.LogoutOthers(context.Background(), 1, "family-1", testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockTokenRepo.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestElevate_IssuesShortLivedElevatedToken(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), "family-1").Return(false, nil)
+	
+	service := NewTokenService(mockTokenRepo, mockUserRepo, TokenOptions{
+		KeySet:          signing.NewHMACKeySet("test-secret-key"),
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		StepUpTTL:       5 * time.Minute,
+	})
+	
+	// Call the method being tested
+	elevated, err := service.Elevate(context.Background(), &AccessClaims{UserID: 1, SessionID: "family-1"})
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), elevated.ExpiresAt, time.Second)
+	
+	claims, err := service.ValidateAccessToken(context.Background(), elevated.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "family-1", claims.SessionID)
+	
+	auth := claims.AuthContext()
+	assert.Equal(t, AuthLevelElevated, auth.AuthLevel)
+	assert.WithinDuration(t, time.Now(), auth.AuthTime, 2*time.Second)
+}
+
+func TestRefresh_KeepsOriginalAuthTime(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	loggedInAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	stored := &models.RefreshToken{
+		ID:        1,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		AuthTime:  loggedInAt,
+	}
+	mockTokenRepo.On("FindRefreshTokenByHash", hashToken("refresh-token")).Return(stored, nil)
+	mockTokenRepo.On("MarkRefreshTokenUsed", uint(1)).Return(true, nil)
+	mockTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.RefreshToken) bool {
+		return token.AuthTime.Equal(loggedInAt)
+	})).Return(nil)
+	mockTokenRepo.On("ExtendSession", "family-1", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(nil)
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), "family-1").Return(false, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1}, nil)
+	
+	// Call the method being tested
+	tokens, err := service.Refresh(context.Background(), "refresh-token")
+	assert.NoError(t, err)
+	
+	// Assert expectations - a refreshed token is not a fresh authentication
+	claims, err := service.ValidateAccessToken(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err)
+	auth := claims.AuthContext()
+	assert.Equal(t, AuthLevelBasic, auth.AuthLevel)
+	assert.True(t, auth.AuthTime.Equal(loggedInAt))
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestIssueClientToken_CarriesScopes(t *testing.T) {
+	// Create mocks
+	mockTokenRepo := new(MockTokenRepository)
+	mockUserRepo := new(MockUserRepository)
+	service := newTestTokenService(mockTokenRepo, mockUserRepo)
+	
+	mockTokenRepo.On("IsAccessTokenRevoked", mock.AnythingOfType("string"), "").Return(false, nil)
+	
+	// Call the method being tested
+	token, err := service.IssueClientToken(context.Background(), "svc_batch", []string{models.ScopeAccountsRead, models.ScopeTransactionsRead})
+	assert.NoError(t, err)
+	
+	// Assert expectations - client tokens identify no user and have no session
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, int64(3600), token.ExpiresIn)
+	assert.Equal(t, "accounts:read transactions:read", token.Scope)
+	claims, err := service.ValidateAccessToken(context.Background(), token.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "svc_batch", claims.ClientID)
+	assert.Equal(t, "accounts:read transactions:read", claims.Scope)
+	assert.Equal(t, uint(0), claims.UserID)
+	assert.Empty(t, claims.SessionID)
+}
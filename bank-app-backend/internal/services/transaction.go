@@ -1,32 +1,79 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TransactionService interface {
-	CreateTransaction(transaction *models.Transaction) error
-	GetTransactionByID(id uint) (*models.Transaction, error)
-	GetTransactionsByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error)
-	GetAllTransactions(limit, offset int) ([]models.Transaction, error)
-	Transfer(request *models.TransferRequest) error
+	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactionByID(ctx context.Context, id uint) (*models.Transaction, error)
+	GetTransactionsByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error)
+	GetAllTransactions(ctx context.Context, limit, offset int) ([]models.Transaction, error)
+	Transfer(ctx context.Context, request *models.TransferRequest, auth *AuthContext, actor models.AuditActor) error
+	GetTransferReviews(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error)
+	ApproveTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) error
+	RejectTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) error
+}
+
+var (
+	// ErrInvalidAmount is returned for a transfer of zero or less
+	ErrInvalidAmount = errors.New("transfer amount must be positive")
+	// ErrAmountPrecision is returned for a transfer of a fraction of a cent
+	ErrAmountPrecision = errors.New("transfer amount cannot have more than 2 decimal places")
+	// ErrSameAccount is returned for a transfer to the account it is made from
+	ErrSameAccount = errors.New("cannot transfer to the same account")
+	// ErrInsufficientFunds is returned when the account cannot cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrAccountFrozen is returned when money would move into or out of a frozen account
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrTransferBlocked is returned when fraud or sanctions screening refuses a transfer
+	ErrTransferBlocked = errors.New("transfer was declined")
+	// ErrReviewNotPending is returned when approving or rejecting a review that was already decided
+	ErrReviewNotPending = repository.ErrReviewNotPending
+)
+
+// TransferHeldError is returned when screening holds a transfer for
+// review. No money has moved.
+type TransferHeldError struct {
+	Review *models.TransferReview
+}
+
+func (e *TransferHeldError) Error() string {
+	return "transfer is held for review"
+}
+
+// TransactionOptions - Limits applied to money movements
+type TransactionOptions struct {
+	StepUpThreshold float64          // Transfers above this amount need step-up authentication, 0 disables the check
+	StepUpMaxAge    time.Duration    // How recent the step-up authentication must be
+	Fraud           FraudService     // Screens every transfer before it is made, nil disables screening
+	Sanctions       SanctionsService // Screens the payee of every transfer, nil disables screening
+	KYC             KYCService       // Only verified users may transfer, within their level's limits; nil disables the check
 }
 
 type transactionService struct {
 	transactionRepo repository.TransactionRepository
 	accountRepo     repository.AccountRepository
+	auditRepo       repository.AuditRepository
+	reviewRepo      repository.TransferReviewRepository
+	options         TransactionOptions
 }
 
-func NewTransactionService(transactionRepo repository.TransactionRepository, accountRepo repository.AccountRepository) TransactionService {
-	return &transactionService{transactionRepo, accountRepo}
+func NewTransactionService(transactionRepo repository.TransactionRepository, accountRepo repository.AccountRepository, auditRepo repository.AuditRepository, reviewRepo repository.TransferReviewRepository, options TransactionOptions) TransactionService {
+	return &transactionService{transactionRepo, accountRepo, auditRepo, reviewRepo, options}
 }
 
-func (s *transactionService) CreateTransaction(transaction *models.Transaction) error {
+func (s *transactionService) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
 	// Set transaction date if not provided
 	if transaction.TransactionDate.IsZero() {
 		transaction.TransactionDate = time.Now()
@@ -38,7 +85,7 @@ func (s *transactionService) CreateTransaction(transaction *models.Transaction)
 	}
 
 	// Get account to update balance
-	account, err := s.accountRepo.FindByID(transaction.AccountID)
+	account, err := s.accountRepo.FindByID(ctx, transaction.AccountID)
 	if err != nil {
 		return err
 	}
@@ -49,7 +96,7 @@ func (s *transactionService) CreateTransaction(transaction *models.Transaction)
 		account.Balance += transaction.Amount
 	case models.Withdrawal:
 		if account.Balance < transaction.Amount {
-			return errors.New("insufficient funds")
+			return ErrInsufficientFunds
 		}
 		account.Balance -= transaction.Amount
 	case models.Transfer:
@@ -61,131 +108,389 @@ func (s *transactionService) CreateTransaction(transaction *models.Transaction)
 	transaction.Balance = account.Balance
 
 	// Update account balance
-	if err := s.accountRepo.Update(account); err != nil {
+	if err := s.accountRepo.Update(ctx, account); err != nil {
 		return err
 	}
 
 	// Create transaction
-	if err := s.transactionRepo.Create(transaction); err != nil {
+	if err := s.transactionRepo.Create(ctx, transaction); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *transactionService) GetTransactionByID(id uint) (*models.Transaction, error) {
-	return s.transactionRepo.FindByID(id)
+func (s *transactionService) GetTransactionByID(ctx context.Context, id uint) (*models.Transaction, error) {
+	return s.transactionRepo.FindByID(ctx, id)
+}
+
+func (s *transactionService) GetTransactionsByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error) {
+	return s.transactionRepo.FindByAccountID(ctx, accountID, limit, offset)
 }
 
-func (s *transactionService) GetTransactionsByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error) {
-	return s.transactionRepo.FindByAccountID(accountID, limit, offset)
+func (s *transactionService) GetAllTransactions(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
+	return s.transactionRepo.FindAll(ctx, limit, offset)
 }
 
-func (s *transactionService) GetAllTransactions(limit, offset int) ([]models.Transaction, error) {
-	return s.transactionRepo.FindAll(limit, offset)
+// Transfer moves money between two accounts once it passes step-up and fraud
+// screening. A held transfer returns a *TransferHeldError.
+func (s *transactionService) Transfer(ctx context.Context, request *models.TransferRequest, auth *AuthContext, actor models.AuditActor) (err error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.Transfer", transferAttributes(request)...)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.transfer(ctx, request, auth, actor)
+
+	var held *TransferHeldError
+	switch {
+	case err == nil:
+		metrics.ObserveTransfer(metrics.TransferCompleted, request.Amount)
+	case errors.As(err, &held):
+		metrics.ObserveTransfer(metrics.TransferHeld, request.Amount)
+	default:
+		metrics.ObserveTransferFailure(transferFailureReason(err))
+	}
+	return err
 }
 
-func (s *transactionService) Transfer(request *models.TransferRequest) error {
+func (s *transactionService) transfer(ctx context.Context, request *models.TransferRequest, auth *AuthContext, actor models.AuditActor) error {
 	if request.Amount <= 0 {
-		return errors.New("transfer amount must be positive")
+		return ErrInvalidAmount
+	}
+	// Money is stored in whole cents, so a fraction of one would be rounded away
+	if cents := request.Amount * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		return ErrAmountPrecision
 	}
 
 	if request.FromAccountID == request.ToAccountID {
-		return errors.New("cannot transfer to the same account")
+		return ErrSameAccount
 	}
 
-	// Lock the from account for update to prevent race conditions
-	fromAccount, fromTx, err := s.accountRepo.FindByIDWithLock(request.FromAccountID)
-	if err != nil {
-		return err
+	// High-value transfers need fresh proof of identity
+	if s.options.StepUpThreshold > 0 && request.Amount > s.options.StepUpThreshold {
+		if err := RequireRecentAuth(auth, s.options.StepUpMaxAge); err != nil {
+			return err
+		}
+	}
+
+	var fromAccount *models.Account
+	if s.options.KYC != nil || s.options.Fraud != nil {
+		var err error
+		if fromAccount, err = s.accountRepo.FindByID(ctx, request.FromAccountID); err != nil {
+			return err
+		}
 	}
-	defer func() {
-		if fromTx != nil {
-			fromTx.Rollback()
+
+	// Unverified users can view their accounts but not move money
+	if s.options.KYC != nil {
+		if err := s.options.KYC.CheckTransfer(ctx, fromAccount.UserID, request.Amount); err != nil {
+			actorLogger(actor).Info("transfer refused by KYC policy",
+				"from_account_id", request.FromAccountID, "amount", request.Amount, "reason", err.Error())
+			return err
 		}
-	}()
+	}
 
-	// Check if from account has sufficient balance
-	if fromAccount.Balance < request.Amount {
-		return errors.New("insufficient funds")
+	if s.options.Fraud != nil || s.options.Sanctions != nil {
+		if err := s.screen(ctx, request, fromAccount, actor); err != nil {
+			return err
+		}
 	}
 
-	// Lock the to account for update
-	toAccount, toTx, err := s.accountRepo.FindByIDWithLock(request.ToAccountID)
-	if err != nil {
+	if err := s.executeTransfer(ctx, request, actor, nil); err != nil {
 		return err
 	}
-	defer func() {
-		if toTx != nil {
-			toTx.Rollback()
+	actorLogger(actor).Info("transfer completed",
+		"from_account_id", request.FromAccountID, "to_account_id", request.ToAccountID, "amount", request.Amount)
+	return nil
+}
+
+// screen runs fraud and sanctions screening and records a held or blocked
+// transfer. The from account is only needed for fraud screening.
+func (s *transactionService) screen(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account, actor models.AuditActor) (err error) {
+	_, span := tracing.Start(ctx, "TransactionService.screen")
+	defer func() { tracing.End(span, err) }()
+
+	assessment := &models.FraudAssessment{Reasons: []string{}, Decision: models.FraudAllow}
+	if s.options.Fraud != nil {
+		var err error
+		assessment, err = s.options.Fraud.Assess(ctx, request, fromAccount)
+		if err != nil {
+			return err
+		}
+	}
+	if s.options.Sanctions != nil {
+		if err := s.screenPayee(ctx, request, assessment); err != nil {
+			return err
 		}
-	}()
+	}
+	span.SetAttributes(attribute.String("fraud.decision", assessment.Decision), attribute.Int("fraud.score", assessment.Score))
+	if assessment.Decision == models.FraudAllow {
+		return nil
+	}
 
-	// Update balances
-	fromAccount.Balance -= request.Amount
-	toAccount.Balance += request.Amount
+	review := &models.TransferReview{
+		FromAccountID: request.FromAccountID,
+		ToAccountID:   request.ToAccountID,
+		Amount:        request.Amount,
+		Description:   request.Description,
+		Status:        models.ReviewPending,
+		Score:         assessment.Score,
+		Reasons:       assessment.Reasons,
+		RequestedBy:   actor.Type + ":" + actor.ID,
+	}
+	action := models.AuditTransferHeld
+	if assessment.Decision == models.FraudBlock {
+		review.Status = models.ReviewBlocked
+		action = models.AuditTransferBlocked
+	}
 
-	// Save from account
-	result := fromTx.Save(fromAccount)
-	if err := result.Error(); err != nil {
+	entry := models.NewAuditEntry(actor, action, models.AuditTargetTransferReview, "").
+		WithChange(nil, assessment)
+	if err := s.reviewRepo.Create(ctx, review, entry); err != nil {
 		return err
 	}
+	actorLogger(actor).Warn("transfer stopped by screening",
+		"review_id", review.ID, "status", review.Status, "score", assessment.Score, "reasons", assessment.Reasons,
+		"from_account_id", request.FromAccountID, "to_account_id", request.ToAccountID, "amount", request.Amount)
 
-	// Save to account
-	result = toTx.Save(toAccount)
-	if err := result.Error(); err != nil {
-		return err
+	if review.Status == models.ReviewBlocked {
+		return ErrTransferBlocked
 	}
+	return &TransferHeldError{Review: review}
+}
 
-	// Create withdrawal transaction for from account
-	withdrawalDesc := fmt.Sprintf("Transfer to account %s: %s", toAccount.AccountNumber, request.Description)
-	withdrawal := &models.Transaction{
-		AccountID:       fromAccount.ID,
-		SourceAccountID: &fromAccount.ID,
-		TargetAccountID: &toAccount.ID,
-		Amount:          request.Amount,
-		Balance:         fromAccount.Balance,
-		Type:            models.Transfer,
-		Description:     withdrawalDesc,
-		TransactionDate: time.Now(),
-	}
-
-	// Create deposit transaction for to account
-	depositDesc := fmt.Sprintf("Transfer from account %s: %s", fromAccount.AccountNumber, request.Description)
-	deposit := &models.Transaction{
-		AccountID:       toAccount.ID,
-		SourceAccountID: &fromAccount.ID,
-		TargetAccountID: &toAccount.ID,
-		Amount:          request.Amount,
-		Balance:         toAccount.Balance,
-		Type:            models.Transfer,
-		Description:     depositDesc,
-		TransactionDate: time.Now(),
-	}
-
-	// Create withdrawal transaction
-	if err := s.transactionRepo.CreateWithTx(withdrawal, fromTx); err != nil {
+// screenPayee screens the owner of the to account. A watchlist match holds
+// the transfer, or blocks it when the match is close enough.
+func (s *transactionService) screenPayee(ctx context.Context, request *models.TransferRequest, assessment *models.FraudAssessment) error {
+	toAccount, err := s.accountRepo.FindByID(ctx, request.ToAccountID)
+	if err != nil {
+		return err
+	}
+	screening, err := s.options.Sanctions.ScreenUser(ctx, toAccount.UserID, models.ScreeningTransfer)
+	if err != nil {
 		return err
 	}
 
-	// Create deposit transaction
-	if err := s.transactionRepo.CreateWithTx(deposit, toTx); err != nil {
+	switch screening.Decision {
+	case models.ScreeningBlock:
+		assessment.Decision = models.FraudBlock
+	case models.ScreeningFlag:
+		if assessment.Decision == models.FraudAllow {
+			assessment.Decision = models.FraudReview
+		}
+	default:
+		return nil
+	}
+	assessment.Reasons = append(assessment.Reasons, "sanctions: payee matches the watchlist")
+	return nil
+}
+
+// executeTransfer moves the money and records it in the audit log in one
+// transaction, so the debit, the credit and the audit entry are kept or lost
+// together. Approving a review resolves it in that transaction too.
+func (s *transactionService) executeTransfer(ctx context.Context, request *models.TransferRequest, actor models.AuditActor, review *models.TransferReview) (err error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.executeTransfer")
+	defer func() { tracing.End(span, err) }()
+
+	// Lock both accounts for update to prevent race conditions
+	return s.accountRepo.LockForTransfer(ctx, request.FromAccountID, request.ToAccountID, func(fromAccount, toAccount *models.Account, tx repository.GormTx) error {
+		// Check if from account has sufficient balance
+		if fromAccount.Balance < request.Amount {
+			return ErrInsufficientFunds
+		}
+
+		// Frozen accounts are checked under the locks, so a freeze applies to
+		// transfers already being screened or waiting for review
+		if fromAccount.FrozenAt != nil || toAccount.FrozenAt != nil {
+			return ErrAccountFrozen
+		}
+
+		// The daily limit is checked again under the locks, counting the transfers
+		// committed since the first check. Approved transfers were checked before
+		// they were held.
+		if s.options.KYC != nil && review == nil {
+			if err := s.options.KYC.CheckTransferWithTx(ctx, fromAccount.UserID, request.Amount, tx); err != nil {
+				actorLogger(actor).Info("transfer refused by KYC policy",
+					"from_account_id", request.FromAccountID, "amount", request.Amount, "reason", err.Error())
+				return err
+			}
+		}
+
+		before := transferAudit{fromAccount.ID, fromAccount.Balance, toAccount.ID, toAccount.Balance}
+
+		// Update balances
+		fromAccount.Balance -= request.Amount
+		toAccount.Balance += request.Amount
+
+		// Save from account
+		result := tx.Save(fromAccount)
+		if err := result.Error(); err != nil {
+			return err
+		}
+
+		// Save to account
+		result = tx.Save(toAccount)
+		if err := result.Error(); err != nil {
+			return err
+		}
+
+		// Create withdrawal transaction for from account
+		withdrawalDesc := fmt.Sprintf("Transfer to account %s: %s", toAccount.AccountNumber, request.Description)
+		withdrawal := &models.Transaction{
+			AccountID:       fromAccount.ID,
+			SourceAccountID: &fromAccount.ID,
+			TargetAccountID: &toAccount.ID,
+			Amount:          request.Amount,
+			Balance:         fromAccount.Balance,
+			Type:            models.Transfer,
+			Description:     withdrawalDesc,
+			TransactionDate: time.Now(),
+		}
+
+		// Create deposit transaction for to account
+		depositDesc := fmt.Sprintf("Transfer from account %s: %s", fromAccount.AccountNumber, request.Description)
+		deposit := &models.Transaction{
+			AccountID:       toAccount.ID,
+			SourceAccountID: &fromAccount.ID,
+			TargetAccountID: &toAccount.ID,
+			Amount:          request.Amount,
+			Balance:         toAccount.Balance,
+			Type:            models.Transfer,
+			Description:     depositDesc,
+			TransactionDate: time.Now(),
+		}
+
+		// Create withdrawal transaction
+		if err := s.transactionRepo.CreateWithTx(ctx, withdrawal, tx); err != nil {
+			return err
+		}
+
+		// Create deposit transaction
+		if err := s.transactionRepo.CreateWithTx(ctx, deposit, tx); err != nil {
+			return err
+		}
+
+		// Record the transfer with the money movement
+		entry := models.NewAuditEntry(actor, models.AuditTransferCompleted, models.AuditTargetAccount, fromAccount.ID)
+		if review != nil {
+			if err := s.reviewRepo.ResolveWithTx(ctx, review, tx); err != nil {
+				return err
+			}
+			entry = models.NewAuditEntry(actor, models.AuditTransferApproved, models.AuditTargetTransferReview, review.ID)
+		}
+		entry.WithChange(before, transferAudit{fromAccount.ID, fromAccount.Balance, toAccount.ID, toAccount.Balance})
+		return s.auditRepo.AppendWithTx(ctx, entry, tx)
+	})
+}
+
+// GetTransferReviews returns transfers held or blocked by fraud screening,
+// oldest first. An empty status returns every review.
+func (s *transactionService) GetTransferReviews(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error) {
+	return s.reviewRepo.FindByStatus(ctx, status, limit, offset)
+}
+
+// ApproveTransfer completes a held transfer. Step-up and fraud screening are
+// not repeated, but the from account must still have the funds.
+func (s *transactionService) ApproveTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) (err error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.ApproveTransfer", attribute.Int64("review.id", int64(reviewID)))
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.pendingReview(ctx, reviewID)
+	if err != nil {
 		return err
 	}
 
-	// Commit from account transaction
-	commitResult := fromTx.Commit()
-	if err := commitResult.Error(); err != nil {
+	resolveReview(review, models.ReviewApproved, adminID, note)
+	if err := s.executeTransfer(ctx, review.TransferRequest(), actor, review); err != nil {
 		return err
 	}
-	fromTx = nil
+	metrics.ObserveTransfer(metrics.TransferCompleted, review.Amount)
+	actorLogger(actor).Info("held transfer approved", "review_id", review.ID, "amount", review.Amount)
+	return nil
+}
 
-	// Commit to account transaction
-	commitResult = toTx.Commit()
-	if err := commitResult.Error(); err != nil {
+// RejectTransfer closes a held transfer without moving any money
+func (s *transactionService) RejectTransfer(ctx context.Context, reviewID, adminID uint, note string, actor models.AuditActor) (err error) {
+	_, span := tracing.Start(ctx, "TransactionService.RejectTransfer", attribute.Int64("review.id", int64(reviewID)))
+	defer func() { tracing.End(span, err) }()
+
+	review, err := s.pendingReview(ctx, reviewID)
+	if err != nil {
 		return err
 	}
-	toTx = nil
 
+	resolveReview(review, models.ReviewRejected, adminID, note)
+	entry := models.NewAuditEntry(actor, models.AuditTransferRejected, models.AuditTargetTransferReview, review.ID).
+		WithChange(nil, reviewAudit{review.Status, note})
+	if err := s.reviewRepo.Resolve(ctx, review, entry); err != nil {
+		return err
+	}
+	actorLogger(actor).Info("held transfer rejected", "review_id", review.ID, "amount", review.Amount)
 	return nil
 }
+
+// transferAttributes describes a transfer on its span
+func transferAttributes(request *models.TransferRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64("transfer.from_account_id", int64(request.FromAccountID)),
+		attribute.Int64("transfer.to_account_id", int64(request.ToAccountID)),
+		attribute.Float64("transfer.amount", request.Amount),
+	}
+}
+
+// transferFailureReason names why a transfer failed, from a fixed set of
+// values so that error messages do not become metric labels
+func transferFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrAmountPrecision):
+		return "invalid_amount"
+	case errors.Is(err, ErrSameAccount):
+		return "same_account"
+	case errors.Is(err, ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, ErrAccountFrozen):
+		return "account_frozen"
+	case errors.Is(err, ErrStepUpRequired):
+		return "step_up_required"
+	case errors.Is(err, ErrKYCRequired):
+		return "kyc_required"
+	case errors.Is(err, ErrKYCLimitExceeded):
+		return "kyc_limit_exceeded"
+	case errors.Is(err, ErrTransferBlocked):
+		return "blocked"
+	default:
+		return "error"
+	}
+}
+
+func (s *transactionService) pendingReview(ctx context.Context, reviewID uint) (*models.TransferReview, error) {
+	review, err := s.reviewRepo.FindByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.ReviewPending {
+		return nil, ErrReviewNotPending
+	}
+	return review, nil
+}
+
+func resolveReview(review *models.TransferReview, status string, adminID uint, note string) {
+	now := time.Now()
+	review.Status = status
+	review.ReviewedByID = &adminID
+	review.ReviewNote = note
+	review.ReviewedAt = &now
+}
+
+// reviewAudit - Decision on a transfer review as recorded in the audit log
+type reviewAudit struct {
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// transferAudit - Balances of the accounts in a transfer as recorded in the audit log
+type transferAudit struct {
+	FromAccountID uint    `json:"fromAccountId"`
+	FromBalance   float64 `json:"fromBalance"`
+	ToAccountID   uint    `json:"toAccountId"`
+	ToBalance     float64 `json:"toBalance"`
+}
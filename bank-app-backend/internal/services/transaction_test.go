@@ -1,14 +1,21 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // Create a mock for the transaction repository
@@ -16,12 +23,12 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) Create(transaction *models.Transaction) error {
+func (m *MockTransactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
 	args := m.Called(transaction)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) FindByID(id uint) (*models.Transaction, error) {
+func (m *MockTransactionRepository) FindByID(ctx context.Context, id uint) (*models.Transaction, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,31 +36,107 @@ func (m *MockTransactionRepository) FindByID(id uint) (*models.Transaction, erro
 	return args.Get(0).(*models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(accountID, limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindAll(limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindAll(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CountByAccountID(accountID uint) (int64, error) {
+func (m *MockTransactionRepository) CountByAccountID(ctx context.Context, accountID uint) (int64, error) {
 	args := m.Called(accountID)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CountAll() (int64, error) {
+func (m *MockTransactionRepository) CountAll(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CreateWithTx(transaction *models.Transaction, tx repository.GormTx) error {
+func (m *MockTransactionRepository) CreateWithTx(ctx context.Context, transaction *models.Transaction, tx repository.GormTx) error {
 	args := m.Called(transaction, tx)
 	return args.Error(0)
 }
 
+func (m *MockTransactionRepository) LedgerTotals(ctx context.Context) ([]models.LedgerTotals, error) {
+	args := m.Called()
+	return args.Get(0).([]models.LedgerTotals), args.Error(1)
+}
+
+func (m *MockTransactionRepository) CountTransfersFrom(ctx context.Context, accountID uint, since time.Time) (int64, error) {
+	args := m.Called(accountID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) AverageTransferFrom(ctx context.Context, accountID uint) (float64, int64, error) {
+	args := m.Called(accountID)
+	return args.Get(0).(float64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransactionRepository) HasTransferred(ctx context.Context, fromAccountID, toAccountID uint) (bool, error) {
+	args := m.Called(fromAccountID, toAccountID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumTransfersByUser(ctx context.Context, userID uint, since time.Time) (float64, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumTransfersByUserWithTx(ctx context.Context, userID uint, since time.Time, tx repository.GormTx) (float64, error) {
+	args := m.Called(userID, since, tx)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+// Create a mock for the transfer review repository
+type MockTransferReviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferReviewRepository) Create(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	args := m.Called(review, entry)
+	return args.Error(0)
+}
+
+func (m *MockTransferReviewRepository) FindByID(ctx context.Context, id uint) (*models.TransferReview, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransferReview), args.Error(1)
+}
+
+func (m *MockTransferReviewRepository) FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.TransferReview), args.Error(1)
+}
+
+func (m *MockTransferReviewRepository) Resolve(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	args := m.Called(review, entry)
+	return args.Error(0)
+}
+
+func (m *MockTransferReviewRepository) ResolveWithTx(ctx context.Context, review *models.TransferReview, tx repository.GormTx) error {
+	args := m.Called(review, tx)
+	return args.Error(0)
+}
+
+// Create a mock for the fraud service
+type MockFraudService struct {
+	mock.Mock
+}
+
+func (m *MockFraudService) Assess(ctx context.Context, request *models.TransferRequest, fromAccount *models.Account) (*models.FraudAssessment, error) {
+	args := m.Called(request, fromAccount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FraudAssessment), args.Error(1)
+}
+
 func TestCreateTransaction_Deposit(t *testing.T) {
 	// Create mock repositories
 	mockTransactionRepo := new(MockTransactionRepository)
@@ -89,10 +172,10 @@ func TestCreateTransaction_Deposit(t *testing.T) {
 	})).Return(nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.CreateTransaction(testTransaction)
+	err := service.CreateTransaction(context.Background(), testTransaction)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -136,10 +219,10 @@ func TestCreateTransaction_Withdrawal_Success(t *testing.T) {
 	})).Return(nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.CreateTransaction(testTransaction)
+	err := service.CreateTransaction(context.Background(), testTransaction)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -174,10 +257,10 @@ func TestCreateTransaction_Withdrawal_InsufficientFunds(t *testing.T) {
 	mockAccountRepo.On("FindByID", uint(1)).Return(testAccount, nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.CreateTransaction(testTransaction)
+	err := service.CreateTransaction(context.Background(), testTransaction)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -205,10 +288,10 @@ func TestCreateTransaction_InvalidType(t *testing.T) {
 	}
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.CreateTransaction(testTransaction)
+	err := service.CreateTransaction(context.Background(), testTransaction)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -239,10 +322,10 @@ func TestGetTransactionByID_Success(t *testing.T) {
 	mockTransactionRepo.On("FindByID", uint(1)).Return(testTransaction, nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	transaction, err := service.GetTransactionByID(1)
+	transaction, err := service.GetTransactionByID(context.Background(), 1)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -261,10 +344,10 @@ func TestGetTransactionByID_NotFound(t *testing.T) {
 	mockTransactionRepo.On("FindByID", uint(999)).Return(nil, errors.New("transaction not found"))
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	transaction, err := service.GetTransactionByID(999)
+	transaction, err := service.GetTransactionByID(context.Background(), 999)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -288,10 +371,10 @@ func TestGetTransactionsByAccountID_Success(t *testing.T) {
 	mockTransactionRepo.On("FindByAccountID", uint(1), 10, 0).Return(testTransactions, nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	transactions, err := service.GetTransactionsByAccountID(1, 10, 0)
+	transactions, err := service.GetTransactionsByAccountID(context.Background(), 1, 10, 0)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -316,10 +399,10 @@ func TestGetAllTransactions_Success(t *testing.T) {
 	mockTransactionRepo.On("FindAll", 10, 0).Return(testTransactions, nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	transactions, err := service.GetAllTransactions(10, 0)
+	transactions, err := service.GetAllTransactions(context.Background(), 10, 0)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -393,17 +476,23 @@ func TestTransfer_Success(t *testing.T) {
 	}
 	
 	// Set up expectations
-	mockAccountRepo.On("FindByIDWithLock", uint(1)).Return(fromAccount, mockTx, nil)
-	mockAccountRepo.On("FindByIDWithLock", uint(2)).Return(toAccount, mockTx, nil)
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
 	mockTransactionRepo.On("CreateWithTx", mock.AnythingOfType("*models.Transaction"), mockTx).Return(nil).Twice()
 	mockTx.On("Save", mock.Anything).Return(GormDBResult{Err: nil}).Twice()
-	mockTx.On("Commit").Return(GormDBResult{Err: nil}).Twice()
+	
+	// The transfer is audited in the from account's transaction
+	mockAuditRepo := new(MockAuditRepository)
+	mockAuditRepo.On("AppendWithTx", mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditTransferCompleted && entry.TargetID == "1" &&
+			entry.Before == `{"fromAccountId":1,"fromBalance":100,"toAccountId":2,"toBalance":50}` &&
+			entry.After == `{"fromAccountId":1,"fromBalance":75,"toAccountId":2,"toBalance":75}`
+	}), mockTx).Return(nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, mockAuditRepo, new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.Transfer(transferRequest)
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -414,6 +503,68 @@ func TestTransfer_Success(t *testing.T) {
 	mockTx.AssertExpectations(t)
 	mockAccountRepo.AssertExpectations(t)
 	mockTransactionRepo.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+}
+
+func TestTransfer_AuditFailureRollsBack(t *testing.T) {
+	// Create mock repositories
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockTx := new(MockDB)
+	
+	fromAccount := &models.Account{ID: 1, AccountNumber: "1234567890", Balance: 100.0}
+	toAccount := &models.Account{ID: 2, AccountNumber: "0987654321", Balance: 50.0}
+	transferRequest := &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}
+	
+	// Set up expectations
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
+	mockTransactionRepo.On("CreateWithTx", mock.AnythingOfType("*models.Transaction"), mockTx).Return(nil).Twice()
+	mockTx.On("Save", mock.Anything).Return(GormDBResult{Err: nil}).Twice()
+	mockAuditRepo.On("AppendWithTx", mock.Anything, mockTx).Return(errors.New("audit log unavailable"))
+	
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, mockAuditRepo, new(MockTransferReviewRepository), TransactionOptions{})
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
+	
+	// Assert expectations - the error reaches the locked transaction, which
+	// rolls back, so nothing is committed without its audit entry
+	assert.EqualError(t, err, "audit log unavailable")
+	mockTx.AssertExpectations(t)
+}
+
+func TestTransfer_CreditFailureRollsBack(t *testing.T) {
+	// Create mock repositories
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockTx := new(MockDB)
+	
+	fromAccount := &models.Account{ID: 1, AccountNumber: "1234567890", Balance: 100.0}
+	toAccount := &models.Account{ID: 2, AccountNumber: "0987654321", Balance: 50.0}
+	transferRequest := &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}
+	
+	// Set up expectations - the debit is written, the credit fails
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
+	mockTx.On("Save", mock.Anything).Return(GormDBResult{Err: nil}).Twice()
+	mockTransactionRepo.On("CreateWithTx", mock.MatchedBy(func(transaction *models.Transaction) bool {
+		return transaction.AccountID == 1
+	}), mockTx).Return(nil)
+	mockTransactionRepo.On("CreateWithTx", mock.MatchedBy(func(transaction *models.Transaction) bool {
+		return transaction.AccountID == 2
+	}), mockTx).Return(errors.New("deposit not written"))
+	
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, mockAuditRepo, new(MockTransferReviewRepository), TransactionOptions{})
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
+	
+	// Assert expectations - the debit was made in the same transaction as the
+	// failed credit, which rolls back, and nothing is audited
+	assert.EqualError(t, err, "deposit not written")
+	mockAuditRepo.AssertNotCalled(t, "AppendWithTx", mock.Anything, mock.Anything)
+	mockTransactionRepo.AssertExpectations(t)
 }
 
 func TestTransfer_InsufficientFunds(t *testing.T) {
@@ -439,16 +590,14 @@ func TestTransfer_InsufficientFunds(t *testing.T) {
 		Description:   "Test transfer",
 	}
 	
-	// Set up expectations
-	mockAccountRepo.On("FindByIDWithLock", uint(1)).Return(fromAccount, mockTx, nil)
-	// We expect Rollback to be called since we're failing with insufficient funds
-	mockTx.On("Rollback").Return(GormDBResult{Err: nil})
+	// Set up expectations - the transaction rolls back without saving anything
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, &models.Account{ID: 2}, mockTx, nil)
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.Transfer(transferRequest)
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -473,10 +622,10 @@ func TestTransfer_SameAccount(t *testing.T) {
 	}
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.Transfer(transferRequest)
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -497,12 +646,370 @@ func TestTransfer_InvalidAmount(t *testing.T) {
 	}
 	
 	// Create service with mock repos
-	service := NewTransactionService(mockTransactionRepo, mockAccountRepo)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
 	
 	// Call the method being tested
-	err := service.Transfer(transferRequest)
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
 	
 	// Assert expectations
 	assert.Error(t, err)
 	assert.Equal(t, "transfer amount must be positive", err.Error())
 }
+
+func TestTransfer_FractionOfACent(t *testing.T) {
+	// Create mock repositories
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	
+	// Create service with mock repos
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
+	
+	// Call the method being tested - amounts are stored in whole cents
+	err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 10.005}, nil, testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrAmountPrecision)
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+}
+
+func TestTransfer_WholeCentsAllowed(t *testing.T) {
+	// Create service with mock repos
+	service := NewTransactionService(new(MockTransactionRepository), new(MockAccountRepository), new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
+	
+	// Floating point cannot represent these exactly, which must not count as a
+	// fraction of a cent; the transfers get as far as the same-account check
+	for _, amount := range []float64{0.29, 19.99, 1234.56, 0.1 + 0.2} {
+		err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 1, Amount: amount}, nil, testActor)
+		assert.ErrorIs(t, err, ErrSameAccount, "%v", amount)
+	}
+}
+
+func TestTransfer_FailureMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *models.TransferRequest
+		reason  string
+	}{
+		{"same account", &models.TransferRequest{FromAccountID: 1, ToAccountID: 1, Amount: 25.0}, "same_account"},
+		{"invalid amount", &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: -25.0}, "invalid_amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			service := NewTransactionService(new(MockTransactionRepository), new(MockAccountRepository), new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
+			failures := testutil.ToFloat64(metrics.TransferFailures.WithLabelValues(tt.reason))
+			failed := testutil.ToFloat64(metrics.Transfers.WithLabelValues(metrics.TransferFailed))
+
+			// Act
+			err := service.Transfer(context.Background(), tt.request, nil, testActor)
+
+			// Assert
+			assert.Error(t, err)
+			assert.Equal(t, failures+1, testutil.ToFloat64(metrics.TransferFailures.WithLabelValues(tt.reason)))
+			assert.Equal(t, failed+1, testutil.ToFloat64(metrics.Transfers.WithLabelValues(metrics.TransferFailed)))
+		})
+	}
+}
+
+func TestTransfer_Span(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	service := NewTransactionService(new(MockTransactionRepository), new(MockAccountRepository), new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
+
+	// Act
+	err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 1, Amount: 25.0}, nil, testActor)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrSameAccount)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "TransactionService.Transfer", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func TestTransferFailureReason(t *testing.T) {
+	assert.Equal(t, "insufficient_funds", transferFailureReason(ErrInsufficientFunds))
+	assert.Equal(t, "step_up_required", transferFailureReason(ErrStepUpRequired))
+	assert.Equal(t, "kyc_limit_exceeded", transferFailureReason(ErrKYCLimitExceeded))
+	assert.Equal(t, "blocked", transferFailureReason(ErrTransferBlocked))
+	assert.Equal(t, "error", transferFailureReason(errors.New("account not found")), "unknown errors do not become labels")
+}
+
+func TestTransfer_StepUpRequired(t *testing.T) {
+	// Create mocks
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	
+	// Create service with a step-up threshold
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{
+		StepUpThreshold: 1000,
+		StepUpMaxAge:    5 * time.Minute,
+	})
+	
+	transferRequest := &models.TransferRequest{
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        1500.0,
+	}
+	
+	// A regular session, and an elevated one that is too old
+	basic := &AuthContext{UserID: 1, AuthTime: time.Now(), AuthLevel: AuthLevelBasic}
+	stale := &AuthContext{UserID: 1, AuthTime: time.Now().Add(-10 * time.Minute), AuthLevel: AuthLevelElevated}
+	
+	// Call the method being tested
+	assert.ErrorIs(t, service.Transfer(context.Background(), transferRequest, nil, testActor), ErrStepUpRequired)
+	assert.ErrorIs(t, service.Transfer(context.Background(), transferRequest, basic, testActor), ErrStepUpRequired)
+	assert.ErrorIs(t, service.Transfer(context.Background(), transferRequest, stale, testActor), ErrStepUpRequired)
+	
+	// No account is touched before step-up
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+}
+
+func TestTransfer_FrozenAccount(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockTx := new(MockDB)
+	
+	frozenAt := time.Now()
+	fromAccount := &models.Account{ID: 1, AccountNumber: "1234567890", Balance: 100.0}
+	toAccount := &models.Account{ID: 2, AccountNumber: "0987654321", Balance: 50.0, FrozenAt: &frozenAt}
+	
+	// The locked transaction rolls back without saving anything
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
+	
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{})
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}, nil, testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrAccountFrozen)
+	mockTx.AssertExpectations(t)
+	mockTx.AssertNotCalled(t, "Save", mock.Anything)
+	mockTransactionRepo.AssertNotCalled(t, "CreateWithTx", mock.Anything, mock.Anything)
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	elevated := &AuthContext{UserID: 1, AuthTime: time.Now().Add(-time.Minute), AuthLevel: AuthLevelElevated}
+	
+	assert.NoError(t, RequireRecentAuth(elevated, 5*time.Minute))
+	assert.ErrorIs(t, RequireRecentAuth(elevated, 30*time.Second), ErrStepUpRequired)
+	assert.ErrorIs(t, RequireRecentAuth(nil, 5*time.Minute), ErrStepUpRequired)
+}
+
+func TestTransfer_HeldForReview(t *testing.T) {
+	// Create mocks
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	mockReviewRepo := new(MockTransferReviewRepository)
+	mockFraud := new(MockFraudService)
+	
+	fromAccount := &models.Account{ID: 1, UserID: 3, Balance: 10000.0}
+	transferRequest := &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 6000.0}
+	
+	// Set up expectations
+	mockAccountRepo.On("FindByID", uint(1)).Return(fromAccount, nil)
+	mockFraud.On("Assess", transferRequest, fromAccount).Return(&models.FraudAssessment{
+		Score:    70,
+		Reasons:  []string{"new payee: first transfer to account 2 is 6000.00", "password change: owner's password was changed recently"},
+		Decision: models.FraudReview,
+	}, nil)
+	mockReviewRepo.On("Create", mock.MatchedBy(func(review *models.TransferReview) bool {
+		return review.Status == models.ReviewPending && review.Score == 70 && len(review.Reasons) == 2 && review.RequestedBy == "user:1"
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditTransferHeld && entry.TargetType == models.AuditTargetTransferReview
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.TransferReview).ID = 9
+	}).Return(nil)
+	
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), mockReviewRepo, TransactionOptions{Fraud: mockFraud})
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
+	
+	// Assert expectations - no money moves while the transfer is held
+	var held *TransferHeldError
+	assert.ErrorAs(t, err, &held)
+	assert.Equal(t, uint(9), held.Review.ID)
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+	mockReviewRepo.AssertExpectations(t)
+}
+
+func TestTransfer_Blocked(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockReviewRepo := new(MockTransferReviewRepository)
+	mockFraud := new(MockFraudService)
+	
+	fromAccount := &models.Account{ID: 1, UserID: 3, Balance: 10000.0}
+	transferRequest := &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 6000.0}
+	
+	// Set up expectations
+	mockAccountRepo.On("FindByID", uint(1)).Return(fromAccount, nil)
+	mockFraud.On("Assess", transferRequest, fromAccount).Return(&models.FraudAssessment{Score: 120, Reasons: []string{"velocity: 12 transfers in the last 10m0s"}, Decision: models.FraudBlock}, nil)
+	mockReviewRepo.On("Create", mock.MatchedBy(func(review *models.TransferReview) bool {
+		return review.Status == models.ReviewBlocked
+	}), mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditTransferBlocked
+	})).Return(nil)
+	
+	service := NewTransactionService(new(MockTransactionRepository), mockAccountRepo, new(MockAuditRepository), mockReviewRepo, TransactionOptions{Fraud: mockFraud})
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), transferRequest, nil, testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrTransferBlocked)
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+	mockReviewRepo.AssertExpectations(t)
+}
+
+func TestApproveTransfer_Success(t *testing.T) {
+	// Create mocks
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockAccountRepo := new(MockAccountRepository)
+	mockAuditRepo := new(MockAuditRepository)
+	mockReviewRepo := new(MockTransferReviewRepository)
+	mockFraud := new(MockFraudService)
+	mockTx := new(MockDB)
+	
+	review := &models.TransferReview{ID: 9, FromAccountID: 1, ToAccountID: 2, Amount: 25.0, Status: models.ReviewPending}
+	fromAccount := &models.Account{ID: 1, AccountNumber: "1234567890", Balance: 100.0}
+	toAccount := &models.Account{ID: 2, AccountNumber: "0987654321", Balance: 50.0}
+	
+	// Set up expectations
+	mockReviewRepo.On("FindByID", uint(9)).Return(review, nil)
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
+	mockTransactionRepo.On("CreateWithTx", mock.AnythingOfType("*models.Transaction"), mockTx).Return(nil).Twice()
+	mockTx.On("Save", mock.Anything).Return(GormDBResult{Err: nil}).Twice()
+	mockReviewRepo.On("ResolveWithTx", mock.MatchedBy(func(review *models.TransferReview) bool {
+		return review.Status == models.ReviewApproved && *review.ReviewedByID == 5 && review.ReviewNote == "customer confirmed"
+	}), mockTx).Return(nil)
+	mockAuditRepo.On("AppendWithTx", auditEntryFor(models.AuditTransferApproved, models.AuditTargetTransferReview, "9"), mockTx).Return(nil)
+	
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, mockAuditRepo, mockReviewRepo, TransactionOptions{Fraud: mockFraud})
+	
+	// Call the method being tested
+	err := service.ApproveTransfer(context.Background(), 9, 5, "customer confirmed", testActor)
+	
+	// Assert expectations - the approved transfer is not screened again
+	assert.NoError(t, err)
+	assert.Equal(t, 75.0, fromAccount.Balance)
+	assert.Equal(t, 75.0, toAccount.Balance)
+	mockFraud.AssertNotCalled(t, "Assess", mock.Anything, mock.Anything)
+	mockReviewRepo.AssertExpectations(t)
+	mockAuditRepo.AssertExpectations(t)
+	mockTx.AssertExpectations(t)
+}
+
+func TestRejectTransfer(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockReviewRepo := new(MockTransferReviewRepository)
+	service := NewTransactionService(new(MockTransactionRepository), mockAccountRepo, new(MockAuditRepository), mockReviewRepo, TransactionOptions{})
+	
+	// Set up expectations
+	mockReviewRepo.On("FindByID", uint(9)).Return(&models.TransferReview{ID: 9, Status: models.ReviewPending}, nil)
+	mockReviewRepo.On("FindByID", uint(10)).Return(&models.TransferReview{ID: 10, Status: models.ReviewApproved}, nil)
+	mockReviewRepo.On("Resolve", mock.MatchedBy(func(review *models.TransferReview) bool {
+		return review.ID == 9 && review.Status == models.ReviewRejected && *review.ReviewedByID == 5
+	}), auditEntryFor(models.AuditTransferRejected, models.AuditTargetTransferReview, "9")).Return(nil)
+	
+	// Call the method being tested
+	err := service.RejectTransfer(context.Background(), 9, 5, "", testActor)
+	notPendingErr := service.RejectTransfer(context.Background(), 10, 5, "", testActor)
+	
+	// Assert expectations - no money moves and a resolved review stays resolved
+	assert.NoError(t, err)
+	assert.ErrorIs(t, notPendingErr, ErrReviewNotPending)
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+	mockReviewRepo.AssertExpectations(t)
+}
+
+func TestTransfer_PayeeOnWatchlist(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision string
+		expected error
+	}{
+		{"flagged payee holds the transfer", models.ScreeningFlag, &TransferHeldError{}},
+		{"blocked payee refuses the transfer", models.ScreeningBlock, ErrTransferBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange - sanctions screening only, so the from account is not read
+			mockAccountRepo := new(MockAccountRepository)
+			mockReviewRepo := new(MockTransferReviewRepository)
+			mockSanctions := new(MockSanctionsService)
+			mockAccountRepo.On("FindByID", uint(2)).Return(&models.Account{ID: 2, UserID: 8}, nil)
+			mockSanctions.On("ScreenUser", uint(8), models.ScreeningTransfer).Return(&models.ScreeningResult{Decision: tt.decision}, nil)
+			mockReviewRepo.On("Create", mock.MatchedBy(func(review *models.TransferReview) bool {
+				return len(review.Reasons) == 1 && review.Reasons[0] == "sanctions: payee matches the watchlist"
+			}), mock.Anything).Return(nil)
+			service := NewTransactionService(new(MockTransactionRepository), mockAccountRepo, new(MockAuditRepository), mockReviewRepo, TransactionOptions{Sanctions: mockSanctions})
+
+			// Act
+			err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}, nil, testActor)
+
+			// Assert
+			assert.IsType(t, tt.expected, err)
+			mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+			mockReviewRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransfer_KYCCheck(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockKYC := new(MockKYCService)
+	service := NewTransactionService(new(MockTransactionRepository), mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{KYC: mockKYC})
+	
+	// Set up expectations
+	mockAccountRepo.On("FindByID", uint(1)).Return(&models.Account{ID: 1, UserID: 7}, nil)
+	mockKYC.On("CheckTransfer", uint(7), 25.0).Return(ErrKYCRequired)
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}, nil, testActor)
+	
+	// Assert expectations
+	assert.Equal(t, ErrKYCRequired, err)
+	mockKYC.AssertExpectations(t)
+	mockAccountRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
+}
+
+func TestTransfer_KYCDailyLimitRecheckedUnderLocks(t *testing.T) {
+	// Create mocks
+	mockAccountRepo := new(MockAccountRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	mockKYC := new(MockKYCService)
+	mockTx := new(MockDB)
+	service := NewTransactionService(mockTransactionRepo, mockAccountRepo, new(MockAuditRepository), new(MockTransferReviewRepository), TransactionOptions{KYC: mockKYC})
+	
+	fromAccount := &models.Account{ID: 1, UserID: 7, AccountNumber: "1234567890", Balance: 100.0}
+	toAccount := &models.Account{ID: 2, AccountNumber: "0987654321", Balance: 50.0}
+	
+	// Set up expectations - another transfer used up the day's limit between
+	// the first check and the locks
+	mockAccountRepo.On("FindByID", uint(1)).Return(fromAccount, nil)
+	mockKYC.On("CheckTransfer", uint(7), 25.0).Return(nil)
+	mockAccountRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockTx, nil)
+	mockKYC.On("CheckTransferWithTx", uint(7), 25.0, mockTx).Return(ErrKYCLimitExceeded)
+	
+	// Call the method being tested
+	err := service.Transfer(context.Background(), &models.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 25.0}, nil, testActor)
+	
+	// Assert expectations - nothing is saved
+	assert.ErrorIs(t, err, ErrKYCLimitExceeded)
+	mockKYC.AssertExpectations(t)
+	mockTx.AssertExpectations(t)
+	mockTx.AssertNotCalled(t, "Save", mock.Anything)
+	mockTransactionRepo.AssertNotCalled(t, "CreateWithTx", mock.Anything, mock.Anything)
+}
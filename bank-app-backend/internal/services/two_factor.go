@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+	challengeAudience = "login-2fa"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+// TwoFactorOptions - Settings used by the two-factor service
+type TwoFactorOptions struct {
+	KeySet       *signing.KeySet
+	Issuer       string // Shown by authenticator apps next to the account name
+	ChallengeTTL time.Duration
+}
+
+// ChallengeClaims - Claims carried by the token that links the two login
+// steps. The user is the subject, not the "id" claim of access tokens, so a
+// challenge cannot be read as an access token for the same user.
+type ChallengeClaims struct {
+	jwt.RegisteredClaims
+}
+
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userID uint) (*models.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userID uint, code string, actor models.AuditActor) ([]string, error)
+	CreateChallenge(ctx context.Context, user *models.User) (*models.TwoFactorChallenge, error)
+	ChallengedUser(ctx context.Context, challengeToken string) (*models.User, error)
+	VerifyLoginCode(ctx context.Context, user *models.User, code string) error
+	VerifyCode(ctx context.Context, user *models.User, code string) error
+	Reset(ctx context.Context, userID uint, actor models.AuditActor) error
+}
+
+type twoFactorService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	options          TwoFactorOptions
+}
+
+func NewTwoFactorService(userRepo repository.UserRepository, recoveryCodeRepo repository.RecoveryCodeRepository, options TwoFactorOptions) TwoFactorService {
+	return &twoFactorService{userRepo, recoveryCodeRepo, options}
+}
+
+// Enroll generates a new TOTP secret for the user. The secret only takes
+// effect once a code generated from it is confirmed.
+func (s *twoFactorService) Enroll(ctx context.Context, userID uint) (*models.TwoFactorEnrollment, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.options.Issuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTwoFactorSecret(ctx, user.ID, key.Secret()); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+	}, nil
+}
+
+// Confirm enables 2FA once the user proves their authenticator is set up and
+// returns a fresh set of recovery codes
+func (s *twoFactorService) Confirm(ctx context.Context, userID uint, code string, actor models.AuditActor) ([]string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+
+	if err := s.useTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := models.NewAuditEntry(actor, models.AuditTwoFactorEnabled, models.AuditTargetUser, user.ID)
+	if err := s.userRepo.EnableTwoFactor(ctx, user.ID, entry); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CreateChallenge issues the short-lived token a client exchanges, together
+// with a second factor, for a token pair
+func (s *twoFactorService) CreateChallenge(ctx context.Context, user *models.User) (*models.TwoFactorChallenge, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.options.ChallengeTTL)
+	claims := &ChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	tokenString, err := s.options.KeySet.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    tokenString,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// ChallengedUser returns the user a login challenge was issued to, who must
+// still have 2FA enabled. The code is verified separately, so the caller can
+// check the user may log in before a code is used up.
+func (s *twoFactorService) ChallengedUser(ctx context.Context, challengeToken string) (*models.User, error) {
+	claims := &ChallengeClaims{}
+	token, err := jwt.ParseWithClaims(challengeToken, claims, s.options.KeySet.Keyfunc)
+	if err != nil || !token.Valid || !claims.VerifyAudience(challengeAudience, true) {
+		return nil, errors.New("invalid or expired challenge")
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	return user, nil
+}
+
+// VerifyLoginCode completes a two-factor login with either a TOTP code or an
+// unused recovery code, which it uses up. A wrong code returns
+// ErrInvalidTwoFactorCode.
+func (s *twoFactorService) VerifyLoginCode(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return s.useTOTP(ctx, user, code)
+	}
+
+	used, err := s.recoveryCodeRepo.Use(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// VerifyCode checks a TOTP code of a user with 2FA enabled, e.g. for step-up
+// authentication. Recovery codes are only accepted at login.
+func (s *twoFactorService) VerifyCode(ctx context.Context, user *models.User, code string) error {
+	if !user.TwoFactorEnabled() {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	return s.useTOTP(ctx, user, strings.TrimSpace(code))
+}
+
+// Reset removes the user's second factor and recovery codes, e.g. after they
+// lost their device
+func (s *twoFactorService) Reset(ctx context.Context, userID uint, actor models.AuditActor) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	entry := models.NewAuditEntry(actor, models.AuditTwoFactorReset, models.AuditTargetUser, userID).
+		WithChange(twoFactorAudit{Enabled: user.TwoFactorEnabled()}, twoFactorAudit{Enabled: false})
+	return s.userRepo.ResetTwoFactor(ctx, userID, entry)
+}
+
+// twoFactorAudit - A user's 2FA state as recorded in the audit log
+type twoFactorAudit struct {
+	Enabled bool `json:"enabled"`
+}
+
+// useTOTP validates a code against the user's secret and consumes its time
+// step so the same code cannot be replayed
+func (s *twoFactorService) useTOTP(ctx context.Context, user *models.User, code string) error {
+	step, ok := matchTOTPStep(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.userRepo.UseTwoFactorStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *twoFactorService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	stored := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		stored[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		}
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(ctx, userID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// matchTOTPStep returns the time step a code belongs to, allowing one step of
+// clock drift either way
+func matchTOTPStep(secret, code string, now time.Time) (int64, bool) {
+	for _, skew := range []int64{0, -1, 1} {
+		at := now.Add(time.Duration(skew*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCode returns a code formatted as two groups of five characters
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a mock for the recovery code repository
+type MockRecoveryCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockRecoveryCodeRepository) ReplaceForUser(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	args := m.Called(userID, codes)
+	return args.Error(0)
+}
+
+func (m *MockRecoveryCodeRepository) Use(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func newTestTwoFactorService(userRepo *MockUserRepository, recoveryCodeRepo *MockRecoveryCodeRepository) TwoFactorService {
+	return NewTwoFactorService(userRepo, recoveryCodeRepo, TwoFactorOptions{
+		KeySet:       signing.NewHMACKeySet("test-secret-key"),
+		Issuer:       "Drank Bank",
+		ChallengeTTL: 5 * time.Minute,
+	})
+}
+
+func TestEnroll_ReturnsSecretAndURL(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("SetTwoFactorSecret", uint(1), mock.AnythingOfType("string")).Return(nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested
+	enrollment, err := service.Enroll(context.Background(), 1)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.NotEmpty(t, enrollment.Secret)
+	assert.Contains(t, enrollment.OTPAuthURL, "otpauth://totp/")
+	assert.Contains(t, enrollment.OTPAuthURL, "secret="+enrollment.Secret)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestEnroll_AlreadyEnabled(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	
+	enabledAt := time.Now()
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, TwoFactorEnabledAt: &enabledAt}, nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, new(MockRecoveryCodeRepository))
+	
+	// Call the method being tested
+	_, err := service.Enroll(context.Background(), 1)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrTwoFactorAlreadyEnabled)
+	mockUserRepo.AssertNotCalled(t, "SetTwoFactorSecret", mock.Anything, mock.Anything)
+}
+
+func TestConfirm_EnablesAndReturnsRecoveryCodes(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	user := &models.User{ID: 1, TwoFactorSecret: testTOTPSecret}
+	code, _ := totp.GenerateCode(testTOTPSecret, time.Now())
+	
+	// Capture the stored recovery codes
+	var stored []models.RecoveryCode
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("UseTwoFactorStep", uint(1), mock.AnythingOfType("int64")).Return(true, nil)
+	mockUserRepo.On("EnableTwoFactor", uint(1), auditEntryFor(models.AuditTwoFactorEnabled, models.AuditTargetUser, "1")).Return(nil)
+	mockRecoveryCodeRepo.On("ReplaceForUser", uint(1), mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).([]models.RecoveryCode) }).
+		Return(nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested
+	codes, err := service.Confirm(context.Background(), 1, code, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Len(t, stored, 10)
+	
+	// Only hashes of the codes are stored
+	assert.Equal(t, hashToken(normalizeRecoveryCode(codes[0])), stored[0].CodeHash)
+	assert.NotContains(t, stored[0].CodeHash, codes[0])
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestConfirm_InvalidCode(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, TwoFactorSecret: testTOTPSecret}, nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested
+	_, err := service.Confirm(context.Background(), 1, "not-a-code", testActor)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	mockUserRepo.AssertNotCalled(t, "EnableTwoFactor", mock.Anything, mock.Anything)
+}
+
+func TestChallengedUser_ThenTOTP(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	enabledAt := time.Now()
+	user := &models.User{ID: 1, TwoFactorSecret: testTOTPSecret, TwoFactorEnabledAt: &enabledAt}
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("UseTwoFactorStep", uint(1), mock.AnythingOfType("int64")).Return(true, nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	challenge, err := service.CreateChallenge(context.Background(), user)
+	assert.NoError(t, err)
+	
+	// Call the method being tested
+	result, err := service.ChallengedUser(context.Background(), challenge.ChallengeToken)
+	assert.NoError(t, err)
+	code, _ := totp.GenerateCode(testTOTPSecret, time.Now())
+	err = service.VerifyLoginCode(context.Background(), result, code)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), result.ID)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestVerifyLoginCode_ReplayedTOTP(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	enabledAt := time.Now()
+	user := &models.User{ID: 1, TwoFactorSecret: testTOTPSecret, TwoFactorEnabledAt: &enabledAt}
+	mockUserRepo.On("UseTwoFactorStep", uint(1), mock.AnythingOfType("int64")).Return(false, nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested
+	code, _ := totp.GenerateCode(testTOTPSecret, time.Now())
+	err := service.VerifyLoginCode(context.Background(), user, code)
+	
+	// Assert expectations
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+}
+
+func TestVerifyLoginCode_WithRecoveryCode(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	enabledAt := time.Now()
+	user := &models.User{ID: 1, TwoFactorSecret: testTOTPSecret, TwoFactorEnabledAt: &enabledAt}
+	mockRecoveryCodeRepo.On("Use", uint(1), hashToken("abcde12345")).Return(true, nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested - codes are accepted regardless of case
+	err := service.VerifyLoginCode(context.Background(), user, "ABCDE-12345")
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockRecoveryCodeRepo.AssertExpectations(t)
+}
+
+func TestChallengedUser_RejectsAccessToken(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockTokenRepo := new(MockTokenRepository)
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	expectFirstSession(mockTokenRepo)
+	
+	// An access token signed with the same key is not a login challenge
+	tokens, err := newTestTokenService(mockTokenRepo, mockUserRepo).IssueTokens(context.Background(), &models.User{ID: 1}, models.DeviceInfo{})
+	assert.NoError(t, err)
+	
+	service := NewTwoFactorService(mockUserRepo, new(MockRecoveryCodeRepository), TwoFactorOptions{
+		KeySet:       signing.NewHMACKeySet("test-secret-key"),
+		ChallengeTTL: 5 * time.Minute,
+	})
+	
+	// Call the method being tested
+	_, err = service.ChallengedUser(context.Background(), tokens.AccessToken)
+	
+	// Assert expectations
+	assert.Error(t, err)
+	mockUserRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+}
+
+func TestTwoFactorReset(t *testing.T) {
+	// Create mocks
+	mockUserRepo := new(MockUserRepository)
+	mockRecoveryCodeRepo := new(MockRecoveryCodeRepository)
+	
+	mockUserRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1}, nil)
+	// The recovery codes are deleted by ResetTwoFactor, in the same transaction
+	// as the secret and the audit entry
+	mockUserRepo.On("ResetTwoFactor", uint(1), auditEntryFor(models.AuditTwoFactorReset, models.AuditTargetUser, "1")).Return(nil)
+	
+	service := newTestTwoFactorService(mockUserRepo, mockRecoveryCodeRepo)
+	
+	// Call the method being tested
+	err := service.Reset(context.Background(), 1, testActor)
+	
+	// Assert expectations
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	assert.Empty(t, mockRecoveryCodeRepo.Calls)
+}
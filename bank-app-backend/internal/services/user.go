@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
@@ -8,13 +9,13 @@ import (
 )
 
 type UserService interface {
-	CreateUser(user *models.User) error
-	GetUserByID(id uint) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id uint) error
-	AuthenticateUser(email, password string) (*models.User, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	DeleteUser(ctx context.Context, id uint) error
+	AuthenticateUser(ctx context.Context, email, password string) (*models.User, error)
 }
 
 type userService struct {
@@ -25,58 +26,60 @@ func NewUserService(userRepo repository.UserRepository) UserService {
 	return &userService{userRepo}
 }
 
-func (s *userService) CreateUser(user *models.User) error {
+// CreateUser stores a new user whose password has already been hashed with
+// User.SetPassword or PasswordService.SetPassword
+func (s *userService) CreateUser(ctx context.Context, user *models.User) error {
 	// Check if user with the same email already exists
-	existingUser, err := s.userRepo.FindByEmail(user.Email)
+	existingUser, err := s.userRepo.FindByEmail(ctx, user.Email)
 	if err == nil && existingUser != nil {
 		return errors.New("user with this email already exists")
 	}
 
-	return s.userRepo.Create(user)
+	return s.userRepo.Create(ctx, user)
 }
 
-func (s *userService) GetUserByID(id uint) (*models.User, error) {
-	return s.userRepo.FindByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return s.userRepo.FindByID(ctx, id)
 }
 
-func (s *userService) GetUserByEmail(email string) (*models.User, error) {
-	return s.userRepo.FindByEmail(email)
+func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.userRepo.FindByEmail(ctx, email)
 }
 
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepo.FindAll()
+func (s *userService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return s.userRepo.FindAll(ctx)
 }
 
-func (s *userService) UpdateUser(user *models.User) error {
+func (s *userService) UpdateUser(ctx context.Context, user *models.User) error {
 	// Check if the user exists
-	_, err := s.userRepo.FindByID(user.ID)
+	_, err := s.userRepo.FindByID(ctx, user.ID)
 	if err != nil {
 		return err
 	}
 
 	// Check if the new email is already taken by another user
 	if user.Email != "" {
-		existingUser, err := s.userRepo.FindByEmail(user.Email)
+		existingUser, err := s.userRepo.FindByEmail(ctx, user.Email)
 		if err == nil && existingUser != nil && existingUser.ID != user.ID {
 			return errors.New("email is already taken by another user")
 		}
 	}
 
-	return s.userRepo.Update(user)
+	return s.userRepo.Update(ctx, user)
 }
 
-func (s *userService) DeleteUser(id uint) error {
+func (s *userService) DeleteUser(ctx context.Context, id uint) error {
 	// Check if the user exists
-	_, err := s.userRepo.FindByID(id)
+	_, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.userRepo.Delete(id)
+	return s.userRepo.Delete(ctx, id)
 }
 
-func (s *userService) AuthenticateUser(email, password string) (*models.User, error) {
-	user, err := s.userRepo.FindByEmail(email)
+func (s *userService) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, errors.New("invalid email or password")
 	}
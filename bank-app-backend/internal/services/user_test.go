@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/stretchr/testify/assert"
@@ -15,12 +17,12 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
+func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -28,7 +30,7 @@ func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -36,21 +38,76 @@ func (m *MockUserRepository) FindByEmail(email string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindAll() ([]models.User, error) {
+func (m *MockUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string, entry *models.AuditEntry) error {
+	args := m.Called(id, passwordHash, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) SetTwoFactorSecret(ctx context.Context, id uint, secret string) error {
+	args := m.Called(id, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTwoFactorStep(ctx context.Context, id uint, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementFailedLogins(ctx context.Context, id uint, windowStart time.Time) (int, error) {
+	args := m.Called(id, windowStart)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Lock(ctx context.Context, id uint, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateAudited(ctx context.Context, user *models.User, entry *models.AuditEntry) error {
+	args := m.Called(user, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Disable(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
 func TestAuthenticateUser_Success(t *testing.T) {
 	// Create a mock repository
 	mockRepo := new(MockUserRepository)
@@ -70,7 +127,7 @@ func TestAuthenticateUser_Success(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method being tested
-	user, err := service.AuthenticateUser("test@example.com", "password123")
+	user, err := service.AuthenticateUser(context.Background(), "test@example.com", "password123")
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -98,7 +155,7 @@ func TestAuthenticateUser_InvalidCredentials(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method with wrong password
-	user, err := service.AuthenticateUser("test@example.com", "wrongpassword")
+	user, err := service.AuthenticateUser(context.Background(), "test@example.com", "wrongpassword")
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -118,7 +175,7 @@ func TestAuthenticateUser_UserNotFound(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method
-	user, err := service.AuthenticateUser("nonexistent@example.com", "password123")
+	user, err := service.AuthenticateUser(context.Background(), "nonexistent@example.com", "password123")
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -146,7 +203,7 @@ func TestGetUserByID_Success(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method being tested
-	user, err := service.GetUserByID(1)
+	user, err := service.GetUserByID(context.Background(), 1)
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -167,7 +224,7 @@ func TestGetUserByID_NotFound(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method
-	user, err := service.GetUserByID(999)
+	user, err := service.GetUserByID(context.Background(), 999)
 	
 	// Assert expectations
 	assert.Error(t, err)
@@ -193,7 +250,7 @@ func TestGetAllUsers_Success(t *testing.T) {
 	service := NewUserService(mockRepo)
 	
 	// Call the method being tested
-	users, err := service.GetAllUsers()
+	users, err := service.GetAllUsers(context.Background())
 	
 	// Assert expectations
 	assert.NoError(t, err)
@@ -0,0 +1,177 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, dir, kid string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func writePublicKey(t *testing.T, dir, kid string, key interface{}) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func testClaims() jwt.Claims {
+	return jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+func parse(ks *KeySet, tokenString string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, ks.Keyfunc)
+}
+
+func TestLoadKeySet_SignsWithLatestKey(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, dir, "2026-01-01", oldKey)
+	writeKey(t, dir, "2026-07-01", rsaKey)
+
+	ks, err := LoadKeySet(dir, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-07-01", ks.SigningKeyID())
+
+	tokenString, err := ks.Sign(testClaims())
+	assert.NoError(t, err)
+
+	token, err := parse(ks, tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-07-01", token.Header["kid"])
+	assert.Equal(t, AlgorithmRS256, token.Method.Alg())
+}
+
+func TestLoadKeySet_OldKeysStillVerify(t *testing.T) {
+	dir := t.TempDir()
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "2026-01-01", oldKey)
+
+	before, err := LoadKeySet(dir, "")
+	assert.NoError(t, err)
+	tokenString, err := before.Sign(testClaims())
+	assert.NoError(t, err)
+
+	// Rotate: add a new key, keep only the public part of the old one
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "2026-07-01", newKey)
+	writePublicKey(t, dir, "2026-01-01", oldKey.Public())
+
+	after, err := LoadKeySet(dir, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-07-01", after.SigningKeyID())
+
+	_, err = parse(after, tokenString)
+	assert.NoError(t, err)
+}
+
+func TestLoadKeySet_ExplicitSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	_, first, _ := ed25519.GenerateKey(rand.Reader)
+	_, second, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "a", first)
+	writeKey(t, dir, "b", second)
+
+	ks, err := LoadKeySet(dir, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", ks.SigningKeyID())
+
+	// A verify-only key cannot sign
+	writePublicKey(t, dir, "c", second.Public())
+	_, err = LoadKeySet(dir, "c")
+	assert.Error(t, err)
+}
+
+func TestLoadKeySet_RejectsWeakRSAKey(t *testing.T) {
+	dir := t.TempDir()
+	weak, _ := rsa.GenerateKey(rand.Reader, 1024)
+	writeKey(t, dir, "weak", weak)
+
+	_, err := LoadKeySet(dir, "")
+	assert.Error(t, err)
+}
+
+func TestLoadKeySet_EmptyDir(t *testing.T) {
+	_, err := LoadKeySet(t.TempDir(), "")
+	assert.Error(t, err)
+}
+
+func TestKeyfunc_RejectsUnknownKid(t *testing.T) {
+	dir := t.TempDir()
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "known", key)
+	ks, _ := LoadKeySet(dir, "")
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	token.Header["kid"] = "unknown"
+	tokenString, _ := token.SignedString(key)
+
+	_, err := parse(ks, tokenString)
+	assert.Error(t, err)
+}
+
+func TestKeyfunc_RejectsHMACWithPublicKey(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, dir, "rsa", rsaKey)
+	ks, _ := LoadKeySet(dir, "")
+
+	// An attacker signs with the public key as an HMAC secret
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = "rsa"
+	tokenString, _ := token.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	_, err := parse(ks, tokenString)
+	assert.Error(t, err)
+}
+
+func TestHMACKeySet(t *testing.T) {
+	ks := NewHMACKeySet("secret")
+
+	tokenString, err := ks.Sign(testClaims())
+	assert.NoError(t, err)
+
+	_, err = parse(ks, tokenString)
+	assert.NoError(t, err)
+	_, err = parse(NewHMACKeySet("other-secret"), tokenString)
+	assert.Error(t, err)
+	assert.Empty(t, ks.JWKS().Keys)
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, dir, "ed", edKey)
+	writeKey(t, dir, "rsa", rsaKey)
+	ks, _ := LoadKeySet(dir, "")
+
+	set := ks.JWKS()
+
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, JSONWebKey{KeyType: "OKP", KeyID: "ed", Use: "sig", Algorithm: AlgorithmEdDSA, Curve: "Ed25519", X: base64URL(edPublic)}, set.Keys[0])
+	assert.Equal(t, "RSA", set.Keys[1].KeyType)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+	assert.Equal(t, base64URL(rsaKey.N.Bytes()), set.Keys[1].N)
+}
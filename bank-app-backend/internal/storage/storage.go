@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrInvalidKey is returned for a key that Save could not have produced
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Store keeps uploaded files under keys it chooses
+type Store interface {
+	Save(data []byte) (string, error)
+	Load(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// validKey matches the keys Save produces, so a key never escapes the directory
+var validKey = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// LocalStore keeps files in a directory on the local disk, readable only by
+// the backend's user
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir}
+}
+
+// Save writes data under a new random key
+func (s *LocalStore) Save(data []byte) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b)
+	if err := os.WriteFile(filepath.Join(s.dir, key), data, 0o600); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Check writes and removes a probe file, so a missing, full or read-only
+// directory is found before an upload fails
+func (s *LocalStore) Check(ctx context.Context) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(s.dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+func (s *LocalStore) Load(key string) ([]byte, error) {
+	if !validKey.MatchString(key) {
+		return nil, ErrInvalidKey
+	}
+	return os.ReadFile(filepath.Join(s.dir, key))
+}
+
+// Delete removes a file; deleting a missing file is not an error
+func (s *LocalStore) Delete(key string) error {
+	if !validKey.MatchString(key) {
+		return ErrInvalidKey
+	}
+	if err := os.Remove(filepath.Join(s.dir, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
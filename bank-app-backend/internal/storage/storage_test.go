@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "documents")
+	store := NewLocalStore(dir)
+
+	t.Run("Save and Load should round trip", func(t *testing.T) {
+		// Act
+		key, err := store.Save([]byte("scan"))
+		data, loadErr := store.Load(key)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, loadErr)
+		assert.Equal(t, []byte("scan"), data)
+
+		info, _ := os.Stat(filepath.Join(dir, key))
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Delete should remove the file and ignore a missing one", func(t *testing.T) {
+		// Arrange
+		key, _ := store.Save([]byte("scan"))
+
+		// Act & Assert
+		assert.NoError(t, store.Delete(key))
+		assert.NoError(t, store.Delete(key))
+		_, err := store.Load(key)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("Check should pass for a writable directory and leave nothing behind", func(t *testing.T) {
+		// Arrange
+		store := NewLocalStore(filepath.Join(t.TempDir(), "new"))
+
+		// Act
+		err := store.Check(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		entries, _ := os.ReadDir(store.dir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Check should fail when the directory cannot be created", func(t *testing.T) {
+		// Arrange - a file stands where the directory should be
+		file := filepath.Join(t.TempDir(), "file")
+		os.WriteFile(file, []byte("x"), 0o600)
+		store := NewLocalStore(filepath.Join(file, "documents"))
+
+		// Act & Assert
+		assert.Error(t, store.Check(context.Background()))
+	})
+
+	t.Run("Keys should not escape the directory", func(t *testing.T) {
+		_, err := store.Load("../../etc/passwd")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.ErrorIs(t, store.Delete("../secret"), ErrInvalidKey)
+	})
+}
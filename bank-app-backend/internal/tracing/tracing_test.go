@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("The stdout exporter writes spans to the file", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "spans.json")
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		// Act
+		shutdown, err := Setup(context.Background(), Options{ServiceName: "test", Exporter: ExporterStdout, File: file, SampleRatio: 1})
+		require.NoError(t, err)
+		_, span := Start(context.Background(), "work")
+		span.End()
+		require.NoError(t, shutdown(context.Background()))
+
+		// Assert
+		written, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(written), `"Name":"work"`)
+	})
+
+	t.Run("An unknown exporter is rejected", func(t *testing.T) {
+		_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+		assert.Error(t, err)
+	})
+}
+
+func TestEnd(t *testing.T) {
+	// Arrange
+	recorder := recordSpans(t)
+
+	// Act
+	_, span := Start(context.Background(), "failing")
+	End(span, errors.New("boom"))
+
+	// Assert
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+}
+
+func TestGormPlugin(t *testing.T) {
+	// Arrange - a dry run builds the SQL without a database
+	recorder := recordSpans(t)
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+	ctx, parent := Start(context.Background(), "request")
+
+	// Act
+	db.WithContext(ctx).Where("name = ?", "secret gear").First(&widget{})
+	db.First(&widget{})
+	parent.End()
+
+	// Assert - only the query made for the request is traced
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "gorm.query widgets", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	for _, attr := range query.Attributes() {
+		if attr.Key == "db.statement" {
+			assert.NotContains(t, attr.Value.AsString(), "secret gear", "values are redacted")
+		}
+	}
+}
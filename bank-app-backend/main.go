@@ -1,31 +1,10 @@
 package main
 
 import (
-	"context"
-	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 
 	_ "github.com/jbadhree/drank/bank-app-backend/docs" // This is for swagger
-	"github.com/jbadhree/drank/bank-app-backend/internal/config"
-	"github.com/jbadhree/drank/bank-app-backend/internal/handlers"
-	"github.com/jbadhree/drank/bank-app-backend/internal/middleware"
-	"github.com/jbadhree/drank/bank-app-backend/internal/models"
-	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
-	"github.com/jbadhree/drank/bank-app-backend/internal/services"
-	"github.com/jbadhree/drank/bank-app-backend/seed"
 )
 
 // @title           Banking API
@@ -48,145 +27,11 @@ import (
 // @name                        Authorization
 // @description                 Bearer token for authentication
 func main() {
-	// Load environment variables
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, using system environment variables")
-	}
-
-	// Configure the application
-	cfg := config.New()
-
-	// Initialize database
-	db, err := initDB(cfg)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-
-	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Transaction{})
-	if err != nil {
-		log.Fatalf("Failed to migrate database schema: %v", err)
-	}
-
-	// Check if seed flag is provided
-	if len(os.Args) > 1 && os.Args[1] == "--seed" {
-		log.Println("Seeding database 'drank' on port 5434...")
-		if err := seed.SeedDatabase(db); err != nil {
-			log.Fatalf("Failed to seed database: %v", err)
-		}
-		log.Println("Database seeded successfully")
-		return
-	}
-
-	// Initialize repositories
-	userRepo := repository.NewUserRepository(db)
-	accountRepo := repository.NewAccountRepository(db)
-	transactionRepo := repository.NewTransactionRepository(db)
-
-	// Initialize services
-	userService := services.NewUserService(userRepo)
-	accountService := services.NewAccountService(accountRepo)
-	transactionService := services.NewTransactionService(transactionRepo, accountRepo)
-
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
-	userHandler := handlers.NewUserHandler(userService)
-	accountHandler := handlers.NewAccountHandler(accountService)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
-
-	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret)
-
-	// Initialize Gin router
-	router := gin.Default()
-
-	// Configure CORS - allow requests from both localhost and the actual server hostname
-	// Get frontend URL from environment or use default
-	frontendURL := os.Getenv("FRONTEND_URL")
-	allowedOrigins := []string{"http://localhost:3000"}
-	if frontendURL != "" {
-		allowedOrigins = append(allowedOrigins, frontendURL)
-	}
-
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     allowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// Swagger documentation
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	// API routes
-	v1 := router.Group("/api/v1")
-	{
-		// Auth routes - no auth required
-		v1.POST("/auth/login", authHandler.Login)
-
-		// User routes - auth required
-		users := v1.Group("/users")
-		users.Use(authMiddleware.Authenticate())
-		{
-			users.GET("", userHandler.GetAllUsers)
-			users.GET("/:id", userHandler.GetUserByID)
-			users.GET("/me", userHandler.GetCurrentUser)
-		}
-
-		// Account routes - auth required
-		accounts := v1.Group("/accounts")
-		accounts.Use(authMiddleware.Authenticate())
-		{
-			accounts.GET("", accountHandler.GetAllAccounts)
-			accounts.GET("/:id", accountHandler.GetAccountByID)
-			accounts.GET("/user/:userId", accountHandler.GetAccountsByUserID)
-		}
-
-		// Transaction routes - auth required
-		transactions := v1.Group("/transactions")
-		transactions.Use(authMiddleware.Authenticate())
-		{
-			transactions.GET("", transactionHandler.GetAllTransactions)
-			transactions.GET("/:id", transactionHandler.GetTransactionByID)
-			transactions.GET("/account/:accountId", transactionHandler.GetTransactionsByAccountID)
-			transactions.POST("/transfer", transactionHandler.Transfer)
-		}
-	}
-
-	// Start server
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Port),
-		Handler: router,
-	}
-
-	// Graceful shutdown
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
-		}
-	}()
-
-	// Wait for interrupt signal to gracefully shut down the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
-	}
-
-	log.Println("Server exited")
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
 }
 
-func initDB(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
-	
-	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// fatal logs an error and exits with status 1
+func fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
 }
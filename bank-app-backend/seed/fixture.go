@@ -0,0 +1,236 @@
+package seed
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/*.yaml
+var builtin embed.FS
+
+// Built-in profiles, loaded from the fixtures directory
+const (
+	ProfileDemo    = "demo"    // Two customers with verified identities, accounts and a month of history, and an admin
+	ProfileMinimal = "minimal" // Only the admin, for trying registration and onboarding from scratch
+)
+
+// Profiles lists the built-in profiles in the order they are documented
+var Profiles = []string{ProfileDemo, ProfileMinimal}
+
+// defaultHistoryDays is how far back history starts when a fixture does not say
+const defaultHistoryDays = 30
+
+// Fixture describes what a seed loads: users with their accounts, the random
+// activity on those accounts and the transfers between them. The same file
+// loads into the Postgres and the Firestore backend; a backend ignores the
+// fields it has no use for.
+type Fixture struct {
+	Name        string              `yaml:"name"`
+	Description string              `yaml:"description"`
+	Password    string              `yaml:"password"`     // Password of the users that do not set their own
+	HistoryDays int                 `yaml:"history_days"` // Days of history before today, 30 when unset
+	Users       []FixtureUser       `yaml:"users"`
+	Activity    map[string]Activity `yaml:"activity"` // Patterns of random transactions, by name
+	Transfers   []FixtureTransfer   `yaml:"transfers"`
+}
+
+// FixtureUser is a user and their accounts
+type FixtureUser struct {
+	Email      string           `yaml:"email"`
+	FirstName  string           `yaml:"first_name"`
+	LastName   string           `yaml:"last_name"`
+	Password   string           `yaml:"password"`
+	Role       string           `yaml:"role"`       // customer, the default, or admin
+	Unverified bool             `yaml:"unverified"` // Leave the email address unverified
+	KYCLevel   int              `yaml:"kyc_level"`  // Verified identity level, 0 for none
+	Accounts   []FixtureAccount `yaml:"accounts"`
+}
+
+// FixtureAccount is an account, opened with a deposit when history starts
+type FixtureAccount struct {
+	Ref            string  `yaml:"ref"`    // Name that transfers use for the account
+	Type           string  `yaml:"type"`   // CHECKING or SAVINGS
+	Number         string  `yaml:"number"` // Generated from the seed value when empty
+	OpeningBalance float64 `yaml:"opening_balance"`
+	Activity       string  `yaml:"activity"` // Name of the activity pattern, none when empty
+}
+
+// Activity is a pattern of random deposits and withdrawals over the history
+type Activity struct {
+	Transactions IntRange   `yaml:"transactions"` // How many per account
+	Amount       MoneyRange `yaml:"amount"`
+	Deposits     []string   `yaml:"deposits"`    // Descriptions to pick from; no deposits when empty
+	Withdrawals  []string   `yaml:"withdrawals"` // Descriptions to pick from; no withdrawals when empty
+}
+
+// IntRange is an inclusive range of whole numbers
+type IntRange struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// MoneyRange is an inclusive range of amounts
+type MoneyRange struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// FixtureTransfer moves money between two accounts at noon on a day of the history
+type FixtureTransfer struct {
+	From        string  `yaml:"from"` // Ref of the account
+	To          string  `yaml:"to"`   // Ref of the account
+	Amount      float64 `yaml:"amount"`
+	DaysAgo     int     `yaml:"days_ago"`
+	Description string  `yaml:"description"` // "Transfer to/from account <number>" when empty
+}
+
+// LoadFixture loads a built-in profile by name, or else a fixture file. YAML
+// and JSON files are both read as YAML, which JSON is a subset of.
+func LoadFixture(nameOrPath string) (*Fixture, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if isProfile(nameOrPath) {
+		data, err = builtin.ReadFile(path.Join("fixtures", nameOrPath+".yaml"))
+	} else {
+		data, err = os.ReadFile(nameOrPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fixture, err := ParseFixture(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nameOrPath, err)
+	}
+	return fixture, nil
+}
+
+func isProfile(name string) bool {
+	for _, profile := range Profiles {
+		if profile == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFixture parses and validates a fixture. Unknown fields are rejected,
+// so that a misspelt field does not silently fall back to its default.
+func ParseFixture(data []byte) (*Fixture, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var fixture Fixture
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, err
+	}
+	if fixture.HistoryDays == 0 {
+		fixture.HistoryDays = defaultHistoryDays
+	}
+	if err := fixture.validate(); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
+
+// validate checks everything that does not depend on what is in the database
+func (f *Fixture) validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if f.HistoryDays < 1 {
+		add("history_days must be positive")
+	}
+	for name, activity := range f.Activity {
+		if activity.Transactions.Min < 0 || activity.Transactions.Max < activity.Transactions.Min {
+			add("activity %s: transactions must have 0 <= min <= max", name)
+		}
+		if activity.Amount.Min <= 0 || activity.Amount.Max < activity.Amount.Min {
+			add("activity %s: amount must have 0 < min <= max", name)
+		}
+		if len(activity.Deposits) == 0 && len(activity.Withdrawals) == 0 {
+			add("activity %s: needs deposit or withdrawal descriptions", name)
+		}
+	}
+
+	emails := map[string]bool{}
+	refs := map[string]bool{}
+	numbers := map[string]bool{}
+	for i, user := range f.Users {
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		switch {
+		case email == "":
+			add("user %d: email is required", i+1)
+		case emails[email]:
+			add("user %s: email appears twice", user.Email)
+		}
+		emails[email] = true
+		if user.FirstName == "" || user.LastName == "" {
+			add("user %s: first_name and last_name are required", user.Email)
+		}
+		if user.Password == "" && f.Password == "" {
+			add("user %s: password is required when the fixture sets none", user.Email)
+		}
+		if user.Role != "" && user.Role != "customer" && user.Role != "admin" {
+			add("user %s: role must be customer or admin", user.Email)
+		}
+		if user.KYCLevel < 0 || user.KYCLevel > 2 {
+			add("user %s: kyc_level must be 0, 1 or 2", user.Email)
+		}
+
+		for j, account := range user.Accounts {
+			name := fmt.Sprintf("user %s account %d", user.Email, j+1)
+			if account.Ref != "" {
+				if refs[account.Ref] {
+					add("%s: ref %s appears twice", name, account.Ref)
+				}
+				refs[account.Ref] = true
+			}
+			if account.Type != "CHECKING" && account.Type != "SAVINGS" {
+				add("%s: type must be CHECKING or SAVINGS", name)
+			}
+			if account.Number != "" {
+				if numbers[account.Number] {
+					add("%s: number %s appears twice", name, account.Number)
+				}
+				numbers[account.Number] = true
+			}
+			if account.OpeningBalance < 0 {
+				add("%s: opening_balance cannot be negative", name)
+			}
+			if _, ok := f.Activity[account.Activity]; account.Activity != "" && !ok {
+				add("%s: unknown activity %s", name, account.Activity)
+			}
+		}
+	}
+
+	for i, transfer := range f.Transfers {
+		name := fmt.Sprintf("transfer %d", i+1)
+		if !refs[transfer.From] || !refs[transfer.To] {
+			add("%s: from and to must be account refs", name)
+		} else if transfer.From == transfer.To {
+			add("%s: from and to are the same account", name)
+		}
+		if transfer.Amount <= 0 {
+			add("%s: amount must be positive", name)
+		}
+		if transfer.DaysAgo < 1 || transfer.DaysAgo > f.HistoryDays {
+			add("%s: days_ago must be between 1 and history_days", name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFixture_BuiltinProfiles(t *testing.T) {
+	for _, profile := range Profiles {
+		t.Run(profile, func(t *testing.T) {
+			// Act
+			fixture, err := LoadFixture(profile)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, profile, fixture.Name)
+			assert.NotEmpty(t, fixture.Users)
+		})
+	}
+}
+
+func TestParseFixture_DefaultsHistoryDays(t *testing.T) {
+	// Act
+	fixture, err := ParseFixture([]byte(`{"password": "secret123", "users": []}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, defaultHistoryDays, fixture.HistoryDays)
+}
+
+func TestParseFixture_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    string
+	}{
+		{"unknown field", "pasword: x", "field pasword not found"},
+		{"duplicate email", `
+password: x
+users:
+  - {email: a@example.com, first_name: A, last_name: A}
+  - {email: A@example.com, first_name: B, last_name: B}`, "email appears twice"},
+		{"missing password", `
+users:
+  - {email: a@example.com, first_name: A, last_name: A}`, "password is required"},
+		{"bad role", `
+password: x
+users:
+  - {email: a@example.com, first_name: A, last_name: A, role: root}`, "role must be customer or admin"},
+		{"bad account type", `
+password: x
+users:
+  - email: a@example.com
+    first_name: A
+    last_name: A
+    accounts: [{type: LOAN}]`, "type must be CHECKING or SAVINGS"},
+		{"unknown activity", `
+password: x
+users:
+  - email: a@example.com
+    first_name: A
+    last_name: A
+    accounts: [{type: CHECKING, activity: busy}]`, "unknown activity busy"},
+		{"unknown transfer ref", `
+password: x
+transfers: [{from: a, to: b, amount: 1, days_ago: 1}]`, "from and to must be account refs"},
+		{"transfer before history", `
+password: x
+history_days: 7
+users:
+  - email: a@example.com
+    first_name: A
+    last_name: A
+    accounts: [{ref: a, type: CHECKING}, {ref: b, type: SAVINGS}]
+transfers: [{from: a, to: b, amount: 1, days_ago: 8}]`, "days_ago must be between 1 and history_days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := ParseFixture([]byte(tt.fixture))
+
+			// Assert
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package seed
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Generate simulates customers and their history with Simulate and adds them
+// to the database, keeping the existing data. Each batch of customers is
+// written in its own database transaction, so an interrupted run keeps the
+// batches it finished; running it again with another email prefix adds more
+// customers.
+func Generate(db *gorm.DB, opts SimulateOptions) (*Summary, error) {
+	taken, err := takenNumbers(db)
+	if err != nil {
+		return nil, err
+	}
+	opts.TakenNumbers = taken
+
+	total := &Summary{}
+	err = Simulate(opts, func(plan *Plan) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			emails := make([]string, len(plan.Users))
+			for i, user := range plan.Users {
+				emails[i] = user.Email
+			}
+			existing, err := existingUsers(tx, emails)
+			if err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				return fmt.Errorf("%d of the customers already exist, starting with %s; generate them with another email prefix", len(existing), firstExisting(emails, existing))
+			}
+
+			summary, err := write(tx, plan)
+			if err != nil {
+				return err
+			}
+			total.Users += summary.Users
+			total.Accounts += summary.Accounts
+			total.Transactions += summary.Transactions
+			slog.Info("Generated customers", "customers", total.Users, "of", opts.Customers, "transactions", total.Transactions)
+			return nil
+		})
+	})
+	return total, err
+}
+
+// firstExisting returns the first of the emails that exists
+func firstExisting(emails []string, existing map[string]bool) string {
+	for _, email := range emails {
+		if existing[strings.ToLower(email)] {
+			return email
+		}
+	}
+	return ""
+}
@@ -0,0 +1,240 @@
+package seed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry kinds, as the transaction types they are stored as
+const (
+	EntryDeposit    = "DEPOSIT"
+	EntryWithdrawal = "WITHDRAWAL"
+	EntryTransfer   = "TRANSFER"
+)
+
+// Plan is everything a fixture loads, worked out before anything is written.
+// The same fixture, seed value and day always give the same plan.
+type Plan struct {
+	Users    []FixtureUser // The users to create, without the ones that were skipped, with their passwords set
+	Skipped  []string      // Emails of the users that already exist
+	Accounts []PlannedAccount
+	Entries  []Entry // Transactions in the order they happened
+}
+
+// PlannedAccount is an account of a planned user with its balance after every entry
+type PlannedAccount struct {
+	User    int // Index into Plan.Users
+	Type    string
+	Number  string
+	Balance float64
+}
+
+// Entry is one transaction on one account. A transfer is two entries, the
+// outgoing one first, that point at each other's accounts.
+type Entry struct {
+	Account     int // Index into Plan.Accounts
+	Kind        string
+	Amount      float64 // Always positive
+	Outgoing    bool    // Money left the account
+	Balance     float64 // Balance of the account after the entry
+	Source      int     // For transfers, index of the account paying; -1 otherwise
+	Target      int     // For transfers, index of the account paid; -1 otherwise
+	Description string
+	Date        time.Time
+}
+
+// PlanOptions changes how a fixture is planned
+type PlanOptions struct {
+	SeedValue     int64           // Seeds the random activity and account numbers
+	Now           time.Time       // History ends the day before; the zero value means now
+	ExistingUsers map[string]bool // Lowercase emails of users to skip
+	TakenNumbers  map[string]bool // Account numbers already in use
+}
+
+// event is an entry before balances are applied; transfers are still one event
+type event struct {
+	from, to    int // from is -1 for a deposit, to is -1 for a withdrawal
+	amount      float64
+	description string
+	date        time.Time
+}
+
+// BuildPlan works out the users, accounts and transactions a fixture loads.
+// Users that already exist are skipped along with every transfer that touches
+// their accounts. Withdrawals and transfers never overdraw an account: one
+// that would is cut to half the balance, and dropped when that is under a cent.
+func BuildPlan(f *Fixture, opts PlanOptions) (*Plan, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	start := today.AddDate(0, 0, -f.HistoryDays)
+
+	plan := &Plan{}
+	taken := map[string]bool{}
+	for number := range opts.TakenNumbers {
+		taken[number] = true
+	}
+	refs := map[string]int{}
+	var events []event
+
+	// Fixed numbers are claimed first so that generated ones cannot take them
+	for _, user := range f.Users {
+		if opts.ExistingUsers[strings.ToLower(user.Email)] {
+			continue
+		}
+		for _, account := range user.Accounts {
+			if account.Number == "" {
+				continue
+			}
+			if taken[account.Number] {
+				return nil, fmt.Errorf("account number %s is already in use", account.Number)
+			}
+			taken[account.Number] = true
+		}
+	}
+
+	for _, user := range f.Users {
+		if opts.ExistingUsers[strings.ToLower(user.Email)] {
+			plan.Skipped = append(plan.Skipped, user.Email)
+			continue
+		}
+		if user.Password == "" {
+			user.Password = f.Password
+		}
+		plan.Users = append(plan.Users, user)
+
+		for j, account := range user.Accounts {
+			key := account.Ref
+			if key == "" {
+				key = fmt.Sprintf("%s#%d", strings.ToLower(user.Email), j)
+			}
+			rng := rand.New(rand.NewSource(opts.SeedValue ^ hashKey(key)))
+
+			number := account.Number
+			for number == "" || (account.Number == "" && taken[number]) {
+				number = fmt.Sprintf("%010d", rng.Int63n(1e10))
+			}
+			taken[number] = true
+
+			index := len(plan.Accounts)
+			plan.Accounts = append(plan.Accounts, PlannedAccount{User: len(plan.Users) - 1, Type: account.Type, Number: number})
+			if account.Ref != "" {
+				refs[account.Ref] = index
+			}
+
+			if account.OpeningBalance > 0 {
+				events = append(events, event{from: -1, to: index, amount: roundCents(account.OpeningBalance), description: "Initial deposit", date: start})
+			}
+			if account.Activity != "" {
+				events = append(events, randomActivity(rng, f.Activity[account.Activity], index, today, f.HistoryDays)...)
+			}
+		}
+	}
+
+	for _, transfer := range f.Transfers {
+		from, okFrom := refs[transfer.From]
+		to, okTo := refs[transfer.To]
+		if !okFrom || !okTo {
+			continue // One side belongs to a skipped user
+		}
+		events = append(events, event{
+			from:        from,
+			to:          to,
+			amount:      roundCents(transfer.Amount),
+			description: transfer.Description,
+			date:        today.AddDate(0, 0, -transfer.DaysAgo).Add(12 * time.Hour),
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].date.Before(events[j].date) })
+	for _, e := range events {
+		plan.apply(e)
+	}
+	return plan, nil
+}
+
+// randomActivity draws the deposits and withdrawals of one account, each on
+// one of the days of the history at a random time of day
+func randomActivity(rng *rand.Rand, activity Activity, account int, today time.Time, days int) []event {
+	count := activity.Transactions.Min + rng.Intn(activity.Transactions.Max-activity.Transactions.Min+1)
+	events := make([]event, 0, count)
+	for i := 0; i < count; i++ {
+		deposit := len(activity.Withdrawals) == 0 || (len(activity.Deposits) > 0 && rng.Intn(2) == 0)
+		amount := roundCents(activity.Amount.Min + rng.Float64()*(activity.Amount.Max-activity.Amount.Min))
+		date := today.AddDate(0, 0, -(1 + rng.Intn(days))).Add(time.Duration(rng.Int63n(int64(24 * time.Hour))))
+
+		e := event{from: -1, to: -1, amount: amount, date: date}
+		if deposit {
+			e.to = account
+			e.description = activity.Deposits[rng.Intn(len(activity.Deposits))]
+		} else {
+			e.from = account
+			e.description = activity.Withdrawals[rng.Intn(len(activity.Withdrawals))]
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// apply turns an event into entries and moves the balances
+func (p *Plan) apply(e event) {
+	amount := e.amount
+	if e.from >= 0 && p.Accounts[e.from].Balance < amount {
+		amount = roundCents(p.Accounts[e.from].Balance / 2)
+		if amount < 0.01 {
+			return
+		}
+	}
+
+	switch {
+	case e.from < 0:
+		p.entry(e.to, EntryDeposit, amount, false, -1, -1, e.description, e.date)
+	case e.to < 0:
+		p.entry(e.from, EntryWithdrawal, amount, true, -1, -1, e.description, e.date)
+	default:
+		out, in := e.description, e.description
+		if out == "" {
+			out = fmt.Sprintf("Transfer to account %s", p.Accounts[e.to].Number)
+			in = fmt.Sprintf("Transfer from account %s", p.Accounts[e.from].Number)
+		}
+		p.entry(e.from, EntryTransfer, amount, true, e.from, e.to, out, e.date)
+		p.entry(e.to, EntryTransfer, amount, false, e.from, e.to, in, e.date)
+	}
+}
+
+func (p *Plan) entry(account int, kind string, amount float64, outgoing bool, source, target int, description string, date time.Time) {
+	balance := p.Accounts[account].Balance + amount
+	if outgoing {
+		balance = p.Accounts[account].Balance - amount
+	}
+	balance = roundCents(balance)
+	p.Accounts[account].Balance = balance
+	p.Entries = append(p.Entries, Entry{
+		Account:     account,
+		Kind:        kind,
+		Amount:      amount,
+		Outgoing:    outgoing,
+		Balance:     balance,
+		Source:      source,
+		Target:      target,
+		Description: description,
+		Date:        date,
+	})
+}
+
+func hashKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
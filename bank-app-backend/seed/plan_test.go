@@ -0,0 +1,144 @@
+package seed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var planNow = time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+
+func demoFixture(t *testing.T) *Fixture {
+	fixture, err := LoadFixture(ProfileDemo)
+	require.NoError(t, err)
+	return fixture
+}
+
+func TestBuildPlan_IsDeterministic(t *testing.T) {
+	// Arrange
+	fixture := demoFixture(t)
+
+	// Act
+	first, err := BuildPlan(fixture, PlanOptions{SeedValue: 7, Now: planNow})
+	require.NoError(t, err)
+	second, err := BuildPlan(fixture, PlanOptions{SeedValue: 7, Now: planNow})
+	require.NoError(t, err)
+	other, err := BuildPlan(fixture, PlanOptions{SeedValue: 8, Now: planNow})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first.Entries, other.Entries)
+}
+
+func TestBuildPlan_BalancesFollowEntries(t *testing.T) {
+	// Arrange
+	fixture := demoFixture(t)
+
+	// Act
+	plan, err := BuildPlan(fixture, PlanOptions{SeedValue: 1, Now: planNow})
+
+	// Assert
+	require.NoError(t, err)
+	balances := make([]float64, len(plan.Accounts))
+	for i, e := range plan.Entries {
+		if i > 0 {
+			assert.False(t, e.Date.Before(plan.Entries[i-1].Date), "entries are in date order")
+		}
+		if e.Outgoing {
+			balances[e.Account] -= e.Amount
+		} else {
+			balances[e.Account] += e.Amount
+		}
+		assert.InDelta(t, balances[e.Account], e.Balance, 0.001)
+		assert.GreaterOrEqual(t, e.Balance, 0.0)
+		assert.True(t, e.Date.Before(planNow), "history ends before today")
+	}
+	for i, account := range plan.Accounts {
+		assert.InDelta(t, balances[i], account.Balance, 0.001)
+	}
+}
+
+func TestBuildPlan_Transfers(t *testing.T) {
+	// Arrange
+	fixture := demoFixture(t)
+
+	// Act
+	plan, err := BuildPlan(fixture, PlanOptions{SeedValue: 1, Now: planNow})
+
+	// Assert
+	require.NoError(t, err)
+	var transfers []Entry
+	for _, e := range plan.Entries {
+		if e.Kind == EntryTransfer {
+			transfers = append(transfers, e)
+		}
+	}
+	require.Len(t, transfers, 4)
+	out, in := transfers[0], transfers[1]
+	assert.True(t, out.Outgoing)
+	assert.False(t, in.Outgoing)
+	assert.Equal(t, out.Source, out.Account)
+	assert.Equal(t, in.Target, in.Account)
+	assert.Equal(t, "Transfer to account 1000000002", out.Description)
+	assert.Equal(t, "Transfer from account 1000000001", in.Description)
+	assert.Equal(t, time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), out.Date)
+}
+
+func TestBuildPlan_SkipsExistingUsers(t *testing.T) {
+	// Arrange
+	fixture := demoFixture(t)
+
+	// Act
+	plan, err := BuildPlan(fixture, PlanOptions{
+		SeedValue:     1,
+		Now:           planNow,
+		ExistingUsers: map[string]bool{"john.doe@example.com": true},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"john.doe@example.com"}, plan.Skipped)
+	assert.Len(t, plan.Users, 2)
+	assert.Len(t, plan.Accounts, 2)
+	for _, e := range plan.Entries {
+		assert.Less(t, e.Account, 2)
+	}
+}
+
+func TestBuildPlan_GeneratesFreeAccountNumbers(t *testing.T) {
+	// Arrange
+	fixture, err := ParseFixture([]byte(`
+password: x
+users:
+  - email: a@example.com
+    first_name: A
+    last_name: A
+    accounts: [{type: CHECKING}, {type: SAVINGS}]`))
+	require.NoError(t, err)
+	first, err := BuildPlan(fixture, PlanOptions{SeedValue: 1, Now: planNow})
+	require.NoError(t, err)
+	taken := map[string]bool{first.Accounts[0].Number: true}
+
+	// Act
+	plan, err := BuildPlan(fixture, PlanOptions{SeedValue: 1, Now: planNow, TakenNumbers: taken})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, plan.Accounts[0].Number, 10)
+	assert.NotEqual(t, first.Accounts[0].Number, plan.Accounts[0].Number)
+	assert.Equal(t, first.Accounts[1].Number, plan.Accounts[1].Number)
+}
+
+func TestBuildPlan_FixedNumberInUse(t *testing.T) {
+	// Arrange
+	fixture := demoFixture(t)
+
+	// Act
+	_, err := BuildPlan(fixture, PlanOptions{Now: planNow, TakenNumbers: map[string]bool{"1000000003": true}})
+
+	// Assert
+	assert.EqualError(t, err, "account number 1000000003 is already in use")
+}
@@ -2,260 +2,274 @@ package seed
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// SeedDatabase seeds the 'drank' database with initial data
-func SeedDatabase(db *gorm.DB) error {
-	// Clear existing data
-	if err := clearData(db); err != nil {
-		return err
-	}
+// insertBatchSize is how many rows go into one INSERT, well under the
+// 65535 parameters Postgres allows in a statement
+const insertBatchSize = 1000
 
-	// Seed users
-	users, err := seedUsers(db)
-	if err != nil {
-		return err
-	}
+// Options changes how Seed loads a fixture
+type Options struct {
+	SeedValue int64 // Seeds the random activity and account numbers
+	Additive  bool  // Keep the existing data and skip users that already exist
+}
 
-	// Seed accounts
-	accounts, err := seedAccounts(db, users)
-	if err != nil {
-		return err
-	}
+// Summary counts what Seed loaded
+type Summary struct {
+	Users        int
+	Accounts     int
+	Transactions int
+	Skipped      []string // Emails of the users that already existed
+}
 
-	// Seed transactions
-	if err := seedTransactions(db, accounts); err != nil {
+// SeedDatabase seeds the 'drank' database with the demo profile
+func SeedDatabase(db *gorm.DB) error {
+	fixture, err := LoadFixture(ProfileDemo)
+	if err != nil {
 		return err
 	}
-
-	return nil
+	_, err = Seed(db, fixture, Options{SeedValue: 1})
+	return err
 }
 
-func clearData(db *gorm.DB) error {
-	// Drop tables in reverse order to avoid foreign key constraints
-	if err := db.Exec("DELETE FROM transactions").Error; err != nil {
-		return err
-	}
-	if err := db.Exec("DELETE FROM accounts").Error; err != nil {
-		return err
-	}
-	if err := db.Exec("DELETE FROM users").Error; err != nil {
+// Seed loads a fixture in one database transaction. Unless opts.Additive is
+// set it first deletes all data, so the result is the same on every run with
+// the same seed value on the same day.
+func Seed(db *gorm.DB, fixture *Fixture, opts Options) (*Summary, error) {
+	var summary *Summary
+	err := db.Transaction(func(tx *gorm.DB) error {
+		planOpts := PlanOptions{SeedValue: opts.SeedValue}
+		if opts.Additive {
+			emails := make([]string, len(fixture.Users))
+			for i, user := range fixture.Users {
+				emails[i] = user.Email
+			}
+			existing, err := existingUsers(tx, emails)
+			if err != nil {
+				return err
+			}
+			taken, err := takenNumbers(tx)
+			if err != nil {
+				return err
+			}
+			planOpts.ExistingUsers = existing
+			planOpts.TakenNumbers = taken
+		} else if err := clearData(tx); err != nil {
+			return err
+		}
+
+		plan, err := BuildPlan(fixture, planOpts)
+		if err != nil {
+			return err
+		}
+		summary, err = write(tx, plan)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return summary, nil
 }
 
-func seedUsers(db *gorm.DB) ([]models.User, error) {
-	users := []models.User{
-		{
-			Email:     "john.doe@example.com",
-			Password:  "password123",
-			FirstName: "John",
-			LastName:  "Doe",
-		},
-		{
-			Email:     "jane.smith@example.com",
-			Password:  "password123",
-			FirstName: "Jane",
-			LastName:  "Smith",
-		},
+// existingUsers finds which of the emails belong to users in the database,
+// returning them normalized as their index is
+func existingUsers(db *gorm.DB, emails []string) (map[string]bool, error) {
+	byIndex := map[string]string{}
+	indexes := make([]string, 0, len(emails))
+	for _, email := range emails {
+		index, err := pii.EmailIndex(email)
+		if err != nil {
+			return nil, err
+		}
+		byIndex[index] = pii.NormalizeEmail(email)
+		indexes = append(indexes, index)
 	}
 
-	for i := range users {
-		if err := db.Create(&users[i]).Error; err != nil {
+	var found []string
+	if len(indexes) > 0 {
+		if err := db.Model(&models.User{}).Where("email_index IN ?", indexes).Pluck("email_index", &found).Error; err != nil {
 			return nil, err
 		}
 	}
-
-	return users, nil
+	existing := map[string]bool{}
+	for _, index := range found {
+		existing[byIndex[index]] = true
+	}
+	return existing, nil
 }
 
-func seedAccounts(db *gorm.DB, users []models.User) ([]models.Account, error) {
-	accounts := []models.Account{}
+// takenNumbers lists the account numbers in use
+func takenNumbers(db *gorm.DB) (map[string]bool, error) {
+	var numbers []string
+	if err := db.Model(&models.Account{}).Pluck("account_number", &numbers).Error; err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(numbers))
+	for _, number := range numbers {
+		taken[number] = true
+	}
+	return taken, nil
+}
 
-	accountTypes := []models.AccountType{models.Checking, models.Savings}
-	
-	// Create checking and savings accounts for each user
-	for _, user := range users {
-		for _, accountType := range accountTypes {
-			account := models.Account{
-				UserID:        user.ID,
-				AccountNumber: generateAccountNumber(),
-				AccountType:   accountType,
-				Balance:       5000.00, // Initial balance
-			}
-			
-			if err := db.Create(&account).Error; err != nil {
+// write creates the plan's users, identities, accounts and transactions with
+// bulk inserts. Users sharing a password share its hash, as bcrypt is slow
+// enough to dominate a large plan otherwise.
+func write(db *gorm.DB, plan *Plan) (*Summary, error) {
+	now := time.Now()
+	hashes := map[string]string{}
+	users := make([]models.User, len(plan.Users))
+	for i, u := range plan.Users {
+		if _, ok := hashes[u.Password]; !ok {
+			if err := users[i].SetPassword(u.Password, bcrypt.DefaultCost); err != nil {
 				return nil, err
 			}
-			
-			accounts = append(accounts, account)
+			hashes[u.Password] = users[i].Password
+		}
+		role := models.RoleCustomer
+		if u.Role != "" {
+			role = u.Role
+		}
+		users[i] = models.User{Email: u.Email, Password: hashes[u.Password], FirstName: u.FirstName, LastName: u.LastName, Role: role}
+		if !u.Unverified {
+			users[i].EmailVerifiedAt = &now
+		}
+	}
+	if len(users) > 0 {
+		if err := db.CreateInBatches(users, insertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("create users: %w", err)
 		}
 	}
 
-	return accounts, nil
-}
+	var profiles []models.KYCProfile
+	for i, u := range plan.Users {
+		if u.KYCLevel > 0 {
+			profiles = append(profiles, kycProfile(users[i].ID, u.KYCLevel, now))
+		}
+	}
+	if len(profiles) > 0 {
+		if err := db.CreateInBatches(profiles, insertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("create identities: %w", err)
+		}
+	}
 
-func seedTransactions(db *gorm.DB, accounts []models.Account) error {
-	// Transaction types
-	transactionTypes := []models.TransactionType{models.Deposit, models.Withdrawal}
-	
-	// Description templates
-	depositDescriptions := []string{
-		"Salary deposit",
-		"Refund",
-		"Interest earned",
-		"Client payment",
-		"Tax return",
-	}
-	
-	withdrawalDescriptions := []string{
-		"ATM withdrawal",
-		"Online purchase",
-		"Bill payment",
-		"Subscription payment",
-		"Rent payment",
-	}
-	
-	// Seed random transactions for each account
-	for _, account := range accounts {
-		// Initial deposit to set up the account
-		initialDeposit := models.Transaction{
-			AccountID:       account.ID,
-			Amount:          account.Balance,
-			Balance:         account.Balance,
-			Type:            models.Deposit,
-			Description:     "Initial deposit",
-			TransactionDate: time.Now().AddDate(0, 0, -30), // 30 days ago
+	// Accounts are created with their final balance, since every entry is known
+	accounts := make([]models.Account, len(plan.Accounts))
+	for i, a := range plan.Accounts {
+		accounts[i] = models.Account{
+			UserID:        users[a.User].ID,
+			AccountNumber: a.Number,
+			AccountType:   models.AccountType(a.Type),
+			Balance:       a.Balance,
 		}
-		
-		if err := db.Create(&initialDeposit).Error; err != nil {
-			return err
+	}
+	if len(accounts) > 0 {
+		if err := db.CreateInBatches(accounts, insertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("create accounts: %w", err)
 		}
-		
-		// Add 10-15 random transactions for each account
-		numTransactions := rand.Intn(6) + 10 // 10-15 transactions
-		
-		balance := account.Balance
-		
-		for i := 0; i < numTransactions; i++ {
-			// Randomize transaction type
-			txType := transactionTypes[rand.Intn(len(transactionTypes))]
-			
-			// Randomize amount between $10 and $1000
-			amount := 10.0 + rand.Float64()*990.0
-			amount = float64(int(amount*100)) / 100 // Round to 2 decimal places
-			
-			// Handle balance changes based on transaction type
-			var description string
-			if txType == models.Deposit {
-				balance += amount
-				description = depositDescriptions[rand.Intn(len(depositDescriptions))]
-			} else {
-				// For withdrawals, ensure we don't go below zero
-				if balance < amount {
-					amount = balance * 0.5 // Take only half of what's left
-				}
-				balance -= amount
-				description = withdrawalDescriptions[rand.Intn(len(withdrawalDescriptions))]
-			}
-			
-			// Create transaction
-			transaction := models.Transaction{
-				AccountID:       account.ID,
-				Amount:          amount,
-				Balance:         balance,
-				Type:            txType,
-				Description:     description,
-				TransactionDate: time.Now().AddDate(0, 0, -rand.Intn(30)), // Random date within the last 30 days
-			}
-			
-			if err := db.Create(&transaction).Error; err != nil {
-				return err
-			}
+	}
+
+	transactions := make([]models.Transaction, len(plan.Entries))
+	for i, e := range plan.Entries {
+		transactions[i] = models.Transaction{
+			AccountID:       accounts[e.Account].ID,
+			Amount:          e.Amount,
+			Balance:         e.Balance,
+			Type:            models.TransactionType(e.Kind),
+			Description:     e.Description,
+			TransactionDate: e.Date,
 		}
-		
-		// Update the account balance to match final transaction
-		if err := db.Model(&account).Update("balance", balance).Error; err != nil {
-			return err
+		if e.Kind == EntryTransfer {
+			transactions[i].SourceAccountID = &accounts[e.Source].ID
+			transactions[i].TargetAccountID = &accounts[e.Target].ID
 		}
 	}
-	
-	// Add some transfers between accounts
-	if len(accounts) >= 2 {
-		// For each user, create a transfer between their checking and savings accounts
-		for i := 0; i < len(accounts); i += 2 {
-			if i+1 < len(accounts) {
-				// Get source and target accounts
-				fromAccount := accounts[i]
-				toAccount := accounts[i+1]
-				
-				// Transfer amount
-				amount := 500.0
-				
-				// Update balances
-				fromBalance := fromAccount.Balance - amount
-				toBalance := toAccount.Balance + amount
-				
-				// Create withdrawal transaction for source account
-				withdrawalTx := models.Transaction{
-					AccountID:       fromAccount.ID,
-					SourceAccountID: &fromAccount.ID,
-					TargetAccountID: &toAccount.ID,
-					Amount:          amount,
-					Balance:         fromBalance,
-					Type:            models.Transfer,
-					Description:     fmt.Sprintf("Transfer to account %s", toAccount.AccountNumber),
-					TransactionDate: time.Now().AddDate(0, 0, -5), // 5 days ago
-				}
-				
-				// Create deposit transaction for target account
-				depositTx := models.Transaction{
-					AccountID:       toAccount.ID,
-					SourceAccountID: &fromAccount.ID,
-					TargetAccountID: &toAccount.ID,
-					Amount:          amount,
-					Balance:         toBalance,
-					Type:            models.Transfer,
-					Description:     fmt.Sprintf("Transfer from account %s", fromAccount.AccountNumber),
-					TransactionDate: time.Now().AddDate(0, 0, -5), // 5 days ago
-				}
-				
-				// Save transactions
-				if err := db.Create(&withdrawalTx).Error; err != nil {
-					return err
-				}
-				
-				if err := db.Create(&depositTx).Error; err != nil {
-					return err
-				}
-				
-				// Update account balances
-				if err := db.Model(&fromAccount).Update("balance", fromBalance).Error; err != nil {
-					return err
-				}
-				
-				if err := db.Model(&toAccount).Update("balance", toBalance).Error; err != nil {
-					return err
-				}
-			}
+	if len(transactions) > 0 {
+		if err := db.CreateInBatches(transactions, insertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("create transactions: %w", err)
 		}
 	}
-	
-	return nil
+
+	return &Summary{
+		Users:        len(users),
+		Accounts:     len(accounts),
+		Transactions: len(transactions),
+		Skipped:      plan.Skipped,
+	}, nil
 }
 
-func generateAccountNumber() string {
-	// Generate a random 10-digit account number
-	rand.Seed(time.Now().UnixNano())
-	accountNumber := ""
-	for i := 0; i < 10; i++ {
-		accountNumber += fmt.Sprintf("%d", rand.Intn(10))
-	}
-	return accountNumber
+// kycProfile is a verified identity at the given level
+func kycProfile(userID uint, level int, verifiedAt time.Time) models.KYCProfile {
+	return models.KYCProfile{
+		UserID:       userID,
+		DateOfBirth:  "1985-06-15",
+		AddressLine1: "100 Market Street",
+		City:         "San Francisco",
+		PostalCode:   "94105",
+		Country:      "US",
+		IDType:       models.IDTypePassport,
+		IDNumber:     fmt.Sprintf("P%08d", userID),
+		Status:       models.KYCVerified,
+		Level:        level,
+		SubmittedAt:  &verifiedAt,
+		ReviewedAt:   &verifiedAt,
+	}
+}
+
+func clearData(db *gorm.DB) error {
+	// Drop tables in reverse order to avoid foreign key constraints
+	if err := db.Exec("DELETE FROM kyc_documents").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM kyc_profiles").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM screening_hits").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM transfer_reviews").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM audit_entries").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM sessions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM service_client_usages").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM service_clients").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM login_attempts").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM recovery_codes").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM email_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM revoked_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM refresh_tokens").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM transactions").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM accounts").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM users").Error; err != nil {
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,303 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// SimulateOptions describes the customers Simulate makes up
+type SimulateOptions struct {
+	Customers    int
+	From, To     time.Time // First and last day of the history, in UTC
+	SeedValue    int64     // Seeds everything about the customers
+	Password     string    // Password of every customer
+	EmailPrefix  string    // Customer n gets the email <prefix><n>@example.com
+	BatchSize    int       // Customers in each plan handed to emit
+	TakenNumbers map[string]bool
+}
+
+// spendingCategory is a kind of card spending and how often a customer with
+// average spending makes it
+type spendingCategory struct {
+	name      string
+	perWeek   float64
+	min, max  float64
+	weekend   float64 // How much more often it happens on a weekend day
+	merchants []string
+}
+
+var spendingCategories = []spendingCategory{
+	{"Groceries", 2.5, 15, 180, 1.2, []string{"FreshMart", "Green Grocer", "SuperSave", "Corner Market"}},
+	{"Restaurants", 1.5, 12, 95, 1.8, []string{"Luigi's Trattoria", "Burger Barn", "Sushi Go", "The Local Diner"}},
+	{"Coffee", 3, 3, 9, 0.7, []string{"Bean There", "Daily Grind", "Cafe Aroma"}},
+	{"Transport", 1.2, 20, 90, 0.8, []string{"QuickFuel", "City Transit", "RideShare"}},
+	{"Online shopping", 1, 10, 250, 1.3, []string{"ShopNow", "MegaMart Online", "BookNook"}},
+	{"Pharmacy", 0.3, 5, 60, 1, []string{"HealthPlus Pharmacy", "Corner Chemist"}},
+	{"Entertainment", 0.5, 10, 80, 2, []string{"Cineplex", "GameZone", "Concert Hall Tickets"}},
+	{"Travel", 0.05, 150, 1200, 1, []string{"SkyHigh Airlines", "StayWell Hotels"}},
+}
+
+// bill is a recurring payment; a variable bill costs something else every month
+type bill struct {
+	name     string
+	min, max float64
+	variable bool
+}
+
+var bills = []bill{
+	{"Electricity", 40, 160, true},
+	{"Water", 20, 60, true},
+	{"Internet", 45, 90, false},
+	{"Mobile phone", 25, 80, false},
+	{"Video streaming", 9.99, 19.99, false},
+	{"Music streaming", 4.99, 14.99, false},
+	{"Gym membership", 20, 60, false},
+	{"Car insurance", 60, 180, false},
+}
+
+var (
+	employers  = []string{"Acme Corp", "Globex", "Initech", "Umbrella Health", "Stark Industries", "City Council", "Northwind Traders"}
+	landlords  = []string{"Parkside Properties", "Oak Lettings", "Harbor Homes", "Summit Realty"}
+	firstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Riley", "Jamie", "Avery", "Quinn", "Priya", "Wei", "Amara", "Mateo", "Sofia", "Noah"}
+	lastNames  = []string{"Smith", "Garcia", "Chen", "Okafor", "Novak", "Patel", "Kim", "Silva", "Müller", "Dubois", "Rossi", "Nguyen", "Khan", "Brown"}
+)
+
+// customer is what a simulated customer does with their money
+type customer struct {
+	checking, savings int // Indexes into Plan.Accounts; savings is -1 without one
+	pay               float64
+	payDay            int       // Day of the month paid, or 0 when paid every other week
+	biweeklyFrom      time.Time // First payday of a customer paid every other week
+	employer          string
+	rent              float64
+	rentTo            string
+	bills             []bill
+	billDays          []int
+	billAmounts       []float64
+	spending          float64 // Multiplies how often the customer spends
+	saveRate          float64 // Share of every pay moved to savings
+}
+
+// Simulate makes up customers with a checking account, and usually a savings
+// account, and their everyday banking between opts.From and opts.To: payroll,
+// rent or a mortgage, recurring bills, card spending by merchant category and
+// transfers between their own accounts. Card payments that the balance does
+// not cover are declined; rent and bills are paid from savings when the
+// checking account runs short, and missed when savings do not cover them
+// either. Customers are handed to emit opts.BatchSize at a time, so any number
+// of them fits in memory. The same options give the same customers.
+func Simulate(opts SimulateOptions, emit func(*Plan) error) error {
+	switch {
+	case opts.Customers < 1:
+		return errors.New("customers must be positive")
+	case opts.BatchSize < 1:
+		return errors.New("batch size must be positive")
+	case opts.EmailPrefix == "":
+		return errors.New("email prefix is required")
+	case opts.Password == "":
+		return errors.New("password is required")
+	}
+	from := opts.From.UTC().Truncate(24 * time.Hour)
+	to := opts.To.UTC().Truncate(24 * time.Hour)
+	if to.Before(from) {
+		return errors.New("history must end on or after the day it starts")
+	}
+
+	taken := map[string]bool{}
+	for number := range opts.TakenNumbers {
+		taken[number] = true
+	}
+	for start := 1; start <= opts.Customers; start += opts.BatchSize {
+		plan := &Plan{}
+		for n := start; n < start+opts.BatchSize && n <= opts.Customers; n++ {
+			email := fmt.Sprintf("%s%d@example.com", opts.EmailPrefix, n)
+			rng := rand.New(rand.NewSource(opts.SeedValue ^ hashKey(email)))
+			plan.simulate(rng, email, opts.Password, from, to, taken)
+		}
+		if err := emit(plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulate adds one customer, their accounts and their history to the plan
+func (p *Plan) simulate(rng *rand.Rand, email, password string, from, to time.Time, taken map[string]bool) {
+	p.Users = append(p.Users, FixtureUser{
+		Email:     email,
+		FirstName: pick(rng, firstNames),
+		LastName:  pick(rng, lastNames),
+		Password:  password,
+		KYCLevel:  2,
+	})
+	user := len(p.Users) - 1
+
+	// Monthly pay is spread like incomes are, with a long tail of high earners
+	c := customer{
+		savings:  -1,
+		pay:      roundCents(math.Min(2000*math.Exp(rng.NormFloat64()*0.45)+1200, 15000)),
+		employer: pick(rng, employers),
+		spending: 0.5 + rng.Float64(),
+		saveRate: 0.05 + rng.Float64()*0.15,
+	}
+	if rng.Intn(3) == 0 {
+		c.pay = roundCents(c.pay * 12 / 26)
+		c.biweeklyFrom = from.AddDate(0, 0, (12-int(from.Weekday()))%7+7*rng.Intn(2)) // A Friday in the first two weeks
+	} else {
+		c.payDay = []int{1, 15, 25, 28}[rng.Intn(4)]
+	}
+	monthly := c.pay
+	if c.payDay == 0 {
+		monthly = c.pay * 26 / 12
+	}
+	c.rent = roundCents(monthly * (0.25 + rng.Float64()*0.15))
+	c.rentTo = "Rent - " + pick(rng, landlords)
+	if rng.Intn(4) == 0 {
+		c.rentTo = "Mortgage payment"
+	}
+	for _, i := range rng.Perm(len(bills))[:3+rng.Intn(4)] {
+		c.bills = append(c.bills, bills[i])
+		c.billDays = append(c.billDays, 2+rng.Intn(26))
+		c.billAmounts = append(c.billAmounts, roundCents(bills[i].min+rng.Float64()*(bills[i].max-bills[i].min)))
+	}
+
+	open := func(accountType string, balance float64) int {
+		number := ""
+		for number == "" || taken[number] {
+			number = fmt.Sprintf("%010d", rng.Int63n(1e10))
+		}
+		taken[number] = true
+		p.Accounts = append(p.Accounts, PlannedAccount{User: user, Type: accountType, Number: number})
+		index := len(p.Accounts) - 1
+		if balance >= 0.01 {
+			p.entry(index, EntryDeposit, roundCents(balance), false, -1, -1, "Initial deposit", from)
+		}
+		return index
+	}
+	c.checking = open("CHECKING", monthly*(0.3+rng.Float64()))
+	if rng.Intn(10) < 7 {
+		c.savings = open("SAVINGS", monthly*rng.Float64()*6)
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		p.simulateDay(rng, &c, day)
+	}
+}
+
+// dayEvent is something a customer does at a time of day
+type dayEvent struct {
+	at time.Duration
+	do func(time.Time)
+}
+
+// simulateDay adds what the customer does on one day, in the order they do it
+func (p *Plan) simulateDay(rng *rand.Rand, c *customer, day time.Time) {
+	var events []dayEvent
+	add := func(at time.Duration, do func(time.Time)) {
+		events = append(events, dayEvent{at: at, do: do})
+	}
+	minutes := func(fromHour, toHour int) time.Duration {
+		return time.Duration(fromHour*60+rng.Intn((toHour-fromHour)*60)) * time.Minute
+	}
+
+	paid := day.Day() == c.payDay
+	if c.payDay == 0 {
+		paid = !day.Before(c.biweeklyFrom) && int(day.Sub(c.biweeklyFrom).Hours()/24)%14 == 0
+	}
+	if paid {
+		at := minutes(5, 7)
+		add(at, func(t time.Time) {
+			p.entry(c.checking, EntryDeposit, c.pay, false, -1, -1, "Payroll - "+c.employer, t)
+		})
+		if c.savings >= 0 {
+			add(at+time.Minute, func(t time.Time) {
+				p.transfer(c.checking, c.savings, roundCents(c.pay*c.saveRate), t)
+			})
+		}
+	}
+
+	if day.Day() == 1 {
+		add(minutes(8, 9), func(t time.Time) { p.payBill(c, c.rent, c.rentTo, t) })
+	}
+	for i, b := range c.bills {
+		if day.Day() != c.billDays[i] {
+			continue
+		}
+		amount := c.billAmounts[i]
+		if b.variable {
+			amount = roundCents(b.min + rng.Float64()*(b.max-b.min))
+		}
+		description := b.name
+		add(minutes(8, 10), func(t time.Time) { p.payBill(c, amount, description, t) })
+	}
+
+	weekend := day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+	for _, category := range spendingCategories {
+		rate := category.perWeek / 7 * c.spending
+		if weekend {
+			rate *= category.weekend
+		}
+		for n := poisson(rng, rate); n > 0; n-- {
+			amount := roundCents(category.min + rng.Float64()*rng.Float64()*(category.max-category.min)) // Mostly small, sometimes large
+			description := "Card purchase - " + pick(rng, category.merchants)
+			add(minutes(7, 23), func(t time.Time) {
+				if p.Accounts[c.checking].Balance >= amount {
+					p.entry(c.checking, EntryWithdrawal, amount, true, -1, -1, description, t)
+				}
+			})
+		}
+	}
+
+	// Events are at least two seconds apart, as paying a bill can take two
+	// entries a second apart, so that no two entries of an account share a time
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at < events[j].at })
+	var next time.Duration
+	for _, e := range events {
+		at := e.at
+		if at < next {
+			at = next
+		}
+		e.do(day.Add(at))
+		next = at + 2*time.Second
+	}
+}
+
+// payBill pays from checking, first moving what is missing from savings. The
+// payment is a second after the transfer, so that ordering by time finds the
+// payment's balance last.
+func (p *Plan) payBill(c *customer, amount float64, description string, t time.Time) {
+	short := roundCents(amount - p.Accounts[c.checking].Balance)
+	if short > 0 {
+		if c.savings < 0 || p.Accounts[c.savings].Balance < short {
+			return // Missed
+		}
+		p.transfer(c.savings, c.checking, short, t)
+	}
+	p.entry(c.checking, EntryWithdrawal, amount, true, -1, -1, description, t.Add(time.Second))
+}
+
+// transfer moves money between two accounts of a customer, if the source has it
+func (p *Plan) transfer(from, to int, amount float64, t time.Time) {
+	if amount < 0.01 || p.Accounts[from].Balance < amount {
+		return
+	}
+	p.apply(event{from: from, to: to, amount: amount, date: t})
+}
+
+// poisson draws how many times something with the given mean happens
+func poisson(rng *rand.Rand, mean float64) int {
+	limit, n, product := math.Exp(-mean), 0, rng.Float64()
+	for product > limit {
+		n++
+		product *= rng.Float64()
+	}
+	return n
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
@@ -0,0 +1,166 @@
+package seed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func simulateOptions() SimulateOptions {
+	return SimulateOptions{
+		Customers:   25,
+		From:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		SeedValue:   1,
+		Password:    "password123",
+		EmailPrefix: "customer",
+		BatchSize:   10,
+	}
+}
+
+func simulate(t *testing.T, opts SimulateOptions) []*Plan {
+	var plans []*Plan
+	require.NoError(t, Simulate(opts, func(plan *Plan) error {
+		plans = append(plans, plan)
+		return nil
+	}))
+	return plans
+}
+
+func TestSimulate_Batches(t *testing.T) {
+	// Act
+	plans := simulate(t, simulateOptions())
+
+	// Assert
+	require.Len(t, plans, 3)
+	assert.Len(t, plans[0].Users, 10)
+	assert.Len(t, plans[2].Users, 5)
+	assert.Equal(t, "customer1@example.com", plans[0].Users[0].Email)
+	assert.Equal(t, "customer25@example.com", plans[2].Users[4].Email)
+	for _, plan := range plans {
+		for _, account := range plan.Accounts {
+			assert.Less(t, account.User, len(plan.Users))
+		}
+	}
+}
+
+func TestSimulate_IsDeterministic(t *testing.T) {
+	// Arrange
+	opts := simulateOptions()
+	other := simulateOptions()
+	other.SeedValue = 2
+
+	// Act
+	first := simulate(t, opts)
+	second := simulate(t, opts)
+	third := simulate(t, other)
+
+	// Assert
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first[0].Entries, third[0].Entries)
+}
+
+func TestSimulate_RunningBalances(t *testing.T) {
+	// Arrange
+	opts := simulateOptions()
+
+	// Act
+	plans := simulate(t, opts)
+
+	// Assert
+	numbers := map[string]bool{}
+	for _, plan := range plans {
+		balances := make([]float64, len(plan.Accounts))
+		last := make([]time.Time, len(plan.Accounts))
+		for _, e := range plan.Entries {
+			if e.Outgoing {
+				balances[e.Account] -= e.Amount
+			} else {
+				balances[e.Account] += e.Amount
+			}
+			assert.InDelta(t, balances[e.Account], e.Balance, 0.001)
+			assert.GreaterOrEqual(t, e.Balance, 0.0)
+			assert.True(t, e.Date.After(last[e.Account]), "entries of an account are in date order, without ties")
+			assert.False(t, e.Date.Before(opts.From) || e.Date.After(opts.To.Add(24*time.Hour)))
+			last[e.Account] = e.Date
+			if e.Kind == EntryTransfer {
+				assert.Equal(t, plan.Accounts[e.Source].User, plan.Accounts[e.Target].User, "transfers are between own accounts")
+			}
+		}
+		for i, account := range plan.Accounts {
+			assert.InDelta(t, balances[i], account.Balance, 0.001)
+			assert.False(t, numbers[account.Number], "account numbers are unique")
+			numbers[account.Number] = true
+		}
+	}
+}
+
+func TestSimulate_EverydayBanking(t *testing.T) {
+	// Act
+	plans := simulate(t, simulateOptions())
+
+	// Assert
+	kinds := map[string]int{}
+	for _, plan := range plans {
+		for _, e := range plan.Entries {
+			switch {
+			case strings.HasPrefix(e.Description, "Payroll - "):
+				kinds["payroll"]++
+			case strings.HasPrefix(e.Description, "Rent - "), e.Description == "Mortgage payment":
+				kinds["housing"]++
+			case strings.HasPrefix(e.Description, "Card purchase - "):
+				kinds["card"]++
+			case e.Kind == EntryTransfer:
+				kinds["transfer"]++
+			}
+		}
+	}
+	assert.GreaterOrEqual(t, kinds["payroll"], 25*3, "every customer is paid at least monthly")
+	assert.Greater(t, kinds["housing"], 25*2)
+	assert.Greater(t, kinds["card"], kinds["payroll"])
+	assert.Greater(t, kinds["transfer"], 0)
+}
+
+func TestSimulate_SkipsTakenNumbers(t *testing.T) {
+	// Arrange
+	opts := simulateOptions()
+	opts.Customers = 1
+	first := simulate(t, opts)
+	opts.TakenNumbers = map[string]bool{first[0].Accounts[0].Number: true}
+
+	// Act
+	plans := simulate(t, opts)
+
+	// Assert
+	assert.NotEqual(t, first[0].Accounts[0].Number, plans[0].Accounts[0].Number)
+}
+
+func TestSimulate_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*SimulateOptions)
+		want   string
+	}{
+		{"no customers", func(o *SimulateOptions) { o.Customers = 0 }, "customers must be positive"},
+		{"no batch size", func(o *SimulateOptions) { o.BatchSize = 0 }, "batch size must be positive"},
+		{"no prefix", func(o *SimulateOptions) { o.EmailPrefix = "" }, "email prefix is required"},
+		{"ends before it starts", func(o *SimulateOptions) { o.To = o.From.AddDate(0, 0, -1) }, "history must end on or after the day it starts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			opts := simulateOptions()
+			tt.change(&opts)
+
+			// Act
+			err := Simulate(opts, func(*Plan) error { return nil })
+
+			// Assert
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/handlers"
+	"github.com/jbadhree/drank/bank-app-backend/internal/health"
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
+)
+
+// serve runs the API server until it is told to stop by SIGINT or SIGTERM
+func serve(a *app) error {
+	s, err := a.services()
+	if err != nil {
+		return err
+	}
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(s.user, s.token, s.identity, s.twoFactor, s.loginAttempt, s.password)
+	twoFactorHandler := handlers.NewTwoFactorHandler(s.twoFactor)
+	loginAttemptHandler := handlers.NewLoginAttemptHandler(s.loginAttempt)
+	serviceClientHandler := handlers.NewServiceClientHandler(s.serviceClient)
+	userHandler := handlers.NewUserHandler(s.user)
+	sessionHandler := handlers.NewSessionHandler(s.token)
+	passwordHandler := handlers.NewPasswordHandler(s.password, s.token, s.loginAttempt)
+	accountHandler := handlers.NewAccountHandler(s.account)
+	transactionHandler := handlers.NewTransactionHandler(s.transaction)
+	auditHandler := handlers.NewAuditHandler(s.audit)
+	screeningHandler := handlers.NewScreeningHandler(s.sanctions)
+	kycHandler := handlers.NewKYCHandler(s.kyc, a.cfg.KYCMaxDocumentSize)
+	jwksHandler := handlers.NewJWKSHandler(s.keySet)
+
+	// Readiness depends on the database, its schema and the KYC document
+	// directory. The README explains why the other dependencies are left out.
+	checker := health.New(a.cfg.HealthCheckTimeout)
+	checker.Add("database", health.Database(a.db))
+	checker.Add("migrations", a.migrator.Check)
+	checker.Add("kyc_documents", s.documents.Check)
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	// Initialize auth middleware
+	authMiddleware := middleware.NewAuthMiddleware(s.token, s.serviceClient)
+
+	// Rate limits are kept by this instance, or in the database when several
+	// instances share them
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if a.cfg.RateLimitStore == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(a.db)
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, a.cfg.RateLimitFailOpen)
+	authLimit := rateLimiter.Limit("auth", a.cfg.RateLimitAuth)
+	apiLimit := rateLimiter.Limit("api", a.cfg.RateLimitAPI)
+
+	// Initialize Gin router. Requests are logged by RequestLogger, so
+	// gin's own text logger is left out.
+	if !a.cfg.IsDevelopment() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+
+	// Client IPs, which login lockouts and rate limits are keyed on, are only
+	// taken from X-Forwarded-For when a trusted proxy sent it
+	if err := router.SetTrustedProxies(a.cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+
+	// Tag every request with an ID that its log lines and audit entries
+	// record, and log it once served
+	router.Use(middleware.RequestID(), middleware.RequestLogger(a.logger), middleware.Tracing(), middleware.Metrics(), middleware.Recovery())
+
+	// Configure CORS - allow requests from both localhost and the actual server hostname
+	allowedOrigins := []string{"http://localhost:3000"}
+	if a.cfg.FrontendURL != "" {
+		allowedOrigins = append(allowedOrigins, a.cfg.FrontendURL)
+	}
+
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     allowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.RequestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    append([]string{"Content-Length", middleware.RequestIDHeader}, middleware.RateLimitHeaders...),
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	// Swagger documentation
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Liveness and readiness probes
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+
+	// Prometheus metrics. They show traffic, error rates and login failures, so
+	// scrapers present METRICS_TOKEN as a bearer token. A token rather than an
+	// address allow-list, as scrapers in another network or behind NAT have no
+	// stable address; production refuses to start without one.
+	router.GET("/metrics", middleware.MetricsAuth(a.cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+
+	// API routes
+	v1 := router.Group("/api/v1")
+	{
+		// Auth routes - no auth required, limited per IP address
+		v1.POST("/auth/login", authLimit, authHandler.Login)
+		v1.POST("/auth/login/2fa", authLimit, authHandler.LoginTwoFactor)
+		v1.POST("/auth/refresh", authLimit, authHandler.Refresh)
+		v1.POST("/auth/register", authLimit, authHandler.Register)
+		v1.POST("/auth/verify-email", authLimit, authHandler.VerifyEmail)
+		v1.POST("/auth/resend-verification", authLimit, authHandler.ResendVerification)
+		v1.POST("/auth/forgot-password", authLimit, authHandler.ForgotPassword)
+		v1.POST("/auth/reset-password", authLimit, authHandler.ResetPassword)
+		v1.POST("/auth/token", authLimit, serviceClientHandler.Token)
+
+		// Logout and step-up routes - auth required
+		v1.POST("/auth/logout", authMiddleware.Authenticate(), apiLimit, authHandler.Logout)
+		v1.POST("/auth/logout-all", authMiddleware.Authenticate(), apiLimit, authHandler.LogoutAll)
+		v1.POST("/auth/reauthenticate", authMiddleware.Authenticate(), apiLimit, authHandler.Reauthenticate)
+
+		// Two-factor enrollment routes - auth required
+		v1.POST("/auth/2fa/enroll", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Enroll)
+		v1.POST("/auth/2fa/confirm", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Confirm)
+
+		// User routes - auth required, service clients need users:read
+		users := v1.Group("/users")
+		users.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireScope(models.ScopeUsersRead))
+		{
+			users.GET("", userHandler.GetAllUsers)
+			users.GET("/:id", userHandler.GetUserByID)
+			users.GET("/me", userHandler.GetCurrentUser)
+			users.POST("/me/password", passwordHandler.ChangePassword)
+			users.GET("/me/sessions", sessionHandler.GetSessions)
+			users.DELETE("/me/sessions/:id", sessionHandler.RevokeSession)
+			users.GET("/me/kyc", kycHandler.GetMyProfile)
+			users.PUT("/me/kyc", kycHandler.SaveMyProfile)
+			users.POST("/me/kyc/documents", kycHandler.UploadDocument)
+			users.POST("/me/kyc/submit", kycHandler.Submit)
+		}
+
+		// Account routes - auth required, service clients need accounts:read
+		accounts := v1.Group("/accounts")
+		accounts.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireScope(models.ScopeAccountsRead))
+		{
+			accounts.GET("", accountHandler.GetAllAccounts)
+			accounts.GET("/:id", accountHandler.GetAccountByID)
+			accounts.GET("/user/:userId", accountHandler.GetAccountsByUserID)
+		}
+
+		// Transaction routes - auth required, service clients need the scope per route
+		transactions := v1.Group("/transactions")
+		transactions.Use(authMiddleware.Authenticate(), rateLimiter.Limit("transactions", a.cfg.RateLimitTransactions))
+		{
+			transactions.GET("", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetAllTransactions)
+			transactions.GET("/:id", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetTransactionByID)
+			transactions.GET("/account/:accountId", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetTransactionsByAccountID)
+			transactions.POST("/transfer", authMiddleware.RequireScope(models.ScopeTransfersWrite), transactionHandler.Transfer)
+		}
+
+		// Admin routes - admin role required
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			admin.POST("/users/:id/2fa/reset", twoFactorHandler.Reset)
+			admin.POST("/users/:id/unlock", loginAttemptHandler.Unlock)
+			admin.GET("/users/:id/login-attempts", loginAttemptHandler.GetAttemptsByUserID)
+			admin.POST("/clients", serviceClientHandler.CreateClient)
+			admin.GET("/clients", serviceClientHandler.GetAllClients)
+			admin.GET("/clients/:id", serviceClientHandler.GetClientByID)
+			admin.DELETE("/clients/:id", serviceClientHandler.RevokeClient)
+			admin.GET("/clients/:id/usage", serviceClientHandler.GetUsage)
+			admin.GET("/audit", auditHandler.GetEntries)
+			admin.GET("/audit/verify", auditHandler.Verify)
+			admin.GET("/transfers/reviews", transactionHandler.GetTransferReviews)
+			admin.POST("/transfers/reviews/:id/approve", transactionHandler.ApproveTransfer)
+			admin.POST("/transfers/reviews/:id/reject", transactionHandler.RejectTransfer)
+			admin.GET("/screening/hits", screeningHandler.GetHits)
+			admin.POST("/screening/hits/:id/clear", screeningHandler.ClearHit)
+			admin.GET("/kyc", kycHandler.GetProfiles)
+			admin.GET("/kyc/documents/:id", kycHandler.GetDocument)
+			admin.GET("/kyc/:userId", kycHandler.GetProfile)
+			admin.POST("/kyc/:userId/approve", kycHandler.Approve)
+			admin.POST("/kyc/:userId/reject", kycHandler.Reject)
+		}
+	}
+
+	// Start server
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", a.cfg.Port),
+		Handler: router,
+	}
+
+	// Graceful shutdown
+	slog.Info("Server starting", "port", a.cfg.Port)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Server failed", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shut down the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Fail readiness first so load balancers stop routing new requests here
+	slog.Info("Draining server", "delay", a.cfg.ShutdownDrainDelay)
+	checker.Drain()
+	time.Sleep(a.cfg.ShutdownDrainDelay)
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	slog.Info("Server exited")
+	return nil
+}
@@ -14,10 +14,14 @@ for i in {1..30}; do
   sleep 1
 done
 
+# Bring the schema up to date; the server refuses to start against an old one
+echo "Applying database migrations..."
+./drank-backend migrate up
+
 # Seed the database first if SEED_DB is set to true
 if [ "$SEED_DB" = "true" ]; then
   echo "Seeding database..."
-  ./drank-backend --seed
+  ./drank-backend seed -profile "${SEED_PROFILE:-demo}"
   echo "Database seeded successfully!"
 fi
 
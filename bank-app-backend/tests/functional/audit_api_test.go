@@ -0,0 +1,149 @@
+package functional
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestAuditAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	// Create a customer with two accounts and an admin
+	user, err := CreateTestUser("audited@example.com", "password123", "Audit", "Ed")
+	assert.NoError(t, err)
+	fromAccount, err := CreateTestAccount(user.ID, "AUD1000001", models.Checking, 1000.0)
+	assert.NoError(t, err)
+	toAccount, err := CreateTestAccount(user.ID, "AUD1000002", models.Savings, 500.0)
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	token, err := LoginTestUser("audited@example.com", "password123")
+	assert.NoError(t, err)
+	adminToken, err := LoginTestUser("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	getEntries := func(query string) []models.AuditEntry {
+		w := MakeRequest("GET", "/api/v1/admin/audit"+query, nil, adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var entries []models.AuditEntry
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+		return entries
+	}
+
+	t.Run("Transfers are audited with the request ID", func(t *testing.T) {
+		// Arrange
+		transferReq := models.TransferRequest{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        100.0,
+			Description:   "Audited transfer",
+		}
+
+		// Act
+		w := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		requestID := w.Header().Get("X-Request-ID")
+		assert.NotEmpty(t, requestID)
+
+		entries := getEntries("?action=" + models.AuditTransferCompleted)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, models.AuditActorUser, entries[0].ActorType)
+			assert.Equal(t, fmt.Sprint(user.ID), entries[0].ActorID)
+			assert.Equal(t, fmt.Sprint(fromAccount.ID), entries[0].TargetID)
+			assert.Equal(t, requestID, entries[0].RequestID)
+			assert.Contains(t, entries[0].After, `"fromBalance":900`)
+		}
+	})
+
+	t.Run("A transfer whose credit fails leaves no debit and no audit entry", func(t *testing.T) {
+		// Arrange - fail the deposit row of the to account, after the debit
+		// has been written
+		const failCredit = "test:fail_credit"
+		assert.NoError(t, testDB.Callback().Create().Before("gorm:create").Register(failCredit, func(db *gorm.DB) {
+			if transaction, ok := db.Statement.Dest.(*models.Transaction); ok && transaction.AccountID == toAccount.ID {
+				db.AddError(errors.New("credit failed"))
+			}
+		}))
+		defer testDB.Callback().Create().Remove(failCredit)
+		transferReq := models.TransferRequest{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        50.0,
+			Description:   "Failed credit",
+		}
+
+		// Act
+		w := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token)
+
+		// Assert - the debit was rolled back with the credit, and only the
+		// earlier transfer is audited
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var account models.Account
+		assert.NoError(t, testDB.First(&account, fromAccount.ID).Error)
+		assert.Equal(t, 900.0, account.Balance)
+		var debits int64
+		assert.NoError(t, testDB.Model(&models.Transaction{}).Where("description LIKE ?", "%Failed credit").Count(&debits).Error)
+		assert.Zero(t, debits)
+		assert.Len(t, getEntries("?action="+models.AuditTransferCompleted), 1)
+	})
+
+	t.Run("Logins are audited and entries are chained", func(t *testing.T) {
+		// Act
+		entries := getEntries(fmt.Sprintf("?action=%s&actorId=%d", models.AuditLoginSucceeded, user.ID))
+
+		// Assert
+		assert.Len(t, entries, 1)
+
+		all := getEntries("")
+		assert.NotEmpty(t, all)
+		for i := 0; i+1 < len(all); i++ {
+			// Newest first, so each entry links to the next one in the list
+			assert.Equal(t, all[i+1].Hash, all[i].PrevHash)
+		}
+	})
+
+	t.Run("Verify accepts an untouched log", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/audit/verify", nil, adminToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result models.AuditVerification
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.True(t, result.Valid)
+		assert.Greater(t, result.Checked, 0)
+	})
+
+	t.Run("Verify detects a modified entry", func(t *testing.T) {
+		// Arrange - tamper with the transfer directly in the database
+		assert.NoError(t, testDB.Exec("UPDATE audit_entries SET after = ? WHERE action = ?", `{"fromBalance":0}`, models.AuditTransferCompleted).Error)
+
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/audit/verify", nil, adminToken)
+
+		// Assert
+		var result models.AuditVerification
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.False(t, result.Valid)
+		assert.NotZero(t, result.BrokenAt)
+	})
+
+	t.Run("Customers cannot read the audit log", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/audit", nil, token)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
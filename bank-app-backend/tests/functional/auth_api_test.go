@@ -41,6 +41,33 @@ func TestAuthAPI(t *testing.T) {
 		assert.Equal(t, "User", response.User.LastName)
 	})
 	
+	t.Run("Login should match the email regardless of case", func(t *testing.T) {
+		// Arrange
+		loginReq := models.LoginRequest{
+			Email:    "Auth@Example.COM",
+			Password: "password123",
+		}
+		
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/login", loginReq, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	
+	t.Run("JWKS endpoint is public", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/.well-known/jwks.json", nil, "")
+		
+		// Assert - the test router signs with a shared secret, so no keys are published
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Contains(t, response, "keys")
+	})
+	
 	t.Run("Login with invalid email should fail", func(t *testing.T) {
 		// Arrange
 		loginReq := models.LoginRequest{
@@ -153,4 +180,110 @@ func TestAuthAPI(t *testing.T) {
 		assert.Equal(t, "Auth", response.FirstName)
 		assert.Equal(t, "User", response.LastName)
 	})
+	
+	t.Run("Refresh rotates the refresh token and rejects reuse", func(t *testing.T) {
+		// Arrange - login to get a token pair
+		loginReq := models.LoginRequest{
+			Email:    "auth@example.com",
+			Password: "password123",
+		}
+		w := MakeRequest("POST", "/api/v1/auth/login", loginReq, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var login models.LoginResponse
+		err := json.Unmarshal(w.Body.Bytes(), &login)
+		assert.NoError(t, err)
+		
+		// Act - refresh once
+		w = MakeRequest("POST", "/api/v1/auth/refresh", models.RefreshRequest{RefreshToken: login.RefreshToken}, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var rotated models.TokenPair
+		err = json.Unmarshal(w.Body.Bytes(), &rotated)
+		assert.NoError(t, err)
+		assert.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+		
+		// Act - replay the original refresh token
+		w = MakeRequest("POST", "/api/v1/auth/refresh", models.RefreshRequest{RefreshToken: login.RefreshToken}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		
+		// Assert - the whole family is revoked, including the rotated token
+		w = MakeRequest("POST", "/api/v1/auth/refresh", models.RefreshRequest{RefreshToken: rotated.RefreshToken}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		
+		w = MakeRequest("GET", "/api/v1/users/me", nil, rotated.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	
+	t.Run("Logout revokes the access token", func(t *testing.T) {
+		// Arrange - login to get a valid token
+		token, err := LoginTestUser("auth@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/logout", nil, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		w = MakeRequest("GET", "/api/v1/users/me", nil, token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	
+	t.Run("Logout from all devices revokes every session", func(t *testing.T) {
+		// Arrange - login twice to create two sessions
+		firstToken, err := LoginTestUser("auth@example.com", "password123")
+		assert.NoError(t, err)
+		secondToken, err := LoginTestUser("auth@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/logout-all", nil, firstToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		w = MakeRequest("GET", "/api/v1/users/me", nil, secondToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	
+	t.Run("Registered user cannot login before verifying email", func(t *testing.T) {
+		// Arrange
+		registerReq := models.RegisterRequest{
+			Email:     "register@example.com",
+			Password:  "password123",
+			FirstName: "New",
+			LastName:  "User",
+		}
+		
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/register", registerReq, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		created := w.Body.String()
+		
+		// Registering the same email again looks the same to the caller
+		w = MakeRequest("POST", "/api/v1/auth/register", registerReq, "")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, created, w.Body.String())
+		
+		// Login is refused until the email is verified
+		loginReq := models.LoginRequest{
+			Email:    "register@example.com",
+			Password: "password123",
+		}
+		w = MakeRequest("POST", "/api/v1/auth/login", loginReq, "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	
+	t.Run("Forgot password does not reveal unknown emails", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/forgot-password", models.EmailRequest{Email: "nobody@example.com"}, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
 }
@@ -0,0 +1,123 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFraudScreeningAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	// Create a customer with two accounts and an admin
+	user, err := CreateTestUser("screened@example.com", "password123", "Screened", "Sam")
+	assert.NoError(t, err)
+	fromAccount, err := CreateTestAccount(user.ID, "FRD1000001", models.Checking, 1000.0)
+	assert.NoError(t, err)
+	toAccount, err := CreateTestAccount(user.ID, "FRD1000002", models.Savings, 0.0)
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	token, err := LoginTestUser("screened@example.com", "password123")
+	assert.NoError(t, err)
+	adminToken, err := LoginTestUser("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	transfer := func() *models.HeldTransferResponse {
+		transferReq := models.TransferRequest{FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 10.0}
+		w := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token)
+		if w.Code != http.StatusAccepted {
+			assert.Equal(t, http.StatusOK, w.Code)
+			return nil
+		}
+		var held models.HeldTransferResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &held))
+		return &held
+	}
+
+	balance := func(accountID uint) float64 {
+		var account models.Account
+		assert.NoError(t, testDB.First(&account, accountID).Error)
+		return account.Balance
+	}
+
+	var held *models.HeldTransferResponse
+
+	t.Run("A burst of transfers is held for review", func(t *testing.T) {
+		// Arrange - the default velocity rule allows ten transfers in ten minutes
+		for i := 0; i < 10; i++ {
+			assert.Nil(t, transfer())
+		}
+
+		// Act
+		held = transfer()
+
+		// Assert - no money moved
+		if assert.NotNil(t, held) {
+			assert.Equal(t, models.ReviewPending, held.Status)
+		}
+		assert.Equal(t, 900.0, balance(fromAccount.ID))
+	})
+
+	t.Run("Admins see the held transfer with its reasons", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/transfers/reviews", nil, adminToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		var reviews []models.TransferReview
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reviews))
+		if assert.Len(t, reviews, 1) {
+			assert.Equal(t, held.ReviewID, reviews[0].ID)
+			assert.NotEmpty(t, reviews[0].Reasons)
+		}
+	})
+
+	t.Run("Customers cannot work the review queue", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/transfers/reviews/%d/approve", held.ReviewID), nil, token)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Approving completes the transfer once", func(t *testing.T) {
+		// Arrange
+		url := fmt.Sprintf("/api/v1/admin/transfers/reviews/%d/approve", held.ReviewID)
+
+		// Act
+		w := MakeRequest("POST", url, models.ReviewDecisionRequest{Note: "customer confirmed"}, adminToken)
+		again := MakeRequest("POST", url, nil, adminToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusConflict, again.Code)
+		assert.Equal(t, 890.0, balance(fromAccount.ID))
+		assert.Equal(t, 110.0, balance(toAccount.ID))
+	})
+
+	t.Run("Rejecting leaves the balances alone", func(t *testing.T) {
+		// Arrange
+		rejected := transfer()
+		if !assert.NotNil(t, rejected) {
+			return
+		}
+
+		// Act
+		w := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/transfers/reviews/%d/reject", rejected.ReviewID), nil, adminToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 890.0, balance(fromAccount.ID))
+
+		var review models.TransferReview
+		assert.NoError(t, testDB.First(&review, rejected.ReviewID).Error)
+		assert.Equal(t, models.ReviewRejected, review.Status)
+	})
+}
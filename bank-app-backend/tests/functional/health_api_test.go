@@ -0,0 +1,36 @@
+package functional
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	t.Run("Liveness needs no authentication", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/healthz", nil, "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Readiness checks the database and schema", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/readyz", nil, "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		var report health.Report
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, health.StatusOK, report.Status)
+		assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+		assert.Equal(t, health.StatusOK, report.Checks["migrations"].Status)
+	})
+}
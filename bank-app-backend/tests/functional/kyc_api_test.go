@@ -0,0 +1,168 @@
+package functional
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadKYCDocument posts a document to the current user's KYC profile
+func uploadKYCDocument(token, kind, fileName string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("kind", kind)
+	part, _ := writer.CreateFormFile("file", fileName)
+	part.Write(data)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/me/kyc/documents", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestKYCAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	// Create a customer who has not verified their identity, a payee, and an admin
+	user, err := CreateTestUser("newcomer@example.com", "password123", "Nina", "Newcomer")
+	assert.NoError(t, err)
+	assert.NoError(t, testDB.Where("user_id = ?", user.ID).Delete(&models.KYCProfile{}).Error)
+	fromAccount, err := CreateTestAccount(user.ID, "KYC1000001", models.Checking, 5000.0)
+	assert.NoError(t, err)
+	payee, err := CreateTestUser("payee@example.com", "password123", "Paul", "Payee")
+	assert.NoError(t, err)
+	toAccount, err := CreateTestAccount(payee.ID, "KYC1000002", models.Checking, 0.0)
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	token, err := LoginTestUser("newcomer@example.com", "password123")
+	assert.NoError(t, err)
+	adminToken, err := LoginTestUser("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	transfer := func(amount float64) *httptest.ResponseRecorder {
+		return MakeRequest("POST", "/api/v1/transactions/transfer", models.TransferRequest{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        amount,
+			Description:   "KYC test",
+		}, token)
+	}
+
+	t.Run("An unverified user can view but not transfer", func(t *testing.T) {
+		// Act
+		accounts := MakeRequest("GET", fmt.Sprintf("/api/v1/accounts/user/%d", user.ID), nil, token)
+		response := transfer(100.0)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, accounts.Code)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		assert.Contains(t, response.Body.String(), "identity verification is required")
+
+		w := MakeRequest("GET", "/api/v1/users/me/kyc", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var profile models.KYCProfile
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+		assert.Equal(t, models.KYCNotStarted, profile.Status)
+	})
+
+	var documentID uint
+
+	t.Run("The user saves their details, uploads a document and submits", func(t *testing.T) {
+		// Act
+		details := models.KYCProfileRequest{
+			DateOfBirth:  "1990-04-12",
+			AddressLine1: "1 Main St",
+			City:         "Springfield",
+			PostalCode:   "12345",
+			Country:      "US",
+			IDType:       models.IDTypePassport,
+			IDNumber:     "X1234567",
+		}
+		saved := MakeRequest("PUT", "/api/v1/users/me/kyc", details, token)
+		early := MakeRequest("POST", "/api/v1/users/me/kyc/submit", nil, token)
+		rejected := uploadKYCDocument(token, models.KYCDocumentIDFront, "script.sh", []byte("#!/bin/sh\n"))
+		uploaded := uploadKYCDocument(token, models.KYCDocumentIDFront, "passport.pdf", []byte("%PDF-1.4\n%test document\n"))
+		submitted := MakeRequest("POST", "/api/v1/users/me/kyc/submit", nil, token)
+		locked := MakeRequest("PUT", "/api/v1/users/me/kyc", details, token)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, saved.Code)
+		assert.Equal(t, http.StatusBadRequest, early.Code, "an identity document is required")
+		assert.Equal(t, http.StatusBadRequest, rejected.Code)
+		assert.Equal(t, http.StatusCreated, uploaded.Code)
+		assert.Equal(t, http.StatusOK, submitted.Code)
+		assert.Equal(t, http.StatusConflict, locked.Code, "a pending profile cannot be changed")
+
+		var document models.KYCDocument
+		assert.NoError(t, json.Unmarshal(uploaded.Body.Bytes(), &document))
+		assert.Equal(t, "application/pdf", document.ContentType)
+		documentID = document.ID
+
+		// Personal details are encrypted at rest
+		var stored map[string]interface{}
+		testDB.Table("kyc_profiles").Where("user_id = ?", user.ID).Take(&stored)
+		assert.NotEqual(t, "X1234567", stored["id_number"])
+		assert.NotEqual(t, "1990-04-12", stored["date_of_birth"])
+	})
+
+	t.Run("An admin downloads the document and approves the profile", func(t *testing.T) {
+		// Act
+		queue := MakeRequest("GET", "/api/v1/admin/kyc", nil, adminToken)
+		download := MakeRequest("GET", fmt.Sprintf("/api/v1/admin/kyc/documents/%d", documentID), nil, adminToken)
+		fullLevel := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/kyc/%d/approve", user.ID), models.KYCApproveRequest{Level: models.KYCLevelFull}, adminToken)
+		approved := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/kyc/%d/approve", user.ID), models.KYCApproveRequest{Level: models.KYCLevelBasic}, adminToken)
+		again := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/kyc/%d/approve", user.ID), models.KYCApproveRequest{Level: models.KYCLevelBasic}, adminToken)
+		forbidden := MakeRequest("GET", "/api/v1/admin/kyc", nil, token)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, queue.Code)
+		var profiles []models.KYCProfile
+		assert.NoError(t, json.Unmarshal(queue.Body.Bytes(), &profiles))
+		if assert.Len(t, profiles, 1) {
+			assert.Equal(t, user.ID, profiles[0].UserID)
+			assert.Equal(t, "X1234567", profiles[0].IDNumber)
+		}
+		assert.Equal(t, http.StatusOK, download.Code)
+		assert.Equal(t, "%PDF-1.4\n%test document\n", download.Body.String())
+		assert.Equal(t, http.StatusBadRequest, fullLevel.Code, "the full level needs a proof of address")
+		assert.Equal(t, http.StatusOK, approved.Code)
+		assert.Equal(t, http.StatusConflict, again.Code)
+		assert.Equal(t, http.StatusForbidden, forbidden.Code)
+	})
+
+	t.Run("Transfers are limited by the verification level", func(t *testing.T) {
+		// Act - stay under the step-up threshold and reach the basic level's daily limit
+		first := transfer(900.0)
+		second := transfer(900.0)
+		overLimit := transfer(800.0)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.Code)
+		assert.Equal(t, http.StatusOK, second.Code)
+		assert.Equal(t, http.StatusForbidden, overLimit.Code)
+		assert.Contains(t, overLimit.Body.String(), "limit for your verification level")
+	})
+
+	t.Run("Submitting and reviewing are audited", func(t *testing.T) {
+		// Act
+		var count int64
+		testDB.Model(&models.AuditEntry{}).Where("action IN ?", []string{models.AuditKYCSubmitted, models.AuditKYCApproved}).Count(&count)
+
+		// Assert
+		assert.Equal(t, int64(2), count)
+	})
+}
@@ -0,0 +1,83 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginProtectionAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// Create a test user and an admin
+	user, err := CreateTestUser("lockout@example.com", "password123", "Lock", "Out")
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+	
+	t.Run("Repeated failures lock the account", func(t *testing.T) {
+		// Arrange
+		wrongReq := models.LoginRequest{
+			Email:    "lockout@example.com",
+			Password: "wrongpassword",
+		}
+		
+		// Act - fail as often as allowed
+		for i := 0; i < testConfig.LoginMaxFailures; i++ {
+			w := MakeRequest("POST", "/api/v1/auth/login", wrongReq, "")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+		
+		// Assert - even the right password is refused now
+		w := MakeRequest("POST", "/api/v1/auth/login", models.LoginRequest{Email: "lockout@example.com", Password: "password123"}, "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+	
+	t.Run("Admin can review attempts and unlock the account", func(t *testing.T) {
+		// Arrange
+		adminToken, err := LoginTestUser("admin@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act - review the attempts
+		w := MakeRequest("GET", fmt.Sprintf("/api/v1/admin/users/%d/login-attempts", user.ID), nil, adminToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var attempts []models.LoginAttempt
+		err = json.Unmarshal(w.Body.Bytes(), &attempts)
+		assert.NoError(t, err)
+		assert.Len(t, attempts, testConfig.LoginMaxFailures)
+		for _, attempt := range attempts {
+			assert.False(t, attempt.Success)
+			assert.NotEmpty(t, attempt.IPAddress)
+		}
+		
+		// Act - unlock
+		w = MakeRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/unlock", user.ID), nil, adminToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		_, err = LoginTestUser("lockout@example.com", "password123")
+		assert.NoError(t, err)
+	})
+	
+	t.Run("Customers cannot unlock accounts", func(t *testing.T) {
+		// Arrange
+		token, err := LoginTestUser("lockout@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act
+		w := MakeRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/unlock", user.ID), nil, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
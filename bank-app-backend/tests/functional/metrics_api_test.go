@@ -0,0 +1,66 @@
+package functional
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	user, err := CreateTestUser("metrics@example.com", "password123", "Mia", "Metrics")
+	assert.NoError(t, err)
+	account, err := CreateTestAccount(user.ID, "MET1000001", models.Checking, 100.0)
+	assert.NoError(t, err)
+	token, err := LoginTestUser("metrics@example.com", "password123")
+	assert.NoError(t, err)
+
+	t.Run("Metrics cover requests, queries, transfers and logins", func(t *testing.T) {
+		// Act
+		get := MakeRequest("GET", fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil, token)
+		transfer := MakeRequest("POST", "/api/v1/transactions/transfer", models.TransferRequest{
+			FromAccountID: account.ID,
+			ToAccountID:   account.ID,
+			Amount:        10.0,
+		}, token)
+		w := MakeRequest("GET", "/metrics", nil, "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, get.Code)
+		assert.Equal(t, http.StatusBadRequest, transfer.Code)
+		assert.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, `http_requests_total{method="GET",route="/api/v1/accounts/:id",status="200"}`, "routes are labelled by template")
+		assert.NotContains(t, body, fmt.Sprintf(`route="/api/v1/accounts/%d"`, account.ID))
+		assert.Contains(t, body, `db_query_duration_seconds_count{operation="query",table="accounts"}`)
+		assert.Contains(t, body, `bank_transfer_failures_total{reason="same_account"}`)
+		assert.Contains(t, body, `bank_logins_total{result="success"}`)
+	})
+	
+	t.Run("With METRICS_TOKEN set, scrapers must present it", func(t *testing.T) {
+		// The shared router has no metrics token, so use one of its own
+		router := SetupTestRouter(testDB, map[string]string{"METRICS_TOKEN": "metrics-token"})
+		scrape := func(token string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", "/metrics", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		
+		// Act & Assert - a user's access token is not the metrics token
+		assert.Equal(t, http.StatusUnauthorized, scrape("").Code)
+		assert.Equal(t, http.StatusUnauthorized, scrape(token).Code)
+		w := scrape("metrics-token")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "http_requests_total")
+	})
+}
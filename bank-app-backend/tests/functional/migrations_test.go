@@ -0,0 +1,154 @@
+package functional
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The models of the first release, which created its schema with AutoMigrate
+
+type baselineUser struct {
+	ID        uint              `gorm:"primaryKey"`
+	Email     string            `gorm:"uniqueIndex;not null"`
+	Password  string            `gorm:"not null"`
+	FirstName string            `gorm:"not null"`
+	LastName  string            `gorm:"not null"`
+	Accounts  []baselineAccount `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+type baselineAccount struct {
+	ID            uint                  `gorm:"primaryKey"`
+	UserID        uint                  `gorm:"not null"`
+	AccountNumber string                `gorm:"uniqueIndex;not null"`
+	AccountType   string                `gorm:"not null"`
+	Balance       float64               `gorm:"not null;default:0"`
+	Transactions  []baselineTransaction `gorm:"foreignKey:AccountID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineAccount) TableName() string {
+	return "accounts"
+}
+
+type baselineTransaction struct {
+	ID              uint `gorm:"primaryKey"`
+	AccountID       uint `gorm:"not null"`
+	SourceAccountID *uint
+	TargetAccountID *uint
+	Amount          float64 `gorm:"not null"`
+	Balance         float64 `gorm:"not null"`
+	Type            string  `gorm:"not null"`
+	Description     string
+	TransactionDate time.Time `gorm:"not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+func (baselineTransaction) TableName() string {
+	return "transactions"
+}
+
+func TestMigrateUpFromBaseline(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	ctx := context.Background()
+
+	// Arrange - a schema of its own, created and filled like the first release did
+	assert.NoError(t, testDB.Exec(`DROP SCHEMA IF EXISTS "baseline_upgrade" CASCADE`).Error)
+	assert.NoError(t, testDB.Exec(`CREATE SCHEMA "baseline_upgrade"`).Error)
+	defer testDB.Exec(`DROP SCHEMA IF EXISTS "baseline_upgrade" CASCADE`)
+
+	db, err := gorm.Open(postgres.Open(testDSN()+" search_path=baseline_upgrade"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	assert.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineAccount{}, &baselineTransaction{}))
+	user := baselineUser{Email: "Early.Adopter@example.com", Password: "hash", FirstName: "Early", LastName: "Adopter"}
+	assert.NoError(t, db.Create(&user).Error)
+	account := baselineAccount{UserID: user.ID, AccountNumber: "BASE000001", AccountType: "CHECKING", Balance: 100}
+	assert.NoError(t, db.Create(&account).Error)
+	assert.NoError(t, db.Create(&baselineTransaction{
+		AccountID: account.ID, Amount: 100, Balance: 100, Type: "DEPOSIT", TransactionDate: time.Now(),
+	}).Error)
+
+	// Act
+	migrator, err := testMigrator(db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = migrator.Up(ctx)
+
+	// Assert - every migration applied, and the first release's users gained
+	// the columns added since, with their defaults
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, migrator.Check(ctx))
+	var row struct {
+		Role              string
+		TwoFactorLastStep int64
+		FailedLoginCount  int
+		EmailIndex        *string
+	}
+	assert.NoError(t, db.Table("users").Select("role, two_factor_last_step, failed_login_count, email_index").Where("id = ?", user.ID).Take(&row).Error)
+	assert.Equal(t, "customer", row.Role)
+	assert.Zero(t, row.TwoFactorLastStep)
+	assert.Zero(t, row.FailedLoginCount)
+	assert.Nil(t, row.EmailIndex)
+	var plaintextIndexes int64
+	db.Raw(`SELECT count(*) FROM pg_indexes WHERE schemaname = 'baseline_upgrade' AND indexname = 'idx_users_email'`).Scan(&plaintextIndexes)
+	assert.Zero(t, plaintextIndexes, "emails are unique by their blind index")
+
+	// Act - encrypt the plaintext PII, as the upgrade instructions say
+	keyring := pii.NewDevelopmentKeyring()
+	rewritten, err := repository.ReencryptUsers(ctx, db, keyring, 100)
+
+	// Assert - the user can be found by their email again
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rewritten)
+	assert.NoError(t, db.Table("users").Select("email_index").Where("id = ?", user.ID).Take(&row).Error)
+	if assert.NotNil(t, row.EmailIndex) {
+		assert.Equal(t, keyring.EmailIndex("early.adopter@example.com"), *row.EmailIndex)
+	}
+}
+
+func TestMoneyColumnsKeepWholeCents(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	for _, column := range [][2]string{{"accounts", "balance"}, {"transactions", "amount"}, {"transactions", "balance"}, {"transfer_reviews", "amount"}} {
+		// Act
+		var scale *int
+		err := testDB.Raw(`SELECT numeric_scale FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+			column[0], column[1]).Scan(&scale).Error
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.NotNil(t, scale, "%s.%s", column[0], column[1]) {
+			assert.Equal(t, 2, *scale, "%s.%s", column[0], column[1])
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package functional
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// Create a test user, hashed with a lower cost than configured
+	user, err := CreateTestUser("password@example.com", "password123", "Pass", "Word")
+	assert.NoError(t, err)
+	token, err := LoginTestUser("password@example.com", "password123")
+	assert.NoError(t, err)
+	
+	t.Run("Login rehashes with the configured cost", func(t *testing.T) {
+		// Act
+		var stored models.User
+		err := testDB.First(&stored, user.ID).Error
+		
+		// Assert
+		assert.NoError(t, err)
+		cost, err := bcrypt.Cost([]byte(stored.Password))
+		assert.NoError(t, err)
+		assert.Equal(t, testConfig.BcryptCost, cost)
+		assert.NoError(t, stored.ComparePassword("password123"))
+	})
+	
+	t.Run("Wrong current password", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", "/api/v1/users/me/password", models.ChangePasswordRequest{
+			CurrentPassword: "wrongpassword",
+			NewPassword:     "new-password-456",
+		}, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	
+	t.Run("New password must meet the policy", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", "/api/v1/users/me/password", models.ChangePasswordRequest{
+			CurrentPassword: "password123",
+			NewPassword:     "short",
+		}, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	
+	t.Run("Change password", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", "/api/v1/users/me/password", models.ChangePasswordRequest{
+			CurrentPassword: "password123",
+			NewPassword:     "new-password-456",
+		}, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		_, err := LoginTestUser("password@example.com", "password123")
+		assert.Error(t, err)
+		_, err = LoginTestUser("password@example.com", "new-password-456")
+		assert.NoError(t, err)
+	})
+}
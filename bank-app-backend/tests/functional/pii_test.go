@@ -0,0 +1,193 @@
+package functional
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// storedColumns reads a user's columns as they are stored in the database
+func storedColumns(t *testing.T, id uint) map[string]interface{} {
+	row := map[string]interface{}{}
+	assert.NoError(t, testDB.Table("users").Select("email, email_index, first_name, last_name").Where("id = ?", id).Take(&row).Error)
+	return row
+}
+
+func TestPIIEncryption(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	key1, key2, indexKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{9}, 32)
+	keyring, err := pii.NewKeyring(map[int][]byte{1: key1}, indexKey)
+	assert.NoError(t, err)
+	pii.Register(keyring)
+	defer pii.Register(pii.NewDevelopmentKeyring())
+
+	user, err := CreateTestUser("private@example.com", "password123", "Priya", "Vate")
+	assert.NoError(t, err)
+
+	t.Run("PII is encrypted at rest", func(t *testing.T) {
+		// Act
+		row := storedColumns(t, user.ID)
+
+		// Assert
+		for _, column := range []string{"email", "first_name", "last_name"} {
+			assert.True(t, pii.IsEncrypted(row[column].(string)), column)
+		}
+		assert.NotContains(t, row["email_index"], "private")
+	})
+
+	t.Run("Users can still be found by email", func(t *testing.T) {
+		// Act
+		token, err := LoginTestUser("private@example.com", "password123")
+		assert.NoError(t, err)
+		w := MakeRequest("GET", "/api/v1/users/me", nil, token)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"firstName":"Priya"`)
+	})
+
+	t.Run("Re-encryption moves rows to the newest key", func(t *testing.T) {
+		// Arrange - add a second key
+		rotated, err := pii.NewKeyring(map[int][]byte{1: key1, 2: key2}, indexKey)
+		assert.NoError(t, err)
+		pii.Register(rotated)
+
+		// Act
+		count, err := repository.ReencryptUsers(context.Background(), testDB, rotated, 10)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.True(t, strings.HasPrefix(storedColumns(t, user.ID)["first_name"].(string), "enc:v2:"))
+
+		var reloaded models.User
+		assert.NoError(t, testDB.First(&reloaded, user.ID).Error)
+		assert.Equal(t, "Priya", reloaded.FirstName)
+
+		// Running again has nothing left to do
+		count, err = repository.ReencryptUsers(context.Background(), testDB, rotated, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Re-encryption encrypts rows written before encryption", func(t *testing.T) {
+		// Arrange
+		assert.NoError(t, testDB.Exec("UPDATE users SET last_name = ?, email_index = NULL WHERE id = ?", "Plain", user.ID).Error)
+
+		// Act
+		rotated, _ := pii.Registered()
+		count, err := repository.ReencryptUsers(context.Background(), testDB, rotated, 10)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		row := storedColumns(t, user.ID)
+		assert.True(t, pii.IsEncrypted(row["last_name"].(string)))
+		assert.Equal(t, rotated.BlindIndex("private@example.com"), row["email_index"])
+	})
+}
+
+func TestKYCReencryption(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	key1, key2, indexKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{9}, 32)
+	keyring, err := pii.NewKeyring(map[int][]byte{1: key1}, indexKey)
+	assert.NoError(t, err)
+	pii.Register(keyring)
+	defer pii.Register(pii.NewDevelopmentKeyring())
+	ctx := context.Background()
+
+	// A verified user with a document and a screening hit, all under key 1
+	user, err := CreateTestUser("kyc@example.com", "password123", "Kay", "Wyc")
+	assert.NoError(t, err)
+	var profile models.KYCProfile
+	assert.NoError(t, testDB.Where("user_id = ?", user.ID).First(&profile).Error)
+
+	documents := storage.NewLocalStore(filepath.Join(t.TempDir(), "kyc-documents"))
+	ciphertext, err := keyring.Encrypt("%PDF-1.4\n%test document\n")
+	assert.NoError(t, err)
+	oldKey, err := documents.Save([]byte(ciphertext))
+	assert.NoError(t, err)
+	document := &models.KYCDocument{ProfileID: profile.ID, Kind: models.KYCDocumentIDFront, FileName: "passport.pdf", StorageKey: oldKey}
+	assert.NoError(t, testDB.Create(document).Error)
+
+	hit := &models.ScreeningHit{SubjectID: &user.ID, Name: "Kay Wyc", NameIndex: "index", Context: models.ScreeningRegistration, EntryID: "SDN-1", Status: models.HitFlagged}
+	assert.NoError(t, testDB.Create(hit).Error)
+
+	// Add a second key
+	rotated, err := pii.NewKeyring(map[int][]byte{1: key1, 2: key2}, indexKey)
+	assert.NoError(t, err)
+	pii.Register(rotated)
+
+	// storedColumn reads a column as it is stored in the database
+	storedColumn := func(table, column string, id uint) string {
+		var value string
+		assert.NoError(t, testDB.Table(table).Select(column).Where("id = ?", id).Scan(&value).Error)
+		return value
+	}
+
+	t.Run("KYC profiles move to the newest key", func(t *testing.T) {
+		// Act
+		count, err := repository.ReencryptKYCProfiles(ctx, testDB, rotated, 10)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, count, 1)
+		assert.True(t, strings.HasPrefix(storedColumn("kyc_profiles", "date_of_birth", profile.ID), "enc:v2:"))
+
+		var reloaded models.KYCProfile
+		assert.NoError(t, testDB.First(&reloaded, profile.ID).Error)
+		assert.Equal(t, "1990-01-01", reloaded.DateOfBirth)
+	})
+
+	t.Run("KYC documents and their files move to the newest key", func(t *testing.T) {
+		// Act
+		count, err := repository.ReencryptKYCDocuments(ctx, testDB, rotated, documents, 10)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.True(t, strings.HasPrefix(storedColumn("kyc_documents", "file_name", document.ID), "enc:v2:"))
+
+		newKey := storedColumn("kyc_documents", "storage_key", document.ID)
+		assert.NotEqual(t, oldKey, newKey)
+		data, err := documents.Load(newKey)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(data), "enc:v2:"))
+		plaintext, err := rotated.Decrypt(string(data))
+		assert.NoError(t, err)
+		assert.Equal(t, "%PDF-1.4\n%test document\n", plaintext)
+		_, err = documents.Load(oldKey)
+		assert.ErrorIs(t, err, os.ErrNotExist, "the file under the old key is deleted")
+
+		// Running again has nothing left to do
+		count, err = repository.ReencryptKYCDocuments(ctx, testDB, rotated, documents, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Screening hits move to the newest key", func(t *testing.T) {
+		// Act
+		count, err := repository.ReencryptScreeningHits(ctx, testDB, rotated, 10)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.True(t, strings.HasPrefix(storedColumn("screening_hits", "name", hit.ID), "enc:v2:"))
+
+		var reloaded models.ScreeningHit
+		assert.NoError(t, testDB.First(&reloaded, hit.ID).Error)
+		assert.Equal(t, "Kay Wyc", reloaded.Name)
+	})
+}
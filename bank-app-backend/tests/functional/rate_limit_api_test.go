@@ -0,0 +1,72 @@
+package functional
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/middleware"
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// The shared router has no rate limits, so use one of its own
+	router := SetupTestRouter(testDB, map[string]string{"RATE_LIMIT_AUTH": "2/1m"})
+	
+	// login tries to log in from ip, claiming to be forwardedFor when it is set
+	login := func(ip, forwardedFor string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(models.LoginRequest{Email: "nobody@example.com", Password: "wrongpassword"})
+		req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	
+	t.Run("Login is refused with 429 once the caller's bucket is empty", func(t *testing.T) {
+		// Act
+		first := login("192.0.2.1", "")
+		second := login("192.0.2.1", "")
+		refused := login("192.0.2.1", "")
+		
+		// Assert - the attempts within the limit reach the handler
+		assert.Equal(t, http.StatusUnauthorized, first.Code)
+		assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, http.StatusUnauthorized, second.Code)
+		assert.Equal(t, "0", second.Header().Get("RateLimit-Remaining"))
+		
+		assert.Equal(t, http.StatusTooManyRequests, refused.Code)
+		for _, header := range middleware.RateLimitHeaders {
+			assert.NotEmpty(t, refused.Header().Get(header), header)
+		}
+		assert.Equal(t, "2", refused.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "2;w=60", refused.Header().Get("RateLimit-Policy"))
+	})
+	
+	t.Run("X-Forwarded-For does not give a caller a new bucket", func(t *testing.T) {
+		// Act - no proxy is trusted, so the claimed address is ignored
+		w := login("192.0.2.1", "203.0.113.7")
+		
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+	
+	t.Run("Other addresses have buckets of their own", func(t *testing.T) {
+		// Act
+		w := login("192.0.2.2", "")
+		
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	})
+}
@@ -0,0 +1,144 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanctionsScreeningAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+
+	// Create a customer with an account, and an admin
+	user, err := CreateTestUser("payer@example.com", "password123", "Pat", "Payer")
+	assert.NoError(t, err)
+	fromAccount, err := CreateTestAccount(user.ID, "SAN1000001", models.Checking, 1000.0)
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	token, err := LoginTestUser("payer@example.com", "password123")
+	assert.NoError(t, err)
+	adminToken, err := LoginTestUser("admin@example.com", "password123")
+	assert.NoError(t, err)
+
+	register := func(email, firstName, lastName string) *httptest.ResponseRecorder {
+		return MakeRequest("POST", "/api/v1/auth/register", models.RegisterRequest{
+			Email:     email,
+			Password:  "password123",
+			FirstName: firstName,
+			LastName:  lastName,
+		}, "")
+	}
+
+	getHits := func(query string) []models.ScreeningHit {
+		w := MakeRequest("GET", "/api/v1/admin/screening/hits"+query, nil, adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var hits []models.ScreeningHit
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hits))
+		return hits
+	}
+
+	t.Run("Registration is refused for a name on the watchlist", func(t *testing.T) {
+		// Act
+		response := register("ivan@example.com", "Ivan", "Volkov")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		var count int64
+		testDB.Model(&models.User{}).Where("email_index <> ''").Count(&count)
+		assert.Equal(t, int64(2), count, "only the customer and admin exist")
+
+		hits := getHits("?status=" + models.HitBlocked)
+		if assert.Len(t, hits, 1) {
+			assert.Nil(t, hits[0].SubjectID)
+			assert.Equal(t, "9001", hits[0].EntryID)
+			assert.Equal(t, "Ivan Volkov", hits[0].Name)
+		}
+	})
+
+	var payeeID uint
+	var flagged models.ScreeningHit
+
+	t.Run("A close match registers but is flagged", func(t *testing.T) {
+		// Act
+		response := register("ivana@example.com", "Ivana", "Volk")
+
+		// Assert - the hit is recorded against the new user
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		hits := getHits("?status=" + models.HitFlagged)
+		if assert.Len(t, hits, 1) && assert.NotNil(t, hits[0].SubjectID) {
+			flagged = hits[0]
+			payeeID = *flagged.SubjectID
+			assert.Equal(t, models.ScreeningRegistration, flagged.Context)
+		}
+	})
+
+	t.Run("Transfers to a flagged payee are held until the hit is cleared", func(t *testing.T) {
+		// Arrange
+		toAccount, err := CreateTestAccount(payeeID, "SAN1000002", models.Savings, 0.0)
+		assert.NoError(t, err)
+		transferReq := models.TransferRequest{FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 50.0}
+		clearURL := fmt.Sprintf("/api/v1/admin/screening/hits/%d/clear", flagged.ID)
+
+		// Act
+		held := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token)
+		tooShort := MakeRequest("POST", clearURL, models.ClearScreeningHitRequest{Justification: "ok"}, adminToken)
+		cleared := MakeRequest("POST", clearURL, models.ClearScreeningHitRequest{Justification: "Verified passport, different date of birth"}, adminToken)
+		again := MakeRequest("POST", clearURL, models.ClearScreeningHitRequest{Justification: "Verified passport, different date of birth"}, adminToken)
+		completed := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, held.Code)
+		assert.Equal(t, http.StatusBadRequest, tooShort.Code)
+		assert.Equal(t, http.StatusOK, cleared.Code)
+		assert.Equal(t, http.StatusConflict, again.Code)
+		assert.Equal(t, http.StatusOK, completed.Code)
+		assert.Len(t, getHits(""), 1, "only the refused registration is still open")
+
+		var entries []models.AuditEntry
+		testDB.Where("action = ?", models.AuditScreeningHitCleared).Find(&entries)
+		if assert.Len(t, entries, 1) {
+			assert.Contains(t, entries[0].After, "different date of birth")
+		}
+	})
+
+	t.Run("Customers cannot clear screening hits", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/screening/hits", nil, token)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Clearing a refused registration only clears that applicant", func(t *testing.T) {
+		// Arrange - clear the hit from the refused registration
+		blocked := getHits("?status=" + models.HitBlocked)
+		if !assert.Len(t, blocked, 1) {
+			return
+		}
+		clearURL := fmt.Sprintf("/api/v1/admin/screening/hits/%d/clear", blocked[0].ID)
+		cleared := MakeRequest("POST", clearURL, models.ClearScreeningHitRequest{Justification: "Verified passport, different date of birth"}, adminToken)
+		assert.Equal(t, http.StatusOK, cleared.Code)
+
+		// Act
+		other := register("other.ivan@example.com", "Ivan", "Volkov")
+		same := register("ivan@example.com", "Ivan", "Volkov")
+
+		// Assert - someone else with the same name is still refused
+		assert.Equal(t, http.StatusForbidden, other.Code)
+		assert.Equal(t, http.StatusAccepted, same.Code)
+		hits := getHits("?status=" + models.HitBlocked)
+		if assert.Len(t, hits, 1) {
+			assert.Nil(t, hits[0].SubjectID)
+			assert.NotEqual(t, blocked[0].ID, hits[0].ID)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceClientAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// Create an admin and an account for the client to read
+	user, err := CreateTestUser("owner@example.com", "password123", "Account", "Owner")
+	assert.NoError(t, err)
+	_, err = CreateTestAccount(user.ID, "5000000001", models.Checking, 100)
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+	adminToken, err := LoginTestUser("admin@example.com", "password123")
+	assert.NoError(t, err)
+	
+	// Register a client that may read accounts and transactions
+	w := MakeRequest("POST", "/api/v1/admin/clients", models.CreateServiceClientRequest{
+		Name:   "Nightly report",
+		Scopes: []string{models.ScopeAccountsRead, models.ScopeTransactionsRead},
+	}, adminToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	
+	var credentials models.ServiceClientCredentials
+	err = json.Unmarshal(w.Body.Bytes(), &credentials)
+	assert.NoError(t, err)
+	
+	// requestWithAPIKey calls the API with the client's API key
+	requestWithAPIKey := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, req)
+		return w
+	}
+	
+	// requestToken runs the client-credentials grant
+	requestToken := func(scope string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {"client_credentials"}, "scope": {scope}}
+		req, _ := http.NewRequest("POST", "/api/v1/auth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(credentials.Client.ClientID, credentials.ClientSecret)
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, req)
+		return w
+	}
+	
+	t.Run("API key grants the client's scopes", func(t *testing.T) {
+		// Act
+		w := requestWithAPIKey("GET", "/api/v1/accounts", credentials.APIKey)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	
+	t.Run("Missing scope is rejected", func(t *testing.T) {
+		// Act - the client was not granted users:read
+		w := requestWithAPIKey("GET", "/api/v1/users", credentials.APIKey)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	
+	t.Run("Client-credentials token carries the requested scope only", func(t *testing.T) {
+		// Act
+		w := requestToken(models.ScopeTransactionsRead)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var token models.ClientToken
+		err := json.Unmarshal(w.Body.Bytes(), &token)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ScopeTransactionsRead, token.Scope)
+		
+		w = MakeRequest("GET", "/api/v1/transactions", nil, token.AccessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = MakeRequest("GET", "/api/v1/accounts", nil, token.AccessToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+	
+	t.Run("Clients cannot use admin or user-only routes", func(t *testing.T) {
+		// Act
+		adminResponse := requestWithAPIKey("GET", "/api/v1/admin/clients", credentials.APIKey)
+		enrollResponse := requestWithAPIKey("POST", "/api/v1/auth/2fa/enroll", credentials.APIKey)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, adminResponse.Code)
+		assert.Equal(t, http.StatusUnauthorized, enrollResponse.Code)
+	})
+	
+	t.Run("Admin can review usage", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", fmt.Sprintf("/api/v1/admin/clients/%d/usage", credentials.Client.ID), nil, adminToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var usage []models.ServiceClientUsage
+		err := json.Unmarshal(w.Body.Bytes(), &usage)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, usage)
+		
+		// Rejected requests are recorded too
+		var deniedAdminCall bool
+		for _, request := range usage {
+			if request.Path == "/api/v1/admin/clients" && request.StatusCode == http.StatusForbidden {
+				deniedAdminCall = true
+			}
+		}
+		assert.True(t, deniedAdminCall)
+	})
+	
+	t.Run("Revoked clients are rejected", func(t *testing.T) {
+		// Arrange - get a token before revoking
+		tokenResponse := requestToken("")
+		var token models.ClientToken
+		json.Unmarshal(tokenResponse.Body.Bytes(), &token)
+		
+		// Act
+		w := MakeRequest("DELETE", fmt.Sprintf("/api/v1/admin/clients/%d", credentials.Client.ID), nil, adminToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		// Assert - the API key, issued tokens and the grant all stop working
+		assert.Equal(t, http.StatusUnauthorized, requestWithAPIKey("GET", "/api/v1/accounts", credentials.APIKey).Code)
+		assert.Equal(t, http.StatusUnauthorized, MakeRequest("GET", "/api/v1/accounts", nil, token.AccessToken).Code)
+		assert.Equal(t, http.StatusUnauthorized, requestToken("").Code)
+	})
+	
+	t.Run("Customers cannot manage clients", func(t *testing.T) {
+		// Arrange
+		token, err := LoginTestUser("owner@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act
+		w := MakeRequest("GET", "/api/v1/admin/clients", nil, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,82 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// Create a test user logged in on two devices
+	_, err := CreateTestUser("sessions@example.com", "password123", "Session", "User")
+	assert.NoError(t, err)
+	firstToken, err := LoginTestUser("sessions@example.com", "password123")
+	assert.NoError(t, err)
+	secondToken, err := LoginTestUser("sessions@example.com", "password123")
+	assert.NoError(t, err)
+	
+	var otherSession models.SessionDTO
+	
+	t.Run("List sessions", func(t *testing.T) {
+		// Act
+		w := MakeRequest("GET", "/api/v1/users/me/sessions", nil, secondToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var sessions []models.SessionDTO
+		err := json.Unmarshal(w.Body.Bytes(), &sessions)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		
+		current := 0
+		for _, session := range sessions {
+			if session.Current {
+				current++
+			} else {
+				otherSession = session
+			}
+		}
+		assert.Equal(t, 1, current)
+	})
+	
+	t.Run("Revoke another session", func(t *testing.T) {
+		// Act
+		w := MakeRequest("DELETE", fmt.Sprintf("/api/v1/users/me/sessions/%d", otherSession.ID), nil, secondToken)
+		
+		// Assert - the revoked session's token stops working at once
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		w = MakeRequest("GET", "/api/v1/users/me", nil, firstToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		
+		w = MakeRequest("GET", "/api/v1/users/me", nil, secondToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	
+	t.Run("Cannot revoke another user's session", func(t *testing.T) {
+		// Arrange
+		_, err := CreateTestUser("other@example.com", "password123", "Other", "User")
+		assert.NoError(t, err)
+		otherToken, err := LoginTestUser("other@example.com", "password123")
+		assert.NoError(t, err)
+		
+		w := MakeRequest("GET", "/api/v1/users/me/sessions", nil, secondToken)
+		var sessions []models.SessionDTO
+		json.Unmarshal(w.Body.Bytes(), &sessions)
+		assert.Len(t, sessions, 1)
+		
+		// Act
+		w = MakeRequest("DELETE", fmt.Sprintf("/api/v1/users/me/sessions/%d", sessions[0].ID), nil, otherToken)
+		
+		// Assert
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -2,20 +2,34 @@ package functional
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jbadhree/drank/bank-app-backend/internal/config"
 	"github.com/jbadhree/drank/bank-app-backend/internal/handlers"
+	"github.com/jbadhree/drank/bank-app-backend/internal/mailer"
+	"github.com/jbadhree/drank/bank-app-backend/internal/health"
+	"github.com/jbadhree/drank/bank-app-backend/internal/metrics"
+	"github.com/jbadhree/drank/bank-app-backend/internal/migrations"
 	"github.com/jbadhree/drank/bank-app-backend/internal/middleware"
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
+	"github.com/jbadhree/drank/bank-app-backend/internal/ratelimit"
 	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
+	"github.com/jbadhree/drank/bank-app-backend/internal/sanctions"
 	"github.com/jbadhree/drank/bank-app-backend/internal/services"
+	"github.com/jbadhree/drank/bank-app-backend/internal/signing"
+	"github.com/jbadhree/drank/bank-app-backend/internal/storage"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -27,14 +41,42 @@ var (
 	testConfig *config.Config
 )
 
-// SetupTestDB initializes a test database connection
-func SetupTestDB(t *testing.T) (*gorm.DB, error) {
+// testWatchlist is the sanctions watchlist the test router screens against
+var testWatchlist = sanctions.NewList([]sanctions.Entry{
+	{ID: "9001", Name: "VOLKOV, Ivan", Type: "individual", Program: "SDGT", Aliases: []string{"Ivan Volkoff"}},
+})
+
+// unlimited turns the rate limits off for the shared test router, as
+// subtests call the API far more often than any client would
+var unlimited = map[string]string{
+	"RATE_LIMIT_AUTH":         "off",
+	"RATE_LIMIT_TRANSACTIONS": "off",
+	"RATE_LIMIT_API":          "off",
+}
+
+// mustLoadConfig loads the configuration from the environment and the
+// overrides, which the tests cannot run without
+func mustLoadConfig(overrides map[string]string) *config.Config {
+	cfg, err := config.Load(config.Sources{Overrides: overrides})
+	if err != nil {
+		panic(fmt.Sprintf("invalid test configuration: %v", err))
+	}
+	return cfg
+}
+
+// testDSN returns the connection string of the test database
+func testDSN() string {
 	// Load test environment variables
-	cfg := config.New()
+	cfg := mustLoadConfig(nil)
 	
 	// Use test database settings with different port (5435 for test db)
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost, 5435, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+}
+
+// SetupTestDB initializes a test database connection
+func SetupTestDB(t *testing.T) (*gorm.DB, error) {
+	dsn := testDSN()
 	
 	// Configure the database with minimal logging for tests
 	dbConfig := &gorm.Config{
@@ -45,9 +87,18 @@ func SetupTestDB(t *testing.T) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to test database: %v", err)
 	}
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %v", err)
+	}
+	
+	// Encrypt PII with the development keyring
+	pii.Register(pii.NewDevelopmentKeyring())
 	
-	// Auto-migrate the schema for test database
-	err = db.AutoMigrate(&models.User{}, &models.Account{}, &models.Transaction{})
+	// Bring the test database's schema up to date
+	migrator, err := testMigrator(db)
+	if err == nil {
+		_, err = migrator.Up(context.Background())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate test database schema: %v", err)
 	}
@@ -55,6 +106,19 @@ func SetupTestDB(t *testing.T) (*gorm.DB, error) {
 	return db, nil
 }
 
+// testMigrator returns a migrator for the migrations compiled into the binary
+func testMigrator(db *gorm.DB) (*migrations.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		return nil, err
+	}
+	return migrations.New(sqlDB, embedded), nil
+}
+
 // CleanupTestDB drops all test tables
 func CleanupTestDB(db *gorm.DB) error {
 	// Get a generic database object
@@ -67,68 +131,243 @@ func CleanupTestDB(db *gorm.DB) error {
 	return sqlDB.Close()
 }
 
-// SetupTestRouter creates a router with all the routes for testing
-func SetupTestRouter(db *gorm.DB) *gin.Engine {
+// SetupTestRouter creates a router with all the routes for testing, configured
+// like the server apart from the overrides
+func SetupTestRouter(db *gorm.DB, overrides map[string]string) *gin.Engine {
 	// Set gin to test mode
 	gin.SetMode(gin.TestMode)
 	
 	// Use default test configuration
-	cfg := config.New()
+	cfg := mustLoadConfig(overrides)
+	
+	// Write emails to a temporary directory
+	mail := mailer.NewFileMailer(filepath.Join(os.TempDir(), "drank-test-mail"), cfg.MailFrom)
+	
+	// Sign tokens with the shared test secret
+	keySet := signing.NewHMACKeySet(cfg.JWTSecret)
 	
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	accountRepo := repository.NewAccountRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	emailTokenRepo := repository.NewEmailTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	serviceClientRepo := repository.NewServiceClientRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	transferReviewRepo := repository.NewTransferReviewRepository(db)
+	screeningHitRepo := repository.NewScreeningHitRepository(db)
+	kycRepo := repository.NewKYCRepository(db)
 	
 	// Initialize services
 	userService := services.NewUserService(userRepo)
 	accountService := services.NewAccountService(accountRepo)
-	transactionService := services.NewTransactionService(transactionRepo, accountRepo)
+	var fraudService services.FraudService
+	if cfg.FraudScreening {
+		fraudService = services.NewFraudService(transactionRepo, auditRepo, services.FraudOptions{
+			ReviewScore:          cfg.FraudReviewScore,
+			BlockScore:           cfg.FraudBlockScore,
+			VelocityCount:        cfg.FraudVelocityCount,
+			VelocityWindow:       cfg.FraudVelocityWindow,
+			VelocityScore:        cfg.FraudVelocityScore,
+			AverageMultiplier:    cfg.FraudAverageMultiplier,
+			AverageMinHistory:    cfg.FraudAverageMinHistory,
+			AverageScore:         cfg.FraudAverageScore,
+			NewPayeeAmount:       cfg.FraudNewPayeeAmount,
+			NewPayeeScore:        cfg.FraudNewPayeeScore,
+			PasswordChangeWindow: cfg.FraudPasswordChangeWindow,
+			PasswordChangeScore:  cfg.FraudPasswordChangeScore,
+		})
+	}
+	sanctionsService := services.NewSanctionsService(screeningHitRepo, userRepo, services.SanctionsOptions{
+		List:       testWatchlist,
+		FlagScore:  cfg.SanctionsFlagScore,
+		BlockScore: cfg.SanctionsBlockScore,
+	})
+	var screening services.SanctionsService
+	if testWatchlist != nil {
+		screening = sanctionsService
+	}
+	kycService := services.NewKYCService(kycRepo, transactionRepo, services.KYCOptions{
+		Documents:       storage.NewLocalStore(filepath.Join(os.TempDir(), "drank-test-kyc")),
+		MaxDocumentSize: cfg.KYCMaxDocumentSize,
+		Limits: map[int]services.KYCLimits{
+			models.KYCLevelBasic: {PerTransfer: cfg.KYCLevel1TransferLimit, Daily: cfg.KYCLevel1DailyLimit},
+			models.KYCLevelFull:  {PerTransfer: cfg.KYCLevel2TransferLimit, Daily: cfg.KYCLevel2DailyLimit},
+		},
+	})
+	var kycCheck services.KYCService
+	if cfg.KYCRequired {
+		kycCheck = kycService
+	}
+	transactionService := services.NewTransactionService(transactionRepo, accountRepo, auditRepo, transferReviewRepo, services.TransactionOptions{
+		StepUpThreshold: cfg.StepUpTransferThreshold,
+		StepUpMaxAge:    cfg.StepUpTTL,
+		Fraud:           fraudService,
+		Sanctions:       screening,
+		KYC:             kycCheck,
+	})
+	passwordService := services.NewPasswordService(userRepo, services.PasswordOptions{
+		MinLength:  cfg.PasswordMinLength,
+		BcryptCost: cfg.BcryptCost,
+	})
+	tokenService := services.NewTokenService(tokenRepo, userRepo, services.TokenOptions{
+		KeySet:          keySet,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
+		StepUpTTL:       cfg.StepUpTTL,
+		ClientTokenTTL:  cfg.ClientTokenTTL,
+
+		SessionTouchInterval: cfg.SessionTouchInterval,
+		NewDeviceNotifier:    services.NewMailSessionNotifier(mail),
+	})
+	identityService := services.NewIdentityService(userRepo, emailTokenRepo, tokenService, passwordService, mail, services.IdentityOptions{
+		AppBaseURL:               cfg.AppBaseURL,
+		RequireEmailVerification: cfg.RequireEmailVerification,
+		VerificationTokenTTL:     cfg.VerificationTokenTTL,
+		PasswordResetTokenTTL:    cfg.PasswordResetTokenTTL,
+		Sanctions:                screening,
+	})
+	twoFactorService := services.NewTwoFactorService(userRepo, recoveryCodeRepo, services.TwoFactorOptions{
+		KeySet:       keySet,
+		Issuer:       cfg.TwoFactorIssuer,
+		ChallengeTTL: cfg.TwoFactorChallengeTTL,
+	})
+	loginAttemptService := services.NewLoginAttemptService(loginAttemptRepo, userRepo, services.LoginAttemptOptions{
+		MaxFailures:     cfg.LoginMaxFailures,
+		FailureWindow:   cfg.LoginFailureWindow,
+		LockoutDuration: cfg.LoginLockoutDuration,
+		IPMaxFailures:   cfg.LoginIPMaxFailures,
+		DelayBase:       0, // Subtests log in right after failed attempts
+	})
+	serviceClientService := services.NewServiceClientService(serviceClientRepo, tokenService)
+	auditService := services.NewAuditService(auditRepo)
 	
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
+	authHandler := handlers.NewAuthHandler(userService, tokenService, identityService, twoFactorService, loginAttemptService, passwordService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	loginAttemptHandler := handlers.NewLoginAttemptHandler(loginAttemptService)
+	serviceClientHandler := handlers.NewServiceClientHandler(serviceClientService)
 	userHandler := handlers.NewUserHandler(userService)
+	sessionHandler := handlers.NewSessionHandler(tokenService)
+	passwordHandler := handlers.NewPasswordHandler(passwordService, tokenService, loginAttemptService)
 	accountHandler := handlers.NewAccountHandler(accountService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	screeningHandler := handlers.NewScreeningHandler(sanctionsService)
+	kycHandler := handlers.NewKYCHandler(kycService, cfg.KYCMaxDocumentSize)
+	jwksHandler := handlers.NewJWKSHandler(keySet)
+	checker := health.New(time.Second)
+	checker.Add("database", health.Database(db))
+	migrator, err := testMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+	checker.Add("migrations", migrator.Check)
+	healthHandler := handlers.NewHealthHandler(checker)
 	
 	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret)
+	authMiddleware := middleware.NewAuthMiddleware(tokenService, serviceClientService)
+	
+	// Every router gets its own rate limits
+	rateLimiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), cfg.RateLimitFailOpen)
+	authLimit := rateLimiter.Limit("auth", cfg.RateLimitAuth)
+	apiLimit := rateLimiter.Limit("api", cfg.RateLimitAPI)
 	
 	// Initialize router
-	router := gin.Default()
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic(err)
+	}
+	router.Use(middleware.RequestID(), middleware.RequestLogger(slog.Default()), middleware.Tracing(), middleware.Metrics(), middleware.Recovery())
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+	router.GET("/metrics", middleware.MetricsAuth(cfg.MetricsToken), gin.WrapH(metrics.Handler()))
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
 	
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
-		// Auth routes - no auth required
-		v1.POST("/auth/login", authHandler.Login)
+		// Auth routes - no auth required, limited per IP address
+		v1.POST("/auth/login", authLimit, authHandler.Login)
+		v1.POST("/auth/login/2fa", authLimit, authHandler.LoginTwoFactor)
+		v1.POST("/auth/refresh", authLimit, authHandler.Refresh)
+		v1.POST("/auth/register", authLimit, authHandler.Register)
+		v1.POST("/auth/verify-email", authLimit, authHandler.VerifyEmail)
+		v1.POST("/auth/resend-verification", authLimit, authHandler.ResendVerification)
+		v1.POST("/auth/forgot-password", authLimit, authHandler.ForgotPassword)
+		v1.POST("/auth/reset-password", authLimit, authHandler.ResetPassword)
+		v1.POST("/auth/token", authLimit, serviceClientHandler.Token)
+
+		// Logout and step-up routes - auth required
+		v1.POST("/auth/logout", authMiddleware.Authenticate(), apiLimit, authHandler.Logout)
+		v1.POST("/auth/logout-all", authMiddleware.Authenticate(), apiLimit, authHandler.LogoutAll)
+		v1.POST("/auth/reauthenticate", authMiddleware.Authenticate(), apiLimit, authHandler.Reauthenticate)
+
+		// Two-factor enrollment routes - auth required
+		v1.POST("/auth/2fa/enroll", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Enroll)
+		v1.POST("/auth/2fa/confirm", authMiddleware.Authenticate(), apiLimit, twoFactorHandler.Confirm)
 		
-		// User routes - auth required
+		// User routes - auth required, service clients need users:read
 		users := v1.Group("/users")
-		users.Use(authMiddleware.Authenticate())
+		users.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireScope(models.ScopeUsersRead))
 		{
 			users.GET("", userHandler.GetAllUsers)
 			users.GET("/:id", userHandler.GetUserByID)
 			users.GET("/me", userHandler.GetCurrentUser)
+			users.POST("/me/password", passwordHandler.ChangePassword)
+			users.GET("/me/sessions", sessionHandler.GetSessions)
+			users.DELETE("/me/sessions/:id", sessionHandler.RevokeSession)
+			users.GET("/me/kyc", kycHandler.GetMyProfile)
+			users.PUT("/me/kyc", kycHandler.SaveMyProfile)
+			users.POST("/me/kyc/documents", kycHandler.UploadDocument)
+			users.POST("/me/kyc/submit", kycHandler.Submit)
 		}
 		
-		// Account routes - auth required
+		// Account routes - auth required, service clients need accounts:read
 		accounts := v1.Group("/accounts")
-		accounts.Use(authMiddleware.Authenticate())
+		accounts.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireScope(models.ScopeAccountsRead))
 		{
 			accounts.GET("", accountHandler.GetAllAccounts)
 			accounts.GET("/:id", accountHandler.GetAccountByID)
 			accounts.GET("/user/:userId", accountHandler.GetAccountsByUserID)
 		}
 		
-		// Transaction routes - auth required
+		// Transaction routes - auth required, service clients need the scope per route
 		transactions := v1.Group("/transactions")
-		transactions.Use(authMiddleware.Authenticate())
+		transactions.Use(authMiddleware.Authenticate(), rateLimiter.Limit("transactions", cfg.RateLimitTransactions))
 		{
-			transactions.GET("", transactionHandler.GetAllTransactions)
-			transactions.GET("/:id", transactionHandler.GetTransactionByID)
-			transactions.GET("/account/:accountId", transactionHandler.GetTransactionsByAccountID)
-			transactions.POST("/transfer", transactionHandler.Transfer)
+			transactions.GET("", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetAllTransactions)
+			transactions.GET("/:id", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetTransactionByID)
+			transactions.GET("/account/:accountId", authMiddleware.RequireScope(models.ScopeTransactionsRead), transactionHandler.GetTransactionsByAccountID)
+			transactions.POST("/transfer", authMiddleware.RequireScope(models.ScopeTransfersWrite), transactionHandler.Transfer)
+		}
+
+		// Admin routes - admin role required
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware.Authenticate(), apiLimit, authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			admin.POST("/users/:id/2fa/reset", twoFactorHandler.Reset)
+			admin.POST("/users/:id/unlock", loginAttemptHandler.Unlock)
+			admin.GET("/users/:id/login-attempts", loginAttemptHandler.GetAttemptsByUserID)
+			admin.POST("/clients", serviceClientHandler.CreateClient)
+			admin.GET("/clients", serviceClientHandler.GetAllClients)
+			admin.GET("/clients/:id", serviceClientHandler.GetClientByID)
+			admin.DELETE("/clients/:id", serviceClientHandler.RevokeClient)
+			admin.GET("/clients/:id/usage", serviceClientHandler.GetUsage)
+			admin.GET("/audit", auditHandler.GetEntries)
+			admin.GET("/audit/verify", auditHandler.Verify)
+			admin.GET("/transfers/reviews", transactionHandler.GetTransferReviews)
+			admin.POST("/transfers/reviews/:id/approve", transactionHandler.ApproveTransfer)
+			admin.POST("/transfers/reviews/:id/reject", transactionHandler.RejectTransfer)
+			admin.GET("/screening/hits", screeningHandler.GetHits)
+			admin.POST("/screening/hits/:id/clear", screeningHandler.ClearHit)
+			admin.GET("/kyc", kycHandler.GetProfiles)
+			admin.GET("/kyc/documents/:id", kycHandler.GetDocument)
+			admin.GET("/kyc/:userId", kycHandler.GetProfile)
+			admin.POST("/kyc/:userId/approve", kycHandler.Approve)
+			admin.POST("/kyc/:userId/reject", kycHandler.Reject)
 		}
 	}
 	
@@ -147,16 +386,16 @@ func SetupTest(t *testing.T) {
 	}
 	
 	// Clean up any existing data
-	testDB.Exec("TRUNCATE users, accounts, transactions RESTART IDENTITY CASCADE")
+	testDB.Exec("TRUNCATE users, accounts, transactions, refresh_tokens, revoked_tokens, email_tokens, recovery_codes, login_attempts, service_clients, service_client_usages, sessions, audit_entries, transfer_reviews, screening_hits, kyc_profiles, kyc_documents RESTART IDENTITY CASCADE")
 	
 	// Initialize router only once
 	if testRouter == nil {
-		testRouter = SetupTestRouter(testDB)
+		testRouter = SetupTestRouter(testDB, unlimited)
 	}
 	
 	// Initialize config
 	if testConfig == nil {
-		testConfig = config.New()
+		testConfig = mustLoadConfig(nil)
 	}
 }
 
@@ -184,13 +423,50 @@ func MakeRequest(method, url string, body interface{}, token string) *httptest.R
 	return w
 }
 
-// CreateTestUser creates a test user for tests
+// CreateTestUser creates a verified test user for tests. The user has also
+// passed identity verification at the full level, so they can transfer.
 func CreateTestUser(email, password, firstName, lastName string) (*models.User, error) {
+	verifiedAt := time.Now()
 	user := &models.User{
-		Email:     email,
-		Password:  password,
-		FirstName: firstName,
-		LastName:  lastName,
+		Email:           email,
+		FirstName:       firstName,
+		LastName:        lastName,
+		EmailVerifiedAt: &verifiedAt,
+	}
+	if err := user.SetPassword(password, bcrypt.MinCost); err != nil {
+		return nil, err
+	}
+	
+	if err := testDB.Create(user).Error; err != nil {
+		return nil, err
+	}
+	
+	profile := &models.KYCProfile{
+		UserID:      user.ID,
+		DateOfBirth: "1990-01-01",
+		Country:     "US",
+		Status:      models.KYCVerified,
+		Level:       models.KYCLevelFull,
+	}
+	if err := testDB.Create(profile).Error; err != nil {
+		return nil, err
+	}
+	
+	return user, nil
+}
+
+// CreateTestAdmin creates a verified test user with the admin role
+func CreateTestAdmin(email, password string) (*models.User, error) {
+	verifiedAt := time.Now()
+	user := &models.User{
+		Email:           email,
+		FirstName:       "Admin",
+		LastName:        "User",
+		Role:            models.RoleAdmin,
+		EmailVerifiedAt: &verifiedAt,
+	}
+	if err := user.SetPassword(password, bcrypt.MinCost); err != nil {
+		return nil, err
 	}
 	
 	if err := testDB.Create(user).Error; err != nil {
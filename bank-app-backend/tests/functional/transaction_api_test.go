@@ -274,4 +274,38 @@ func TestTransactionAPI(t *testing.T) {
 			assert.Equal(t, account1.ID, transaction.AccountID)
 		}
 	})
+	
+	t.Run("High-value transfer should require step-up authentication", func(t *testing.T) {
+		// Arrange - above the default step-up threshold
+		transferReq := models.TransferRequest{
+			FromAccountID: account3.ID,
+			ToAccountID:   account1.ID,
+			Amount:        1500.0,
+			Description:   "High-value transfer",
+		}
+		
+		// Act
+		w := MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, token2)
+		
+		// Assert
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		
+		var challenge models.StepUpChallenge
+		err := json.Unmarshal(w.Body.Bytes(), &challenge)
+		assert.NoError(t, err)
+		assert.True(t, challenge.StepUpRequired)
+		
+		// Act - re-authenticate and retry with the elevated token
+		w = MakeRequest("POST", "/api/v1/auth/reauthenticate", models.ReauthenticateRequest{Password: "password123"}, token2)
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var elevated models.ElevatedToken
+		err = json.Unmarshal(w.Body.Bytes(), &elevated)
+		assert.NoError(t, err)
+		
+		w = MakeRequest("POST", "/api/v1/transactions/transfer", transferReq, elevated.Token)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
 }
@@ -0,0 +1,133 @@
+package functional
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorAPI(t *testing.T) {
+	// Set up the test environment
+	SetupTest(t)
+	
+	// Create a test user and an admin
+	user, err := CreateTestUser("twofactor@example.com", "password123", "Two", "Factor")
+	assert.NoError(t, err)
+	_, err = CreateTestAdmin("admin@example.com", "password123")
+	assert.NoError(t, err)
+	
+	loginReq := models.LoginRequest{
+		Email:    "twofactor@example.com",
+		Password: "password123",
+	}
+	
+	var recoveryCodes []string
+	
+	t.Run("Enroll and confirm enables two-factor authentication", func(t *testing.T) {
+		// Arrange
+		token, err := LoginTestUser("twofactor@example.com", "password123")
+		assert.NoError(t, err)
+		
+		// Act - start enrollment
+		w := MakeRequest("POST", "/api/v1/auth/2fa/enroll", nil, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var enrollment models.TwoFactorEnrollment
+		err = json.Unmarshal(w.Body.Bytes(), &enrollment)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, enrollment.Secret)
+		
+		// Act - confirm with a code from the authenticator
+		code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+		assert.NoError(t, err)
+		w = MakeRequest("POST", "/api/v1/auth/2fa/confirm", models.TwoFactorCodeRequest{Code: code}, token)
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response models.RecoveryCodesResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Len(t, response.RecoveryCodes, 10)
+		recoveryCodes = response.RecoveryCodes
+	})
+	
+	t.Run("Login requires a second factor once enabled", func(t *testing.T) {
+		// Act
+		w := MakeRequest("POST", "/api/v1/auth/login", loginReq, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		
+		var challenge models.TwoFactorChallenge
+		err := json.Unmarshal(w.Body.Bytes(), &challenge)
+		assert.NoError(t, err)
+		assert.True(t, challenge.TwoFactorRequired)
+		
+		// The challenge token is not an access token
+		w = MakeRequest("GET", "/api/v1/users/me", nil, challenge.ChallengeToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		
+		// Act - complete the login with a recovery code
+		w = MakeRequest("POST", "/api/v1/auth/login/2fa", models.TwoFactorLoginRequest{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           recoveryCodes[0],
+		}, "")
+		
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var login models.LoginResponse
+		err = json.Unmarshal(w.Body.Bytes(), &login)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, login.Token)
+		assert.True(t, login.User.TwoFactorEnabled)
+		
+		// A recovery code can only be used once
+		w = MakeRequest("POST", "/api/v1/auth/login/2fa", models.TwoFactorLoginRequest{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           recoveryCodes[0],
+		}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+	
+	t.Run("Only admins can reset two-factor authentication", func(t *testing.T) {
+		resetURL := fmt.Sprintf("/api/v1/admin/users/%d/2fa/reset", user.ID)
+		
+		// Arrange - a regular user's token
+		w := MakeRequest("POST", "/api/v1/auth/login", loginReq, "")
+		var challenge models.TwoFactorChallenge
+		json.Unmarshal(w.Body.Bytes(), &challenge)
+		w = MakeRequest("POST", "/api/v1/auth/login/2fa", models.TwoFactorLoginRequest{
+			ChallengeToken: challenge.ChallengeToken,
+			Code:           recoveryCodes[1],
+		}, "")
+		var login models.LoginResponse
+		json.Unmarshal(w.Body.Bytes(), &login)
+		
+		// Act & Assert - regular users are forbidden
+		w = MakeRequest("POST", resetURL, nil, login.Token)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		
+		// Act - reset as admin
+		adminToken, err := LoginTestUser("admin@example.com", "password123")
+		assert.NoError(t, err)
+		w = MakeRequest("POST", resetURL, nil, adminToken)
+		
+		// Assert - password login works again and the recovery codes are gone
+		assert.Equal(t, http.StatusOK, w.Code)
+		_, err = LoginTestUser("twofactor@example.com", "password123")
+		assert.NoError(t, err)
+		var codes int64
+		testDB.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&codes)
+		assert.Zero(t, codes)
+	})
+}
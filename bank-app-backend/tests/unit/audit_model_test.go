@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEntryModel(t *testing.T) {
+	newEntry := func() *models.AuditEntry {
+		actor := models.AuditActor{Type: models.AuditActorUser, ID: "1", IPAddress: "10.0.0.1", RequestID: "req-1"}
+		entry := models.NewAuditEntry(actor, models.AuditTransferCompleted, models.AuditTargetAccount, uint(7)).
+			WithChange(map[string]float64{"balance": 100}, map[string]float64{"balance": 75})
+		entry.PrevHash = "previous"
+		entry.CreatedAt = time.Date(2026, 1, 1, 12, 0, 0, 123456000, time.UTC)
+		return entry
+	}
+
+	t.Run("NewAuditEntry should copy the actor and target", func(t *testing.T) {
+		// Act
+		entry := newEntry()
+
+		// Assert
+		assert.Equal(t, models.AuditActorUser, entry.ActorType)
+		assert.Equal(t, "1", entry.ActorID)
+		assert.Equal(t, "7", entry.TargetID)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, `{"balance":100}`, entry.Before)
+		assert.Equal(t, `{"balance":75}`, entry.After)
+	})
+
+	t.Run("ComputeHash should be stable across time zones", func(t *testing.T) {
+		// Arrange
+		entry := newEntry()
+		hash := entry.ComputeHash()
+
+		// Act - the database returns times in the local zone
+		entry.CreatedAt = entry.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+
+		// Assert
+		assert.Len(t, hash, 64)
+		assert.Equal(t, hash, entry.ComputeHash())
+	})
+
+	t.Run("ComputeHash should change with any recorded field", func(t *testing.T) {
+		// Arrange
+		hash := newEntry().ComputeHash()
+		changes := map[string]func(e *models.AuditEntry){
+			"PrevHash":  func(e *models.AuditEntry) { e.PrevHash = "other" },
+			"CreatedAt": func(e *models.AuditEntry) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+			"ActorID":   func(e *models.AuditEntry) { e.ActorID = "2" },
+			"Action":    func(e *models.AuditEntry) { e.Action = models.AuditLogout },
+			"TargetID":  func(e *models.AuditEntry) { e.TargetID = "8" },
+			"After":     func(e *models.AuditEntry) { e.After = `{"balance":0}` },
+			"IPAddress": func(e *models.AuditEntry) { e.IPAddress = "10.0.0.2" },
+		}
+
+		for field, change := range changes {
+			// Act
+			entry := newEntry()
+			change(entry)
+
+			// Assert
+			assert.NotEqual(t, hash, entry.ComputeHash(), field)
+		}
+	})
+
+	t.Run("ComputeHash should not let fields run into each other", func(t *testing.T) {
+		// Arrange
+		a := newEntry()
+		a.ActorID, a.Action = "1a", "b"
+		b := newEntry()
+		b.ActorID, b.Action = "1", "ab"
+
+		// Act & Assert
+		assert.NotEqual(t, a.ComputeHash(), b.ComputeHash())
+	})
+}
@@ -1,6 +1,9 @@
 package unit
 
 import (
+	"context"
+	"time"
+
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
 	"github.com/jbadhree/drank/bank-app-backend/internal/repository"
 	"github.com/stretchr/testify/mock"
@@ -11,12 +14,12 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Create(user *models.User) error {
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
+func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -24,7 +27,7 @@ func (m *MockUserRepository) FindByID(id uint) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -32,32 +35,87 @@ func (m *MockUserRepository) FindByEmail(email string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindAll() ([]models.User, error) {
+func (m *MockUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	args := m.Called()
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) Update(user *models.User) error {
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Delete(id uint) error {
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string, entry *models.AuditEntry) error {
+	args := m.Called(id, passwordHash, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetTwoFactorSecret(ctx context.Context, id uint, secret string) error {
+	args := m.Called(id, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetTwoFactor(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTwoFactorStep(ctx context.Context, id uint, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementFailedLogins(ctx context.Context, id uint, windowStart time.Time) (int, error) {
+	args := m.Called(id, windowStart)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Lock(ctx context.Context, id uint, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateAudited(ctx context.Context, user *models.User, entry *models.AuditEntry) error {
+	args := m.Called(user, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Disable(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogins(ctx context.Context, id uint, entry *models.AuditEntry) error {
+	args := m.Called(id, entry)
+	return args.Error(0)
+}
+
 // Mock for AccountRepository
 type MockAccountRepository struct {
 	mock.Mock
 }
 
-func (m *MockAccountRepository) Create(account *models.Account) error {
+func (m *MockAccountRepository) Create(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) FindByID(id uint) (*models.Account, error) {
+func (m *MockAccountRepository) FindByID(ctx context.Context, id uint) (*models.Account, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -65,12 +123,12 @@ func (m *MockAccountRepository) FindByID(id uint) (*models.Account, error) {
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByUserID(userID uint) ([]models.Account, error) {
+func (m *MockAccountRepository) FindByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
 	args := m.Called(userID)
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindByAccountNumber(accountNumber string) (*models.Account, error) {
+func (m *MockAccountRepository) FindByAccountNumber(ctx context.Context, accountNumber string) (*models.Account, error) {
 	args := m.Called(accountNumber)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -78,36 +136,44 @@ func (m *MockAccountRepository) FindByAccountNumber(accountNumber string) (*mode
 	return args.Get(0).(*models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) FindAll() ([]models.Account, error) {
+func (m *MockAccountRepository) FindAll(ctx context.Context) ([]models.Account, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Account), args.Error(1)
 }
 
-func (m *MockAccountRepository) Update(account *models.Account) error {
+func (m *MockAccountRepository) Update(ctx context.Context, account *models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) Delete(id uint) error {
+func (m *MockAccountRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) UpdateBalance(id uint, amount float64) error {
+func (m *MockAccountRepository) UpdateBalance(ctx context.Context, id uint, amount float64) error {
 	args := m.Called(id, amount)
 	return args.Error(0)
 }
 
-func (m *MockAccountRepository) FindByIDWithLock(id uint) (*models.Account, repository.GormTx, error) {
-	args := m.Called(id)
-	var account *models.Account
-	
-	if args.Get(0) != nil {
-		account = args.Get(0).(*models.Account)
+// LockForTransfer runs fn with the accounts and transaction set up for the
+// two IDs, and returns what fn returns
+func (m *MockAccountRepository) LockForTransfer(ctx context.Context, fromID, toID uint, fn func(from, to *models.Account, tx repository.GormTx) error) error {
+	args := m.Called(fromID, toID)
+	if err := args.Error(3); err != nil {
+		return err
 	}
-	
-	// Return nil for the DB, as we'll use our MockDB for testing
-	return account, nil, args.Error(2)
+	return fn(args.Get(0).(*models.Account), args.Get(1).(*models.Account), args.Get(2).(repository.GormTx))
+}
+
+func (m *MockAccountRepository) SetFrozen(ctx context.Context, id uint, frozen bool, entry *models.AuditEntry) error {
+	args := m.Called(id, frozen, entry)
+	return args.Error(0)
+}
+
+func (m *MockAccountRepository) CreditOnce(ctx context.Context, id uint, amount float64, description string, date time.Time, entry *models.AuditEntry) (bool, error) {
+	args := m.Called(id, amount, description, date, entry)
+	return args.Bool(0), args.Error(1)
 }
 
 // Mock for TransactionRepository
@@ -115,17 +181,22 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) Create(transaction *models.Transaction) error {
+func (m *MockTransactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
 	args := m.Called(transaction)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) CreateWithTx(transaction *models.Transaction, tx repository.GormTx) error {
+func (m *MockTransactionRepository) CreateWithTx(ctx context.Context, transaction *models.Transaction, tx repository.GormTx) error {
 	args := m.Called(transaction, tx)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) FindByID(id uint) (*models.Transaction, error) {
+func (m *MockTransactionRepository) LedgerTotals(ctx context.Context) ([]models.LedgerTotals, error) {
+	args := m.Called()
+	return args.Get(0).([]models.LedgerTotals), args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindByID(ctx context.Context, id uint) (*models.Transaction, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -133,26 +204,104 @@ func (m *MockTransactionRepository) FindByID(id uint) (*models.Transaction, erro
 	return args.Get(0).(*models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindByAccountID(accountID uint, limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindByAccountID(ctx context.Context, accountID uint, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(accountID, limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) FindAll(limit, offset int) ([]models.Transaction, error) {
+func (m *MockTransactionRepository) FindAll(ctx context.Context, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(limit, offset)
 	return args.Get(0).([]models.Transaction), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CountByAccountID(accountID uint) (int64, error) {
+func (m *MockTransactionRepository) CountByAccountID(ctx context.Context, accountID uint) (int64, error) {
 	args := m.Called(accountID)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockTransactionRepository) CountAll() (int64, error) {
+func (m *MockTransactionRepository) CountAll(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockTransactionRepository) CountTransfersFrom(ctx context.Context, accountID uint, since time.Time) (int64, error) {
+	args := m.Called(accountID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) AverageTransferFrom(ctx context.Context, accountID uint) (float64, int64, error) {
+	args := m.Called(accountID)
+	return args.Get(0).(float64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTransactionRepository) HasTransferred(ctx context.Context, fromAccountID, toAccountID uint) (bool, error) {
+	args := m.Called(fromAccountID, toAccountID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumTransfersByUser(ctx context.Context, userID uint, since time.Time) (float64, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockTransactionRepository) SumTransfersByUserWithTx(ctx context.Context, userID uint, since time.Time, tx repository.GormTx) (float64, error) {
+	args := m.Called(userID, since, tx)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+// Mock for TransferReviewRepository
+type MockTransferReviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransferReviewRepository) Create(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	args := m.Called(review, entry)
+	return args.Error(0)
+}
+
+func (m *MockTransferReviewRepository) FindByID(ctx context.Context, id uint) (*models.TransferReview, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransferReview), args.Error(1)
+}
+
+func (m *MockTransferReviewRepository) FindByStatus(ctx context.Context, status string, limit, offset int) ([]models.TransferReview, error) {
+	args := m.Called(status, limit, offset)
+	return args.Get(0).([]models.TransferReview), args.Error(1)
+}
+
+func (m *MockTransferReviewRepository) Resolve(ctx context.Context, review *models.TransferReview, entry *models.AuditEntry) error {
+	args := m.Called(review, entry)
+	return args.Error(0)
+}
+
+func (m *MockTransferReviewRepository) ResolveWithTx(ctx context.Context, review *models.TransferReview, tx repository.GormTx) error {
+	args := m.Called(review, tx)
+	return args.Error(0)
+}
+
+// Mock for AuditRepository
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) AppendWithTx(ctx context.Context, entry *models.AuditEntry, tx repository.GormTx) error {
+	args := m.Called(entry, tx)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) Find(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditRepository) FindAfter(ctx context.Context, id uint, limit int) ([]models.AuditEntry, error) {
+	args := m.Called(id, limit)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
 // MockDBResult implements repository.GormResult
 type MockDBResult struct {
 	Err error
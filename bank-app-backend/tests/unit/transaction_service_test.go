@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -15,7 +16,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		account := &models.Account{
 			ID:            1,
@@ -49,7 +50,7 @@ func TestTransactionService(t *testing.T) {
 		})).Return(nil)
 		
 		// Act
-		err := service.CreateTransaction(transaction)
+		err := service.CreateTransaction(context.Background(), transaction)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -62,7 +63,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		account := &models.Account{
 			ID:            1,
@@ -96,7 +97,7 @@ func TestTransactionService(t *testing.T) {
 		})).Return(nil)
 		
 		// Act
-		err := service.CreateTransaction(transaction)
+		err := service.CreateTransaction(context.Background(), transaction)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -109,7 +110,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		account := &models.Account{
 			ID:            1,
@@ -130,7 +131,7 @@ func TestTransactionService(t *testing.T) {
 		mockAccRepo.On("FindByID", uint(1)).Return(account, nil)
 		
 		// Act
-		err := service.CreateTransaction(transaction)
+		err := service.CreateTransaction(context.Background(), transaction)
 		
 		// Assert
 		assert.Error(t, err)
@@ -143,7 +144,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		fromAccount := &models.Account{
 			ID:            1,
@@ -164,9 +165,8 @@ func TestTransactionService(t *testing.T) {
 		// Mock DB for transactions
 		mockDB := new(MockDB)
 		
-		// Mock FindByIDWithLock calls - return nil for DB
-		mockAccRepo.On("FindByIDWithLock", uint(1)).Return(fromAccount, nil, nil)
-		mockAccRepo.On("FindByIDWithLock", uint(2)).Return(toAccount, nil, nil)
+		// Mock LockForTransfer - the mock DB stands in for the transaction
+		mockAccRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, toAccount, mockDB, nil)
 		
 		// Skip this test for now as we need to refactor it to properly mock DB transactions
 		t.Skip("Skipping transfer test until DB transaction mocking is refactored")
@@ -183,8 +183,6 @@ func TestTransactionService(t *testing.T) {
 			}
 			return false
 		})).Return(mockDB)
-		mockDB.On("Commit").Return(mockDB)
-		mockDB.On("Rollback").Return(mockDB)
 		mockDB.On("Error").Return(nil)
 		
 		// Mock transaction creation
@@ -213,7 +211,7 @@ func TestTransactionService(t *testing.T) {
 		}
 		
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req, nil, models.AuditActor{})
 		
 		// Assert
 		assert.NoError(t, err)
@@ -226,7 +224,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		fromAccount := &models.Account{
 			ID:            1,
@@ -242,11 +240,8 @@ func TestTransactionService(t *testing.T) {
 		// Skip this test for now as we need to refactor it
 		t.Skip("Skipping transfer test until DB transaction mocking is refactored")
 		
-		// Mock FindByIDWithLock calls
-		mockAccRepo.On("FindByIDWithLock", uint(1)).Return(fromAccount, nil, nil)
-		
-		// Mock transaction operations
-		mockDB.On("Rollback").Return(mockDB)
+		// Mock LockForTransfer
+		mockAccRepo.On("LockForTransfer", uint(1), uint(2)).Return(fromAccount, &models.Account{ID: 2}, mockDB, nil)
 		
 		// Request for transfer with amount greater than balance
 		req := &models.TransferRequest{
@@ -257,7 +252,7 @@ func TestTransactionService(t *testing.T) {
 		}
 		
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req, nil, models.AuditActor{})
 		
 		// Assert
 		assert.Error(t, err)
@@ -273,7 +268,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		// Request for transfer with zero amount
 		req := &models.TransferRequest{
@@ -284,12 +279,12 @@ func TestTransactionService(t *testing.T) {
 		}
 		
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req, nil, models.AuditActor{})
 		
 		// Assert
 		assert.Error(t, err)
 		assert.Equal(t, "transfer amount must be positive", err.Error())
-		mockAccRepo.AssertNotCalled(t, "FindByIDWithLock", mock.Anything)
+		mockAccRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
 		mockTransRepo.AssertNotCalled(t, "CreateWithTx", mock.Anything, mock.Anything)
 	})
 	
@@ -299,7 +294,7 @@ func TestTransactionService(t *testing.T) {
 		// Arrange
 		mockTransRepo := new(MockTransactionRepository)
 		mockAccRepo := new(MockAccountRepository)
-		service := services.NewTransactionService(mockTransRepo, mockAccRepo)
+		service := services.NewTransactionService(mockTransRepo, mockAccRepo, new(MockAuditRepository), new(MockTransferReviewRepository), services.TransactionOptions{})
 		
 		// Request for transfer to the same account
 		req := &models.TransferRequest{
@@ -310,12 +305,12 @@ func TestTransactionService(t *testing.T) {
 		}
 		
 		// Act
-		err := service.Transfer(req)
+		err := service.Transfer(context.Background(), req, nil, models.AuditActor{})
 		
 		// Assert
 		assert.Error(t, err)
 		assert.Equal(t, "cannot transfer to the same account", err.Error())
-		mockAccRepo.AssertNotCalled(t, "FindByIDWithLock", mock.Anything)
+		mockAccRepo.AssertNotCalled(t, "LockForTransfer", mock.Anything, mock.Anything)
 		mockTransRepo.AssertNotCalled(t, "CreateWithTx", mock.Anything, mock.Anything)
 	})
 }
@@ -5,23 +5,22 @@ import (
 	"time"
 
 	"github.com/jbadhree/drank/bank-app-backend/internal/models"
+	"github.com/jbadhree/drank/bank-app-backend/internal/pii"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 func TestUserModel(t *testing.T) {
-	t.Run("BeforeSave should hash password", func(t *testing.T) {
+	t.Run("SetPassword should hash password", func(t *testing.T) {
 		// Arrange
 		user := &models.User{
 			Email:     "test@example.com",
-			Password:  "password123",
 			FirstName: "Test",
 			LastName:  "User",
 		}
 
 		// Act
-		err := user.BeforeSave(&gorm.DB{})
+		err := user.SetPassword("password123", bcrypt.MinCost)
 
 		// Assert
 		assert.NoError(t, err)
@@ -31,21 +30,15 @@ func TestUserModel(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
-	t.Run("BeforeSave should not hash empty password", func(t *testing.T) {
+	t.Run("PasswordNeedsRehash should compare bcrypt costs", func(t *testing.T) {
 		// Arrange
-		user := &models.User{
-			Email:     "test@example.com",
-			Password:  "",
-			FirstName: "Test",
-			LastName:  "User",
-		}
+		user := &models.User{}
+		_ = user.SetPassword("password123", bcrypt.MinCost)
 
-		// Act
-		err := user.BeforeSave(&gorm.DB{})
-
-		// Assert
-		assert.NoError(t, err)
-		assert.Equal(t, "", user.Password)
+		// Act & Assert
+		assert.True(t, user.PasswordNeedsRehash(bcrypt.MinCost+1))
+		assert.False(t, user.PasswordNeedsRehash(bcrypt.MinCost))
+		assert.False(t, (&models.User{}).PasswordNeedsRehash(bcrypt.DefaultCost))
 	})
 
 	t.Run("ComparePassword should verify correct password", func(t *testing.T) {
@@ -101,4 +94,19 @@ func TestUserModel(t *testing.T) {
 		assert.Equal(t, now, dto.CreatedAt)
 		assert.Equal(t, now, dto.UpdatedAt)
 	})
+
+	t.Run("Creating a user should index the email", func(t *testing.T) {
+		// Arrange
+		keyring := pii.NewDevelopmentKeyring()
+		pii.Register(keyring)
+		user := &models.User{Email: "test@example.com"}
+
+		// Act
+		err := user.BeforeCreate(nil)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, keyring.BlindIndex("test@example.com"), user.EmailIndex)
+		assert.NotContains(t, user.EmailIndex, "test")
+	})
 }
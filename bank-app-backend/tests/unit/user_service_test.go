@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -28,7 +29,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("Create", user).Return(nil)
 		
 		// Act
-		err := service.CreateUser(user)
+		err := service.CreateUser(context.Background(), user)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -57,7 +58,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "existing@example.com").Return(existingUser, nil)
 		
 		// Act
-		err := service.CreateUser(user)
+		err := service.CreateUser(context.Background(), user)
 		
 		// Assert
 		assert.Error(t, err)
@@ -80,7 +81,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByID", uint(1)).Return(user, nil)
 		
 		// Act
-		result, err := service.GetUserByID(1)
+		result, err := service.GetUserByID(context.Background(), 1)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -96,7 +97,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByID", uint(999)).Return(nil, errors.New("user not found"))
 		
 		// Act
-		result, err := service.GetUserByID(999)
+		result, err := service.GetUserByID(context.Background(), 999)
 		
 		// Assert
 		assert.Error(t, err)
@@ -124,7 +125,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "test@example.com").Return(user, nil)
 		
 		// Act
-		result, err := service.AuthenticateUser("test@example.com", password)
+		result, err := service.AuthenticateUser(context.Background(), "test@example.com", password)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -140,7 +141,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "nonexistent@example.com").Return(nil, errors.New("user not found"))
 		
 		// Act
-		result, err := service.AuthenticateUser("nonexistent@example.com", "password123")
+		result, err := service.AuthenticateUser(context.Background(), "nonexistent@example.com", "password123")
 		
 		// Assert
 		assert.Error(t, err)
@@ -168,7 +169,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "test@example.com").Return(user, nil)
 		
 		// Act
-		result, err := service.AuthenticateUser("test@example.com", "wrongpassword")
+		result, err := service.AuthenticateUser(context.Background(), "test@example.com", "wrongpassword")
 		
 		// Assert
 		assert.Error(t, err)
@@ -194,7 +195,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("Update", user).Return(nil)
 		
 		// Act
-		err := service.UpdateUser(user)
+		err := service.UpdateUser(context.Background(), user)
 		
 		// Assert
 		assert.NoError(t, err)
@@ -224,7 +225,7 @@ func TestUserService(t *testing.T) {
 		mockRepo.On("FindByEmail", "taken@example.com").Return(existingUser, nil)
 		
 		// Act
-		err := service.UpdateUser(user)
+		err := service.UpdateUser(context.Background(), user)
 		
 		// Assert
 		assert.Error(t, err)